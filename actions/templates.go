@@ -0,0 +1,10 @@
+// Package actions holds the built-in catalog of action templates.
+package actions
+
+import "embed"
+
+// Each template is a Nix file with Go template syntax, so not valid Nix,
+// and a JSON file of the same name that describes it and its parameters.
+//
+//go:embed templates/*.nix.tmpl templates/*.json
+var Templates embed.FS
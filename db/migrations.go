@@ -0,0 +1,8 @@
+// Package db holds the database migrations
+// so that they can be applied without dbmate.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,13 @@
+-- migrate:up
+
+CREATE INDEX IF NOT EXISTS index_nomad_event_evaluation_job_id
+  ON nomad_event ((payload #>> '{Evaluation,JobID}'::text[]));
+
+CREATE INDEX IF NOT EXISTS index_nomad_event_job_id_job
+  ON nomad_event ((payload #>> '{Job,ID}'::text[]));
+
+-- migrate:down
+
+DROP INDEX IF EXISTS index_nomad_event_evaluation_job_id;
+
+DROP INDEX IF EXISTS index_nomad_event_job_id_job;
@@ -0,0 +1,18 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD nomad_job_gced_at timestamp;
+
+CREATE TABLE run_allocation (
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	allocation jsonb NOT NULL
+);
+
+CREATE INDEX run_allocation_run_id ON run_allocation (run_id);
+
+-- migrate:down
+
+DROP TABLE run_allocation;
+
+ALTER TABLE run
+DROP nomad_job_gced_at;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+CREATE TABLE evaluation_cache (
+	action_id uuid NOT NULL REFERENCES action (id) ON DELETE CASCADE,
+	inputs_hash bytea NOT NULL,
+	job jsonb,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (action_id, inputs_hash)
+);
+
+-- migrate:down
+
+DROP TABLE evaluation_cache;
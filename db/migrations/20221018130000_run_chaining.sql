@@ -0,0 +1,17 @@
+-- migrate:up
+
+ALTER TABLE action
+ADD chain jsonb NOT NULL DEFAULT '[]';
+
+ALTER TABLE invocation
+ADD chained_from uuid REFERENCES run (nomad_job_id) ON DELETE SET NULL;
+
+CREATE INDEX invocation_chained_from ON invocation (chained_from);
+
+-- migrate:down
+
+ALTER TABLE invocation
+DROP chained_from;
+
+ALTER TABLE action
+DROP chain;
@@ -0,0 +1,17 @@
+-- migrate:up
+
+CREATE TABLE api_token (
+	id uuid PRIMARY KEY DEFAULT public.gen_random_uuid(),
+	name text NOT NULL,
+	hash bytea NOT NULL UNIQUE,
+	scopes text[] NOT NULL DEFAULT '{}',
+	created_by text NOT NULL,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	expires_at timestamp,
+	last_used_at timestamp,
+	revoked_at timestamp
+);
+
+-- migrate:down
+
+DROP TABLE api_token;
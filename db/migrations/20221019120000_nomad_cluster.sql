@@ -0,0 +1,19 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD nomad_cluster text NOT NULL DEFAULT '';
+
+ALTER TABLE nomad_event
+ADD nomad_cluster text NOT NULL DEFAULT '';
+
+CREATE INDEX nomad_event_nomad_cluster_index ON nomad_event (nomad_cluster, "index");
+
+-- migrate:down
+
+DROP INDEX nomad_event_nomad_cluster_index;
+
+ALTER TABLE nomad_event
+DROP nomad_cluster;
+
+ALTER TABLE run
+DROP nomad_cluster;
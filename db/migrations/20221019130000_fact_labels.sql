@@ -0,0 +1,21 @@
+-- migrate:up
+
+ALTER TABLE fact
+ADD namespace text NOT NULL DEFAULT '',
+ADD name text NOT NULL DEFAULT '',
+ADD tags text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX fact_namespace_name ON fact (namespace, name, created_at DESC);
+
+CREATE INDEX fact_tags ON fact USING gin (tags);
+
+-- migrate:down
+
+DROP INDEX fact_tags;
+
+DROP INDEX fact_namespace_name;
+
+ALTER TABLE fact
+DROP namespace,
+DROP name,
+DROP tags;
@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE run_usage (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	cpu_seconds double precision NOT NULL,
+	memory_byte_seconds double precision NOT NULL,
+	measured_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP()
+);
+
+CREATE INDEX run_finished_at ON run (finished_at);
+
+-- migrate:down
+
+DROP INDEX run_finished_at;
+
+DROP TABLE run_usage;
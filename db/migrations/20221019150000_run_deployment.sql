@@ -0,0 +1,11 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD deployment_status text NOT NULL DEFAULT '',
+ADD deployment_status_description text NOT NULL DEFAULT '';
+
+-- migrate:down
+
+ALTER TABLE run
+DROP deployment_status,
+DROP deployment_status_description;
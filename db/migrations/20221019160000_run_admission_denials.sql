@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD admission_denials text[] NOT NULL DEFAULT '{}';
+
+-- migrate:down
+
+ALTER TABLE run
+DROP admission_denials;
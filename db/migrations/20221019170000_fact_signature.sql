@@ -0,0 +1,25 @@
+-- migrate:up
+
+CREATE TABLE fact_publisher (
+	name text PRIMARY KEY,
+	public_key text NOT NULL,
+	key_id text NOT NULL UNIQUE,
+	created_by text NOT NULL,
+	created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE fact
+ADD signature text,
+ADD signed_by text;
+
+CREATE INDEX fact_signed_by ON fact (signed_by, created_at DESC);
+
+-- migrate:down
+
+DROP INDEX fact_signed_by;
+
+ALTER TABLE fact
+DROP signature,
+DROP signed_by;
+
+DROP TABLE fact_publisher;
@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD suspect_since timestamp;
+
+-- migrate:down
+
+ALTER TABLE run
+DROP suspect_since;
@@ -0,0 +1,15 @@
+-- migrate:up
+
+CREATE TABLE run_log (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	log lo NOT NULL,
+	lines integer NOT NULL,
+	archived_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP()
+);
+
+CREATE TRIGGER log BEFORE UPDATE OR DELETE ON run_log
+FOR EACH ROW EXECUTE FUNCTION lo_manage(log);
+
+-- migrate:down
+
+DROP TABLE run_log;
@@ -0,0 +1,23 @@
+-- migrate:up
+
+CREATE SEQUENCE fact_seq;
+
+ALTER TABLE fact ADD seq bigint;
+
+UPDATE fact SET seq = numbered.seq
+FROM (
+	SELECT id, nextval('fact_seq') AS seq
+	FROM (SELECT id FROM fact ORDER BY created_at, id) AS ordered
+) AS numbered
+WHERE fact.id = numbered.id;
+
+ALTER TABLE fact
+ALTER seq SET DEFAULT nextval('fact_seq'),
+ALTER seq SET NOT NULL,
+ADD CONSTRAINT fact_seq_key UNIQUE (seq);
+
+ALTER SEQUENCE fact_seq OWNED BY fact.seq;
+
+-- migrate:down
+
+ALTER TABLE fact DROP seq;
@@ -0,0 +1,30 @@
+-- migrate:up
+
+CREATE TABLE quota (
+	subject text NOT NULL CHECK (subject IN ('project', 'token')),
+	name text NOT NULL,
+	max_runs_per_hour integer CHECK (max_runs_per_hour >= 0),
+	max_concurrent_runs integer CHECK (max_concurrent_runs >= 0),
+	max_fact_bytes bigint CHECK (max_fact_bytes >= 0),
+	override_until timestamp,
+	override_reason text NOT NULL DEFAULT '',
+	updated_by text NOT NULL,
+	updated_at timestamp NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (subject, name)
+);
+
+ALTER TABLE fact
+ADD api_token_id uuid REFERENCES api_token (id) ON DELETE SET NULL,
+ADD binary_size bigint;
+
+CREATE INDEX fact_api_token_id_idx ON fact (api_token_id);
+
+-- migrate:down
+
+DROP INDEX fact_api_token_id_idx;
+
+ALTER TABLE fact
+DROP api_token_id,
+DROP binary_size;
+
+DROP TABLE quota;
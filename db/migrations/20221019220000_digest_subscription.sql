@@ -0,0 +1,19 @@
+-- migrate:up
+
+CREATE TABLE digest_subscription (
+	id uuid PRIMARY KEY DEFAULT public.gen_random_uuid(),
+	email text NOT NULL,
+	project text,
+	period text NOT NULL CHECK (period IN ('daily', 'weekly')),
+	created_by text NOT NULL,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	last_sent_at timestamp
+);
+
+CREATE INDEX run_failed_finished_at_idx ON run (finished_at) WHERE status = 'failed';
+
+-- migrate:down
+
+DROP INDEX run_failed_finished_at_idx;
+
+DROP TABLE digest_subscription;
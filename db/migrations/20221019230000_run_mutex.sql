@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE run_mutex (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	name text NOT NULL,
+	-- The Nomad job to register once the mutex is acquired.
+	job jsonb,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	acquired_at timestamp
+);
+
+CREATE INDEX run_mutex_name_created_at_idx ON run_mutex (name, created_at);
+
+-- migrate:down
+
+DROP TABLE run_mutex;
@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE fact_link (
+	fact_id uuid NOT NULL REFERENCES fact (id) ON DELETE CASCADE,
+	kind text NOT NULL CHECK (kind IN ('supersedes', 'caused-by', 'correlates-with')),
+	target_id uuid NOT NULL REFERENCES fact (id) ON DELETE CASCADE,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (fact_id, kind, target_id),
+	CHECK (fact_id <> target_id)
+);
+
+CREATE INDEX fact_link_target_id_idx ON fact_link (target_id);
+
+-- migrate:down
+
+DROP TABLE fact_link;
@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE run_manifest (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	-- Not jsonb so that the job is kept byte for byte.
+	job json NOT NULL,
+	evaluators jsonb NOT NULL DEFAULT '[]',
+	cicero_version text NOT NULL,
+	cicero_commit text NOT NULL,
+	images jsonb NOT NULL DEFAULT '[]',
+	created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+-- migrate:down
+
+DROP TABLE run_manifest;
@@ -0,0 +1,33 @@
+-- migrate:up
+
+-- Tells the RunMutexScheduler which mutex may have become free
+-- or got a Run waiting for it. Postgres delivers notifications
+-- only once the transaction commits.
+CREATE FUNCTION notify_run_mutex()
+RETURNS trigger
+LANGUAGE plpgsql AS $$
+	BEGIN
+		IF TG_TABLE_NAME = 'run_mutex' THEN
+			PERFORM pg_notify('cicero_run_mutex', NEW.name);
+		ELSE
+			PERFORM pg_notify('cicero_run_mutex', run_mutex.name)
+			FROM run_mutex
+			WHERE run_mutex.run_id = NEW.nomad_job_id;
+		END IF;
+		RETURN NULL;
+	END;
+$$;
+
+CREATE TRIGGER notify_run_mutex AFTER INSERT ON run_mutex
+FOR EACH ROW EXECUTE FUNCTION notify_run_mutex();
+
+CREATE TRIGGER notify_run_mutex AFTER UPDATE OF finished_at, status ON run
+FOR EACH ROW
+WHEN (OLD.finished_at IS DISTINCT FROM NEW.finished_at OR OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION notify_run_mutex();
+
+-- migrate:down
+
+DROP TRIGGER notify_run_mutex ON run;
+DROP TRIGGER notify_run_mutex ON run_mutex;
+DROP FUNCTION notify_run_mutex;
@@ -0,0 +1,23 @@
+-- migrate:up
+
+-- Tokens that let a Run's job report its progress.
+CREATE TABLE run_token (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	hash bytea NOT NULL UNIQUE
+);
+
+CREATE TABLE run_progress (
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	done double precision NOT NULL,
+	total double precision,
+	message text NOT NULL DEFAULT '',
+	value jsonb,
+	created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP()
+);
+
+CREATE INDEX run_progress_run_id_created_at_idx ON run_progress (run_id, created_at);
+
+-- migrate:down
+
+DROP TABLE run_progress;
+DROP TABLE run_token;
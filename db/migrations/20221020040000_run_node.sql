@@ -0,0 +1,15 @@
+-- migrate:up
+
+-- Nomad nodes that allocations of a Run ran on.
+CREATE TABLE run_node (
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	node_id text NOT NULL,
+	created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	PRIMARY KEY (run_id, node_id)
+);
+
+CREATE INDEX run_node_created_at_idx ON run_node (created_at);
+
+-- migrate:down
+
+DROP TABLE run_node;
@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD reconcile_note text;
+
+-- migrate:down
+
+ALTER TABLE run
+DROP reconcile_note;
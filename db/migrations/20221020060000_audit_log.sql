@@ -0,0 +1,41 @@
+-- migrate:up
+
+-- Mutating API calls. Rows cannot be changed or deleted.
+CREATE TABLE audit_log (
+	id bigserial PRIMARY KEY,
+	created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	-- Null if the request was not authenticated.
+	identity text,
+	auth_method text,
+	-- Not a foreign key so that entries outlive their token.
+	api_token_id uuid,
+	method text NOT NULL,
+	path text NOT NULL,
+	query text NOT NULL DEFAULT '',
+	-- As given by the client, null if unknown.
+	body_size bigint,
+	status smallint NOT NULL,
+	duration_ms integer NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+CREATE INDEX audit_log_identity_created_at_idx ON audit_log (identity, created_at);
+
+CREATE FUNCTION audit_log_append_only()
+RETURNS trigger
+LANGUAGE plpgsql AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END;
+$$;
+
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER append_only_truncate BEFORE TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+-- migrate:down
+
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_append_only;
@@ -0,0 +1,21 @@
+-- migrate:up
+
+-- Jobs dispatched from the parameterized job of a Run.
+-- They are inserted before they are dispatched
+-- so that the Run only ends once all of them ended.
+CREATE TABLE run_dispatch (
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	number smallint NOT NULL,
+	-- Null until dispatched.
+	nomad_job_id text UNIQUE,
+	meta jsonb,
+	payload bytea,
+	created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	finished_at timestamp,
+	status run_status NOT NULL DEFAULT 'running',
+	PRIMARY KEY (run_id, number)
+);
+
+-- migrate:down
+
+DROP TABLE run_dispatch;
@@ -0,0 +1,36 @@
+-- migrate:up
+
+CREATE TABLE run_queue (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	-- The Nomad job to register once it is the Run's turn.
+	job jsonb NOT NULL,
+	created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX run_queue_created_at_idx ON run_queue (created_at);
+
+-- Tells the RunQueueScheduler that a Run was queued
+-- or that one ended so that another may be submitted.
+CREATE FUNCTION notify_run_queue()
+RETURNS trigger
+LANGUAGE plpgsql AS $$
+	BEGIN
+		PERFORM pg_notify('cicero_run_queue', '');
+		RETURN NULL;
+	END;
+$$;
+
+CREATE TRIGGER notify_run_queue AFTER INSERT ON run_queue
+FOR EACH STATEMENT EXECUTE FUNCTION notify_run_queue();
+
+CREATE TRIGGER notify_run_queue AFTER UPDATE OF finished_at ON run
+FOR EACH ROW
+WHEN (OLD.finished_at IS NULL AND NEW.finished_at IS NOT NULL)
+EXECUTE FUNCTION notify_run_queue();
+
+-- migrate:down
+
+DROP TRIGGER notify_run_queue ON run;
+DROP TRIGGER notify_run_queue ON run_queue;
+DROP FUNCTION notify_run_queue;
+DROP TABLE run_queue;
@@ -0,0 +1,29 @@
+-- migrate:up
+
+-- Preview environments created by Runs of actions
+-- with an `environment` meta attribute.
+CREATE TABLE environment (
+	id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	name text NOT NULL,
+	url text,
+	action_name text NOT NULL,
+	-- The latest Run that created or updated the environment.
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	teardown_action text NOT NULL,
+	-- CUE that a fact must match to close the environment.
+	closed_by text,
+	-- The facts up to this one were checked against `closed_by`.
+	fact_seq bigint NOT NULL DEFAULT 0,
+	created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	updated_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	expires_at timestamp,
+	torn_down_at timestamp,
+	teardown_reason text
+);
+
+-- Runs update the open environment of the same name instead of creating another one.
+CREATE UNIQUE INDEX environment_open_idx ON environment (action_name, name) WHERE torn_down_at IS NULL;
+
+-- migrate:down
+
+DROP TABLE environment;
@@ -0,0 +1,21 @@
+-- migrate:up
+
+-- How often each identity read each fact through the API.
+CREATE TABLE fact_access (
+	fact_id uuid NOT NULL REFERENCES fact (id) ON DELETE CASCADE,
+	-- Empty if the reader was not authenticated.
+	identity text NOT NULL,
+	reads bigint NOT NULL DEFAULT 1,
+	first_read_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	last_read_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	PRIMARY KEY (fact_id, identity)
+);
+
+-- Counting how often facts triggered actions looks them up by fact.
+CREATE INDEX invocation_inputs_fact_id_idx ON invocation_inputs (fact_id);
+
+-- migrate:down
+
+DROP INDEX invocation_inputs_fact_id_idx;
+
+DROP TABLE fact_access;
@@ -0,0 +1,16 @@
+-- migrate:up
+
+-- Filters of Runs or facts that are shared by name.
+CREATE TABLE saved_query (
+	name text PRIMARY KEY,
+	kind text NOT NULL CHECK (kind IN ('run', 'fact')),
+	-- URL query of the Run filters or CUE that facts must match.
+	query text NOT NULL,
+	description text NOT NULL DEFAULT '',
+	updated_by text NOT NULL,
+	updated_at timestamp NOT NULL DEFAULT NOW()
+);
+
+-- migrate:down
+
+DROP TABLE saved_query;
@@ -0,0 +1,9 @@
+-- migrate:up
+
+-- Consumers that shard by topic look up the last index of each topic.
+CREATE INDEX nomad_event_nomad_cluster_topic_index
+  ON nomad_event (nomad_cluster, topic, "index");
+
+-- migrate:down
+
+DROP INDEX nomad_event_nomad_cluster_topic_index;
@@ -0,0 +1,8 @@
+-- migrate:up
+
+-- Cascading cancellation looks up the facts that Runs published.
+CREATE INDEX fact_run_id_idx ON fact (run_id);
+
+-- migrate:down
+
+DROP INDEX fact_run_id_idx;
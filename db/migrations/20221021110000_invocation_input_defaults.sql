@@ -0,0 +1,8 @@
+-- migrate:up
+
+-- Values of the optional inputs that no fact satisfied by input name.
+ALTER TABLE invocation ADD input_defaults jsonb;
+
+-- migrate:down
+
+ALTER TABLE invocation DROP input_defaults;
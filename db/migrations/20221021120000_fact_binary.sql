@@ -0,0 +1,81 @@
+-- migrate:up
+
+-- Binaries are stored once per content and shared by all facts that have it.
+CREATE TABLE fact_binary (
+	hash text PRIMARY KEY,
+	"binary" lo NOT NULL,
+	size bigint,
+	-- Number of facts with this binary. Deleted when it drops to zero.
+	refs bigint NOT NULL DEFAULT 0 CHECK (refs >= 0)
+);
+
+CREATE TRIGGER "binary" BEFORE UPDATE OR DELETE ON fact_binary
+FOR EACH ROW EXECUTE FUNCTION lo_manage("binary");
+
+INSERT INTO fact_binary (hash, "binary", size, refs)
+SELECT DISTINCT ON (binary_hash) binary_hash, "binary", binary_size, count(*) OVER (PARTITION BY binary_hash)
+FROM fact
+WHERE binary_hash IS NOT NULL
+ORDER BY binary_hash, created_at;
+
+SELECT lo_unlink(fact."binary")
+FROM fact
+JOIN fact_binary ON fact_binary.hash = fact.binary_hash
+WHERE fact."binary" <> fact_binary."binary";
+
+DROP TRIGGER "binary" ON fact;
+DROP VIEW api.artifact;
+ALTER TABLE fact DROP "binary";
+ALTER TABLE fact ADD FOREIGN KEY (binary_hash) REFERENCES fact_binary (hash);
+
+CREATE VIEW api.artifact AS
+SELECT fact.*, fact_binary."binary"
+FROM fact
+JOIN fact_binary ON fact_binary.hash = fact.binary_hash;
+
+CREATE FUNCTION fact_binary_refs() RETURNS trigger LANGUAGE plpgsql AS $$
+	BEGIN
+		IF TG_OP <> 'INSERT' AND OLD.binary_hash IS NOT NULL THEN
+			UPDATE fact_binary SET refs = refs - 1 WHERE hash = OLD.binary_hash;
+		END IF;
+		IF TG_OP <> 'DELETE' AND NEW.binary_hash IS NOT NULL THEN
+			UPDATE fact_binary SET refs = refs + 1 WHERE hash = NEW.binary_hash;
+		END IF;
+		IF TG_OP <> 'INSERT' AND OLD.binary_hash IS NOT NULL THEN
+			DELETE FROM fact_binary WHERE hash = OLD.binary_hash AND refs = 0;
+		END IF;
+		RETURN NULL;
+	END;
+$$;
+
+CREATE TRIGGER binary_refs AFTER INSERT OR UPDATE OF binary_hash OR DELETE ON fact
+FOR EACH ROW EXECUTE FUNCTION fact_binary_refs();
+
+-- migrate:down
+
+DROP TRIGGER binary_refs ON fact;
+DROP FUNCTION fact_binary_refs;
+
+DROP VIEW api.artifact;
+ALTER TABLE fact DROP CONSTRAINT fact_binary_hash_fkey;
+ALTER TABLE fact ADD "binary" lo;
+
+-- Every fact gets its own copy again.
+UPDATE fact
+SET "binary" = lo_from_bytea(0, lo_get(fact_binary."binary"))
+FROM fact_binary
+WHERE fact_binary.hash = fact.binary_hash;
+
+ALTER TABLE fact ADD CHECK (("binary" IS NULL) = (binary_hash IS NULL));
+
+CREATE TRIGGER "binary" BEFORE UPDATE OR DELETE ON fact
+FOR EACH ROW EXECUTE FUNCTION lo_manage("binary");
+
+CREATE VIEW api.artifact AS
+SELECT *
+FROM fact
+WHERE "binary" IS NOT NULL;
+
+-- Dropping the table does not fire its triggers.
+SELECT lo_unlink("binary") FROM fact_binary;
+DROP TABLE fact_binary;
@@ -0,0 +1,17 @@
+-- migrate:up
+
+ALTER TABLE run_queue
+ADD overridden_by text,
+ADD override_reason text;
+
+-- Overridden Runs may be submitted right away.
+CREATE TRIGGER notify_run_queue_override AFTER UPDATE OF overridden_by ON run_queue
+FOR EACH STATEMENT EXECUTE FUNCTION notify_run_queue();
+
+-- migrate:down
+
+DROP TRIGGER notify_run_queue_override ON run_queue;
+
+ALTER TABLE run_queue
+DROP overridden_by,
+DROP override_reason;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- A row is saved once the Run's log was scanned,
+-- even if no line matched, so that it is not scanned again.
+CREATE TABLE run_annotation (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	annotations jsonb NOT NULL,
+	scanned_at timestamp NOT NULL DEFAULT NOW()
+);
+
+-- migrate:down
+
+DROP TABLE run_annotation;
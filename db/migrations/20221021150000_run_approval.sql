@@ -0,0 +1,20 @@
+-- migrate:up
+
+-- The job of a Run is kept until someone decides whether to submit it.
+CREATE TABLE run_approval (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	approvers text[] NOT NULL,
+	job jsonb,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	expires_at timestamp,
+	approved boolean,
+	decided_by text,
+	decided_at timestamp,
+	comment text
+);
+
+CREATE INDEX run_approval_pending_idx ON run_approval (created_at) WHERE decided_at IS NULL;
+
+-- migrate:down
+
+DROP TABLE run_approval;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+CREATE TABLE run_usage_peak (
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	task_group text NOT NULL,
+	cpu_cores double precision NOT NULL,
+	memory_bytes double precision NOT NULL,
+	PRIMARY KEY (run_id, task_group)
+);
+
+-- migrate:down
+
+DROP TABLE run_usage_peak;
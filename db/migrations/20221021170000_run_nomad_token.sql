@@ -0,0 +1,19 @@
+-- migrate:up
+
+CREATE TABLE run_nomad_token (
+	accessor_id text PRIMARY KEY,
+	run_id uuid NOT NULL REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	nomad_cluster text NOT NULL,
+	policies text[] NOT NULL,
+	created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP(),
+	expires_at timestamp NOT NULL,
+	revoked_at timestamp
+);
+
+CREATE INDEX run_nomad_token_run_id ON run_nomad_token (run_id);
+
+CREATE INDEX run_nomad_token_unrevoked ON run_nomad_token (expires_at) WHERE revoked_at IS NULL;
+
+-- migrate:down
+
+DROP TABLE run_nomad_token;
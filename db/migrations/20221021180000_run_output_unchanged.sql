@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE run ADD COLUMN output_unchanged_fact_id uuid REFERENCES fact (id) ON DELETE SET NULL;
+
+-- migrate:down
+
+ALTER TABLE run DROP COLUMN output_unchanged_fact_id;
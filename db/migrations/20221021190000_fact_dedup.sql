@@ -0,0 +1,11 @@
+-- migrate:up
+
+ALTER TABLE fact ADD COLUMN dedup_hash text;
+
+CREATE INDEX fact_dedup_hash_idx ON fact (dedup_hash, created_at) WHERE dedup_hash IS NOT NULL;
+
+-- migrate:down
+
+DROP INDEX fact_dedup_hash_idx;
+
+ALTER TABLE fact DROP COLUMN dedup_hash;
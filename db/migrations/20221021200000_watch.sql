@@ -0,0 +1,20 @@
+-- migrate:up
+
+CREATE TABLE watch (
+	id uuid PRIMARY KEY DEFAULT public.gen_random_uuid(),
+	"user" text NOT NULL,
+	run_id uuid REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	action_name text,
+	email text,
+	created_at timestamp NOT NULL DEFAULT NOW(),
+	last_notified_at timestamp,
+	CHECK ((run_id IS NULL) <> (action_name IS NULL))
+);
+
+CREATE UNIQUE INDEX watch_user_run_id_idx ON watch ("user", run_id) WHERE run_id IS NOT NULL;
+
+CREATE UNIQUE INDEX watch_user_action_name_idx ON watch ("user", action_name) WHERE action_name IS NOT NULL;
+
+-- migrate:down
+
+DROP TABLE watch;
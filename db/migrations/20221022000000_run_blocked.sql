@@ -0,0 +1,11 @@
+-- migrate:up
+
+ALTER TABLE run
+ADD blocked_since timestamp,
+ADD blocked_reasons text[] NOT NULL DEFAULT '{}';
+
+-- migrate:down
+
+ALTER TABLE run
+DROP blocked_since,
+DROP blocked_reasons;
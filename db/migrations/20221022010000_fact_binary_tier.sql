@@ -0,0 +1,34 @@
+-- migrate:up
+
+-- Warm binaries are compressed with gzip,
+-- cold ones are moved out of the database.
+ALTER TABLE fact_binary
+ALTER "binary" DROP NOT NULL,
+ADD tier text NOT NULL DEFAULT 'hot' CHECK (tier IN ('hot', 'warm', 'cold')),
+ADD CHECK (("binary" IS NULL) = (tier = 'cold'));
+
+DROP VIEW api.artifact;
+CREATE VIEW api.artifact AS
+SELECT fact.*, fact_binary."binary", fact_binary.tier AS binary_tier
+FROM fact
+JOIN fact_binary ON fact_binary.hash = fact.binary_hash;
+
+-- migrate:down
+
+DO $$
+BEGIN
+	IF EXISTS (SELECT FROM fact_binary WHERE tier <> 'hot') THEN
+		RAISE EXCEPTION 'Move all fact binaries to the hot tier first';
+	END IF;
+END
+$$;
+
+DROP VIEW api.artifact;
+CREATE VIEW api.artifact AS
+SELECT fact.*, fact_binary."binary"
+FROM fact
+JOIN fact_binary ON fact_binary.hash = fact.binary_hash;
+
+ALTER TABLE fact_binary
+DROP tier,
+ALTER "binary" SET NOT NULL;
@@ -0,0 +1,8 @@
+-- migrate:up
+
+-- Null for entries recorded before it was known.
+ALTER TABLE audit_log ADD client_ip text;
+
+-- migrate:down
+
+ALTER TABLE audit_log DROP client_ip;
@@ -0,0 +1,23 @@
+-- migrate:up
+
+-- Events are read by handlers in the order they were stored.
+-- Existing events are numbered in no particular order.
+ALTER TABLE nomad_event
+	ADD id bigserial,
+	ADD created_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP();
+CREATE UNIQUE INDEX nomad_event_id_idx ON nomad_event (id);
+
+-- The last event each NomadEventHandler has handled.
+CREATE TABLE nomad_event_checkpoint (
+	handler text PRIMARY KEY,
+	event_id bigint NOT NULL,
+	updated_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP()
+);
+
+-- migrate:down
+
+DROP TABLE nomad_event_checkpoint;
+
+ALTER TABLE nomad_event
+	DROP id,
+	DROP created_at;
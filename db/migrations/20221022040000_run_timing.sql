@@ -0,0 +1,15 @@
+-- migrate:up
+
+-- Phases that a Run never reached are null.
+CREATE TABLE run_timing (
+	run_id uuid PRIMARY KEY REFERENCES run (nomad_job_id) ON DELETE CASCADE,
+	queued_seconds double precision NOT NULL,
+	pending_seconds double precision,
+	executing_seconds double precision,
+	finalizing_seconds double precision,
+	measured_at timestamp NOT NULL DEFAULT STATEMENT_TIMESTAMP()
+);
+
+-- migrate:down
+
+DROP TABLE run_timing;
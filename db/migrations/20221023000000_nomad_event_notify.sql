@@ -0,0 +1,20 @@
+-- migrate:up
+
+-- Tells NomadEventHandlerRunners that events were saved
+-- so that they do not wait for the interval.
+CREATE FUNCTION notify_nomad_event()
+RETURNS trigger
+LANGUAGE plpgsql AS $$
+	BEGIN
+		PERFORM pg_notify('cicero_nomad_event', '');
+		RETURN NULL;
+	END;
+$$;
+
+CREATE TRIGGER notify_nomad_event AFTER INSERT ON nomad_event
+FOR EACH STATEMENT EXECUTE FUNCTION notify_nomad_event();
+
+-- migrate:down
+
+DROP TRIGGER notify_nomad_event ON nomad_event;
+DROP FUNCTION notify_nomad_event;
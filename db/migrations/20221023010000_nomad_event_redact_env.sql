@@ -0,0 +1,44 @@
+-- migrate:up
+
+-- Events saved before Cicero redacted the environment of tasks
+-- may contain unsealed values and tokens in plaintext.
+CREATE FUNCTION redact_task_env(job jsonb)
+RETURNS jsonb
+LANGUAGE sql IMMUTABLE AS $$
+	SELECT CASE WHEN jsonb_typeof(job->'TaskGroups') = 'array' THEN
+		jsonb_set(job, '{TaskGroups}', (
+			SELECT COALESCE(jsonb_agg(
+				CASE WHEN jsonb_typeof(task_group->'Tasks') = 'array' THEN
+					jsonb_set(task_group, '{Tasks}', (
+						SELECT COALESCE(jsonb_agg(
+							CASE WHEN jsonb_typeof(task->'Env') = 'object' THEN
+								jsonb_set(task, '{Env}', (
+									SELECT COALESCE(jsonb_object_agg(key, '"[REDACTED]"'::jsonb), '{}'::jsonb)
+									FROM jsonb_object_keys(task->'Env') AS key
+								))
+							ELSE task END
+							ORDER BY task_index
+						), '[]'::jsonb)
+						FROM jsonb_array_elements(task_group->'Tasks') WITH ORDINALITY AS tasks(task, task_index)
+					))
+				ELSE task_group END
+				ORDER BY task_group_index
+			), '[]'::jsonb)
+			FROM jsonb_array_elements(job->'TaskGroups') WITH ORDINALITY AS task_groups(task_group, task_group_index)
+		))
+	ELSE job END
+$$;
+
+UPDATE nomad_event
+SET payload = jsonb_set(payload, '{Job}', redact_task_env(payload->'Job'))
+WHERE jsonb_typeof(payload->'Job') = 'object';
+
+UPDATE nomad_event
+SET payload = jsonb_set(payload, '{Allocation,Job}', redact_task_env(payload->'Allocation'->'Job'))
+WHERE jsonb_typeof(payload->'Allocation'->'Job') = 'object';
+
+DROP FUNCTION redact_task_env;
+
+-- migrate:down
+
+-- The redacted values cannot be restored.
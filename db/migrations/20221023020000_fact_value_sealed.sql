@@ -0,0 +1,10 @@
+-- migrate:up
+
+-- The original value of a fact whose value is stored with
+-- the parts that fact redactions hide redacted,
+-- encrypted against Cicero's seal key.
+ALTER TABLE fact ADD value_sealed bytea;
+
+-- migrate:down
+
+ALTER TABLE fact DROP value_sealed;
@@ -17,15 +17,17 @@ var buildCommit = "dirty"
 
 func main() {
 	args := &CLI{}
-	parser, err := parseArgs(args)
+	parser, err := parseArgs(args, os.Args[1:])
 	abort(parser, err)
 
-	level, err := zerolog.ParseLevel(args.LogLevel)
+	logger, logLevels, err := args.configureLogger()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	logger := config.ConfigureLogger(level)
+	if args.Start != nil {
+		args.Start.LogLevels = logLevels
+	}
 
 	domain.Build.Version = buildVersion
 	domain.Build.Commit = buildCommit
@@ -34,8 +36,72 @@ func main() {
 }
 
 type CLI struct {
-	LogLevel string           `arg:"--log-level" default:"info"`
-	Start    *cicero.StartCmd `arg:"subcommand:start"`
+	ConfigFile string `arg:"--config-file,env:CICERO_CONFIG_FILE" help:"JSON file with options that are overridden by environment variables and flags"`
+
+	LogLevel           string   `arg:"--log-level,env:CICERO_LOG_LEVEL" default:"info"`
+	LogComponentLevels []string `arg:"--log-component-level,env:CICERO_LOG_COMPONENT_LEVELS" help:"log levels of components as component=level, like Web=debug"`
+	LogFormat          string   `arg:"--log-format,env:CICERO_LOG_FORMAT" default:"json" help:"json or console"`
+	LogFile            string   `arg:"--log-file,env:CICERO_LOG_FILE" help:"also log to this file, rotating it"`
+	LogFileMaxSize     int      `arg:"--log-file-max-size,env:CICERO_LOG_FILE_MAX_SIZE" default:"10" help:"size in MB at which the log file is rotated"`
+	LogFileMaxBackups  int      `arg:"--log-file-max-backups,env:CICERO_LOG_FILE_MAX_BACKUPS" default:"10" help:"how many rotated log files to keep"`
+	LogFileMaxAge      int      `arg:"--log-file-max-age,env:CICERO_LOG_FILE_MAX_AGE" default:"10" help:"how many days to keep rotated log files"`
+
+	Start       *cicero.StartCmd       `arg:"subcommand:start"`
+	Runs        *cicero.RunsCmd        `arg:"subcommand:runs"`
+	Actions     *cicero.ActionsCmd     `arg:"subcommand:actions"`
+	Token       *cicero.TokenCmd       `arg:"subcommand:token"`
+	Quota       *cicero.QuotaCmd       `arg:"subcommand:quota"`
+	Facts       *cicero.FactsCmd       `arg:"subcommand:facts"`
+	Seal        *cicero.SealCmd        `arg:"subcommand:seal"`
+	Templates   *cicero.TemplatesCmd   `arg:"subcommand:templates"`
+	Maintenance *cicero.MaintenanceCmd `arg:"subcommand:maintenance"`
+	Config      *cicero.ConfigCmd      `arg:"subcommand:config"`
+}
+
+func (self CLI) Validate() error {
+	if _, err := zerolog.ParseLevel(self.LogLevel); err != nil {
+		return config.KeyError{Key: "log-level", Err: err}
+	}
+	if _, err := config.ParseComponentLevels(self.LogComponentLevels); err != nil {
+		return config.KeyError{Key: "log-component-level", Err: err}
+	}
+	switch self.LogFormat {
+	case "json", "console":
+	default:
+		return config.KeyError{Key: "log-format", Err: fmt.Errorf("unknown log format %q", self.LogFormat)}
+	}
+	return nil
+}
+
+func (self CLI) configureLogger() (*zerolog.Logger, *config.LogLevels, error) {
+	level, err := zerolog.ParseLevel(self.LogLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	componentLevels, err := config.ParseComponentLevels(self.LogComponentLevels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var console bool
+	switch self.LogFormat {
+	case "json":
+	case "console":
+		console = true
+	default:
+		return nil, nil, fmt.Errorf("Unknown log format: %s", self.LogFormat)
+	}
+
+	return config.ConfigureLogger(config.LoggerConfig{
+		Level:                 level,
+		ComponentLevels:       componentLevels,
+		ConsoleLoggingEnabled: console,
+		File:                  self.LogFile,
+		MaxSize:               self.LogFileMaxSize,
+		MaxBackups:            self.LogFileMaxBackups,
+		MaxAge:                self.LogFileMaxAge,
+	})
 }
 
 func Version() string {
@@ -62,13 +128,21 @@ func abort(parser *arg.Parser, err error) {
 	}
 }
 
-func parseArgs(args *CLI) (parser *arg.Parser, err error) {
+func parseArgs(args *CLI, osArgs []string) (parser *arg.Parser, err error) {
 	parser, err = arg.NewParser(arg.Config{}, args)
 	if err != nil {
 		return
 	}
 
-	err = parser.Parse(os.Args[1:])
+	if err = parser.Parse(osArgs); err != nil {
+		return
+	}
+
+	if err = config.ApplyFile(args, osArgs, args.ConfigFile); err != nil {
+		return
+	}
+
+	err = config.Validate(args)
 	return
 }
 
@@ -76,6 +150,30 @@ func Run(parser *arg.Parser, args *CLI, logger *zerolog.Logger) error {
 	switch {
 	case args.Start != nil:
 		return args.Start.Run(logger)
+	case args.Runs != nil:
+		return args.Runs.Run(logger)
+	case args.Actions != nil:
+		return args.Actions.Run(logger)
+	case args.Token != nil:
+		return args.Token.Run(logger)
+	case args.Quota != nil:
+		return args.Quota.Run(logger)
+	case args.Facts != nil:
+		return args.Facts.Run(logger)
+	case args.Seal != nil:
+		return args.Seal.Run(logger)
+	case args.Templates != nil:
+		return args.Templates.Run(logger)
+	case args.Maintenance != nil:
+		return args.Maintenance.Run(logger)
+	case args.Config != nil:
+		return args.Config.Run(func(osArgs []string) (interface{}, error) {
+			// The configuration file of this invocation
+			// also applies to the command to print unless it has its own.
+			cli := &CLI{ConfigFile: args.ConfigFile}
+			_, err := parseArgs(cli, osArgs)
+			return cli, err
+		})
 	default:
 		parser.WriteHelp(os.Stderr)
 	}
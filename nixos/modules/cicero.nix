@@ -47,14 +47,7 @@ in {
         ConfigurationDirectory = "cicero";
       };
 
-      path = [cfg.package pkgs.dbmate];
-
-      preStart = ''
-        dbmate \
-          --no-dump-schema \
-          --migrations-dir ${../../db/migrations} \
-          up
-      '';
+      path = [cfg.package];
 
       script = ''
         argsFile="$CONFIGURATION_DIRECTORY"/start.args
@@ -66,7 +59,11 @@ in {
       '';
       scriptArgs = cfg.args;
 
-      environment.DATABASE_URL = cfg.postgres.url;
+      environment = {
+        DATABASE_URL = cfg.postgres.url;
+        # The migrations are built into Cicero.
+        CICERO_MIGRATE = "true";
+      };
     };
 
     users = {
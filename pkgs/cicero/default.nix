@@ -22,11 +22,15 @@
         ../../go.mod
         ../../go.sum
         ../../main.go
+        ../../db
         ../../src
       ];
 
       nativeBuildInputs = [go-mockery];
 
+      # Static and free of the host's libc so that it runs anywhere.
+      CGO_ENABLED = 0;
+
       preBuild = ''
         go generate ./...
       '';
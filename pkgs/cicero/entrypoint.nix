@@ -12,6 +12,7 @@
   dbmate,
   vault-bin,
   netcat,
+  open-policy-agent,
   ...
 }:
 writeShellApplication {
@@ -27,6 +28,7 @@ writeShellApplication {
     dbmate
     vault-bin
     netcat
+    open-policy-agent
   ];
 
   text = ''
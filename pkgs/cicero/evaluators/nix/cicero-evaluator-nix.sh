@@ -8,7 +8,7 @@ export NIX_CONFIG="${NIX_CONFIG:-}"$'\n'"extra-experimental-features = nix-comma
 
 function usage {
 	{
-		echo "Usage: $(basename "$0") [list] [eval <attrs...>]"
+		echo "Usage: $(basename "$0") [list] [eval <attrs...>] [serve]"
 		echo
 		echo 'For eval, the following env vars must be set:'
 		echo -e '\t- CICERO_ACTION_NAME'
@@ -20,6 +20,9 @@ function usage {
 		echo -e '\t- CICERO_EVALUATOR_NIX_OCI_REGISTRY'
 		echo -e '\t- CICERO_EVALUATOR_NIX_BINARY_CACHE'
 		echo
+		echo 'For serve, requests are read from stdin'
+		echo 'with the same env vars as JSON lines.'
+		echo
 		echo 'The following env vars are optional:'
 		echo -e '\t- CICERO_EVALUATOR_NIX_EXTRA_ARGS'
 		echo -e '\t- CICERO_EVALUATOR_NIX_VERBOSE'
@@ -117,98 +120,138 @@ function prepare {
 	done
 }
 
-case "${1:-}" in
-list)
-	shift
-	msg result result="$(evaluate --apply __attrNames)"
-	;;
-eval)
-	shift
+function main {
+	case "${1:-}" in
+	list)
+		shift
+		msg result result="$(evaluate --apply __attrNames)"
+		;;
+	eval)
+		shift
+
+		echo >&2 'Evaluating variables…'
+		vars=$(
+			nix-instantiate --eval --strict \
+				--expr '{...} @ vars: {
+				  args = __mapAttrs (k: v: if v == "" then null else v) {
+				    inherit (vars) id ociRegistry;
+				  } // {
+				    inputs = __fromJSON vars.inputs;
+				  };
+
+				  inherit (vars) name system;
+				  attrs =
+				    let requestedAttrs = __filter __isString (__split "[[:space:]]" vars.attrs); in
+				    requestedAttrs ++
+				    # Add "prepare" to the list of attributes to evaluate
+				    # if "job" is present so that the preparation hooks can run.
+				    (if __elem "job" requestedAttrs then [ "prepare" ] else []);
+				}' \
+				--argstr name "${CICERO_ACTION_NAME:-}" \
+				--argstr id "${CICERO_ACTION_ID:-}" \
+				--argstr inputs "${CICERO_ACTION_INPUTS:-null}" \
+				--argstr ociRegistry "${CICERO_EVALUATOR_NIX_OCI_REGISTRY:-}" \
+				--argstr system "$system" \
+				--argstr attrs "${*}"
+		)
+
+		result=$(evaluate --apply "$(
+			cat <<-EOF
+				with $vars;
+
+				let
+				  # in a separate let-block so it cannot access all the stuff below
+				  allArgs = args // ${CICERO_EVALUATOR_NIX_EXTRA_ARGS:-"{}"};
+				in
+
+				actions:
+
+				let
+				  mapAttrs' = fn: attrs: __listToAttrs (
+				    __filter
+				      (kv: kv.name != null)
+				      (map
+				        (name: fn name attrs.\${name})
+				        (__attrNames attrs)
+				      )
+				  );
+
+				  actionFn = actions.\${name};
+				  actionFnArgs =
+				    # If the action takes named args
+				    # provide only those requested (like callPackage).
+				    # If it does not simply provide everything.
+				    let fnArgs = __functionArgs actionFn; in
+				    if fnArgs == {} then allArgs else mapAttrs' (k: v: {
+				      name = if allArgs.\${k} or null == null then null else k;
+				      value = allArgs.\${k} or null;
+				    }) fnArgs;
+				  action = actionFn actionFnArgs;
+				in
+
+				mapAttrs' (k: value: {
+				  name = if __elem k attrs then k else null;
+				  inherit value;
+				}) action
+			EOF
+		)")
+
+		msg result result="$result"
+
+		echo -n "$result" | jq -c '.prepare[]?' | prepare
+		;;
+	serve)
+		serve
+		;;
+	*)
+		if [[ -n "${1:-}" ]]; then
+			error="Unknown command: $1"
+		else
+			error='No command given'
+		fi
+
+		msg error error="$error"
+
+		echo >&2 "$error"
+		echo >&2
+		usage
+
+		exit 1
+		;;
+	esac
+}
+
+# Runs evaluations for requests like
+# `{"dir": "…", "args": ["eval", "job"], "env": ["CICERO_ACTION_NAME=…"]}`
+# read line by line from stdin without starting up again for each.
+function serve {
+	local stderrFile
+	stderrFile=$(mktemp)
+	# shellcheck disable=SC2064
+	trap "rm -f '$stderrFile'" EXIT
+
+	local request dir status
+	local -a args env
+	while IFS= read -r request; do
+		dir=$(<<<"$request" jq -r .dir)
+		readarray -t args < <(<<<"$request" jq -r '.args[]')
+		readarray -t env < <(<<<"$request" jq -r '.env[]?')
+
+		set +e
+		(
+			set -e
+			cd "$dir"
+			if [[ ${#env[@]} -gt 0 ]]; then
+				export "${env[@]}"
+			fi
+			main "${args[@]}"
+		) 2>"$stderrFile"
+		status=$?
+		set -e
+
+		jq -R -c '{event: "stderr", line: .}' <"$stderrFile"
+		msg done status="$status"
+	done
+}
 
-	echo >&2 'Evaluating variables…'
-	vars=$(
-		nix-instantiate --eval --strict \
-			--expr '{...} @ vars: {
-			  args = __mapAttrs (k: v: if v == "" then null else v) {
-			    inherit (vars) id ociRegistry;
-			  } // {
-			    inputs = __fromJSON vars.inputs;
-			  };
-
-			  inherit (vars) name system;
-			  attrs =
-			    let requestedAttrs = __filter __isString (__split "[[:space:]]" vars.attrs); in
-			    requestedAttrs ++
-			    # Add "prepare" to the list of attributes to evaluate
-			    # if "job" is present so that the preparation hooks can run.
-			    (if __elem "job" requestedAttrs then [ "prepare" ] else []);
-			}' \
-			--argstr name "${CICERO_ACTION_NAME:-}" \
-			--argstr id "${CICERO_ACTION_ID:-}" \
-			--argstr inputs "${CICERO_ACTION_INPUTS:-null}" \
-			--argstr ociRegistry "${CICERO_EVALUATOR_NIX_OCI_REGISTRY:-}" \
-			--argstr system "$system" \
-			--argstr attrs "${*}"
-	)
-
-	result=$(evaluate --apply "$(
-		cat <<-EOF
-			with $vars;
-
-			let
-			  # in a separate let-block so it cannot access all the stuff below
-			  allArgs = args // ${CICERO_EVALUATOR_NIX_EXTRA_ARGS:-"{}"};
-			in
-
-			actions:
-
-			let
-			  mapAttrs' = fn: attrs: __listToAttrs (
-			    __filter
-			      (kv: kv.name != null)
-			      (map
-			        (name: fn name attrs.\${name})
-			        (__attrNames attrs)
-			      )
-			  );
-
-			  actionFn = actions.\${name};
-			  actionFnArgs =
-			    # If the action takes named args
-			    # provide only those requested (like callPackage).
-			    # If it does not simply provide everything.
-			    let fnArgs = __functionArgs actionFn; in
-			    if fnArgs == {} then allArgs else mapAttrs' (k: v: {
-			      name = if allArgs.\${k} or null == null then null else k;
-			      value = allArgs.\${k} or null;
-			    }) fnArgs;
-			  action = actionFn actionFnArgs;
-			in
-
-			mapAttrs' (k: value: {
-			  name = if __elem k attrs then k else null;
-			  inherit value;
-			}) action
-		EOF
-	)")
-
-	msg result result="$result"
-
-	echo -n "$result" | jq -c '.prepare[]?' | prepare
-	;;
-*)
-	if [[ -n "${1:-}" ]]; then
-		error="Unknown command: $1"
-	else
-		error='No command given'
-	fi
-
-	msg error error="$error"
-
-	echo >&2 "$error"
-	echo >&2
-	usage
-
-	exit 1
-	;;
-esac
+main "$@"
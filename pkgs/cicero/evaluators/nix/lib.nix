@@ -236,7 +236,7 @@ in rec {
               < $base/fact \
               jq --compact-output --join-output \
               | "''${concat[@]}" \
-              | curl "$CICERO_API_URL"/api/run/"$NOMAD_JOB_ID"/fact \
+              | curl "$CICERO_API_URL"/api/v1/run/"$NOMAD_JOB_ID"/fact \
                 --output /dev/null --fail \
                 --no-progress-meter \
                 --data-binary @-
@@ -0,0 +1,247 @@
+package cicero
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type ActionsCmd struct {
+	Export *ActionsExportCmd `arg:"subcommand:export" help:"write all actions with their versions to a directory or tarball"`
+	Import *ActionsImportCmd `arg:"subcommand:import" help:"create, update, and (de)activate actions to match an export"`
+}
+
+func (cmd *ActionsCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.Export != nil:
+		return cmd.Export.Run(logger)
+	case cmd.Import != nil:
+		return cmd.Import.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+// An export is a directory or tarball with a JSON file per action
+// named after the action so that it can be kept in version control.
+const actionExportExt = ".json"
+
+type ActionsExportCmd struct {
+	Path string `arg:"positional,required" help:"directory with a JSON file per action, or a tarball of them if it ends with .tar, .tar.gz, or .tgz; - for a tarball on stdin or stdout"`
+
+	ApiFlags
+}
+
+func (cmd *ActionsExportCmd) Run(logger *zerolog.Logger) error {
+	exports := []domain.ActionExport{}
+	if err := cmd.request(http.MethodGet, "/api/v1/action/export", nil, &exports); err != nil {
+		return errors.WithMessage(err, "Could not export actions")
+	}
+
+	if err := writeActionExports(cmd.Path, exports); err != nil {
+		return err
+	}
+
+	logger.Info().Int("actions", len(exports)).Msg("Exported actions")
+	return nil
+}
+
+type ActionsImportCmd struct {
+	Path   string `arg:"positional,required" help:"directory with a JSON file per action, or a tarball of them if it ends with .tar, .tar.gz, or .tgz; - for a tarball on stdin or stdout"`
+	DryRun bool   `arg:"--dry-run" help:"only show what would change"`
+	Prune  bool   `arg:"--prune" help:"deactivate actions that are not in the export"`
+
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *ActionsImportCmd) Run(logger *zerolog.Logger) error {
+	exports, err := readActionExports(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("dry-run", strconv.FormatBool(cmd.DryRun))
+	query.Set("prune", strconv.FormatBool(cmd.Prune))
+
+	changes := []domain.ActionImportChange{}
+	if err := cmd.request(http.MethodPost, "/api/v1/action/import?"+query.Encode(), exports, &changes); err != nil {
+		return errors.WithMessage(err, "Could not import actions")
+	}
+
+	return cmd.print(changes, outputFormatTable, func() outputTable {
+		table := outputTable{Header: []string{"name", "change", "source", "previous source", "active"}}
+		for _, change := range changes {
+			previous := "-"
+			if change.PreviousSource != nil {
+				previous = *change.PreviousSource
+			}
+			table.add(change.Name, string(change.Kind), change.Source, previous, strconv.FormatBool(change.Active))
+		}
+		return table
+	})
+}
+
+func isActionExportTarball(exportPath string) bool {
+	return exportPath == "-" ||
+		strings.HasSuffix(exportPath, ".tar") ||
+		isActionExportGzip(exportPath)
+}
+
+func isActionExportGzip(exportPath string) bool {
+	return strings.HasSuffix(exportPath, ".tar.gz") || strings.HasSuffix(exportPath, ".tgz")
+}
+
+// Action names may contain slashes so they are escaped.
+func actionExportFileName(name string) string {
+	return url.PathEscape(name) + actionExportExt
+}
+
+func writeActionExports(exportPath string, exports []domain.ActionExport) error {
+	files := make(map[string][]byte, len(exports))
+	for _, export := range exports {
+		exportJson, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return errors.WithMessagef(err, "Could not marshal action %q", export.Name)
+		}
+		files[actionExportFileName(export.Name)] = append(exportJson, '\n')
+	}
+
+	if !isActionExportTarball(exportPath) {
+		if err := os.MkdirAll(exportPath, 0o755); err != nil {
+			return errors.WithMessagef(err, "Could not create %q", exportPath)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(exportPath, name), content, 0o644); err != nil {
+				return errors.WithMessagef(err, "Could not write %q", name)
+			}
+		}
+		return nil
+	}
+
+	output := io.WriteCloser(os.Stdout)
+	if exportPath != "-" {
+		file, err := os.Create(exportPath)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not create %q", exportPath)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	if isActionExportGzip(exportPath) {
+		output = gzip.NewWriter(output)
+	}
+
+	tarball := tar.NewWriter(output)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		if err := writeFactBundleEntry(tarball, name, now, int64(len(files[name])), bytes.NewReader(files[name])); err != nil {
+			return err
+		}
+	}
+
+	if err := tarball.Close(); err != nil {
+		return errors.WithMessage(err, "Could not finish tarball")
+	}
+	if output != os.Stdout {
+		if err := output.Close(); err != nil {
+			return errors.WithMessagef(err, "Could not write %q", exportPath)
+		}
+	}
+	return nil
+}
+
+func readActionExports(exportPath string) ([]domain.ActionExport, error) {
+	exports := []domain.ActionExport{}
+
+	decode := func(name string, content io.Reader) error {
+		var export domain.ActionExport
+		if err := json.NewDecoder(content).Decode(&export); err != nil {
+			return errors.WithMessagef(err, "Could not decode %q", name)
+		}
+		exports = append(exports, export)
+		return nil
+	}
+
+	if !isActionExportTarball(exportPath) {
+		entries, err := os.ReadDir(exportPath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not read %q", exportPath)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != actionExportExt {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(exportPath, entry.Name()))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "Could not read %q", entry.Name())
+			}
+			if err := decode(entry.Name(), bytes.NewReader(content)); err != nil {
+				return nil, err
+			}
+		}
+		return exports, nil
+	}
+
+	input := io.Reader(os.Stdin)
+	if exportPath != "-" {
+		file, err := os.Open(exportPath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not open %q", exportPath)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	if isActionExportGzip(exportPath) {
+		reader, err := gzip.NewReader(input)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not decompress %q", exportPath)
+		}
+		defer reader.Close()
+		input = reader
+	}
+
+	tarball := tar.NewReader(input)
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithMessage(err, "Could not read tarball")
+		}
+
+		if header.Typeflag != tar.TypeReg || path.Ext(header.Name) != actionExportExt {
+			continue
+		}
+		if err := decode(header.Name, tarball); err != nil {
+			return nil, err
+		}
+	}
+
+	return exports, nil
+}
@@ -0,0 +1,62 @@
+package cicero
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/client"
+)
+
+// Flags of subcommands that talk to the API of a running Cicero.
+type ApiFlags struct {
+	Url   string `arg:"--url,env:CICERO_URL" default:"http://127.0.0.1:8080"`
+	User  string `arg:"--user,env:CICERO_USER" help:"user for basic authentication"`
+	Pass  string `arg:"--pass,env:CICERO_PASS" help:"password for basic authentication" secret:"true"`
+	Token string `arg:"--token,env:CICERO_TOKEN" help:"token for bearer authentication" secret:"true"`
+}
+
+// The path may have a query.
+func (self ApiFlags) url(path string) (*url.URL, error) {
+	u, err := url.Parse(self.Url)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Invalid URL")
+	}
+	path, u.RawQuery, _ = strings.Cut(path, "?")
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u, nil
+}
+
+func (self ApiFlags) header() http.Header {
+	header := http.Header{}
+	if self.Token != "" {
+		header.Set("Authorization", "Bearer "+self.Token)
+	} else if self.User != "" {
+		req := http.Request{Header: header}
+		req.SetBasicAuth(self.User, self.Pass)
+	}
+	return header
+}
+
+func (self ApiFlags) client() *client.Client {
+	c := client.New(self.Url)
+	c.Token = self.Token
+	c.User = self.User
+	c.Pass = self.Pass
+	return c
+}
+
+// Sends the body as JSON and decodes the response into result if not nil.
+func (self ApiFlags) request(method, path string, body, result interface{}) error {
+	return self.client().Request(context.Background(), method, path, body, result)
+}
+
+// Sends the body as is with the given header in addition to authentication
+// and returns the response whose body must be closed if there is no error.
+func (self ApiFlags) do(method, path string, header http.Header, body io.Reader) (*http.Response, error) {
+	return self.client().Do(context.Background(), method, path, header, body)
+}
@@ -0,0 +1,286 @@
+package application
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+)
+
+var (
+	metricCircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "circuit_breaker",
+		Name:      "state",
+		Help:      "State of the circuit breaker of a dependency: 0 closed, 1 half-open, 2 open.",
+	}, []string{"dependency"})
+
+	metricCircuitBreakerCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "circuit_breaker",
+		Name:      "calls_total",
+		Help:      "Number of calls to a dependency by result: success, failure, or rejected, and also cached for those of them answered from the cache.",
+	}, []string{"dependency", "result"})
+)
+
+type CircuitBreakerState int
+
+const (
+	CircuitClosed CircuitBreakerState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (self CircuitBreakerState) String() string {
+	switch self {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Returned instead of calling a dependency whose circuit is open.
+type CircuitOpenError struct {
+	Dependency string
+}
+
+func (self CircuitOpenError) Error() string {
+	return "Circuit breaker of " + self.Dependency + " is open"
+}
+
+// Whether the call was not made because the circuit is open.
+func IsCircuitOpen(err error) bool {
+	return errors.As(err, &CircuitOpenError{})
+}
+
+// Stops calling a dependency after it failed too many times in a row
+// so that callers fail fast instead of waiting for it.
+// After the cooldown one call is let through to probe whether it recovered.
+type CircuitBreaker struct {
+	dependency string
+	// Consecutive failures that open the circuit, 0 to never open it.
+	threshold int
+	cooldown  time.Duration
+	logger    zerolog.Logger
+
+	mutex    sync.Mutex
+	state    CircuitBreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(dependency string, threshold int, cooldown time.Duration, logger *zerolog.Logger) *CircuitBreaker {
+	metricCircuitBreakerState.WithLabelValues(dependency).Set(float64(CircuitClosed))
+	return &CircuitBreaker{
+		dependency: dependency,
+		threshold:  threshold,
+		cooldown:   cooldown,
+		logger:     logger.With().Str("component", "CircuitBreaker").Str("dependency", dependency).Logger(),
+	}
+}
+
+func (self *CircuitBreaker) State() CircuitBreakerState {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.state
+}
+
+// Returns a `CircuitOpenError` if the call must not be made.
+// Otherwise `Done()` must be called with its outcome.
+func (self *CircuitBreaker) Allow() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	switch self.state {
+	case CircuitOpen:
+		if time.Since(self.openedAt) < self.cooldown {
+			break
+		}
+		self.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if self.probing {
+			break
+		}
+		self.probing = true
+		return nil
+	default:
+		return nil
+	}
+
+	metricCircuitBreakerCalls.WithLabelValues(self.dependency, "rejected").Inc()
+	return CircuitOpenError{self.dependency}
+}
+
+func (self *CircuitBreaker) Done(failed bool) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if !failed {
+		metricCircuitBreakerCalls.WithLabelValues(self.dependency, "success").Inc()
+		self.failures = 0
+		self.probing = false
+		if self.state != CircuitClosed {
+			self.setState(CircuitClosed)
+		}
+		return
+	}
+
+	metricCircuitBreakerCalls.WithLabelValues(self.dependency, "failure").Inc()
+	self.failures++
+	if self.threshold > 0 && (self.state == CircuitHalfOpen || self.failures >= self.threshold) {
+		self.openedAt = time.Now()
+		self.probing = false
+		if self.state != CircuitOpen {
+			self.setState(CircuitOpen)
+		}
+	}
+}
+
+// Like `Done()` for calls whose outcome says nothing about the dependency,
+// like those that the caller canceled.
+func (self *CircuitBreaker) Skip() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.probing = false
+}
+
+func (self *CircuitBreaker) setState(state CircuitBreakerState) {
+	self.logger.Warn().Stringer("from", self.state).Stringer("to", state).Int("failures", self.failures).Msg("Circuit breaker changed state")
+	self.state = state
+	metricCircuitBreakerState.WithLabelValues(self.dependency).Set(float64(state))
+}
+
+// Answers GET requests from the last successful response to them
+// if the dependency fails or its circuit is open.
+type circuitBreakingApiClient struct {
+	api.Client
+	breaker *CircuitBreaker
+
+	cacheSize  int
+	cacheMutex sync.Mutex
+	// Most recently used first.
+	cacheList  *list.List
+	cacheIndex map[string]*list.Element
+}
+
+type circuitBreakingApiCacheEntry struct {
+	key      string
+	response http.Response
+	body     []byte
+}
+
+// Responses larger than this are not cached.
+const circuitBreakingApiCacheBodyLimit = 1 << 20
+
+// Wraps a client of an API like that of Prometheus or Loki.
+// Up to `cacheSize` responses are kept to fall back to.
+func NewCircuitBreakingApiClient(client api.Client, breaker *CircuitBreaker, cacheSize int) api.Client {
+	return &circuitBreakingApiClient{
+		Client:     client,
+		breaker:    breaker,
+		cacheSize:  cacheSize,
+		cacheList:  list.New(),
+		cacheIndex: map[string]*list.Element{},
+	}
+}
+
+func (self *circuitBreakingApiClient) Do(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	if err := self.breaker.Allow(); err != nil {
+		if response, body, cached := self.recall(req); cached {
+			return response, body, nil
+		}
+		return nil, nil, err
+	}
+
+	response, body, err := self.Client.Do(ctx, req)
+
+	// The caller giving up says nothing about the dependency.
+	if errors.Is(err, context.Canceled) {
+		self.breaker.Skip()
+		return response, body, err
+	}
+
+	failed := err != nil || response.StatusCode >= http.StatusInternalServerError
+	self.breaker.Done(failed)
+
+	if failed {
+		if response, body, cached := self.recall(req); cached {
+			return response, body, nil
+		}
+	} else if response.StatusCode == http.StatusOK {
+		self.remember(req, response, body)
+	}
+
+	return response, body, err
+}
+
+func (self *circuitBreakingApiClient) remember(req *http.Request, response *http.Response, body []byte) {
+	if self.cacheSize <= 0 || req.Method != http.MethodGet || len(body) > circuitBreakingApiCacheBodyLimit {
+		return
+	}
+
+	self.cacheMutex.Lock()
+	defer self.cacheMutex.Unlock()
+
+	key := req.URL.String()
+	entry := circuitBreakingApiCacheEntry{
+		key: key,
+		response: http.Response{
+			Status:     response.Status,
+			StatusCode: response.StatusCode,
+			Header:     response.Header.Clone(),
+		},
+		body: body,
+	}
+
+	if element, exists := self.cacheIndex[key]; exists {
+		element.Value = entry
+		self.cacheList.MoveToFront(element)
+		return
+	}
+
+	self.cacheIndex[key] = self.cacheList.PushFront(entry)
+	for self.cacheList.Len() > self.cacheSize {
+		oldest := self.cacheList.Back()
+		self.cacheList.Remove(oldest)
+		delete(self.cacheIndex, oldest.Value.(circuitBreakingApiCacheEntry).key)
+	}
+}
+
+func (self *circuitBreakingApiClient) recall(req *http.Request) (*http.Response, []byte, bool) {
+	if req.Method != http.MethodGet {
+		return nil, nil, false
+	}
+
+	self.cacheMutex.Lock()
+	defer self.cacheMutex.Unlock()
+
+	element, exists := self.cacheIndex[req.URL.String()]
+	if !exists {
+		return nil, nil, false
+	}
+	self.cacheList.MoveToFront(element)
+
+	metricCircuitBreakerCalls.WithLabelValues(self.breaker.dependency, "cached").Inc()
+
+	entry := element.Value.(circuitBreakingApiCacheEntry)
+	response := entry.response
+	response.Header = entry.response.Header.Clone()
+	response.Header.Set("Warning", `110 - "Response is Stale"`)
+	response.Request = req
+	return &response, entry.body, true
+}
@@ -0,0 +1,111 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	logger := zerolog.Nop()
+	breaker := NewCircuitBreaker(t.Name(), 2, 10*time.Millisecond, &logger)
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Done(true)
+	assert.Equal(t, CircuitClosed, breaker.State())
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Done(true)
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	err := breaker.Allow()
+	assert.True(t, IsCircuitOpen(err))
+	assert.True(t, IsNomadUnreachable(errors.WithMessage(err, "wrapped")))
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Only one call probes.
+	assert.NoError(t, breaker.Allow())
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+	assert.True(t, IsCircuitOpen(breaker.Allow()))
+
+	// A failed probe opens it again right away.
+	breaker.Done(true)
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Done(false)
+	assert.Equal(t, CircuitClosed, breaker.State())
+
+	// A success resets the count.
+	assert.NoError(t, breaker.Allow())
+	breaker.Done(true)
+	assert.NoError(t, breaker.Allow())
+	breaker.Done(false)
+	assert.NoError(t, breaker.Allow())
+	breaker.Done(true)
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
+
+type fakeApiClient struct {
+	status int
+	body   string
+	err    error
+}
+
+func (self *fakeApiClient) URL(ep string, _ map[string]string) *url.URL {
+	return &url.URL{Scheme: "http", Host: "loki", Path: ep}
+}
+
+func (self *fakeApiClient) Do(_ context.Context, req *http.Request) (*http.Response, []byte, error) {
+	if self.err != nil {
+		return nil, nil, self.err
+	}
+	return &http.Response{StatusCode: self.status, Header: http.Header{}, Request: req}, []byte(self.body), nil
+}
+
+func TestCircuitBreakingApiClient(t *testing.T) {
+	t.Parallel()
+
+	logger := zerolog.Nop()
+	fake := &fakeApiClient{status: http.StatusOK, body: "fresh"}
+	client := NewCircuitBreakingApiClient(fake, NewCircuitBreaker(t.Name(), 1, time.Hour, &logger), 1)
+
+	get := func(path string) (*http.Response, []byte, error) {
+		req, err := http.NewRequest(http.MethodGet, client.URL(path, nil).String(), http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.Do(context.Background(), req)
+	}
+
+	_, body, err := get("/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", string(body))
+
+	fake.status = http.StatusServiceUnavailable
+	response, body, err := get("/a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "fresh", string(body))
+		assert.NotEmpty(t, response.Header.Get("Warning"))
+	}
+
+	// Open now and nothing cached for it.
+	_, _, err = get("/b")
+	assert.True(t, IsCircuitOpen(err))
+
+	_, body, err = get("/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", string(body))
+}
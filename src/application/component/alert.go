@@ -0,0 +1,232 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Sends alerts about actions whose Runs keep failing
+// and Nomad events that wait too long to be processed to Alertmanager
+// so that they reach the same on-call pipelines as other alerts
+// and can be grouped and silenced by their labels.
+type AlertNotifier struct {
+	Logger       zerolog.Logger
+	AlertService service.AlertService
+
+	// Like "http://alertmanager:9093".
+	URL string
+	// How often to check for alerts and repeat those still firing.
+	Interval time.Duration
+
+	// Alert when this many Runs of an action failed in a row, 0 disables it.
+	FailureStreak int
+	// Alert when Nomad events waited this long, 0 disables it.
+	EventLag time.Duration
+
+	// Added to all alerts, like the environment.
+	Labels map[string]string
+	// Where the web UI is served for links to Runs.
+	BaseURL string
+
+	// The alerts sent last time by fingerprint
+	// so that those no longer firing can be resolved.
+	firing map[string]alertmanagerAlert
+}
+
+// An alert as posted to Alertmanager's v2 API.
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Identifies an alert like Alertmanager does, by its labels.
+func (self alertmanagerAlert) fingerprint() string {
+	pairs := make([]string, 0, len(self.Labels))
+	for k, v := range self.Labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// Parses labels given as name=value.
+func ParseAlertLabels(strs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, str := range strs {
+		name, value, ok := strings.Cut(str, "=")
+		if !ok || name == "" {
+			return nil, errors.Errorf("Invalid alert label %q, must be name=value", str)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+func (self *AlertNotifier) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Str("url", self.URL).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.notify(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *AlertNotifier) notify(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	firing, err := self.alerts()
+	if err != nil {
+		return err
+	}
+
+	alerts := make([]alertmanagerAlert, 0, len(firing)+len(self.firing))
+	for key, alert := range firing {
+		if previous, ok := self.firing[key]; ok && alert.StartsAt.IsZero() {
+			alert.StartsAt = previous.StartsAt
+		} else if alert.StartsAt.IsZero() {
+			alert.StartsAt = now
+		}
+		// Alertmanager resolves the alert by itself if Cicero stops repeating it.
+		alert.EndsAt = now.Add(3 * self.Interval)
+		firing[key] = alert
+		alerts = append(alerts, alert)
+	}
+	for key, alert := range self.firing {
+		if _, ok := firing[key]; !ok {
+			alert.EndsAt = now
+			alerts = append(alerts, alert)
+		}
+	}
+
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	if err := self.send(ctx, alerts); err != nil {
+		// Try again next interval, Alertmanager may be unavailable for a while.
+		// Resolved alerts stay in `self.firing` so that they are resolved then.
+		self.Logger.Err(err).Int("alerts", len(alerts)).Msg("Could not send alerts")
+		return nil
+	}
+
+	self.Logger.Debug().Int("firing", len(firing)).Int("resolved", len(alerts)-len(firing)).Msg("Sent alerts")
+	self.firing = firing
+
+	return nil
+}
+
+// Returns the alerts that are firing now by fingerprint.
+// Their start is zero if it is not known.
+func (self *AlertNotifier) alerts() (map[string]alertmanagerAlert, error) {
+	alerts := map[string]alertmanagerAlert{}
+	add := func(alert alertmanagerAlert) {
+		for k, v := range self.Labels {
+			if _, ok := alert.Labels[k]; !ok {
+				alert.Labels[k] = v
+			}
+		}
+		alerts[alert.fingerprint()] = alert
+	}
+
+	if self.FailureStreak > 0 {
+		streaks, err := self.AlertService.GetRunFailureStreaks(self.FailureStreak)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, streak := range streaks {
+			alert := alertmanagerAlert{
+				Labels: map[string]string{
+					"alertname": "CiceroRunFailureStreak",
+					"severity":  "warning",
+					"action":    streak.ActionName,
+					"project":   streak.Project,
+				},
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("The last %d Runs of action %q failed", streak.Failures, streak.ActionName),
+					"description": fmt.Sprintf("Runs of action %q have failed since %s without a successful one in between.", streak.ActionName, streak.Since.Format(time.RFC3339)),
+				},
+				StartsAt:     streak.Since,
+				GeneratorURL: fmt.Sprintf("%s/run/%s", strings.TrimSuffix(self.BaseURL, "/"), streak.LastRunId),
+			}
+			// So that Alertmanager can route the alert to the action's owner.
+			if streak.Team != "" {
+				alert.Labels["team"] = streak.Team
+			}
+			if streak.Contact != "" {
+				alert.Annotations["contact"] = streak.Contact
+			}
+			if streak.Escalation != "" {
+				alert.Annotations["escalation"] = streak.Escalation
+			}
+			add(alert)
+		}
+	}
+
+	if lag := getNomadEventLag(); self.EventLag > 0 && lag >= self.EventLag {
+		add(alertmanagerAlert{
+			Labels: map[string]string{
+				"alertname": "CiceroNomadEventLag",
+				"severity":  "warning",
+			},
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("Nomad events wait %s to be processed", lag.Round(time.Second)),
+				"description": "Runs do not reflect the state of their Nomad jobs until the events are processed.",
+			},
+		})
+	}
+
+	return alerts, nil
+}
+
+func (self *AlertNotifier) send(ctx context.Context, alerts []alertmanagerAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return errors.WithMessage(err, "Could not marshal alerts")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(self.URL, "/")+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return errors.WithMessage(err, "Could not create request to Alertmanager")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.WithMessage(err, "Could not send alerts to Alertmanager")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("Alertmanager responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Emails subscribers a summary of the Runs that failed
+// since their last digest, daily or weekly.
+type DigestNotifier struct {
+	Logger        zerolog.Logger
+	DigestService service.DigestService
+
+	// How often to look for digests that are due.
+	Interval time.Duration
+
+	// Like "smtp.example.com:587".
+	SMTPAddr string
+	// Sends without authentication if empty.
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+
+	// Where the web UI is served for links to Runs.
+	BaseURL string
+}
+
+// The lines of a Run's log excerpt are shortened to this many characters.
+const digestExcerptLineLimit = 200
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"truncate": func(line string) string {
+		if runes := []rune(line); len(runes) > digestExcerptLineLimit {
+			return string(runes[:digestExcerptLineLimit]) + "…"
+		}
+		return line
+	},
+}).Parse(`{{.Digest.Runs}} Runs failed between {{time .Digest.From}} and {{time .Digest.To}}.
+{{range .Digest.Actions}}
+{{.Name}} ({{.Project}}): {{len .Runs}} failed
+{{range .Runs}}  {{time .CreatedAt}} {{$.BaseURL}}/run/{{.NomadJobID}}
+{{range .Excerpt}}    {{truncate .}}
+{{end}}{{end}}{{end}}
+You receive this {{.Digest.Subscription.Period}} digest{{with .Digest.Subscription.Project}} for {{.}}{{end}} because of subscription {{.Digest.Subscription.ID}}.
+`))
+
+func (self *DigestNotifier) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.notify(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *DigestNotifier) notify() error {
+	now := time.Now().UTC()
+
+	subscriptions, err := self.DigestService.GetDue(now)
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("subscriptions", len(subscriptions)).Msg("Sending digests")
+
+	for _, subscription := range subscriptions {
+		digest, err := self.DigestService.Build(subscription, now)
+		if err != nil {
+			return err
+		}
+
+		// Nothing to report but the next digest starts from now.
+		if digest.Runs() > 0 {
+			if err := self.send(digest); err != nil {
+				// Try again next interval, the mail server may be unavailable for a while.
+				self.Logger.Err(err).Stringer("subscription", subscription.ID).Msg("Could not send digest")
+				continue
+			}
+			self.Logger.Debug().Stringer("subscription", subscription.ID).Int("runs", digest.Runs()).Msg("Sent digest")
+		}
+
+		if err := self.DigestService.MarkSent(digest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *DigestNotifier) send(digest domain.Digest) error {
+	body := &bytes.Buffer{}
+	if err := digestTemplate.Execute(body, struct {
+		Digest  domain.Digest
+		BaseURL string
+	}{digest, strings.TrimSuffix(self.BaseURL, "/")}); err != nil {
+		return errors.WithMessage(err, "Could not render digest")
+	}
+
+	return errors.WithMessagef(
+		sendMail(
+			self.SMTPAddr, self.SMTPUser, self.SMTPPassword, self.From, digest.Subscription.Email,
+			fmt.Sprintf("Cicero: %d failed Runs of %d actions", digest.Runs(), len(digest.Actions)),
+			digest.To, body.String(),
+		),
+		"Could not send digest to %q", digest.Subscription.Email,
+	)
+}
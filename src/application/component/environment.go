@@ -0,0 +1,57 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+// Tears down environments that expired or were closed by a fact.
+type EnvironmentJanitor struct {
+	Logger             zerolog.Logger
+	EnvironmentService service.EnvironmentService
+	Db                 config.PgxIface
+
+	// How often to look for environments to tear down.
+	Interval time.Duration
+}
+
+func (self *EnvironmentJanitor) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Try again next interval, the teardown actions may not be runnable yet.
+		if err := self.invoke(self.EnvironmentService.Expire()); err != nil {
+			self.Logger.Err(err).Msg("Could not tear down expired environments")
+		}
+		if err := self.invoke(self.EnvironmentService.Close()); err != nil {
+			self.Logger.Err(err).Msg("Could not tear down closed environments")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Runs what was invoked even if a later teardown failed
+// as the earlier ones were committed already.
+func (self *EnvironmentJanitor) invoke(runFunc service.InvokeRunFunc, err error) error {
+	if runFunc != nil {
+		if _, registerFunc, err := runFunc(self.Db); err != nil {
+			return err
+		} else if err := registerFunc(); err != nil {
+			return err
+		}
+	}
+	return err
+}
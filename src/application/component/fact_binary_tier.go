@@ -0,0 +1,96 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Moves fact binaries between storage tiers
+// according to when they were last accessed.
+type FactBinaryTierer struct {
+	Logger            zerolog.Logger
+	FactBinaryService service.FactBinaryService
+	// Decides the tiers with FactBinaryTiers.
+	Runtime *config.RuntimeConfig
+
+	// Whether there is a cold storage directory.
+	// If not, binaries that would be cold stay warm.
+	Cold bool
+
+	// How often to move binaries.
+	Interval time.Duration
+}
+
+// How long files in the cold storage directory without a binary
+// are kept because they may belong to a binary that is moving there.
+const factBinaryColdPruneGrace = time.Hour
+
+func (self *FactBinaryTierer) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Bool("cold", self.Cold).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.tier(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *FactBinaryTierer) tier() error {
+	policies := self.Runtime.Get().FactBinaryTiers
+
+	binaries, err := self.FactBinaryService.GetAccesses()
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("binaries", len(binaries)).Int("policies", len(policies)).Msg("Moving binaries between tiers")
+
+	now := time.Now()
+	moved := 0
+	for _, binary := range binaries {
+		to := policies.Tier(binary, now)
+		if !self.Cold {
+			if binary.Tier == domain.FactBinaryTierCold {
+				continue
+			}
+			if to == domain.FactBinaryTierCold {
+				to = domain.FactBinaryTierWarm
+			}
+		}
+		if to == binary.Tier {
+			continue
+		}
+
+		if ok, err := self.FactBinaryService.Move(binary, to); err != nil {
+			// Try again next interval, the cold storage may be unavailable for a while.
+			self.Logger.Err(err).Str("hash", binary.Hash).Msg("Could not move binary")
+			return nil
+		} else if ok {
+			moved++
+		}
+	}
+
+	pruned, err := self.FactBinaryService.PruneCold(now.Add(-factBinaryColdPruneGrace))
+	if err != nil {
+		self.Logger.Err(err).Msg("Could not prune cold storage directory")
+	}
+
+	self.Logger.Debug().Int("moved", moved).Int("pruned", pruned).Msg("Moved binaries between tiers")
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package component
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// A subscription to a subject of a NATS server
+// that speaks the client protocol, which is simple enough
+// to not need a client library for just receiving messages:
+// https://docs.nats.io/reference/reference-protocols/nats-protocol
+// Messages are received at most once as core NATS does not acknowledge them.
+type natsSubscription struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	closed chan struct{}
+}
+
+const natsDefaultPort = "4222"
+
+// The ID of the only subscription on a connection.
+const natsSid = "1"
+
+func subscribeNats(ctx context.Context, rawUrl, subject, queue string) (*natsSubscription, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Invalid NATS URL %q", rawUrl)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), natsDefaultPort)
+	}
+
+	dialer := net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not connect to NATS server %q", addr)
+	}
+
+	self := &natsSubscription{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		closed: make(chan struct{}),
+	}
+
+	// Unblock `Next()` when the context ends.
+	// Closing the TCP connection also ends TLS on top of it.
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-self.closed:
+		}
+	}()
+
+	if err := self.handshake(u, subject, queue); err != nil {
+		self.Close()
+		return nil, err
+	}
+
+	return self, nil
+}
+
+func (self *natsSubscription) handshake(u *url.URL, subject, queue string) error {
+	// The server greets with information about itself.
+	line, err := self.readLine()
+	if err != nil {
+		return errors.WithMessage(err, "Could not read INFO from NATS server")
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return errors.Errorf("Expected INFO from NATS server but got %q", line)
+	}
+	info := struct {
+		TLSRequired bool `json:"tls_required"`
+	}{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return errors.WithMessage(err, "Could not decode INFO from NATS server")
+	}
+
+	// The connection is upgraded to TLS after the greeting.
+	if u.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(self.conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			return errors.WithMessage(err, "Could not establish TLS with NATS server")
+		}
+		self.conn = tlsConn
+		self.reader = bufio.NewReader(tlsConn)
+	}
+
+	connect := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "cicero",
+		"lang":     "go",
+		"version":  domain.Build.Version,
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			connect["user"] = u.User.Username()
+			connect["pass"] = password
+		} else {
+			connect["auth_token"] = u.User.Username()
+		}
+	}
+	connectJson, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+
+	sub := []string{"SUB", subject}
+	if queue != "" {
+		sub = append(sub, queue)
+	}
+	sub = append(sub, natsSid)
+
+	// The server answers the PING once it processed the others
+	// so an error about them, like failed authentication, comes before the PONG.
+	if err := self.write("CONNECT "+string(connectJson), strings.Join(sub, " "), "PING"); err != nil {
+		return errors.WithMessage(err, "Could not subscribe on NATS server")
+	}
+	for {
+		line, err := self.readLine()
+		if err != nil {
+			return errors.WithMessage(err, "Could not subscribe on NATS server")
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.Errorf("NATS server refused subscription: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Blocks until the next message arrives.
+func (self *natsSubscription) Next() (factSourceMessage, error) {
+	for {
+		line, err := self.readLine()
+		if err != nil {
+			return factSourceMessage{}, errors.WithMessage(err, "Could not read from NATS server")
+		}
+
+		switch {
+		case line == "PING":
+			if err := self.write("PONG"); err != nil {
+				return factSourceMessage{}, errors.WithMessage(err, "Could not answer NATS server")
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return factSourceMessage{}, errors.Errorf("NATS server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				return factSourceMessage{}, errors.Errorf("Invalid message from NATS server: %q", line)
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return factSourceMessage{}, errors.WithMessagef(err, "Invalid message size from NATS server: %q", line)
+			}
+
+			// The payload is followed by CRLF.
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(self.reader, data); err != nil {
+				return factSourceMessage{}, errors.WithMessage(err, "Could not read message from NATS server")
+			}
+
+			return factSourceMessage{Subject: fields[1], Data: data[:size]}, nil
+		default:
+			// Like PONG, +OK, or INFO with updates about the cluster.
+		}
+	}
+}
+
+func (self *natsSubscription) Close() error {
+	select {
+	case <-self.closed:
+		return nil
+	default:
+		close(self.closed)
+	}
+	return self.conn.Close()
+}
+
+func (self *natsSubscription) readLine() (string, error) {
+	line, err := self.reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (self *natsSubscription) write(lines ...string) error {
+	_, err := io.WriteString(self.conn, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}
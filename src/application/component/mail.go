@@ -0,0 +1,37 @@
+package component
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sends a plain text email, authenticating
+// to the SMTP server only if a user is given.
+func sendMail(addr, user, password, from, to, subject string, date time.Time, body string) error {
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", to)
+	fmt.Fprintf(msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if user != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return errors.WithMessagef(err, "Invalid SMTP address %q", addr)
+		}
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+
+	return smtp.SendMail(addr, auth, from, []string{to}, msg.Bytes())
+}
@@ -0,0 +1,51 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+// Keeps the events of the maintenance windows' iCalendar feeds up to date.
+type MaintenanceCalendar struct {
+	Logger             zerolog.Logger
+	MaintenanceService service.MaintenanceService
+	// Feeds are fetched right away when it is reloaded
+	// as windows may have been added.
+	Runtime *config.RuntimeConfig
+
+	// How often to fetch the feeds.
+	Interval time.Duration
+}
+
+func (self *MaintenanceCalendar) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	reloaded := make(chan struct{}, 1)
+	self.Runtime.OnReload(func(config.Runtime) {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+
+	for {
+		// Failures are logged by the service
+		// and the windows keep their events until the next try.
+		_ = self.MaintenanceService.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-reloaded:
+		}
+	}
+}
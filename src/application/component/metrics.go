@@ -0,0 +1,42 @@
+package component
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+// Serves Prometheus metrics on a listener separate from the web component
+// so that they are not exposed to the same audience as the API.
+type Metrics struct {
+	Listen string
+	Logger zerolog.Logger
+}
+
+func (self *Metrics) Start(ctx context.Context) error {
+	self.Logger.Info().Str("listen", self.Listen).Msg("Starting")
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: self.Listen, Handler: mux}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			self.Logger.Err(err).Msgf("Failed to start metrics server on %s", self.Listen)
+		}
+	}()
+
+	<-ctx.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		self.Logger.Err(err).Msg("Failed to stop metrics server")
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package component
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,7 @@ import (
 	"github.com/input-output-hk/cicero/src/application/service"
 	"github.com/input-output-hk/cicero/src/config"
 	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
 )
 
 type NomadEventConsumer struct {
@@ -22,26 +24,44 @@ type NomadEventConsumer struct {
 	NomadEventService service.NomadEventService
 	RunService        service.RunService
 	InvocationService service.InvocationService
-	Db                config.PgxIface
-	NomadClient       application.NomadClient
+	ActionService     service.ActionService
+	// Creates the environments of successful Runs.
+	EnvironmentService service.EnvironmentService
+	Db                 config.PgxIface
+	// The cluster to consume events of.
+	NomadCluster application.NomadCluster
+	// All names that refer to NomadCluster.
+	NomadClusterNames []string
+	// How many received events may wait to be processed.
+	QueueSize int
+	// Which events to save and handle, others are dropped when received.
+	EventFilter domain.NomadEventFilter
+	// Consume each topic in its own stream, see `consumeShards()`.
+	ShardByTopic bool
 }
 
 func (self *NomadEventConsumer) WithQuerier(querier config.PgxIface) *NomadEventConsumer {
 	return &NomadEventConsumer{
-		Logger:            self.Logger,
-		FactService:       self.FactService.WithQuerier(querier),
-		NomadEventService: self.NomadEventService.WithQuerier(querier),
-		RunService:        self.RunService.WithQuerier(querier),
-		InvocationService: self.InvocationService.WithQuerier(querier),
-		Db:                querier,
-		NomadClient:       self.NomadClient,
+		Logger:             self.Logger,
+		FactService:        self.FactService.WithQuerier(querier),
+		NomadEventService:  self.NomadEventService.WithQuerier(querier),
+		RunService:         self.RunService.WithQuerier(querier),
+		InvocationService:  self.InvocationService.WithQuerier(querier),
+		ActionService:      self.ActionService.WithQuerier(querier),
+		EnvironmentService: self.EnvironmentService.WithQuerier(querier),
+		Db:                 querier,
+		NomadCluster:       self.NomadCluster,
+		NomadClusterNames:  self.NomadClusterNames,
+		QueueSize:          self.QueueSize,
+		EventFilter:        self.EventFilter,
+		ShardByTopic:       self.ShardByTopic,
 	}
 }
 
 func (self *NomadEventConsumer) Start(ctx context.Context) error {
 	self.Logger.Info().Msg("Starting")
 
-	if events, err := self.NomadEventService.GetByHandled(false); err != nil {
+	if events, err := self.NomadEventService.GetByHandled(false, self.NomadClusterNames); err != nil {
 		return err
 	} else {
 		self.Logger.Debug().Int("num-unhandled", len(events)).Msg("Handling unhandled events")
@@ -52,34 +72,73 @@ func (self *NomadEventConsumer) Start(ctx context.Context) error {
 		}
 	}
 
-	index, err := self.NomadEventService.GetLastNomadEventIndex()
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+	topics := self.EventFilter.Topics()
+	if _, all := topics[nomad.TopicAll]; self.ShardByTopic && !all && len(topics) > 1 {
+		return self.consumeShards(ctx, topics)
+	}
+
+	index, err := self.NomadEventService.GetLastNomadEventIndex(self.NomadClusterNames)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return errors.WithMessage(err, "Could not get last Nomad event index")
 	}
 	index += 1
 
+	return self.consume(ctx, topics, index, nil)
+}
+
+// Processes events of the topics from the index on until an error occurs
+// or so many events were already handled that it is better to start over.
+// Events of a job wait for those of other shards if the order is given.
+func (self *NomadEventConsumer) consume(ctx context.Context, topics map[nomad.Topic][]string, index uint64, order *nomadEventOrder) error {
 	self.Logger.Debug().Uint64("index", index).Msg("Listening to Nomad events")
 
-	stream, err := self.NomadClient.EventStream(ctx, index)
+	stream, err := self.NomadCluster.EventStream(ctx, index, topics)
 	if err != nil {
 		return errors.WithMessage(err, "Could not listen to Nomad events")
 	}
 
-	for events := range stream {
-		if events.Err != nil {
-			return errors.WithMessage(events.Err, "Error getting next events from Nomad event stream")
-		}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		if events.Index < index {
-			// We always get the last event even if we start at
-			// an index greater than the last so we have to ignore it.
-			// https://github.com/hashicorp/nomad/issues/11296
-			continue
+	queue := newNomadEventQueue(self.QueueSize)
+	receiveErr := make(chan error, 1)
+	go func() {
+		defer close(queue.events)
+		receiveErr <- self.receive(ctx, stream, index, queue, order)
+	}()
+
+	batchSize := adaptiveBatchSize{Min: 1, Max: 100, Target: time.Second}
+
+	var numConsecutiveAlreadyHandled uint8 = 0
+	for {
+		batch, err := queue.pop(ctx, batchSize.Get())
+		if err != nil {
+			return err
 		}
+		if batch == nil {
+			if err := <-receiveErr; err != nil {
+				return err
+			}
+			break
+		}
+
+		start := time.Now()
+		for _, event := range batch {
+			setNomadEventLag(time.Since(event.received))
+
+			if order != nil {
+				if err := order.wait(ctx, event.jobId, event.Index); err != nil {
+					return err
+				}
+			}
+
+			err := self.processNomadEvent(ctx, &domain.NomadEvent{Event: event.Event, NomadCluster: self.NomadCluster.Name})
 
-		var numConsecutiveAlreadyHandled uint8 = 0
-		for _, rawEvent := range events.Events {
-			if err := self.processNomadEvent(ctx, &domain.NomadEvent{Event: rawEvent}); err != nil {
+			if order != nil {
+				order.done(event.jobId, event.Index)
+			}
+
+			if err != nil {
 				if errors.Is(err, errAlreadyHandled) {
 					numConsecutiveAlreadyHandled++
 					if numConsecutiveAlreadyHandled == 25 {
@@ -97,13 +156,116 @@ func (self *NomadEventConsumer) Start(ctx context.Context) error {
 				numConsecutiveAlreadyHandled = 0
 			}
 		}
+		pause := batchSize.Took(time.Since(start))
 
-		index = events.Index
+		if len(queue.events) == 0 {
+			setNomadEventLag(0)
+			self.refetchDropped(ctx, queue)
+		}
+
+		if pause > 0 {
+			self.Logger.Debug().Dur("pause", pause).Int("batch-size", batchSize.Get()).Msg("Slowing down")
+			select {
+			case <-time.After(pause):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
 	}
 
 	return errors.New("Nomad event service finished")
 }
 
+// Puts events from the stream into the queue until the stream ends.
+func (self *NomadEventConsumer) receive(ctx context.Context, stream <-chan *nomad.Events, index uint64, queue *nomadEventQueue, order *nomadEventOrder) error {
+	for events := range stream {
+		if events.Err != nil {
+			return errors.WithMessage(events.Err, "Error getting next events from Nomad event stream")
+		}
+
+		if events.Index < index {
+			// We always get the last event even if we start at
+			// an index greater than the last so we have to ignore it.
+			// https://github.com/hashicorp/nomad/issues/11296
+			continue
+		}
+
+		for _, event := range events.Events {
+			if !self.EventFilter.Match(event.Topic, event.Type) {
+				metricNomadEventFiltered.WithLabelValues(string(event.Topic)).Inc()
+				continue
+			}
+
+			var jobId string
+			if order != nil {
+				jobId = nomadEventJobId(&event)
+				order.add(jobId, event.Index)
+			}
+
+			if queued, err := queue.push(ctx, event, jobId); err != nil {
+				return err
+			} else if !queued && order != nil {
+				order.done(jobId, event.Index)
+			}
+		}
+
+		index = events.Index
+	}
+
+	return nil
+}
+
+// Saves the latest state of allocations whose events were dropped
+// so that it is not missing from the Runs' history.
+// The events are flagged as handled because the handlers
+// would have ignored the dropped events anyway.
+func (self *NomadEventConsumer) refetchDropped(ctx context.Context, queue *nomadEventQueue) {
+	for allocId, index := range queue.takeRefetch() {
+		logger := self.Logger.With().Str("allocation", allocId).Logger()
+
+		allocation, _, err := self.NomadCluster.AllocationsInfo(allocId, &nomad.QueryOptions{})
+		if err != nil {
+			if !application.IsNomadNotFound(err) {
+				logger.Err(err).Msg("Could not refetch allocation of dropped events")
+			}
+			continue
+		}
+
+		var payload map[string]interface{}
+		if allocationJson, err := json.Marshal(map[string]interface{}{"Allocation": allocation}); err != nil {
+			logger.Err(err).Msg("Could not marshal refetched allocation")
+			continue
+		} else if err := json.Unmarshal(allocationJson, &payload); err != nil {
+			logger.Err(err).Msg("Could not unmarshal refetched allocation")
+			continue
+		}
+
+		event := domain.NomadEvent{Event: nomad.Event{
+			Topic:   nomad.TopicAllocation,
+			Type:    "AllocationUpdated",
+			Key:     allocId,
+			Index:   index,
+			Payload: payload,
+		}, NomadCluster: self.NomadCluster.Name}
+
+		if err := self.Db.BeginFunc(ctx, func(tx pgx.Tx) error {
+			txSelf := self.WithQuerier(tx)
+
+			if err := txSelf.NomadEventService.Save(&event); err != nil {
+				return err
+			}
+
+			event.Handled = true
+			return txSelf.NomadEventService.Update(&event)
+		}); err != nil {
+			logger.Err(err).Msg("Could not save refetched allocation")
+			continue
+		}
+
+		logger.Trace().Uint64("index", index).Msg("Saved refetched allocation")
+	}
+}
+
 var errAlreadyHandled = errors.New("Event has already been handled")
 
 func (self *NomadEventConsumer) processNomadEvent(ctx context.Context, event *domain.NomadEvent) error {
@@ -185,6 +347,8 @@ func (self *NomadEventConsumer) handleNomadEvent(ctx context.Context, event *nom
 		return self.handleNomadJobEvent(ctx, event)
 	case "Deployment":
 		return self.handleNomadDeploymentEvent(ctx, event)
+	case "Evaluation":
+		return self.handleNomadEvaluationEvent(ctx, event)
 	default:
 		self.Logger.Trace().
 			Str("topic", string(event.Topic)).
@@ -214,6 +378,20 @@ func (self *NomadEventConsumer) handleNomadAllocationEvent(ctx context.Context,
 		Str("nomad-job-id", allocation.JobID).
 		Logger()
 
+	// Remember where the Run ran so that later Runs of its action can prefer the node.
+	switch allocation.ClientStatus {
+	case nomad.AllocClientStatusRunning, nomad.AllocClientStatusComplete:
+		runIdStr := allocation.JobID
+		if parentId, isDispatch := domain.RunDispatchParentJobId(allocation.JobID); isDispatch {
+			runIdStr = parentId
+		}
+		if runId, err := uuid.Parse(runIdStr); err == nil && allocation.NodeID != "" {
+			if err := self.RunService.SaveNode(runId, allocation.NodeID); err != nil {
+				return err
+			}
+		}
+	}
+
 	switch allocation.ClientStatus {
 	case nomad.AllocClientStatusFailed, nomad.AllocClientStatusLost:
 	default:
@@ -235,6 +413,11 @@ func (self *NomadEventConsumer) handleNomadAllocationEvent(ctx context.Context,
 	if err := self.Db.BeginFunc(ctx, func(tx pgx.Tx) error {
 		txSelf := self.WithQuerier(tx)
 
+		if parentId, isDispatch := domain.RunDispatchParentJobId(allocation.JobID); isDispatch {
+			runFunc, err = txSelf.endDispatch(ctx, logger, parentId, allocation.JobID, allocation.ModifyTime, domain.RunStatusFailed)
+			return err
+		}
+
 		run, err := txSelf.getRun(logger, allocation.JobID)
 		if run == nil || err != nil {
 			return err
@@ -303,25 +486,36 @@ func (self *NomadEventConsumer) handleNomadJobEvent(ctx context.Context, event *
 	if err := self.Db.BeginFunc(ctx, func(tx pgx.Tx) error {
 		txSelf := self.WithQuerier(tx)
 
-		run, err := txSelf.getRun(logger, *job.ID)
-		if run == nil || err != nil {
+		if parentId, isDispatch := domain.RunDispatchParentJobId(*job.ID); isDispatch {
+			status := domain.RunStatusSucceeded
+			if event.Type == "JobDeregistered" {
+				status = domain.RunStatusFailed
+			}
+
+			modifyTime, err := txSelf.jobModifyTime(*job.ID)
+			if err != nil {
+				return err
+			}
+
+			runFunc, err = txSelf.endDispatch(ctx, logger, parentId, *job.ID, modifyTime, status)
 			return err
 		}
 
-		allocs, _, err := txSelf.NomadClient.JobsAllocations(*job.ID, false, &nomad.QueryOptions{})
-		if err != nil {
+		run, err := txSelf.getRun(logger, *job.ID)
+		if run == nil || err != nil {
 			return err
 		}
 
-		if len(allocs) == 0 {
-			panic("Found no allocations for this job")
+		// A parameterized job never runs itself so the Run
+		// ends with its dispatches unless it was canceled.
+		if job.IsParameterized() && run.Status == domain.RunStatusRunning {
+			logger.Trace().Msg("Ignoring job event (Run is ended by its dispatches)")
+			return nil
 		}
 
-		modifyTime := allocs[0].ModifyTime
-		for _, alloc := range allocs[1:] {
-			if alloc.ModifyTime > modifyTime {
-				modifyTime = alloc.ModifyTime
-			}
+		modifyTime, err := txSelf.jobModifyTime(*job.ID)
+		if err != nil {
+			return err
 		}
 
 		runFunc, err = txSelf.endRun(ctx, run, modifyTime, domain.RunStatusSucceeded)
@@ -341,9 +535,31 @@ func (self *NomadEventConsumer) handleNomadJobEvent(ctx context.Context, event *
 	return nil
 }
 
+// Returns when the job's allocations were last modified
+// or the current time if it has none.
+func (self *NomadEventConsumer) jobModifyTime(jobId string) (int64, error) {
+	allocs, _, err := self.NomadCluster.JobsAllocations(jobId, false, &nomad.QueryOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(allocs) == 0 {
+		return time.Now().UnixNano(), nil
+	}
+
+	modifyTime := allocs[0].ModifyTime
+	for _, alloc := range allocs[1:] {
+		if alloc.ModifyTime > modifyTime {
+			modifyTime = alloc.ModifyTime
+		}
+	}
+
+	return modifyTime, nil
+}
+
 func (self *NomadEventConsumer) handleNomadDeploymentEvent(ctx context.Context, event *nomad.Event) error {
 	switch event.Type {
-	case "PlanResult":
+	case "PlanResult", "DeploymentStatusUpdate", "DeploymentAllocHealth":
 	default:
 		self.Logger.Trace().
 			Str("topic", string(event.Topic)).
@@ -361,31 +577,6 @@ func (self *NomadEventConsumer) handleNomadDeploymentEvent(ctx context.Context,
 		Str("nomad-job-id", deployment.JobID).
 		Logger()
 
-	switch deployment.Status {
-	case nomad.DeploymentStatusSuccessful:
-	default:
-		logger.Trace().
-			Str("status", deployment.Status).
-			Msg("Ignoring deployment event (status is not successful)")
-		return nil
-	}
-
-	job, _, err := self.NomadClient.JobsInfo(deployment.JobID, &nomad.QueryOptions{})
-	if err != nil {
-		return err
-	}
-
-	if job == nil {
-		panic("Found no job for this deployment")
-	}
-
-	if job.Type != nil && *job.Type != nomad.JobTypeService {
-		logger.Trace().
-			Str("nomad-job-type", *job.Type).
-			Msg("Ignoring deployment event (job type is not service)")
-		return nil
-	}
-
 	var runFunc service.InvokeRunFunc
 
 	if err := self.Db.BeginFunc(ctx, func(tx pgx.Tx) error {
@@ -396,9 +587,52 @@ func (self *NomadEventConsumer) handleNomadDeploymentEvent(ctx context.Context,
 			return err
 		}
 
-		run.Status = domain.RunStatusSucceeded
-		runFunc, err = txSelf.publishRunOutput(ctx, run)
-		return err
+		run.DeploymentStatus = runDeploymentStatus(deployment)
+		run.DeploymentStatusDescription = deployment.StatusDescription
+		if err := txSelf.RunService.UpdateDeployment(run); err != nil {
+			return err
+		}
+
+		switch {
+		case deployment.Status == nomad.DeploymentStatusFailed:
+			logger.Debug().
+				Str("status-description", deployment.StatusDescription).
+				Msg("Deployment failed")
+
+			runFunc, err = txSelf.endRun(ctx, run, time.Now().UnixNano(), domain.RunStatusFailed)
+			return err
+		case deployment.Status == nomad.DeploymentStatusSuccessful && event.Type == "PlanResult":
+			job, _, err := txSelf.NomadCluster.JobsInfo(deployment.JobID, &nomad.QueryOptions{})
+			if err != nil {
+				return err
+			}
+
+			if job == nil {
+				panic("Found no job for this deployment")
+			}
+
+			if job.Type != nil && *job.Type != nomad.JobTypeService {
+				logger.Trace().
+					Str("nomad-job-type", *job.Type).
+					Msg("Ignoring deployment event (job type is not service)")
+				return nil
+			}
+
+			run.Status = domain.RunStatusSucceeded
+			output, outputRunFunc, err := txSelf.publishRunOutput(ctx, run)
+			if err != nil {
+				return err
+			}
+
+			chainRunFunc, err := txSelf.ActionService.InvokeChain(run, output)
+			runFunc = service.JoinInvokeRunFuncs(outputRunFunc, chainRunFunc)
+			return err
+		default:
+			logger.Trace().
+				Str("status", run.DeploymentStatus).
+				Msg("Updated deployment status")
+			return nil
+		}
 	}); err != nil {
 		return err
 	}
@@ -414,6 +648,75 @@ func (self *NomadEventConsumer) handleNomadDeploymentEvent(ctx context.Context,
 	return nil
 }
 
+// Returns Nomad's status of the deployment or `domain.RunDeploymentDegraded`
+// if it has not failed yet but some allocations are unhealthy.
+func runDeploymentStatus(deployment *nomad.Deployment) string {
+	if deployment.Status != nomad.DeploymentStatusFailed {
+		for _, state := range deployment.TaskGroups {
+			if state != nil && state.UnhealthyAllocs > 0 {
+				return domain.RunDeploymentDegraded
+			}
+		}
+	}
+	return deployment.Status
+}
+
+// Marks Runs as blocked whose job Nomad could not place
+// and unblocks them once it could.
+func (self *NomadEventConsumer) handleNomadEvaluationEvent(ctx context.Context, event *nomad.Event) error {
+	switch event.Type {
+	case "EvaluationUpdated":
+	default:
+		self.Logger.Trace().
+			Str("topic", string(event.Topic)).
+			Str("type", string(event.Type)).
+			Msg("Ignoring event")
+		return nil
+	}
+
+	eval, err := event.Evaluation()
+	if err != nil {
+		return errors.WithMessage(err, "Error getting Nomad event's evaluation")
+	}
+
+	logger := self.Logger.With().
+		Str("nomad-job-id", eval.JobID).
+		Str("evaluation", eval.ID).
+		Logger()
+
+	reasons, ok := domain.RunBlockedReasons(*eval)
+	if !ok {
+		logger.Trace().
+			Str("status", eval.Status).
+			Msg("Ignoring evaluation event (not done)")
+		return nil
+	}
+
+	return self.Db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx)
+
+		run, err := txSelf.getRun(logger, eval.JobID)
+		if run == nil || err != nil {
+			return err
+		}
+
+		if reasons == nil {
+			if run.BlockedSince == nil {
+				return nil
+			}
+			logger.Debug().Msg("Run is no longer blocked")
+			run.BlockedSince = nil
+		} else if run.BlockedSince == nil {
+			logger.Debug().Strs("reasons", reasons).Msg("Run is blocked")
+			blockedSince := time.Unix(0, eval.ModifyTime).UTC()
+			run.BlockedSince = &blockedSince
+		}
+		run.BlockedReasons = reasons
+
+		return txSelf.RunService.UpdateBlocked(run)
+	})
+}
+
 func (self *NomadEventConsumer) getRun(logger zerolog.Logger, idStr string) (*domain.Run, error) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -438,16 +741,31 @@ func (self *NomadEventConsumer) getRun(logger zerolog.Logger, idStr string) (*do
 	return run, nil
 }
 
-func (self *NomadEventConsumer) publishRunOutput(ctx context.Context, run *domain.Run) (service.InvokeRunFunc, error) {
+func (self *NomadEventConsumer) publishRunOutput(ctx context.Context, run *domain.Run) (*domain.Fact, service.InvokeRunFunc, error) {
 	output, err := self.InvocationService.GetOutputById(run.InvocationId)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	fact := domain.Fact{
 		RunId: &run.NomadJobID,
 	}
 
+	// Output facts are named after the action that published them.
+	action, err := self.ActionService.GetByRunId(run.NomadJobID)
+	if err != nil {
+		return nil, nil, err
+	} else if action != nil {
+		fact.Namespace = action.FactNamespace()
+		fact.Name = action.Name
+
+		if run.Status == domain.RunStatusSucceeded {
+			if _, err := self.EnvironmentService.Create(*action, *run); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
 	switch run.Status {
 	case domain.RunStatusSucceeded:
 		if output.Success.Exists() {
@@ -461,12 +779,45 @@ func (self *NomadEventConsumer) publishRunOutput(ctx context.Context, run *domai
 		panic("run status is not final in publishRunOutput()")
 	}
 
-	if fact.Value != nil {
-		_, runFunc, err := self.FactService.Save(&fact, nil)
-		return runFunc, err
+	if fact.Value == nil {
+		return nil, nil, nil
 	}
 
-	return nil, nil
+	if action != nil {
+		if unchanged, err := self.getUnchangedOutput(*action, *run, fact); err != nil {
+			return nil, nil, err
+		} else if unchanged != nil {
+			self.Logger.Debug().
+				Stringer("run", run.NomadJobID).
+				Stringer("unchanged-fact", unchanged.ID).
+				Msg("Not publishing unchanged output")
+
+			run.OutputUnchangedFactId = &unchanged.ID
+			return nil, nil, self.RunService.UpdateOutputUnchanged(run)
+		}
+	}
+
+	_, runFunc, err := self.FactService.Save(&fact, nil)
+	return &fact, runFunc, err
+}
+
+// Returns the previous output of the action if it is identical
+// to the given output and the action only publishes changed output.
+func (self *NomadEventConsumer) getUnchangedOutput(action domain.Action, run domain.Run, output domain.Fact) (*domain.Fact, error) {
+	if onChange, err := action.OutputOnChange(); err != nil || !onChange {
+		return nil, err
+	}
+
+	previous, err := self.FactService.GetLatestOutput(action.Name, run.NomadJobID)
+	if err != nil || previous == nil {
+		return nil, err
+	}
+
+	if same, err := output.SameValue(*previous); err != nil || !same {
+		return nil, err
+	}
+
+	return previous, nil
 }
 
 func (self *NomadEventConsumer) endRun(ctx context.Context, run *domain.Run, timestamp int64, status domain.RunStatus) (service.InvokeRunFunc, error) {
@@ -481,10 +832,12 @@ func (self *NomadEventConsumer) endRun(ctx context.Context, run *domain.Run, tim
 		case domain.RunStatusCanceled:
 		case domain.RunStatusRunning:
 			run.Status = status
-			if runFunc_, err := txSelf.publishRunOutput(ctx, run); err != nil {
+			if output, runFunc_, err := txSelf.publishRunOutput(ctx, run); err != nil {
+				return err
+			} else if chainRunFunc, err := txSelf.ActionService.InvokeChain(run, output); err != nil {
 				return err
 			} else {
-				runFunc = runFunc_
+				runFunc = service.JoinInvokeRunFuncs(runFunc_, chainRunFunc)
 			}
 		}
 
@@ -501,3 +854,35 @@ func (self *NomadEventConsumer) endRun(ctx context.Context, run *domain.Run, tim
 
 	return runFunc, nil
 }
+
+// Ends the dispatch of the given job and
+// the Run once all of its dispatches ended.
+func (self *NomadEventConsumer) endDispatch(ctx context.Context, logger zerolog.Logger, runIdStr, nomadJobId string, timestamp int64, status domain.RunStatus) (service.InvokeRunFunc, error) {
+	finishedAt := time.Unix(
+		timestamp/int64(time.Second),
+		timestamp%int64(time.Second),
+	).UTC()
+
+	if ended, err := self.RunService.EndDispatch(nomadJobId, status, finishedAt); err != nil {
+		return nil, err
+	} else if !ended {
+		logger.Trace().Msg("Ignoring event (no such dispatch or it already ended)")
+		return nil, nil
+	}
+
+	run, err := self.getRun(logger, runIdStr)
+	if run == nil || err != nil {
+		return nil, err
+	}
+
+	dispatches, err := self.RunService.GetDispatches(run.NomadJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status, finishedAt, done := domain.AggregateRunDispatches(dispatches); done {
+		return self.endRun(ctx, run, finishedAt.UnixNano(), status)
+	}
+
+	return nil, nil
+}
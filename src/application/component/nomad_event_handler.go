@@ -0,0 +1,202 @@
+package component
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Handles stored Nomad events in addition to Cicero,
+// for example to export custom metrics or to sync a CMDB.
+// Implementations are compiled in and registered
+// with RegisterNomadEventHandler.
+type NomadEventHandler interface {
+	// Identifies the handler's checkpoint so it must not change.
+	Name() string
+
+	// Called with events in the order they were stored.
+	// If an error is returned the events are handed in again later,
+	// which also happens if Cicero stops before the checkpoint is saved.
+	Handle(context.Context, []domain.NomadEvent) error
+}
+
+var (
+	nomadEventHandlers      = map[string]NomadEventHandler{}
+	nomadEventHandlersMutex sync.Mutex
+)
+
+// Registers a handler to be started with the nomad component,
+// usually from the `init()` function of a package
+// that is imported for its side effects in `main.go`.
+// Panics if a handler of the same name is registered already.
+func RegisterNomadEventHandler(handler NomadEventHandler) {
+	nomadEventHandlersMutex.Lock()
+	defer nomadEventHandlersMutex.Unlock()
+
+	name := handler.Name()
+	if name == "" {
+		panic("Nomad event handler has no name")
+	}
+	if _, exists := nomadEventHandlers[name]; exists {
+		panic("Nomad event handler " + name + " is registered already")
+	}
+	nomadEventHandlers[name] = handler
+}
+
+// Returns the registered handlers ordered by name.
+func NomadEventHandlers() []NomadEventHandler {
+	nomadEventHandlersMutex.Lock()
+	defer nomadEventHandlersMutex.Unlock()
+
+	handlers := make([]NomadEventHandler, 0, len(nomadEventHandlers))
+	for _, handler := range nomadEventHandlers {
+		handlers = append(handlers, handler)
+	}
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Name() < handlers[j].Name()
+	})
+	return handlers
+}
+
+// How old events must be before they are handed to handlers.
+// IDs are taken when events are inserted but transactions may commit
+// in another order, so a newer event could become visible
+// before an older one that would then be skipped.
+const nomadEventHandlerDelay = 10 * time.Second
+
+// Hands stored Nomad events to a NomadEventHandler
+// and remembers the last one it handled.
+type NomadEventHandlerRunner struct {
+	Logger            zerolog.Logger
+	NomadEventService service.NomadEventService
+	Handler           NomadEventHandler
+	// Notifies when events were saved
+	// so that they do not wait for the interval.
+	Db config.PgxIface
+
+	// How often to look for new events in case a notification was missed.
+	Interval time.Duration
+	// How many events to hand in at once.
+	BatchSize int
+}
+
+func (self *NomadEventHandlerRunner) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Int("batch-size", self.BatchSize).Msg("Starting")
+
+	checkpoint, err := self.NomadEventService.GetCheckpoint(self.Handler.Name())
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil {
+		// New handlers start with the events that come in from now on.
+		id, err := self.NomadEventService.GetLastId()
+		if err != nil {
+			return err
+		}
+		if err := self.NomadEventService.SaveCheckpoint(self.Handler.Name(), id); err != nil {
+			return err
+		}
+		checkpoint = &id
+	}
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	wake := make(chan struct{}, 1)
+	go self.listen(ctx, wake)
+
+	for {
+		for {
+			handled, err := self.handle(ctx, checkpoint)
+			if err != nil {
+				// Try again next interval, the handler may depend on something that is unavailable for a while.
+				self.Logger.Err(err).Int64("checkpoint", *checkpoint).Msg("Could not handle nomad events")
+				break
+			}
+			if handled < self.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// Notified by a trigger on `nomad_event`.
+const nomadEventNotifyChannel = "cicero_nomad_event"
+
+// Wakes the runner on notifications until the context ends,
+// once the events are old enough to be handed in.
+// While not listening the runner still runs every interval.
+func (self *NomadEventHandlerRunner) listen(ctx context.Context, wake chan<- struct{}) {
+	// Whether a wake is pending so that notifications
+	// that arrive in the meantime do not start more timers.
+	var pending int32
+
+	for {
+		err := config.DBListen(ctx, self.Db, []string{nomadEventNotifyChannel}, func(*pgconn.Notification) {
+			self.Logger.Trace().Msg("Notified about nomad events")
+
+			if !atomic.CompareAndSwapInt32(&pending, 0, 1) {
+				return
+			}
+			time.AfterFunc(nomadEventHandlerDelay, func() {
+				atomic.StoreInt32(&pending, 0)
+
+				// Notifications that arrive while handling are handled by the next run.
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		self.Logger.Err(err).Msg("Stopped listening for notifications about nomad events")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(self.Interval):
+		}
+	}
+}
+
+// Hands in one batch of events and advances the checkpoint.
+func (self *NomadEventHandlerRunner) handle(ctx context.Context, checkpoint *int64) (int, error) {
+	events, err := self.NomadEventService.GetAfterId(*checkpoint, time.Now().Add(-nomadEventHandlerDelay), self.BatchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	last := events[len(events)-1].ID
+
+	self.Logger.Debug().Int("events", len(events)).Int64("from", events[0].ID).Int64("to", last).Msg("Handling nomad events")
+
+	if err := self.Handler.Handle(ctx, events); err != nil {
+		return 0, errors.WithMessagef(err, "Nomad event handler %q failed", self.Handler.Name())
+	}
+
+	if err := self.NomadEventService.SaveCheckpoint(self.Handler.Name(), last); err != nil {
+		return 0, err
+	}
+	*checkpoint = last
+
+	return len(events), nil
+}
@@ -0,0 +1,227 @@
+package component
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricNomadEventQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "nomad_event",
+		Name:      "queue_length",
+		Help:      "Number of Nomad events received but not yet processed.",
+	})
+	metricNomadEventLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "nomad_event",
+		Name:      "lag_seconds",
+		Help:      "How long the last processed Nomad event waited in the queue. Alert if this keeps growing.",
+	})
+	metricNomadEventDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "nomad_event",
+		Name:      "dropped_total",
+		Help:      "Number of low-value Nomad events dropped because the queue was full by topic.",
+	}, []string{"topic"})
+	metricNomadEventFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "nomad_event",
+		Name:      "filtered_total",
+		Help:      "Number of Nomad events neither saved nor handled because of the event filter by topic.",
+	}, []string{"topic"})
+	metricNomadEventBatchSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "nomad_event",
+		Name:      "batch_size",
+		Help:      "Number of Nomad events currently processed between pauses.",
+	})
+)
+
+// The value of `metricNomadEventLag` in nanoseconds for `AlertNotifier`.
+var nomadEventLag int64
+
+func setNomadEventLag(lag time.Duration) {
+	metricNomadEventLag.Set(lag.Seconds())
+	atomic.StoreInt64(&nomadEventLag, int64(lag))
+}
+
+func getNomadEventLag() time.Duration {
+	return time.Duration(atomic.LoadInt64(&nomadEventLag))
+}
+
+type queuedNomadEvent struct {
+	nomad.Event
+	received time.Time
+	// Only known when consuming shards, see `nomadEventJobId()`.
+	jobId string
+}
+
+// Decouples receiving Nomad events from processing them.
+// When the queue is full, events that no handler acts on are dropped
+// instead of blocking the stream. Allocations are remembered so that
+// their latest state can be fetched from Nomad once the queue drained.
+type nomadEventQueue struct {
+	events chan queuedNomadEvent
+
+	// Index of the last dropped event by allocation ID.
+	refetch      map[string]uint64
+	refetchMutex sync.Mutex
+}
+
+func newNomadEventQueue(size int) *nomadEventQueue {
+	if size < 1 {
+		size = 1
+	}
+	return &nomadEventQueue{
+		events:  make(chan queuedNomadEvent, size),
+		refetch: map[string]uint64{},
+	}
+}
+
+// Events that do not change the state of Runs and are only kept for their history.
+func isLowValueNomadEvent(event *nomad.Event) bool {
+	switch event.Topic {
+	case nomad.TopicEvaluation:
+		return true
+	case nomad.TopicAllocation:
+		allocation, err := event.Allocation()
+		if err != nil {
+			return false
+		}
+		switch allocation.ClientStatus {
+		case nomad.AllocClientStatusFailed, nomad.AllocClientStatusLost:
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Enqueues the event, blocking only if it must not be dropped.
+// Returns whether the event was enqueued.
+func (self *nomadEventQueue) push(ctx context.Context, event nomad.Event, jobId string) (bool, error) {
+	queued := queuedNomadEvent{Event: event, received: time.Now(), jobId: jobId}
+
+	select {
+	case self.events <- queued:
+		metricNomadEventQueueLength.Set(float64(len(self.events)))
+		return true, nil
+	default:
+	}
+
+	if isLowValueNomadEvent(&event) {
+		metricNomadEventDropped.WithLabelValues(string(event.Topic)).Inc()
+
+		if event.Topic == nomad.TopicAllocation {
+			self.refetchMutex.Lock()
+			self.refetch[event.Key] = event.Index
+			self.refetchMutex.Unlock()
+		}
+
+		return false, nil
+	}
+
+	select {
+	case self.events <- queued:
+		metricNomadEventQueueLength.Set(float64(len(self.events)))
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Takes up to max events without waiting, at least one.
+func (self *nomadEventQueue) pop(ctx context.Context, max int) ([]queuedNomadEvent, error) {
+	batch := []queuedNomadEvent{}
+
+	select {
+	case event, ok := <-self.events:
+		if !ok {
+			return nil, nil
+		}
+		batch = append(batch, event)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for len(batch) < max {
+		select {
+		case event, ok := <-self.events:
+			if !ok {
+				return batch, nil
+			}
+			batch = append(batch, event)
+		default:
+			metricNomadEventQueueLength.Set(float64(len(self.events)))
+			return batch, nil
+		}
+	}
+
+	metricNomadEventQueueLength.Set(float64(len(self.events)))
+	return batch, nil
+}
+
+// Returns the allocations whose events were dropped and forgets them.
+func (self *nomadEventQueue) takeRefetch() map[string]uint64 {
+	self.refetchMutex.Lock()
+	defer self.refetchMutex.Unlock()
+
+	refetch := self.refetch
+	self.refetch = map[string]uint64{}
+	return refetch
+}
+
+// Grows the batch size while batches are processed quickly
+// and shrinks it when the database cannot keep up.
+type adaptiveBatchSize struct {
+	Min, Max int
+	// How long processing one batch should take.
+	Target time.Duration
+
+	current int
+}
+
+func (self *adaptiveBatchSize) Get() int {
+	if self.current == 0 {
+		self.current = self.Min
+	}
+	return self.current
+}
+
+// Adjusts the batch size to how long the last batch took
+// and returns how long to pause before the next one.
+func (self *adaptiveBatchSize) Took(duration time.Duration) (pause time.Duration) {
+	current := self.Get()
+
+	switch {
+	case duration > self.Target:
+		current /= 2
+		// Give the database time to recover.
+		pause = duration - self.Target
+		if pause > self.Target {
+			pause = self.Target
+		}
+	case duration < self.Target/2:
+		current *= 2
+	}
+
+	if current < self.Min {
+		current = self.Min
+	}
+	if current > self.Max {
+		current = self.Max
+	}
+	self.current = current
+
+	metricNomadEventBatchSize.Set(float64(current))
+
+	return
+}
@@ -0,0 +1,161 @@
+package component
+
+import (
+	"context"
+	"sync"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+// Consumes each topic in its own stream so that they are processed in parallel.
+// Each shard starts after the last saved event of its topic.
+// Events of a job are still processed in the order of their index
+// as far as they were received: an event waits until the events
+// of its job with a lower index that any shard received are processed.
+// When one shard stops all are restarted together.
+func (self *NomadEventConsumer) consumeShards(ctx context.Context, topics map[nomad.Topic][]string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	order := newNomadEventOrder()
+
+	errs := make(chan error, len(topics))
+	for topic, types := range topics {
+		index, err := self.NomadEventService.GetLastNomadEventIndexByTopic(topic, self.NomadClusterNames)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return errors.WithMessagef(err, "Could not get last Nomad event index of topic %q", topic)
+		}
+		index += 1
+
+		shard := *self
+		shard.Logger = self.Logger.With().Str("topic", string(topic)).Logger()
+		shardTopics := map[nomad.Topic][]string{topic: types}
+
+		go func() { errs <- shard.consume(ctx, shardTopics, index, order) }()
+	}
+
+	err := <-errs
+	cancel()
+	for i := 1; i < len(topics); i++ {
+		<-errs
+	}
+	return err
+}
+
+// Returns the ID of the job an event is about, empty if it has none.
+// Events of a parameterized job's dispatches belong to the parent job.
+func nomadEventJobId(event *nomad.Event) (jobId string) {
+	switch event.Topic {
+	case nomad.TopicAllocation:
+		if allocation, err := event.Allocation(); err == nil && allocation != nil {
+			jobId = allocation.JobID
+		}
+	case nomad.TopicEvaluation:
+		if evaluation, err := event.Evaluation(); err == nil && evaluation != nil {
+			jobId = evaluation.JobID
+		}
+	case nomad.TopicDeployment:
+		if deployment, err := event.Deployment(); err == nil && deployment != nil {
+			jobId = deployment.JobID
+		}
+	case nomad.TopicJob:
+		// The key of job events is the job's ID.
+		jobId = event.Key
+	}
+
+	if parentId, ok := domain.RunDispatchParentJobId(jobId); ok {
+		return parentId
+	}
+	return
+}
+
+// Tracks which received events of each job are not processed yet
+// so that shards can wait for each other.
+type nomadEventOrder struct {
+	mutex sync.Mutex
+	// Indexes of pending events by job ID.
+	pending map[string][]uint64
+	// Closed and replaced whenever an event was processed.
+	changed chan struct{}
+}
+
+func newNomadEventOrder() *nomadEventOrder {
+	return &nomadEventOrder{
+		pending: map[string][]uint64{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Marks an event as received.
+func (self *nomadEventOrder) add(jobId string, index uint64) {
+	if jobId == "" {
+		return
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.pending[jobId] = append(self.pending[jobId], index)
+}
+
+// Marks an event as processed, or dropped.
+func (self *nomadEventOrder) done(jobId string, index uint64) {
+	if jobId == "" {
+		return
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	indexes := self.pending[jobId]
+	for i, pending := range indexes {
+		if pending == index {
+			indexes = append(indexes[:i], indexes[i+1:]...)
+			break
+		}
+	}
+	if len(indexes) == 0 {
+		delete(self.pending, jobId)
+	} else {
+		self.pending[jobId] = indexes
+	}
+
+	close(self.changed)
+	self.changed = make(chan struct{})
+}
+
+// Blocks until no event of the job with a lower index is pending.
+// This cannot deadlock as every shard processes its events
+// in the order of their index so the lowest pending one can always proceed.
+func (self *nomadEventOrder) wait(ctx context.Context, jobId string, index uint64) error {
+	if jobId == "" {
+		return nil
+	}
+
+	for {
+		self.mutex.Lock()
+		earlier := false
+		for _, pending := range self.pending[jobId] {
+			if pending < index {
+				earlier = true
+				break
+			}
+		}
+		changed := self.changed
+		self.mutex.Unlock()
+
+		if !earlier {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
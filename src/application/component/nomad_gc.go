@@ -0,0 +1,101 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application"
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Keeps track of which Runs' Nomad jobs have been garbage collected
+// and optionally purges them itself after a retention period.
+type NomadGC struct {
+	Logger        zerolog.Logger
+	RunService    service.RunService
+	NomadClusters application.NomadClusters
+	// Purges the Nomad jobs of Runs that finished NomadGCPurgeAfter ago.
+	// Zero leaves garbage collection to Nomad.
+	Runtime *config.RuntimeConfig
+
+	// How often to look for garbage collected jobs.
+	Interval time.Duration
+}
+
+// How many Runs to check per interval.
+const nomadGCBatchSize = 500
+
+func (self *NomadGC) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.collect(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *NomadGC) collect() error {
+	runs, err := self.RunService.GetFinishedWithoutNomadJobGC(time.Now(), nomadGCBatchSize)
+	if err != nil {
+		return err
+	}
+
+	purgeAfter := time.Duration(self.Runtime.Get().NomadGCPurgeAfter)
+
+	self.Logger.Debug().Int("runs", len(runs)).Dur("purge-after", purgeAfter).Msg("Checking whether Nomad jobs were garbage collected")
+
+	for _, run := range runs {
+		run := run
+		logger := self.Logger.With().Str("nomad-job-id", run.NomadJobID.String()).Str("nomad-cluster", run.NomadCluster).Logger()
+
+		cluster, ok := self.NomadClusters.Get(run.NomadCluster)
+		if !ok {
+			logger.Warn().Msg("Nomad cluster of Run is unknown")
+			continue
+		}
+
+		if _, _, err := cluster.JobsInfo(run.NomadJobID.String(), &nomad.QueryOptions{}); err != nil {
+			if !application.IsNomadNotFound(err) {
+				logger.Err(err).Msg("Could not get Nomad job")
+				continue
+			}
+
+			logger.Debug().Msg("Nomad job was garbage collected")
+			if err := self.RunService.MarkNomadJobGCed(&run); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if purgeAfter > 0 && time.Since(*run.FinishedAt) > purgeAfter {
+			if err := self.purge(&run); err != nil {
+				logger.Err(err).Msg("Could not purge Nomad job")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (self *NomadGC) purge(run *domain.Run) error {
+	// Runs that ended before snapshots were introduced have none yet.
+	if err := self.RunService.SnapshotAllocations(run); err != nil {
+		return err
+	}
+	return self.RunService.PurgeNomadJob(run)
+}
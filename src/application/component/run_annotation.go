@@ -0,0 +1,62 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Annotates finished Runs with the lines of their log
+// that match the log matchers of their action.
+type RunLogAnnotator struct {
+	Logger               zerolog.Logger
+	RunAnnotationService service.RunAnnotationService
+
+	// How often to look for Runs to annotate.
+	Interval time.Duration
+}
+
+// How many Runs to annotate per interval.
+const runLogAnnotateBatchSize = 100
+
+func (self *RunLogAnnotator) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.annotate(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *RunLogAnnotator) annotate() error {
+	// Waits as long as the archiver for the last log lines to arrive in Loki.
+	runs, err := self.RunAnnotationService.GetFinishedWithoutAnnotations(time.Now().Add(-runLogArchiveDelay), runLogAnnotateBatchSize)
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("runs", len(runs)).Msg("Annotating Runs")
+
+	for _, run := range runs {
+		if _, err := self.RunAnnotationService.Annotate(run); err != nil {
+			// Try again next interval, Loki may be unavailable for a while.
+			self.Logger.Err(err).Str("nomad-job-id", run.NomadJobID.String()).Msg("Could not annotate Run")
+			return nil
+		}
+	}
+
+	return nil
+}
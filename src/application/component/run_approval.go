@@ -0,0 +1,69 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Rejects Runs that waited for approval longer than their action allows.
+type RunApprovalExpirer struct {
+	Logger             zerolog.Logger
+	RunApprovalService service.RunApprovalService
+	RunService         service.RunService
+	ActionService      service.ActionService
+
+	// How often to look for expired approvals.
+	Interval time.Duration
+}
+
+func (self *RunApprovalExpirer) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.expire(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *RunApprovalExpirer) expire() error {
+	approvals, err := self.RunApprovalService.GetExpired(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	for _, approval := range approvals {
+		logger := self.Logger.With().Str("nomad-job-id", approval.RunId.String()).Logger()
+
+		run, err := self.RunService.GetByNomadJobId(approval.RunId)
+		if err != nil {
+			return err
+		}
+		if run == nil {
+			continue
+		}
+
+		comment := "Nobody decided within " + approval.ExpiresAt.Sub(run.CreatedAt).String()
+		if decided, err := self.ActionService.Decide(run, false, domain.RunApprovalTimeoutDecider, comment); err != nil {
+			return err
+		} else if decided != nil {
+			logger.Info().Msg("Rejected Run whose approval expired")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+var (
+	metricRunHeartbeats = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "run_heartbeat",
+		Name:      "published_total",
+		Help:      "Number of heartbeat facts published for running Runs.",
+	})
+	metricRunHeartbeatQuiet = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "run_heartbeat",
+		Name:      "max_quiet_seconds",
+		Help:      "Longest time any running Run has not logged anything as of the last heartbeats.",
+	})
+)
+
+// Publishes a heartbeat fact for each Run that has been running
+// for at least an interval so that monitors can tell whether it is alive.
+type RunHeartbeat struct {
+	Logger        zerolog.Logger
+	RunService    service.RunService
+	ActionService service.ActionService
+	FactService   service.FactService
+	Db            config.PgxIface
+
+	// How often to publish heartbeats.
+	Interval time.Duration
+
+	// When each Run last logged so that Loki is only asked for newer lines.
+	lastLogs map[uuid.UUID]time.Time
+}
+
+func (self *RunHeartbeat) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	self.lastLogs = map[uuid.UUID]time.Time{}
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := self.beat(); err != nil {
+			return err
+		}
+	}
+}
+
+func (self *RunHeartbeat) beat() error {
+	runs, err := self.RunService.GetRunning()
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("runs", len(runs)).Msg("Publishing heartbeats of Runs")
+
+	now := time.Now().UTC()
+	lastLogs := make(map[uuid.UUID]time.Time, len(runs))
+	var maxQuiet time.Duration
+
+	for _, run := range runs {
+		run := run
+		logger := self.Logger.With().Str("nomad-job-id", run.NomadJobID.String()).Logger()
+
+		// Short Runs are over before anyone needs to know whether they are alive.
+		if now.Sub(run.CreatedAt) < self.Interval {
+			continue
+		}
+
+		heartbeat := domain.RunHeartbeat{Run: run, At: now}
+
+		if lastLog, ok := self.lastLogs[run.NomadJobID]; ok {
+			heartbeat.LastLog = &lastLog
+		}
+		if lastLog, err := self.getLastLog(run, heartbeat.LastLog); err != nil {
+			// Still publish the heartbeat, Loki may be unavailable for a while.
+			logger.Err(err).Msg("Could not get last log line of Run")
+		} else if lastLog != nil {
+			heartbeat.LastLog = lastLog
+		}
+		if heartbeat.LastLog != nil {
+			lastLogs[run.NomadJobID] = *heartbeat.LastLog
+		}
+
+		if progress, err := self.RunService.GetLatestProgress(run.NomadJobID); err != nil {
+			return err
+		} else {
+			heartbeat.Progress = progress
+		}
+
+		if action, err := self.ActionService.GetByRunId(run.NomadJobID); err != nil {
+			return err
+		} else if action != nil {
+			heartbeat.Action = action.Name
+		}
+
+		if err := self.publish(heartbeat); err != nil {
+			return errors.WithMessagef(err, "Could not publish heartbeat of Run %q", run.NomadJobID)
+		}
+		metricRunHeartbeats.Inc()
+
+		if quiet := heartbeat.Quiet(); quiet > maxQuiet {
+			maxQuiet = quiet
+		}
+	}
+
+	// Forget about Runs that are no longer running.
+	self.lastLogs = lastLogs
+
+	metricRunHeartbeatQuiet.Set(maxQuiet.Seconds())
+
+	return nil
+}
+
+// Returns nil if the Run logged nothing after the given time,
+// or nothing at all if none is given.
+func (self *RunHeartbeat) getLastLog(run domain.Run, after *time.Time) (*time.Time, error) {
+	start := run.CreatedAt
+	if after != nil {
+		start = after.Add(time.Nanosecond)
+	}
+
+	page, err := self.RunService.JobLog(run.NomadJobID, start, nil, service.LokiPage{Direction: service.LokiBackward, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	var last *time.Time
+	for _, line := range page.Log {
+		line := line
+		if last == nil || line.Time.After(*last) {
+			last = &line.Time
+		}
+	}
+	return last, nil
+}
+
+func (self *RunHeartbeat) publish(heartbeat domain.RunHeartbeat) error {
+	fact := domain.Fact{Value: heartbeat.Fact()}
+	if _, runFunc, err := self.FactService.Save(&fact, nil); err != nil {
+		return err
+	} else if _, registerFunc, err := runFunc(self.Db); err != nil {
+		return err
+	} else {
+		return registerFunc()
+	}
+}
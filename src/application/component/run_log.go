@@ -0,0 +1,64 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Archives the logs of finished Runs so that they can be served
+// after Loki's retention period deleted them.
+type RunLogArchiver struct {
+	Logger     zerolog.Logger
+	RunService service.RunService
+
+	// How often to look for Runs to archive the logs of.
+	Interval time.Duration
+}
+
+// How many Runs to archive the logs of per interval.
+const runLogArchiveBatchSize = 100
+
+// How long to wait after a Run finished for its last log lines to arrive in Loki.
+const runLogArchiveDelay = 2 * time.Minute
+
+func (self *RunLogArchiver) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.archive(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *RunLogArchiver) archive() error {
+	runs, err := self.RunService.GetFinishedWithoutLogArchive(time.Now().Add(-runLogArchiveDelay), runLogArchiveBatchSize)
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("runs", len(runs)).Msg("Archiving logs of Runs")
+
+	for _, run := range runs {
+		if err := self.RunService.ArchiveLog(run); err != nil {
+			// Try again next interval, Loki may be unavailable for a while.
+			self.Logger.Err(err).Str("nomad-job-id", run.NomadJobID.String()).Msg("Could not archive log of Run")
+			return nil
+		}
+	}
+
+	return nil
+}
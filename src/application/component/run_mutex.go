@@ -0,0 +1,123 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+// Passes mutexes whose holders ended on to the next waiting Run
+// and registers its job. Holders release their mutex by ending,
+// even if Cicero was not running at the time.
+type RunMutexScheduler struct {
+	Logger          zerolog.Logger
+	RunMutexService service.RunMutexService
+	RunService      service.RunService
+	ActionService   service.ActionService
+	// Notifies when a mutex may have become free
+	// so that the next Run does not wait for the interval.
+	Db config.PgxIface
+
+	// How often to look for free mutexes in case a notification was missed.
+	Interval time.Duration
+}
+
+// Notified by triggers on `run` and `run_mutex` with the mutex's name.
+const runMutexNotifyChannel = "cicero_run_mutex"
+
+func (self *RunMutexScheduler) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	wake := make(chan struct{}, 1)
+	go self.listen(ctx, wake)
+
+	for {
+		if err := self.schedule(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// Wakes the scheduler on notifications until the context ends.
+// While not listening the scheduler still runs every interval.
+func (self *RunMutexScheduler) listen(ctx context.Context, wake chan<- struct{}) {
+	for {
+		err := config.DBListen(ctx, self.Db, []string{runMutexNotifyChannel}, func(notification *pgconn.Notification) {
+			self.Logger.Trace().Str("mutex", notification.Payload).Msg("Notified about mutex")
+
+			// Notifications that arrive while scheduling are handled by the next run.
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		self.Logger.Err(err).Msg("Stopped listening for notifications about mutexes")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(self.Interval):
+		}
+	}
+}
+
+func (self *RunMutexScheduler) schedule() error {
+	states, err := self.RunMutexService.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, state := range states {
+		if state.Holder != nil || len(state.Waiting) == 0 {
+			continue
+		}
+
+		mutex, job, err := self.RunMutexService.Pass(state.Name)
+		if err != nil {
+			return err
+		}
+		if mutex == nil {
+			continue
+		}
+
+		logger := self.Logger.With().Str("mutex", mutex.Name).Str("nomad-job-id", mutex.RunId.String()).Logger()
+
+		run, err := self.RunService.GetByNomadJobId(mutex.RunId)
+		if err != nil {
+			return err
+		}
+		if run == nil || job == nil {
+			logger.Error().Msg("Run that acquired mutex has no job")
+			continue
+		}
+
+		if err := self.ActionService.RegisterJob(run, job); err != nil {
+			// The Run now holds the mutex but has no job.
+			// The watchdog notices it as stuck so it can be canceled.
+			logger.Err(err).Msg("Could not register job of Run that acquired mutex")
+			continue
+		}
+
+		logger.Info().Msg("Registered job of Run that acquired mutex")
+	}
+
+	return nil
+}
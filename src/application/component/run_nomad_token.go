@@ -0,0 +1,40 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Revokes the Nomad tokens of Runs that ended or held them for too long.
+type RunNomadTokenRevoker struct {
+	Logger               zerolog.Logger
+	RunNomadTokenService service.RunNomadTokenService
+
+	// How often to look for tokens to revoke.
+	Interval time.Duration
+}
+
+func (self *RunNomadTokenRevoker) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if revoked, err := self.RunNomadTokenService.RevokeExpired(); err != nil {
+			return err
+		} else if revoked > 0 {
+			self.Logger.Debug().Int("tokens", revoked).Msg("Revoked Nomad tokens")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+// Takes queued Runs off the queue as others end
+// and registers their jobs.
+type RunQueueScheduler struct {
+	Logger          zerolog.Logger
+	RunQueueService service.RunQueueService
+	RunService      service.RunService
+	ActionService   service.ActionService
+	// Notifies when a Run was queued or ended
+	// so that the next Run does not wait for the interval.
+	Db config.PgxIface
+
+	// How often to look at the queue in case a notification was missed.
+	Interval time.Duration
+}
+
+// Notified by triggers on `run` and `run_queue`.
+const runQueueNotifyChannel = "cicero_run_queue"
+
+func (self *RunQueueScheduler) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	wake := make(chan struct{}, 1)
+	go self.listen(ctx, wake)
+
+	for {
+		if err := self.schedule(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// Wakes the scheduler on notifications until the context ends.
+// While not listening the scheduler still runs every interval.
+func (self *RunQueueScheduler) listen(ctx context.Context, wake chan<- struct{}) {
+	for {
+		err := config.DBListen(ctx, self.Db, []string{runQueueNotifyChannel}, func(*pgconn.Notification) {
+			self.Logger.Trace().Msg("Notified about queue")
+
+			// Notifications that arrive while scheduling are handled by the next run.
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		self.Logger.Err(err).Msg("Stopped listening for notifications about the queue")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(self.Interval):
+		}
+	}
+}
+
+func (self *RunQueueScheduler) schedule() error {
+	entries, jobs, err := self.RunQueueService.Schedule()
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		logger := self.Logger.With().
+			Str("project", entry.Project).
+			Str("action", entry.ActionName).
+			Str("nomad-job-id", entry.RunId.String()).
+			Logger()
+
+		run, err := self.RunService.GetByNomadJobId(entry.RunId)
+		if err != nil {
+			return err
+		}
+		if run == nil {
+			logger.Error().Msg("Queued Run does not exist")
+			continue
+		}
+
+		if err := self.ActionService.RegisterJob(run, jobs[i]); err != nil {
+			// The Run is now off the queue but has no job.
+			// The watchdog notices it as stuck so it can be canceled.
+			logger.Err(err).Msg("Could not register job of queued Run")
+			continue
+		}
+
+		logger.Info().Msg("Registered job of queued Run")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Ends running Runs whose Nomad job vanished or died
+// without Cicero noticing, for example while it was down.
+type RunReconciler struct {
+	Logger     zerolog.Logger
+	RunService service.RunService
+
+	// How often to reconcile Runs.
+	Interval time.Duration
+	// How old Runs must be to be reconciled
+	// so that those whose job is just being submitted are left alone.
+	Grace time.Duration
+}
+
+func (self *RunReconciler) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Dur("grace", self.Grace).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if runs, err := self.RunService.Reconcile(time.Now().Add(-self.Grace), false); err != nil {
+			// Try again next interval, Nomad may be unavailable for a while.
+			self.Logger.Err(err).Msg("Could not reconcile Runs")
+		} else {
+			for _, run := range runs {
+				self.Logger.Warn().Str("nomad-job-id", run.NomadJobID.String()).Str("note", *run.ReconcileNote).Msg("Reconciled Run")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Measures how long finished Runs spent in each phase
+// so that bottlenecks of their actions can be found.
+type RunTimingCollector struct {
+	Logger           zerolog.Logger
+	RunTimingService service.RunTimingService
+
+	// How often to look for Runs to measure.
+	Interval time.Duration
+}
+
+// How many Runs to measure per interval.
+const runTimingBatchSize = 100
+
+// How long to wait after a Run finished for the last events of its job.
+const runTimingDelay = time.Minute
+
+func (self *RunTimingCollector) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.collect(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *RunTimingCollector) collect() error {
+	runs, err := self.RunTimingService.GetUnmeasured(time.Now().Add(-runTimingDelay), runTimingBatchSize)
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("runs", len(runs)).Msg("Measuring timing of Runs")
+
+	for _, run := range runs {
+		if _, err := self.RunTimingService.Measure(run); err != nil {
+			self.Logger.Err(err).Str("nomad-job-id", run.NomadJobID.String()).Msg("Could not measure timing of Run")
+		}
+	}
+
+	return nil
+}
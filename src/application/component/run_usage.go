@@ -0,0 +1,64 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Measures the resources used by finished Runs
+// so that their cost can be reported.
+type RunUsageCollector struct {
+	Logger      zerolog.Logger
+	CostService service.CostService
+
+	// How often to look for Runs to measure.
+	Interval time.Duration
+}
+
+// How many Runs to measure per interval.
+const runUsageBatchSize = 100
+
+// How long to wait after a Run finished for its last metrics to be scraped.
+const runUsageDelay = 5 * time.Minute
+
+func (self *RunUsageCollector) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.collect(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *RunUsageCollector) collect() error {
+	runs, err := self.CostService.GetUnmeasured(time.Now().Add(-runUsageDelay), runUsageBatchSize)
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("runs", len(runs)).Msg("Measuring usage of Runs")
+
+	for _, run := range runs {
+		if _, err := self.CostService.Measure(run); err != nil {
+			// Try again next interval, metrics may be unavailable for a while.
+			self.Logger.Err(err).Str("nomad-job-id", run.NomadJobID.String()).Msg("Could not measure usage of Run")
+			return nil
+		}
+	}
+
+	return nil
+}
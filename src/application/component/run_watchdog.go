@@ -0,0 +1,174 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+var (
+	metricRunWatchdogSuspect = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "run_watchdog",
+		Name:      "suspect_runs",
+		Help:      "Number of running Runs that seem stuck. Alert if this is not zero.",
+	})
+	metricRunWatchdogActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "run_watchdog",
+		Name:      "actions_total",
+		Help:      "Number of suspect Runs that were restarted or canceled by action.",
+	}, []string{"action"})
+)
+
+// What the watchdog does with Runs that seem stuck
+// besides marking them as suspect.
+type RunWatchdogAction string
+
+const (
+	RunWatchdogNone    RunWatchdogAction = ""
+	RunWatchdogRestart RunWatchdogAction = "restart"
+	RunWatchdogCancel  RunWatchdogAction = "cancel"
+)
+
+// Marks running Runs as suspect whose allocations have neither changed
+// in Nomad nor logged anything for a while, as they are probably stuck.
+type RunWatchdog struct {
+	Logger            zerolog.Logger
+	RunService        service.RunService
+	InvocationService service.InvocationService
+	Db                config.PgxIface
+
+	// How often to look for stuck Runs.
+	Interval time.Duration
+	// How long a Run may be quiet before it is suspect.
+	StuckAfter time.Duration
+	Action     RunWatchdogAction
+}
+
+func (self *RunWatchdog) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Dur("stuck-after", self.StuckAfter).Str("action", string(self.Action)).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.check(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *RunWatchdog) check() error {
+	runs, err := self.RunService.GetRunning()
+	if err != nil {
+		return err
+	}
+
+	self.Logger.Debug().Int("runs", len(runs)).Msg("Checking whether Runs are stuck")
+
+	cutoff := time.Now().Add(-self.StuckAfter)
+	suspects := 0
+
+	for _, run := range runs {
+		run := run
+		logger := self.Logger.With().Str("nomad-job-id", run.NomadJobID.String()).Logger()
+
+		// Service jobs are quiet once they are deployed.
+		if run.DeploymentStatus != "" {
+			continue
+		}
+
+		// Blocked Runs are quiet until Nomad can place them.
+		if run.BlockedSince != nil {
+			continue
+		}
+
+		lastActivity, err := self.RunService.GetLastActivity(run, cutoff)
+		if err != nil {
+			// Try again next interval, Loki may be unavailable for a while.
+			logger.Err(err).Msg("Could not get last activity of Run")
+			continue
+		}
+
+		if !lastActivity.Before(cutoff) {
+			if run.SuspectSince != nil {
+				logger.Info().Time("last-activity", lastActivity).Msg("Run is active again")
+				run.SuspectSince = nil
+				if err := self.RunService.UpdateSuspect(&run); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if run.SuspectSince == nil {
+			logger.Warn().Time("last-activity", lastActivity).Msg("Run seems stuck")
+			now := time.Now().UTC()
+			run.SuspectSince = &now
+			if err := self.RunService.UpdateSuspect(&run); err != nil {
+				return err
+			}
+		}
+
+		switch self.Action {
+		case RunWatchdogRestart:
+			if err := self.restart(&run); err != nil {
+				logger.Err(err).Msg("Could not restart suspect Run")
+				suspects++
+			}
+		case RunWatchdogCancel:
+			if err := self.RunService.Cancel(&run); err != nil {
+				logger.Err(err).Msg("Could not cancel suspect Run")
+				suspects++
+			} else {
+				logger.Info().Msg("Canceled suspect Run")
+				metricRunWatchdogActions.WithLabelValues(string(self.Action)).Inc()
+			}
+		default:
+			suspects++
+		}
+	}
+
+	metricRunWatchdogSuspect.Set(float64(suspects))
+
+	return nil
+}
+
+// Cancels the Run and invokes its action again with the same inputs.
+func (self *RunWatchdog) restart(run *domain.Run) error {
+	if err := self.RunService.Cancel(run); err != nil {
+		return err
+	}
+
+	invocation, runFunc, err := self.InvocationService.Retry(run.InvocationId)
+	if err != nil {
+		return err
+	}
+
+	runs, registerFunc, err := runFunc(self.Db)
+	if err != nil {
+		return err
+	}
+	if err := registerFunc(); err != nil {
+		return err
+	}
+
+	self.Logger.Info().Str("nomad-job-id", run.NomadJobID.String()).Stringer("invocation", invocation.Id).Interface("runs", runs).Msg("Restarted suspect Run")
+	metricRunWatchdogActions.WithLabelValues(string(self.Action)).Inc()
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Emails users that gave an address with their watches
+// when the watched Run or Runs of the watched action end.
+type WatchNotifier struct {
+	Logger       zerolog.Logger
+	WatchService service.WatchService
+
+	// How often to look for ended Runs.
+	Interval time.Duration
+
+	// Like "smtp.example.com:587".
+	SMTPAddr string
+	// Sends without authentication if empty.
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+
+	// Where the web UI is served for links to Runs.
+	BaseURL string
+}
+
+var watchTemplate = template.Must(template.New("watch").Funcs(template.FuncMap{
+	"time": func(t *time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+}).Parse(`{{range .Runs}}{{.ActionName}} {{.Status}} at {{time .FinishedAt}}
+  {{$.BaseURL}}/run/{{.NomadJobID}}
+{{end}}
+You receive this because you watch {{with .Watch.RunId}}Run {{.}}{{else}}action {{.Watch.ActionName}}{{end}}.
+Unwatch it at {{.BaseURL}}/watch
+`))
+
+func (self *WatchNotifier) Start(ctx context.Context) error {
+	self.Logger.Info().Dur("interval", self.Interval).Msg("Starting")
+
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := self.notify(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *WatchNotifier) notify() error {
+	now := time.Now().UTC()
+
+	watches, err := self.WatchService.GetNotifiable()
+	if err != nil {
+		return err
+	}
+
+	for _, watch := range watches {
+		runs, err := self.WatchService.GetEndedRuns(watch, now)
+		if err != nil {
+			return err
+		}
+
+		if len(runs) > 0 {
+			if err := self.send(watch, runs, now); err != nil {
+				// Try again next interval, the mail server may be unavailable for a while.
+				self.Logger.Err(err).Stringer("watch", watch.ID).Msg("Could not send watch notification")
+				continue
+			}
+			self.Logger.Debug().Stringer("watch", watch.ID).Int("runs", len(runs)).Msg("Sent watch notification")
+		}
+
+		if err := self.WatchService.MarkNotified(watch, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *WatchNotifier) send(watch domain.Watch, runs []domain.WatchedRun, now time.Time) error {
+	body := &bytes.Buffer{}
+	if err := watchTemplate.Execute(body, struct {
+		Watch   domain.Watch
+		Runs    []domain.WatchedRun
+		BaseURL string
+	}{watch, runs, strings.TrimSuffix(self.BaseURL, "/")}); err != nil {
+		return errors.WithMessage(err, "Could not render watch notification")
+	}
+
+	subject := fmt.Sprintf("Cicero: %d watched Runs ended", len(runs))
+	if len(runs) == 1 {
+		subject = fmt.Sprintf("Cicero: Run of %s %s", runs[0].ActionName, runs[0].Status)
+	}
+
+	return errors.WithMessagef(
+		sendMail(self.SMTPAddr, self.SMTPUser, self.SMTPPassword, self.From, *watch.Email, subject, now, body.String()),
+		"Could not send watch notification to %q", *watch.Email,
+	)
+}
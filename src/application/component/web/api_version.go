@@ -0,0 +1,122 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The API is versioned by the segment after /api/, like /api/v1/fact,
+// so that the routes of a new version can be added next to those of the old one.
+// Requests to paths without a version, like /api/fact, are served by the version
+// given in the `Cicero-Api-Version` header or `apiVersionDefault`
+// for clients that predate versioning, but they are deprecated.
+type apiVersion struct {
+	Name string
+	// Responses tell clients to move on in a `Deprecation` header.
+	Deprecated bool
+	// When a deprecated version is removed, sent in a `Sunset` header if not zero.
+	Sunset time.Time
+}
+
+// Latest last.
+var apiVersions = []apiVersion{
+	{Name: "v1"},
+}
+
+const (
+	apiVersionDefault = "v1"
+	apiVersionHeader  = "Cicero-Api-Version"
+)
+
+func getApiVersion(name string) *apiVersion {
+	for i := range apiVersions {
+		if apiVersions[i].Name == name {
+			return &apiVersions[i]
+		}
+	}
+	return nil
+}
+
+// Whether the path segment looks like a version, known or not.
+func isApiVersion(segment string) bool {
+	return len(segment) > 1 && segment[0] == 'v' &&
+		strings.Trim(segment[1:], "0123456789") == ""
+}
+
+// Returns the API path without its version, unchanged if it has none,
+// so that the version does not matter for things like scopes.
+func unversionedApiPath(path string) string {
+	rest := strings.TrimPrefix(path, "/api/")
+	if rest == path {
+		return path
+	}
+	if segment, tail, _ := strings.Cut(rest, "/"); isApiVersion(segment) {
+		return "/api/" + tail
+	}
+	return path
+}
+
+// Serves requests to unversioned API paths by the negotiated version
+// and tells clients about deprecations.
+// Responses from the API carry the version that served them in the `Cicero-Api-Version` header.
+func (self *Web) apiVersioning(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.EscapedPath()
+		// see the /_dispatch/method/{method}/ route
+		if rest := strings.TrimPrefix(path, "/_dispatch/method/"); rest != path {
+			_, path, _ = strings.Cut(rest, "/")
+			path = "/" + path
+		}
+
+		rest := strings.TrimPrefix(path, "/api/")
+		if rest == path {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		name, _, _ := strings.Cut(rest, "/")
+		versioned := isApiVersion(name)
+		if !versioned {
+			if name = req.Header.Get(apiVersionHeader); name == "" {
+				name = apiVersionDefault
+			}
+		}
+
+		version := getApiVersion(name)
+		if version == nil {
+			supported := make([]string, len(apiVersions))
+			for i, version := range apiVersions {
+				supported[i] = version.Name
+			}
+			self.Error(w, HandlerError{
+				errors.Errorf("Unknown API version %q, supported are %s", name, strings.Join(supported, ", ")),
+				http.StatusNotFound,
+			})
+			return
+		}
+
+		if !versioned {
+			req.URL.Path = strings.Replace(req.URL.Path, "/api/", "/api/"+version.Name+"/", 1)
+			if req.URL.RawPath != "" {
+				req.URL.RawPath = strings.Replace(req.URL.RawPath, "/api/", "/api/"+version.Name+"/", 1)
+			}
+		}
+
+		w.Header().Set(apiVersionHeader, version.Name)
+
+		latest := apiVersions[len(apiVersions)-1]
+		if !versioned || version.Deprecated {
+			w.Header().Set("Deprecation", "true")
+			successor := "/api/" + latest.Name + "/" + strings.TrimPrefix(unversionedApiPath("/api/"+rest), "/api/")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		if version.Deprecated && !version.Sunset.IsZero() {
+			w.Header().Set("Sunset", version.Sunset.UTC().Format(http.TimeFormat))
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/application/component/web/auth"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Records requests that may change something in the audit log.
+// Executing commands in a Run's tasks is recorded as well.
+func (self *Web) auditLog(next http.Handler) http.Handler {
+	if self.AuditLogService == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method, path := req.Method, req.URL.Path
+		// see the /_dispatch/method/{method}/ route
+		if rest := strings.TrimPrefix(path, "/_dispatch/method/"); rest != path {
+			method, path, _ = strings.Cut(rest, "/")
+			path = "/" + path
+		}
+
+		if (method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions) &&
+			!strings.HasSuffix(requiredScope(req), ":exec") {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, req)
+
+		entry := domain.AuditLogEntry{
+			Method:     strings.ToUpper(method),
+			Path:       path,
+			Query:      req.URL.RawQuery,
+			Status:     recorder.status,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if ip := clientIP(req); ip != "" {
+			entry.ClientIP = &ip
+		}
+		if req.ContentLength >= 0 {
+			entry.BodySize = &req.ContentLength
+		}
+		if identity := auth.IdentityFromContext(req.Context()); identity != nil {
+			entry.Identity = &identity.Name
+			entry.AuthMethod = &identity.Method
+			entry.ApiTokenId = identity.TokenId
+		}
+
+		if err := self.AuditLogService.Save(&entry); err != nil {
+			self.Logger.Err(err).Str("method", entry.Method).Str("path", entry.Path).Msg("Could not record request in audit log")
+		}
+	})
+}
+
+// Remembers the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (self *statusRecorder) WriteHeader(status int) {
+	if !self.wroteHeader {
+		self.status = status
+		self.wroteHeader = true
+	}
+	self.ResponseWriter.WriteHeader(status)
+}
+
+func (self *statusRecorder) Write(b []byte) (int, error) {
+	self.wroteHeader = true
+	return self.ResponseWriter.Write(b)
+}
+
+func (self *statusRecorder) Flush() {
+	if flusher, ok := self.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Needed to upgrade to WebSocket connections.
+func (self *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := self.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && !self.wroteHeader {
+		self.status = http.StatusSwitchingProtocols
+		self.wroteHeader = true
+	}
+	return conn, rw, err
+}
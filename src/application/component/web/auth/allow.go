@@ -0,0 +1,18 @@
+package auth
+
+// Names of identities that may do something.
+// The name "*" allows everyone who is authenticated.
+// An empty list allows no one.
+type Allowlist []string
+
+func (self Allowlist) Allows(identity *Identity) bool {
+	if identity == nil {
+		return false
+	}
+	for _, name := range self {
+		if name == "*" || name == identity.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Who made a request and how they proved it.
+type Identity struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	// What the identity may do, see `HasScope()`.
+	// Nil means everything.
+	Scopes []string `json:"scopes,omitempty"`
+	// Set if authenticated with an API token.
+	TokenId *uuid.UUID `json:"token_id,omitempty"`
+}
+
+// Scopes look like "facts:write". The part after the colon
+// may be "*" to match any and the scope "*" matches all.
+func (self *Identity) HasScope(scope string) bool {
+	if self.Scopes == nil {
+		return true
+	}
+
+	resource, _, _ := strings.Cut(scope, ":")
+	for _, s := range self.Scopes {
+		if s == "*" || s == scope || s == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+type Authenticator interface {
+	// Name of the method as used in the configuration.
+	Name() string
+
+	// Returns `nil, nil` if the request carries no credentials
+	// for this method so that the next one in the chain can be tried.
+	// Returns an error if credentials are present but invalid.
+	Authenticate(*http.Request) (*Identity, error)
+}
+
+// Implemented by authenticators that can tell clients
+// how to authenticate in a `WWW-Authenticate` header.
+type Challenger interface {
+	Challenge() string
+}
+
+// Tries authenticators in order until one of them identifies the request.
+// An empty chain lets all requests through.
+type Chain []Authenticator
+
+// Paths starting with any of the public prefixes are not authenticated.
+func (self Chain) Handler(next http.Handler, publicPrefixes ...string) http.Handler {
+	if len(self) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, prefix := range publicPrefixes {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				next.ServeHTTP(w, req)
+				return
+			}
+		}
+
+		for _, authenticator := range self {
+			if identity, err := authenticator.Authenticate(req); err != nil {
+				self.unauthorized(w, err.Error())
+				return
+			} else if identity != nil {
+				identity.Method = authenticator.Name()
+				next.ServeHTTP(w, req.WithContext(WithIdentity(req.Context(), identity)))
+				return
+			}
+		}
+
+		self.unauthorized(w, "No credentials given")
+	})
+}
+
+func (self Chain) unauthorized(w http.ResponseWriter, msg string) {
+	for _, authenticator := range self {
+		if challenger, ok := authenticator.(Challenger); ok {
+			w.Header().Add("WWW-Authenticate", challenger.Challenge())
+		}
+	}
+	http.Error(w, msg, http.StatusUnauthorized)
+}
+
+type identityContextKey struct{}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// Returns nil if the request was not authenticated.
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestChain(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenHash := sha256.Sum256([]byte("token"))
+
+	chain, err := Config{
+		BasicFile:  writeFile(t, "alice:"+string(hash)+"\n"),
+		BearerFile: writeFile(t, "# comment\nbot:"+hex.EncodeToString(tokenHash[:])+"\n"),
+		Tokens: &Token{Prefix: "cicero_", Verify: func(token string) (*Identity, error) {
+			if token == "cicero_valid" {
+				return &Identity{Name: "ci", Scopes: []string{"facts:write"}}, nil
+			}
+			return nil, nil
+		}},
+	}.Chain([]string{"basic", "token", "bearer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var identity *Identity
+	handler := chain.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		identity = IdentityFromContext(req.Context())
+	}), "/static/")
+
+	for _, c := range []struct {
+		name     string
+		path     string
+		modify   func(*http.Request)
+		status   int
+		identity *Identity
+	}{
+		{"none", "/", func(*http.Request) {}, http.StatusUnauthorized, nil},
+		{"public", "/static/x", func(*http.Request) {}, http.StatusOK, nil},
+		{"basic", "/", func(req *http.Request) { req.SetBasicAuth("alice", "secret") }, http.StatusOK, &Identity{Name: "alice", Method: "basic"}},
+		{"basic wrong", "/", func(req *http.Request) { req.SetBasicAuth("alice", "wrong") }, http.StatusUnauthorized, nil},
+		{"bearer", "/", func(req *http.Request) { req.Header.Set("Authorization", "Bearer token") }, http.StatusOK, &Identity{Name: "bot", Method: "bearer"}},
+		{"bearer wrong", "/", func(req *http.Request) { req.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized, nil},
+		{"token", "/", func(req *http.Request) { req.Header.Set("Authorization", "Bearer cicero_valid") }, http.StatusOK, &Identity{Name: "ci", Method: "token", Scopes: []string{"facts:write"}}},
+		{"token wrong", "/", func(req *http.Request) { req.Header.Set("Authorization", "Bearer cicero_wrong") }, http.StatusUnauthorized, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			identity = nil
+
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			c.modify(req)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != c.status {
+				t.Fatal(c.status, w.Code)
+			}
+			if c.status == http.StatusUnauthorized && len(w.Header().Values("WWW-Authenticate")) != 3 {
+				t.Fatal(w.Header())
+			}
+			if !reflect.DeepEqual(identity, c.identity) {
+				t.Fatal(c.identity, identity)
+			}
+		})
+	}
+}
+
+func TestChainBearerFirst(t *testing.T) {
+	tokenHash := sha256.Sum256([]byte("token"))
+
+	config := Config{
+		BearerFile:   writeFile(t, "bot:"+hex.EncodeToString(tokenHash[:])+"\n"),
+		OIDCIssuer:   "http://127.0.0.1:0/",
+		OIDCClientID: "cicero",
+		Tokens: &Token{Prefix: "cicero_", Verify: func(token string) (*Identity, error) {
+			if token == "cicero_valid" {
+				return &Identity{Name: "ci"}, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	for _, c := range []struct {
+		methods []string
+		token   string
+		status  int
+		body    string
+	}{
+		{[]string{"bearer", "token"}, "cicero_valid", http.StatusOK, ""},
+		{[]string{"bearer", "token"}, "cicero_wrong", http.StatusUnauthorized, "Invalid or expired token"},
+		{[]string{"bearer", "token"}, "wrong", http.StatusUnauthorized, "Invalid bearer token"},
+		{[]string{"bearer", "oidc"}, "a.b.c", http.StatusUnauthorized, "Invalid ID token"},
+		{[]string{"bearer", "oidc"}, "token", http.StatusOK, ""},
+	} {
+		t.Run(strings.Join(c.methods, ",")+" "+c.token, func(t *testing.T) {
+			chain, err := config.Chain(c.methods)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := chain.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+c.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != c.status {
+				t.Fatal(c.status, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), c.body) {
+				t.Fatal(c.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	sign := func(claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	oidc := NewOIDC(server.URL+"/", "cicero")
+	exp := time.Now().Add(time.Hour).Unix()
+
+	for _, c := range []struct {
+		name   string
+		claims map[string]interface{}
+		valid  bool
+	}{
+		{"valid", map[string]interface{}{"iss": server.URL, "aud": "cicero", "exp": exp, "sub": "1", "email": "a@b.c"}, true},
+		{"audience array", map[string]interface{}{"iss": server.URL, "aud": []string{"x", "cicero"}, "exp": exp, "sub": "1"}, true},
+		{"wrong issuer", map[string]interface{}{"iss": "other", "aud": "cicero", "exp": exp}, false},
+		{"wrong audience", map[string]interface{}{"iss": server.URL, "aud": "other", "exp": exp}, false},
+		{"expired", map[string]interface{}{"iss": server.URL, "aud": "cicero", "exp": time.Now().Add(-time.Minute).Unix()}, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+sign(c.claims))
+
+			identity, err := oidc.Authenticate(req)
+			if c.valid && (err != nil || identity == nil) {
+				t.Fatal(identity, err)
+			}
+			if !c.valid && err == nil {
+				t.Fatal(identity)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer opaque")
+	if identity, err := oidc.Authenticate(req); identity != nil || err != nil {
+		t.Fatal("opaque tokens should be left to other authenticators", identity, err)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	alice := &Identity{Name: "alice"}
+	bob := &Identity{Name: "bob"}
+
+	for _, c := range []struct {
+		list     Allowlist
+		identity *Identity
+		allowed  bool
+	}{
+		{Allowlist{}, alice, false},
+		{Allowlist{"alice"}, alice, true},
+		{Allowlist{"alice"}, bob, false},
+		{Allowlist{"*"}, bob, true},
+		{Allowlist{"*"}, nil, false},
+	} {
+		if allowed := c.list.Allows(c.identity); allowed != c.allowed {
+			t.Error(c.list, c.identity, allowed)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	for _, c := range []struct {
+		scopes []string
+		scope  string
+		has    bool
+	}{
+		{nil, "facts:write", true},
+		{[]string{}, "facts:read", false},
+		{[]string{"facts:write"}, "facts:write", true},
+		{[]string{"facts:write"}, "facts:read", false},
+		{[]string{"facts:*"}, "facts:read", true},
+		{[]string{"facts:*"}, "runs:read", false},
+		{[]string{"*"}, "runs:exec", true},
+	} {
+		identity := &Identity{Scopes: c.scopes}
+		if has := identity.HasScope(c.scope); has != c.has {
+			t.Error(strings.Join(c.scopes, ","), c.scope, has)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HTTP basic authentication against bcrypt password hashes.
+type Basic struct {
+	users map[string][]byte
+}
+
+// Reads a file with one `user:bcrypt-hash` per line
+// as written by `htpasswd -B`.
+func NewBasicFromFile(path string) (*Basic, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not open basic auth file %q", path)
+	}
+	defer file.Close()
+
+	basic := Basic{users: map[string][]byte{}}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		user, hash, found := strings.Cut(text, ":")
+		if !found {
+			return nil, errors.Errorf("Malformed line %d in basic auth file %q", line, path)
+		}
+		basic.users[user] = []byte(hash)
+	}
+
+	return &basic, scanner.Err()
+}
+
+func (self *Basic) Name() string {
+	return "basic"
+}
+
+func (self *Basic) Challenge() string {
+	return `Basic realm="cicero"`
+}
+
+func (self *Basic) Authenticate(req *http.Request) (*Identity, error) {
+	user, password, ok := req.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	hash, exists := self.users[user]
+	if !exists {
+		return nil, errors.New("Invalid user or password")
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return nil, errors.New("Invalid user or password")
+	}
+
+	return &Identity{Name: user}, nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Returns the token of a request's `Authorization: Bearer` header
+// or an empty string if there is none.
+func BearerToken(req *http.Request) string {
+	const prefix = "Bearer "
+	header := req.Header.Get("Authorization")
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// Static bearer tokens, identified by the SHA-256 hash of the token.
+type Bearer struct {
+	// token hash -> name
+	tokens map[[sha256.Size]byte]string
+	// Prefix of the tokens managed through the API, if any.
+	tokenPrefix string
+}
+
+// Reads a file with one `name:sha256-hex-of-token` per line.
+func NewBearerFromFile(path string) (*Bearer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not open bearer token file %q", path)
+	}
+	defer file.Close()
+
+	bearer := Bearer{tokens: map[[sha256.Size]byte]string{}}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, hashHex, found := strings.Cut(text, ":")
+		if !found {
+			return nil, errors.Errorf("Malformed line %d in bearer token file %q", line, path)
+		}
+
+		var hash [sha256.Size]byte
+		if n, err := hex.Decode(hash[:], []byte(hashHex)); err != nil || n != len(hash) {
+			return nil, errors.Errorf("Invalid SHA-256 hash on line %d in bearer token file %q", line, path)
+		}
+		bearer.tokens[hash] = name
+	}
+
+	return &bearer, scanner.Err()
+}
+
+func (self *Bearer) Name() string {
+	return "bearer"
+}
+
+func (self *Bearer) Challenge() string {
+	return `Bearer realm="cicero"`
+}
+
+func (self *Bearer) Authenticate(req *http.Request) (*Identity, error) {
+	token := BearerToken(req)
+	if token == "" {
+		return nil, nil
+	}
+
+	// Looking up the hash instead of the token itself
+	// does not leak anything useful through timing.
+	if name, exists := self.tokens[sha256.Sum256([]byte(token))]; exists {
+		return &Identity{Name: name}, nil
+	}
+
+	// Tokens for other authenticators are left to them
+	// so that they may come after this one in the chain.
+	if strings.Count(token, ".") == 2 || (self.tokenPrefix != "" && strings.HasPrefix(token, self.tokenPrefix)) {
+		return nil, nil
+	}
+
+	return nil, errors.New("Invalid bearer token")
+}
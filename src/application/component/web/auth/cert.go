@@ -0,0 +1,18 @@
+package auth
+
+import "net/http"
+
+// Identifies clients by the common name of their TLS client certificate.
+// The certificate must already have been verified by the TLS server.
+type ClientCert struct{}
+
+func (self ClientCert) Name() string {
+	return "cert"
+}
+
+func (self ClientCert) Authenticate(req *http.Request) (*Identity, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	return &Identity{Name: req.TLS.VerifiedChains[0][0].Subject.CommonName}, nil
+}
@@ -0,0 +1,59 @@
+package auth
+
+import "github.com/pkg/errors"
+
+type Config struct {
+	BasicFile    string
+	BearerFile   string
+	OIDCIssuer   string
+	OIDCClientID string
+	Tokens       *Token
+}
+
+// Builds a chain from the names of authentication methods
+// in the order in which they should be tried.
+func (self Config) Chain(methods []string) (Chain, error) {
+	chain := Chain{}
+
+	for _, method := range methods {
+		switch method {
+		case "basic":
+			if self.BasicFile == "" {
+				return nil, errors.New("Basic authentication requires a file with users")
+			}
+			if basic, err := NewBasicFromFile(self.BasicFile); err != nil {
+				return nil, err
+			} else {
+				chain = append(chain, basic)
+			}
+		case "bearer":
+			if self.BearerFile == "" {
+				return nil, errors.New("Bearer authentication requires a file with tokens")
+			}
+			if bearer, err := NewBearerFromFile(self.BearerFile); err != nil {
+				return nil, err
+			} else {
+				if self.Tokens != nil {
+					bearer.tokenPrefix = self.Tokens.Prefix
+				}
+				chain = append(chain, bearer)
+			}
+		case "token":
+			if self.Tokens == nil {
+				return nil, errors.New("Token authentication requires a token store")
+			}
+			chain = append(chain, self.Tokens)
+		case "cert":
+			chain = append(chain, ClientCert{})
+		case "oidc":
+			if self.OIDCIssuer == "" || self.OIDCClientID == "" {
+				return nil, errors.New("OIDC authentication requires an issuer and client ID")
+			}
+			chain = append(chain, NewOIDC(self.OIDCIssuer, self.OIDCClientID))
+		default:
+			return nil, errors.Errorf("Unknown authentication method: %s", method)
+		}
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Verifies OpenID Connect ID tokens given as bearer tokens.
+// Only RSA signatures are supported which is what most providers use.
+type OIDC struct {
+	Issuer   string
+	ClientID string
+	Client   *http.Client
+
+	mutex       sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+func NewOIDC(issuer, clientID string) *OIDC {
+	return &OIDC{
+		Issuer:   strings.TrimSuffix(issuer, "/"),
+		ClientID: clientID,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (self *OIDC) Name() string {
+	return "oidc"
+}
+
+func (self *OIDC) Challenge() string {
+	return `Bearer realm="cicero"`
+}
+
+var oidcHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+type oidcClaims struct {
+	Issuer            string       `json:"iss"`
+	Subject           string       `json:"sub"`
+	Audience          oidcAudience `json:"aud"`
+	Expiry            int64        `json:"exp"`
+	NotBefore         int64        `json:"nbf"`
+	Email             string       `json:"email"`
+	PreferredUsername string       `json:"preferred_username"`
+}
+
+// The audience claim may be a single string or an array of strings.
+type oidcAudience []string
+
+func (self *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*self = oidcAudience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(self))
+}
+
+func (self *OIDC) Authenticate(req *http.Request) (*Identity, error) {
+	token := BearerToken(req)
+	// Opaque tokens are left to other authenticators.
+	if strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+
+	claims, err := self.verify(token, time.Now())
+	if err != nil {
+		return nil, errors.WithMessage(err, "Invalid ID token")
+	}
+
+	identity := Identity{Name: claims.Subject}
+	switch {
+	case claims.Email != "":
+		identity.Name = claims.Email
+	case claims.PreferredUsername != "":
+		identity.Name = claims.PreferredUsername
+	}
+	return &identity, nil
+}
+
+func (self *OIDC) verify(token string, now time.Time) (*oidcClaims, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, errors.WithMessage(err, "Could not decode header")
+	}
+
+	hash, supported := oidcHashes[header.Alg]
+	if !supported {
+		return nil, errors.Errorf("Unsupported signature algorithm %q", header.Alg)
+	}
+
+	key, err := self.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not decode signature")
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, hash, hasher.Sum(nil), signature); err != nil {
+		return nil, errors.WithMessage(err, "Signature does not match")
+	}
+
+	var claims oidcClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errors.WithMessage(err, "Could not decode claims")
+	}
+
+	switch {
+	case claims.Issuer != self.Issuer:
+		return nil, errors.Errorf("Unexpected issuer %q", claims.Issuer)
+	case !claims.Audience.contains(self.ClientID):
+		return nil, errors.Errorf("Token is not meant for client %q", self.ClientID)
+	case claims.Expiry == 0 || now.After(time.Unix(claims.Expiry, 0)):
+		return nil, errors.New("Token has expired")
+	case claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0)):
+		return nil, errors.New("Token is not valid yet")
+	}
+
+	return &claims, nil
+}
+
+func (self oidcAudience) contains(audience string) bool {
+	for _, aud := range self {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeJWTPart(part string, target interface{}) error {
+	if data, err := base64.RawURLEncoding.DecodeString(part); err != nil {
+		return err
+	} else {
+		return json.Unmarshal(data, target)
+	}
+}
+
+// Returns the key with the given ID, fetching the provider's keys
+// if it is unknown. Keys are fetched at most once per minute.
+func (self *OIDC) key(kid string) (*rsa.PublicKey, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if key, found := self.keys[kid]; found {
+		return key, nil
+	}
+
+	if time.Since(self.keysFetched) < time.Minute {
+		return nil, errors.Errorf("Unknown key ID %q", kid)
+	}
+
+	keys, err := self.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+	self.keys = keys
+	self.keysFetched = time.Now()
+
+	if key, found := self.keys[kid]; found {
+		return key, nil
+	}
+	return nil, errors.Errorf("Unknown key ID %q", kid)
+}
+
+func (self *OIDC) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := self.getJSON(self.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, errors.WithMessage(err, "Could not discover OpenID configuration")
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := self.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, errors.WithMessage(err, "Could not fetch JSON web keys")
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid modulus of key %q", jwk.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid exponent of key %q", jwk.Kid)
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+func (self *OIDC) getJSON(url string, target interface{}) error {
+	res, err := self.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("Got status %d from %s", res.StatusCode, url)
+	}
+
+	return json.NewDecoder(res.Body).Decode(target)
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Bearer tokens managed through the API.
+// Only tokens with the prefix are considered
+// so that static bearer tokens can be tried as well.
+type Token struct {
+	Prefix string
+	// Returns nil if the token is not valid.
+	Verify func(token string) (*Identity, error)
+}
+
+func (self *Token) Name() string {
+	return "token"
+}
+
+func (self *Token) Challenge() string {
+	return `Bearer realm="cicero"`
+}
+
+func (self *Token) Authenticate(req *http.Request) (*Identity, error) {
+	token := BearerToken(req)
+	if token == "" || !strings.HasPrefix(token, self.Prefix) {
+		return nil, nil
+	}
+
+	if identity, err := self.Verify(token); err != nil {
+		return nil, errors.WithMessage(err, "Could not verify token")
+	} else if identity != nil {
+		return identity, nil
+	}
+
+	return nil, errors.New("Invalid or expired token")
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// A flat badge in the style of https://shields.io.
+var badgeTemplate = template.Must(template.New("badge").Funcs(template.FuncMap{
+	"xml": func(s string) (string, error) {
+		buf := &bytes.Buffer{}
+		err := xml.EscapeText(buf, []byte(s))
+		return buf.String(), err
+	},
+}).Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{xml .Label}}: {{xml .Message}}">
+	<title>{{xml .Label}}: {{xml .Message}}</title>
+	<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+	<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+	<g clip-path="url(#r)">
+		<rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+		<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+		<rect width="{{.Width}}" height="20" fill="url(#s)"/>
+	</g>
+	<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+		<text x="{{.LabelX}}" y="14">{{xml .Label}}</text>
+		<text x="{{.MessageX}}" y="14">{{xml .Message}}</text>
+	</g>
+</svg>
+`))
+
+type badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// Approximate width of a character in Verdana at 11px.
+const badgeCharWidth = 7
+
+// Horizontal padding around each text.
+const badgePadding = 10
+
+func (self badge) LabelWidth() int {
+	return utf8.RuneCountInString(self.Label)*badgeCharWidth + badgePadding
+}
+
+func (self badge) MessageWidth() int {
+	return utf8.RuneCountInString(self.Message)*badgeCharWidth + badgePadding
+}
+
+func (self badge) Width() int {
+	return self.LabelWidth() + self.MessageWidth()
+}
+
+func (self badge) LabelX() int {
+	return self.LabelWidth() / 2
+}
+
+func (self badge) MessageX() int {
+	return self.LabelWidth() + self.MessageWidth()/2
+}
+
+// Describes the status and duration of the Run, which may be nil.
+func runBadge(label string, run *domain.Run) badge {
+	if run == nil {
+		return badge{label, "no runs", "#9f9f9f"}
+	}
+
+	message := run.Status.String()
+	if run.FinishedAt != nil {
+		message += " " + run.FinishedAt.Sub(run.CreatedAt).Round(time.Second).String()
+	}
+
+	switch run.Status {
+	case domain.RunStatusSucceeded:
+		return badge{label, message, "#4c1"}
+	case domain.RunStatusFailed:
+		return badge{label, message, "#e05d44"}
+	case domain.RunStatusRunning:
+		return badge{label, message, "#007ec6"}
+	default:
+		return badge{label, message, "#9f9f9f"}
+	}
+}
+
+func (self badge) render() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := badgeTemplate.Execute(buf, self)
+	return buf.Bytes(), err
+}
+
+// Serves the badge with an ETag so that clients only
+// download it again after the status changed.
+func (self *Web) badge(w http.ResponseWriter, req *http.Request, b badge) {
+	svg, err := b.render()
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	etag := contentETag(svg)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if notModified(req, etag, time.Time{}) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(svg); err != nil {
+		self.Logger.Err(err).Msg("Could not write badge")
+	}
+}
+
+// Badges are embedded in places that cannot authenticate, like READMEs.
+func isBadgeRequest(req *http.Request) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		strings.HasPrefix(unversionedApiPath(req.URL.Path), "/api/action/") &&
+		strings.HasSuffix(req.URL.Path, "/badge.svg")
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Returns an ETag of the content.
+func contentETag(content []byte) string {
+	hash := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// Whether the client has the representation with the given ETag or last modification already.
+// If-Modified-Since is ignored if If-None-Match is given
+// or the time of the last modification is not known.
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if header := req.Header.Get("If-None-Match"); header != "" {
+		for _, match := range strings.Split(header, ",") {
+			// Our ETags are strong but clients may compare weakly.
+			match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+			if match == etag || match == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have no fractions of seconds.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// Like `json()` but with an ETag of the response and, if not zero,
+// the time of the last modification, so that clients that poll
+// can tell that nothing changed without downloading it again.
+func (self *Web) jsonConditional(w http.ResponseWriter, req *http.Request, obj interface{}, lastModified time.Time) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(obj); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	etag := contentETag(buf.Bytes())
+	w.Header().Set("ETag", etag)
+	// Clients must ask whether it changed every time.
+	w.Header().Set("Cache-Control", "no-cache")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(req, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		self.Logger.Err(err).Msg("Could not write response")
+	}
+}
+
+// Like `jsonList()` but conditional like `jsonConditional()`.
+func (self *Web) jsonListConditional(w http.ResponseWriter, req *http.Request, list interface{}, lastModified time.Time) {
+	if selected, err := getFields(req).Select(list); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.jsonConditional(w, req, selected, lastModified)
+	}
+}
+
+// Facts do not change after they were created.
+func factsLastModified(facts ...domain.Fact) (lastModified time.Time) {
+	for _, fact := range facts {
+		if fact.CreatedAt.After(lastModified) {
+			lastModified = fact.CreatedAt
+		}
+	}
+	return
+}
+
+// Running Runs change without noting when so it is only known for finished ones.
+// Returns zero if any of the Runs are running.
+// Annotations, timing, and usage are saved after a Run ended,
+// see `runsLastModifiedOrMeasured()`.
+func runsLastModified(runs ...domain.Run) (lastModified time.Time) {
+	for _, run := range runs {
+		if run.FinishedAt == nil {
+			return time.Time{}
+		}
+		for _, t := range []*time.Time{&run.CreatedAt, run.FinishedAt, run.NomadJobGCedAt} {
+			if t != nil && t.After(lastModified) {
+				lastModified = *t
+			}
+		}
+	}
+	return
+}
+
+// Like `runsLastModified()` but also takes into account
+// when the Runs' annotations, timing, or usage were saved.
+func (self *Web) runsLastModifiedOrMeasured(runs ...domain.Run) (time.Time, error) {
+	lastModified := runsLastModified(runs...)
+	if lastModified.IsZero() {
+		return lastModified, nil
+	}
+
+	ids := make([]uuid.UUID, len(runs))
+	for i, run := range runs {
+		ids[i] = run.NomadJobID
+	}
+
+	if measuredAt, err := self.RunService.GetLastMeasuredAt(ids...); err != nil {
+		return time.Time{}, err
+	} else if measuredAt != nil && measuredAt.After(lastModified) {
+		lastModified = *measuredAt
+	}
+	return lastModified, nil
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// Only allows connections from the same origin as the page.
+var execUpgrader = websocket.Upgrader{}
+
+// Translates between a websocket speaking the same protocol
+// as Nomad's exec endpoint and the streams of an exec session.
+type execBridge struct {
+	conn   *websocket.Conn
+	cancel context.CancelFunc
+
+	writeMutex sync.Mutex
+
+	stdin        *io.PipeReader
+	stdinWriter  *io.PipeWriter
+	terminalSize chan nomad.TerminalSize
+}
+
+func newExecBridge(conn *websocket.Conn, cancel context.CancelFunc) *execBridge {
+	stdin, stdinWriter := io.Pipe()
+	self := &execBridge{
+		conn:         conn,
+		cancel:       cancel,
+		stdin:        stdin,
+		stdinWriter:  stdinWriter,
+		terminalSize: make(chan nomad.TerminalSize, 1),
+	}
+	go self.read()
+	return self
+}
+
+// Reads input from the client until it goes away,
+// which cancels the exec session.
+func (self *execBridge) read() {
+	defer self.cancel()
+	defer self.stdinWriter.Close()
+
+	for {
+		var input nomad.ExecStreamingInput
+		if err := self.conn.ReadJSON(&input); err != nil {
+			return
+		}
+
+		switch {
+		case input.Stdin != nil && input.Stdin.Close:
+			self.stdinWriter.Close()
+		case input.Stdin != nil:
+			if _, err := self.stdinWriter.Write(input.Stdin.Data); err != nil {
+				return
+			}
+		case input.TTYSize != nil:
+			// Only the latest size matters.
+			select {
+			case <-self.terminalSize:
+			default:
+			}
+			self.terminalSize <- *input.TTYSize
+		}
+	}
+}
+
+func (self *execBridge) write(output nomad.ExecStreamingOutput) error {
+	self.writeMutex.Lock()
+	defer self.writeMutex.Unlock()
+	return self.conn.WriteJSON(output)
+}
+
+type execBridgeWriter struct {
+	bridge *execBridge
+	stderr bool
+}
+
+func (self execBridgeWriter) Write(p []byte) (int, error) {
+	// The exec session reuses the buffer.
+	op := &nomad.ExecStreamingIOOperation{Data: append([]byte{}, p...)}
+
+	output := nomad.ExecStreamingOutput{}
+	if self.stderr {
+		output.Stderr = op
+	} else {
+		output.Stdout = op
+	}
+
+	if err := self.bridge.write(output); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (self *execBridge) stdout() io.Writer {
+	return execBridgeWriter{bridge: self}
+}
+
+func (self *execBridge) stderr() io.Writer {
+	return execBridgeWriter{bridge: self, stderr: true}
+}
+
+// Tells the client how the session ended.
+// Errors are sent as output to stderr.
+func (self *execBridge) exit(exitCode int, err error) {
+	if err != nil {
+		exitCode = -1
+		_, _ = self.stderr().Write([]byte(err.Error() + "\n"))
+	}
+
+	_ = self.write(nomad.ExecStreamingOutput{
+		Exited: true,
+		Result: &nomad.ExecStreamingExitResult{ExitCode: exitCode},
+	})
+
+	self.writeMutex.Lock()
+	defer self.writeMutex.Unlock()
+	_ = self.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
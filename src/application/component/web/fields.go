@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Top-level JSON fields of the items of a list to respond with
+// as given by the `fields` query parameter, like `?fields=id,status`,
+// so that heavy fields like fact values can be left out.
+// Nil means all fields.
+type responseFields map[string]bool
+
+func getFields(req *http.Request) responseFields {
+	var fields responseFields
+	for _, param := range req.URL.Query()["fields"] {
+		for _, field := range strings.Split(param, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			if fields == nil {
+				fields = responseFields{}
+			}
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+func (self responseFields) Has(field string) bool {
+	return self == nil || self[field]
+}
+
+// Returns the items of the list without the fields that were not asked for.
+func (self responseFields) Select(list interface{}) (interface{}, error) {
+	if self == nil {
+		return list, nil
+	}
+
+	encoded, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	// Raw so that the fields that are kept are not decoded.
+	items := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(encoded, &items); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		for field := range item {
+			if !self[field] {
+				delete(item, field)
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// Like `json()` for lists whose items only have the fields given by `getFields()`.
+func (self *Web) jsonList(w http.ResponseWriter, req *http.Request, list interface{}, status int) {
+	if selected, err := getFields(req).Select(list); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, selected, status)
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+// Wraps the tokens of JSON in spans with the classes
+// `key`, `string`, `number`, and `literal` for styling.
+// Everything else is escaped as is, so invalid JSON is shown as text.
+func highlightJson(source string) template.HTML {
+	html := &strings.Builder{}
+	span := func(class, text string) {
+		html.WriteString(`<span class="` + class + `">`)
+		html.WriteString(template.HTMLEscapeString(text))
+		html.WriteString(`</span>`)
+	}
+
+	for i := 0; i < len(source); {
+		switch c := source[i]; {
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(source) {
+				end++
+			}
+
+			class := "string"
+			if rest := strings.TrimLeftFunc(source[end:], unicode.IsSpace); strings.HasPrefix(rest, ":") {
+				class = "key"
+			}
+			span(class, source[i:end])
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(source) && strings.IndexByte("0123456789.eE+-", source[end]) != -1 {
+				end++
+			}
+			span("number", source[i:end])
+			i = end
+		case strings.HasPrefix(source[i:], "true"):
+			span("literal", "true")
+			i += len("true")
+		case strings.HasPrefix(source[i:], "false"):
+			span("literal", "false")
+			i += len("false")
+		case strings.HasPrefix(source[i:], "null"):
+			span("literal", "null")
+			i += len("null")
+		default:
+			html.WriteString(template.HTMLEscapeString(source[i : i+1]))
+			i++
+		}
+	}
+
+	return template.HTML(html.String())
+}
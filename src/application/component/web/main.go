@@ -2,10 +2,15 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/mail"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,6 +28,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/input-output-hk/cicero/src/application/component/web/apidoc"
+	"github.com/input-output-hk/cicero/src/application/component/web/auth"
 	"github.com/input-output-hk/cicero/src/application/service"
 	"github.com/input-output-hk/cicero/src/config"
 	"github.com/input-output-hk/cicero/src/domain"
@@ -40,11 +46,180 @@ type Web struct {
 	NomadEventService service.NomadEventService
 	EvaluationService service.EvaluationService
 	Db                config.PgxIface
+	Runtime           *config.RuntimeConfig
+	LogLevels         *config.LogLevels
+	ApiTokenService   service.ApiTokenService
+	CostService       service.CostService
+	RunTimingService  service.RunTimingService
+	SizingService     service.SizingService
+	QuotaService      service.QuotaService
+	DigestService     service.DigestService
+	WatchService      service.WatchService
+	RunMutexService   service.RunMutexService
+	// Runs of actions that need approval wait for it here.
+	RunApprovalService service.RunApprovalService
+	// Mints Nomad tokens for Runs of actions that need one.
+	RunNomadTokenService service.RunNomadTokenService
+	// Nil if Runs are not queued.
+	RunQueueService    service.RunQueueService
+	MaintenanceService service.MaintenanceService
+	EnvironmentService service.EnvironmentService
+	// Annotations of Runs made by the log matchers of their action.
+	RunAnnotationService service.RunAnnotationService
+	// Catalog of skeletons to write actions from.
+	ActionTemplateService service.ActionTemplateService
+	FactPublisherService  service.FactPublisherService
+	DatabaseStatsService  service.DatabaseStatsService
+	FactUsageService      service.FactUsageService
+	SavedQueryService     service.SavedQueryService
+	Auth                  auth.Chain
+	TLS                   TLS
+	// Who may execute commands in running Runs' tasks.
+	ExecAllowed auth.Allowlist
+	// Serves status badges of actions without authentication.
+	PublicBadges bool
+	// Whose X-Forwarded-* headers tell the client's address and URL.
+	TrustedProxies TrustedProxies
+	// Nil if Cicero has no seal key.
+	Unsealer *domain.Unsealer
+	// How the database schema compares to what this binary expects.
+	Schema config.SchemaStatus
+	// Why the database schema cannot be used, if so,
+	// in which case only requests that read are served.
+	SchemaError error
+	// Only serves requests that read, for example from a replica of the database.
+	ReadOnly bool
+	// Records requests that may change something if not nil.
+	AuditLogService service.AuditLogService
+	// Templates and static files, the embedded ones if nil.
+	Assets *Assets
+}
+
+// Serves HTTPS if a certificate is given.
+// Client certificates signed by ClientCA are verified
+// so that they can be used for authentication.
+type TLS struct {
+	Cert     string
+	Key      string
+	ClientCA string
+}
+
+func (self TLS) config() (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if self.ClientCA != "" {
+		pem, err := os.ReadFile(self.ClientCA)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Could not read client CA")
+		}
+
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("No certificates found in client CA file %q", self.ClientCA)
+		}
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
+
+// Maximum sizes in bytes of a fact's value and binary.
+// Zero means unlimited.
+type FactLimits struct {
+	Value  int64
+	Binary int64
+}
+
+type factLimitsContextKey struct{}
+
+// Overrides the fact limits for a request,
+// for example with those of its API token.
+func WithFactLimits(ctx context.Context, limits FactLimits) context.Context {
+	return context.WithValue(ctx, factLimitsContextKey{}, limits)
+}
+
+func (self *Web) factRedactions() util.Redactions {
+	if self.Runtime == nil {
+		return nil
+	}
+	return self.Runtime.Get().FactRedactions
+}
+
+// Hides sensitive parts of the fact's value
+// so that it can be shown to the client.
+func (self *Web) redactFact(fact *domain.Fact) *domain.Fact {
+	if fact == nil {
+		return nil
+	}
+	redacted := *fact
+	redacted.Value = self.factRedactions().Apply(fact.Value)
+	return &redacted
+}
+
+func (self *Web) redactFacts(facts []domain.Fact) []domain.Fact {
+	redacted := make([]domain.Fact, len(facts))
+	for i := range facts {
+		redacted[i] = *self.redactFact(&facts[i])
+	}
+	return redacted
+}
+
+// Counts reads of the facts in the background so that responses are not delayed.
+// Nothing is counted while writes are refused.
+func (self *Web) recordFactReads(req *http.Request, facts ...domain.Fact) {
+	if self.FactUsageService == nil || self.ReadOnly || self.SchemaError != nil || len(facts) == 0 {
+		return
+	}
+
+	identity := ""
+	if id := auth.IdentityFromContext(req.Context()); id != nil {
+		identity = id.Name
+	}
+
+	ids := make([]uuid.UUID, len(facts))
+	for i, fact := range facts {
+		ids[i] = fact.ID
+	}
+
+	go func() {
+		if err := self.FactUsageService.RecordReads(identity, ids); err != nil {
+			self.Logger.Err(err).Msg("Could not record reads of Facts")
+		}
+	}()
+}
+
+func (self *Web) redactFactsByName(facts map[string]domain.Fact) map[string]domain.Fact {
+	redacted := make(map[string]domain.Fact, len(facts))
+	for name, fact := range facts {
+		fact := fact
+		redacted[name] = *self.redactFact(&fact)
+	}
+	return redacted
+}
+
+func (self *Web) factLimits(req *http.Request) FactLimits {
+	if limits, ok := req.Context().Value(factLimitsContextKey{}).(FactLimits); ok {
+		return limits
+	}
+	if self.Runtime == nil {
+		return FactLimits{}
+	}
+	runtime := self.Runtime.Get()
+	return FactLimits{
+		Value:  runtime.FactValueLimit,
+		Binary: runtime.FactBinaryLimit,
+	}
 }
 
 func (self *Web) Start(ctx context.Context) error {
 	self.Logger.Info().Str("listen", self.Listen).Msg("Starting")
 
+	if self.Assets == nil {
+		self.Assets = EmbeddedAssets()
+	} else if self.Assets.reload {
+		self.Logger.Warn().Msg("Reloading templates and static files, do not use this in production")
+	}
+
 	muxRouter := mux.NewRouter().StrictSlash(true).UseEncodedPath()
 	muxRouter.NotFoundHandler = http.NotFoundHandler()
 
@@ -55,7 +230,17 @@ func (self *Web) Start(ctx context.Context) error {
 
 	// sorted alphabetically, please keep it this way
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/action/current/{name}",
+		"/api/v1/action/current/{name}/badge.svg",
+		self.ApiActionCurrentNameBadgeGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of an action", Value: "actionName"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, nil, "SVG image")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/action/current/{name}",
 		self.ApiActionCurrentNameGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of an action", Value: "actionName"}}),
@@ -65,7 +250,17 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/action/current",
+		"/api/v1/action/catalog",
+		self.ApiActionCatalogGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.ActionCatalogEntry{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/action/current",
 		self.ApiActionCurrentGet,
 		apidoc.BuildSwaggerDef(
 			nil,
@@ -75,7 +270,7 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/action/definition/{source}/{name}/{id}",
+		"/api/v1/action/definition/{source}/{name}/{id}",
 		self.ApiActionDefinitionSourceNameIdGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{
@@ -89,7 +284,7 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/action/definition/{source}",
+		"/api/v1/action/definition/{source}",
 		self.ApiActionDefinitionSourceGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "source", Description: "source of one or more action definitions", Value: "source"}}),
@@ -98,8 +293,28 @@ func (self *Web) Start(ctx context.Context) error {
 	); err != nil {
 		return err
 	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/action/export",
+		self.ApiActionExportGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.ActionExport{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/action/import",
+		self.ApiActionImportPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest([]domain.ActionExport{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.ActionImportChange{}, "OK")),
+	); err != nil {
+		return err
+	}
 	if _, err := r.AddRoute(http.MethodPost,
-		"/api/action/match",
+		"/api/v1/action/match",
 		self.ApiActionMatchPost,
 		apidoc.BuildSwaggerDef(
 			nil,
@@ -109,7 +324,7 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/action/{id}",
+		"/api/v1/action/{id}",
 		self.ApiActionIdGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of the action", Value: "UUID"}}),
@@ -118,8 +333,18 @@ func (self *Web) Start(ctx context.Context) error {
 	); err != nil {
 		return err
 	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/action/{id}/badge.svg",
+		self.ApiActionIdBadgeGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of the action", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, nil, "SVG image")),
+	); err != nil {
+		return err
+	}
 	if _, err := r.AddRoute(http.MethodPatch,
-		"/api/action/{id}",
+		"/api/v1/action/{id}",
 		self.ApiActionIdPatch,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of the action", Value: "UUID"}}),
@@ -128,8 +353,38 @@ func (self *Web) Start(ctx context.Context) error {
 	); err != nil {
 		return err
 	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/action/{id}/simulate",
+		self.ApiActionIdSimulatePost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of the action", Value: "UUID"}}),
+			apidoc.BuildBodyRequest(apiActionIdSimulateBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiActionIdSimulateResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/action/{id}/sizing",
+		self.ApiActionIdSizingGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of the action", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiActionIdSizingResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/action/{id}/timing",
+		self.ApiActionIdTimingGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of the action", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunTimingAggregate{}, "OK")),
+	); err != nil {
+		return err
+	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/action",
+		"/api/v1/action",
 		self.ApiActionGet,
 		apidoc.BuildSwaggerDef(
 			nil,
@@ -139,7 +394,7 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodPost,
-		"/api/action",
+		"/api/v1/action",
 		self.ApiActionPost,
 		apidoc.BuildSwaggerDef(
 			nil,
@@ -148,8 +403,88 @@ func (self *Web) Start(ctx context.Context) error {
 	); err != nil {
 		return err
 	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/admin/reload",
+		self.ApiAdminReloadPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, config.Runtime{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/admin/stats",
+		self.ApiAdminStatsGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.DatabaseStats{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/admin/fact-usage",
+		self.ApiAdminFactUsageGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.FactFieldUsage{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/admin/reconcile-runs",
+		self.ApiAdminReconcileRunsPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Run{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/admin/replay-facts",
+		self.ApiAdminReplayFactsPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.FactReplay{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/admin/audit-log",
+		self.ApiAdminAuditLogGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.AuditLogEntry{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/admin/log-level",
+		self.ApiAdminLogLevelGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiAdminLogLevels{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/admin/log-level",
+		self.ApiAdminLogLevelPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest(apiAdminLogLevelPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiAdminLogLevels{}, "OK")),
+	); err != nil {
+		return err
+	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/invocation/{id}/inputs",
+		"/api/v1/invocation/{id}/inputs",
 		self.ApiInvocationIdInputsGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an Invocation", Value: "UUID"}}),
@@ -159,17 +494,17 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/invocation/{id}/output",
+		"/api/v1/invocation/{id}/output",
 		self.ApiInvocationIdOutputGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an Invocation", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Run{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunOutput{}, "OK")),
 	); err != nil {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodPost,
-		"/api/invocation/{id}",
+		"/api/v1/invocation/{id}",
 		self.ApiInvocationIdPost,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an Invocation", Value: "UUID"}}),
@@ -179,7 +514,7 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/invocation/{id}",
+		"/api/v1/invocation/{id}",
 		self.ApiInvocationIdGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an Invocation", Value: "UUID"}}),
@@ -189,7 +524,7 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if route, err := r.AddRoute(http.MethodGet,
-		"/api/invocation",
+		"/api/v1/invocation",
 		self.ApiInvocationByInputGet,
 		apidoc.BuildSwaggerDef(
 			nil,
@@ -201,7 +536,7 @@ func (self *Web) Start(ctx context.Context) error {
 		route.(*mux.Route).Queries("input", "")
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/invocation",
+		"/api/v1/invocation",
 		self.ApiInvocationGet,
 		apidoc.BuildSwaggerDef(
 			nil,
@@ -210,9 +545,19 @@ func (self *Web) Start(ctx context.Context) error {
 	); err != nil {
 		return err
 	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/exec",
+		self.ApiRunIdExecGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusSwitchingProtocols, nil, "Websocket speaking Nomad's exec protocol")),
+	); err != nil {
+		return err
+	}
 	var value interface{} //TODO: WIP
 	if _, err := r.AddRoute(http.MethodPost,
-		"/api/run/{id}/fact",
+		"/api/v1/run/{id}/fact",
 		self.ApiRunIdFactPost,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
@@ -222,27 +567,77 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/run/{id}/log",
+		"/api/v1/run/{id}/log",
 		self.ApiRunIdLogGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, service.LokiLog{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, service.LokiLogPage{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/log/metrics",
+		self.ApiRunIdLogMetricsGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiRunIdLogMetricsGetResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/log/diff",
+		self.ApiRunIdLogDiffGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiRunIdLogDiffGetResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/tasks",
+		self.ApiRunIdTasksGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunTask{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/state-at",
+		self.ApiRunIdStateAtGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunState{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/timeline",
+		self.ApiRunIdTimelineGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunTimelineEntry{}, "OK")),
 	); err != nil {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/run/{id}",
+		"/api/v1/run/{id}",
 		self.ApiRunIdGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Run{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, service.RunDetail{}, "OK")),
 	); err != nil {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/run/{id}/inputs",
+		"/api/v1/run/{id}/inputs",
 		self.ApiRunIdInputsGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
@@ -252,97 +647,682 @@ func (self *Web) Start(ctx context.Context) error {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/run/{id}/output",
+		"/api/v1/run/{id}/output",
 		self.ApiRunIdOutputGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Run{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunOutput{}, "OK")),
 	); err != nil {
 		return err
 	}
-	if _, err := r.AddRoute(http.MethodDelete,
-		"/api/run/{id}",
-		self.ApiRunIdDelete,
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/usage",
+		self.ApiRunIdUsageGet,
 		apidoc.BuildSwaggerDef(
 			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusNoContent, domain.Run{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, service.RunCost{}, "OK")),
 	); err != nil {
 		return err
 	}
-	if route, err := r.AddRoute(http.MethodGet,
-		"/api/run",
-		self.ApiRunByInputGet,
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/timing",
+		self.ApiRunIdTimingGet,
 		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunTiming{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/manifest",
+		self.ApiRunIdManifestGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Run{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunManifest{}, "OK")),
 	); err != nil {
 		return err
-	} else {
-		route.(*mux.Route).Queries("input", "")
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/run",
-		self.ApiRunGet,
+		"/api/v1/run/{id}/annotations",
+		self.ApiRunIdAnnotationsGet,
 		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunAnnotations{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run/{id}/nomad-token",
+		self.ApiRunIdNomadTokenGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Run{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunNomadToken{}, "OK")),
 	); err != nil {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodPost,
-		"/api/fact/match/latest",
-		self.ApiFactMatchLatestPost,
+		"/api/v1/run/{id}/nomad-token",
+		self.ApiRunIdNomadTokenPost,
 		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiRunIdNomadTokenPostResponse{}, "OK")),
 	); err != nil {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/fact/{id}/binary",
-		self.ApiFactIdBinaryGet,
+		"/api/v1/run/{id}/progress",
+		self.ApiRunIdProgressGet,
 		apidoc.BuildSwaggerDef(
-			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, []byte{}, "OK"),
-		),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunProgress{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/run/{id}/progress",
+		self.ApiRunIdProgressPost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			apidoc.BuildBodyRequest(apiRunIdProgressPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunProgress{}, "OK")),
 	); err != nil {
 		return err
 	}
 	if _, err := r.AddRoute(http.MethodGet,
-		"/api/fact/{id}",
-		self.ApiFactIdGet,
+		"/api/v1/run/{id}/dispatch",
+		self.ApiRunIdDispatchGet,
 		apidoc.BuildSwaggerDef(
-			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunDispatch{}, "OK")),
 	); err != nil {
 		return err
 	}
-	if route, err := r.AddRoute(http.MethodGet,
-		"/api/fact",
-		self.ApiFactByRunGet,
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/cost",
+		self.ApiCostGet,
 		apidoc.BuildSwaggerDef(
 			nil,
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []service.CostReport{}, "OK")),
 	); err != nil {
 		return err
-	} else {
-		route.(*mux.Route).Queries("run", "")
 	}
-	if _, err := r.AddRoute(http.MethodPost,
-		"/api/fact",
-		self.ApiFactPost,
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/template",
+		self.ApiTemplateGet,
 		apidoc.BuildSwaggerDef(
 			nil,
 			nil,
-			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.ActionTemplate{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/template/{name}",
+		self.ApiTemplateNameGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of an action template", Value: "go-build"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.ActionTemplate{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/template/{name}/instantiate",
+		self.ApiTemplateNameInstantiatePost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of an action template", Value: "go-build"}}),
+			apidoc.BuildBodyRequest(apiTemplateNameInstantiatePostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiTemplateNameInstantiatePostResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/run/{id}",
+		self.ApiRunIdDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a run", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, domain.Run{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if route, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run",
+		self.ApiRunByInputGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Run{}, "OK")),
+	); err != nil {
+		return err
+	} else {
+		route.(*mux.Route).Queries("input", "")
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/run",
+		self.ApiRunGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Run{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact/feed",
+		self.ApiFactFeedGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.FactFeed{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/fact/ingest",
+		self.ApiFactIngestPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest(apiFactIngestRequest{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiFactIngestResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/fact/match",
+		self.ApiFactMatchPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Fact{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/fact/match/latest",
+		self.ApiFactMatchLatestPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact/{id}/binary",
+		self.ApiFactIdBinaryGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []byte{}, "OK"),
+		),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact/{id}/binary/preview",
+		self.ApiFactIdBinaryPreviewGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.FactBinaryPreview{}, "OK"),
+		),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact/{id}/link",
+		self.ApiFactIdLinkGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.FactLinks{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/fact/{id}/link",
+		self.ApiFactIdLinkPost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			apidoc.BuildBodyRequest(domain.FactLink{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.FactLink{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/fact/{id}/link/{kind}/{target}",
+		self.ApiFactIdLinkDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{
+				{Name: "id", Description: "id of a fact", Value: "UUID"},
+				{Name: "kind", Description: "kind of the link", Value: "supersedes"},
+				{Name: "target", Description: "id of the fact linked to", Value: "UUID"},
+			}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact/{id}/usage",
+		self.ApiFactIdUsageGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.FactUsage{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact/{id}",
+		self.ApiFactIdGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a fact", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/fact",
+		self.ApiFactGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Fact{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/fact",
+		self.ApiFactPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Fact{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/token/{id}/rotate",
+		self.ApiTokenIdRotatePost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an API token", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiTokenPostResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/token/{id}",
+		self.ApiTokenIdDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an API token", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/token",
+		self.ApiTokenGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.ApiToken{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/token",
+		self.ApiTokenPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest(apiTokenPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiTokenPostResponse{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/publisher",
+		self.ApiPublisherGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.FactPublisher{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/publisher",
+		self.ApiPublisherPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest(apiPublisherPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.FactPublisher{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/publisher/{name}",
+		self.ApiPublisherNameDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of a fact publisher", Value: "release-team"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/quota",
+		self.ApiQuotaGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.QuotaReport{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/quota/{subject}/{name}",
+		self.ApiQuotaSubjectNameGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{
+				{Name: "subject", Description: "what the quota limits", Value: "project"},
+				{Name: "name", Description: "name of a project or API token", Value: "github.com/input-output-hk/cicero"},
+			}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.QuotaReport{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPut,
+		"/api/v1/quota/{subject}/{name}",
+		self.ApiQuotaSubjectNamePut,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{
+				{Name: "subject", Description: "what the quota limits", Value: "project"},
+				{Name: "name", Description: "name of a project or API token", Value: "github.com/input-output-hk/cicero"},
+			}),
+			apidoc.BuildBodyRequest(apiQuotaPutBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Quota{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/quota/{subject}/{name}",
+		self.ApiQuotaSubjectNameDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{
+				{Name: "subject", Description: "what the quota limits", Value: "project"},
+				{Name: "name", Description: "name of a project or API token", Value: "github.com/input-output-hk/cicero"},
+			}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/quota/{subject}/{name}/override",
+		self.ApiQuotaSubjectNameOverridePost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{
+				{Name: "subject", Description: "what the quota limits", Value: "project"},
+				{Name: "name", Description: "name of a project or API token", Value: "github.com/input-output-hk/cicero"},
+			}),
+			apidoc.BuildBodyRequest(apiQuotaOverridePostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Quota{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/digest",
+		self.ApiDigestGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.DigestSubscription{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/digest",
+		self.ApiDigestPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest(apiDigestPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.DigestSubscription{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/digest/{id}",
+		self.ApiDigestIdDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a digest subscription", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/digest/{id}/preview",
+		self.ApiDigestIdPreviewGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a digest subscription", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Digest{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/watch",
+		self.ApiWatchGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.WatchListEntry{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/watch",
+		self.ApiWatchPost,
+		apidoc.BuildSwaggerDef(
+			nil,
+			apidoc.BuildBodyRequest(apiWatchPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Watch{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/watch/{id}",
+		self.ApiWatchIdDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a watch", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/mutex",
+		self.ApiMutexGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunMutexState{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/mutex/{name}",
+		self.ApiMutexNameGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of a mutex", Value: "deploy-prod"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunMutexState{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/queue",
+		self.ApiQueueGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunQueueEntry{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/queue/{id}/override",
+		self.ApiQueueIdOverridePost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a queued Run", Value: "3c3b3dc8-0f15-4c4c-a5f9-bc8c28d6e94c"}}),
+			apidoc.BuildBodyRequest(apiQueueIdOverridePostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/maintenance",
+		self.ApiMaintenanceGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.MaintenanceWindowState{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/approval",
+		self.ApiApprovalGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.RunApproval{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/approval/{id}",
+		self.ApiApprovalIdGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a Run", Value: "3c3b3dc8-0f15-4c4c-a5f9-bc8c28d6e94c"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunApproval{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPost,
+		"/api/v1/approval/{id}",
+		self.ApiApprovalIdPost,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of a Run waiting for approval", Value: "3c3b3dc8-0f15-4c4c-a5f9-bc8c28d6e94c"}}),
+			apidoc.BuildBodyRequest(apiApprovalIdPostBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.RunApproval{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/environment",
+		self.ApiEnvironmentGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Environment{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/environment/{id}",
+		self.ApiEnvironmentIdGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an environment", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Environment{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/environment/{id}",
+		self.ApiEnvironmentIdDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "id", Description: "id of an environment", Value: "UUID"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.Environment{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/query",
+		self.ApiQueryGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.SavedQuery{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/query/{name}",
+		self.ApiQueryNameGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of a saved query", Value: "failed-deploys"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.SavedQuery{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodPut,
+		"/api/v1/query/{name}",
+		self.ApiQueryNamePut,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of a saved query", Value: "failed-deploys"}}),
+			apidoc.BuildBodyRequest(apiQueryPutBody{}),
+			apidoc.BuildResponseSuccessfully(http.StatusOK, domain.SavedQuery{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodDelete,
+		"/api/v1/query/{name}",
+		self.ApiQueryNameDelete,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of a saved query", Value: "failed-deploys"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusNoContent, nil, "NoContent")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/query/{name}/result",
+		self.ApiQueryNameResultGet,
+		apidoc.BuildSwaggerDef(
+			apidoc.BuildSwaggerPathParams([]apidoc.PathParams{{Name: "name", Description: "name of a saved query", Value: "failed-deploys"}}),
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, []domain.Run{}, "OK")),
+	); err != nil {
+		return err
+	}
+	if _, err := r.AddRoute(http.MethodGet,
+		"/api/v1/seal/key",
+		self.ApiSealKeyGet,
+		apidoc.BuildSwaggerDef(
+			nil,
+			nil,
+			apidoc.BuildResponseSuccessfully(http.StatusOK, apiSealKeyResponse{}, "OK")),
 	); err != nil {
 		return err
 	}
@@ -351,708 +1331,2590 @@ func (self *Web) Start(ctx context.Context) error {
 	muxRouter.HandleFunc("/invocation/{id}", self.InvocationIdGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/run/{id}", self.RunIdDelete).Methods(http.MethodDelete)
 	muxRouter.HandleFunc("/run/{id}", self.RunIdGet).Methods(http.MethodGet)
+	muxRouter.HandleFunc("/run/{id}/exec", self.RunIdExecGet).Methods(http.MethodGet)
+	muxRouter.HandleFunc("/run/{id}/log/diff", self.RunIdLogDiffGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/run", self.RunGet).Methods(http.MethodGet)
+	muxRouter.HandleFunc("/approval", self.ApprovalGet).Methods(http.MethodGet)
+	muxRouter.HandleFunc("/approval/{id}", self.ApprovalIdPost).Methods(http.MethodPost)
+	muxRouter.HandleFunc("/watch", self.WatchGet).Methods(http.MethodGet)
+	muxRouter.HandleFunc("/watch", self.WatchPost).Methods(http.MethodPost)
+	muxRouter.HandleFunc("/watch/{id}", self.WatchIdDelete).Methods(http.MethodDelete)
+	muxRouter.HandleFunc("/fact/{id}/binary/preview", self.FactIdBinaryPreviewGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/action/current", self.ActionCurrentGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/action/new", self.ActionNewGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/action/{id}", self.ActionIdGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/action/{id}", self.ActionIdPatch).Methods(http.MethodPatch)
 	muxRouter.HandleFunc("/action/{id}/run", self.ActionIdRunGet).Methods(http.MethodGet)
 	muxRouter.HandleFunc("/action/{id}/version", self.ActionIdVersionGet).Methods(http.MethodGet)
-	muxRouter.PathPrefix("/static/").Handler(http.StripPrefix("/", http.FileServer(http.FS(staticFs))))
+	muxRouter.PathPrefix("/static/").Handler(self.Assets.staticHandler())
+	if self.Assets.reload {
+		muxRouter.Handle("/_reload", self.Assets.eventsHandler()).Methods(http.MethodGet)
+	}
+
+	muxRouter.PathPrefix("/_dispatch/method/{method}/").HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Method = mux.Vars(req)["method"]
+		http.StripPrefix("/_dispatch/method/"+req.Method, muxRouter).ServeHTTP(w, req)
+	})
+
+	// creates /documentation/cicero.json and /documentation/cicero.yaml routes
+	err = r.GenerateAndExposeSwagger()
+	if err != nil {
+		return errors.WithMessage(err, "Failed to generate and expose swagger: %s")
+	}
+
+	var handler http.Handler = self.Auth.Handler(self.auditLog(self.requireScope(self.refuseWrites(muxRouter))), "/static/")
+	if self.PublicBadges {
+		authenticated := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if isBadgeRequest(req) {
+				muxRouter.ServeHTTP(w, req)
+			} else {
+				authenticated.ServeHTTP(w, req)
+			}
+		})
+	}
+
+	rest := handler
+	handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/readyz" && req.Method == http.MethodGet {
+			self.ReadyzGet(w, req)
+		} else {
+			rest.ServeHTTP(w, req)
+		}
+	})
+
+	handler = self.apiVersioning(handler)
+	handler = self.forwarded(handler)
+
+	server := &http.Server{Addr: self.Listen, Handler: handler}
+
+	if self.TLS.Cert != "" {
+		if server.TLSConfig, err = self.TLS.config(); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS(self.TLS.Cert, self.TLS.Key)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil {
+			self.Logger.Err(err).Msgf("Failed to start web server on %s", self.Listen)
+		}
+	}()
+
+	<-ctx.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		self.Logger.Err(err).Msg("Failed to stop web server")
+	}
+
+	return nil
+}
+
+func (self *Web) IndexGet(w http.ResponseWriter, req *http.Request) {
+	http.Redirect(w, req, "/action/current?active", http.StatusFound)
+}
+
+func (self *Web) ActionCurrentGet(w http.ResponseWriter, req *http.Request) {
+	var actions []domain.Action
+	var err error
+
+	_, active := req.URL.Query()["active"]
+	if active {
+		actions, err = self.ActionService.GetCurrentActive()
+	} else {
+		actions, err = self.ActionService.GetCurrent()
+	}
+
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	if err := self.Assets.render("action/current.html", w, map[string]interface{}{
+		"Actions": actions,
+		"active":  active,
+	}); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+}
+
+func (self *Web) ActionIdRunGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
+		return
+	} else if page, err := getPage(req); err != nil {
+		self.BadRequest(w, err)
+		return
+	} else if invocations, err := self.InvocationService.GetByActionId(id, page); err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Could not get Invocations by Action ID: %q", id))
+		return
+	} else {
+		type entry struct {
+			Invocation *domain.Invocation
+			Run        *domain.Run
+		}
+
+		entries := make([]entry, len(invocations))
+
+		{
+			errChan := make(chan error, len(invocations))
+
+			wg := &sync.WaitGroup{}
+
+			wg.Add(len(invocations))
+			for i, invocation := range invocations {
+				// copy so we don't point to loop variable
+				invocation := invocation
+				entries[i].Invocation = &invocation
+
+				go func(i int, id uuid.UUID) {
+					defer wg.Done()
+
+					run, err := self.RunService.GetByInvocationId(id)
+					if err != nil {
+						errChan <- err
+					} else {
+						entries[i].Run = run
+					}
+				}(i, invocation.Id)
+			}
+
+			wg.Wait()
+
+			select {
+			case err := <-errChan:
+				self.ServerError(w, err)
+				return
+			default:
+			}
+		}
+
+		if err := self.Assets.render("action/runs.html", w, struct {
+			Entries []entry
+			*repository.Page
+		}{entries, page}); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+	}
+}
+
+func (self *Web) ActionIdVersionGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
+		return
+	} else if action, err := self.ActionService.GetById(id); err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by ID: %q", id))
+		return
+	} else if page, err := getPage(req); err != nil {
+		self.BadRequest(w, err)
+		return
+	} else if actions, err := self.ActionService.GetByName(action.Name, page); err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by name: %q", action.Name))
+		return
+	} else if err := self.Assets.render("action/version.html", w, struct {
+		ActionID uuid.UUID
+		Actions  []domain.Action
+		*repository.Page
+	}{
+		ActionID: id,
+		Actions:  actions,
+		Page:     page,
+	}); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+}
+
+func (self *Web) ActionIdGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
+	} else if action, err := self.ActionService.GetById(id); err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by ID: %q", id))
+	} else if action == nil {
+		self.NotFound(w, nil)
+	} else if _, inputs, err := self.ActionService.IsRunnable(action); err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Could not get facts that satisfy inputs for Action with ID %q", id))
+	} else if err := self.Assets.render("action/[id].html", w, map[string]interface{}{
+		"Action": action,
+		"inputs": inputs,
+		// An invalid owner could not have been saved.
+		"owner":     func() domain.ActionOwner { owner, _ := action.Owner(); return owner }(),
+		"watchForm": watchForm{"action", action.Name},
+		"badgeURL":  requestBaseURL(req) + "/api/v1/action/current/" + url.PathEscape(action.Name) + "/badge.svg",
+	}); err != nil {
+		self.ServerError(w, err)
+	}
+}
+
+func (self *Web) ActionIdPatch(w http.ResponseWriter, req *http.Request) {
+	self.ApiActionIdPatch(NopResponseWriter{w}, req)
+
+	if referer := req.Header.Get("Referer"); referer != "" {
+		http.Redirect(w, req, referer, http.StatusFound)
+	} else {
+		http.Redirect(w, req, "/action/current?active", http.StatusFound)
+	}
+}
+
+func (self *Web) ActionNewGet(w http.ResponseWriter, req *http.Request) {
+	const templateName = "action/new.html"
+
+	query := req.URL.Query()
+	source := query.Get("source")
+	name := query.Get("name")
+
+	// step 1
+	if source == "" {
+		if err := self.Assets.render(templateName, w, nil); err != nil {
+			self.ServerError(w, err)
+		}
+		return
+	}
+
+	// step 2
+	if name == "" {
+		if names, err := self.EvaluationService.ListActions(source); err != nil {
+			self.ServerError(w, errors.WithMessagef(err, "While listing Actions in %q", source))
+		} else if err := self.Assets.render(templateName, w, map[string]interface{}{"Source": source, "Names": names}); err != nil {
+			self.ServerError(w, err)
+		}
+		return
+	}
+
+	if action, err := self.ActionService.Create(source, name); err != nil {
+		self.ServerError(w, err)
+		return
+	} else {
+		http.Redirect(w, req, "/action/"+action.ID.String(), http.StatusFound)
+		return
+	}
+}
+
+func (self *Web) InvocationIdPost(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, err)
+		return
+	}
+
+	invocation, runFunc, err := self.InvocationService.Retry(id)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+	http.Redirect(w, req, "/invocation/"+invocation.Id.String(), http.StatusFound)
+
+	go func() {
+		if runs, registerFunc, err := runFunc(self.Db); err != nil {
+			self.Logger.Err(err).Stringer("invocation", invocation.Id).Msg("While invoking")
+		} else if err := registerFunc(); err != nil {
+			self.Logger.Err(err).Interface("runs", runs).Msg("While registering job(s) for run(s)")
+		}
+	}()
+}
+
+func (self *Web) InvocationIdGet(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, err)
+		return
+	}
+
+	invocation, err := self.InvocationService.GetById(id)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+	if invocation == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	log, err := self.InvocationService.GetLog(*invocation)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+	log.Deduplicate()
+
+	var run *domain.Run
+	if run_, err := self.RunService.GetByInvocationId(id); err != nil {
+		self.ServerError(w, err)
+		return
+	} else if run_ != nil {
+		run = run_
+	}
+
+	var inputs map[string]domain.Fact
+	if inputFactIds, err := self.InvocationService.GetInputFactIdsById(id); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts IDs"))
+		return
+	} else if inputs_, err := self.FactService.GetInvocationInputFacts(inputFactIds); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts"))
+		return
+	} else {
+		inputs = self.redactFactsByName(inputs_)
+	}
+
+	if err := self.Assets.render("invocation/[id].html", w, map[string]interface{}{
+		"Invocation":    invocation,
+		"Run":           run,
+		"inputs":        inputs,
+		"inputDefaults": invocation.InputDefaults,
+		"log":           log,
+	}); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+}
+
+func (self *Web) RunIdDelete(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, err)
+		return
+	}
+
+	self.ApiRunIdDelete(NopResponseWriter{w}, req)
+
+	if referer := req.Header.Get("Referer"); referer != "" {
+		http.Redirect(w, req, referer, http.StatusFound)
+	} else {
+		http.Redirect(w, req, "/run/"+id.String(), http.StatusFound)
+	}
+}
+
+func (self *Web) RunIdGet(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, err)
+		return
+	}
+
+	run, err := self.RunService.GetByNomadJobId(id)
+	if err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Failed to find Run %q", id))
+		return
+	}
+	if run == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	invocation, err := self.InvocationService.GetById(run.InvocationId)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	action, err := self.ActionService.GetById(invocation.ActionId)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	allocsWithLogs, err := self.RunService.GetRunAllocationsWithLogs(*run)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	allocs := []*nomad.Allocation{}
+	allocsWithLogsByGroup := map[string][]service.AllocationWithLogs{}
+	for _, alloc := range allocsWithLogs {
+		allocs = append(allocs, alloc.Allocation)
+		allocsWithLogsByGroup[alloc.TaskGroup] = append(allocsWithLogsByGroup[alloc.TaskGroup], alloc)
+	}
+
+	var inputs map[string]domain.Fact
+	if inputFactIds, err := self.InvocationService.GetInputFactIdsById(run.InvocationId); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts IDs"))
+		return
+	} else if inputs_, err := self.FactService.GetInvocationInputFacts(inputFactIds); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts"))
+		return
+	} else {
+		inputs = self.redactFactsByName(inputs_)
+	}
+
+	output, err := self.InvocationService.GetOutputById(run.InvocationId)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	facts, err := self.FactService.GetByRunId(id)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	grafanaUrls, err := self.RunService.GrafanaUrls(allocs, run.FinishedAt)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	grafanaLokiUrls, err := self.RunService.GrafanaLokiUrls(allocs, run.FinishedAt)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	cpuMetrics, err := self.RunService.CPUMetrics(allocs, run.FinishedAt)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	memMetrics, err := self.RunService.MemMetrics(allocs, run.FinishedAt)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	timeline, err := self.RunService.GetTimeline(*run)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	chainedRuns, err := self.RunService.GetChainedFrom(run.NomadJobID)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	progress, err := self.RunService.GetLatestProgress(run.NomadJobID)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	dispatches, err := self.RunService.GetDispatches(run.NomadJobID)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	annotations, err := self.RunAnnotationService.GetByRunId(run.NomadJobID)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	approval, err := self.RunApprovalService.GetByRunId(run.NomadJobID)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	if err := self.Assets.render("run/[id].html", w, map[string]interface{}{
+		"Run": struct {
+			domain.Run
+			Action domain.Action
+		}{*run, *action},
+		"inputs":                inputs,
+		"inputDefaults":         invocation.InputDefaults,
+		"output":                output,
+		"facts":                 self.redactFacts(facts),
+		"allocsWithLogsByGroup": allocsWithLogsByGroup,
+		"metrics":               service.GroupMetrics(cpuMetrics, memMetrics),
+		"grafanaUrls":           grafanaUrls,
+		"grafanaLokiUrls":       grafanaLokiUrls,
+		"timeline":              timeline,
+		"logTail":               service.RunLogTail,
+		"chainedFrom":           invocation.ChainedFrom,
+		"chainedRuns":           chainedRuns,
+		"progress":              progress,
+		"dispatches":            dispatches,
+		"annotations":           annotations,
+		"approval":              approval,
+		"watchForm":             watchForm{"run", run.NomadJobID.String()},
+	}); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+}
+
+func (self *Web) ApprovalGet(w http.ResponseWriter, req *http.Request) {
+	type entry struct {
+		Approval domain.RunApproval
+		Run      *domain.Run
+		Action   *domain.Action
+	}
+
+	approvals, err := self.RunApprovalService.GetPending()
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	entries := make([]entry, len(approvals))
+	for i, approval := range approvals {
+		entries[i].Approval = approval
+
+		if entries[i].Run, err = self.RunService.GetByNomadJobId(approval.RunId); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+		if entries[i].Action, err = self.ActionService.GetByRunId(approval.RunId); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+	}
+
+	if err := self.Assets.render("approval/index.html", w, map[string]interface{}{
+		"Entries": entries,
+	}); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+}
+
+func (self *Web) ApprovalIdPost(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, err)
+		return
+	}
+
+	approved, err := strconv.ParseBool(req.FormValue("approved"))
+	if err != nil {
+		self.ClientError(w, errors.WithMessage(err, "approved parameter is invalid, should be true or false"))
+		return
+	}
+
+	if _, err := self.decideApproval(req, id, approved, req.FormValue("comment")); err != nil {
+		self.Error(w, err)
+		return
+	}
+
+	http.Redirect(w, req, "/run/"+id.String(), http.StatusFound)
+}
+
+// What the "watch-form" template watches.
+type watchForm struct {
+	// "run" or "action".
+	Kind  string
+	Value string
+}
+
+func (self *Web) WatchGet(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Watching requires authentication"), http.StatusUnauthorized})
+		return
+	}
+
+	if entries, err := self.WatchService.GetWatchList(identity.Name); err != nil {
+		self.ServerError(w, err)
+	} else if err := self.Assets.render("watch/index.html", w, map[string]interface{}{
+		"Entries": entries,
+	}); err != nil {
+		self.ServerError(w, err)
+	}
+}
+
+func (self *Web) WatchPost(w http.ResponseWriter, req *http.Request) {
+	body := apiWatchPostBody{}
+	if str := req.FormValue("run"); str != "" {
+		if id, err := uuid.Parse(str); err != nil {
+			self.ClientError(w, errors.WithMessage(err, "Failed to parse Run ID"))
+			return
+		} else {
+			body.RunId = &id
+		}
+	}
+	if name := req.FormValue("action"); name != "" {
+		body.ActionName = &name
+	}
+	if email := req.FormValue("email"); email != "" {
+		body.Email = &email
+	}
+
+	if _, err := self.watch(req, body); err != nil {
+		self.Error(w, err)
+		return
+	}
+
+	http.Redirect(w, req, "/watch", http.StatusFound)
+}
+
+func (self *Web) WatchIdDelete(w http.ResponseWriter, req *http.Request) {
+	if err := self.unwatch(req); err != nil {
+		self.Error(w, err)
+		return
+	}
+
+	http.Redirect(w, req, "/watch", http.StatusFound)
+}
+
+func (self *Web) RunIdExecGet(w http.ResponseWriter, req *http.Request) {
+	switch run, ok := self.getRun(w, req); {
+	case !ok:
+	case run == nil:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		allowed, err := self.execAllowed(req, *run)
+		if err != nil {
+			self.ServerError(w, err)
+			return
+		}
+
+		if err := self.Assets.render("run/exec.html", w, map[string]interface{}{
+			"Run":     run,
+			"alloc":   req.FormValue("alloc"),
+			"task":    req.FormValue("task"),
+			"allowed": allowed,
+		}); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+	}
+}
+
+func getPage(req *http.Request) (*repository.Page, error) {
+	page := repository.Page{}
+
+	if offsetStr := req.FormValue("offset"); offsetStr == "" {
+		page.Offset = 0
+	} else if offset, err := strconv.Atoi(offsetStr); err != nil {
+		return nil, errors.WithMessage(err, "offset parameter is invalid, should be positive integer")
+	} else {
+		page.Offset = offset
+	}
+
+	if limitStr := req.FormValue("limit"); limitStr == "" {
+		page.Limit = 10
+	} else if limit, err := strconv.Atoi(limitStr); err != nil {
+		return nil, errors.WithMessage(err, "limit parameter is invalid, should be positive integer")
+	} else {
+		page.Limit = limit
+	}
+
+	return &page, nil
+}
+
+func (self *Web) RunGet(w http.ResponseWriter, req *http.Request) {
+	type entry struct {
+		Run        *domain.Run
+		Invocation *domain.Invocation
+		Action     *domain.Action
+	}
+
+	page, err := getPage(req)
+	if err != nil {
+		self.BadRequest(w, err)
+		return
+	}
+
+	filter, err := self.getRunFilter(req)
+	if err != nil {
+		self.Error(w, err)
+		return
+	}
+
+	queries, err := self.SavedQueryService.GetAll(domain.SavedQueryKindRun)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	var entries []entry
+
+	if filter.Empty() {
+		invocations, err := self.InvocationService.GetAll(page)
+		if err != nil {
+			self.ServerError(w, err)
+			return
+		}
+
+		entries = make([]entry, len(invocations))
+
+		errChan := make(chan error, len(invocations)*2)
+
+		wg := &sync.WaitGroup{}
+
+		wg.Add(len(invocations) * 2)
+		for i, invocation := range invocations {
+			// copy so we don't point to loop variable
+			invocation := invocation
+			entries[i].Invocation = &invocation
+
+			go func(i int, id uuid.UUID) {
+				defer wg.Done()
+				if action, err := self.ActionService.GetByInvocationId(id); err != nil {
+					errChan <- err
+				} else {
+					entries[i].Action = action
+				}
+			}(i, invocation.Id)
+
+			go func(i int, id uuid.UUID) {
+				defer wg.Done()
+				if run, err := self.RunService.GetByInvocationId(id); err != nil {
+					errChan <- err
+				} else {
+					entries[i].Run = run
+				}
+			}(i, invocation.Id)
+		}
+
+		wg.Wait()
+
+		select {
+		case err := <-errChan:
+			self.ServerError(w, err)
+			return
+		default:
+		}
+	} else {
+		// Invocations that did not lead to a Run
+		// have no status nor do they belong to an action by name,
+		// so only Runs can be filtered.
+		runs, err := self.RunService.GetByFilter(filter, page)
+		if err != nil {
+			self.ServerError(w, err)
+			return
+		}
+
+		entries = make([]entry, len(runs))
+
+		errChan := make(chan error, len(runs))
+
+		wg := &sync.WaitGroup{}
+
+		wg.Add(len(runs))
+		for i, run := range runs {
+			// copy so we don't point to loop variable
+			run := run
+			entries[i].Run = &run
+
+			go func(i int, id uuid.UUID) {
+				defer wg.Done()
+				if action, err := self.ActionService.GetByRunId(id); err != nil {
+					errChan <- err
+				} else {
+					entries[i].Action = action
+				}
+			}(i, run.NomadJobID)
+		}
+
+		wg.Wait()
+
+		select {
+		case err := <-errChan:
+			self.ServerError(w, err)
+			return
+		default:
+		}
+	}
+
+	if err := self.Assets.render("run/index.html", w, struct {
+		Entries []entry
+		// Saved queries of Runs to offer as quick filters.
+		Queries []domain.SavedQuery
+		// The name of the saved query in use, if any.
+		Query string
+		// The filters in use, to keep them when paginating.
+		Filter url.Values
+		*repository.Page
+	}{entries, queries, req.URL.Query().Get("query"), filter.Query(), page}); err != nil {
+		self.ServerError(w, err)
+		return
+	}
+}
+
+// Use this to call API request handlers from UI request handlers.
+type NopResponseWriter struct{ http.ResponseWriter }
+
+func (w NopResponseWriter) WriteHeader(int) {}
+
+func (w NopResponseWriter) Write(b []byte) (int, error) {
+	return io.Discard.Write(b)
+}
+
+func (self *Web) ApiActionDefinitionSourceGet(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	source, err := url.PathUnescape(vars["source"])
+	if err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Invalid escaping of action definition source"))
+		return
+	}
+
+	if wfs, err := self.EvaluationService.ListActions(source); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to list actions"))
+		return
+	} else {
+		self.json(w, wfs, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiActionDefinitionSourceNameIdGet(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	if source, err := url.PathUnescape(vars["source"]); err != nil {
+		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action definition source: %q", vars["source"]))
+	} else if name, err := url.PathUnescape(vars["name"]); err != nil {
+		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action name: %q", vars["name"]))
+	} else if idStr, err := url.PathUnescape(vars["id"]); err != nil {
+		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action ID: %q", vars["id"]))
+	} else if id, err := uuid.Parse(idStr); err != nil {
+		self.ClientError(w, errors.WithMessagef(err, "Invalid UUID given as action ID: %q", idStr))
+	} else if def, err := self.EvaluationService.EvaluateAction(source, name, id); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, def, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiInvocationGet(w http.ResponseWriter, req *http.Request) {
+	if page, err := getPage(req); err != nil {
+		self.ServerError(w, err)
+	} else if invocations, err := self.InvocationService.GetAll(page); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "failed to fetch Invocations"))
+	} else {
+		self.json(w, invocations, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiRunGet(w http.ResponseWriter, req *http.Request) {
+	if page, err := getPage(req); err != nil {
+		self.ServerError(w, err)
+	} else if filter, err := self.getRunFilter(req); err != nil {
+		self.Error(w, err)
+	} else if runs, err := self.getRuns(filter, page); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "failed to fetch Runs"))
+	} else if lastModified, err := self.runsLastModifiedOrMeasured(runs...); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.jsonListConditional(w, req, runs, lastModified)
+	}
+}
+
+// Returns the filters given in the URL query
+// or those of the saved query named by its `query` parameter.
+func (self *Web) getRunFilter(req *http.Request) (domain.RunFilter, error) {
+	query := req.URL.Query()
+
+	if name := query.Get("query"); name != "" {
+		if savedQuery, err := self.SavedQueryService.Get(name); err != nil {
+			return domain.RunFilter{}, err
+		} else if savedQuery == nil {
+			return domain.RunFilter{}, HandlerError{errors.Errorf("No saved query named %q", name), http.StatusNotFound}
+		} else if filter, err := savedQuery.RunFilter(); err != nil {
+			return domain.RunFilter{}, HandlerError{err, http.StatusPreconditionFailed}
+		} else {
+			return filter, nil
+		}
+	}
+
+	if filter, err := domain.ParseRunFilter(query); err != nil {
+		return filter, HandlerError{err, http.StatusPreconditionFailed}
+	} else {
+		return filter, nil
+	}
+}
+
+func (self *Web) getRuns(filter domain.RunFilter, page *repository.Page) ([]domain.Run, error) {
+	if filter.Empty() {
+		return self.RunService.GetAll(page)
+	}
+	return self.RunService.GetByFilter(filter, page)
+}
+
+func getByInputParams(req *http.Request) (bool, *bool, []*uuid.UUID, error) {
+	query := req.URL.Query()
+
+	_, recursive := query["recursive"]
+
+	var ok *bool
+	if okStr := query.Get("ok"); okStr != "" {
+		if ok_, err := strconv.ParseBool(okStr); err != nil {
+			return false, nil, nil, err
+		} else {
+			ok = &ok_
+		}
+	}
+
+	factIds := make([]*uuid.UUID, len(query["input"]))
+	for i, str := range query["input"] {
+		if id, err := uuid.Parse(str); err != nil {
+			return false, nil, nil, err
+		} else {
+			factIds[i] = &id
+		}
+	}
+
+	return recursive, ok, factIds, nil
+}
+
+func (self *Web) ApiRunByInputGet(w http.ResponseWriter, req *http.Request) {
+	ok := true
+	if recursive, _, factIds, err := getByInputParams(req); err != nil {
+		self.ClientError(w, err)
+	} else if page, err := getPage(req); err != nil {
+		self.ServerError(w, err)
+	} else if invocations, err := self.InvocationService.GetByInputFactIds(factIds, recursive, &ok, page); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "failed to fetch Invocations"))
+	} else {
+		runs := []domain.Run{}
+		for _, invocation := range invocations {
+			if run, err := self.RunService.GetByInvocationId(invocation.Id); err != nil {
+				self.ServerError(w, err)
+				return
+			} else if run != nil {
+				runs = append(runs, *run)
+			}
+		}
+
+		if lastModified, err := self.runsLastModifiedOrMeasured(runs...); err != nil {
+			self.ServerError(w, err)
+		} else {
+			self.jsonListConditional(w, req, runs, lastModified)
+		}
+	}
+}
+
+func (self *Web) ApiInvocationByInputGet(w http.ResponseWriter, req *http.Request) {
+	if recursive, ok, factIds, err := getByInputParams(req); err != nil {
+		self.ClientError(w, err)
+	} else if page, err := getPage(req); err != nil {
+		self.ServerError(w, err)
+	} else if invocations, err := self.InvocationService.GetByInputFactIds(factIds, recursive, ok, page); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "failed to fetch Invocations"))
+	} else {
+		self.json(w, invocations, http.StatusOK)
+	}
+}
+
+// XXX take form body instead of json?
+type apiActionPostBody struct {
+	Source string  `json:"source"`
+	Name   *string `json:"name"`
+}
+
+func (self *Web) ApiActionPost(w http.ResponseWriter, req *http.Request) {
+	params := apiActionPostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
+
+	if params.Name != nil {
+		if action, err := self.ActionService.Create(params.Source, *params.Name); err != nil {
+			self.ClientError(w, err)
+			return
+		} else {
+			self.json(w, action, http.StatusOK)
+		}
+	} else {
+		if actionNames, err := self.EvaluationService.ListActions(params.Source); err != nil {
+			self.ClientError(w, errors.WithMessage(err, "Failed to list actions"))
+			return
+		} else {
+			actions := make([]*domain.Action, len(actionNames))
+			for i, actionName := range actionNames {
+				if action, err := self.ActionService.Create(params.Source, actionName); err != nil {
+					self.ClientError(w, err)
+					return
+				} else {
+					actions[i] = action
+				}
+			}
+			self.json(w, actions, http.StatusOK)
+		}
+	}
+}
+
+func (self *Web) ApiActionExportGet(w http.ResponseWriter, req *http.Request) {
+	if exports, err := self.ActionService.Export(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, exports, http.StatusOK)
+	}
+}
+
+// Makes the actions match the exported ones in the body.
+// Only returns the changes if `dry-run` is true.
+// If `prune` is true actions that are not exported are deactivated.
+func (self *Web) ApiActionImportPost(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	dryRun := false
+	if str := query.Get("dry-run"); str != "" {
+		if b, err := strconv.ParseBool(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid dry-run"))
+			return
+		} else {
+			dryRun = b
+		}
+	}
+
+	prune := false
+	if str := query.Get("prune"); str != "" {
+		if b, err := strconv.ParseBool(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid prune"))
+			return
+		} else {
+			prune = b
+		}
+	}
+
+	exports := []domain.ActionExport{}
+	if err := json.NewDecoder(req.Body).Decode(&exports); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal exported actions from request body"))
+		return
+	}
+
+	if changes, err := self.ActionService.Import(exports, prune, dryRun); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to import actions"))
+	} else {
+		self.json(w, changes, http.StatusOK)
+	}
+}
+
+// Returns (_, false) if an error occurred.
+// The error is already sent to the client.
+func (self *Web) getRun(w http.ResponseWriter, req *http.Request) (*domain.Run, bool) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+		return nil, false
+	} else if run, err := self.RunService.GetByNomadJobId(id); err != nil {
+		self.ServerError(w, err)
+		return run, false
+	} else {
+		return run, true
+	}
+}
+
+// Returns (_, false) if an error occurred.
+// The error is already sent to the client.
+func (self *Web) getInvocation(w http.ResponseWriter, req *http.Request) (*domain.Invocation, bool) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+		return nil, false
+	} else if invocation, err := self.InvocationService.GetById(id); err != nil {
+		self.ServerError(w, err)
+		return invocation, false
+	} else {
+		return invocation, true
+	}
+}
 
-	muxRouter.PathPrefix("/_dispatch/method/{method}/").HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		req.Method = mux.Vars(req)["method"]
-		http.StripPrefix("/_dispatch/method/"+req.Method, muxRouter).ServeHTTP(w, req)
-	})
+func (self *Web) ApiAdminReloadPost(w http.ResponseWriter, req *http.Request) {
+	if auth.IdentityFromContext(req.Context()) == nil {
+		self.Error(w, HandlerError{errors.New("Reloading the configuration requires authentication"), http.StatusUnauthorized})
+	} else if self.Runtime == nil {
+		self.NotFound(w, errors.New("No runtime configuration to reload"))
+	} else if err := self.Runtime.Reload(); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to reload configuration"))
+	} else {
+		self.Logger.Info().Str("identity", auth.IdentityFromContext(req.Context()).Name).Msg("Reloaded runtime configuration")
+		self.json(w, self.Runtime.Get(), http.StatusOK)
+	}
+}
 
-	// creates /documentation/cicero.json and /documentation/cicero.yaml routes
-	err = r.GenerateAndExposeSwagger()
+type apiAdminLogLevels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+func (self *Web) logLevels() apiAdminLogLevels {
+	level, componentLevels := self.LogLevels.Get()
+	levels := apiAdminLogLevels{Level: level.String(), Components: map[string]string{}}
+	for component, level := range componentLevels {
+		levels.Components[component] = level.String()
+	}
+	return levels
+}
+
+func (self *Web) ApiAdminLogLevelGet(w http.ResponseWriter, req *http.Request) {
+	if self.LogLevels == nil {
+		self.NotFound(w, errors.New("Log levels cannot be changed"))
+	} else {
+		self.json(w, self.logLevels(), http.StatusOK)
+	}
+}
+
+type apiAdminLogLevelPostBody struct {
+	// Empty for components without a level of their own.
+	Component string `json:"component,omitempty"`
+	// Null makes the component use the default level again.
+	Level *string `json:"level"`
+}
+
+func (self *Web) ApiAdminStatsGet(w http.ResponseWriter, req *http.Request) {
+	if stats, err := self.DatabaseStatsService.Get(); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get database statistics"))
+	} else {
+		self.json(w, stats, http.StatusOK)
+	}
+}
+
+// Returns the usage of the top-level fields of facts created within `since`,
+// a duration that defaults to 30 days, so that facts that are published
+// but never read nor invoke an action stand out.
+func (self *Web) ApiAdminFactUsageGet(w http.ResponseWriter, req *http.Request) {
+	since := 30 * 24 * time.Hour
+	if str := req.URL.Query().Get("since"); str != "" {
+		var err error
+		if since, err = time.ParseDuration(str); err != nil || since <= 0 {
+			self.BadRequest(w, errors.Errorf("since parameter is invalid, should be a positive duration: %q", str))
+			return
+		}
+	}
+
+	if self.FactUsageService == nil {
+		self.json(w, []domain.FactFieldUsage{}, http.StatusOK)
+	} else if fields, err := self.FactUsageService.GetFields(time.Now().UTC().Add(-since)); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, fields, http.StatusOK)
+	}
+}
+
+// Ends running Runs whose Nomad job vanished or died without Cicero noticing
+// and returns them. Only returns them if `dry-run` is true.
+// Runs younger than `grace`, 10 minutes by default, are left alone.
+func (self *Web) ApiAdminReconcileRunsPost(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	dryRun := false
+	if str := query.Get("dry-run"); str != "" {
+		if b, err := strconv.ParseBool(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid dry-run"))
+			return
+		} else {
+			dryRun = b
+		}
+	}
+
+	grace := 10 * time.Minute
+	if str := query.Get("grace"); str != "" {
+		if d, err := time.ParseDuration(str); err != nil || d < 0 {
+			self.BadRequest(w, errors.Errorf("Invalid grace %q, must be a non-negative duration", str))
+			return
+		} else {
+			grace = d
+		}
+	}
+
+	if runs, err := self.RunService.Reconcile(time.Now().Add(-grace), dryRun); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to reconcile Runs"))
+	} else {
+		self.json(w, runs, http.StatusOK)
+	}
+}
+
+// Invokes actions with historical facts as if they were just published,
+// for example to catch up after an action was broken or inactive.
+// The facts are given by their IDs as `fact`s or, oldest first,
+// by `namespace`, `name`, and `tag`s paged by `limit` and `offset`.
+// Only the actions named by `action`s are invoked if any.
+// With `dry-run` it only returns which actions would be invoked.
+func (self *Web) ApiAdminReplayFactsPost(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	dryRun := false
+	if str := query.Get("dry-run"); str != "" {
+		if b, err := strconv.ParseBool(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid dry-run"))
+			return
+		} else {
+			dryRun = b
+		}
+	}
+
+	facts := []domain.Fact{}
+	if ids := query["fact"]; len(ids) != 0 {
+		for _, idStr := range ids {
+			if id, err := uuid.Parse(idStr); err != nil {
+				self.BadRequest(w, errors.WithMessagef(err, "Invalid fact ID %q", idStr))
+				return
+			} else if fact, err := self.FactService.GetById(id); err != nil {
+				self.ServerError(w, err)
+				return
+			} else if fact == nil {
+				self.NotFound(w, errors.Errorf("No fact with ID %q", id))
+				return
+			} else {
+				facts = append(facts, *fact)
+			}
+		}
+	} else {
+		labels := domain.FactLabels{
+			Namespace: query.Get("namespace"),
+			Name:      query.Get("name"),
+			Tags:      query["tag"],
+		}
+		if labels.Namespace == "" && labels.Name == "" && len(labels.Tags) == 0 {
+			self.BadRequest(w, errors.New("Give the facts to replay by fact, namespace, name, or tag"))
+			return
+		}
+
+		if page, err := getPage(req); err != nil {
+			self.BadRequest(w, err)
+			return
+		} else if facts, err = self.FactService.GetByLabels(labels, page); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+
+		// Replay in the order they were published.
+		for i, j := 0, len(facts)-1; i < j; i, j = i+1, j-1 {
+			facts[i], facts[j] = facts[j], facts[i]
+		}
+	}
+
+	replays, runFunc, err := self.ActionService.Replay(facts, query["action"], dryRun)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to generate and expose swagger: %s")
+		self.ServerError(w, errors.WithMessage(err, "Failed to replay facts"))
+		return
+	}
+
+	if runFunc != nil {
+		if _, registerFunc, err := runFunc(self.Db); err != nil {
+			self.ServerError(w, err)
+			return
+		} else if err := registerFunc(); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+	}
+
+	self.json(w, replays, http.StatusOK)
+}
+
+// Returns recorded requests that may have changed something, newest first.
+// All filters are optional: `identity`, `token` (an API token's ID), `ip`, `method`,
+// `path` (a prefix), `since` and `until` (RFC 3339 times), and `failed` (bool).
+func (self *Web) ApiAdminAuditLogGet(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	filter := domain.AuditLogFilter{
+		Identity:   query.Get("identity"),
+		ClientIP:   query.Get("ip"),
+		Method:     query.Get("method"),
+		PathPrefix: query.Get("path"),
+	}
+
+	if str := query.Get("token"); str != "" {
+		if id, err := uuid.Parse(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid token"))
+			return
+		} else {
+			filter.ApiTokenId = &id
+		}
+	}
+
+	for name, field := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if str := query.Get(name); str != "" {
+			if t, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				self.BadRequest(w, errors.WithMessagef(err, "Invalid %s", name))
+				return
+			} else {
+				t = t.UTC()
+				*field = &t
+			}
+		}
+	}
+
+	if str := query.Get("failed"); str != "" {
+		if b, err := strconv.ParseBool(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid failed"))
+			return
+		} else {
+			filter.Failed = &b
+		}
+	}
+
+	if page, err := getPage(req); err != nil {
+		self.BadRequest(w, err)
+	} else if entries, err := self.AuditLogService.Get(filter, page); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get audit log"))
+	} else {
+		self.json(w, entries, http.StatusOK)
+	}
+}
+
+// Changes a log level until the runtime configuration is reloaded.
+func (self *Web) ApiAdminLogLevelPost(w http.ResponseWriter, req *http.Request) {
+	body := apiAdminLogLevelPostBody{}
+
+	if auth.IdentityFromContext(req.Context()) == nil {
+		self.Error(w, HandlerError{errors.New("Changing log levels requires authentication"), http.StatusUnauthorized})
+		return
+	} else if self.LogLevels == nil {
+		self.NotFound(w, errors.New("Log levels cannot be changed"))
+		return
+	} else if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.BadRequest(w, errors.WithMessage(err, "Could not decode body"))
+		return
+	}
+
+	var level *zerolog.Level
+	if body.Level != nil {
+		if l, err := zerolog.ParseLevel(*body.Level); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid log level"))
+			return
+		} else {
+			level = &l
+		}
+	} else if body.Component == "" {
+		self.BadRequest(w, errors.New("Components without a level of their own need one"))
+		return
+	}
+
+	self.LogLevels.SetComponent(body.Component, level)
+
+	self.Logger.Info().
+		Str("identity", auth.IdentityFromContext(req.Context()).Name).
+		Str("log-component", body.Component).
+		Interface("log-level", body.Level).
+		Msg("Changed log level")
+
+	self.json(w, self.logLevels(), http.StatusOK)
+}
+
+type apiTokenPostBody struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Like "720h", never expires if empty.
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+type apiTokenPostResponse struct {
+	// Only shown once.
+	Secret string          `json:"secret"`
+	Token  domain.ApiToken `json:"token"`
+}
+
+// Returns the identity managing API tokens
+// or writes an error if there is none.
+func (self *Web) getApiTokenManager(w http.ResponseWriter, req *http.Request) (*auth.Identity, bool) {
+	identity := auth.IdentityFromContext(req.Context())
+	switch {
+	case identity == nil:
+		self.Error(w, HandlerError{errors.New("Managing API tokens requires authentication"), http.StatusUnauthorized})
+	case self.ApiTokenService == nil:
+		self.NotFound(w, errors.New("API tokens are not enabled"))
+	default:
+		return identity, true
+	}
+	return nil, false
+}
+
+func (self *Web) ApiTokenGet(w http.ResponseWriter, req *http.Request) {
+	if _, ok := self.getApiTokenManager(w, req); !ok {
+		return
+	}
+
+	if tokens, err := self.ApiTokenService.GetAll(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, tokens, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiTokenPost(w http.ResponseWriter, req *http.Request) {
+	identity, ok := self.getApiTokenManager(w, req)
+	if !ok {
+		return
+	}
+
+	body := apiTokenPostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
+
+	if body.Name == "" {
+		self.ClientError(w, errors.New("API token needs a name"))
+		return
+	}
+
+	// Scopes would be unlimited otherwise.
+	if body.Scopes == nil {
+		body.Scopes = []string{}
+	}
+
+	// Nobody can create a token that may do more than themselves.
+	for _, scope := range body.Scopes {
+		if !identity.HasScope(scope) {
+			self.Error(w, HandlerError{errors.Errorf("Cannot grant scope %q that you do not have", scope), http.StatusForbidden})
+			return
+		}
+	}
+
+	token := domain.ApiToken{
+		Name:      body.Name,
+		Scopes:    body.Scopes,
+		CreatedBy: identity.Name,
+	}
+
+	if body.ExpiresIn != "" {
+		if expiresIn, err := time.ParseDuration(body.ExpiresIn); err != nil {
+			self.ClientError(w, errors.WithMessage(err, "Invalid expiry"))
+			return
+		} else {
+			expiresAt := time.Now().Add(expiresIn)
+			token.ExpiresAt = &expiresAt
+		}
+	}
+
+	if secret, err := self.ApiTokenService.Create(&token); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Stringer("token", token.ID).Strs("scopes", token.Scopes).Msg("Created API token")
+		self.json(w, apiTokenPostResponse{secret, token}, http.StatusOK)
+	}
+}
+
+// Returns the ID of the token in the path if the identity has all of its scopes.
+// Rotating a token hands out its secret so that must not let anyone do more than themselves,
+// and neither may anyone revoke the tokens of those who may do more.
+func (self *Web) getManageableApiTokenId(w http.ResponseWriter, req *http.Request, identity *auth.Identity) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not parse API token ID"))
+		return id, false
+	}
+
+	token, err := self.ApiTokenService.GetById(id)
+	if err != nil {
+		self.ServerError(w, err)
+		return id, false
+	} else if token == nil {
+		self.NotFound(w, errors.Errorf("No API token with ID %q", id))
+		return id, false
+	}
+
+	// Unlimited.
+	if token.Scopes == nil && identity.Scopes != nil {
+		self.Error(w, HandlerError{errors.Errorf("Cannot manage API token %q with unlimited scopes", id), http.StatusForbidden})
+		return id, false
+	}
+	for _, scope := range token.Scopes {
+		if !identity.HasScope(scope) {
+			self.Error(w, HandlerError{errors.Errorf("Cannot manage API token %q with scope %q that you do not have", id, scope), http.StatusForbidden})
+			return id, false
+		}
+	}
+
+	return id, true
+}
+
+func (self *Web) ApiTokenIdRotatePost(w http.ResponseWriter, req *http.Request) {
+	identity, ok := self.getApiTokenManager(w, req)
+	if !ok {
+		return
+	}
+
+	id, ok := self.getManageableApiTokenId(w, req, identity)
+	if !ok {
+		return
+	}
+
+	if token, secret, err := self.ApiTokenService.Rotate(id); err != nil {
+		self.ServerError(w, err)
+	} else if token == nil {
+		self.NotFound(w, errors.Errorf("No API token with ID %q", id))
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Stringer("old-token", id).Stringer("token", token.ID).Msg("Rotated API token")
+		self.json(w, apiTokenPostResponse{secret, *token}, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiTokenIdDelete(w http.ResponseWriter, req *http.Request) {
+	identity, ok := self.getApiTokenManager(w, req)
+	if !ok {
+		return
+	}
+
+	id, ok := self.getManageableApiTokenId(w, req, identity)
+	if !ok {
+		return
+	}
+
+	if err := self.ApiTokenService.Revoke(id); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Stringer("token", id).Msg("Revoked API token")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (self *Web) ApiPublisherGet(w http.ResponseWriter, req *http.Request) {
+	if publishers, err := self.FactPublisherService.GetAll(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, publishers, http.StatusOK)
+	}
+}
+
+type apiPublisherPostBody struct {
+	Name string `json:"name"`
+	// Minisign public key file or just the line with the key.
+	PublicKey string `json:"public_key"`
+}
+
+func (self *Web) ApiPublisherPost(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Registering fact publishers requires authentication"), http.StatusUnauthorized})
+		return
+	}
+
+	body := apiPublisherPostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
+
+	if body.Name == "" {
+		self.ClientError(w, errors.New("Fact publisher needs a name"))
+		return
+	}
+
+	publisher := domain.FactPublisher{
+		Name:      body.Name,
+		PublicKey: body.PublicKey,
+		CreatedBy: identity.Name,
+	}
+	if invalidErr, err := self.FactPublisherService.Save(&publisher); err != nil {
+		self.ServerError(w, err)
+	} else if invalidErr != nil {
+		self.ClientError(w, errors.WithMessage(invalidErr, "Invalid public key"))
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Str("publisher", publisher.Name).Str("key-id", publisher.KeyId).Msg("Registered fact publisher")
+		self.json(w, publisher, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiPublisherNameDelete(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Removing fact publishers requires authentication"), http.StatusUnauthorized})
+		return
 	}
 
-	server := &http.Server{Addr: self.Listen, Handler: muxRouter}
+	name := mux.Vars(req)["name"]
+	if err := self.FactPublisherService.Delete(name); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Str("publisher", name).Msg("Removed fact publisher")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
 
-	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			self.Logger.Err(err).Msgf("Failed to start web server on %s", self.Listen)
-		}
-	}()
+func (self *Web) ApiQuotaGet(w http.ResponseWriter, req *http.Request) {
+	if reports, err := self.QuotaService.GetAll(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, reports, http.StatusOK)
+	}
+}
 
-	<-ctx.Done()
+// Returns (_, _, false) if an error occurred.
+// The error is already sent to the client.
+func (self *Web) getQuotaSubjectName(w http.ResponseWriter, req *http.Request) (domain.QuotaSubject, string, bool) {
+	vars := mux.Vars(req)
+	if subject, err := domain.ParseQuotaSubject(vars["subject"]); err != nil {
+		self.ClientError(w, err)
+	} else if name, err := url.PathUnescape(vars["name"]); err != nil {
+		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of quota name: %q", vars["name"]))
+	} else {
+		return subject, name, true
+	}
+	return "", "", false
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
-		self.Logger.Err(err).Msg("Failed to stop web server")
+func (self *Web) ApiQuotaSubjectNameGet(w http.ResponseWriter, req *http.Request) {
+	subject, name, ok := self.getQuotaSubjectName(w, req)
+	if !ok {
+		return
 	}
 
-	return nil
+	if report, err := self.QuotaService.Get(subject, name); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, report, http.StatusOK)
+	}
 }
 
-func (self *Web) IndexGet(w http.ResponseWriter, req *http.Request) {
-	http.Redirect(w, req, "/action/current?active", http.StatusFound)
+type apiQuotaPutBody struct {
+	// Limits that are omitted are unlimited.
+	MaxRunsPerHour    *int   `json:"max_runs_per_hour,omitempty"`
+	MaxConcurrentRuns *int   `json:"max_concurrent_runs,omitempty"`
+	MaxFactBytes      *int64 `json:"max_fact_bytes,omitempty"`
 }
 
-func (self *Web) ActionCurrentGet(w http.ResponseWriter, req *http.Request) {
-	var actions []domain.Action
-	var err error
+func (self *Web) ApiQuotaSubjectNamePut(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Setting quotas requires authentication"), http.StatusUnauthorized})
+		return
+	}
 
-	_, active := req.URL.Query()["active"]
-	if active {
-		actions, err = self.ActionService.GetCurrentActive()
-	} else {
-		actions, err = self.ActionService.GetCurrent()
+	subject, name, ok := self.getQuotaSubjectName(w, req)
+	if !ok {
+		return
 	}
 
-	if err != nil {
-		self.ServerError(w, err)
+	body := apiQuotaPutBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
 		return
 	}
 
-	if err := render("action/current.html", w, map[string]interface{}{
-		"Actions": actions,
-		"active":  active,
-	}); err != nil {
-		self.ServerError(w, err)
+	if (body.MaxRunsPerHour != nil && *body.MaxRunsPerHour < 0) ||
+		(body.MaxConcurrentRuns != nil && *body.MaxConcurrentRuns < 0) ||
+		(body.MaxFactBytes != nil && *body.MaxFactBytes < 0) {
+		self.ClientError(w, errors.New("Quota limits must not be negative"))
 		return
 	}
+
+	quota := domain.Quota{
+		Subject:           subject,
+		Name:              name,
+		MaxRunsPerHour:    body.MaxRunsPerHour,
+		MaxConcurrentRuns: body.MaxConcurrentRuns,
+		MaxFactBytes:      body.MaxFactBytes,
+		UpdatedBy:         identity.Name,
+	}
+	if err := self.QuotaService.Save(&quota); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, quota, http.StatusOK)
+	}
 }
 
-func (self *Web) ActionIdRunGet(w http.ResponseWriter, req *http.Request) {
-	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
-		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
-		return
-	} else if page, err := getPage(req); err != nil {
-		self.BadRequest(w, err)
-		return
-	} else if invocations, err := self.InvocationService.GetByActionId(id, page); err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Could not get Invocations by Action ID: %q", id))
+func (self *Web) ApiQuotaSubjectNameDelete(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Removing quotas requires authentication"), http.StatusUnauthorized})
 		return
-	} else {
-		type entry struct {
-			Invocation *domain.Invocation
-			Run        *domain.Run
-		}
+	}
 
-		entries := make([]entry, len(invocations))
+	subject, name, ok := self.getQuotaSubjectName(w, req)
+	if !ok {
+		return
+	}
 
-		{
-			errChan := make(chan error, len(invocations))
+	if err := self.QuotaService.Delete(subject, name); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Str("subject", string(subject)).Str("name", name).Msg("Removed quota")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
 
-			wg := &sync.WaitGroup{}
+type apiQuotaOverridePostBody struct {
+	// Like "2h", lifts the quota until this long from now.
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
 
-			wg.Add(len(invocations))
-			for i, invocation := range invocations {
-				// copy so we don't point to loop variable
-				invocation := invocation
-				entries[i].Invocation = &invocation
+func (self *Web) ApiQuotaSubjectNameOverridePost(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Overriding quotas requires authentication"), http.StatusUnauthorized})
+		return
+	}
 
-				go func(i int, id uuid.UUID) {
-					defer wg.Done()
+	subject, name, ok := self.getQuotaSubjectName(w, req)
+	if !ok {
+		return
+	}
 
-					run, err := self.RunService.GetByInvocationId(id)
-					if err != nil {
-						errChan <- err
-					} else {
-						entries[i].Run = run
-					}
-				}(i, invocation.Id)
-			}
+	body := apiQuotaOverridePostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
 
-			wg.Wait()
+	duration, err := time.ParseDuration(body.Duration)
+	if err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Invalid duration"))
+		return
+	}
+	if body.Reason == "" {
+		self.ClientError(w, errors.New("Overriding a quota needs a reason"))
+		return
+	}
 
-			select {
-			case err := <-errChan:
-				self.ServerError(w, err)
-				return
-			default:
-			}
-		}
+	if quota, err := self.QuotaService.Override(subject, name, time.Now().Add(duration), body.Reason, identity.Name); err != nil {
+		self.ServerError(w, err)
+	} else if quota == nil {
+		self.NotFound(w, errors.Errorf("%s %q has no quota", subject, name))
+	} else {
+		self.json(w, quota, http.StatusOK)
+	}
+}
 
-		if err := render("action/runs.html", w, struct {
-			Entries []entry
-			*repository.Page
-		}{entries, page}); err != nil {
-			self.ServerError(w, err)
-			return
-		}
+func (self *Web) ApiDigestGet(w http.ResponseWriter, req *http.Request) {
+	if subscriptions, err := self.DigestService.GetAll(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, subscriptions, http.StatusOK)
 	}
 }
 
-func (self *Web) ActionIdVersionGet(w http.ResponseWriter, req *http.Request) {
-	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
-		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
+type apiDigestPostBody struct {
+	Email string `json:"email"`
+	// Only Runs of this project's actions are included if given.
+	Project *string `json:"project,omitempty"`
+	// "daily" or "weekly".
+	Period string `json:"period"`
+}
+
+func (self *Web) ApiDigestPost(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Subscribing to digests requires authentication"), http.StatusUnauthorized})
 		return
-	} else if action, err := self.ActionService.GetById(id); err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by ID: %q", id))
+	}
+
+	body := apiDigestPostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
 		return
-	} else if page, err := getPage(req); err != nil {
-		self.BadRequest(w, err)
+	}
+
+	period, err := domain.ParseDigestPeriod(body.Period)
+	if err != nil {
+		self.ClientError(w, err)
 		return
-	} else if actions, err := self.ActionService.GetByName(action.Name, page); err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by name: %q", action.Name))
+	}
+	if _, err := mail.ParseAddress(body.Email); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Invalid email address"))
 		return
-	} else if err := render("action/version.html", w, struct {
-		ActionID uuid.UUID
-		Actions  []domain.Action
-		*repository.Page
-	}{
-		ActionID: id,
-		Actions:  actions,
-		Page:     page,
-	}); err != nil {
+	}
+
+	subscription := domain.DigestSubscription{
+		Email:     body.Email,
+		Project:   body.Project,
+		Period:    period,
+		CreatedBy: identity.Name,
+	}
+	if err := self.DigestService.Subscribe(&subscription); err != nil {
 		self.ServerError(w, err)
-		return
+	} else {
+		self.Logger.Info().Str("identity", identity.Name).Stringer("subscription", subscription.ID).Msg("Subscribed to digest")
+		self.json(w, subscription, http.StatusOK)
 	}
 }
 
-func (self *Web) ActionIdGet(w http.ResponseWriter, req *http.Request) {
+func (self *Web) ApiDigestIdDelete(w http.ResponseWriter, req *http.Request) {
 	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
-		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
-	} else if action, err := self.ActionService.GetById(id); err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by ID: %q", id))
-	} else if action == nil {
-		self.NotFound(w, nil)
-	} else if _, inputs, err := self.ActionService.IsRunnable(action); err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Could not get facts that satisfy inputs for Action with ID %q", id))
-	} else if err := render("action/[id].html", w, map[string]interface{}{
-		"Action": action,
-		"inputs": inputs,
-	}); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if err := self.DigestService.Unsubscribe(id); err != nil {
+		self.ServerError(w, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Shows what the next digest would contain if it was sent now.
+func (self *Web) ApiDigestIdPreviewGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if subscription, err := self.DigestService.GetById(id); err != nil {
 		self.ServerError(w, err)
+	} else if subscription == nil {
+		self.NotFound(w, errors.Errorf("No digest subscription with ID %q", id))
+	} else if digest, err := self.DigestService.Build(*subscription, time.Now().UTC()); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, digest, http.StatusOK)
 	}
 }
 
-func (self *Web) ActionIdPatch(w http.ResponseWriter, req *http.Request) {
-	self.ApiActionIdPatch(NopResponseWriter{w}, req)
+func (self *Web) ApiWatchGet(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Watching requires authentication"), http.StatusUnauthorized})
+		return
+	}
 
-	if referer := req.Header.Get("Referer"); referer != "" {
-		http.Redirect(w, req, referer, http.StatusFound)
+	if entries, err := self.WatchService.GetWatchList(identity.Name); err != nil {
+		self.ServerError(w, err)
 	} else {
-		http.Redirect(w, req, "/action/current?active", http.StatusFound)
+		self.json(w, entries, http.StatusOK)
 	}
 }
 
-func (self *Web) ActionNewGet(w http.ResponseWriter, req *http.Request) {
-	const templateName = "action/new.html"
+type apiWatchPostBody struct {
+	// Either a Run or an action is watched.
+	RunId      *uuid.UUID `json:"run_id,omitempty"`
+	ActionName *string    `json:"action_name,omitempty"`
+	// Where to email when the watched Runs end, if given.
+	Email *string `json:"email,omitempty"`
+}
 
-	query := req.URL.Query()
-	source := query.Get("source")
-	name := query.Get("name")
+func (self *Web) ApiWatchPost(w http.ResponseWriter, req *http.Request) {
+	body := apiWatchPostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
 
-	// step 1
-	if source == "" {
-		if err := render(templateName, w, nil); err != nil {
-			self.ServerError(w, err)
+	if watch, err := self.watch(req, body); err != nil {
+		self.Error(w, err)
+	} else {
+		self.json(w, watch, http.StatusOK)
+	}
+}
+
+func (self *Web) watch(req *http.Request, body apiWatchPostBody) (*domain.Watch, error) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		return nil, HandlerError{errors.New("Watching requires authentication"), http.StatusUnauthorized}
+	}
+
+	if body.Email != nil {
+		if _, err := mail.ParseAddress(*body.Email); err != nil {
+			return nil, HandlerError{errors.WithMessage(err, "Invalid email address"), http.StatusBadRequest}
 		}
-		return
 	}
 
-	// step 2
-	if name == "" {
-		if names, err := self.EvaluationService.ListActions(source); err != nil {
-			self.ServerError(w, errors.WithMessagef(err, "While listing Actions in %q", source))
-		} else if err := render(templateName, w, map[string]interface{}{"Source": source, "Names": names}); err != nil {
-			self.ServerError(w, err)
+	if body.RunId != nil {
+		if run, err := self.RunService.GetByNomadJobId(*body.RunId); err != nil {
+			return nil, err
+		} else if run == nil {
+			return nil, HandlerError{errors.Errorf("No Run with ID %q", *body.RunId), http.StatusNotFound}
 		}
-		return
 	}
 
-	if action, err := self.ActionService.Create(source, name); err != nil {
-		self.ServerError(w, err)
-		return
+	watch := domain.Watch{
+		User:       identity.Name,
+		RunId:      body.RunId,
+		ActionName: body.ActionName,
+		Email:      body.Email,
+	}
+	if err := watch.Validate(); err != nil {
+		return nil, HandlerError{err, http.StatusBadRequest}
+	}
+	if err := self.WatchService.Watch(&watch); err != nil {
+		return nil, err
+	}
+
+	self.Logger.Info().Str("identity", identity.Name).Stringer("watch", watch.ID).Msg("Watching")
+	return &watch, nil
+}
+
+func (self *Web) ApiWatchIdDelete(w http.ResponseWriter, req *http.Request) {
+	if err := self.unwatch(req); err != nil {
+		self.Error(w, err)
 	} else {
-		http.Redirect(w, req, "/action/"+action.ID.String(), http.StatusFound)
-		return
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-func (self *Web) InvocationIdPost(w http.ResponseWriter, req *http.Request) {
+// Users can only unwatch their own watches.
+func (self *Web) unwatch(req *http.Request) error {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		return HandlerError{errors.New("Watching requires authentication"), http.StatusUnauthorized}
+	}
+
 	id, err := uuid.Parse(mux.Vars(req)["id"])
 	if err != nil {
-		self.ClientError(w, err)
-		return
+		return HandlerError{errors.WithMessage(err, "Failed to parse id"), http.StatusBadRequest}
 	}
 
-	invocation, runFunc, err := self.InvocationService.Retry(id)
-	if err != nil {
+	if watch, err := self.WatchService.GetById(id); err != nil {
+		return err
+	} else if watch == nil || watch.User != identity.Name {
+		return HandlerError{errors.Errorf("No watch with ID %q", id), http.StatusNotFound}
+	}
+
+	return self.WatchService.Unwatch(id)
+}
+
+func (self *Web) ApiMutexGet(w http.ResponseWriter, req *http.Request) {
+	if states, err := self.RunMutexService.GetAll(); err != nil {
 		self.ServerError(w, err)
-		return
+	} else {
+		self.json(w, states, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiMutexNameGet(w http.ResponseWriter, req *http.Request) {
+	if name, err := url.PathUnescape(mux.Vars(req)["name"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse name"))
+	} else if state, err := self.RunMutexService.Get(name); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, state, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiQueueGet(w http.ResponseWriter, req *http.Request) {
+	if self.RunQueueService == nil {
+		self.json(w, []domain.RunQueueEntry{}, http.StatusOK)
+	} else if queue, err := self.RunQueueService.GetAll(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, queue, http.StatusOK)
 	}
-	http.Redirect(w, req, "/invocation/"+invocation.Id.String(), http.StatusFound)
+}
 
-	go func() {
-		if runs, registerFunc, err := runFunc(self.Db); err != nil {
-			self.Logger.Err(err).Stringer("invocation", invocation.Id).Msg("While invoking")
-		} else if err := registerFunc(); err != nil {
-			self.Logger.Err(err).Interface("runs", runs).Msg("While registering job(s) for run(s)")
-		}
-	}()
+type apiQueueIdOverridePostBody struct {
+	// Why the Run may not wait for maintenance windows to close.
+	Reason string `json:"reason"`
 }
 
-func (self *Web) InvocationIdGet(w http.ResponseWriter, req *http.Request) {
+// Lets a queued Run skip maintenance windows, like for an emergency.
+func (self *Web) ApiQueueIdOverridePost(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Overriding maintenance windows requires authentication"), http.StatusUnauthorized})
+		return
+	}
+
 	id, err := uuid.Parse(mux.Vars(req)["id"])
 	if err != nil {
 		self.ClientError(w, err)
 		return
 	}
 
-	invocation, err := self.InvocationService.GetById(id)
-	if err != nil {
-		self.ServerError(w, err)
+	body := apiQueueIdOverridePostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
 		return
 	}
-	if invocation == nil {
-		w.WriteHeader(http.StatusNotFound)
+	if strings.TrimSpace(body.Reason) == "" {
+		self.ClientError(w, errors.New("Give a reason to override maintenance windows"))
 		return
 	}
 
-	log, err := self.InvocationService.GetLog(*invocation)
-	if err != nil {
+	if self.RunQueueService == nil {
+		self.NotFound(w, errors.Errorf("Run %q is not queued", id))
+	} else if overridden, err := self.RunQueueService.Override(id, identity.Name, body.Reason); err != nil {
 		self.ServerError(w, err)
-		return
+	} else if !overridden {
+		self.NotFound(w, errors.Errorf("Run %q is not queued", id))
+	} else {
+		w.WriteHeader(http.StatusNoContent)
 	}
-	log.Deduplicate()
+}
 
-	var run *domain.Run
-	if run_, err := self.RunService.GetByInvocationId(id); err != nil {
+func (self *Web) ApiApprovalGet(w http.ResponseWriter, req *http.Request) {
+	if approvals, err := self.RunApprovalService.GetPending(); err != nil {
 		self.ServerError(w, err)
-		return
-	} else if run_ != nil {
-		run = run_
-	}
-
-	var inputs map[string]domain.Fact
-	if inputFactIds, err := self.InvocationService.GetInputFactIdsById(id); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts IDs"))
-		return
-	} else if inputs_, err := self.FactService.GetInvocationInputFacts(inputFactIds); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts"))
-		return
 	} else {
-		inputs = inputs_
+		self.json(w, approvals, http.StatusOK)
 	}
+}
 
-	if err := render("invocation/[id].html", w, map[string]interface{}{
-		"Invocation": invocation,
-		"Run":        run,
-		"inputs":     inputs,
-		"log":        log,
-	}); err != nil {
+func (self *Web) ApiApprovalIdGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if approval, err := self.RunApprovalService.GetByRunId(id); err != nil {
 		self.ServerError(w, err)
-		return
+	} else if approval == nil {
+		self.NotFound(w, errors.Errorf("Run %q did not need approval", id))
+	} else {
+		self.json(w, approval, http.StatusOK)
 	}
 }
 
-func (self *Web) RunIdDelete(w http.ResponseWriter, req *http.Request) {
+type apiApprovalIdPostBody struct {
+	// Whether to submit the Run's job or deny the Run.
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+func (self *Web) ApiApprovalIdPost(w http.ResponseWriter, req *http.Request) {
 	id, err := uuid.Parse(mux.Vars(req)["id"])
 	if err != nil {
 		self.ClientError(w, err)
 		return
 	}
 
-	self.ApiRunIdDelete(NopResponseWriter{w}, req)
+	body := apiApprovalIdPostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
 
-	if referer := req.Header.Get("Referer"); referer != "" {
-		http.Redirect(w, req, referer, http.StatusFound)
+	if approval, err := self.decideApproval(req, id, body.Approved, body.Comment); err != nil {
+		self.Error(w, err)
 	} else {
-		http.Redirect(w, req, "/run/"+id.String(), http.StatusFound)
+		self.json(w, approval, http.StatusOK)
 	}
 }
 
-func (self *Web) RunIdGet(w http.ResponseWriter, req *http.Request) {
-	id, err := uuid.Parse(mux.Vars(req)["id"])
-	if err != nil {
-		self.ClientError(w, err)
-		return
+// Approves or rejects the Run waiting for approval
+// if the request's identity is one of its approvers.
+func (self *Web) decideApproval(req *http.Request, id uuid.UUID, approved bool, comment string) (*domain.RunApproval, error) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		return nil, HandlerError{errors.New("Deciding on approvals requires authentication"), http.StatusUnauthorized}
 	}
 
 	run, err := self.RunService.GetByNomadJobId(id)
 	if err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Failed to find Run %q", id))
-		return
+		return nil, err
 	}
 	if run == nil {
-		w.WriteHeader(http.StatusNotFound)
-		return
+		return nil, HandlerError{errors.Errorf("Run %q does not exist", id), http.StatusNotFound}
 	}
 
-	invocation, err := self.InvocationService.GetById(run.InvocationId)
+	approval, err := self.RunApprovalService.GetByRunId(id)
 	if err != nil {
-		self.ServerError(w, err)
-		return
+		return nil, err
+	}
+	if approval == nil || !approval.Pending() || run.FinishedAt != nil {
+		return nil, HandlerError{errors.Errorf("Run %q does not wait for approval", id), http.StatusNotFound}
+	}
+	if !approval.Allows(identity.Name) {
+		return nil, HandlerError{errors.New("Not allowed to decide on the approval of this Run"), http.StatusForbidden}
 	}
 
-	action, err := self.ActionService.GetById(invocation.ActionId)
+	decided, err := self.ActionService.Decide(run, approved, identity.Name, strings.TrimSpace(comment))
 	if err != nil {
+		return nil, err
+	}
+	if decided == nil {
+		// Someone else decided in the meantime.
+		return nil, HandlerError{errors.Errorf("Run %q does not wait for approval", id), http.StatusNotFound}
+	}
+
+	self.Logger.Info().
+		Str("identity", identity.Name).
+		Stringer("run", id).
+		Bool("approved", approved).
+		Msg("Decided on approval")
+
+	return decided, nil
+}
+
+func (self *Web) ApiMaintenanceGet(w http.ResponseWriter, req *http.Request) {
+	if self.MaintenanceService == nil {
+		self.json(w, []domain.MaintenanceWindowState{}, http.StatusOK)
+	} else {
+		self.json(w, self.MaintenanceService.GetAll(), http.StatusOK)
+	}
+}
+
+// Environments that were torn down are only included with `?torn_down`.
+func (self *Web) ApiEnvironmentGet(w http.ResponseWriter, req *http.Request) {
+	_, tornDown := req.URL.Query()["torn_down"]
+
+	if page, err := getPage(req); err != nil {
+		self.BadRequest(w, err)
+	} else if envs, err := self.EnvironmentService.Get(tornDown, page); err != nil {
 		self.ServerError(w, err)
-		return
+	} else {
+		self.json(w, envs, http.StatusOK)
 	}
+}
 
-	allocsWithLogs, err := self.RunService.GetRunAllocationsWithLogs(*run)
-	if err != nil {
+func (self *Web) getEnvironment(w http.ResponseWriter, req *http.Request) (*domain.Environment, bool) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+		return nil, false
+	} else if env, err := self.EnvironmentService.GetById(id); err != nil {
 		self.ServerError(w, err)
-		return
+		return nil, false
+	} else if env == nil {
+		self.NotFound(w, errors.Errorf("No environment with ID %q", id))
+		return nil, false
+	} else {
+		return env, true
 	}
+}
 
-	allocs := []*nomad.Allocation{}
-	allocsWithLogsByGroup := map[string][]service.AllocationWithLogs{}
-	for _, alloc := range allocsWithLogs {
-		allocs = append(allocs, alloc.Allocation)
-		allocsWithLogsByGroup[alloc.TaskGroup] = append(allocsWithLogsByGroup[alloc.TaskGroup], alloc)
+func (self *Web) ApiEnvironmentIdGet(w http.ResponseWriter, req *http.Request) {
+	if env, ok := self.getEnvironment(w, req); ok {
+		self.json(w, env, http.StatusOK)
 	}
+}
 
-	var inputs map[string]domain.Fact
-	if inputFactIds, err := self.InvocationService.GetInputFactIdsById(run.InvocationId); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts IDs"))
-		return
-	} else if inputs_, err := self.FactService.GetInvocationInputFacts(inputFactIds); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "Failed to fetch input facts"))
+// Tears the environment down now instead of waiting for it to expire or be closed.
+func (self *Web) ApiEnvironmentIdDelete(w http.ResponseWriter, req *http.Request) {
+	env, ok := self.getEnvironment(w, req)
+	if !ok {
 		return
-	} else {
-		inputs = inputs_
 	}
 
-	output, err := self.InvocationService.GetOutputById(run.InvocationId)
-	if err != nil {
+	if runFunc, err := self.EnvironmentService.TearDown(env, domain.EnvironmentTeardownReasonManual); errors.Is(err, repository.ErrNotFound) {
+		self.Error(w, HandlerError{errors.Errorf("Environment %q was torn down already", env.ID), http.StatusConflict})
+	} else if err != nil {
 		self.ServerError(w, err)
-		return
+	} else if _, registerFunc, err := runFunc(self.Db); err != nil {
+		self.ServerError(w, err)
+	} else if err := registerFunc(); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, env, http.StatusOK)
 	}
+}
 
-	facts, err := self.FactService.GetByRunId(id)
-	if err != nil {
-		self.ServerError(w, err)
-		return
+func (self *Web) ApiQueryGet(w http.ResponseWriter, req *http.Request) {
+	var kind domain.SavedQueryKind
+	if kindStr := req.URL.Query().Get("kind"); kindStr != "" {
+		if kind_, err := domain.ParseSavedQueryKind(kindStr); err != nil {
+			self.ClientError(w, err)
+			return
+		} else {
+			kind = kind_
+		}
 	}
 
-	grafanaUrls, err := self.RunService.GrafanaUrls(allocs, run.FinishedAt)
-	if err != nil {
+	if queries, err := self.SavedQueryService.GetAll(kind); err != nil {
 		self.ServerError(w, err)
-		return
+	} else {
+		self.json(w, queries, http.StatusOK)
 	}
+}
 
-	grafanaLokiUrls, err := self.RunService.GrafanaLokiUrls(allocs, run.FinishedAt)
-	if err != nil {
+// Returns (nil, false) if an error occurred.
+// The error is already sent to the client.
+func (self *Web) getSavedQuery(w http.ResponseWriter, req *http.Request) (*domain.SavedQuery, bool) {
+	name := mux.Vars(req)["name"]
+	if query, err := self.SavedQueryService.Get(name); err != nil {
 		self.ServerError(w, err)
-		return
+	} else if query == nil {
+		self.NotFound(w, errors.Errorf("No saved query named %q", name))
+	} else {
+		return query, true
 	}
+	return nil, false
+}
 
-	cpuMetrics, err := self.RunService.CPUMetrics(allocs, run.FinishedAt)
-	if err != nil {
-		self.ServerError(w, err)
-		return
+func (self *Web) ApiQueryNameGet(w http.ResponseWriter, req *http.Request) {
+	if query, ok := self.getSavedQuery(w, req); ok {
+		self.json(w, query, http.StatusOK)
 	}
+}
 
-	memMetrics, err := self.RunService.MemMetrics(allocs, run.FinishedAt)
-	if err != nil {
-		self.ServerError(w, err)
+type apiQueryPutBody struct {
+	Kind domain.SavedQueryKind `json:"kind"`
+	// For Runs, the URL query of `/api/run` like "status=failed&project=cicero".
+	// For facts, CUE they must match.
+	Query       string `json:"query"`
+	Description string `json:"description,omitempty"`
+}
+
+func (self *Web) ApiQueryNamePut(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Saving queries requires authentication"), http.StatusUnauthorized})
 		return
 	}
 
-	if err := render("run/[id].html", w, map[string]interface{}{
-		"Run": struct {
-			domain.Run
-			Action domain.Action
-		}{*run, *action},
-		"inputs":                inputs,
-		"output":                output,
-		"facts":                 facts,
-		"allocsWithLogsByGroup": allocsWithLogsByGroup,
-		"metrics":               service.GroupMetrics(cpuMetrics, memMetrics),
-		"grafanaUrls":           grafanaUrls,
-		"grafanaLokiUrls":       grafanaLokiUrls,
-	}); err != nil {
-		self.ServerError(w, err)
+	body := apiQueryPutBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
 		return
 	}
-}
-
-func getPage(req *http.Request) (*repository.Page, error) {
-	page := repository.Page{}
 
-	if offsetStr := req.FormValue("offset"); offsetStr == "" {
-		page.Offset = 0
-	} else if offset, err := strconv.Atoi(offsetStr); err != nil {
-		return nil, errors.WithMessage(err, "offset parameter is invalid, should be positive integer")
-	} else {
-		page.Offset = offset
+	query := domain.SavedQuery{
+		Name:        mux.Vars(req)["name"],
+		Kind:        body.Kind,
+		Query:       body.Query,
+		Description: body.Description,
+		UpdatedBy:   identity.Name,
+	}
+	if err := query.Validate(); err != nil {
+		self.ClientError(w, err)
+		return
 	}
 
-	if limitStr := req.FormValue("limit"); limitStr == "" {
-		page.Limit = 10
-	} else if limit, err := strconv.Atoi(limitStr); err != nil {
-		return nil, errors.WithMessage(err, "limit parameter is invalid, should be positive integer")
+	if err := self.SavedQueryService.Save(&query); err != nil {
+		self.ServerError(w, err)
 	} else {
-		page.Limit = limit
+		self.json(w, query, http.StatusOK)
 	}
-
-	return &page, nil
 }
 
-func (self *Web) RunGet(w http.ResponseWriter, req *http.Request) {
-	if page, err := getPage(req); err != nil {
-		self.BadRequest(w, err)
+func (self *Web) ApiQueryNameDelete(w http.ResponseWriter, req *http.Request) {
+	identity := auth.IdentityFromContext(req.Context())
+	if identity == nil {
+		self.Error(w, HandlerError{errors.New("Removing queries requires authentication"), http.StatusUnauthorized})
 		return
-	} else if invocations, err := self.InvocationService.GetAll(page); err != nil {
+	}
+
+	name := mux.Vars(req)["name"]
+	if err := self.SavedQueryService.Delete(name); err != nil {
 		self.ServerError(w, err)
-		return
 	} else {
-		type entry struct {
-			Run        *domain.Run
-			Invocation *domain.Invocation
-			Action     *domain.Action
-		}
-
-		entries := make([]entry, len(invocations))
+		self.Logger.Info().Str("identity", identity.Name).Str("name", name).Msg("Removed saved query")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
 
-		{
-			errChan := make(chan error, len(invocations)*2)
+// Responds with a page of the Runs or all the facts the saved query matches.
+func (self *Web) ApiQueryNameResultGet(w http.ResponseWriter, req *http.Request) {
+	query, ok := self.getSavedQuery(w, req)
+	if !ok {
+		return
+	}
 
-			wg := &sync.WaitGroup{}
+	switch query.Kind {
+	case domain.SavedQueryKindRun:
+		if filter, err := query.RunFilter(); err != nil {
+			self.ServerError(w, errors.WithMessagef(err, "Invalid saved query %q", query.Name))
+		} else if page, err := getPage(req); err != nil {
+			self.BadRequest(w, err)
+		} else if runs, err := self.getRuns(filter, page); err != nil {
+			self.ServerError(w, errors.WithMessage(err, "failed to fetch Runs"))
+		} else {
+			self.json(w, runs, http.StatusOK)
+		}
+	case domain.SavedQueryKindFact:
+		match := util.CUEString(query.Query).Value(nil, nil)
+		if err := match.Err(); err != nil {
+			self.ServerError(w, errors.WithMessagef(err, "Invalid saved query %q", query.Name))
+		} else if facts, err := self.FactService.GetByCue(match); err != nil {
+			self.ServerError(w, errors.WithMessage(err, "Failed to get Facts"))
+		} else {
+			self.recordFactReads(req, facts...)
+			self.json(w, self.redactFacts(facts), http.StatusOK)
+		}
+	}
+}
 
-			wg.Add(len(invocations) * 2)
-			for i, invocation := range invocations {
-				// copy so we don't point to loop variable
-				invocation := invocation
-				entries[i].Invocation = &invocation
+type apiSealKeyResponse struct {
+	// Base64 of the public key to seal values against.
+	PublicKey string `json:"public_key"`
+}
 
-				go func(i int, id uuid.UUID) {
-					defer wg.Done()
-					if action, err := self.ActionService.GetByInvocationId(id); err != nil {
-						errChan <- err
-					} else {
-						entries[i].Action = action
-					}
-				}(i, invocation.Id)
+func (self *Web) ApiSealKeyGet(w http.ResponseWriter, req *http.Request) {
+	if self.Unsealer == nil {
+		self.NotFound(w, errors.New("Cicero has no seal key"))
+	} else {
+		self.json(w, apiSealKeyResponse{PublicKey: self.Unsealer.PublicKey().String()}, http.StatusOK)
+	}
+}
 
-				go func(i int, id uuid.UUID) {
-					defer wg.Done()
-					if run, err := self.RunService.GetByInvocationId(id); err != nil {
-						errChan <- err
-					} else {
-						entries[i].Run = run
-					}
-				}(i, invocation.Id)
-			}
+// Returns false if the fact may not be published.
+// The error is already sent to the client.
+func (self *Web) checkFactQuota(w http.ResponseWriter, req *http.Request, fact domain.Fact) bool {
+	if self.QuotaService == nil {
+		return true
+	}
 
-			wg.Wait()
+	var tokenName *string
+	if identity := auth.IdentityFromContext(req.Context()); identity != nil && identity.TokenId != nil {
+		tokenName = &identity.Name
+	}
 
-			select {
-			case err := <-errChan:
-				self.ServerError(w, err)
-				return
-			default:
-			}
-		}
+	var quotaErr *service.QuotaExceededError
+	if err := self.QuotaService.CheckFact(fact, tokenName); errors.As(err, &quotaErr) {
+		self.Error(w, HandlerError{err, http.StatusTooManyRequests})
+	} else if err != nil {
+		self.ServerError(w, err)
+	} else {
+		return true
+	}
+	return false
+}
 
-		if err := render("run/index.html", w, struct {
-			Entries []entry
-			*repository.Page
-		}{entries, page}); err != nil {
+func (self *Web) ApiRunIdGet(w http.ResponseWriter, req *http.Request) {
+	switch run, ok := self.getRun(w, req); {
+	case !ok:
+	case run == nil:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		if placement, err := self.RunService.GetPlacement(*run); err != nil {
 			self.ServerError(w, err)
-			return
+		} else if lastModified, err := self.runsLastModifiedOrMeasured(*run); err != nil {
+			self.ServerError(w, err)
+		} else {
+			self.jsonConditional(w, req, service.RunDetail{Run: *run, Placement: placement}, lastModified)
 		}
 	}
 }
 
-// Use this to call API request handlers from UI request handlers.
-type NopResponseWriter struct{ http.ResponseWriter }
-
-func (w NopResponseWriter) WriteHeader(int) {}
-
-func (w NopResponseWriter) Write(b []byte) (int, error) {
-	return io.Discard.Write(b)
+func (self *Web) ApiRunIdUsageGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if usage, err := self.CostService.GetUsage(id); err != nil {
+		self.ServerError(w, err)
+	} else if usage == nil {
+		self.NotFound(w, errors.New("Usage of this Run has not been measured yet"))
+	} else {
+		self.json(w, service.RunCost{
+			RunUsage: *usage,
+			Cost:     self.CostService.Cost(usage.CPUSeconds, usage.MemoryByteSeconds),
+		}, http.StatusOK)
+	}
 }
 
-func (self *Web) ApiActionDefinitionSourceGet(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	source, err := url.PathUnescape(vars["source"])
-	if err != nil {
-		self.ClientError(w, errors.WithMessage(err, "Invalid escaping of action definition source"))
-		return
+func (self *Web) ApiRunIdTimingGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if timing, err := self.RunTimingService.GetByRunId(id); err != nil {
+		self.ServerError(w, err)
+	} else if timing == nil {
+		self.NotFound(w, errors.New("Timing of this Run has not been measured yet"))
+	} else {
+		self.json(w, timing, http.StatusOK)
 	}
+}
 
-	if wfs, err := self.EvaluationService.ListActions(source); err != nil {
-		self.ClientError(w, errors.WithMessage(err, "Failed to list actions"))
-		return
+func (self *Web) ApiRunIdManifestGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if manifest, err := self.RunService.GetManifest(id); err != nil {
+		self.ServerError(w, err)
+	} else if manifest == nil {
+		self.NotFound(w, errors.New("This Run has no manifest, its job was not submitted or was submitted before manifests were recorded"))
 	} else {
-		self.json(w, wfs, http.StatusOK)
+		self.json(w, manifest, http.StatusOK)
 	}
 }
 
-func (self *Web) ApiActionDefinitionSourceNameIdGet(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	if source, err := url.PathUnescape(vars["source"]); err != nil {
-		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action definition source: %q", vars["source"]))
-	} else if name, err := url.PathUnescape(vars["name"]); err != nil {
-		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action name: %q", vars["name"]))
-	} else if idStr, err := url.PathUnescape(vars["id"]); err != nil {
-		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action ID: %q", vars["id"]))
-	} else if id, err := uuid.Parse(idStr); err != nil {
-		self.ClientError(w, errors.WithMessagef(err, "Invalid UUID given as action ID: %q", idStr))
-	} else if def, err := self.EvaluationService.EvaluateAction(source, name, id); err != nil {
+func (self *Web) ApiRunIdAnnotationsGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if annotations, err := self.RunAnnotationService.GetByRunId(id); err != nil {
 		self.ServerError(w, err)
+	} else if annotations == nil {
+		self.NotFound(w, errors.New("The log of this Run was not scanned, it is still running or its action has no log matchers"))
 	} else {
-		self.json(w, def, http.StatusOK)
+		self.json(w, annotations, http.StatusOK)
 	}
 }
 
-func (self *Web) ApiInvocationGet(w http.ResponseWriter, req *http.Request) {
-	if page, err := getPage(req); err != nil {
+func (self *Web) ApiRunIdProgressGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if progress, err := self.RunService.GetProgress(id); err != nil {
 		self.ServerError(w, err)
-	} else if invocations, err := self.InvocationService.GetAll(page); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "failed to fetch Invocations"))
 	} else {
-		self.json(w, invocations, http.StatusOK)
+		self.json(w, progress, http.StatusOK)
 	}
 }
 
-func (self *Web) ApiRunGet(w http.ResponseWriter, req *http.Request) {
-	if page, err := getPage(req); err != nil {
+func (self *Web) ApiRunIdDispatchGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, err)
+	} else if dispatches, err := self.RunService.GetDispatches(id); err != nil {
 		self.ServerError(w, err)
-	} else if runs, err := self.RunService.GetAll(page); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "failed to fetch Runs"))
 	} else {
-		self.json(w, runs, http.StatusOK)
+		self.json(w, dispatches, http.StatusOK)
 	}
 }
 
-func getByInputParams(req *http.Request) (bool, *bool, []*uuid.UUID, error) {
-	query := req.URL.Query()
+type apiRunIdProgressPostBody struct {
+	Done    float64                `json:"done"`
+	Total   *float64               `json:"total,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Value   map[string]interface{} `json:"value,omitempty"`
+}
 
-	_, recursive := query["recursive"]
+// Lets a running job report how far it got,
+// usually with the token it was given in `CICERO_RUN_TOKEN`.
+func (self *Web) ApiRunIdProgressPost(w http.ResponseWriter, req *http.Request) {
+	body := apiRunIdProgressPostBody{}
 
-	var ok *bool
-	if okStr := query.Get("ok"); okStr != "" {
-		if ok_, err := strconv.ParseBool(okStr); err != nil {
-			return false, nil, nil, err
-		} else {
-			ok = &ok_
-		}
+	run, ok := self.getRun(w, req)
+	if !ok {
+		return
+	} else if run == nil {
+		self.NotFound(w, nil)
+		return
+	} else if run.FinishedAt != nil {
+		self.Error(w, HandlerError{errors.New("Progress can only be reported while the Run is running"), http.StatusConflict})
+		return
+	} else if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.BadRequest(w, errors.WithMessage(err, "Could not decode body"))
+		return
 	}
 
-	factIds := make([]*uuid.UUID, len(query["input"]))
-	for i, str := range query["input"] {
-		if id, err := uuid.Parse(str); err != nil {
-			return false, nil, nil, err
-		} else {
-			factIds[i] = &id
-		}
+	progress := domain.RunProgress{
+		RunId:   run.NomadJobID,
+		Done:    body.Done,
+		Total:   body.Total,
+		Message: body.Message,
+		Value:   body.Value,
+	}
+	if err := progress.Validate(); err != nil {
+		self.BadRequest(w, errors.WithMessage(err, "Invalid progress"))
+		return
 	}
 
-	return recursive, ok, factIds, nil
-}
-
-func (self *Web) ApiRunByInputGet(w http.ResponseWriter, req *http.Request) {
-	ok := true
-	if recursive, _, factIds, err := getByInputParams(req); err != nil {
-		self.ClientError(w, err)
-	} else if page, err := getPage(req); err != nil {
+	if err := self.RunService.SaveProgress(&progress); err != nil {
 		self.ServerError(w, err)
-	} else if invocations, err := self.InvocationService.GetByInputFactIds(factIds, recursive, &ok, page); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "failed to fetch Invocations"))
-	} else {
-		runs := []*domain.Run{}
-		for _, invocation := range invocations {
-			if run, err := self.RunService.GetByInvocationId(invocation.Id); err != nil {
-				self.ServerError(w, err)
-				return
-			} else if run != nil {
-				runs = append(runs, run)
-			}
-		}
-
-		self.json(w, runs, http.StatusOK)
+		return
 	}
+
+	self.json(w, progress, http.StatusOK)
 }
 
-func (self *Web) ApiInvocationByInputGet(w http.ResponseWriter, req *http.Request) {
-	if recursive, ok, factIds, err := getByInputParams(req); err != nil {
-		self.ClientError(w, err)
-	} else if page, err := getPage(req); err != nil {
+func (self *Web) ApiRunIdNomadTokenGet(w http.ResponseWriter, req *http.Request) {
+	if run, ok := self.getRun(w, req); !ok {
+		return
+	} else if run == nil {
+		self.NotFound(w, nil)
+	} else if tokens, err := self.RunNomadTokenService.GetByRunId(run.NomadJobID); err != nil {
 		self.ServerError(w, err)
-	} else if invocations, err := self.InvocationService.GetByInputFactIds(factIds, recursive, ok, page); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "failed to fetch Invocations"))
 	} else {
-		self.json(w, invocations, http.StatusOK)
+		self.json(w, tokens, http.StatusOK)
 	}
 }
 
-// XXX take form body instead of json?
-type apiActionPostBody struct {
-	Source string  `json:"source"`
-	Name   *string `json:"name"`
+type apiRunIdNomadTokenPostResponse struct {
+	domain.RunNomadToken
+	SecretId string `json:"secret_id"`
 }
 
-func (self *Web) ApiActionPost(w http.ResponseWriter, req *http.Request) {
-	params := apiActionPostBody{}
-	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
-		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+func (self *Web) ApiRunIdNomadTokenPost(w http.ResponseWriter, req *http.Request) {
+	run, ok := self.getRun(w, req)
+	if !ok {
+		return
+	} else if run == nil {
+		self.NotFound(w, nil)
+		return
+	} else if run.FinishedAt != nil {
+		self.Error(w, HandlerError{errors.New("Nomad tokens can only be renewed while the Run is running"), http.StatusConflict})
 		return
 	}
 
-	if params.Name != nil {
-		if action, err := self.ActionService.Create(params.Source, *params.Name); err != nil {
-			self.ClientError(w, err)
-			return
-		} else {
-			self.json(w, action, http.StatusOK)
-		}
+	action, err := self.ActionService.GetByInvocationId(run.InvocationId)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	actionToken, err := action.NomadToken()
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	} else if actionToken == nil {
+		self.NotFound(w, errors.Errorf("Action %q does not give its Runs a Nomad token", action.Name))
+		return
+	}
+
+	token, secret, err := self.RunNomadTokenService.Renew(*run, *actionToken)
+	if err != nil {
+		self.ServerError(w, err)
+		return
+	}
+
+	self.json(w, apiRunIdNomadTokenPostResponse{*token, secret}, http.StatusOK)
+}
+
+func (self *Web) ApiTemplateGet(w http.ResponseWriter, req *http.Request) {
+	if templates, err := self.ActionTemplateService.GetAll(); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get action templates"))
 	} else {
-		if actionNames, err := self.EvaluationService.ListActions(params.Source); err != nil {
-			self.ClientError(w, errors.WithMessage(err, "Failed to list actions"))
-			return
-		} else {
-			actions := make([]*domain.Action, len(actionNames))
-			for i, actionName := range actionNames {
-				if action, err := self.ActionService.Create(params.Source, actionName); err != nil {
-					self.ClientError(w, err)
-					return
-				} else {
-					actions[i] = action
-				}
-			}
-			self.json(w, actions, http.StatusOK)
-		}
+		self.json(w, templates, http.StatusOK)
 	}
 }
 
 // Returns (_, false) if an error occurred.
 // The error is already sent to the client.
-func (self *Web) getRun(w http.ResponseWriter, req *http.Request) (*domain.Run, bool) {
-	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
-		self.ClientError(w, err)
+func (self *Web) getActionTemplate(w http.ResponseWriter, req *http.Request) (*domain.ActionTemplate, bool) {
+	name := mux.Vars(req)["name"]
+	if template, err := self.ActionTemplateService.GetByName(name); err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Failed to get action template %q", name))
+		return nil, false
+	} else if template == nil {
+		self.NotFound(w, errors.Errorf("No action template named %q", name))
 		return nil, false
-	} else if run, err := self.RunService.GetByNomadJobId(id); err != nil {
-		self.ServerError(w, err)
-		return run, false
 	} else {
-		return run, true
+		return template, true
 	}
 }
 
-// Returns (_, false) if an error occurred.
-// The error is already sent to the client.
-func (self *Web) getInvocation(w http.ResponseWriter, req *http.Request) (*domain.Invocation, bool) {
-	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+func (self *Web) ApiTemplateNameGet(w http.ResponseWriter, req *http.Request) {
+	if template, ok := self.getActionTemplate(w, req); ok {
+		self.json(w, template, http.StatusOK)
+	}
+}
+
+type apiTemplateNameInstantiatePostBody struct {
+	Params map[string]string `json:"params"`
+}
+
+type apiTemplateNameInstantiatePostResponse struct {
+	Source string `json:"source"`
+}
+
+// Renders the source of an action from a template.
+// It is not created as actions are evaluated from their source's location.
+func (self *Web) ApiTemplateNameInstantiatePost(w http.ResponseWriter, req *http.Request) {
+	template, ok := self.getActionTemplate(w, req)
+	if !ok {
+		return
+	}
+
+	body := apiTemplateNameInstantiatePostBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.BadRequest(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
+
+	if source, err := template.Instantiate(body.Params); err != nil {
 		self.ClientError(w, err)
-		return nil, false
-	} else if invocation, err := self.InvocationService.GetById(id); err != nil {
-		self.ServerError(w, err)
-		return invocation, false
 	} else {
-		return invocation, true
+		self.json(w, apiTemplateNameInstantiatePostResponse{Source: source}, http.StatusOK)
 	}
 }
 
-func (self *Web) ApiRunIdGet(w http.ResponseWriter, req *http.Request) {
-	switch run, ok := self.getRun(w, req); {
-	case !ok:
-	case run == nil:
-		w.WriteHeader(http.StatusNotFound)
+// Reports the monthly cost of Runs by action or project.
+// The months are given as YYYY-MM, both inclusive,
+// and default to the current month.
+func (self *Web) ApiCostGet(w http.ResponseWriter, req *http.Request) {
+	const monthLayout = "2006-01"
+
+	query := req.URL.Query()
+
+	by := domain.RunUsageGroup(query.Get("by"))
+	switch by {
+	case "":
+		by = domain.RunUsageByAction
+	case domain.RunUsageByAction, domain.RunUsageByProject:
 	default:
-		self.json(w, run, http.StatusOK)
+		self.BadRequest(w, errors.Errorf("Cannot report cost by %q, must be %q or %q", by, domain.RunUsageByAction, domain.RunUsageByProject))
+		return
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if query.Has("from") {
+		if t, err := time.Parse(monthLayout, query.Get("from")); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid from month"))
+			return
+		} else {
+			from = t
+		}
+	}
+
+	to := from
+	if query.Has("to") {
+		if t, err := time.Parse(monthLayout, query.Get("to")); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid to month"))
+			return
+		} else {
+			to = t
+		}
+	}
+	if to.Before(from) {
+		self.BadRequest(w, errors.New("The to month must not be before the from month"))
+		return
+	}
+
+	if report, err := self.CostService.GetMonthlyReport(by, from, to.AddDate(0, 1, 0)); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, report, http.StatusOK)
 	}
 }
 
@@ -1080,10 +3942,8 @@ func (self *Web) ApiInvocationIdOutputGet(w http.ResponseWriter, req *http.Reque
 	//nolint:gocritic // IMHO if-else chain is better than switch here
 	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
 		self.ClientError(w, err)
-	} else if output, err := self.InvocationService.GetOutputById(id); err != nil {
+	} else if output, err := self.InvocationService.GetRunOutputById(id); err != nil {
 		self.ServerError(w, err)
-	} else if output == nil {
-		w.WriteHeader(http.StatusNotFound)
 	} else {
 		self.json(w, output, http.StatusOK)
 	}
@@ -1124,7 +3984,8 @@ func (self *Web) ApiRunIdInputsGet(w http.ResponseWriter, req *http.Request) {
 	} else if inputs, err := self.InvocationService.GetInputFactIdsById(run.InvocationId); err != nil {
 		self.ServerError(w, errors.WithMessage(err, "Could not get Run's Invocation's inputs"))
 	} else {
-		self.json(w, inputs, http.StatusOK)
+		// Inputs do not change after the Run was created.
+		self.jsonConditional(w, req, inputs, run.CreatedAt)
 	}
 }
 
@@ -1136,29 +3997,91 @@ func (self *Web) ApiRunIdOutputGet(w http.ResponseWriter, req *http.Request) {
 		self.ServerError(w, err)
 	} else if run == nil {
 		w.WriteHeader(http.StatusNotFound)
-	} else if output, err := self.InvocationService.GetOutputById(run.InvocationId); err != nil {
+	} else if output, err := self.InvocationService.GetRunOutputById(run.InvocationId); err != nil {
 		self.ServerError(w, err)
-	} else if output == nil {
-		w.WriteHeader(http.StatusNotFound)
 	} else {
-		self.json(w, output, http.StatusOK)
+		// The output is decided when the Run is created.
+		self.jsonConditional(w, req, output, run.CreatedAt)
 	}
 }
 
-func (self *Web) ApiRunIdDelete(w http.ResponseWriter, req *http.Request) {
-	if run, ok := self.getRun(w, req); !ok {
-		return
-	} else if run == nil {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	} else if err := self.RunService.Cancel(run); err != nil {
-		self.ServerError(w, errors.WithMessagef(err, "Failed to cancel Run %q", run.NomadJobID))
+// With `cascade` true the unfinished Runs that were chained from the Run
+// or invoked by facts it published are canceled too, and theirs recursively.
+func (self *Web) ApiRunIdDelete(w http.ResponseWriter, req *http.Request) {
+	cascade := false
+	if str := req.URL.Query().Get("cascade"); str != "" {
+		if b, err := strconv.ParseBool(str); err != nil {
+			self.BadRequest(w, errors.WithMessage(err, "Invalid cascade"))
+			return
+		} else {
+			cascade = b
+		}
+	}
+
+	run, ok := self.getRun(w, req)
+	if !ok {
+		return
+	} else if run == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Downstream Runs are looked up first as canceling may end the Run.
+	var downstream []domain.Run
+	if cascade {
+		var err error
+		if downstream, err = self.RunService.GetDownstream(run.NomadJobID); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+	}
+
+	if err := self.cancelRun(run); err != nil {
+		self.ServerError(w, err)
 		return
 	}
 
+	for i := range downstream {
+		if err := self.cancelRun(&downstream[i]); err != nil {
+			self.ServerError(w, err)
+			return
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (self *Web) cancelRun(run *domain.Run) error {
+	// Runs waiting for approval, a mutex, or in the queue have no Nomad job to stop yet.
+	withdrawn := false
+	if self.RunApprovalService != nil {
+		var err error
+		if withdrawn, err = self.RunApprovalService.Withdraw(run); err != nil {
+			return errors.WithMessagef(err, "Failed to cancel Run %q", run.NomadJobID)
+		}
+	}
+	if !withdrawn && self.RunMutexService != nil {
+		var err error
+		if withdrawn, err = self.RunMutexService.Withdraw(run); err != nil {
+			return errors.WithMessagef(err, "Failed to cancel Run %q", run.NomadJobID)
+		}
+	}
+	if !withdrawn && self.RunQueueService != nil {
+		var err error
+		if withdrawn, err = self.RunQueueService.Withdraw(run); err != nil {
+			return errors.WithMessagef(err, "Failed to cancel Run %q", run.NomadJobID)
+		}
+	}
+
+	if !withdrawn {
+		if err := self.RunService.Cancel(run); err != nil {
+			return errors.WithMessagef(err, "Failed to cancel Run %q", run.NomadJobID)
+		}
+	}
+
+	return nil
+}
+
 func (self *Web) ApiRunIdFactPost(w http.ResponseWriter, req *http.Request) {
 	run, ok := self.getRun(w, req)
 	if !ok {
@@ -1182,8 +4105,12 @@ func (self *Web) ApiRunIdFactPost(w http.ResponseWriter, req *http.Request) {
 
 	fact.RunId = &run.NomadJobID
 
+	if !self.checkFactQuota(w, req, fact) {
+		return
+	}
+
 	if _, runFunc, err := self.FactService.Save(&fact, binary); err != nil {
-		self.ServerError(w, err)
+		self.factSaveError(w, err)
 	} else if _, registerFunc, err := runFunc(self.Db); err != nil {
 		self.ServerError(w, err)
 	} else if err := registerFunc(); err != nil {
@@ -1197,7 +4124,22 @@ func (self *Web) ApiActionGet(w http.ResponseWriter, req *http.Request) {
 	if actions, err := self.ActionService.GetAll(); err != nil {
 		self.ServerError(w, errors.WithMessage(err, "Failed to get all actions"))
 	} else {
-		self.json(w, actions, http.StatusOK)
+		self.jsonList(w, req, actions, http.StatusOK)
+	}
+}
+
+// Lists the current actions with what they do, their inputs and output,
+// owner, tags, and the status of their latest Run.
+// The `q` parameter searches names, descriptions, tags, teams, and inputs.
+// Sorts by `name`, `created_at`, or `last_run` as given by `sort`.
+func (self *Web) ApiActionCatalogGet(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if sort, err := domain.ParseActionCatalogSort(query.Get("sort")); err != nil {
+		self.BadRequest(w, err)
+	} else if entries, err := self.ActionService.GetCatalog(query.Get("q"), sort); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get catalog"))
+	} else {
+		self.jsonList(w, req, entries, http.StatusOK)
 	}
 }
 
@@ -1217,7 +4159,7 @@ func (self *Web) ApiActionCurrentGet(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	self.json(w, actions, http.StatusOK)
+	self.jsonList(w, req, actions, http.StatusOK)
 }
 
 func (self *Web) ApiActionCurrentNameGet(w http.ResponseWriter, req *http.Request) {
@@ -1231,6 +4173,40 @@ func (self *Web) ApiActionCurrentNameGet(w http.ResponseWriter, req *http.Reques
 	}
 }
 
+// The label defaults to the action's name
+// and can be changed with the `label` query parameter.
+func (self *Web) ApiActionCurrentNameBadgeGet(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	if name, err := url.PathUnescape(vars["name"]); err != nil {
+		self.ClientError(w, errors.WithMessagef(err, "Invalid escaping of action name: %q", vars["name"]))
+	} else if run, err := self.RunService.GetLatestByActionName(name); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.badge(w, req, runBadge(badgeLabel(req, name), run))
+	}
+}
+
+func (self *Web) ApiActionIdBadgeGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if action, err := self.ActionService.GetById(id); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get action"))
+	} else if action == nil {
+		self.NotFound(w, errors.Errorf("No action with ID %q", id))
+	} else if run, err := self.RunService.GetLatestByActionId(id); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.badge(w, req, runBadge(badgeLabel(req, action.Name), run))
+	}
+}
+
+func badgeLabel(req *http.Request, name string) string {
+	if label := req.URL.Query().Get("label"); label != "" {
+		return label
+	}
+	return name
+}
+
 func (self *Web) ApiActionIdGet(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	if id, err := uuid.Parse(vars["id"]); err != nil {
@@ -1263,19 +4239,332 @@ func (self *Web) ApiActionIdPatch(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func getLokiPage(req *http.Request) (*service.LokiPage, error) {
+	page := service.LokiPage{Direction: service.LokiForward, Limit: 1000}
+
+	switch direction := req.FormValue("direction"); direction {
+	case "", "forward":
+	case "backward":
+		page.Direction = service.LokiBackward
+	default:
+		return nil, errors.Errorf("direction parameter is invalid, should be forward or backward: %q", direction)
+	}
+
+	if limitStr := req.FormValue("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err != nil || limit < 1 || limit > service.LokiMaxLimit {
+			return nil, errors.Errorf("limit parameter is invalid, should be an integer from 1 to %d", service.LokiMaxLimit)
+		} else {
+			page.Limit = limit
+		}
+	}
+
+	if collapseStr := req.FormValue("collapse"); collapseStr != "" {
+		if collapse, err := strconv.ParseBool(collapseStr); err != nil {
+			return nil, errors.Errorf("collapse parameter is invalid, should be a boolean: %q", collapseStr)
+		} else {
+			page.Collapse = collapse
+		}
+	}
+
+	if severityStr := req.FormValue("severity"); severityStr != "" {
+		if severity, err := service.ParseLogSeverityName(severityStr); err != nil {
+			return nil, errors.WithMessage(err, "severity parameter is invalid")
+		} else {
+			page.Severity = severity
+		}
+	}
+
+	cursorStr, atStr, lineStr := req.FormValue("cursor"), req.FormValue("at"), req.FormValue("line")
+	if (cursorStr != "" && atStr != "") || (cursorStr != "" && lineStr != "") || (atStr != "" && lineStr != "") {
+		return nil, errors.New("only one of the cursor, at, and line parameters may be given")
+	}
+
+	if cursorStr != "" {
+		page.Cursor = &service.LokiCursor{}
+		if err := page.Cursor.UnmarshalText([]byte(cursorStr)); err != nil {
+			return nil, errors.WithMessage(err, "cursor parameter is invalid, should be the `next` value of the previous page")
+		}
+	}
+
+	if atStr != "" {
+		if at, err := time.Parse(time.RFC3339Nano, atStr); err != nil {
+			return nil, errors.WithMessage(err, "at parameter is invalid, should be an RFC 3339 timestamp")
+		} else {
+			page.Cursor = &service.LokiCursor{Time: at.UTC()}
+		}
+	}
+
+	if lineStr != "" {
+		page.Anchor = &service.LokiAnchor{}
+		if err := page.Anchor.UnmarshalText([]byte(lineStr)); err != nil {
+			return nil, errors.WithMessage(err, "line parameter is invalid, should be the `Anchor` of a line")
+		}
+	}
+
+	return &page, nil
+}
+
+// Returns the log of the whole Run or, if `alloc`, `group`,
+// and `task` are given, of a single task, one page at a time.
+// Pages start at the `cursor` of the previous one, at a time given by `at`,
+// or at the line whose `Anchor` is given by `line` for permalinks.
 func (self *Web) ApiRunIdLogGet(w http.ResponseWriter, req *http.Request) {
+	if log, ok := self.getRunLogPage(w, req); ok {
+		self.json(w, log, http.StatusOK)
+	}
+}
+
+type apiRunIdLogMetricsGetResponse struct {
+	service.LokiLogPage
+	Metrics []service.LogMetricsWindow `json:"metrics"`
+}
+
+// Like `ApiRunIdLogGet()` but also returns the CPU and memory usage
+// of the allocations that logged the page's lines
+// in windows of time that span the page.
+func (self *Web) ApiRunIdLogMetricsGet(w http.ResponseWriter, req *http.Request) {
+	if log, ok := self.getRunLogPage(w, req); ok {
+		if metrics, err := self.RunService.LogMetrics(log.Log); err != nil {
+			self.ServerError(w, errors.WithMessage(err, "Failed to get metrics"))
+		} else {
+			self.json(w, apiRunIdLogMetricsGetResponse{log, metrics}, http.StatusOK)
+		}
+	}
+}
+
+type apiRunIdLogDiffGetResponse struct {
+	// The latest Run of the same action that succeeded before this one.
+	Previous domain.Run     `json:"previous"`
+	Diff     domain.LogDiff `json:"diff"`
+}
+
+// Compares the Run's log with that of the latest Run of the same action
+// that succeeded before it, ignoring timestamps and IDs.
+func (self *Web) ApiRunIdLogDiffGet(w http.ResponseWriter, req *http.Request) {
+	if res, err := self.getRunLogDiff(req); err != nil {
+		self.Error(w, err)
+	} else {
+		self.json(w, res, http.StatusOK)
+	}
+}
+
+func (self *Web) RunIdLogDiffGet(w http.ResponseWriter, req *http.Request) {
+	if res, err := self.getRunLogDiff(req); err != nil {
+		self.Error(w, err)
+	} else if err := self.Assets.render("run/log-diff.html", w, map[string]interface{}{
+		"Run":      res.run,
+		"Previous": res.Previous,
+		"Diff":     res.Diff,
+	}); err != nil {
+		self.ServerError(w, err)
+	}
+}
+
+type runLogDiff struct {
+	apiRunIdLogDiffGetResponse
+	run domain.Run
+}
+
+// How many unchanged lines are shown around changes by default.
+const logDiffContext = 3
+
+func (self *Web) getRunLogDiff(req *http.Request) (res runLogDiff, err error) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		return res, HandlerError{errors.WithMessage(err, "Failed to parse id"), http.StatusBadRequest}
+	}
+
+	contextLines := logDiffContext
+	if contextStr := req.URL.Query().Get("context"); contextStr != "" {
+		if contextLines, err = strconv.Atoi(contextStr); err != nil || contextLines < 0 {
+			return res, HandlerError{errors.Errorf("Invalid context %q, must be a non-negative integer", contextStr), http.StatusBadRequest}
+		}
+	}
+
+	run, err := self.RunService.GetByNomadJobId(id)
+	if err != nil {
+		return res, errors.WithMessagef(err, "Failed to find Run %q", id)
+	} else if run == nil {
+		return res, HandlerError{errors.New("No such Run"), http.StatusNotFound}
+	}
+	res.run = *run
+
+	previous, err := self.RunService.GetPreviousSucceeded(*run)
+	if err != nil {
+		return res, errors.WithMessage(err, "Failed to find previous succeeded Run")
+	} else if previous == nil {
+		return res, HandlerError{errors.New("No Run of this action succeeded before"), http.StatusNotFound}
+	}
+	res.Previous = *previous
+
+	if res.Diff, err = self.RunService.LogDiff(*previous, *run, contextLines); err != nil {
+		return res, errors.WithMessage(err, "Failed to compare logs")
+	}
+
+	return res, nil
+}
+
+func (self *Web) getRunLogPage(w http.ResponseWriter, req *http.Request) (log service.LokiLogPage, ok bool) {
 	vars := mux.Vars(req)
+	query := req.URL.Query()
+	alloc, group, task := query.Get("alloc"), query.Get("group"), query.Get("task")
+
 	if id, err := uuid.Parse(vars["id"]); err != nil {
 		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if page, err := getLokiPage(req); err != nil {
+		self.BadRequest(w, err)
+	} else if (alloc != "" || group != "" || task != "") && (alloc == "" || group == "" || task == "") {
+		self.BadRequest(w, errors.New("alloc, group, and task parameters must be given together"))
 	} else if run, err := self.RunService.GetByNomadJobId(id); err != nil {
 		self.ClientError(w, errors.WithMessage(err, "Failed to fetch job"))
 	} else if run == nil {
 		w.WriteHeader(http.StatusNotFound)
-	} else if log, err := self.RunService.JobLog(id, run.CreatedAt, run.FinishedAt); err != nil {
-		self.ServerError(w, errors.WithMessage(err, "Failed to get logs"))
 	} else {
-		self.json(w, log, http.StatusOK)
+		if task != "" {
+			log, err = self.RunService.RunLog(id, alloc, group, task, run.CreatedAt, run.FinishedAt, *page)
+		} else {
+			log, err = self.RunService.JobLog(id, run.CreatedAt, run.FinishedAt, *page)
+		}
+		if err != nil {
+			self.ServerError(w, errors.WithMessage(err, "Failed to get logs"))
+		} else {
+			ok = true
+		}
+	}
+	return
+}
+
+func (self *Web) ApiRunIdTimelineGet(w http.ResponseWriter, req *http.Request) {
+	switch run, ok := self.getRun(w, req); {
+	case !ok:
+	case run == nil:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		if timeline, err := self.RunService.GetTimeline(*run); err != nil {
+			self.ServerError(w, errors.WithMessage(err, "Failed to get timeline"))
+		} else {
+			self.json(w, timeline, http.StatusOK)
+		}
+	}
+}
+
+// Reconstructs what the Run's allocations and tasks
+// looked like at the time given as `t` in RFC 3339.
+func (self *Web) ApiRunIdStateAtGet(w http.ResponseWriter, req *http.Request) {
+	at, err := time.Parse(time.RFC3339Nano, req.URL.Query().Get("t"))
+	if err != nil {
+		self.BadRequest(w, errors.WithMessage(err, "Invalid t, must be RFC 3339"))
+		return
+	}
+
+	switch run, ok := self.getRun(w, req); {
+	case !ok:
+	case run == nil:
+		w.WriteHeader(http.StatusNotFound)
+	case at.Before(run.CreatedAt):
+		self.ClientError(w, errors.Errorf("Run was created after %s", at.Format(time.RFC3339Nano)))
+	default:
+		if state, err := self.RunService.GetStateAt(*run, at.UTC()); err != nil {
+			self.ServerError(w, errors.WithMessage(err, "Failed to get state"))
+		} else {
+			self.json(w, state, http.StatusOK)
+		}
+	}
+}
+
+func (self *Web) ApiRunIdTasksGet(w http.ResponseWriter, req *http.Request) {
+	switch run, ok := self.getRun(w, req); {
+	case !ok:
+	case run == nil:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		if tasks, err := self.RunService.GetTasks(*run); err != nil {
+			self.ServerError(w, errors.WithMessage(err, "Failed to get tasks"))
+		} else {
+			self.json(w, tasks, http.StatusOK)
+		}
+	}
+}
+
+// Whether the identity may execute commands in the Run's tasks,
+// either in those of all Runs or as a member of the owner of its action.
+func (self *Web) execAllowed(req *http.Request, run domain.Run) (bool, error) {
+	if self.ReadOnly {
+		return false, nil
+	}
+
+	identity := auth.IdentityFromContext(req.Context())
+	if self.ExecAllowed.Allows(identity) {
+		return true, nil
+	} else if identity == nil {
+		return false, nil
+	}
+
+	action, err := self.ActionService.GetByRunId(run.NomadJobID)
+	if err != nil || action == nil {
+		return false, err
+	}
+	owner, err := action.Owner()
+	if err != nil {
+		return false, err
+	}
+	return owner.HasMember(identity.Name), nil
+}
+
+func (self *Web) ApiRunIdExecGet(w http.ResponseWriter, req *http.Request) {
+	run, ok := self.getRun(w, req)
+	if !ok {
+		return
+	} else if run == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if allowed, err := self.execAllowed(req, *run); err != nil {
+		self.ServerError(w, err)
+		return
+	} else if !allowed {
+		self.Error(w, HandlerError{errors.New("Not allowed to execute commands in this Run"), http.StatusForbidden})
+		return
+	}
+
+	tty := false
+	if ttyStr := req.FormValue("tty"); ttyStr != "" {
+		if tty_, err := strconv.ParseBool(ttyStr); err != nil {
+			self.ClientError(w, errors.WithMessage(err, "Failed to parse tty"))
+			return
+		} else {
+			tty = tty_
+		}
+	}
+
+	command := req.URL.Query()["command"]
+	if len(command) == 0 {
+		command = []string{"/bin/sh"}
+	}
+
+	conn, err := execUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// The upgrader already replied with an error.
+		self.Logger.Err(err).Msg("Failed to upgrade to websocket")
+		return
 	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	bridge := newExecBridge(conn, cancel)
+	bridge.exit(self.RunService.Exec(ctx, *run, service.ExecOptions{
+		AllocId:      req.FormValue("alloc"),
+		Task:         req.FormValue("task"),
+		Command:      command,
+		Tty:          tty,
+		Stdin:        bridge.stdin,
+		Stdout:       bridge.stdout(),
+		Stderr:       bridge.stderr(),
+		TerminalSize: bridge.terminalSize,
+	}))
 }
 
 func (self *Web) ApiFactIdGet(w http.ResponseWriter, req *http.Request) {
@@ -1284,31 +4573,106 @@ func (self *Web) ApiFactIdGet(w http.ResponseWriter, req *http.Request) {
 		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
 	} else if fact, err := self.FactService.GetById(id); err != nil {
 		self.ServerError(w, errors.WithMessage(err, "Failed to get Fact"))
-	} else {
-		self.json(w, fact, http.StatusOK)
+	} else {
+		var lastModified time.Time
+		if fact != nil {
+			self.recordFactReads(req, *fact)
+			lastModified = factsLastModified(*fact)
+		}
+		self.jsonConditional(w, req, self.redactFact(fact), lastModified)
+	}
+}
+
+func (self *Web) ApiFactIdUsageGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if self.FactUsageService == nil {
+		self.json(w, domain.FactUsage{FactId: id, Readers: []domain.FactReader{}}, http.StatusOK)
+	} else if usage, err := self.FactUsageService.GetById(id); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, usage, http.StatusOK)
+	}
+}
+
+// Lists all facts that match the CUE given as body.
+type apiFactIngestRequest struct {
+	Value interface{} `json:"value"`
+	// Defaults to the configured one.
+	Pipeline *util.IngestPipeline `json:"pipeline,omitempty"`
+}
+
+type apiFactIngestResponse struct {
+	Value interface{} `json:"value"`
+}
+
+// Previews how a fact's value would be transformed before it is saved.
+func (self *Web) ApiFactIngestPost(w http.ResponseWriter, req *http.Request) {
+	params := apiFactIngestRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
+
+	var pipeline util.IngestPipeline
+	if params.Pipeline != nil {
+		pipeline = *params.Pipeline
+	} else if self.Runtime != nil {
+		pipeline = self.Runtime.Get().FactIngest
+	}
+
+	self.json(w, apiFactIngestResponse{Value: self.factRedactions().Apply(pipeline.Apply(params.Value))}, http.StatusOK)
+}
+
+func (self *Web) ApiFactMatchPost(w http.ResponseWriter, req *http.Request) {
+	match, ok := self.getMatchCue(w, req)
+	if !ok {
+		return
+	}
+
+	facts, err := self.FactService.GetByCue(match)
+	if err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get Facts"))
+		return
+	}
+	self.recordFactReads(req, facts...)
+
+	self.json(w, self.redactFacts(facts), http.StatusOK)
+}
+
+func (self *Web) ApiFactMatchLatestPost(w http.ResponseWriter, req *http.Request) {
+	match, ok := self.getMatchCue(w, req)
+	if !ok {
+		return
+	}
+
+	fact, err := self.FactService.GetLatestByCue(match)
+	if err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get Fact"))
+		return
 	}
+	if fact != nil {
+		self.recordFactReads(req, *fact)
+	}
+
+	self.json(w, self.redactFact(fact), http.StatusOK)
 }
 
-func (self *Web) ApiFactMatchLatestPost(w http.ResponseWriter, req *http.Request) {
+func (self *Web) getMatchCue(w http.ResponseWriter, req *http.Request) (match cue.Value, ok bool) {
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
 		self.ServerError(w, err)
 		return
 	}
 
-	match := util.CUEString(body).Value(nil, nil)
+	match = util.CUEString(body).Value(nil, nil)
 	if matchErr := match.Err(); matchErr != nil {
 		self.ClientError(w, errors.WithMessage(matchErr, "Failed to parse match CUE"))
 		return
 	}
 
-	fact, err := self.FactService.GetLatestByCue(match)
-	if err != nil {
-		self.ServerError(w, errors.WithMessage(err, "Failed to get Fact"))
-		return
-	}
-
-	self.json(w, fact, http.StatusOK)
+	ok = true
+	return
 }
 
 type apiActionMatchResponse struct {
@@ -1346,6 +4710,8 @@ func (self apiActionMatchResponse) MarshalJSON() ([]byte, error) {
 
 type apiActionMatchResponseInput struct {
 	SatisfiedByFact *string
+	// The input's default if no fact satisfied it.
+	SatisfiedByDefault interface{}
 
 	MatchWithDeps *cue.Value
 	Matched       *apiActionIoMatchResponseUnified
@@ -1356,11 +4722,13 @@ type apiActionMatchResponseInput struct {
 func (self apiActionMatchResponseInput) MarshalJSON() ([]byte, error) {
 	result := struct {
 		SatisfiedByFact    *string                                    `json:"satisfiedByFact"`
+		SatisfiedByDefault interface{}                                `json:"satisfiedByDefault,omitempty"`
 		MatchWithDeps      *string                                    `json:"matchWithDeps"`
 		MatchedAgainstFact map[string]apiActionIoMatchResponseUnified `json:"matchedAgainstFact"`
 		Matched            *apiActionIoMatchResponseUnified           `json:"matched"`
 	}{
 		SatisfiedByFact:    self.SatisfiedByFact,
+		SatisfiedByDefault: self.SatisfiedByDefault,
 		Matched:            self.Matched,
 		MatchedAgainstFact: self.MatchedAgainstFact,
 	}
@@ -1517,7 +4885,9 @@ func (self *Web) ApiActionMatchPost(w http.ResponseWriter, req *http.Request) {
 					}
 
 					if fact, isSatisfied := satisfied[inputName]; isSatisfied {
-						{
+						if fact.IsInputDefault() {
+							responseInput.SatisfiedByDefault = fact.Value
+						} else {
 							factNameOnHeap := factIdToName[fact.ID]
 							responseInput.SatisfiedByFact = &factNameOnHeap
 						}
@@ -1546,14 +4916,176 @@ func (self *Web) ApiActionMatchPost(w http.ResponseWriter, req *http.Request) {
 	self.json(w, response, http.StatusOK)
 }
 
+type apiActionIdSimulateBody struct {
+	// Hypothetical facts' values by arbitrary names.
+	Facts map[string]interface{} `json:"facts"`
+}
+
+type apiActionIdSimulateResponse struct {
+	Runnable bool                                `json:"runnable"`
+	Inputs   map[string]apiActionIdSimulateInput `json:"inputs"`
+}
+
+type apiActionIdSimulateInput struct {
+	Fact domain.Fact `json:"fact"`
+	// Name of the hypothetical fact that satisfied the input,
+	// nil if it was satisfied by an existing fact.
+	Hypothetical *string `json:"hypothetical,omitempty"`
+	// Whether no fact satisfied the input so the fact is its default.
+	Default bool `json:"default,omitempty"`
+}
+
+// Checks whether the action would be invoked if the given facts were published
+// in addition to the existing ones. Nothing is persisted.
+func (self *Web) ApiActionIdSimulatePost(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not parse Action ID"))
+		return
+	}
+
+	action, err := self.ActionService.GetById(id)
+	if err != nil {
+		self.ServerError(w, errors.WithMessagef(err, "Could not get Action by ID: %q", id))
+		return
+	}
+	if action == nil {
+		self.NotFound(w, errors.Errorf("No Action with ID %q", id))
+		return
+	}
+
+	body := apiActionIdSimulateBody{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Could not unmarshal params from request body"))
+		return
+	}
+
+	response := apiActionIdSimulateResponse{
+		Inputs: map[string]apiActionIdSimulateInput{},
+	}
+
+	okErr := errors.New("ok")
+	if err := self.Db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		actionService := self.ActionService.WithQuerier(tx)
+		factService := self.FactService.WithQuerier(tx)
+
+		// Deactivate all actions to avoid invocations upon fact creation.
+		if _, err := tx.Exec(context.Background(), `UPDATE action SET active = false`); err != nil {
+			return err
+		}
+
+		// Create all facts.
+		factIdToName := map[uuid.UUID]string{}
+		for name, value := range body.Facts {
+			fact := domain.Fact{Value: value}
+			if _, _, err := factService.Save(&fact, nil); err != nil {
+				return err
+			}
+			factIdToName[fact.ID] = name
+		}
+
+		runnable, inputs, err := actionService.IsRunnable(action)
+		if err != nil {
+			return err
+		}
+
+		response.Runnable = runnable
+		for inputName, fact := range inputs {
+			fact := fact
+			input := apiActionIdSimulateInput{}
+			if fact.IsInputDefault() {
+				input.Fact = fact
+				input.Default = true
+			} else if name, isHypothetical := factIdToName[fact.ID]; isHypothetical {
+				input.Fact = fact
+				input.Hypothetical = &name
+			} else {
+				input.Fact = *self.redactFact(&fact)
+			}
+			response.Inputs[inputName] = input
+		}
+
+		// IMPORTANT
+		// Return an error to roll back the transaction.
+		// We do not actually want to publish any facts!
+		return okErr
+	}); !errors.Is(err, okErr) {
+		self.ServerError(w, err)
+		return
+	}
+
+	self.json(w, response, http.StatusOK)
+}
+
+type apiActionIdSizingResponse struct {
+	// Nil unless the action's meta asks for its jobs to be resized.
+	Sizing          *domain.ActionSizing            `json:"sizing"`
+	Recommendations []domain.ResourceRecommendation `json:"recommendations"`
+}
+
+func (self *Web) ApiActionIdSizingGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if action, err := self.ActionService.GetById(id); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get action"))
+	} else if action == nil {
+		self.NotFound(w, errors.Errorf("No action with ID %q", id))
+	} else if sizing, err := action.Sizing(); err != nil {
+		self.ClientError(w, err)
+	} else if recommendations, err := self.SizingService.GetRecommendations(action.Name); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, apiActionIdSizingResponse{sizing, recommendations}, http.StatusOK)
+	}
+}
+
+// Aggregates how long the Runs of all actions with the action's name
+// that finished within `since`, a duration that defaults to 30 days,
+// spent in each phase.
+func (self *Web) ApiActionIdTimingGet(w http.ResponseWriter, req *http.Request) {
+	since := 30 * 24 * time.Hour
+	if str := req.URL.Query().Get("since"); str != "" {
+		var err error
+		if since, err = time.ParseDuration(str); err != nil || since <= 0 {
+			self.BadRequest(w, errors.Errorf("since parameter is invalid, should be a positive duration: %q", str))
+			return
+		}
+	}
+
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if action, err := self.ActionService.GetById(id); err != nil {
+		self.ServerError(w, errors.WithMessage(err, "Failed to get action"))
+	} else if action == nil {
+		self.NotFound(w, errors.Errorf("No action with ID %q", id))
+	} else if aggregates, err := self.RunTimingService.GetAggregates(action.Name, time.Now().UTC().Add(-since)); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, aggregates, http.StatusOK)
+	}
+}
+
 func (self *Web) ApiFactIdBinaryGet(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
-	if id, err := uuid.Parse(vars["id"]); err != nil {
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
 		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
-	} else if err := self.Db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		return
+	}
+
+	// Binaries do not change so clients that have it need not wait for it to be read from storage.
+	etag := `"` + id.String() + `"`
+	w.Header().Set("ETag", etag)
+	if notModified(req, etag, time.Time{}) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := self.Db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
 		if binary, err := self.FactService.GetBinaryById(tx, id); err != nil {
 			return errors.WithMessage(err, "Failed to get binary")
 		} else {
+			self.recordFactReads(req, domain.Fact{ID: id})
 			http.ServeContent(w, req, "", time.Time{}, binary)
 			if err := binary.Close(); err != nil {
 				return errors.WithMessage(err, "Failed to close binary")
@@ -1565,13 +5097,201 @@ func (self *Web) ApiFactIdBinaryGet(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (self *Web) ApiFactIdBinaryPreviewGet(w http.ResponseWriter, req *http.Request) {
+	if _, preview, err := self.factBinaryPreview(req); err != nil {
+		self.Error(w, err)
+	} else {
+		self.json(w, preview, http.StatusOK)
+	}
+}
+
+func (self *Web) FactIdBinaryPreviewGet(w http.ResponseWriter, req *http.Request) {
+	if fact, preview, err := self.factBinaryPreview(req); err != nil {
+		self.Error(w, err)
+	} else if err := self.Assets.render("fact/binary-preview.html", w, map[string]interface{}{
+		"Fact":    fact,
+		"preview": preview,
+	}); err != nil {
+		self.ServerError(w, err)
+	}
+}
+
+// Previews the binary of the fact whose ID is in the path.
+func (self *Web) factBinaryPreview(req *http.Request) (*domain.Fact, *domain.FactBinaryPreview, error) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		return nil, nil, HandlerError{errors.WithMessage(err, "Failed to parse id"), http.StatusBadRequest}
+	}
+
+	fact, err := self.FactService.GetById(id)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "Failed to get Fact")
+	} else if fact == nil {
+		return nil, nil, HandlerError{errors.New("No such Fact"), http.StatusNotFound}
+	} else if fact.BinaryHash == nil {
+		return nil, nil, HandlerError{errors.New("Fact has no binary"), http.StatusNotFound}
+	}
+
+	var preview domain.FactBinaryPreview
+	if err := self.Db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		binary, err := self.FactService.GetBinaryById(tx, id)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to get binary")
+		}
+		defer binary.Close()
+
+		preview, err = domain.NewFactBinaryPreview(binary)
+		return errors.WithMessage(err, "Failed to preview binary")
+	}); err != nil {
+		return nil, nil, err
+	}
+	self.recordFactReads(req, *fact)
+
+	return fact, &preview, nil
+}
+
+// Lists the links of a fact to others and from others to it.
+func (self *Web) ApiFactIdLinkGet(w http.ResponseWriter, req *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if fact, err := self.FactService.GetById(id); err != nil {
+		self.ServerError(w, err)
+	} else if fact == nil {
+		w.WriteHeader(http.StatusNotFound)
+	} else if links, err := self.FactService.GetLinks(id); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, links, http.StatusOK)
+	}
+}
+
+// Links a fact to another after it was published.
+func (self *Web) ApiFactIdLinkPost(w http.ResponseWriter, req *http.Request) {
+	link := domain.FactLink{}
+
+	if id, err := uuid.Parse(mux.Vars(req)["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+		return
+	} else if fact, err := self.FactService.GetById(id); err != nil {
+		self.ServerError(w, err)
+		return
+	} else if fact == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err := json.NewDecoder(req.Body).Decode(&link); err != nil {
+		self.BadRequest(w, errors.WithMessage(err, "Could not unmarshal fact link"))
+		return
+	} else {
+		link.FactId = id
+	}
+
+	if err := link.Validate(); err != nil {
+		self.BadRequest(w, err)
+	} else if err := self.checkFactLinkTarget(link.TargetId); err.HasError() {
+		self.Error(w, err)
+	} else if err := self.FactService.Link(&link); err != nil {
+		self.ServerError(w, err)
+	} else {
+		self.json(w, link, http.StatusOK)
+	}
+}
+
+func (self *Web) ApiFactIdLinkDelete(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+
+	if id, err := uuid.Parse(vars["id"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse id"))
+	} else if kind, err := domain.ParseFactLinkKind(vars["kind"]); err != nil {
+		self.BadRequest(w, err)
+	} else if target, err := uuid.Parse(vars["target"]); err != nil {
+		self.ClientError(w, errors.WithMessage(err, "Failed to parse target"))
+	} else if err := self.FactService.Unlink(domain.FactLink{FactId: id, Kind: kind, TargetId: target}); err != nil {
+		self.ServerError(w, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (self *Web) checkFactLinkTarget(id uuid.UUID) HandlerError {
+	if fact, err := self.FactService.GetById(id); err != nil {
+		return HandlerError{err, http.StatusInternalServerError}
+	} else if fact == nil {
+		return HandlerError{errors.Errorf("No fact with ID %q to link to", id), http.StatusPreconditionFailed}
+	}
+	return HandlerError{}
+}
+
+// Lists the facts of a Run if `run` is given,
+// otherwise those with the given `namespace`, `name`, and all `tag`s.
+func (self *Web) ApiFactGet(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if query.Has("run") {
+		self.ApiFactByRunGet(w, req)
+		return
+	}
+
+	labels := domain.FactLabels{
+		Namespace: query.Get("namespace"),
+		Name:      query.Get("name"),
+		Tags:      query["tag"],
+	}
+
+	if page, err := getPage(req); err != nil {
+		self.BadRequest(w, err)
+	} else if facts, err := self.FactService.GetByLabels(labels, page); err != nil {
+		self.ServerError(w, err)
+	} else {
+		// Only count reads of the values that are responded with.
+		if getFields(req).Has("value") {
+			self.recordFactReads(req, facts...)
+		}
+		self.jsonListConditional(w, req, self.redactFacts(facts), factsLastModified(facts...))
+	}
+}
+
+// Maximum number of facts in one response of the feed.
+const factFeedLimit = 1000
+
+func (self *Web) ApiFactFeedGet(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	var since int64
+	if sinceStr := query.Get("since"); sinceStr != "" {
+		var err error
+		if since, err = strconv.ParseInt(sinceStr, 10, 64); err != nil || since < 0 {
+			self.BadRequest(w, errors.Errorf("since parameter is invalid, should be a cursor returned by a previous request: %q", sinceStr))
+			return
+		}
+	}
+
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 || limit > factFeedLimit {
+			self.BadRequest(w, errors.Errorf("limit parameter is invalid, should be between 1 and %d: %q", factFeedLimit, limitStr))
+			return
+		}
+	}
+
+	if feed, err := self.FactService.GetFeed(since, limit); err != nil {
+		self.ServerError(w, err)
+	} else {
+		feed.Facts = self.redactFacts(feed.Facts)
+		self.jsonConditional(w, req, feed, factsLastModified(feed.Facts...))
+	}
+}
+
 func (self *Web) ApiFactByRunGet(w http.ResponseWriter, req *http.Request) {
 	if id, err := uuid.Parse(req.URL.Query().Get("run")); err != nil {
 		self.ClientError(w, errors.WithMessage(err, "Failed to parse Run ID"))
-	} else if fact, err := self.FactService.GetByRunId(id); err != nil {
+	} else if facts, err := self.FactService.GetByRunId(id); err != nil {
 		self.ServerError(w, err)
 	} else {
-		self.json(w, fact, http.StatusOK)
+		// Only count reads of the values that are responded with.
+		if getFields(req).Has("value") {
+			self.recordFactReads(req, facts...)
+		}
+		self.jsonListConditional(w, req, self.redactFacts(facts), factsLastModified(facts...))
 	}
 }
 
@@ -1587,8 +5307,12 @@ func (self *Web) ApiFactPost(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if _, runFunc, err := self.FactService.Save(&fact, binary); err != nil {
-		self.ServerError(w, err)
+	if !self.checkFactQuota(w, req, fact) {
+		return
+	}
+
+	if _, runFunc, err := self.FactService.Ingest(&fact, binary); err != nil {
+		self.factSaveError(w, err)
 	} else if _, registerFunc, err := runFunc(self.Db); err != nil {
 		self.ServerError(w, err)
 	} else if err := registerFunc(); err != nil {
@@ -1599,6 +5323,8 @@ func (self *Web) ApiFactPost(w http.ResponseWriter, req *http.Request) {
 }
 
 func (self *Web) getFact(w http.ResponseWriter, req *http.Request) (fact domain.Fact, binary io.ReadCloser, fErr error) {
+	limits := self.factLimits(req)
+
 	binary = io.NopCloser(io.LimitReader(nil, 0))
 	if reader, err := req.MultipartReader(); err == nil {
 		for i := 0; i < 2; i++ {
@@ -1618,27 +5344,103 @@ func (self *Web) getFact(w http.ResponseWriter, req *http.Request) (fact domain.
 			} else {
 				switch i {
 				case 0:
-					factDecoder := json.NewDecoder(part)
+					factDecoder := json.NewDecoder(util.SizeLimitReader(part, limits.Value))
 					if err := factDecoder.Decode(&fact.Value); err != nil {
-						fErr = HandlerError{
-							errors.WithMessage(err, "Could not unmarshal json body"),
-							http.StatusPreconditionFailed,
-						}
+						fErr = factValueError(errors.WithMessage(err, "Could not unmarshal json body"))
 						return
 					}
 				case 1:
-					binary = part
+					binary = util.NewCompositeReadCloser(util.SizeLimitReader(part, limits.Binary), part)
 				}
 			}
 		}
-	} else if binaryReader, err := fact.FromReader(req.Body, true); err != nil {
-		fErr = HandlerError{err, http.StatusPreconditionFailed}
+	} else if binaryReader, err := fact.FromReader(req.Body, true, limits.Value, limits.Binary); err != nil {
+		fErr = factValueError(err)
+		return
 	} else {
 		binary = io.NopCloser(binaryReader)
 	}
+
+	query := req.URL.Query()
+	if query.Has("id") {
+		// Facts moved from another instance may keep their ID.
+		if id, err := uuid.Parse(query.Get("id")); err != nil {
+			fErr = HandlerError{errors.WithMessage(err, "Failed to parse id"), http.StatusBadRequest}
+			return
+		} else {
+			fact.ID = id
+		}
+	}
+	if identity := auth.IdentityFromContext(req.Context()); identity != nil {
+		fact.ApiTokenId = identity.TokenId
+	}
+
+	fact.Namespace = query.Get("namespace")
+	fact.Name = query.Get("name")
+	fact.Tags = query["tag"]
+	if err := fact.FactLabels.Validate(); err != nil {
+		fErr = HandlerError{err, http.StatusBadRequest}
+		return
+	}
+
+	// Links are given like `caused-by=<id>`.
+	for _, kind := range []domain.FactLinkKind{domain.FactLinkSupersedes, domain.FactLinkCausedBy, domain.FactLinkCorrelatesWith} {
+		for _, targetStr := range query[string(kind)] {
+			if target, err := uuid.Parse(targetStr); err != nil {
+				fErr = HandlerError{errors.WithMessagef(err, "Failed to parse %s", kind), http.StatusBadRequest}
+				return
+			} else if err := self.checkFactLinkTarget(target); err.HasError() {
+				fErr = err
+				return
+			} else {
+				fact.Links = append(fact.Links, domain.FactLink{Kind: kind, TargetId: target})
+			}
+		}
+	}
+
+	if header := req.Header.Get(factSignatureHeader); header != "" {
+		if signature, err := base64.StdEncoding.DecodeString(header); err != nil {
+			fErr = HandlerError{errors.WithMessage(err, "Could not decode signature"), http.StatusBadRequest}
+			return
+		} else {
+			signatureStr := string(signature)
+			fact.Signature = &signatureStr
+		}
+
+		if invalidErr, err := self.FactPublisherService.Verify(&fact); err != nil {
+			fErr = HandlerError{err, http.StatusInternalServerError}
+		} else if invalidErr != nil {
+			fErr = HandlerError{errors.WithMessage(invalidErr, "Invalid signature"), http.StatusPreconditionFailed}
+		}
+	}
+
 	return
 }
 
+// Header with the base64-encoded minisign signature of a fact's value.
+const factSignatureHeader = "Cicero-Signature"
+
+func factValueError(err error) HandlerError {
+	var sizeErr *util.SizeLimitExceededError
+	if errors.As(err, &sizeErr) {
+		metricFactRejected.WithLabelValues("value").Inc()
+		return HandlerError{errors.WithMessage(err, "Fact value too large"), http.StatusRequestEntityTooLarge}
+	}
+	return HandlerError{err, http.StatusPreconditionFailed}
+}
+
+// The value has already been decoded at this point
+// so a size limit can only have been exceeded by the binary.
+func (self *Web) factSaveError(w http.ResponseWriter, err error) {
+	var sizeErr *util.SizeLimitExceededError
+	if errors.As(err, &sizeErr) {
+		metricFactRejected.WithLabelValues("binary").Inc()
+		self.Error(w, HandlerError{errors.WithMessage(err, "Fact binary too large"), http.StatusRequestEntityTooLarge})
+		return
+	}
+	self.ServerError(w, err)
+}
+
 type HandlerError struct {
 	error
 	StatusCode int
@@ -1648,8 +5450,19 @@ func (self HandlerError) HasError() bool {
 	return self.error != nil
 }
 
+// Responds with the status that fits the repository error
+// or 500 for any other error.
 func (self *Web) ServerError(w http.ResponseWriter, err error) {
-	self.Error(w, HandlerError{err, http.StatusInternalServerError})
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, repository.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, repository.ErrForeignKey):
+		status = http.StatusUnprocessableEntity
+	}
+	self.Error(w, HandlerError{err, status})
 }
 
 func (self *Web) ClientError(w http.ResponseWriter, err error) {
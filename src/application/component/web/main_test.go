@@ -0,0 +1,539 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/application/component/web/auth"
+	"github.com/input-output-hk/cicero/src/application/service"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func rawFactRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/api/fact", strings.NewReader(body))
+}
+
+func multipartFactRequest(t *testing.T, value, binary string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, content := range []string{value, binary} {
+		if part, err := writer.CreatePart(nil); err != nil {
+			t.Fatal(err)
+		} else if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/fact", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// Mimics what the fact handlers do with the result of `getFact()`
+// without going through `FactService.Save()`.
+func postFact(self *Web, req *http.Request) int {
+	w := httptest.NewRecorder()
+
+	_, binary, err := self.getFact(w, req)
+	if err != nil {
+		self.Error(w, err)
+	} else if _, err := io.ReadAll(binary); err != nil {
+		self.factSaveError(w, err)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.Code
+}
+
+func TestGetFactLimits(t *testing.T) {
+	// not parallel because the metric is global
+
+	runtime, err := config.NewRuntimeConfig("", config.Runtime{LogLevel: "info", FactValueLimit: 5, FactBinaryLimit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limited := &Web{Logger: zerolog.Nop(), Runtime: runtime}
+	unlimited := &Web{Logger: zerolog.Nop()}
+
+	for _, c := range []struct {
+		name   string
+		web    *Web
+		req    func() *http.Request
+		status int
+		part   string
+	}{
+		{"raw at limits", limited, func() *http.Request { return rawFactRequest(`12345 XYZ`) }, http.StatusOK, ""},
+		{"raw value too large", limited, func() *http.Request { return rawFactRequest(`123456 XYZ`) }, http.StatusRequestEntityTooLarge, "value"},
+		{"raw binary too large", limited, func() *http.Request { return rawFactRequest(`12345 WXYZ`) }, http.StatusRequestEntityTooLarge, "binary"},
+		{"raw unlimited", unlimited, func() *http.Request { return rawFactRequest(`123456 WXYZ`) }, http.StatusOK, ""},
+		{"multipart at limits", limited, func() *http.Request { return multipartFactRequest(t, `12345`, `XYZ`) }, http.StatusOK, ""},
+		{"multipart value too large", limited, func() *http.Request { return multipartFactRequest(t, `123456`, `XYZ`) }, http.StatusRequestEntityTooLarge, "value"},
+		{"multipart binary too large", limited, func() *http.Request { return multipartFactRequest(t, `12345`, `WXYZ`) }, http.StatusRequestEntityTooLarge, "binary"},
+		{"multipart unlimited", unlimited, func() *http.Request { return multipartFactRequest(t, `123456`, `WXYZ`) }, http.StatusOK, ""},
+		{"override", limited, func() *http.Request {
+			req := rawFactRequest(`123456 WXYZ`)
+			return req.WithContext(WithFactLimits(req.Context(), FactLimits{}))
+		}, http.StatusOK, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			// given
+			before := map[string]float64{
+				"value":  testutil.ToFloat64(metricFactRejected.WithLabelValues("value")),
+				"binary": testutil.ToFloat64(metricFactRejected.WithLabelValues("binary")),
+			}
+
+			// when
+			status := postFact(c.web, c.req())
+
+			// then
+			assert.Equal(t, c.status, status)
+			for part, count := range before {
+				expected := count
+				if part == c.part {
+					expected++
+				}
+				assert.Equal(t, expected, testutil.ToFloat64(metricFactRejected.WithLabelValues(part)), part)
+			}
+		})
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	for _, c := range []struct {
+		method, path, scope string
+	}{
+		{http.MethodGet, "/", "ui:read"},
+		{http.MethodPost, "/invocation/1", "ui:write"},
+		{http.MethodGet, "/api/fact/1", "facts:read"},
+		{http.MethodPost, "/api/fact", "facts:write"},
+		{http.MethodPost, "/api/fact/match", "facts:read"},
+		{http.MethodPost, "/api/fact/ingest", "facts:read"},
+		{http.MethodGet, "/api/fact/1/binary/preview", "facts:read"},
+		{http.MethodPost, "/api/action/1/simulate", "actions:read"},
+		{http.MethodPost, "/api/action", "actions:write"},
+		{http.MethodGet, "/api/run/1/exec", "runs:exec"},
+		{http.MethodPost, "/api/run/1/progress", "runs:progress:1"},
+		{http.MethodGet, "/api/run/1/progress", "runs:read"},
+		{http.MethodGet, "/api/run/1/dispatch", "runs:read"},
+		{http.MethodGet, "/api/run/1/log/diff", "runs:read"},
+		{http.MethodPost, "/_dispatch/method/DELETE/api/run/1", "runs:write"},
+		{http.MethodPost, "/api/admin/reload", "admin:write"},
+		{http.MethodGet, "/api/admin/stats", "admin:read"},
+		{http.MethodGet, "/api/admin/fact-usage", "admin:read"},
+		{http.MethodGet, "/api/fact/1/usage", "facts:read"},
+		{http.MethodPost, "/api/admin/reconcile-runs", "admin:write"},
+		{http.MethodPost, "/api/admin/replay-facts", "admin:write"},
+		{http.MethodGet, "/api/admin/audit-log", "admin:read"},
+		{http.MethodPost, "/api/template/go-build/instantiate", "templates:read"},
+		{http.MethodGet, "/api/quota", "quotas:read"},
+		{http.MethodPost, "/api/quota/project/cicero/override", "quotas:write"},
+		{http.MethodGet, "/api/mutex/deploy-prod", "mutexes:read"},
+		{http.MethodGet, "/api/queue", "runs:read"},
+		{http.MethodPost, "/api/v1/queue/1/override", "runs:write"},
+		{http.MethodGet, "/api/v1/maintenance", "runs:read"},
+		{http.MethodGet, "/api/v1/approval", "approvals:read"},
+		{http.MethodGet, "/api/v1/action/export", "actions:read"},
+		{http.MethodPost, "/api/v1/action/import?dry-run=true", "actions:write"},
+		{http.MethodGet, "/api/v1/action/1/sizing", "actions:read"},
+		{http.MethodPost, "/api/v1/approval/1", "approvals:write"},
+		{http.MethodGet, "/api/environment", "environments:read"},
+		{http.MethodDelete, "/api/environment/1", "environments:write"},
+		{http.MethodGet, "/api/query/failed-deploys/result", "queries:read"},
+		{http.MethodPut, "/api/query/failed-deploys", "queries:write"},
+		{http.MethodGet, "/api/seal/key", "seal:read"},
+		{http.MethodGet, "/api/v1/fact/1", "facts:read"},
+		{http.MethodPost, "/api/v1/run/1/progress", "runs:progress:1"},
+		{http.MethodGet, "/api/v1/run/1/nomad-token", "runs:read"},
+		{http.MethodPost, "/api/v1/run/1/nomad-token", "runs:nomad-token:1"},
+		{http.MethodPost, "/_dispatch/method/DELETE/api/v1/run/1", "runs:write"},
+		{http.MethodGet, "/api/v1/watch", "watches:read"},
+		{http.MethodDelete, "/api/v1/watch/1", "watches:write"},
+		{http.MethodPost, "/_dispatch/method/DELETE/watch/1", "ui:write"},
+	} {
+		assert.Equal(t, c.scope, requiredScope(httptest.NewRequest(c.method, c.path, nil)), c.method+" "+c.path)
+	}
+}
+
+func TestBadge(t *testing.T) {
+	created := time.Now()
+	finished := created.Add(90 * time.Second)
+
+	assert.Equal(t, badge{"build", "no runs", "#9f9f9f"}, runBadge("build", nil))
+	assert.Equal(t, badge{"build", "running", "#007ec6"}, runBadge("build", &domain.Run{Status: domain.RunStatusRunning, CreatedAt: created}))
+	assert.Equal(t, badge{"build", "failed 1m30s", "#e05d44"}, runBadge("build", &domain.Run{Status: domain.RunStatusFailed, CreatedAt: created, FinishedAt: &finished}))
+
+	web := &Web{Logger: zerolog.Nop()}
+	b := runBadge("a<b", &domain.Run{Status: domain.RunStatusSucceeded, CreatedAt: created, FinishedAt: &finished})
+
+	res := httptest.NewRecorder()
+	web.badge(res, httptest.NewRequest(http.MethodGet, "/api/action/1/badge.svg", nil), b)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "image/svg+xml", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), "a&lt;b")
+	assert.Contains(t, res.Body.String(), "succeeded 1m30s")
+
+	etag := res.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/action/1/badge.svg", nil)
+	req.Header.Set("If-None-Match", etag)
+	res = httptest.NewRecorder()
+	web.badge(res, req, b)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+}
+
+func TestIsBadgeRequest(t *testing.T) {
+	assert.True(t, isBadgeRequest(httptest.NewRequest(http.MethodGet, "/api/action/1/badge.svg", nil)))
+	assert.True(t, isBadgeRequest(httptest.NewRequest(http.MethodGet, "/api/action/current/foo%2Fbar/badge.svg", nil)))
+	assert.False(t, isBadgeRequest(httptest.NewRequest(http.MethodPost, "/api/action/1/badge.svg", nil)))
+	assert.False(t, isBadgeRequest(httptest.NewRequest(http.MethodGet, "/api/run/1/badge.svg", nil)))
+	assert.True(t, isBadgeRequest(httptest.NewRequest(http.MethodGet, "/api/v1/action/1/badge.svg", nil)))
+}
+
+func TestApiVersioning(t *testing.T) {
+	web := Web{Logger: zerolog.Nop()}
+	var path string
+	handler := web.apiVersioning(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.EscapedPath()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		path = ""
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(httptest.NewRequest(http.MethodGet, "/api/v1/fact/1", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "/api/v1/fact/1", path)
+	assert.Equal(t, "v1", res.Header().Get(apiVersionHeader))
+	assert.Empty(t, res.Header().Get("Deprecation"))
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/action/current/foo%2Fbar/badge.svg", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "/api/v1/action/current/foo%2Fbar/badge.svg", path, "unversioned paths are served by the default version")
+	assert.Equal(t, "v1", res.Header().Get(apiVersionHeader))
+	assert.Equal(t, "true", res.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/action/current/foo%2Fbar/badge.svg>; rel="successor-version"`, res.Header().Get("Link"))
+
+	res = serve(httptest.NewRequest(http.MethodPost, "/_dispatch/method/DELETE/api/run/1", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "/_dispatch/method/DELETE/api/v1/run/1", path)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/fact/1", nil)
+	req.Header.Set(apiVersionHeader, "v1")
+	res = serve(req)
+	assert.Equal(t, "/api/v1/fact/1", path)
+	assert.Equal(t, "true", res.Header().Get("Deprecation"), "only the path is versioned")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/fact/1", nil)
+	req.Header.Set(apiVersionHeader, "v9")
+	res = serve(req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Empty(t, path)
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/v9/fact/1", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Empty(t, path)
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/run/1", nil))
+	assert.Equal(t, "/run/1", path)
+	assert.Empty(t, res.Header().Get(apiVersionHeader), "pages are not versioned")
+}
+
+func TestForwarded(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"192.0.2.1", "10.0.0.0/8"})
+	assert.NoError(t, err)
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	web := Web{Logger: zerolog.Nop(), TrustedProxies: proxies}
+	var ip, baseURL string
+	handler := web.forwarded(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ip, baseURL = clientIP(req), requestBaseURL(req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+		ip, baseURL = "", ""
+		req := httptest.NewRequest(http.MethodGet, "/action/1", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("198.51.100.7:1234", nil)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "198.51.100.7", ip)
+	assert.Equal(t, "http://example.com", baseURL)
+
+	res = serve("192.0.2.1:1234", map[string]string{
+		"X-Forwarded-For":   "203.0.113.5, 198.51.100.7, 10.1.2.3",
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "cicero.example",
+	})
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "198.51.100.7", ip, "addresses left of the first untrusted one may be spoofed")
+	assert.Equal(t, "https://cicero.example", baseURL)
+
+	res = serve("192.0.2.1:1234", map[string]string{"X-Forwarded-For": "10.1.2.3"})
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "10.1.2.3", ip)
+
+	res = serve("192.0.2.1:1234", map[string]string{"X-Forwarded-For": "nonsense"})
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = serve("192.0.2.1:1234", map[string]string{"X-Forwarded-Proto": "gopher"})
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = serve("198.51.100.7:1234", map[string]string{"X-Forwarded-For": "192.0.2.1"})
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Empty(t, ip)
+
+	web.TrustedProxies = nil
+	res = serve("198.51.100.7:1234", map[string]string{"X-Forwarded-For": "192.0.2.1", "X-Forwarded-Host": "evil.example"})
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "198.51.100.7", ip, "headers are ignored without trusted proxies")
+	assert.Equal(t, "http://example.com", baseURL)
+}
+
+func TestRefuseWrites(t *testing.T) {
+	web := Web{Logger: zerolog.Nop(), SchemaError: errors.New("mismatch")}
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	res := httptest.NewRecorder()
+	web.refuseWrites(next).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/run", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = httptest.NewRecorder()
+	web.refuseWrites(next).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/fact", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+
+	res = httptest.NewRecorder()
+	web.ReadyzGet(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Contains(t, res.Body.String(), `"error":"mismatch"`)
+
+	web.SchemaError = nil
+
+	res = httptest.NewRecorder()
+	web.refuseWrites(next).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/fact", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = httptest.NewRecorder()
+	web.ReadyzGet(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	web.ReadOnly = true
+
+	res = httptest.NewRecorder()
+	web.refuseWrites(next).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/run", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = httptest.NewRecorder()
+	web.refuseWrites(next).ServeHTTP(res, httptest.NewRequest(http.MethodDelete, "/api/run/1", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", res.Header().Get("Allow"))
+
+	res = httptest.NewRecorder()
+	web.ReadyzGet(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"read_only":true`)
+}
+
+func TestResponseFields(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, getFields(httptest.NewRequest(http.MethodGet, "/api/v1/fact", nil)))
+
+	fields := getFields(httptest.NewRequest(http.MethodGet, "/api/v1/fact?fields=id,+name&fields=run_id,", nil))
+	assert.Equal(t, responseFields{"id": true, "name": true, "run_id": true}, fields)
+	assert.True(t, fields.Has("id"))
+	assert.False(t, fields.Has("value"))
+	assert.True(t, responseFields(nil).Has("value"))
+
+	list := []map[string]interface{}{{"id": 1, "name": "foo", "value": map[string]interface{}{"big": true}}}
+
+	selected, err := fields.Select(list)
+	if assert.NoError(t, err) {
+		if encoded, err := json.Marshal(selected); assert.NoError(t, err) {
+			assert.JSONEq(t, `[{"id":1,"name":"foo"}]`, string(encoded))
+		}
+	}
+
+	selected, err = responseFields(nil).Select(list)
+	if assert.NoError(t, err) {
+		assert.Equal(t, list, selected)
+	}
+}
+
+func TestJsonConditional(t *testing.T) {
+	t.Parallel()
+
+	web := &Web{Logger: zerolog.Nop()}
+	created := time.Date(2022, 10, 22, 12, 0, 0, 500, time.UTC)
+	fact := domain.Fact{CreatedAt: created, Value: map[string]interface{}{"big": true}}
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/fact/1", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		res := httptest.NewRecorder()
+		web.jsonConditional(res, req, fact, factsLastModified(fact))
+		return res
+	}
+
+	res := serve("", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Equal(t, "Sat, 22 Oct 2022 12:00:00 GMT", res.Header().Get("Last-Modified"))
+	assert.Contains(t, res.Body.String(), `"big":true`)
+
+	etag := res.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	for _, c := range []struct {
+		header, value string
+		status        int
+	}{
+		{"If-None-Match", etag, http.StatusNotModified},
+		{"If-None-Match", `"other", ` + etag, http.StatusNotModified},
+		{"If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"If-None-Match", "*", http.StatusNotModified},
+		{"If-None-Match", `"other"`, http.StatusOK},
+		{"If-Modified-Since", "Sat, 22 Oct 2022 12:00:00 GMT", http.StatusNotModified},
+		{"If-Modified-Since", "Sat, 22 Oct 2022 11:59:59 GMT", http.StatusOK},
+		{"If-Modified-Since", "yesterday", http.StatusOK},
+	} {
+		res := serve(c.header, c.value)
+		assert.Equal(t, c.status, res.Code, c.header+": "+c.value)
+		if c.status == http.StatusNotModified {
+			assert.Empty(t, res.Body.String())
+			assert.Equal(t, etag, res.Header().Get("ETag"))
+		}
+	}
+
+	// Running Runs change without noting when.
+	finished := created.Add(time.Minute)
+	gced := finished.Add(time.Hour)
+	assert.Equal(t, gced, runsLastModified(domain.Run{CreatedAt: created, FinishedAt: &finished, NomadJobGCedAt: &gced}))
+	assert.Equal(t, finished, runsLastModified(domain.Run{CreatedAt: created, FinishedAt: &finished}))
+	assert.True(t, runsLastModified(domain.Run{CreatedAt: created, FinishedAt: &finished}, domain.Run{CreatedAt: created}).IsZero())
+	assert.True(t, runsLastModified().IsZero())
+
+	// Annotations, timing, and usage are saved after the Run ended.
+	measured := gced.Add(time.Minute)
+	measuring := &Web{RunService: &fakeRunService{measuredAt: &measured}}
+	lastModified, err := measuring.runsLastModifiedOrMeasured(domain.Run{CreatedAt: created, FinishedAt: &finished, NomadJobGCedAt: &gced})
+	if assert.NoError(t, err) {
+		assert.Equal(t, measured, lastModified)
+	}
+	lastModified, err = measuring.runsLastModifiedOrMeasured(domain.Run{CreatedAt: created})
+	if assert.NoError(t, err) {
+		assert.True(t, lastModified.IsZero())
+	}
+	measuring.RunService = &fakeRunService{}
+	lastModified, err = measuring.runsLastModifiedOrMeasured(domain.Run{CreatedAt: created, FinishedAt: &finished})
+	if assert.NoError(t, err) {
+		assert.Equal(t, finished, lastModified)
+	}
+}
+
+type fakeRunService struct {
+	service.RunService
+	measuredAt *time.Time
+}
+
+func (self *fakeRunService) GetLastMeasuredAt(...uuid.UUID) (*time.Time, error) {
+	return self.measuredAt, nil
+}
+
+type fakeApiTokenService struct {
+	service.ApiTokenService
+	token   domain.ApiToken
+	revoked bool
+}
+
+func (self *fakeApiTokenService) GetById(id uuid.UUID) (*domain.ApiToken, error) {
+	if id != self.token.ID {
+		return nil, nil
+	}
+	return &self.token, nil
+}
+
+func (self *fakeApiTokenService) Rotate(id uuid.UUID) (*domain.ApiToken, string, error) {
+	self.revoked = true
+	return &domain.ApiToken{ID: uuid.New(), Scopes: self.token.Scopes}, domain.ApiTokenPrefix + "secret", nil
+}
+
+func (self *fakeApiTokenService) Revoke(uuid.UUID) error {
+	self.revoked = true
+	return nil
+}
+
+func TestApiTokenEscalation(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		scopes      []string
+		tokenScopes []string
+		status      int
+	}{
+		{"token with more scopes", []string{"tokens:write"}, []string{"tokens:write", "action:write"}, http.StatusForbidden},
+		{"unlimited token", []string{"tokens:write", "action:write"}, nil, http.StatusForbidden},
+		{"token with own scopes", []string{"tokens:write", "action:*"}, []string{"action:write"}, http.StatusOK},
+		{"unlimited identity", nil, nil, http.StatusOK},
+	} {
+		for _, method := range []string{http.MethodPost, http.MethodDelete} {
+			t.Run(c.name+" "+method, func(t *testing.T) {
+				tokens := &fakeApiTokenService{token: domain.ApiToken{ID: uuid.New(), Scopes: c.tokenScopes}}
+				web := &Web{Logger: zerolog.Nop(), ApiTokenService: tokens}
+
+				req := httptest.NewRequest(method, "/api/token/"+tokens.token.ID.String(), nil)
+				req = mux.SetURLVars(req, map[string]string{"id": tokens.token.ID.String()})
+				req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Name: "someone", Scopes: c.scopes}))
+
+				res := httptest.NewRecorder()
+				if method == http.MethodPost {
+					web.ApiTokenIdRotatePost(res, req)
+				} else {
+					web.ApiTokenIdDelete(res, req)
+				}
+
+				if c.status == http.StatusOK && method == http.MethodDelete {
+					assert.Equal(t, http.StatusNoContent, res.Code)
+				} else {
+					assert.Equal(t, c.status, res.Code)
+				}
+				assert.Equal(t, c.status == http.StatusOK, tokens.revoked)
+			})
+		}
+	}
+}
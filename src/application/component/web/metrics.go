@@ -0,0 +1,13 @@
+package web
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var metricFactRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cicero",
+	Subsystem: "web",
+	Name:      "fact_rejected_total",
+	Help:      "Number of fact uploads rejected for exceeding a size limit.",
+}, []string{"part"})
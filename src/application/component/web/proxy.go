@@ -0,0 +1,170 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Reverse proxies whose X-Forwarded-* headers are believed.
+type TrustedProxies []*net.IPNet
+
+// Parses IP addresses and CIDR ranges like 10.0.0.0/8.
+func ParseTrustedProxies(strs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(strs))
+	for _, str := range strs {
+		if !strings.Contains(str, "/") {
+			ip := net.ParseIP(str)
+			if ip == nil {
+				return nil, errors.Errorf("Invalid IP address %q", str)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(str)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid CIDR range %q", str)
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return proxies, nil
+}
+
+func (self TrustedProxies) Contains(ip net.IP) bool {
+	for _, ipNet := range self {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var forwardedHeaders = []string{"X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host"}
+
+// Takes the client's IP address, the scheme, and the host
+// from the X-Forwarded-* headers of requests from trusted proxies.
+// If proxies are trusted, requests from others with such headers
+// are rejected as they may try to pass as someone else.
+// Otherwise the headers are ignored and removed.
+func (self *Web) forwarded(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hasHeaders := false
+		for _, header := range forwardedHeaders {
+			if _, ok := req.Header[header]; ok {
+				hasHeaders = true
+				break
+			}
+		}
+		if !hasHeaders {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if len(self.TrustedProxies) == 0 {
+			for _, header := range forwardedHeaders {
+				req.Header.Del(header)
+			}
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if peer := remoteIP(req.RemoteAddr); peer == nil || !self.TrustedProxies.Contains(peer) {
+			self.Logger.Debug().Str("remote-addr", req.RemoteAddr).Msg("Rejecting forwarded headers from untrusted peer")
+			self.BadRequest(w, errors.New("X-Forwarded-* headers are only accepted from trusted proxies"))
+			return
+		}
+
+		if client, err := self.forwardedFor(req.Header.Values("X-Forwarded-For")); err != nil {
+			self.BadRequest(w, err)
+			return
+		} else if client != nil {
+			req.RemoteAddr = client.String()
+		}
+
+		switch proto := firstForwarded(req.Header.Get("X-Forwarded-Proto")); proto {
+		case "":
+		case "http", "https":
+			req.URL.Scheme = proto
+		default:
+			self.BadRequest(w, errors.Errorf("Invalid X-Forwarded-Proto %q", proto))
+			return
+		}
+
+		if host := firstForwarded(req.Header.Get("X-Forwarded-Host")); host != "" {
+			req.Host = host
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Returns the rightmost address that is not a trusted proxy
+// as proxies append the address they received the request from.
+// If all of them are trusted, the leftmost is the client.
+func (self *Web) forwardedFor(values []string) (net.IP, error) {
+	addrs := []string{}
+	for _, value := range values {
+		for _, addr := range strings.Split(value, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	var client net.IP
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip := remoteIP(addrs[i])
+		if ip == nil {
+			return nil, errors.Errorf("Invalid address %q in X-Forwarded-For", addrs[i])
+		}
+		client = ip
+		if !self.TrustedProxies.Contains(ip) {
+			break
+		}
+	}
+	return client, nil
+}
+
+// Proxies in a chain may each add a value, the first is the client's.
+func firstForwarded(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.ToLower(strings.TrimSpace(first))
+}
+
+// Parses an address with or without port.
+func remoteIP(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(strings.Trim(addr, "[]"))
+}
+
+// Returns the client's IP address, which is the address
+// the request came from unless it was forwarded by a trusted proxy.
+func clientIP(req *http.Request) string {
+	if ip := remoteIP(req.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// Returns the scheme and host the client used to make the request,
+// to make absolute URLs that also work behind a reverse proxy.
+func requestBaseURL(req *http.Request) string {
+	scheme := req.URL.Scheme
+	if scheme == "" {
+		if req.TLS != nil {
+			scheme = "https"
+		} else {
+			scheme = "http"
+		}
+	}
+	return scheme + "://" + req.Host
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+type readyz struct {
+	Ready bool `json:"ready"`
+	// Only requests that read are served by design.
+	ReadOnly bool                `json:"read_only,omitempty"`
+	Schema   config.SchemaStatus `json:"schema"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// Tells probes whether this instance can serve all requests.
+// It does not authenticate so that probes need no credentials.
+func (self *Web) ReadyzGet(w http.ResponseWriter, req *http.Request) {
+	res := readyz{Ready: self.SchemaError == nil, Schema: self.Schema, ReadOnly: self.ReadOnly}
+	status := http.StatusOK
+	if self.SchemaError != nil {
+		res.Error = self.SchemaError.Error()
+		status = http.StatusServiceUnavailable
+	}
+	self.json(w, res, status)
+}
+
+// Refuses requests that may write to the database
+// in read-only mode or if its schema does not match what this binary expects.
+func (self *Web) refuseWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if isReadRequest(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if self.ReadOnly {
+			w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodHead, http.MethodOptions}, ", "))
+			self.Error(w, HandlerError{errors.New("This instance of Cicero is read-only"), http.StatusMethodNotAllowed})
+			return
+		}
+		if self.SchemaError != nil {
+			self.Error(w, HandlerError{self.SchemaError, http.StatusServiceUnavailable})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func isReadRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/application/component/web/auth"
+)
+
+// API path segments and the resource their scopes are named after.
+var scopeResources = map[string]string{
+	"action":      "actions",
+	"admin":       "admin",
+	"approval":    "approvals",
+	"cost":        "costs",
+	"digest":      "digests",
+	"environment": "environments",
+	"fact":        "facts",
+	"invocation":  "invocations",
+	"maintenance": "runs",
+	"mutex":       "mutexes",
+	"publisher":   "publishers",
+	"query":       "queries",
+	"queue":       "runs",
+	"quota":       "quotas",
+	"run":         "runs",
+	"seal":        "seal",
+	"template":    "templates",
+	"token":       "tokens",
+	"watch":       "watches",
+}
+
+// Returns the scope an identity needs for a request,
+// for example "facts:write" for `POST /api/fact`.
+// Pages outside of the API need "ui:read" or "ui:write".
+func requiredScope(req *http.Request) string {
+	method, path := req.Method, req.URL.Path
+
+	// see the /_dispatch/method/{method}/ route
+	if rest := strings.TrimPrefix(path, "/_dispatch/method/"); rest != path {
+		method, path, _ = strings.Cut(rest, "/")
+		path = "/" + path
+	}
+
+	segments := strings.Split(strings.Trim(unversionedApiPath(path), "/"), "/")
+
+	resource := "ui"
+	if len(segments) > 1 && segments[0] == "api" {
+		resource = scopeResources[segments[1]]
+		if resource == "" {
+			resource = segments[1]
+		}
+	}
+
+	access := "write"
+	switch {
+	case method == http.MethodGet || method == http.MethodHead:
+		access = "read"
+	case resource == "actions" && (segments[len(segments)-1] == "simulate" || segments[len(segments)-1] == "match"):
+		// These only look at the given facts.
+		access = "read"
+	case resource == "facts" && len(segments) > 2 && (segments[2] == "match" || segments[2] == "ingest"):
+		// These only query facts or preview them.
+		access = "read"
+	case resource == "templates" && segments[len(segments)-1] == "instantiate":
+		// This only renders a template.
+		access = "read"
+	}
+
+	if resource == "runs" && segments[len(segments)-1] == "exec" {
+		access = "exec"
+	}
+
+	// A Run's token may only report the progress of its own Run
+	// and renew the Nomad token of its own Run.
+	if resource == "runs" && access == "write" && len(segments) == 4 {
+		switch segments[3] {
+		case "progress", "nomad-token":
+			access = segments[3] + ":" + segments[2]
+		}
+	}
+
+	return resource + ":" + access
+}
+
+// Rejects requests by identities that lack the scope they need.
+func (self *Web) requireScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if identity := auth.IdentityFromContext(req.Context()); identity != nil {
+			if scope := requiredScope(req); !identity.HasScope(scope) {
+				self.Error(w, HandlerError{errors.Errorf("Missing scope %q", scope), http.StatusForbidden})
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
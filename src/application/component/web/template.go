@@ -2,54 +2,101 @@ package web
 
 import (
 	"embed"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"html/template"
-	"log"
+	"io/fs"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/input-output-hk/cicero/src/domain"
 )
 
-//go:embed templates
-var templatesFs embed.FS
+//go:embed templates static
+var embeddedAssets embed.FS
 
-//go:embed static
-var staticFs embed.FS
+// Templates and static files. Templates are parsed once
+// unless reloading, in which case they are parsed on every render
+// and pages reload themselves when a file changes.
+type Assets struct {
+	fs     fs.FS
+	reload bool
 
-var layout *template.Template
-var templates = map[string]*template.Template{}
+	mutex     sync.Mutex
+	templates map[string]*template.Template
+}
 
-func init() {
-	tmpl, err := templatesFs.ReadFile("templates/layout.html")
-	if err != nil {
-		log.Panic(err)
+// Returns the assets built into the binary.
+func EmbeddedAssets() *Assets {
+	return &Assets{fs: embeddedAssets, templates: map[string]*template.Template{}}
+}
+
+// Returns the assets in the given directory, which is
+// usually the source of this package, and reloads them.
+func DirAssets(dir string) (*Assets, error) {
+	for _, sub := range []string{"templates", "static"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil {
+			return nil, errors.WithMessage(err, "Could not find assets")
+		} else if !info.IsDir() {
+			return nil, errors.Errorf("Could not find assets: %q is not a directory", filepath.Join(dir, sub))
+		}
 	}
-	layout = template.Must(template.New("layout.html").Funcs(templateFuncs).Parse(string(tmpl)))
+	return &Assets{fs: os.DirFS(dir), reload: true}, nil
 }
 
-func loadTemplate(route string) (*template.Template, error) {
-	if found, ok := templates[route]; ok {
-		return found, nil
+func (self *Assets) loadTemplate(route string) (*template.Template, error) {
+	if !self.reload {
+		self.mutex.Lock()
+		defer self.mutex.Unlock()
+
+		if found, ok := self.templates[route]; ok {
+			return found, nil
+		}
+	}
+
+	funcs := template.FuncMap{
+		"reload": func() bool {
+			return self.reload
+		},
 	}
 
-	clone := template.Must(layout.Clone())
-	source, err := templatesFs.ReadFile(filepath.Join("templates", route))
+	// Paths in an fs.FS are always separated by slashes.
+	layoutSource, err := fs.ReadFile(self.fs, "templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+	layout, err := template.New("layout.html").Funcs(templateFuncs).Funcs(funcs).Parse(string(layoutSource))
 	if err != nil {
 		return nil, err
 	}
 
-	parsed := template.Must(clone.New(route).Parse(string(source)))
-	templates[route] = parsed
+	source, err := fs.ReadFile(self.fs, path.Join("templates", route))
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := layout.New(route).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	if !self.reload {
+		self.templates[route] = parsed
+	}
 
 	return parsed, nil
 }
 
-func render(route string, w http.ResponseWriter, data interface{}) error {
-	tmpl, err := loadTemplate(route)
+func (self *Assets) render(route string, w http.ResponseWriter, data interface{}) error {
+	tmpl, err := self.loadTemplate(route)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return err
@@ -63,6 +110,83 @@ func render(route string, w http.ResponseWriter, data interface{}) error {
 	return nil
 }
 
+// Serves files under /static/.
+func (self *Assets) staticHandler() http.Handler {
+	handler := http.FileServer(http.FS(self.fs))
+	if self.reload {
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			next.ServeHTTP(w, req)
+		})
+	}
+	return http.StripPrefix("/", handler)
+}
+
+// How often to look for changed files when reloading.
+const assetsPollInterval = 500 * time.Millisecond
+
+// Sends a server-sent event when a file changes so that pages can reload.
+func (self *Assets) eventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		since, err := self.lastModified()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(assetsPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-ticker.C:
+				if modified, err := self.lastModified(); err != nil {
+					continue
+				} else if modified.After(since) {
+					fmt.Fprint(w, "event: reload\ndata: \n\n")
+					flusher.Flush()
+					return
+				}
+			}
+		}
+	})
+}
+
+func (self *Assets) lastModified() (last time.Time, err error) {
+	for _, dir := range []string{"templates", "static"} {
+		if err = fs.WalkDir(self.fs, dir, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().After(last) {
+				last = info.ModTime()
+			}
+			return nil
+		}); err != nil {
+			return
+		}
+	}
+	return
+}
+
 var templateFuncs = template.FuncMap{
 	"buildInfo": func() domain.BuildInfo {
 		return domain.Build
@@ -76,7 +200,12 @@ var templateFuncs = template.FuncMap{
 		}
 		return string(enc)
 	},
-	"pathEscape": url.PathEscape,
+	"pathEscape":    url.PathEscape,
+	"highlightJson": highlightJson,
+	// Only for data that is known to be safe, like thumbnails Cicero encoded itself.
+	"dataUrl": func(mediaType string, data []byte) template.URL {
+		return template.URL("data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data))
+	},
 	"timeUnixNano": func(ns int64) time.Time {
 		return time.Unix(
 			ns/int64(time.Second),
@@ -90,6 +219,9 @@ var templateFuncs = template.FuncMap{
 	"derefString": func(ptr *string) string {
 		return *ptr
 	},
+	"derefBool": func(ptr *bool) bool {
+		return *ptr
+	},
 	"addInt": func(a int, b int) int {
 		return a + b
 	},
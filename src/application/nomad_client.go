@@ -2,15 +2,81 @@ package application
 
 import (
 	"context"
+	"io"
+	"net/url"
+	"strings"
+
 	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
 )
 
 type NomadClient interface {
-	EventStream(ctx context.Context, index uint64) (<-chan *nomad.Events, error)
+	EventStream(ctx context.Context, index uint64, topics map[nomad.Topic][]string) (<-chan *nomad.Events, error)
 	JobsRegister(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobRegisterResponse, *nomad.WriteMeta, error)
 	JobsDeregister(jobID string, purge bool, q *nomad.WriteOptions) (string, *nomad.WriteMeta, error)
+	JobsDispatch(jobID string, meta map[string]string, payload []byte, q *nomad.WriteOptions) (*nomad.JobDispatchResponse, *nomad.WriteMeta, error)
 	JobsAllocations(jobID string, allAllocs bool, q *nomad.QueryOptions) ([]*nomad.AllocationListStub, *nomad.QueryMeta, error)
 	JobsInfo(jobID string, q *nomad.QueryOptions) (*nomad.Job, *nomad.QueryMeta, error)
+	AllocationsInfo(allocID string, q *nomad.QueryOptions) (*nomad.Allocation, *nomad.QueryMeta, error)
+	AllocationsExec(ctx context.Context, alloc *nomad.Allocation, task string, tty bool, command []string, stdin io.Reader, stdout, stderr io.Writer, terminalSizeCh <-chan nomad.TerminalSize, q *nomad.QueryOptions) (int, error)
+	ACLTokensCreate(token *nomad.ACLToken, q *nomad.WriteOptions) (*nomad.ACLToken, *nomad.WriteMeta, error)
+	ACLTokensDelete(accessorID string, q *nomad.WriteOptions) (*nomad.WriteMeta, error)
+}
+
+// Whether the error is Nomad's response to a request for
+// something that does not exist (anymore).
+func IsNomadNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "Unexpected response code: 404")
+}
+
+// Whether the error means that Nomad could not be reached
+// as opposed to Nomad rejecting the request.
+func IsNomadUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || IsCircuitOpen(err) {
+		return true
+	}
+	for _, code := range []string{"502", "503", "504"} {
+		if strings.Contains(err.Error(), "Unexpected response code: "+code) {
+			return true
+		}
+	}
+	return false
+}
+
+type NomadCluster struct {
+	Name string
+	NomadClient
+}
+
+// Nomad clusters in order of preference.
+// The first one is the default.
+type NomadClusters []NomadCluster
+
+// Returns the cluster with the given name.
+// The empty name refers to the default cluster
+// which is where Runs went before there were multiple clusters.
+func (self NomadClusters) Get(name string) (NomadCluster, bool) {
+	if name == "" && len(self) > 0 {
+		return self[0], true
+	}
+	for _, cluster := range self {
+		if cluster.Name == name {
+			return cluster, true
+		}
+	}
+	return NomadCluster{}, false
+}
+
+// Returns the names that refer to the cluster with the given name.
+func (self NomadClusters) Aliases(name string) []string {
+	if len(self) > 0 && self[0].Name == name {
+		return []string{name, ""}
+	}
+	return []string{name}
 }
 
 type nomadClient struct {
@@ -23,17 +89,8 @@ func NewNomadClient(nClient *nomad.Client) NomadClient {
 	}
 }
 
-func (self *nomadClient) EventStream(ctx context.Context, nomadIndex uint64) (<-chan *nomad.Events, error) {
-	return self.nClient.EventStream().Stream(
-		ctx,
-		map[nomad.Topic][]string{
-			nomad.TopicAllocation: {string(nomad.TopicAll)},
-			nomad.TopicJob:        {string(nomad.TopicAll)},
-			nomad.TopicDeployment: {string(nomad.TopicAll)},
-		},
-		nomadIndex,
-		nil,
-	)
+func (self *nomadClient) EventStream(ctx context.Context, nomadIndex uint64, topics map[nomad.Topic][]string) (<-chan *nomad.Events, error) {
+	return self.nClient.EventStream().Stream(ctx, topics, nomadIndex, nil)
 }
 
 func (self *nomadClient) JobsRegister(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobRegisterResponse, *nomad.WriteMeta, error) {
@@ -44,6 +101,10 @@ func (self *nomadClient) JobsDeregister(jobID string, purge bool, q *nomad.Write
 	return self.nClient.Jobs().Deregister(jobID, purge, q)
 }
 
+func (self *nomadClient) JobsDispatch(jobID string, meta map[string]string, payload []byte, q *nomad.WriteOptions) (*nomad.JobDispatchResponse, *nomad.WriteMeta, error) {
+	return self.nClient.Jobs().Dispatch(jobID, meta, payload, q)
+}
+
 func (self *nomadClient) JobsAllocations(jobID string, allAllocs bool, q *nomad.QueryOptions) ([]*nomad.AllocationListStub, *nomad.QueryMeta, error) {
 	return self.nClient.Jobs().Allocations(jobID, allAllocs, q)
 }
@@ -51,3 +112,122 @@ func (self *nomadClient) JobsAllocations(jobID string, allAllocs bool, q *nomad.
 func (self *nomadClient) JobsInfo(jobID string, q *nomad.QueryOptions) (*nomad.Job, *nomad.QueryMeta, error) {
 	return self.nClient.Jobs().Info(jobID, q)
 }
+
+func (self *nomadClient) AllocationsInfo(allocID string, q *nomad.QueryOptions) (*nomad.Allocation, *nomad.QueryMeta, error) {
+	return self.nClient.Allocations().Info(allocID, q)
+}
+
+func (self *nomadClient) AllocationsExec(ctx context.Context, alloc *nomad.Allocation, task string, tty bool, command []string, stdin io.Reader, stdout, stderr io.Writer, terminalSizeCh <-chan nomad.TerminalSize, q *nomad.QueryOptions) (int, error) {
+	return self.nClient.Allocations().Exec(ctx, alloc, task, tty, command, stdin, stdout, stderr, terminalSizeCh, q)
+}
+
+func (self *nomadClient) ACLTokensCreate(token *nomad.ACLToken, q *nomad.WriteOptions) (*nomad.ACLToken, *nomad.WriteMeta, error) {
+	return self.nClient.ACLTokens().Create(token, q)
+}
+
+func (self *nomadClient) ACLTokensDelete(accessorID string, q *nomad.WriteOptions) (*nomad.WriteMeta, error) {
+	return self.nClient.ACLTokens().Delete(accessorID, q)
+}
+
+// Fails fast while Nomad is unreachable so that callers do not all wait for it.
+// Nomad rejecting requests does not count as a failure.
+// The event stream is left alone as it is consumed in the background
+// and would only be restarted right away.
+type circuitBreakingNomadClient struct {
+	NomadClient
+	breaker *CircuitBreaker
+}
+
+func NewCircuitBreakingNomadClient(client NomadClient, breaker *CircuitBreaker) NomadClient {
+	return &circuitBreakingNomadClient{client, breaker}
+}
+
+func (self *circuitBreakingNomadClient) done(err error) {
+	if errors.Is(err, context.Canceled) {
+		self.breaker.Skip()
+	} else {
+		self.breaker.Done(IsNomadUnreachable(err))
+	}
+}
+
+func (self *circuitBreakingNomadClient) JobsRegister(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobRegisterResponse, *nomad.WriteMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	response, meta, err := self.NomadClient.JobsRegister(job, q)
+	self.done(err)
+	return response, meta, err
+}
+
+func (self *circuitBreakingNomadClient) JobsDeregister(jobID string, purge bool, q *nomad.WriteOptions) (string, *nomad.WriteMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return "", nil, err
+	}
+	evalID, meta, err := self.NomadClient.JobsDeregister(jobID, purge, q)
+	self.done(err)
+	return evalID, meta, err
+}
+
+func (self *circuitBreakingNomadClient) JobsDispatch(jobID string, meta map[string]string, payload []byte, q *nomad.WriteOptions) (*nomad.JobDispatchResponse, *nomad.WriteMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	response, writeMeta, err := self.NomadClient.JobsDispatch(jobID, meta, payload, q)
+	self.done(err)
+	return response, writeMeta, err
+}
+
+func (self *circuitBreakingNomadClient) JobsAllocations(jobID string, allAllocs bool, q *nomad.QueryOptions) ([]*nomad.AllocationListStub, *nomad.QueryMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	allocs, meta, err := self.NomadClient.JobsAllocations(jobID, allAllocs, q)
+	self.done(err)
+	return allocs, meta, err
+}
+
+func (self *circuitBreakingNomadClient) JobsInfo(jobID string, q *nomad.QueryOptions) (*nomad.Job, *nomad.QueryMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	job, meta, err := self.NomadClient.JobsInfo(jobID, q)
+	self.done(err)
+	return job, meta, err
+}
+
+func (self *circuitBreakingNomadClient) AllocationsInfo(allocID string, q *nomad.QueryOptions) (*nomad.Allocation, *nomad.QueryMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	alloc, meta, err := self.NomadClient.AllocationsInfo(allocID, q)
+	self.done(err)
+	return alloc, meta, err
+}
+
+// Sessions are interactive and long so they are only refused while the circuit is open
+// but their outcome is not judged.
+func (self *circuitBreakingNomadClient) AllocationsExec(ctx context.Context, alloc *nomad.Allocation, task string, tty bool, command []string, stdin io.Reader, stdout, stderr io.Writer, terminalSizeCh <-chan nomad.TerminalSize, q *nomad.QueryOptions) (int, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return 0, err
+	}
+	self.breaker.Skip()
+	return self.NomadClient.AllocationsExec(ctx, alloc, task, tty, command, stdin, stdout, stderr, terminalSizeCh, q)
+}
+
+func (self *circuitBreakingNomadClient) ACLTokensCreate(token *nomad.ACLToken, q *nomad.WriteOptions) (*nomad.ACLToken, *nomad.WriteMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	created, meta, err := self.NomadClient.ACLTokensCreate(token, q)
+	self.done(err)
+	return created, meta, err
+}
+
+func (self *circuitBreakingNomadClient) ACLTokensDelete(accessorID string, q *nomad.WriteOptions) (*nomad.WriteMeta, error) {
+	if err := self.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	meta, err := self.NomadClient.ACLTokensDelete(accessorID, q)
+	self.done(err)
+	return meta, err
+}
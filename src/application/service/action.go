@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"cuelang.org/go/cue"
@@ -32,15 +33,38 @@ type ActionService interface {
 	GetAll() ([]domain.Action, error)
 	GetCurrent() ([]domain.Action, error)
 	GetCurrentActive() ([]domain.Action, error)
+	// Returns the current actions whose catalog entries match the query, all if it is empty.
+	GetCatalog(query string, sort domain.ActionCatalogSort) ([]domain.ActionCatalogEntry, error)
 	Save(*domain.Action) error
 	Update(*domain.Action) error
+	// Evaluates the actions whose meta was not saved with them
+	// and saves it. Those that fail to evaluate are skipped.
+	BackfillMeta() error
 	GetSatisfiedInputs(*domain.Action) (map[string]domain.Fact, bool, error)
 	IsRunnable(*domain.Action) (bool, map[string]domain.Fact, error)
 	Create(string, string) (*domain.Action, error)
 	// Returns a nil pointer for the first return value if the Action was not runnable.
 	Invoke(*domain.Action) (*domain.Invocation, InvokeRunFunc, error)
 	InvokeCurrentActive() ([]domain.Invocation, InvokeRunFunc, error)
+	InvokeChain(run *domain.Run, output *domain.Fact) (InvokeRunFunc, error)
+	// Invokes the current active actions, or only those named if any,
+	// with each of the facts in each input it matches, regardless of
+	// whether they were invoked with it before. The other inputs are
+	// satisfied as usual. Only returns the replays if `dryRun`.
+	Replay(facts []domain.Fact, actionNames []string, dryRun bool) ([]domain.FactReplay, InvokeRunFunc, error)
 	NewInvokeRunFunc(*domain.Action, *domain.Invocation, map[string]domain.Fact) InvokeRunFunc
+	// Registers the job of a Run that waited for its action's mutex.
+	RegisterJob(*domain.Run, *nomad.Job) error
+	// Approves or rejects the Run waiting for approval
+	// and submits its job if it was approved.
+	// Returns nil if the Run does not wait for approval.
+	Decide(run *domain.Run, approved bool, by, comment string) (*domain.RunApproval, error)
+	// Returns the current version of each action with the history of its versions.
+	Export() ([]domain.ActionExport, error)
+	// Creates, updates, activates, and deactivates actions so that they match the exports
+	// and returns the changes in order of name, which are only planned if `dryRun`.
+	// If an action fails to import the changes before it stay applied and are returned.
+	Import(exports []domain.ActionExport, prune, dryRun bool) ([]domain.ActionImportChange, error)
 }
 
 // Evaluates the run definition and ends the invocation.
@@ -62,19 +86,42 @@ type actionService struct {
 	actionRepository  repository.ActionRepository
 	evaluationService EvaluationService
 	runService        RunService
-	nomadClient       application.NomadClient
-	db                config.PgxIface
+	runMutexService   RunMutexService
+	// Keeps jobs of actions that need approval until someone decides.
+	runApprovalService RunApprovalService
+	// Holds back jobs until there is room, nil submits them right away.
+	runQueueService RunQueueService
+	// Resizes jobs of actions by the resources their previous Runs used.
+	sizingService SizingService
+	// Mints Nomad tokens for Runs of actions that need one.
+	runNomadTokenService RunNomadTokenService
+	nomadClusters        application.NomadClusters
+	// Added to all jobs before those of the action.
+	jobScheduling domain.JobScheduling
+	// Decides whether Runs' jobs may be submitted, nil admits all.
+	admissionHook AdmissionHook
+	// Decrypts sealed values in jobs before they are submitted, nil if Cicero has no seal key.
+	unsealer *domain.Unsealer
+	db       config.PgxIface
 	ActionServiceCyclicDependencies
 }
 
-func NewActionService(db config.PgxIface, nomadClient application.NomadClient, invocationService *InvocationService, factService *FactService, runService RunService, evaluationService EvaluationService, logger *zerolog.Logger) ActionService {
+func NewActionService(db config.PgxIface, nomadClusters application.NomadClusters, invocationService *InvocationService, factService *FactService, runService RunService, runMutexService RunMutexService, runApprovalService RunApprovalService, runQueueService RunQueueService, sizingService SizingService, runNomadTokenService RunNomadTokenService, evaluationService EvaluationService, jobScheduling domain.JobScheduling, admissionHook AdmissionHook, unsealer *domain.Unsealer, logger *zerolog.Logger) ActionService {
 	return &actionService{
-		logger:            logger.With().Str("component", "ActionService").Logger(),
-		actionRepository:  persistence.NewActionRepository(db),
-		evaluationService: evaluationService,
-		nomadClient:       nomadClient,
-		runService:        runService,
-		db:                db,
+		logger:               logger.With().Str("component", "ActionService").Logger(),
+		actionRepository:     persistence.NewActionRepository(db),
+		evaluationService:    evaluationService,
+		nomadClusters:        nomadClusters,
+		runService:           runService,
+		runMutexService:      runMutexService,
+		runApprovalService:   runApprovalService,
+		runQueueService:      runQueueService,
+		sizingService:        sizingService,
+		runNomadTokenService: runNomadTokenService,
+		jobScheduling:        jobScheduling,
+		admissionHook:        admissionHook,
+		unsealer:             unsealer,
+		db:                   db,
 		ActionServiceCyclicDependencies: ActionServiceCyclicDependencies{
 			invocationService: invocationService,
 			factService:       factService,
@@ -91,12 +138,23 @@ func (self actionService) withQuerier(querier config.PgxIface, cyclicDeps Action
 		logger:                          self.logger,
 		actionRepository:                self.actionRepository.WithQuerier(querier),
 		runService:                      self.runService.WithQuerier(querier),
+		runMutexService:                 self.runMutexService.WithQuerier(querier),
+		runApprovalService:              self.runApprovalService.WithQuerier(querier),
+		sizingService:                   self.sizingService.WithQuerier(querier),
+		runNomadTokenService:            self.runNomadTokenService.WithQuerier(querier),
 		evaluationService:               self.evaluationService,
-		nomadClient:                     self.nomadClient,
+		nomadClusters:                   self.nomadClusters,
+		jobScheduling:                   self.jobScheduling,
+		admissionHook:                   self.admissionHook,
+		unsealer:                        self.unsealer,
 		db:                              querier,
 		ActionServiceCyclicDependencies: cyclicDeps,
 	}
 
+	if self.runQueueService != nil {
+		result.runQueueService = self.runQueueService.WithQuerier(querier)
+	}
+
 	if result.invocationService == nil {
 		r := ActionService(result)
 		result.invocationService = new(InvocationService)
@@ -155,6 +213,24 @@ func (self actionService) GetAll() ([]domain.Action, error) {
 
 func (self actionService) Save(action *domain.Action) error {
 	self.logger.Trace().Str("name", action.Name).Msg("Saving new Action")
+	if err := action.InOut.ValidateOutput(); err != nil {
+		return errors.WithMessagef(err, "Invalid output of Action %q", action.Name)
+	}
+	if _, err := action.JobScheduling(); err != nil {
+		return errors.WithMessagef(err, "Invalid Action %q", action.Name)
+	}
+	if _, err := action.Owner(); err != nil {
+		return errors.WithMessagef(err, "Invalid Action %q", action.Name)
+	}
+	if _, err := action.NodeAffinity(); err != nil {
+		return errors.WithMessagef(err, "Invalid Action %q", action.Name)
+	}
+	if _, err := action.Tags(); err != nil {
+		return errors.WithMessagef(err, "Invalid Action %q", action.Name)
+	}
+	if _, err := action.DispatchEach(); err != nil {
+		return errors.WithMessagef(err, "Invalid Action %q", action.Name)
+	}
 	if err := self.actionRepository.Save(action); err != nil {
 		return errors.WithMessagef(err, "Could not insert Action")
 	}
@@ -171,6 +247,33 @@ func (self actionService) Update(action *domain.Action) error {
 	return nil
 }
 
+func (self actionService) BackfillMeta() error {
+	actions, err := self.actionRepository.GetWithoutMeta()
+	if err != nil {
+		return errors.WithMessage(err, "Could not select Actions without meta")
+	}
+
+	for i := range actions {
+		action := &actions[i]
+		logger := self.logger.With().Str("id", action.ID.String()).Str("name", action.Name).Logger()
+
+		def, err := self.evaluationService.EvaluateAction(action.Source, action.Name, action.ID)
+		if err != nil {
+			// The source may not be available anymore. It is tried again on the next start.
+			logger.Warn().Err(err).Msg("Could not evaluate Action to backfill its meta")
+			continue
+		}
+
+		action.Meta = def.Meta
+		if err := self.actionRepository.UpdateMeta(action); err != nil {
+			return errors.WithMessagef(err, "Could not update meta of Action %q", action.ID)
+		}
+		logger.Debug().Msg("Backfilled meta of Action")
+	}
+
+	return nil
+}
+
 func (self actionService) GetCurrent() (actions []domain.Action, err error) {
 	self.logger.Trace().Msg("Getting current Actions")
 	actions, err = self.actionRepository.GetCurrent()
@@ -185,7 +288,44 @@ func (self actionService) GetCurrentActive() (actions []domain.Action, err error
 	return
 }
 
+func (self actionService) GetCatalog(query string, sort domain.ActionCatalogSort) ([]domain.ActionCatalogEntry, error) {
+	actions, err := self.GetCurrent()
+	if err != nil {
+		return nil, err
+	}
+
+	self.logger.Trace().Str("query", query).Str("sort", string(sort)).Int("actions", len(actions)).Msg("Getting catalog of Actions")
+
+	entries := []domain.ActionCatalogEntry{}
+	for _, action := range actions {
+		lastRun, err := self.runService.GetLatestByActionName(action.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := domain.NewActionCatalogEntry(action, lastRun)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not describe Action %q", action.Name)
+		}
+
+		if query == "" || entry.Matches(query) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Sort(entries)
+
+	return entries, nil
+}
+
 func (self actionService) GetSatisfiedInputs(action *domain.Action) (map[string]domain.Fact, bool, error) {
+	return self.getSatisfiedInputs(action, nil)
+}
+
+// Facts given in overrides are matched against the respective input
+// instead of the latest fact that matches it.
+// Unlike other facts they must match even if the input is optional.
+func (self actionService) getSatisfiedInputs(action *domain.Action, overrides map[string]domain.Fact) (map[string]domain.Fact, bool, error) {
 	logger := self.logger.With().
 		Str("name", action.Name).
 		Str("id", action.ID.String()).
@@ -214,9 +354,30 @@ func (self actionService) GetSatisfiedInputs(action *domain.Action) (map[string]
 		dbConnMutex.Lock()
 		defer dbConnMutex.Unlock()
 
-		switch fact, err := (*self.factService).GetLatestByCue(tValue); {
-		case err != nil:
+		var fact *domain.Fact
+		override, overridden := overrides[name]
+		if overridden {
+			if input.AcceptsSigner(override) {
+				fact = &override
+			}
+		} else if len(input.SignedBy) > 0 {
+			if fact, err = (*self.factService).GetLatestByCueSignedBy(tValue, input.SignedBy); err != nil {
+				return err
+			}
+		} else if fact, err = (*self.factService).GetLatestByCue(tValue); err != nil {
 			return err
+		}
+
+		switch {
+		case fact == nil && input.Default != nil:
+			inputLogger.Debug().Msg("No fact found for input, using its default")
+			inputs[name] = input.DefaultFact()
+
+			if err := t.Fill(struct {
+				Value interface{} `json:"value"`
+			}{input.Default}); err != nil {
+				return err
+			}
 		case fact == nil:
 			if !input.Not && !input.Optional {
 				inputLogger.Debug().
@@ -241,7 +402,7 @@ func (self actionService) GetSatisfiedInputs(action *domain.Action) (map[string]
 						Msg("Fact matches negated input")
 					delete(inputs, name)
 					return errNotRunnable
-				case matchErr != nil && !input.Not && !input.Optional:
+				case matchErr != nil && !input.Not && (!input.Optional || overridden):
 					inputLogger.Debug().
 						Bool("runnable", false).
 						Str("fact", fact.ID.String()).
@@ -325,7 +486,8 @@ func (self actionService) IsRunnable(action *domain.Action) (bool, map[string]do
 
 				switch {
 				case input.Optional && oldFactId == nil:
-					if _, exists := inputs[name]; exists {
+					// Defaults are not facts, so the input is still not satisfied by one.
+					if fact, exists := inputs[name]; exists && !fact.IsInputDefault() {
 						if logger.Debug().Enabled() {
 							logger.Debug().
 								Str("input", name).
@@ -491,6 +653,183 @@ func (self actionService) Invoke(action *domain.Action) (*domain.Invocation, Inv
 	return invocation, self.NewInvokeRunFunc(action, invocation, inputs), nil
 }
 
+// Invokes the actions that the Run's action declares in its chain.
+// Actions whose inputs are not satisfied are skipped.
+func (self actionService) InvokeChain(run *domain.Run, output *domain.Fact) (InvokeRunFunc, error) {
+	action, err := self.GetByRunId(run.NomadJobID)
+	if err != nil || action == nil {
+		return nil, err
+	}
+
+	var runFunc InvokeRunFunc
+
+	for _, link := range action.Chain {
+		logger := self.logger.With().
+			Str("run", run.NomadJobID.String()).
+			Str("chained-action", link.Action).
+			Logger()
+
+		if run.Status != domain.RunStatusSucceeded && !link.ContinueOnError {
+			logger.Debug().Str("status", run.Status.String()).Msg("Not continuing chain after unsuccessful Run")
+			continue
+		}
+
+		next, err := self.GetLatestByName(link.Action)
+		if err != nil {
+			return nil, err
+		} else if next == nil || !next.Active {
+			logger.Warn().Msg("Chained action does not exist or is not active")
+			continue
+		}
+
+		overrides := map[string]domain.Fact{}
+		if link.Input != "" {
+			if output == nil {
+				logger.Warn().Str("input", link.Input).Msg("Run published no output for chained action's input")
+				continue
+			}
+			overrides[link.Input] = *output
+		}
+
+		inputs, satisfied, err := self.getSatisfiedInputs(next, overrides)
+		if err != nil {
+			return nil, err
+		} else if !satisfied {
+			logger.Warn().Msg("Chained action's inputs are not satisfied")
+			continue
+		}
+
+		invocation := &domain.Invocation{ActionId: next.ID, ChainedFrom: &run.NomadJobID}
+		if err := (*self.invocationService).Save(invocation, inputs); err != nil {
+			return nil, err
+		}
+
+		logger.Debug().Str("invocation", invocation.Id.String()).Msg("Invoked chained action")
+
+		runFunc = JoinInvokeRunFuncs(runFunc, self.NewInvokeRunFunc(next, invocation, inputs))
+	}
+
+	return runFunc, nil
+}
+
+func (self actionService) Replay(facts []domain.Fact, actionNames []string, dryRun bool) ([]domain.FactReplay, InvokeRunFunc, error) {
+	replays := []domain.FactReplay{}
+	var runFunc InvokeRunFunc
+
+	names := make(map[string]struct{}, len(actionNames))
+	for _, name := range actionNames {
+		names[name] = struct{}{}
+	}
+
+	if err := self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*actionService)
+
+		actions, err := txSelf.GetCurrentActive()
+		if err != nil {
+			return err
+		}
+
+		for _, action := range actions {
+			// copy so we don't point to loop variable
+			action := action
+
+			if _, named := names[action.Name]; len(names) != 0 && !named {
+				continue
+			}
+
+			definitions, err := action.InOut.Inputs(nil)
+			if err != nil {
+				return errors.WithMessagef(err, "Could not get inputs of Action %q", action.Name)
+			}
+
+			inputNames := make([]string, 0, len(definitions))
+			for name, definition := range definitions {
+				if !definition.Not {
+					inputNames = append(inputNames, name)
+				}
+			}
+			sort.Strings(inputNames)
+
+			for _, fact := range facts {
+				for _, inputName := range inputNames {
+					logger := self.logger.With().
+						Str("action", action.Name).
+						Str("input", inputName).
+						Stringer("fact", fact.ID).
+						Logger()
+
+					inputs, satisfied, err := txSelf.getSatisfiedInputs(&action, map[string]domain.Fact{inputName: fact})
+					if err != nil {
+						return err
+					} else if !satisfied || inputs[inputName].ID != fact.ID {
+						logger.Trace().Msg("Fact does not satisfy input")
+						continue
+					}
+
+					replay := domain.FactReplay{
+						FactId:     fact.ID,
+						ActionId:   action.ID,
+						ActionName: action.Name,
+						Input:      inputName,
+					}
+
+					if !dryRun {
+						invocation := &domain.Invocation{ActionId: action.ID}
+						if err := (*txSelf.invocationService).Save(invocation, inputs); err != nil {
+							return err
+						}
+						replay.InvocationId = &invocation.Id
+
+						logger.Debug().Stringer("invocation", invocation.Id).Msg("Replayed fact")
+
+						runFunc = JoinInvokeRunFuncs(runFunc, self.NewInvokeRunFunc(&action, invocation, inputs))
+					}
+
+					replays = append(replays, replay)
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return replays, runFunc, nil
+}
+
+// Returns an InvokeRunFunc that calls both. Either may be nil.
+func JoinInvokeRunFuncs(a, b InvokeRunFunc) InvokeRunFunc {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+
+	return func(db config.PgxIface) ([]domain.Run, InvokeRegisterFunc, error) {
+		runsA, registerA, err := a(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		runsB, registerB, err := b(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(runsA, runsB...), func() error {
+			for _, register := range []InvokeRegisterFunc{registerA, registerB} {
+				if register == nil {
+					continue
+				}
+				if err := register(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+}
+
 func (self actionService) InvokeCurrentActive() ([]domain.Invocation, InvokeRunFunc, error) {
 	runFuncs := []InvokeRunFunc{}
 	invocations := []domain.Invocation{}
@@ -551,6 +890,46 @@ func (self actionService) InvokeCurrentActive() ([]domain.Invocation, InvokeRunF
 		})
 }
 
+// Meta attribute of an action with the name or a list of names
+// of the Nomad clusters to place its Runs on in order of preference.
+// If it is not given all clusters are tried in the configured order.
+const ActionMetaNomadCluster = "nomad_cluster"
+
+// Returns the Nomad clusters to try registering the action's job with in order.
+func (self actionService) placement(action *domain.Action) (application.NomadClusters, error) {
+	var names []string
+	switch meta := action.Meta[ActionMetaNomadCluster].(type) {
+	case nil:
+		return self.nomadClusters, nil
+	case string:
+		names = []string{meta}
+	case []interface{}:
+		for _, name := range meta {
+			if nameStr, ok := name.(string); ok {
+				names = append(names, nameStr)
+			} else {
+				return nil, errors.Errorf("Action meta %q must only contain strings but has %T", ActionMetaNomadCluster, name)
+			}
+		}
+	default:
+		return nil, errors.Errorf("Action meta %q must be a string or list of strings but is %T", ActionMetaNomadCluster, meta)
+	}
+
+	clusters := application.NomadClusters{}
+	for _, name := range names {
+		if cluster, ok := self.nomadClusters.Get(name); ok {
+			clusters = append(clusters, cluster)
+		} else {
+			self.logger.Warn().Str("action", action.Name).Str("nomad-cluster", name).Msg("Ignoring unknown Nomad cluster in placement")
+		}
+	}
+	if len(clusters) == 0 {
+		return nil, errors.Errorf("None of the Nomad clusters %q that action %q is placed on are known", names, action.Name)
+	}
+
+	return clusters, nil
+}
+
 func (self actionService) NewInvokeRunFunc(action *domain.Action, invocation *domain.Invocation, inputs map[string]domain.Fact) InvokeRunFunc {
 	return func(db config.PgxIface) ([]domain.Run, InvokeRegisterFunc, error) {
 		job, err := self.evaluationService.EvaluateRun(action.Source, action.Name, action.ID, invocation.Id, inputs)
@@ -581,9 +960,30 @@ func (self actionService) NewInvokeRunFunc(action *domain.Action, invocation *do
 				return err
 			}
 
+			clusters, err := self.placement(action)
+			if err != nil {
+				return err
+			}
+
+			approval, err := action.Approval()
+			if err != nil {
+				return err
+			}
+
+			scheduling, err := action.JobScheduling()
+			if err != nil {
+				return err
+			}
+			if affinity, err := txSelf.nodeAffinity(action); err != nil {
+				return err
+			} else if affinity != nil {
+				scheduling.Affinities = append(scheduling.Affinities, *affinity)
+			}
+
 			run := domain.Run{
 				InvocationId: invocation.Id,
 				Status:       domain.RunStatusRunning,
+				NomadCluster: clusters[0].Name,
 			}
 
 			if err := txSelf.runService.Save(&run); err != nil {
@@ -620,20 +1020,73 @@ func (self actionService) NewInvokeRunFunc(action *domain.Action, invocation *do
 			runId := run.NomadJobID.String()
 			job.ID = &runId
 
+			self.jobScheduling.Merge(scheduling).Apply(job)
+
+			if _, err := txSelf.sizingService.Resize(action, job); err != nil {
+				return err
+			}
+
+			if self.admissionHook != nil {
+				denials, err := self.admissionHook.Admit(AdmissionRequest{Action: *action, Inputs: inputs, Job: job})
+				if err != nil {
+					// Deny rather than submit jobs that could not be checked.
+					self.logger.Err(err).Str("nomad-job", runId).Msg("Could not decide on admission of Run")
+					denials = []string{err.Error()}
+				}
+
+				if len(denials) > 0 {
+					if err := txSelf.runService.Deny(&run, denials); err != nil {
+						return err
+					}
+
+					runs = append(runs, run)
+					// Return a dummy registerFunc as there is nothing to register.
+					registerFunc = func() error { return nil }
+					return nil
+				}
+			}
+
+			if job.IsParameterized() {
+				if dispatches, err := txSelf.newDispatches(action, run, job, inputs); err != nil {
+					if err := txSelf.runService.Deny(&run, []string{err.Error()}); err != nil {
+						return err
+					}
+
+					runs = append(runs, run)
+					// Return a dummy registerFunc as there is nothing to register.
+					registerFunc = func() error { return nil }
+					return nil
+				} else if err := txSelf.runService.SaveDispatches(dispatches); err != nil {
+					return err
+				}
+			}
+
+			if err := txSelf.saveManifest(action, run, job); err != nil {
+				return err
+			}
+
 			runs = append(runs, run)
-			registerFunc = func() error {
-				if response, _, err := self.nomadClient.JobsRegister(job, &nomad.WriteOptions{}); err != nil {
-					return errors.WithMessage(err, "Failed to run Action")
-				} else if len(response.Warnings) > 0 {
-					self.logger.Warn().
-						Str("nomad-job", runId).
-						Str("nomad-evaluation", response.EvalID).
-						Str("warnings", response.Warnings).
-						Msg("Warnings occured registering Nomad job")
+
+			if approval != nil {
+				if _, err := txSelf.runApprovalService.Request(run, *approval, job); err != nil {
+					return err
 				}
+				// The job is scheduled once someone approves the Run.
+				registerFunc = func() error { return nil }
+				return nil
+			}
+
+			if ready, err := txSelf.schedule(action, run, job); err != nil {
+				return err
+			} else if !ready {
+				registerFunc = func() error { return nil }
 				return nil
 			}
 
+			registerFunc = func() error {
+				return self.registerJob(action, &run, job, clusters)
+			}
+
 			return nil
 		}); err != nil {
 			if err2 := (*self.invocationService).WithQuerier(db).End(invocation.Id); err2 != nil {
@@ -646,3 +1099,243 @@ func (self actionService) NewInvokeRunFunc(action *domain.Action, invocation *do
 		return runs, registerFunc, nil
 	}
 }
+
+// Acquires the action's mutex for the Run or queues it if it has to wait.
+// Returns whether the Run's job can be registered right away.
+// Must be called in a transaction.
+func (self actionService) schedule(action *domain.Action, run domain.Run, job *nomad.Job) (bool, error) {
+	mutex, err := action.Mutex()
+	if err != nil {
+		return false, err
+	}
+
+	if mutex != "" {
+		if acquired, err := self.runMutexService.Acquire(run, mutex, job); err != nil {
+			return false, err
+		} else if !acquired {
+			// The job is registered once the mutex is passed on to this Run.
+			return false, nil
+		}
+	}
+
+	if self.runQueueService != nil && self.runQueueService.Queues(*action) {
+		if err := self.runQueueService.Enqueue(run, job); err != nil {
+			return false, err
+		}
+		// The job is registered once it is this Run's turn.
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// How many of the nodes that ran the latest Runs of an action its next Runs prefer.
+const nodeAffinityNodes = 3
+
+// Returns the affinity to the nodes that ran the latest Runs of the action
+// or nil if it wants none or there are no such nodes yet.
+func (self actionService) nodeAffinity(action *domain.Action) (*domain.JobAffinity, error) {
+	weight, err := action.NodeAffinity()
+	if err != nil || weight == 0 {
+		return nil, err
+	}
+
+	nodeIds, err := self.runService.GetLatestNodesByActionName(action.Name, nodeAffinityNodes)
+	if err != nil || len(nodeIds) == 0 {
+		return nil, err
+	}
+
+	affinity := domain.NewNodeAffinity(nodeIds, weight)
+	return &affinity, nil
+}
+
+// Returns the jobs to dispatch from the Run's parameterized job.
+func (self actionService) newDispatches(action *domain.Action, run domain.Run, job *nomad.Job, inputs map[string]domain.Fact) ([]domain.RunDispatch, error) {
+	each, err := action.DispatchEach()
+	if err != nil {
+		return nil, err
+	}
+
+	dispatches, err := domain.NewRunDispatches(run.NomadJobID, job, inputs, each)
+	return dispatches, errors.WithMessage(err, "Could not dispatch parameterized job")
+}
+
+// Records what the Run's job is submitted with.
+func (self actionService) saveManifest(action *domain.Action, run domain.Run, job *nomad.Job) error {
+	evaluators, err := self.evaluationService.GetEvaluators(action.Source)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := domain.NewRunManifest(run.NomadJobID, job, evaluators)
+	if err != nil {
+		return err
+	}
+
+	return self.runService.SaveManifest(&manifest)
+}
+
+// Environment variables that tell the job's tasks how to report the Run's progress.
+const (
+	envRunId    = "CICERO_RUN_ID"
+	envRunToken = "CICERO_RUN_TOKEN"
+)
+
+// Gives the job's tasks a token with which they can report the Run's progress.
+// Called right before the job is submitted so that it is not saved anywhere with the secret,
+// like with the manifest or while it waits for a mutex, queue, or approval.
+func (self actionService) addRunToken(run domain.Run, job *nomad.Job) error {
+	secret, err := self.runService.CreateToken(run.NomadJobID)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range job.TaskGroups {
+		for _, task := range group.Tasks {
+			if task.Env == nil {
+				task.Env = map[string]string{}
+			}
+			task.Env[envRunId] = run.NomadJobID.String()
+			task.Env[envRunToken] = secret
+		}
+	}
+
+	return nil
+}
+
+// Env var with the secret of the Nomad token of the Run.
+const envNomadToken = "NOMAD_TOKEN"
+
+func (self actionService) addNomadToken(run domain.Run, cluster application.NomadCluster, nomadToken domain.ActionNomadToken, job *nomad.Job) error {
+	_, secret, err := self.runNomadTokenService.Mint(run, cluster, nomadToken)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range job.TaskGroups {
+		for _, task := range group.Tasks {
+			if task.Env == nil {
+				task.Env = map[string]string{}
+			}
+			task.Env[envNomadToken] = secret
+		}
+	}
+
+	return nil
+}
+
+// Registers the job with the first reachable Nomad cluster.
+// Sealed values are only unsealed here so that Cicero does not save the job
+// with their plaintext. Nomad sends it back in events though, which are saved
+// without the tasks' environment but with plaintext anywhere else in the job.
+func (self actionService) registerJob(action *domain.Action, run *domain.Run, job *nomad.Job, clusters application.NomadClusters) error {
+	runId := run.NomadJobID.String()
+
+	if err := self.unsealer.UnsealJob(action.Name, job); err != nil {
+		return errors.WithMessagef(err, "Could not unseal values in job of Run %q", runId)
+	}
+
+	if err := self.addRunToken(*run, job); err != nil {
+		return err
+	}
+
+	nomadToken, err := action.NomadToken()
+	if err != nil {
+		return err
+	}
+
+	for i, cluster := range clusters {
+		// Minted only now so that it is valid for as long as possible
+		// and only on the cluster that the job is registered with.
+		if nomadToken != nil {
+			if err := self.addNomadToken(*run, cluster, *nomadToken, job); err != nil {
+				if application.IsNomadUnreachable(err) && i < len(clusters)-1 {
+					self.logger.Warn().Err(err).
+						Str("nomad-job", runId).
+						Str("nomad-cluster", cluster.Name).
+						Str("next-nomad-cluster", clusters[i+1].Name).
+						Msg("Nomad cluster is unreachable, failing over")
+					continue
+				}
+				return err
+			}
+		}
+
+		response, _, err := cluster.JobsRegister(job, &nomad.WriteOptions{})
+		if err != nil {
+			if application.IsNomadUnreachable(err) && i < len(clusters)-1 {
+				self.logger.Warn().Err(err).
+					Str("nomad-job", runId).
+					Str("nomad-cluster", cluster.Name).
+					Str("next-nomad-cluster", clusters[i+1].Name).
+					Msg("Nomad cluster is unreachable, failing over")
+				continue
+			}
+			return errors.WithMessagef(err, "Failed to run Action on Nomad cluster %q", cluster.Name)
+		}
+
+		if len(response.Warnings) > 0 {
+			self.logger.Warn().
+				Str("nomad-job", runId).
+				Str("nomad-evaluation", response.EvalID).
+				Str("warnings", response.Warnings).
+				Msg("Warnings occured registering Nomad job")
+		}
+
+		if cluster.Name != run.NomadCluster {
+			run.NomadCluster = cluster.Name
+			if err := self.runService.UpdateNomadCluster(run); err != nil {
+				return err
+			}
+		}
+
+		// A parameterized job does nothing until it is dispatched.
+		if job.IsParameterized() {
+			return self.runService.Dispatch(run)
+		}
+
+		return nil
+	}
+	return nil
+}
+
+func (self actionService) RegisterJob(run *domain.Run, job *nomad.Job) error {
+	action, err := self.GetByInvocationId(run.InvocationId)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := self.placement(action)
+	if err != nil {
+		return err
+	}
+
+	return self.registerJob(action, run, job, clusters)
+}
+
+func (self actionService) Decide(run *domain.Run, approved bool, by, comment string) (approval *domain.RunApproval, err error) {
+	var job *nomad.Job
+	ready := false
+	if err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*actionService)
+
+		if approval, job, err = txSelf.runApprovalService.Decide(run, approved, by, comment); err != nil || approval == nil || !approved {
+			return err
+		}
+
+		action, err := txSelf.GetByInvocationId(run.InvocationId)
+		if err != nil {
+			return err
+		}
+
+		ready, err = txSelf.schedule(action, *run, job)
+		return err
+	}); err != nil || !ready {
+		return
+	}
+
+	// If this fails the Run was approved but has no job.
+	// The watchdog notices it as stuck so it can be canceled.
+	err = self.RegisterJob(run, job)
+	return
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func (self actionService) Export() ([]domain.ActionExport, error) {
+	self.logger.Trace().Msg("Exporting Actions")
+	actions, err := self.GetAll()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select Actions to export")
+	}
+	return domain.NewActionExports(actions), nil
+}
+
+func (self actionService) Import(exports []domain.ActionExport, prune, dryRun bool) ([]domain.ActionImportChange, error) {
+	current, err := self.GetCurrent()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := domain.PlanActionImport(current, exports, prune)
+	if err != nil || dryRun {
+		return changes, err
+	}
+
+	for i, change := range changes {
+		logger := self.logger.With().Str("name", change.Name).Str("change", string(change.Kind)).Logger()
+
+		switch change.Kind {
+		case domain.ActionImportUnchanged:
+			continue
+		case domain.ActionImportCreate, domain.ActionImportUpdate:
+			// New versions are active.
+			action, err := self.Create(change.Source, change.Name)
+			if err != nil {
+				return changes[:i], errors.WithMessagef(err, "Could not import Action %q", change.Name)
+			}
+			if !change.Active {
+				action.Active = false
+				if err := self.Update(action); err != nil {
+					return changes[:i], err
+				}
+			}
+		case domain.ActionImportActivate, domain.ActionImportDeactivate:
+			action, err := self.GetLatestByName(change.Name)
+			if err != nil {
+				return changes[:i], err
+			}
+			action.Active = change.Active
+			if err := self.Update(action); err != nil {
+				return changes[:i], err
+			}
+		}
+
+		logger.Debug().Msg("Imported Action")
+	}
+
+	return changes, nil
+}
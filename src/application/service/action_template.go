@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type ActionTemplateService interface {
+	GetAll() ([]domain.ActionTemplate, error)
+	GetByName(string) (*domain.ActionTemplate, error)
+}
+
+// File name suffixes of an action template's description and source.
+const (
+	actionTemplateDescriptionSuffix = ".json"
+	actionTemplateSourceSuffix      = ".nix.tmpl"
+)
+
+type actionTemplateService struct {
+	logger   zerolog.Logger
+	catalogs []fs.FS
+}
+
+// Templates are read from the catalogs each time
+// so that they can be edited without a restart.
+// Templates in later catalogs replace those with the same name in earlier ones.
+func NewActionTemplateService(catalogs []fs.FS, logger *zerolog.Logger) ActionTemplateService {
+	return &actionTemplateService{
+		logger:   logger.With().Str("component", "ActionTemplateService").Logger(),
+		catalogs: catalogs,
+	}
+}
+
+func (self actionTemplateService) GetAll() ([]domain.ActionTemplate, error) {
+	self.logger.Trace().Msg("Getting all action templates")
+
+	byName := map[string]domain.ActionTemplate{}
+	for _, catalog := range self.catalogs {
+		descriptions, err := fs.Glob(catalog, "*"+actionTemplateDescriptionSuffix)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Could not list action templates")
+		}
+		for _, description := range descriptions {
+			name := strings.TrimSuffix(description, actionTemplateDescriptionSuffix)
+			template, err := self.read(catalog, name)
+			if err != nil {
+				return nil, err
+			}
+			byName[name] = *template
+		}
+	}
+
+	templates := make([]domain.ActionTemplate, 0, len(byName))
+	for _, template := range byName {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+func (self actionTemplateService) GetByName(name string) (*domain.ActionTemplate, error) {
+	self.logger.Trace().Str("name", name).Msg("Getting action template by name")
+
+	if !fs.ValidPath(name) || strings.Contains(name, "/") {
+		return nil, nil
+	}
+
+	for i := len(self.catalogs) - 1; i >= 0; i-- {
+		if template, err := self.read(self.catalogs[i], name); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else {
+			return template, err
+		}
+	}
+	return nil, nil
+}
+
+func (self actionTemplateService) read(catalog fs.FS, name string) (*domain.ActionTemplate, error) {
+	template := domain.ActionTemplate{Name: name}
+
+	if description, err := fs.ReadFile(catalog, name+actionTemplateDescriptionSuffix); err != nil {
+		return nil, errors.WithMessagef(err, "Could not read description of action template %q", name)
+	} else if err := json.Unmarshal(description, &template); err != nil {
+		return nil, errors.WithMessagef(err, "Could not unmarshal description of action template %q", name)
+	}
+	// The name is given by the file name only.
+	template.Name = name
+
+	if source, err := fs.ReadFile(catalog, name+actionTemplateSourceSuffix); err != nil {
+		return nil, errors.WithMessagef(err, "Could not read source of action template %q", name)
+	} else {
+		template.Source = string(source)
+	}
+
+	return &template, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// What an AdmissionHook decides on.
+type AdmissionRequest struct {
+	Action domain.Action          `json:"action"`
+	Inputs map[string]domain.Fact `json:"inputs"`
+	Job    *nomad.Job             `json:"job"`
+}
+
+// Decides whether a Run's job may be submitted to Nomad.
+type AdmissionHook interface {
+	// Returns the reasons to deny the job, none to admit it.
+	Admit(AdmissionRequest) ([]string, error)
+}
+
+// Admits jobs that all hooks admit.
+type AdmissionHooks []AdmissionHook
+
+func (self AdmissionHooks) Admit(request AdmissionRequest) ([]string, error) {
+	denials := []string{}
+	for _, hook := range self {
+		reasons, err := hook.Admit(request)
+		if err != nil {
+			return nil, err
+		}
+		denials = append(denials, reasons...)
+	}
+	return denials, nil
+}
+
+// Evaluates Rego policies with the `opa` executable.
+// The query is given the AdmissionRequest as input and
+// must result in a set of reasons, or a boolean, to deny the job.
+type OPAAdmissionHook struct {
+	// Files or directories of Rego policies and data.
+	Policies []string
+	Query    string
+	Logger   zerolog.Logger
+}
+
+func (self OPAAdmissionHook) Admit(request AdmissionRequest) ([]string, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not marshal admission request")
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, policy := range self.Policies {
+		args = append(args, "--data", policy)
+	}
+	args = append(args, self.Query)
+
+	cmd := exec.Command("opa", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	self.Logger.Debug().
+		Stringer("command", cmd).
+		Str("action", request.Action.Name).
+		Msg("Evaluating admission policies")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to evaluate admission policies\nStderr: %s", stderr.String())
+	}
+
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value interface{} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, errors.WithMessage(err, "Could not unmarshal admission policy result")
+	}
+
+	denials := []string{}
+	for _, r := range result.Result {
+		for _, expression := range r.Expressions {
+			switch value := expression.Value.(type) {
+			case bool:
+				if value {
+					denials = append(denials, "Denied by policy "+self.Query)
+				}
+			case []interface{}:
+				for _, reason := range value {
+					if reasonStr, ok := reason.(string); ok {
+						denials = append(denials, reasonStr)
+					} else {
+						return nil, errors.Errorf("Admission policy %s must result in strings but has %T", self.Query, reason)
+					}
+				}
+			default:
+				return nil, errors.Errorf("Admission policy %s must result in a set or boolean but is %T", self.Query, value)
+			}
+		}
+	}
+
+	return denials, nil
+}
+
+// Denies jobs with sealed values that cannot be unsealed for their action
+// so that they are not left waiting for a submission that must fail.
+type SealAdmissionHook struct {
+	// Nil if Cicero has no seal key.
+	Unsealer *domain.Unsealer
+}
+
+func (self SealAdmissionHook) Admit(request AdmissionRequest) ([]string, error) {
+	return self.Unsealer.CheckJob(request.Action.Name, request.Job), nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type AlertService interface {
+	WithQuerier(config.PgxIface) AlertService
+
+	// Returns the actions whose last Runs failed at least the given number of times.
+	GetRunFailureStreaks(min int) ([]domain.RunFailureStreak, error)
+}
+
+type alertService struct {
+	logger          zerolog.Logger
+	alertRepository repository.AlertRepository
+}
+
+func NewAlertService(db config.PgxIface, logger *zerolog.Logger) AlertService {
+	return &alertService{
+		logger:          logger.With().Str("component", "AlertService").Logger(),
+		alertRepository: persistence.NewAlertRepository(db),
+	}
+}
+
+func (self alertService) WithQuerier(querier config.PgxIface) AlertService {
+	return &alertService{
+		logger:          self.logger,
+		alertRepository: self.alertRepository.WithQuerier(querier),
+	}
+}
+
+func (self alertService) GetRunFailureStreaks(min int) (streaks []domain.RunFailureStreak, err error) {
+	self.logger.Trace().Int("min", min).Msg("Getting Run failure streaks")
+	streaks, err = self.alertRepository.GetRunFailureStreaks(min)
+	err = errors.WithMessagef(err, "Could not select streaks of at least %d failed Runs", min)
+	return
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type ApiTokenService interface {
+	WithQuerier(config.PgxIface) ApiTokenService
+
+	GetAll() ([]domain.ApiToken, error)
+	GetById(uuid.UUID) (*domain.ApiToken, error)
+	// Returns the secret which is not stored.
+	Create(*domain.ApiToken) (string, error)
+	// Revokes the token and creates a new one with the same
+	// name, scopes, and expiry. Returns nil if there is no such token.
+	Rotate(uuid.UUID) (*domain.ApiToken, string, error)
+	Revoke(uuid.UUID) error
+	// Makes the tokens created by bootstrapping match the declared ones:
+	// creates those that do not exist with their secret yet,
+	// updates those that do, and revokes those that are not declared anymore.
+	// Fails if the secret of a declared token was revoked.
+	Bootstrap([]domain.BootstrapToken) error
+	// Returns nil if the secret does not belong to a token
+	// or the token is revoked or expired.
+	Authenticate(secret string) (*domain.ApiToken, error)
+}
+
+type apiTokenService struct {
+	logger             zerolog.Logger
+	apiTokenRepository repository.ApiTokenRepository
+	db                 config.PgxIface
+}
+
+func NewApiTokenService(db config.PgxIface, logger *zerolog.Logger) ApiTokenService {
+	return &apiTokenService{
+		logger:             logger.With().Str("component", "ApiTokenService").Logger(),
+		apiTokenRepository: persistence.NewApiTokenRepository(db),
+		db:                 db,
+	}
+}
+
+func (self apiTokenService) WithQuerier(querier config.PgxIface) ApiTokenService {
+	return &apiTokenService{
+		logger:             self.logger,
+		apiTokenRepository: self.apiTokenRepository.WithQuerier(querier),
+		db:                 querier,
+	}
+}
+
+// Returns a random secret with the prefix.
+func newTokenSecret(prefix string) (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+func apiTokenHash(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
+	return hash[:]
+}
+
+func (self apiTokenService) GetAll() (tokens []domain.ApiToken, err error) {
+	self.logger.Trace().Msg("Getting all API tokens")
+	tokens, err = self.apiTokenRepository.GetAll()
+	err = errors.WithMessage(err, "Could not select API tokens")
+	return
+}
+
+func (self apiTokenService) GetById(id uuid.UUID) (token *domain.ApiToken, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting API token by ID")
+	token, err = self.apiTokenRepository.GetById(id)
+	err = errors.WithMessagef(err, "Could not select API token by ID %q", id)
+	return
+}
+
+func (self apiTokenService) Create(token *domain.ApiToken) (string, error) {
+	secret, err := newTokenSecret(domain.ApiTokenPrefix)
+	if err != nil {
+		return "", errors.WithMessage(err, "Could not generate API token secret")
+	}
+
+	token.Hash = apiTokenHash(secret)
+
+	self.logger.Trace().Str("name", token.Name).Strs("scopes", token.Scopes).Msg("Saving new API token")
+	if err := self.apiTokenRepository.Save(token); err != nil {
+		return "", errors.WithMessagef(err, "Could not insert API token %q", token.Name)
+	}
+	self.logger.Trace().Stringer("id", token.ID).Msg("Created API token")
+
+	return secret, nil
+}
+
+func (self apiTokenService) Rotate(id uuid.UUID) (*domain.ApiToken, string, error) {
+	var newToken *domain.ApiToken
+	var secret string
+
+	if err := self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx)
+
+		token, err := txSelf.GetById(id)
+		if err != nil || token == nil {
+			return err
+		}
+		if token.RevokedAt != nil {
+			return errors.Errorf("API token %q is revoked", id)
+		}
+
+		if err := txSelf.Revoke(id); err != nil {
+			return err
+		}
+
+		newToken = &domain.ApiToken{
+			Name:      token.Name,
+			Scopes:    token.Scopes,
+			CreatedBy: token.CreatedBy,
+			ExpiresAt: token.ExpiresAt,
+		}
+		secret, err = txSelf.Create(newToken)
+		return err
+	}); err != nil {
+		return nil, "", err
+	}
+
+	return newToken, secret, nil
+}
+
+func (self apiTokenService) Revoke(id uuid.UUID) error {
+	self.logger.Trace().Stringer("id", id).Msg("Revoking API token")
+	if err := self.apiTokenRepository.Revoke(id); err != nil {
+		return errors.WithMessagef(err, "Could not revoke API token %q", id)
+	}
+	self.logger.Trace().Stringer("id", id).Msg("Revoked API token")
+	return nil
+}
+
+func (self apiTokenService) Bootstrap(declared []domain.BootstrapToken) error {
+	return self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*apiTokenService)
+
+		keep := map[uuid.UUID]struct{}{}
+		for _, bootstrap := range declared {
+			logger := self.logger.With().Str("name", bootstrap.Name).Logger()
+
+			token, err := txSelf.apiTokenRepository.GetByHash(apiTokenHash(bootstrap.Secret))
+			if err != nil {
+				return errors.WithMessagef(err, "Could not select API token %q by hash", bootstrap.Name)
+			}
+
+			scopes := bootstrap.Scopes
+			if scopes == nil {
+				scopes = []string{}
+			}
+
+			switch {
+			case token == nil:
+				token = &domain.ApiToken{
+					Name:      bootstrap.Name,
+					Hash:      apiTokenHash(bootstrap.Secret),
+					Scopes:    scopes,
+					CreatedBy: domain.BootstrapCreatedBy,
+					ExpiresAt: bootstrap.ExpiresAt,
+				}
+				if err := txSelf.apiTokenRepository.Save(token); err != nil {
+					return errors.WithMessagef(err, "Could not insert API token %q", bootstrap.Name)
+				}
+				logger.Debug().Stringer("id", token.ID).Msg("Created API token")
+			case token.RevokedAt != nil:
+				// Reviving it would undo the revocation, which may have been due to a leak.
+				return errors.Errorf("The secret of API token %q was revoked, declare a new one", bootstrap.Name)
+			case token.Name != bootstrap.Name || !reflect.DeepEqual(token.Scopes, scopes) || !equalTimes(token.ExpiresAt, bootstrap.ExpiresAt):
+				token.Name = bootstrap.Name
+				token.Scopes = scopes
+				token.ExpiresAt = bootstrap.ExpiresAt
+				if err := txSelf.apiTokenRepository.Update(token); err != nil {
+					return errors.WithMessagef(err, "Could not update API token %q", bootstrap.Name)
+				}
+				logger.Debug().Stringer("id", token.ID).Msg("Updated API token")
+			}
+
+			keep[token.ID] = struct{}{}
+		}
+
+		tokens, err := txSelf.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if _, kept := keep[token.ID]; kept || token.CreatedBy != domain.BootstrapCreatedBy || token.RevokedAt != nil {
+				continue
+			}
+			if err := txSelf.Revoke(token.ID); err != nil {
+				return err
+			}
+			self.logger.Debug().Str("name", token.Name).Stringer("id", token.ID).Msg("Revoked API token that is not declared anymore")
+		}
+
+		return nil
+	})
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Last use is recorded at most this often to avoid a write on every request.
+const apiTokenLastUsedPrecision = time.Minute
+
+func (self apiTokenService) Authenticate(secret string) (*domain.ApiToken, error) {
+	if !strings.HasPrefix(secret, domain.ApiTokenPrefix) {
+		return nil, nil
+	}
+
+	token, err := self.apiTokenRepository.GetByHash(apiTokenHash(secret))
+	switch {
+	case err != nil:
+		return nil, errors.WithMessage(err, "Could not select API token by hash")
+	case token == nil:
+		return nil, nil
+	case token.RevokedAt != nil:
+		self.logger.Debug().Stringer("id", token.ID).Msg("Rejecting revoked API token")
+		return nil, nil
+	case token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt):
+		self.logger.Debug().Stringer("id", token.ID).Msg("Rejecting expired API token")
+		return nil, nil
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > apiTokenLastUsedPrecision {
+		if err := self.apiTokenRepository.UpdateLastUsed(token.ID); err != nil {
+			// Not fatal, the token is still valid.
+			self.logger.Err(err).Stringer("id", token.ID).Msg("Could not update last use of API token")
+		}
+	}
+
+	return token, nil
+}
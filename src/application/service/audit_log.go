@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+
+	promtail "github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+// Label value of audit log entries sent to Loki.
+const lokiAudit = "audit"
+
+type AuditLogService interface {
+	WithQuerier(config.PgxIface) AuditLogService
+
+	// Returns matching entries, newest first.
+	Get(domain.AuditLogFilter, *repository.Page) ([]domain.AuditLogEntry, error)
+	// Also exports the entry if configured to.
+	// Failing to export is logged but not returned.
+	Save(*domain.AuditLogEntry) error
+}
+
+type auditLogService struct {
+	logger             zerolog.Logger
+	auditLogRepository repository.AuditLogRepository
+	promtailChan       chan<- promtail.Entry
+	syslog             io.Writer
+}
+
+// Entries are exported to Loki and syslog
+// unless the respective argument is nil.
+func NewAuditLogService(db config.PgxIface, promtailChan chan<- promtail.Entry, syslog io.Writer, logger *zerolog.Logger) AuditLogService {
+	return &auditLogService{
+		logger:             logger.With().Str("component", "AuditLogService").Logger(),
+		auditLogRepository: persistence.NewAuditLogRepository(db),
+		promtailChan:       promtailChan,
+		syslog:             syslog,
+	}
+}
+
+func (self auditLogService) WithQuerier(querier config.PgxIface) AuditLogService {
+	return &auditLogService{
+		logger:             self.logger,
+		auditLogRepository: self.auditLogRepository.WithQuerier(querier),
+		promtailChan:       self.promtailChan,
+		syslog:             self.syslog,
+	}
+}
+
+func (self auditLogService) Get(filter domain.AuditLogFilter, page *repository.Page) (entries []domain.AuditLogEntry, err error) {
+	self.logger.Trace().Interface("filter", filter).Int("offset", page.Offset).Int("limit", page.Limit).Msg("Getting audit log")
+	entries, err = self.auditLogRepository.Get(filter, page)
+	err = errors.WithMessage(err, "Could not select audit log")
+	return
+}
+
+func (self auditLogService) Save(entry *domain.AuditLogEntry) error {
+	self.logger.Trace().Str("method", entry.Method).Str("path", entry.Path).Int("status", entry.Status).Msg("Saving audit log entry")
+	if err := self.auditLogRepository.Save(entry); err != nil {
+		return errors.WithMessagef(err, "Could not insert audit log entry for %s %s", entry.Method, entry.Path)
+	}
+	self.logger.Trace().Int64("id", entry.ID).Msg("Saved audit log entry")
+
+	if self.promtailChan == nil && self.syslog == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		self.logger.Err(err).Int64("id", entry.ID).Msg("Could not marshal audit log entry for export")
+		return nil
+	}
+
+	if self.promtailChan != nil {
+		self.promtailChan <- promtail.Entry{
+			Labels: model.LabelSet{
+				"cicero": lokiAudit,
+			},
+			Entry: logproto.Entry{
+				Timestamp: entry.CreatedAt,
+				Line:      string(line),
+			},
+		}
+	}
+
+	if self.syslog != nil {
+		if _, err := self.syslog.Write(line); err != nil {
+			self.logger.Err(err).Int64("id", entry.ID).Msg("Could not send audit log entry to syslog")
+		}
+	}
+
+	return nil
+}
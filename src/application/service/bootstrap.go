@@ -0,0 +1,96 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type BootstrapService interface {
+	// Makes the instance have what is declared.
+	// Maintenance windows are runtime settings and not applied here.
+	Apply(domain.Bootstrap) error
+}
+
+type bootstrapService struct {
+	logger          zerolog.Logger
+	db              config.PgxIface
+	actionService   ActionService
+	factService     FactService
+	apiTokenService ApiTokenService
+}
+
+func NewBootstrapService(db config.PgxIface, actionService ActionService, factService FactService, apiTokenService ApiTokenService, logger *zerolog.Logger) BootstrapService {
+	return &bootstrapService{
+		logger:          logger.With().Str("component", "BootstrapService").Logger(),
+		db:              db,
+		actionService:   actionService,
+		factService:     factService,
+		apiTokenService: apiTokenService,
+	}
+}
+
+func (self bootstrapService) Apply(bootstrap domain.Bootstrap) error {
+	if err := self.applyActions(bootstrap.Actions); err != nil {
+		return err
+	}
+
+	if err := self.apiTokenService.Bootstrap(bootstrap.Tokens); err != nil {
+		return errors.WithMessage(err, "Could not bootstrap API tokens")
+	}
+
+	return self.applyFacts(bootstrap.Facts)
+}
+
+func (self bootstrapService) applyActions(actions []domain.BootstrapAction) error {
+	exports := make([]domain.ActionExport, len(actions))
+	for i, action := range actions {
+		exports[i] = action.Export()
+	}
+
+	// Actions that are not declared may have been created through the API.
+	changes, err := self.actionService.Import(exports, false, false)
+	if err != nil {
+		return errors.WithMessage(err, "Could not bootstrap actions")
+	}
+
+	for _, change := range changes {
+		if change.Kind != domain.ActionImportUnchanged {
+			self.logger.Info().Str("name", change.Name).Str("change", string(change.Kind)).Msg("Bootstrapped action")
+		}
+	}
+
+	return nil
+}
+
+func (self bootstrapService) applyFacts(facts []domain.BootstrapFact) error {
+	for _, bootstrap := range facts {
+		fact, err := bootstrap.Fact()
+		if err != nil {
+			return err
+		}
+
+		if existing, err := self.factService.GetById(fact.ID); err != nil {
+			return err
+		} else if existing != nil {
+			continue
+		}
+
+		_, runFunc, err := self.factService.Save(&fact, nil)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not bootstrap fact %q", fact.ID)
+		}
+
+		if _, registerFunc, err := runFunc(self.db); err != nil {
+			return errors.WithMessagef(err, "Could not invoke actions for bootstrapped fact %q", fact.ID)
+		} else if err := registerFunc(); err != nil {
+			return errors.WithMessagef(err, "Could not register Runs for bootstrapped fact %q", fact.ID)
+		}
+
+		self.logger.Info().Stringer("id", fact.ID).Msg("Bootstrapped fact")
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	prometheus "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type CostService interface {
+	WithQuerier(config.PgxIface) CostService
+
+	GetUsage(runId uuid.UUID) (*domain.RunUsage, error)
+	GetUnmeasured(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	// Sums up the resources used by the Run's allocations and saves them
+	// along with the peak usage of each task group.
+	Measure(domain.Run) (*domain.RunUsage, error)
+	// Returns the cost of the given usage in the configured unit costs.
+	Cost(cpuSeconds, memoryByteSeconds float64) float64
+	// Returns the usage and cost of Runs that finished
+	// in the months from `from` up to but not including `to`.
+	GetMonthlyReport(by domain.RunUsageGroup, from, to time.Time) ([]CostReport, error)
+}
+
+type RunCost struct {
+	domain.RunUsage
+	Cost float64 `json:"cost"`
+}
+
+type CostReport struct {
+	domain.RunUsageSum
+	Cost float64 `json:"cost"`
+}
+
+type costService struct {
+	logger             zerolog.Logger
+	runUsageRepository repository.RunUsageRepository
+	runService         RunService
+	metrics            promv1.API
+	runtime            *config.RuntimeConfig
+}
+
+func NewCostService(db config.PgxIface, runService RunService, metricsClient prometheus.Client, runtime *config.RuntimeConfig, logger *zerolog.Logger) CostService {
+	return &costService{
+		logger:             logger.With().Str("component", "CostService").Logger(),
+		runUsageRepository: persistence.NewRunUsageRepository(db),
+		runService:         runService,
+		metrics:            promv1.NewAPI(metricsClient),
+		runtime:            runtime,
+	}
+}
+
+func (self costService) WithQuerier(querier config.PgxIface) CostService {
+	return &costService{
+		logger:             self.logger,
+		runUsageRepository: self.runUsageRepository.WithQuerier(querier),
+		runService:         self.runService.WithQuerier(querier),
+		metrics:            self.metrics,
+		runtime:            self.runtime,
+	}
+}
+
+func (self costService) GetUsage(runId uuid.UUID) (usage *domain.RunUsage, err error) {
+	self.logger.Trace().Stringer("run-id", runId).Msg("Getting usage of Run")
+	usage, err = self.runUsageRepository.GetByRunId(runId)
+	err = errors.WithMessagef(err, "Could not select usage of Run with ID %q", runId)
+	return
+}
+
+func (self costService) GetUnmeasured(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	self.logger.Trace().Time("finished-before", finishedBefore).Int("limit", limit).Msg("Getting Runs without usage")
+	runs, err = self.runUsageRepository.GetUnmeasured(finishedBefore, limit)
+	err = errors.WithMessage(err, "Could not select Runs without usage")
+	return
+}
+
+func (self costService) Measure(run domain.Run) (*domain.RunUsage, error) {
+	allocs, err := self.runService.GetAllocations(run)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := domain.RunUsage{RunId: run.NomadJobID}
+	peaks := map[string]*domain.RunUsagePeak{}
+
+	for _, alloc := range allocs {
+		from := time.Unix(0, alloc.CreateTime)
+		to := time.Unix(0, alloc.ModifyTime)
+		if run.FinishedAt != nil && run.FinishedAt.Before(to) {
+			to = *run.FinishedAt
+		}
+
+		// Range selectors only take whole seconds.
+		duration := math.Ceil(to.Sub(from).Seconds())
+		if duration <= 0 {
+			continue
+		}
+
+		matcher := fmt.Sprintf(`{cgroup=~".*%s.*payload"}`, alloc.ID)
+		selector := fmt.Sprintf(`%s[%.fs]`, matcher, duration)
+
+		cpuSeconds, err := self.query(`sum(increase(host_cgroup_cpu_usage_seconds_total`+selector+`))`, to)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not query CPU usage of allocation %q", alloc.ID)
+		}
+
+		memoryBytes, err := self.query(`sum(avg_over_time(host_cgroup_memory_current_bytes`+selector+`))`, to)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not query memory usage of allocation %q", alloc.ID)
+		}
+
+		usage.CPUSeconds += cpuSeconds
+		usage.MemoryByteSeconds += memoryBytes * duration
+
+		// Subqueries sum up the tasks of the allocation at each step.
+		subquery := fmt.Sprintf(`[%.fs:%s]`, duration, runUsagePeakStep)
+
+		peakCPUCores, err := self.query(`max_over_time(sum(rate(host_cgroup_cpu_usage_seconds_total`+matcher+`[`+runUsagePeakStep+`]))`+subquery+`)`, to)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not query peak CPU usage of allocation %q", alloc.ID)
+		}
+
+		peakMemoryBytes, err := self.query(`max_over_time(sum(host_cgroup_memory_current_bytes`+matcher+`)`+subquery+`)`, to)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not query peak memory usage of allocation %q", alloc.ID)
+		}
+
+		peak, ok := peaks[alloc.TaskGroup]
+		if !ok {
+			peak = &domain.RunUsagePeak{RunId: run.NomadJobID, TaskGroup: alloc.TaskGroup}
+			peaks[alloc.TaskGroup] = peak
+		}
+		peak.CPUCores = math.Max(peak.CPUCores, peakCPUCores)
+		peak.MemoryBytes = math.Max(peak.MemoryBytes, peakMemoryBytes)
+	}
+
+	peaksSlice := make([]domain.RunUsagePeak, 0, len(peaks))
+	for _, peak := range peaks {
+		peaksSlice = append(peaksSlice, *peak)
+	}
+
+	// Saved first because the usage marks the Run as measured.
+	if err := self.runUsageRepository.SavePeaks(peaksSlice); err != nil {
+		return nil, errors.WithMessagef(err, "Could not insert peak usage of Run with ID %q", run.NomadJobID)
+	}
+
+	self.logger.Trace().Stringer("run-id", run.NomadJobID).Float64("cpu-seconds", usage.CPUSeconds).Float64("memory-byte-seconds", usage.MemoryByteSeconds).Msg("Saving usage of Run")
+	if err := self.runUsageRepository.Save(&usage); err != nil {
+		return nil, errors.WithMessagef(err, "Could not insert usage of Run with ID %q", run.NomadJobID)
+	}
+
+	return &usage, nil
+}
+
+// Resolution at which peak usage is measured.
+const runUsagePeakStep = "1m"
+
+// Returns the sum of the query's result, zero if it is empty.
+func (self costService) query(query string, ts time.Time) (float64, error) {
+	value, warnings, err := self.metrics.Query(context.Background(), query, ts)
+	if err != nil {
+		return 0, err
+	}
+	for _, warning := range warnings {
+		self.logger.Warn().Str("query", query).Msg(warning)
+	}
+
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return 0, errors.Errorf("Expected a vector but got a %s", value.Type())
+	}
+
+	sum := float64(0)
+	for _, sample := range vector {
+		if !math.IsNaN(float64(sample.Value)) {
+			sum += float64(sample.Value)
+		}
+	}
+	return sum, nil
+}
+
+func (self costService) Cost(cpuSeconds, memoryByteSeconds float64) float64 {
+	runtime := self.runtime.Get()
+	return cpuSeconds/60/60*runtime.CostCPUHour +
+		memoryByteSeconds/gib/60/60*runtime.CostMemoryGiBHour
+}
+
+func (self costService) GetMonthlyReport(by domain.RunUsageGroup, from, to time.Time) ([]CostReport, error) {
+	self.logger.Trace().Str("by", string(by)).Time("from", from).Time("to", to).Msg("Getting monthly usage")
+	sums, err := self.runUsageRepository.GetMonthlySums(by, from, to)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not select monthly usage by %s", by)
+	}
+
+	report := make([]CostReport, len(sums))
+	for i, sum := range sums {
+		report[i] = CostReport{
+			RunUsageSum: sum,
+			Cost:        self.Cost(sum.CPUSeconds, sum.MemoryByteSeconds),
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type DatabaseStatsService interface {
+	WithQuerier(config.PgxIface) DatabaseStatsService
+
+	Get() (domain.DatabaseStats, error)
+}
+
+type databaseStatsService struct {
+	logger                  zerolog.Logger
+	databaseStatsRepository repository.DatabaseStatsRepository
+}
+
+func NewDatabaseStatsService(db config.PgxIface, logger *zerolog.Logger) DatabaseStatsService {
+	return &databaseStatsService{
+		logger:                  logger.With().Str("component", "DatabaseStatsService").Logger(),
+		databaseStatsRepository: persistence.NewDatabaseStatsRepository(db),
+	}
+}
+
+func (self databaseStatsService) WithQuerier(querier config.PgxIface) DatabaseStatsService {
+	return &databaseStatsService{
+		logger:                  self.logger,
+		databaseStatsRepository: self.databaseStatsRepository.WithQuerier(querier),
+	}
+}
+
+func (self databaseStatsService) Get() (stats domain.DatabaseStats, err error) {
+	self.logger.Trace().Msg("Getting database statistics")
+
+	if stats.Tables, err = self.databaseStatsRepository.GetTables(); err != nil {
+		return stats, errors.WithMessage(err, "Could not select table statistics")
+	}
+
+	if stats.UnhandledNomadEvents, err = self.databaseStatsRepository.GetUnhandledNomadEvents(); err != nil {
+		return stats, errors.WithMessage(err, "Could not select unhandled Nomad events")
+	}
+
+	if stats.FactBytesByProject, err = self.databaseStatsRepository.GetFactBytesByProject(); err != nil {
+		return stats, errors.WithMessage(err, "Could not select fact bytes by project")
+	}
+
+	if stats.Retention, err = self.databaseStatsRepository.GetRetention(); err != nil {
+		return stats, errors.WithMessage(err, "Could not select retention statistics")
+	}
+
+	if stats.FactBinaryTiers, err = self.databaseStatsRepository.GetFactBinaryTiers(); err != nil {
+		return stats, errors.WithMessage(err, "Could not select fact binary tier statistics")
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type DigestService interface {
+	WithQuerier(config.PgxIface) DigestService
+
+	GetAll() ([]domain.DigestSubscription, error)
+	GetById(uuid.UUID) (*domain.DigestSubscription, error)
+	Subscribe(*domain.DigestSubscription) error
+	Unsubscribe(uuid.UUID) error
+	// Returns the subscriptions whose digest should be sent now.
+	GetDue(now time.Time) ([]domain.DigestSubscription, error)
+	// Collects the Runs that failed since the last digest
+	// or during the last period if none was sent yet.
+	Build(subscription domain.DigestSubscription, now time.Time) (domain.Digest, error)
+	MarkSent(domain.Digest) error
+}
+
+type digestService struct {
+	logger           zerolog.Logger
+	digestRepository repository.DigestRepository
+	runService       RunService
+}
+
+func NewDigestService(db config.PgxIface, runService RunService, logger *zerolog.Logger) DigestService {
+	return &digestService{
+		logger:           logger.With().Str("component", "DigestService").Logger(),
+		digestRepository: persistence.NewDigestRepository(db),
+		runService:       runService,
+	}
+}
+
+func (self digestService) WithQuerier(querier config.PgxIface) DigestService {
+	return &digestService{
+		logger:           self.logger,
+		digestRepository: self.digestRepository.WithQuerier(querier),
+		runService:       self.runService.WithQuerier(querier),
+	}
+}
+
+// How many of the last lines of a failed Run's log are included in a digest.
+const digestExcerptLines = 10
+
+func (self digestService) GetAll() (subscriptions []domain.DigestSubscription, err error) {
+	self.logger.Trace().Msg("Getting all digest subscriptions")
+	subscriptions, err = self.digestRepository.GetAll()
+	err = errors.WithMessage(err, "Could not select digest subscriptions")
+	return
+}
+
+func (self digestService) GetById(id uuid.UUID) (subscription *domain.DigestSubscription, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting digest subscription by ID")
+	subscription, err = self.digestRepository.GetById(id)
+	err = errors.WithMessagef(err, "Could not select digest subscription by ID %q", id)
+	return
+}
+
+func (self digestService) Subscribe(subscription *domain.DigestSubscription) error {
+	self.logger.Trace().Str("email", subscription.Email).Str("period", string(subscription.Period)).Msg("Saving digest subscription")
+	if err := self.digestRepository.Save(subscription); err != nil {
+		return errors.WithMessagef(err, "Could not insert digest subscription of %q", subscription.Email)
+	}
+	self.logger.Trace().Stringer("id", subscription.ID).Msg("Created digest subscription")
+	return nil
+}
+
+func (self digestService) Unsubscribe(id uuid.UUID) error {
+	self.logger.Trace().Stringer("id", id).Msg("Deleting digest subscription")
+	if err := self.digestRepository.Delete(id); err != nil {
+		return errors.WithMessagef(err, "Could not delete digest subscription %q", id)
+	}
+	return nil
+}
+
+func (self digestService) GetDue(now time.Time) ([]domain.DigestSubscription, error) {
+	subscriptions, err := self.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	due := []domain.DigestSubscription{}
+	for _, subscription := range subscriptions {
+		if subscription.Due(now) {
+			due = append(due, subscription)
+		}
+	}
+	return due, nil
+}
+
+func (self digestService) Build(subscription domain.DigestSubscription, now time.Time) (domain.Digest, error) {
+	from := subscription.Since(now)
+
+	self.logger.Trace().Stringer("id", subscription.ID).Time("from", from).Time("to", now).Msg("Getting failed Runs for digest")
+	runs, err := self.digestRepository.GetFailedRuns(subscription.Project, from, now)
+	if err != nil {
+		return domain.Digest{}, errors.WithMessage(err, "Could not select failed Runs")
+	}
+
+	for i := range runs {
+		if excerpt, err := self.excerpt(runs[i].Run); err != nil {
+			// The digest is still useful without it.
+			self.logger.Err(err).Stringer("nomad-job-id", runs[i].NomadJobID).Msg("Could not get log excerpt of failed Run")
+		} else {
+			runs[i].Excerpt = excerpt
+		}
+	}
+
+	return domain.NewDigest(subscription, from, now, runs), nil
+}
+
+func (self digestService) excerpt(run domain.Run) ([]string, error) {
+	if len(run.AdmissionDenials) > 0 {
+		return run.AdmissionDenials, nil
+	}
+
+	log, err := self.runService.JobLog(run.NomadJobID, run.CreatedAt, run.FinishedAt, LokiPage{
+		Direction: LokiBackward,
+		Limit:     digestExcerptLines,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	excerpt := make([]string, len(log.Log))
+	for i, line := range log.Log {
+		excerpt[i] = line.Text
+	}
+	return excerpt, nil
+}
+
+func (self digestService) MarkSent(digest domain.Digest) error {
+	if err := self.digestRepository.UpdateLastSent(digest.Subscription.ID, digest.To); err != nil {
+		return errors.WithMessagef(err, "Could not update last digest of subscription %q", digest.Subscription.ID)
+	}
+	return nil
+}
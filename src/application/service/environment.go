@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+type EnvironmentService interface {
+	WithQuerier(config.PgxIface) EnvironmentService
+
+	GetById(uuid.UUID) (*domain.Environment, error)
+	Get(tornDown bool, page *repository.Page) ([]domain.Environment, error)
+	// Creates or updates the environment described by the output
+	// of the successful Run if its action creates environments.
+	// Returns nil if it does not or the output does not describe one.
+	Create(domain.Action, domain.Run) (*domain.Environment, error)
+	// Publishes the fact that invokes the teardown action.
+	// Returns `repository.ErrNotFound` if the environment was torn down already.
+	TearDown(*domain.Environment, domain.EnvironmentTeardownReason) (InvokeRunFunc, error)
+	// Tears down the environments that expired.
+	Expire() (InvokeRunFunc, error)
+	// Tears down the environments that were closed by facts published since they were last checked.
+	Close() (InvokeRunFunc, error)
+}
+
+type environmentService struct {
+	logger                zerolog.Logger
+	environmentRepository repository.EnvironmentRepository
+	factService           FactService
+	invocationService     InvocationService
+	db                    config.PgxIface
+}
+
+func NewEnvironmentService(db config.PgxIface, factService FactService, invocationService InvocationService, logger *zerolog.Logger) EnvironmentService {
+	return &environmentService{
+		logger:                logger.With().Str("component", "EnvironmentService").Logger(),
+		environmentRepository: persistence.NewEnvironmentRepository(db),
+		factService:           factService,
+		invocationService:     invocationService,
+		db:                    db,
+	}
+}
+
+func (self environmentService) WithQuerier(querier config.PgxIface) EnvironmentService {
+	return &environmentService{
+		logger:                self.logger,
+		environmentRepository: self.environmentRepository.WithQuerier(querier),
+		factService:           self.factService.WithQuerier(querier),
+		invocationService:     self.invocationService.WithQuerier(querier),
+		db:                    querier,
+	}
+}
+
+// How many facts are checked against the environments' `closed_by` at once.
+const environmentCloseBatch = 1000
+
+func (self environmentService) GetById(id uuid.UUID) (env *domain.Environment, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting environment by ID")
+	env, err = self.environmentRepository.GetById(id)
+	err = errors.WithMessagef(err, "Could not select environment by ID %q", id)
+	return
+}
+
+func (self environmentService) Get(tornDown bool, page *repository.Page) (envs []domain.Environment, err error) {
+	self.logger.Trace().Bool("torn-down", tornDown).Int("offset", page.Offset).Int("limit", page.Limit).Msg("Getting environments")
+	envs, err = self.environmentRepository.Get(tornDown, page)
+	err = errors.WithMessage(err, "Could not select environments")
+	return
+}
+
+func (self environmentService) Create(action domain.Action, run domain.Run) (*domain.Environment, error) {
+	if actionEnv, err := action.Environment(); err != nil {
+		// The Run succeeded nonetheless so this must not stop its output from being published.
+		self.logger.Warn().Err(err).Stringer("run", run.NomadJobID).Msg("Not creating environment")
+		return nil, nil
+	} else if actionEnv == nil {
+		return nil, nil
+	}
+
+	output, err := self.invocationService.GetRunOutputById(run.InvocationId)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := domain.NewEnvironment(action, run, *output)
+	if err != nil {
+		self.logger.Warn().Err(err).Stringer("run", run.NomadJobID).Msg("Not creating environment")
+		return nil, nil
+	}
+
+	if err := self.environmentRepository.Save(env); err != nil {
+		return nil, errors.WithMessagef(err, "Could not save environment %q of action %q", env.Name, env.ActionName)
+	}
+
+	self.logger.Debug().Stringer("id", env.ID).Str("name", env.Name).Str("action", env.ActionName).Stringer("run", run.NomadJobID).Msg("Saved environment")
+
+	return env, nil
+}
+
+func (self environmentService) TearDown(env *domain.Environment, reason domain.EnvironmentTeardownReason) (runFunc InvokeRunFunc, err error) {
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*environmentService)
+
+		if err := txSelf.environmentRepository.TearDown(env, reason); err != nil {
+			return errors.WithMessagef(err, "Could not tear down environment %q", env.ID)
+		}
+
+		fact := domain.Fact{Value: env.TeardownFact(reason)}
+		if _, runFunc_, err := txSelf.factService.Save(&fact, nil); err != nil {
+			return errors.WithMessagef(err, "Could not publish teardown fact of environment %q", env.ID)
+		} else {
+			runFunc = runFunc_
+		}
+
+		self.logger.Debug().Stringer("id", env.ID).Str("name", env.Name).Str("reason", string(reason)).Stringer("fact", fact.ID).Msg("Tearing down environment")
+
+		return nil
+	})
+	return
+}
+
+func (self environmentService) Expire() (runFunc InvokeRunFunc, err error) {
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*environmentService)
+
+		envs, err := txSelf.environmentRepository.GetExpired(time.Now().UTC())
+		if err != nil {
+			return errors.WithMessage(err, "Could not select expired environments")
+		}
+
+		for i := range envs {
+			if runFunc_, err := txSelf.TearDown(&envs[i], domain.EnvironmentTeardownReasonExpired); errors.Is(err, repository.ErrNotFound) {
+				continue
+			} else if err != nil {
+				return err
+			} else {
+				runFunc = JoinInvokeRunFuncs(runFunc, runFunc_)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		// Nothing was invoked as the transaction was rolled back.
+		runFunc = nil
+	}
+	return
+}
+
+// Environments that were last checked at the same fact are checked together.
+// Those behind the others are checked first until all have caught up.
+func (self environmentService) Close() (runFunc InvokeRunFunc, err error) {
+	for {
+		var caughtUp bool
+		var batchRunFunc InvokeRunFunc
+		if err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+			txSelf := self.WithQuerier(tx).(*environmentService)
+
+			envs, err := txSelf.environmentRepository.GetClosable()
+			if err != nil {
+				return errors.WithMessage(err, "Could not select closable environments")
+			}
+			if len(envs) == 0 {
+				caughtUp = true
+				return nil
+			}
+
+			since := envs[0].FactSeq
+			feed, err := txSelf.factService.GetFeed(since, environmentCloseBatch)
+			if err != nil {
+				return err
+			}
+			// The others were checked up to a later fact, if any.
+			if len(feed.Facts) == 0 {
+				caughtUp = true
+				return nil
+			}
+
+			for i := range envs {
+				env := &envs[i]
+				if env.FactSeq != since {
+					break
+				}
+
+				if closed, err := txSelf.closedBy(*env, feed.Facts); err != nil {
+					return err
+				} else if closed != nil {
+					self.logger.Debug().Stringer("id", env.ID).Stringer("fact", closed.ID).Msg("Environment closed by fact")
+
+					if runFunc_, err := txSelf.TearDown(env, domain.EnvironmentTeardownReasonClosed); errors.Is(err, repository.ErrNotFound) {
+						continue
+					} else if err != nil {
+						return err
+					} else {
+						batchRunFunc = JoinInvokeRunFuncs(batchRunFunc, runFunc_)
+					}
+					continue
+				}
+
+				if err := txSelf.environmentRepository.UpdateFactSeq(env.ID, feed.Cursor); err != nil {
+					return errors.WithMessagef(err, "Could not update fact seq of environment %q", env.ID)
+				}
+			}
+
+			return nil
+		}); err != nil || caughtUp {
+			return
+		}
+
+		// Only what was committed can be run.
+		runFunc = JoinInvokeRunFuncs(runFunc, batchRunFunc)
+	}
+}
+
+// Returns the first fact that matches the environment's `closed_by`, nil if none does.
+func (self environmentService) closedBy(env domain.Environment, facts []domain.Fact) (*domain.Fact, error) {
+	match := util.CUEString(*env.ClosedBy).Value(nil, nil)
+	if err := match.Err(); err != nil {
+		// Validated when the environment was saved.
+		return nil, errors.WithMessagef(err, "Invalid closed_by of environment %q", env.ID)
+	}
+
+	for i := range facts {
+		if _, matchErr, err := self.factService.Match(&facts[i], match); err != nil {
+			return nil, err
+		} else if matchErr == nil {
+			return &facts[i], nil
+		}
+	}
+
+	return nil, nil
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"os/exec"
@@ -37,6 +38,8 @@ type EvaluationService interface {
 	ListActions(src string) ([]string, error)
 	EvaluateAction(src, name string, id uuid.UUID) (domain.ActionDefinition, error)
 	EvaluateRun(src, name string, id, invocationId uuid.UUID, inputs map[string]domain.Fact) (*nomad.Job, error)
+	// Returns the evaluators that are tried for the source in order.
+	GetEvaluators(src string) ([]domain.RunManifestEvaluator, error)
 }
 
 const (
@@ -70,39 +73,147 @@ func parseSource(src string) (fetchUrl *url.URL, evaluator string, err error) {
 type evaluationService struct {
 	Evaluators   []string // Default evaluators. Will be tried in order if none is given for a source.
 	Transformers []string
+	stdio        StdioEvaluators
+	runtime      *config.RuntimeConfig
 	promtailChan chan<- promtail.Entry
-	logger       zerolog.Logger
+	// Warm processes of the default evaluators, if enabled.
+	pools  map[string]*evaluatorPool
+	logger zerolog.Logger
 }
 
-func NewEvaluationService(evaluators, transformers []string, promtailChan chan<- promtail.Entry, logger *zerolog.Logger) EvaluationService {
-	return &evaluationService{
+// Keeps `poolSize` processes of each default evaluator running
+// that are replaced after `poolMaxEvaluations`. A `poolSize` of 0 starts
+// a new evaluator process for each evaluation.
+func NewEvaluationService(evaluators, transformers []string, stdio StdioEvaluators, poolSize, poolMaxEvaluations int, runtime *config.RuntimeConfig, promtailChan chan<- promtail.Entry, logger *zerolog.Logger) EvaluationService {
+	e := &evaluationService{
 		Evaluators:   evaluators,
 		Transformers: transformers,
+		stdio:        stdio,
+		runtime:      runtime,
 		promtailChan: promtailChan,
+		pools:        map[string]*evaluatorPool{},
 		logger:       logger.With().Str("component", "EvaluationService").Logger(),
 	}
+
+	if poolSize > 0 {
+		for _, evaluator := range evaluators {
+			// Stdio evaluators cannot serve more than one request.
+			if _, ok := stdio.Paths[evaluator]; ok {
+				continue
+			}
+			// The built-in evaluator does not run in a process.
+			if evaluator == cueEvaluator {
+				continue
+			}
+			e.pools[evaluator] = newEvaluatorPool(evaluator, poolSize, poolMaxEvaluations, &e.logger)
+		}
+	}
+
+	return e
 }
 
-// Evaluation failed due to a faulty action definition or transformer output.
-type EvaluationError exec.ExitError
+func (e evaluationService) GetEvaluators(src string) ([]domain.RunManifestEvaluator, error) {
+	_, evaluator, err := parseSource(src)
+	if err != nil {
+		return nil, err
+	}
 
-func (e *EvaluationError) Error() string {
-	return (*exec.ExitError)(e).Error()
+	if evaluator == "" {
+		// The source was already fetched for evaluation.
+		if dir, err := e.cacheDir(src); err != nil {
+			return nil, err
+		} else if evaluator, err = e.stdio.ByExtension(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	names := e.Evaluators
+	if evaluator != "" {
+		names = []string{evaluator}
+	}
+
+	evaluators := make([]domain.RunManifestEvaluator, len(names))
+	for i, name := range names {
+		evaluators[i].Name = name
+
+		executable := "cicero-evaluator-" + name
+		if path, ok := e.stdio.Paths[name]; ok {
+			executable = path
+		} else if name == cueEvaluator {
+			// built in
+			if executable, err = os.Executable(); err != nil {
+				e.logger.Debug().Err(err).Str("evaluator", name).Msg("Could not find own executable")
+				continue
+			}
+		}
+
+		if path, err := exec.LookPath(executable); err != nil {
+			e.logger.Debug().Err(err).Str("evaluator", name).Msg("Could not find evaluator")
+		} else if path, err := filepath.EvalSymlinks(path); err == nil {
+			evaluators[i].Path = path
+		}
+	}
+
+	return evaluators, nil
 }
 
-func (e *EvaluationError) Unwrap() error {
-	return (*exec.ExitError)(e)
+const envActionInputs = "CICERO_ACTION_INPUTS="
+
+// Redacts the values of the input facts in the environment
+// so that it can be logged.
+func (e evaluationService) redactEnv(env []string) []string {
+	if e.runtime == nil {
+		return env
+	}
+	redactions := e.runtime.Get().FactRedactions
+	if len(redactions) == 0 {
+		return env
+	}
+
+	redacted := make([]string, len(env))
+	for i, v := range env {
+		redacted[i] = v
+
+		if !strings.HasPrefix(v, envActionInputs) {
+			continue
+		}
+
+		inputs := map[string]domain.Fact{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(v, envActionInputs)), &inputs); err != nil {
+			redacted[i] = envActionInputs + util.Redacted
+			continue
+		}
+		for name, fact := range inputs {
+			fact.Value = redactions.Apply(fact.Value)
+			inputs[name] = fact
+		}
+		if inputsJson, err := json.Marshal(inputs); err != nil {
+			redacted[i] = envActionInputs + util.Redacted
+		} else {
+			redacted[i] = envActionInputs + string(inputsJson)
+		}
+	}
+	return redacted
+}
+
+// Evaluation failed due to a faulty action definition or transformer output.
+type EvaluationError struct {
+	ExitCode int
+}
+
+func (e *EvaluationError) Error() string {
+	return fmt.Sprintf("exit status %d", e.ExitCode)
 }
 
 func (e evaluationService) cacheDir(src string) (string, error) {
 	cacheDir := config.GetenvStr("CICERO_CACHE_DIR")
 	if cacheDir == "" {
 		e.logger.Debug().Msg("Falling back to XDG cache directory")
-		cacheDir = xdg.CacheHome + "/cicero"
+		cacheDir = filepath.Join(xdg.CacheHome, "cicero")
 	}
-	cacheDir += "/sources"
+	cacheDir = filepath.Join(cacheDir, "sources")
 
-	return filepath.Abs(cacheDir + "/" + base64.RawURLEncoding.EncodeToString([]byte(src)))
+	return filepath.Abs(filepath.Join(cacheDir, base64.RawURLEncoding.EncodeToString([]byte(src))))
 }
 
 func (e evaluationService) fetchSource(src string) (string, string, error) {
@@ -136,15 +247,26 @@ func (e evaluationService) fetchSource(src string) (string, string, error) {
 	return dst, evaluator, err
 }
 
-func (e evaluationService) evaluate(src, evaluator string, args, extraEnv []string, invocationId *uuid.UUID) ([]byte, []byte, error) {
-	tryEval := func(evaluator string) ([]byte, []byte, error) {
+// Stdio evaluators are sent the request instead of the arguments and environment.
+func (e evaluationService) evaluate(src, evaluator string, args, extraEnv []string, request stdioEvaluatorRequest, invocationId *uuid.UUID) ([]byte, []byte, error) {
+	tryEvalCold := func(evaluator string) ([]byte, []byte, error) {
 		cmd := exec.Command("cicero-evaluator-"+evaluator, args...)
 		cmd.Env = append(os.Environ(), extraEnv...) //nolint:gocritic // false positive
+		if path, ok := e.stdio.Paths[evaluator]; ok {
+			requestJson, err := json.Marshal(request)
+			if err != nil {
+				return nil, nil, errors.WithMessage(err, "Could not marshal evaluator request")
+			}
+
+			cmd = exec.Command(path)
+			cmd.Env = os.Environ()
+			cmd.Stdin = bytes.NewReader(append(requestJson, '\n'))
+		}
 		cmd.Dir = src
 
 		e.logger.Debug().
 			Stringer("command", cmd).
-			Strs("environment", extraEnv).
+			Strs("environment", e.redactEnv(extraEnv)).
 			Str("directory", src).
 			Msg("Running evaluator")
 
@@ -172,40 +294,16 @@ func (e evaluationService) evaluate(src, evaluator string, args, extraEnv []stri
 		var scanErr error
 		var result []byte
 		scanner := newScanner(stdout)
-	Scan:
 		for scanner.Scan() {
 			if invocationId != nil {
 				e.promtailChan <- promtailEntry(scanner.Text(), lokiEval, lokiFdStdout, *invocationId)
 			}
 
-			var msg map[string]interface{}
-			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			if r, err := e.handleMessage(scanner.Bytes()); err != nil {
 				scanErr = err
 				break
-			}
-
-			if event, ok := msg["event"]; !ok {
-				scanErr = fmt.Errorf("Message without event key received from evaluator: %v", msg)
-				break
-			} else {
-				switch event {
-				default:
-					scanErr = fmt.Errorf("Message with unknown event received from evaluator: %q", event)
-					break Scan
-				case "error":
-					scanErr = fmt.Errorf("Error message received from evaluator: %q", msg["error"])
-					break Scan
-				case "result":
-					// XXX Take *interface{} to unmarshal result into using json.Decoder instead of returning its []byte?
-					if r, err := json.Marshal(msg["result"]); err != nil {
-						scanErr = errors.WithMessage(err, "While marshaling evaluator result")
-						break Scan
-					} else {
-						result = r
-					}
-				case "prepare":
-					e.logger.Debug().Fields(msg).Msg("Running prepare step")
-				}
+			} else if r != nil {
+				result = r
 			}
 		}
 		if err := scanner.Err(); err != nil {
@@ -232,7 +330,7 @@ func (e evaluationService) evaluate(src, evaluator string, args, extraEnv []stri
 		if err := cmd.Wait(); err != nil {
 			var errExit *exec.ExitError
 			if errors.As(err, &errExit) {
-				evalErr := EvaluationError(*errExit)
+				evalErr := EvaluationError{errExit.ExitCode()}
 				err = errors.WithMessage(&evalErr, "Failed to evaluate")
 			}
 			return nil, stderrBuf.Bytes(), err
@@ -241,6 +339,84 @@ func (e evaluationService) evaluate(src, evaluator string, args, extraEnv []stri
 		return result, stderrBuf.Bytes(), scanErr
 	}
 
+	tryEvalPooled := func(evaluator string, pool *evaluatorPool) ([]byte, []byte, error) {
+		e.logger.Debug().
+			Str("evaluator", evaluator).
+			Strs("arguments", args).
+			Strs("environment", e.redactEnv(extraEnv)).
+			Str("directory", src).
+			Msg("Running evaluation on warm evaluator")
+
+		var scanErr error
+		var result []byte
+		stderrBuf := &bytes.Buffer{}
+
+		err := pool.evaluate(src, args, extraEnv, func(line []byte) {
+			if invocationId != nil {
+				e.promtailChan <- promtailEntry(string(line), lokiEval, lokiFdStdout, *invocationId)
+			}
+
+			// The evaluation has to be read to its end anyway
+			// so that the process can be used again.
+			if scanErr != nil {
+				return
+			}
+
+			if r, err := e.handleMessage(line); err != nil {
+				scanErr = err
+			} else if r != nil {
+				result = r
+			}
+		}, func(line string) {
+			if invocationId != nil {
+				e.promtailChan <- promtailEntry(line, lokiEval, lokiFdStderr, *invocationId)
+			}
+			stderrBuf.WriteString(line)
+			stderrBuf.WriteByte('\n')
+		})
+		if err != nil {
+			return nil, stderrBuf.Bytes(), err
+		}
+
+		return result, stderrBuf.Bytes(), scanErr
+	}
+	tryEvalCUE := func() ([]byte, []byte, error) {
+		e.logger.Debug().
+			Str("command", request.Command).
+			Str("directory", src).
+			Msg("Running built-in CUE evaluator")
+
+		result, err := evaluateCUE(src, request)
+		if err != nil {
+			if invocationId != nil {
+				e.promtailChan <- promtailEntry(err.Error(), lokiEval, lokiFdStderr, *invocationId)
+			}
+			// Fails like an evaluator process so that the next one is tried.
+			evalErr := EvaluationError{1}
+			return nil, []byte(err.Error()), errors.WithMessage(&evalErr, "Failed to evaluate")
+		}
+
+		return result, nil, nil
+	}
+	tryEval := func(evaluator string) ([]byte, []byte, error) {
+		if _, ok := e.stdio.Paths[evaluator]; !ok && evaluator == cueEvaluator {
+			return tryEvalCUE()
+		}
+		if pool, ok := e.pools[evaluator]; ok {
+			return tryEvalPooled(evaluator, pool)
+		}
+		return tryEvalCold(evaluator)
+	}
+
+	if evaluator == "" {
+		if byExtension, err := e.stdio.ByExtension(src); err != nil {
+			return nil, nil, errors.WithMessage(err, "While looking for files with evaluator extensions")
+		} else if byExtension != "" {
+			e.logger.Debug().Str("evaluator", byExtension).Msg("No evaluator given in source, using the one for its files' extension")
+			evaluator = byExtension
+		}
+	}
+
 	if evaluator != "" {
 		if output, stderr, err := tryEval(evaluator); err != nil {
 			if invocationId != nil {
@@ -280,6 +456,38 @@ func (e evaluationService) evaluate(src, evaluator string, args, extraEnv []stri
 	}
 }
 
+// Handles a message printed by an evaluator.
+// Returns the result if the message has one.
+func (e evaluationService) handleMessage(line []byte) ([]byte, error) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return nil, err
+	}
+
+	event, ok := msg["event"]
+	if !ok {
+		return nil, fmt.Errorf("Message without event key received from evaluator: %v", msg)
+	}
+
+	switch event {
+	default:
+		return nil, fmt.Errorf("Message with unknown event received from evaluator: %q", event)
+	case "error":
+		return nil, fmt.Errorf("Error message received from evaluator: %q", msg["error"])
+	case "result":
+		// XXX Take *interface{} to unmarshal result into using json.Decoder instead of returning its []byte?
+		if r, err := json.Marshal(msg["result"]); err != nil {
+			return nil, errors.WithMessage(err, "While marshaling evaluator result")
+		} else {
+			return r, nil
+		}
+	case "prepare":
+		e.logger.Debug().Fields(msg).Msg("Running prepare step")
+	}
+
+	return nil, nil
+}
+
 func promtailEntry(line, label, fd string, invocationId uuid.UUID) promtail.Entry {
 	return promtail.Entry{
 		Labels: map[model.LabelName]model.LabelValue{
@@ -339,11 +547,15 @@ func (e evaluationService) EvaluateAction(src, name string, id uuid.UUID) (domai
 
 	if output, stderr, err := e.evaluate(
 		dst, evaluator,
-		[]string{"eval", "meta", "io"},
+		[]string{"eval", "meta", "io", "chain"},
 		[]string{
 			"CICERO_ACTION_NAME=" + name,
 			"CICERO_ACTION_ID=" + id.String(),
 		},
+		stdioEvaluatorRequest{
+			Command: "action",
+			Action:  &stdioEvaluatorRequestAction{Name: name, Id: id},
+		},
 		nil,
 	); err != nil {
 		return def, err
@@ -352,9 +564,36 @@ func (e evaluationService) EvaluateAction(src, name string, id uuid.UUID) (domai
 		return def, errors.WithMessage(err, "While unmarshaling evaluator output")
 	}
 
+	if err := e.defaultOwner(&def, dst, name); err != nil {
+		return def, err
+	}
+
 	return def, nil
 }
 
+// Sets the owner from the source's owners file, if any,
+// unless the action declares one itself.
+func (e evaluationService) defaultOwner(def *domain.ActionDefinition, src, name string) error {
+	file, err := os.Open(filepath.Join(src, domain.ActionOwnersFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	owners, err := domain.ParseActionOwners(file)
+	if err != nil {
+		return errors.WithMessagef(err, "Invalid %s", domain.ActionOwnersFile)
+	}
+
+	if owner, ok := owners.Match(name); ok {
+		e.logger.Debug().Str("name", name).Str("team", owner.Team).Msg("Setting owner from owners file")
+		return def.DefaultOwner(owner)
+	}
+	return nil
+}
+
 func (e evaluationService) EvaluateRun(src, name string, id, invocationId uuid.UUID, inputs map[string]domain.Fact) (*nomad.Job, error) {
 	dst, evaluator, err := e.fetchSource(src)
 	if err != nil {
@@ -371,10 +610,16 @@ func (e evaluationService) EvaluateRun(src, name string, id, invocationId uuid.U
 	extraEnv := []string{
 		"CICERO_ACTION_NAME=" + name,
 		"CICERO_ACTION_ID=" + id.String(),
-		"CICERO_ACTION_INPUTS=" + string(inputsJson),
+		envActionInputs + string(inputsJson),
+	}
+
+	request := stdioEvaluatorRequest{
+		Command: "run",
+		Action:  &stdioEvaluatorRequestAction{Name: name, Id: id},
+		Inputs:  inputs,
 	}
 
-	output, stderr, err := e.evaluate(dst, evaluator, []string{"eval", "job"}, extraEnv, &invocationId)
+	output, stderr, err := e.evaluate(dst, evaluator, []string{"eval", "job"}, extraEnv, request, &invocationId)
 	if err != nil {
 		return nil, err
 	}
@@ -520,7 +765,7 @@ func (e evaluationService) transform(output []byte, src string, extraEnv []strin
 
 		e.logger.Debug().
 			Stringer("command", cmd).
-			Strs("environment", extraEnv).
+			Strs("environment", e.redactEnv(extraEnv)).
 			Str("transformer", transformer).
 			Msg("Running transformer")
 
@@ -573,7 +818,7 @@ func (e evaluationService) transform(output []byte, src string, extraEnv []strin
 
 			var errExit *exec.ExitError
 			if errors.As(err, &errExit) {
-				evalErr := EvaluationError(*errExit)
+				evalErr := EvaluationError{errExit.ExitCode()}
 				err = errors.WithMessagef(&evalErr, "Failed to transform\nStderr: %s", stderrBuf.String())
 			}
 
@@ -592,7 +837,7 @@ func (e evaluationService) ListActions(src string) ([]string, error) {
 		return nil, err
 	}
 
-	output, stderr, err := e.evaluate(dst, evaluator, []string{"list"}, nil, nil)
+	output, stderr, err := e.evaluate(dst, evaluator, []string{"list"}, nil, stdioEvaluatorRequest{Command: "list"}, nil)
 	if err != nil {
 		return nil, err
 	}
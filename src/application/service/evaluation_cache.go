@@ -0,0 +1,87 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+var metricEvaluationCache = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cicero",
+	Subsystem: "evaluation",
+	Name:      "cache_total",
+	Help:      "Number of run evaluations looked up in the cache by result.",
+}, []string{"result"})
+
+// Remembers the jobs rendered for an action and its inputs
+// so that the evaluator does not run again for the same ones.
+// Actions are immutable: changing the source creates a new one
+// so its ID and the input facts determine the evaluation's result.
+type cachingEvaluationService struct {
+	EvaluationService
+	logger                    zerolog.Logger
+	evaluationCacheRepository repository.EvaluationCacheRepository
+}
+
+func NewCachingEvaluationService(evaluationService EvaluationService, db config.PgxIface, logger *zerolog.Logger) EvaluationService {
+	return &cachingEvaluationService{
+		EvaluationService:         evaluationService,
+		logger:                    logger.With().Str("component", "EvaluationCache").Logger(),
+		evaluationCacheRepository: persistence.NewEvaluationCacheRepository(db),
+	}
+}
+
+// Hashes the facts' IDs and contents so that the key changes
+// when any input is replaced by a newer fact.
+func evaluationInputsHash(inputs map[string]domain.Fact) ([]byte, error) {
+	// map keys are sorted when marshaling
+	inputsJson, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(inputsJson)
+	return hash[:], nil
+}
+
+func (self *cachingEvaluationService) EvaluateRun(src, name string, id, invocationId uuid.UUID, inputs map[string]domain.Fact) (*nomad.Job, error) {
+	inputsHash, err := evaluationInputsHash(inputs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not hash inputs: %v", inputs)
+	}
+
+	logger := self.logger.With().Str("action-id", id.String()).Hex("inputs-hash", inputsHash).Logger()
+
+	// The cache is only an optimization so failing to use it is not fatal.
+	if job, found, err := self.evaluationCacheRepository.Get(id, inputsHash); err != nil {
+		logger.Err(err).Msg("Could not look up evaluation cache")
+	} else if found {
+		logger.Debug().Msg("Using cached evaluation")
+		metricEvaluationCache.WithLabelValues("hit").Inc()
+		return job, nil
+	}
+	metricEvaluationCache.WithLabelValues("miss").Inc()
+
+	job, err := self.EvaluationService.EvaluateRun(src, name, id, invocationId, inputs)
+	if err != nil {
+		return job, err
+	}
+
+	if err := self.evaluationCacheRepository.Save(id, inputsHash, job); err != nil {
+		logger.Err(err).Msg("Could not save evaluation to cache")
+	} else {
+		logger.Trace().Msg("Saved evaluation to cache")
+	}
+
+	return job, nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	cueformat "cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+// Name of the evaluator that is built into Cicero
+// and evaluates actions written in CUE.
+const cueEvaluator = "cue"
+
+// Evaluates the CUE files in the source's root directory,
+// which must be of the same package, like a stdio evaluator would.
+// Only packages of CUE's standard library can be imported.
+//
+// The actions are the fields of `actions`, each with a `meta`, `io`, and `chain`
+// like the definitions of other evaluators, and a `job` that may refer to
+// `io.inputs.<name>.value` and is given in Nomad's JSON or HCL-JSON format.
+// The `io` may refer to its own fields and the package's definitions.
+// For Runs the facts are validated against the `match` of their input.
+func evaluateCUE(dir string, request stdioEvaluatorRequest) ([]byte, error) {
+	inst, err := cueInstance(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	util.WithCUEContext(func(ctx *cue.Context) {
+		value := ctx.BuildInstance(inst)
+		if err = value.Err(); err != nil {
+			return
+		}
+
+		actions := value.LookupPath(cue.MakePath(cue.Str("actions")))
+		if !actions.Exists() {
+			err = errors.New(`The CUE files define no "actions"`)
+			return
+		}
+
+		switch request.Command {
+		case "list":
+			result, err = cueActionNames(actions)
+		case "action":
+			result, err = cueActionDefinition(value, actions, request.Action.Name)
+		case "run":
+			result, err = cueActionJob(actions, request.Action.Name, request.Inputs)
+		default:
+			err = errors.Errorf("Unknown command %q", request.Command)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Schemas that refer to something outside of `io` fail here
+	// when the action is saved rather than when it is first invoked.
+	if def, ok := result.(domain.ActionDefinition); ok {
+		if err := util.CUEString(def.InOut).Value(nil, nil).Err(); err != nil {
+			return nil, errors.WithMessagef(err, "The io of action %q refers to something outside of it", request.Action.Name)
+		}
+	}
+
+	return json.Marshal(result)
+}
+
+func cueInstance(dir string) (*build.Instance, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.cue"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("No CUE files in %s", dir)
+	}
+
+	inst := build.NewContext().NewInstance(dir, nil)
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := parser.ParseFile(path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		switch pkg := file.PackageName(); {
+		case inst.PkgName == "":
+			inst.PkgName = pkg
+		case pkg != inst.PkgName:
+			return nil, errors.Errorf("%s is of package %q instead of %q", filepath.Base(path), pkg, inst.PkgName)
+		}
+
+		if err := inst.AddSyntax(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return inst, nil
+}
+
+func cueActionNames(actions cue.Value) ([]string, error) {
+	fields, err := actions.Fields()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for fields.Next() {
+		names = append(names, fields.Label())
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func cueAction(actions cue.Value, name string) (cue.Value, error) {
+	action := actions.LookupPath(cue.MakePath(cue.Str(name)))
+	if !action.Exists() {
+		return action, errors.Errorf("No action named %q", name)
+	}
+	return action, action.Err()
+}
+
+func cueActionDefinition(root, actions cue.Value, name string) (def domain.ActionDefinition, err error) {
+	action, err := cueAction(actions, name)
+	if err != nil {
+		return
+	}
+
+	io := action.LookupPath(cue.MakePath(cue.Str("io")))
+	if !io.Exists() {
+		err = errors.Errorf(`Action %q has no "io"`, name)
+		return
+	}
+
+	var inOut util.CUEString
+	if err = inOut.FromValue(io); err != nil {
+		return
+	}
+
+	// References to the package's definitions
+	// are kept so they must come along.
+	defs, err := root.Fields(cue.Definitions(true))
+	if err != nil {
+		return
+	}
+	src := strings.Builder{}
+	src.WriteString(string(inOut))
+	for defs.Next() {
+		if !defs.Selector().IsDefinition() {
+			continue
+		}
+
+		var syntax []byte
+		if syntax, err = cueformat.Node(defs.Value().Syntax(
+			cue.Definitions(true),
+			cue.Optional(true),
+			cue.ResolveReferences(false),
+		)); err != nil {
+			return
+		}
+		src.WriteString("\n" + defs.Selector().String() + ": " + string(syntax))
+	}
+	def.InOut = domain.InOutCUEString(src.String())
+
+	for _, field := range []struct {
+		name string
+		ptr  interface{}
+	}{
+		{"meta", &def.Meta},
+		{"chain", &def.Chain},
+	} {
+		value := action.LookupPath(cue.MakePath(cue.Str(field.name)))
+		if !value.Exists() {
+			continue
+		}
+		if err = cueDecodeJSON(value, field.ptr); err != nil {
+			err = errors.WithMessagef(err, "Invalid %q of action %q", field.name, name)
+			return
+		}
+	}
+
+	return
+}
+
+func cueActionJob(actions cue.Value, name string, inputs map[string]domain.Fact) (interface{}, error) {
+	action, err := cueAction(actions, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for inputName, fact := range inputs {
+		var factValue interface{}
+		if factJson, err := json.Marshal(fact); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(factJson, &factValue); err != nil {
+			return nil, err
+		}
+
+		path := cue.MakePath(cue.Str("io"), cue.Str("inputs"), cue.Str(inputName))
+
+		if match := action.LookupPath(path).LookupPath(cue.MakePath(cue.Str("match"))); match.Exists() {
+			if err := match.Unify(match.Context().Encode(fact.Value)).Validate(); err != nil {
+				return nil, errors.WithMessagef(err, "Fact for input %q does not match", inputName)
+			}
+		}
+
+		action = action.FillPath(path, factValue)
+	}
+
+	job := action.LookupPath(cue.MakePath(cue.Str("job")))
+	if !job.Exists() {
+		return struct{}{}, nil
+	}
+
+	var decoded interface{}
+	if err := cueDecodeJSON(job, &decoded); err != nil {
+		return nil, errors.WithMessagef(err, "Invalid job of action %q", name)
+	}
+	return map[string]interface{}{"job": decoded}, nil
+}
+
+// Decodes like JSON so that the fields' tags are respected.
+func cueDecodeJSON(value cue.Value, ptr interface{}) error {
+	valueJson, err := value.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(valueJson, ptr)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func TestEvaluateCUE(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "build.cue"), []byte(`package actions
+
+#Rev: =~"^[0-9a-f]{40}$"
+
+actions: "ci/build": {
+	meta: description: "Builds a commit"
+	chain: [{action: "ci/deploy"}]
+	io: {
+		inputs: start: match: {rev: #Rev}
+		output: success: {ok: true, rev: inputs.start.value.rev}
+	}
+	job: TaskGroups: [{
+		Name: "build"
+		Tasks: [{Name: "build", Driver: "exec", Config: {command: "make", args: [io.inputs.start.value.rev]}}]
+	}]
+}
+`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "deploy.cue"), []byte(`package actions
+
+actions: "ci/deploy": io: inputs: build: match: ok: true
+`), 0o644))
+
+	evaluate := func(request stdioEvaluatorRequest, result interface{}) error {
+		output, err := evaluateCUE(dir, request)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(output, result)
+	}
+
+	names := []string{}
+	assert.NoError(t, evaluate(stdioEvaluatorRequest{Command: "list"}, &names))
+	assert.Equal(t, []string{"ci/build", "ci/deploy"}, names)
+
+	build := &stdioEvaluatorRequestAction{Name: "ci/build"}
+
+	def := domain.ActionDefinition{}
+	if assert.NoError(t, evaluate(stdioEvaluatorRequest{Command: "action", Action: build}, &def)) {
+		assert.Equal(t, "Builds a commit", def.Meta["description"])
+		assert.Equal(t, domain.ActionChain{{Action: "ci/deploy"}}, def.Chain)
+		assert.NoError(t, def.InOut.ValidateOutput())
+		if inputs, err := def.InOut.Inputs(nil); assert.NoError(t, err) {
+			assert.Contains(t, inputs, "start")
+			assert.Error(t, inputs["start"].Match.Unify(inputs["start"].Match.Context().Encode(map[string]interface{}{"rev": "main"})).Validate(), "definitions come along")
+		}
+	}
+
+	rev := "0123456789abcdef0123456789abcdef01234567"
+	run := struct {
+		Job struct {
+			TaskGroups []struct {
+				Tasks []struct {
+					Config struct {
+						Args []string `json:"args"`
+					}
+				}
+			}
+		} `json:"job"`
+	}{}
+	if assert.NoError(t, evaluate(stdioEvaluatorRequest{
+		Command: "run",
+		Action:  build,
+		Inputs:  map[string]domain.Fact{"start": {Value: map[string]interface{}{"rev": rev}}},
+	}, &run)) {
+		assert.Equal(t, []string{rev}, run.Job.TaskGroups[0].Tasks[0].Config.Args)
+	}
+
+	_, err := evaluateCUE(dir, stdioEvaluatorRequest{
+		Command: "run",
+		Action:  build,
+		Inputs:  map[string]domain.Fact{"start": {Value: map[string]interface{}{"rev": "main"}}},
+	})
+	assert.Error(t, err, "facts must match their input")
+
+	noJob := map[string]interface{}{}
+	assert.NoError(t, evaluate(stdioEvaluatorRequest{
+		Command: "run",
+		Action:  &stdioEvaluatorRequestAction{Name: "ci/deploy"},
+		Inputs:  map[string]domain.Fact{"build": {Value: map[string]interface{}{"ok": true}}},
+	}, &noJob))
+	assert.Empty(t, noJob)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.cue"), []byte(`package other`), 0o644))
+	_, err = evaluateCUE(dir, stdioEvaluatorRequest{Command: "list"})
+	assert.Error(t, err, "all files must be of the same package")
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+)
+
+var (
+	metricEvaluatorPoolProcesses = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cicero",
+		Subsystem: "evaluator_pool",
+		Name:      "processes",
+		Help:      "Number of warm evaluator processes by state.",
+	}, []string{"evaluator", "state"})
+	metricEvaluatorPoolEvaluations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "evaluator_pool",
+		Name:      "evaluations_total",
+		Help:      "Number of evaluations run on warm evaluator processes by result.",
+	}, []string{"evaluator", "result"})
+	metricEvaluatorPoolRecycled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cicero",
+		Subsystem: "evaluator_pool",
+		Name:      "recycled_total",
+		Help:      "Number of warm evaluator processes that were replaced by reason.",
+	}, []string{"evaluator", "reason"})
+	metricEvaluatorPoolWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "cicero",
+		Subsystem: "evaluator_pool",
+		Name:      "wait_seconds",
+		Help:      "Time evaluations waited for a warm evaluator process.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, []string{"evaluator"})
+)
+
+// Keeps evaluator processes running between evaluations
+// so that they do not pay for starting up every time.
+//
+// The processes are started as `cicero-evaluator-<name> serve`.
+// They read one request per line from stdin and print the same
+// messages as for a single evaluation, followed by
+// `{"event": "done", "status": <exit code>}`.
+// Their stderr is wrapped in `{"event": "stderr", "line": "…"}` messages.
+type evaluatorPool struct {
+	evaluator      string
+	maxEvaluations int
+	// Holds a token for every process that is idle, busy, or starting.
+	slots  chan struct{}
+	idle   chan *evaluatorProcess
+	logger zerolog.Logger
+}
+
+type evaluatorProcess struct {
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	stdout      *bufio.Scanner
+	evaluations int
+}
+
+type evaluatorRequest struct {
+	Dir  string   `json:"dir"`
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+}
+
+type evaluatorServeMessage struct {
+	Event  string `json:"event"`
+	Line   string `json:"line"`
+	Status int    `json:"status"`
+}
+
+func newEvaluatorPool(evaluator string, size, maxEvaluations int, logger *zerolog.Logger) *evaluatorPool {
+	pool := &evaluatorPool{
+		evaluator:      evaluator,
+		maxEvaluations: maxEvaluations,
+		slots:          make(chan struct{}, size),
+		idle:           make(chan *evaluatorProcess, size),
+		logger:         logger.With().Str("evaluator", evaluator).Logger(),
+	}
+
+	for i := 0; i < size; i++ {
+		pool.slots <- struct{}{}
+		go pool.replenish()
+	}
+
+	return pool
+}
+
+func (self *evaluatorPool) start() (*evaluatorProcess, error) {
+	cmd := exec.Command("cicero-evaluator-"+self.evaluator, "serve")
+	cmd.Env = os.Environ()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.WithMessagef(err, "Could not start evaluator %q", self.evaluator)
+	}
+
+	self.logger.Debug().Int("pid", cmd.Process.Pid).Msg("Started warm evaluator")
+
+	return &evaluatorProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: newScanner(stdout),
+	}, nil
+}
+
+// Starts a process in place of one that was stopped
+// and gives up its slot if that fails.
+func (self *evaluatorPool) replenish() {
+	if process, err := self.start(); err != nil {
+		self.logger.Err(err).Msg("Could not start warm evaluator")
+		<-self.slots
+	} else {
+		metricEvaluatorPoolProcesses.WithLabelValues(self.evaluator, "idle").Inc()
+		self.idle <- process
+	}
+}
+
+func (self *evaluatorPool) acquire() (*evaluatorProcess, error) {
+	start := time.Now()
+	defer func() {
+		metricEvaluatorPoolWait.WithLabelValues(self.evaluator).Observe(time.Since(start).Seconds())
+	}()
+
+	select {
+	case process := <-self.idle:
+		metricEvaluatorPoolProcesses.WithLabelValues(self.evaluator, "idle").Dec()
+		metricEvaluatorPoolProcesses.WithLabelValues(self.evaluator, "busy").Inc()
+		return process, nil
+	case self.slots <- struct{}{}:
+		// A process failed to start earlier so there is room for another one.
+		process, err := self.start()
+		if err != nil {
+			<-self.slots
+			return nil, err
+		}
+		metricEvaluatorPoolProcesses.WithLabelValues(self.evaluator, "busy").Inc()
+		return process, nil
+	}
+}
+
+// Puts the process back into the pool
+// or replaces it if it is used up or broken.
+func (self *evaluatorPool) release(process *evaluatorProcess, broken bool) {
+	metricEvaluatorPoolProcesses.WithLabelValues(self.evaluator, "busy").Dec()
+
+	if !broken && (self.maxEvaluations <= 0 || process.evaluations < self.maxEvaluations) {
+		metricEvaluatorPoolProcesses.WithLabelValues(self.evaluator, "idle").Inc()
+		self.idle <- process
+		return
+	}
+
+	reason := "exhausted"
+	if broken {
+		reason = "broken"
+	}
+	metricEvaluatorPoolRecycled.WithLabelValues(self.evaluator, reason).Inc()
+
+	self.logger.Debug().
+		Int("pid", process.cmd.Process.Pid).
+		Int("evaluations", process.evaluations).
+		Str("reason", reason).
+		Msg("Recycling warm evaluator")
+
+	// Evaluators exit when their stdin is closed.
+	if err := process.stdin.Close(); err != nil || broken {
+		if err := process.cmd.Process.Kill(); err != nil {
+			self.logger.Err(err).Int("pid", process.cmd.Process.Pid).Msg("Could not kill warm evaluator")
+		}
+	}
+	go func() {
+		if err := process.cmd.Wait(); err != nil {
+			self.logger.Debug().Err(err).Int("pid", process.cmd.Process.Pid).Msg("Warm evaluator exited")
+		}
+	}()
+
+	go self.replenish()
+}
+
+// Runs an evaluation on a warm process, calling the given functions
+// with each line the evaluator prints on stdout and stderr.
+// Returns an `*EvaluationError` if the evaluation failed.
+func (self *evaluatorPool) evaluate(dir string, args, env []string, onStdout func([]byte), onStderr func(string)) (err error) {
+	process, err := self.acquire()
+	if err != nil {
+		return err
+	}
+
+	broken := true
+	defer func() {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		metricEvaluatorPoolEvaluations.WithLabelValues(self.evaluator, result).Inc()
+
+		self.release(process, broken)
+	}()
+
+	process.evaluations++
+
+	if request, err := json.Marshal(evaluatorRequest{Dir: dir, Args: args, Env: env}); err != nil {
+		return errors.WithMessage(err, "Could not marshal evaluator request")
+	} else if _, err := process.stdin.Write(append(request, '\n')); err != nil {
+		return errors.WithMessage(err, "Could not send request to warm evaluator")
+	}
+
+	for process.stdout.Scan() {
+		line := process.stdout.Bytes()
+
+		var msg evaluatorServeMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return errors.WithMessagef(err, "Invalid message received from warm evaluator: %s", line)
+		}
+
+		switch msg.Event {
+		case "stderr":
+			onStderr(msg.Line)
+		case "done":
+			broken = false
+			if msg.Status != 0 {
+				return errors.WithMessage(&EvaluationError{msg.Status}, "Failed to evaluate")
+			}
+			return nil
+		default:
+			onStdout(line)
+		}
+	}
+
+	if err := process.stdout.Err(); err != nil {
+		return errors.WithMessage(err, "While scanning stdout of warm evaluator")
+	}
+	return errors.New("Warm evaluator exited during evaluation")
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Evaluators that are sent a request as JSON on stdin
+// instead of CLI arguments and environment variables
+// so that any executable can serve as one.
+//
+// The request is a single line:
+//
+//	{"command": "list"}
+//	{"command": "action", "action": {"name": "…", "id": "…"}}
+//	{"command": "run", "action": {"name": "…", "id": "…"}, "inputs": {"…": <fact>}}
+//
+// The evaluator prints the same messages on stdout as the others,
+// ending with `{"event": "result", "result": …}`: the action names for "list",
+// the action definition for "action", and `{"job": …}` for "run".
+// Lines on stderr are logged.
+type StdioEvaluators struct {
+	// Executables by evaluator name.
+	Paths map[string]string
+	// Evaluator names by file extension without the dot.
+	// Sources without an evaluator use that of the first file
+	// in their root directory with one of these extensions.
+	Extensions map[string]string
+}
+
+type stdioEvaluatorRequest struct {
+	Command string                       `json:"command"`
+	Action  *stdioEvaluatorRequestAction `json:"action,omitempty"`
+	Inputs  map[string]domain.Fact       `json:"inputs,omitempty"`
+}
+
+type stdioEvaluatorRequestAction struct {
+	Name string    `json:"name"`
+	Id   uuid.UUID `json:"id"`
+}
+
+// Parses evaluators given as name=path and extensions given as ext=name.
+func ParseStdioEvaluators(paths, extensions []string) (StdioEvaluators, error) {
+	evaluators := StdioEvaluators{
+		Paths:      make(map[string]string, len(paths)),
+		Extensions: make(map[string]string, len(extensions)),
+	}
+
+	for _, str := range paths {
+		name, path, ok := strings.Cut(str, "=")
+		if !ok || name == "" || path == "" {
+			return evaluators, errors.Errorf("Invalid evaluator %q, must be name=path", str)
+		}
+		evaluators.Paths[name] = path
+	}
+
+	for _, str := range extensions {
+		ext, name, ok := strings.Cut(str, "=")
+		ext = strings.TrimPrefix(ext, ".")
+		if !ok || ext == "" || name == "" {
+			return evaluators, errors.Errorf("Invalid evaluator extension %q, must be ext=name", str)
+		}
+		evaluators.Extensions[ext] = name
+	}
+
+	return evaluators, nil
+}
+
+// Returns the evaluator for the first file in the directory
+// with a registered extension, if any.
+func (self StdioEvaluators) ByExtension(dir string) (string, error) {
+	if len(self.Extensions) == 0 {
+		return "", nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if name, ok := self.Extensions[strings.TrimPrefix(filepath.Ext(entry.Name()), ".")]; ok {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
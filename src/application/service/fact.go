@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"cuelang.org/go/cue"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 
 	"github.com/input-output-hk/cicero/src/config"
@@ -17,16 +20,39 @@ import (
 	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
 )
 
+var metricFactDeduplicated = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "cicero",
+	Subsystem: "fact",
+	Name:      "deduplicated_total",
+	Help:      "Number of ingested facts that were coalesced with an identical one saved shortly before.",
+})
+
 type FactService interface {
 	WithQuerier(config.PgxIface) FactService
 	withQuerier(config.PgxIface, FactServiceCyclicDependencies) FactService
 
 	GetById(uuid.UUID) (*domain.Fact, error)
 	GetByRunId(uuid.UUID) ([]domain.Fact, error)
+	// Returns the latest output fact published by a Run
+	// of an action with the given name other than the given Run.
+	GetLatestOutput(actionName string, exceptRunId uuid.UUID) (*domain.Fact, error)
 	GetBinaryById(pgx.Tx, uuid.UUID) (io.ReadSeekCloser, error)
 	GetLatestByCue(cue.Value) (*domain.Fact, error)
+	GetLatestByCueSignedBy(cue.Value, []string) (*domain.Fact, error)
 	GetByCue(cue.Value) ([]domain.Fact, error)
+	GetByLabels(domain.FactLabels, *repository.Page) ([]domain.Fact, error)
+	GetFeed(since int64, limit int) (domain.FactFeed, error)
+	// Also saves the fact's links.
 	Save(*domain.Fact, io.Reader) ([]domain.Invocation, InvokeRunFunc, error)
+	// Like `Save()` for facts from outside, like webhooks,
+	// which may deliver the same event more than once.
+	// If an identical fact from the same source was saved within
+	// the runtime config's `fact_dedup_window`, the fact is filled in
+	// with that one instead and nothing is invoked.
+	Ingest(*domain.Fact, io.Reader) ([]domain.Invocation, InvokeRunFunc, error)
+	GetLinks(uuid.UUID) (domain.FactLinks, error)
+	Link(*domain.FactLink) error
+	Unlink(domain.FactLink) error
 	GetInvocationInputFacts(map[string]uuid.UUID) (map[string]domain.Fact, error)
 	Match(*domain.Fact, cue.Value) (cue.Value, error, error)
 }
@@ -36,17 +62,26 @@ type FactServiceCyclicDependencies struct {
 }
 
 type factService struct {
-	logger         zerolog.Logger
-	factRepository repository.FactRepository
-	db             config.PgxIface
+	logger             zerolog.Logger
+	factRepository     repository.FactRepository
+	factLinkRepository repository.FactLinkRepository
+	factBinaryService  FactBinaryService
+	db                 config.PgxIface
+	runtime            *config.RuntimeConfig
+	// Seals the original values of facts that are stored redacted.
+	unsealer *domain.Unsealer
 	FactServiceCyclicDependencies
 }
 
-func NewFactService(db config.PgxIface, actionService *ActionService, logger *zerolog.Logger) FactService {
+func NewFactService(db config.PgxIface, actionService *ActionService, factBinaryService FactBinaryService, runtime *config.RuntimeConfig, unsealer *domain.Unsealer, logger *zerolog.Logger) FactService {
 	return &factService{
-		logger:         logger.With().Str("component", "FactService").Logger(),
-		factRepository: persistence.NewFactRepository(db),
-		db:             db,
+		logger:             logger.With().Str("component", "FactService").Logger(),
+		factRepository:     persistence.NewFactRepository(db),
+		factLinkRepository: persistence.NewFactLinkRepository(db),
+		factBinaryService:  factBinaryService,
+		db:                 db,
+		runtime:            runtime,
+		unsealer:           unsealer,
 		FactServiceCyclicDependencies: FactServiceCyclicDependencies{
 			actionService: actionService,
 		},
@@ -61,7 +96,11 @@ func (self factService) withQuerier(querier config.PgxIface, cyclicDeps FactServ
 	result := factService{
 		logger:                        self.logger,
 		factRepository:                self.factRepository.WithQuerier(querier),
+		factLinkRepository:            self.factLinkRepository.WithQuerier(querier),
+		factBinaryService:             self.factBinaryService.WithQuerier(querier),
 		db:                            querier,
+		runtime:                       self.runtime,
+		unsealer:                      self.unsealer,
 		FactServiceCyclicDependencies: cyclicDeps,
 	}
 
@@ -77,6 +116,9 @@ func (self factService) GetById(id uuid.UUID) (fact *domain.Fact, err error) {
 	self.logger.Trace().Str("id", id.String()).Msg("Getting Fact by ID")
 	fact, err = self.factRepository.GetById(id)
 	err = errors.WithMessagef(err, "Could not select existing Fact with ID %q", id)
+	if err == nil && fact != nil {
+		err = fact.UnsealRedacted(self.unsealer)
+	}
 	return
 }
 
@@ -84,29 +126,94 @@ func (self factService) GetByRunId(id uuid.UUID) (facts []domain.Fact, err error
 	self.logger.Trace().Str("id", id.String()).Msg("Getting Facts by Run ID")
 	facts, err = self.factRepository.GetByRunId(id)
 	err = errors.WithMessagef(err, "Could not select Facts for Run with ID %q", id)
+	if err == nil {
+		err = self.unsealRedacted(facts)
+	}
+	return
+}
+
+func (self factService) GetLatestOutput(actionName string, exceptRunId uuid.UUID) (fact *domain.Fact, err error) {
+	self.logger.Trace().Str("action", actionName).Stringer("except-run-id", exceptRunId).Msg("Getting latest output Fact of action")
+	fact, err = self.factRepository.GetLatestOutput(actionName, exceptRunId)
+	err = errors.WithMessagef(err, "Could not select latest output Fact of action %q", actionName)
+	if err == nil && fact != nil {
+		err = fact.UnsealRedacted(self.unsealer)
+	}
 	return
 }
 
 func (self factService) GetBinaryById(tx pgx.Tx, id uuid.UUID) (binary io.ReadSeekCloser, err error) {
 	self.logger.Trace().Str("id", id.String()).Msg("Getting binary by ID")
-	binary, err = self.factRepository.GetBinaryById(tx, id)
+	binary, err = self.factBinaryService.Open(tx, id)
 	err = errors.WithMessagef(err, "Could not select binary from Fact with ID %q", id)
 	return
 }
 
 func (self factService) Save(fact *domain.Fact, binary io.Reader) ([]domain.Invocation, InvokeRunFunc, error) {
+	return self.save(fact, binary, false)
+}
+
+func (self factService) Ingest(fact *domain.Fact, binary io.Reader) ([]domain.Invocation, InvokeRunFunc, error) {
+	return self.save(fact, binary, true)
+}
+
+func (self factService) save(fact *domain.Fact, binary io.Reader, dedup bool) ([]domain.Invocation, InvokeRunFunc, error) {
 	var runFunc InvokeRunFunc
 	var invocations []domain.Invocation
 
+	if fact.Signature == nil && self.runtime != nil {
+		fact.Value = self.runtime.Get().FactIngest.Apply(fact.Value)
+	}
+
+	if err := fact.FactLabels.Validate(); err != nil {
+		return nil, nil, errors.WithMessage(err, "Invalid Fact labels")
+	}
+	for _, link := range fact.Links {
+		// The fact has no ID yet so only the kind can be checked.
+		if _, err := domain.ParseFactLinkKind(string(link.Kind)); err != nil {
+			return nil, nil, errors.WithMessage(err, "Invalid Fact link")
+		}
+	}
+
 	if err := self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
 		txSelf := self.WithQuerier(tx).(*factService)
 
+		if fact.RunId != nil && fact.Namespace == "" {
+			if action, err := (*txSelf.actionService).GetByRunId(*fact.RunId); err != nil {
+				return err
+			} else if action != nil {
+				fact.Namespace = action.FactNamespace()
+			}
+		}
+
+		original, err := self.sealRedacted(fact)
+		if err != nil {
+			return errors.WithMessage(err, "Could not seal redacted Fact value")
+		}
+
 		self.logger.Trace().Msg("Saving new Fact")
-		if err := txSelf.factRepository.Save(fact, binary); err != nil {
+		if window := self.dedupWindow(); dedup && window > 0 {
+			if duplicate, err := txSelf.factRepository.SaveUnlessDuplicate(fact, binary, time.Now().Add(-window).UTC()); err != nil {
+				return errors.WithMessagef(err, "Could not insert Fact")
+			} else if duplicate {
+				metricFactDeduplicated.Inc()
+				self.logger.Debug().Stringer("id", fact.ID).Msg("Coalesced duplicate Fact")
+				runFunc = noopInvokeRunFunc
+				return fact.UnsealRedacted(self.unsealer)
+			}
+		} else if err := txSelf.factRepository.Save(fact, binary); err != nil {
 			return errors.WithMessagef(err, "Could not insert Fact")
 		}
+		fact.Value = original
 		self.logger.Trace().Str("id", fact.ID.String()).Msg("Created Fact")
 
+		for i := range fact.Links {
+			fact.Links[i].FactId = fact.ID
+			if err := txSelf.Link(&fact.Links[i]); err != nil {
+				return err
+			}
+		}
+
 		if invocations_, runFunc_, err := (*txSelf.actionService).InvokeCurrentActive(); err != nil {
 			return errors.WithMessagef(err, "Could not invoke currently active Actions")
 		} else {
@@ -121,10 +228,81 @@ func (self factService) Save(fact *domain.Fact, binary io.Reader) ([]domain.Invo
 	return invocations, runFunc, nil
 }
 
+func (self factService) dedupWindow() time.Duration {
+	if self.runtime == nil {
+		return 0
+	}
+	return time.Duration(self.runtime.Get().FactDedupWindow)
+}
+
+func noopInvokeRunFunc(config.PgxIface) ([]domain.Run, InvokeRegisterFunc, error) {
+	return nil, func() error { return nil }, nil
+}
+
+func (self factService) GetLinks(id uuid.UUID) (links domain.FactLinks, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting links of Fact")
+	links, err = self.factLinkRepository.GetByFactId(id)
+	err = errors.WithMessagef(err, "Could not select links of Fact with ID %q", id)
+	return
+}
+
+func (self factService) Link(link *domain.FactLink) error {
+	if err := link.Validate(); err != nil {
+		return errors.WithMessage(err, "Invalid Fact link")
+	}
+
+	self.logger.Trace().Stringer("fact", link.FactId).Str("kind", string(link.Kind)).Stringer("target", link.TargetId).Msg("Saving Fact link")
+	if err := self.factLinkRepository.Save(link); err != nil {
+		return errors.WithMessagef(err, "Could not insert link from Fact %q to %q", link.FactId, link.TargetId)
+	}
+	return nil
+}
+
+func (self factService) Unlink(link domain.FactLink) error {
+	self.logger.Trace().Stringer("fact", link.FactId).Str("kind", string(link.Kind)).Stringer("target", link.TargetId).Msg("Deleting Fact link")
+	if err := self.factLinkRepository.Delete(link); err != nil {
+		return errors.WithMessagef(err, "Could not delete link from Fact %q to %q", link.FactId, link.TargetId)
+	}
+	return nil
+}
+
+func (self factService) GetByLabels(labels domain.FactLabels, page *repository.Page) (facts []domain.Fact, err error) {
+	self.logger.Trace().Str("namespace", labels.Namespace).Str("name", labels.Name).Strs("tags", labels.Tags).Msg("Getting Facts by labels")
+	facts, err = self.factRepository.GetByLabels(labels, page)
+	err = errors.WithMessagef(err, "Could not select Facts by labels %+v", labels)
+	if err == nil {
+		err = self.unsealRedacted(facts)
+	}
+	return
+}
+
+func (self factService) GetFeed(since int64, limit int) (feed domain.FactFeed, err error) {
+	self.logger.Trace().Int64("since", since).Int("limit", limit).Msg("Getting feed of Facts")
+	feed, err = self.factRepository.GetFeed(since, limit)
+	err = errors.WithMessagef(err, "Could not select feed of Facts since %d", since)
+	if err == nil {
+		err = self.unsealRedacted(feed.Facts)
+	}
+	return
+}
+
 func (self factService) GetLatestByCue(value cue.Value) (fact *domain.Fact, err error) {
 	self.logger.Trace().Str("cue", fmt.Sprint(value)).Msg("Getting latest Fact by CUE")
 	fact, err = self.factRepository.GetLatestByCue(value)
 	err = errors.WithMessagef(err, "Could not select latest Fact by CUE %q", value)
+	if err == nil && fact != nil {
+		err = fact.UnsealRedacted(self.unsealer)
+	}
+	return
+}
+
+func (self factService) GetLatestByCueSignedBy(value cue.Value, signers []string) (fact *domain.Fact, err error) {
+	self.logger.Trace().Str("cue", fmt.Sprint(value)).Strs("signers", signers).Msg("Getting latest signed Fact by CUE")
+	fact, err = self.factRepository.GetLatestByCueSignedBy(value, signers)
+	err = errors.WithMessagef(err, "Could not select latest Fact signed by %v by CUE %q", signers, value)
+	if err == nil && fact != nil {
+		err = fact.UnsealRedacted(self.unsealer)
+	}
 	return
 }
 
@@ -132,9 +310,31 @@ func (self factService) GetByCue(value cue.Value) (facts []domain.Fact, err erro
 	self.logger.Trace().Str("cue", fmt.Sprint(value)).Msg("Getting Facts by CUE")
 	facts, err = self.factRepository.GetByCue(value)
 	err = errors.WithMessagef(err, "Could not select Facts by CUE %q", value)
+	if err == nil {
+		err = self.unsealRedacted(facts)
+	}
 	return
 }
 
+func (self factService) unsealRedacted(facts []domain.Fact) error {
+	for i := range facts {
+		if err := facts[i].UnsealRedacted(self.unsealer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stores the parts of the fact's value that fact redactions hide
+// only sealed if Cicero has a key to seal them with.
+// Returns the original value.
+func (self factService) sealRedacted(fact *domain.Fact) (interface{}, error) {
+	if self.runtime == nil || self.unsealer == nil {
+		return fact.Value, nil
+	}
+	return fact.SealRedacted(self.runtime.Get().FactRedactions, self.unsealer)
+}
+
 // XXX Could probably be done entirely in the DB with a single SQL query
 // XXX Should this be `InvocationService.GetInputsById()`?
 func (self factService) GetInvocationInputFacts(inputFactIds map[string]uuid.UUID) (map[string]domain.Fact, error) {
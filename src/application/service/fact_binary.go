@@ -0,0 +1,276 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type FactBinaryService interface {
+	WithQuerier(config.PgxIface) FactBinaryService
+
+	// Opens the fact's binary from whichever tier it is in.
+	// Binaries that are not hot are decompressed
+	// to a temporary file first, which is removed on close.
+	Open(tx pgx.Tx, factId uuid.UUID) (io.ReadSeekCloser, error)
+	GetAccesses() ([]domain.FactBinaryAccess, error)
+	// Returns whether the binary was still in the tier it was moved from.
+	Move(binary domain.FactBinaryAccess, to domain.FactBinaryTier) (bool, error)
+	// Deletes the files of cold binaries that no fact has anymore
+	// if they were last modified before the given time.
+	PruneCold(before time.Time) (int, error)
+}
+
+type factBinaryService struct {
+	logger               zerolog.Logger
+	factBinaryRepository repository.FactBinaryRepository
+	db                   config.PgxIface
+	// Where cold binaries are stored, empty if there is no cold tier.
+	coldDir string
+}
+
+func NewFactBinaryService(db config.PgxIface, coldDir string, logger *zerolog.Logger) FactBinaryService {
+	return &factBinaryService{
+		logger:               logger.With().Str("component", "FactBinaryService").Logger(),
+		factBinaryRepository: persistence.NewFactBinaryRepository(db),
+		db:                   db,
+		coldDir:              coldDir,
+	}
+}
+
+func (self factBinaryService) WithQuerier(querier config.PgxIface) FactBinaryService {
+	return &factBinaryService{
+		logger:               self.logger,
+		factBinaryRepository: self.factBinaryRepository.WithQuerier(querier),
+		db:                   querier,
+		coldDir:              self.coldDir,
+	}
+}
+
+// Hashes are SRI strings that may contain slashes.
+func (self factBinaryService) coldPath(hash string) string {
+	return filepath.Join(self.coldDir, strings.NewReplacer("/", "_", "+", "-").Replace(hash)+".gz")
+}
+
+func (self factBinaryService) Open(tx pgx.Tx, factId uuid.UUID) (io.ReadSeekCloser, error) {
+	hash, tier, err := self.factBinaryRepository.GetTierByFactId(factId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not select binary of Fact with ID %q", factId)
+	}
+
+	self.logger.Trace().Stringer("fact", factId).Str("hash", hash).Str("tier", string(tier)).Msg("Opening binary")
+
+	var compressed io.ReadCloser
+	switch tier {
+	case domain.FactBinaryTierHot:
+		return self.factBinaryRepository.Open(tx, hash)
+	case domain.FactBinaryTierWarm:
+		if compressed, err = self.factBinaryRepository.Open(tx, hash); err != nil {
+			return nil, err
+		}
+	case domain.FactBinaryTierCold:
+		if compressed, err = os.Open(self.coldPath(hash)); err != nil {
+			return nil, errors.WithMessagef(err, "Could not open cold binary with hash %q", hash)
+		}
+	default:
+		return nil, errors.Errorf("Binary with hash %q is in unknown tier %q", hash, tier)
+	}
+	defer compressed.Close()
+
+	return decompressToTemp(compressed)
+}
+
+type tempFile struct{ *os.File }
+
+func (self tempFile) Close() error {
+	closeErr := self.File.Close()
+	if err := os.Remove(self.File.Name()); err != nil {
+		return errors.WithMessage(err, "Could not remove temporary file")
+	}
+	return closeErr
+}
+
+func decompressToTemp(compressed io.Reader) (io.ReadSeekCloser, error) {
+	gz, err := gzip.NewReader(compressed)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not decompress binary")
+	}
+
+	file, err := os.CreateTemp("", "cicero-fact-binary-")
+	if err != nil {
+		return nil, err
+	}
+	temp := tempFile{file}
+
+	if _, err := io.Copy(file, gz); err != nil {
+		temp.Close()
+		return nil, errors.WithMessage(err, "Could not decompress binary")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		temp.Close()
+		return nil, err
+	}
+
+	return temp, nil
+}
+
+func (self factBinaryService) GetAccesses() (binaries []domain.FactBinaryAccess, err error) {
+	self.logger.Trace().Msg("Getting accesses of binaries")
+	binaries, err = self.factBinaryRepository.GetAccesses()
+	err = errors.WithMessage(err, "Could not select accesses of binaries")
+	return
+}
+
+func (self factBinaryService) Move(binary domain.FactBinaryAccess, to domain.FactBinaryTier) (moved bool, err error) {
+	if (binary.Tier == domain.FactBinaryTierCold || to == domain.FactBinaryTierCold) && self.coldDir == "" {
+		return false, errors.Errorf("Cannot move binary with hash %q from %s to %s without a cold storage directory", binary.Hash, binary.Tier, to)
+	}
+
+	self.logger.Trace().Str("hash", binary.Hash).Str("from", string(binary.Tier)).Str("to", string(to)).Msg("Moving binary")
+
+	coldPath := self.coldPath(binary.Hash)
+
+	if err := self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*factBinaryService)
+
+		var content io.Reader
+		switch binary.Tier {
+		case domain.FactBinaryTierHot:
+			stored, err := txSelf.factBinaryRepository.Open(tx, binary.Hash)
+			if err != nil {
+				return err
+			}
+			defer stored.Close()
+			content = stored
+		case domain.FactBinaryTierWarm:
+			stored, err := txSelf.factBinaryRepository.Open(tx, binary.Hash)
+			if err != nil {
+				return err
+			}
+			defer stored.Close()
+			if content, err = gzip.NewReader(stored); err != nil {
+				return errors.WithMessage(err, "Could not decompress binary")
+			}
+		case domain.FactBinaryTierCold:
+			stored, err := os.Open(coldPath)
+			if err != nil {
+				return err
+			}
+			defer stored.Close()
+			if content, err = gzip.NewReader(stored); err != nil {
+				return errors.WithMessage(err, "Could not decompress binary")
+			}
+		}
+
+		if to == domain.FactBinaryTierCold {
+			// The file is written before the database is updated
+			// so that the binary is never lost. If the transaction
+			// fails it is left for `PruneCold()`.
+			if err := writeColdFile(coldPath, content); err != nil {
+				return errors.WithMessagef(err, "Could not write cold binary with hash %q", binary.Hash)
+			}
+		}
+
+		moved, err = txSelf.factBinaryRepository.Move(tx, binary.Hash, binary.Tier, to, func(w io.Writer) error {
+			if to == domain.FactBinaryTierWarm {
+				gz := gzip.NewWriter(w)
+				if _, err := io.Copy(gz, content); err != nil {
+					return err
+				}
+				return gz.Close()
+			}
+			_, err := io.Copy(w, content)
+			return err
+		})
+		return err
+	}); err != nil {
+		return false, errors.WithMessagef(err, "Could not move binary with hash %q from %s to %s", binary.Hash, binary.Tier, to)
+	}
+
+	if moved && binary.Tier == domain.FactBinaryTierCold {
+		if err := os.Remove(coldPath); err != nil {
+			self.logger.Err(err).Str("hash", binary.Hash).Msg("Could not remove cold binary after moving it, leaving it for pruning")
+		}
+	}
+
+	return moved, nil
+}
+
+func writeColdFile(path string, content io.Reader) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".cicero-fact-binary-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	gz := gzip.NewWriter(file)
+	if _, err := io.Copy(gz, content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (self factBinaryService) PruneCold(before time.Time) (int, error) {
+	if self.coldDir == "" {
+		return 0, nil
+	}
+
+	hashes, err := self.factBinaryRepository.GetColdHashes()
+	if err != nil {
+		return 0, errors.WithMessage(err, "Could not select cold binaries")
+	}
+	cold := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		cold[filepath.Base(self.coldPath(hash))] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(self.coldDir)
+	if err != nil {
+		return 0, errors.WithMessage(err, "Could not list cold storage directory")
+	}
+
+	pruned := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".gz") {
+			continue
+		}
+		if _, ok := cold[entry.Name()]; ok {
+			continue
+		}
+
+		// The binary may just be moving to the cold tier.
+		if info, err := entry.Info(); err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(self.coldDir, entry.Name())); err != nil {
+			return pruned, errors.WithMessagef(err, "Could not remove pruned cold binary %q", entry.Name())
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
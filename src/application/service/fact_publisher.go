@@ -0,0 +1,118 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+type FactPublisherService interface {
+	WithQuerier(config.PgxIface) FactPublisherService
+
+	GetAll() ([]domain.FactPublisher, error)
+	GetByName(string) (*domain.FactPublisher, error)
+	// Replaces a publisher with the same name.
+	// Returns an invalidErr if the public key cannot be parsed.
+	Save(*domain.FactPublisher) (invalidErr error, err error)
+	Delete(string) error
+	// Verifies the fact's signature and sets who signed it.
+	// Returns an invalidErr if the signature is invalid
+	// or not made by the key of a known publisher.
+	Verify(*domain.Fact) (invalidErr error, err error)
+}
+
+type factPublisherService struct {
+	logger                  zerolog.Logger
+	factPublisherRepository repository.FactPublisherRepository
+}
+
+func NewFactPublisherService(db config.PgxIface, logger *zerolog.Logger) FactPublisherService {
+	return &factPublisherService{
+		logger:                  logger.With().Str("component", "FactPublisherService").Logger(),
+		factPublisherRepository: persistence.NewFactPublisherRepository(db),
+	}
+}
+
+func (self factPublisherService) WithQuerier(querier config.PgxIface) FactPublisherService {
+	return &factPublisherService{
+		logger:                  self.logger,
+		factPublisherRepository: self.factPublisherRepository.WithQuerier(querier),
+	}
+}
+
+func (self factPublisherService) GetAll() (publishers []domain.FactPublisher, err error) {
+	self.logger.Trace().Msg("Getting all fact publishers")
+	publishers, err = self.factPublisherRepository.GetAll()
+	err = errors.WithMessage(err, "Could not select fact publishers")
+	return
+}
+
+func (self factPublisherService) GetByName(name string) (publisher *domain.FactPublisher, err error) {
+	self.logger.Trace().Str("name", name).Msg("Getting fact publisher by name")
+	publisher, err = self.factPublisherRepository.GetByName(name)
+	err = errors.WithMessagef(err, "Could not select fact publisher %q", name)
+	return
+}
+
+func (self factPublisherService) Save(publisher *domain.FactPublisher) (error, error) {
+	key, err := util.ParseMinisignPublicKey(publisher.PublicKey)
+	if err != nil {
+		return err, nil
+	}
+	publisher.KeyId = util.MinisignKeyIdString(key.KeyId)
+
+	self.logger.Trace().Str("name", publisher.Name).Str("key-id", publisher.KeyId).Msg("Saving fact publisher")
+	if err := self.factPublisherRepository.Save(publisher); err != nil {
+		return nil, errors.WithMessagef(err, "Could not insert fact publisher %q", publisher.Name)
+	}
+	return nil, nil
+}
+
+func (self factPublisherService) Delete(name string) error {
+	self.logger.Trace().Str("name", name).Msg("Deleting fact publisher")
+	return errors.WithMessagef(self.factPublisherRepository.Delete(name), "Could not delete fact publisher %q", name)
+}
+
+func (self factPublisherService) Verify(fact *domain.Fact) (error, error) {
+	fact.SignedBy = nil
+	if fact.Signature == nil {
+		return errors.New("Fact has no signature"), nil
+	}
+
+	sig, err := util.ParseMinisignSignature(*fact.Signature)
+	if err != nil {
+		return err, nil
+	}
+
+	keyId := util.MinisignKeyIdString(sig.KeyId)
+	publisher, err := self.factPublisherRepository.GetByKeyId(keyId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not select fact publisher by key ID %s", keyId)
+	}
+	if publisher == nil {
+		return errors.Errorf("No fact publisher has the key %s", keyId), nil
+	}
+
+	key, err := util.ParseMinisignPublicKey(publisher.PublicKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Invalid public key of fact publisher %q", publisher.Name)
+	}
+
+	value, err := fact.SignedValue()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not marshal fact value")
+	}
+
+	if err := key.Verify(value, *sig); err != nil {
+		return errors.WithMessagef(err, "Fact is not signed by %q", publisher.Name), nil
+	}
+
+	self.logger.Trace().Str("publisher", publisher.Name).Msg("Verified signature of fact")
+	fact.SignedBy = &publisher.Name
+	return nil, nil
+}
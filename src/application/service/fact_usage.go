@@ -0,0 +1,70 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type FactUsageService interface {
+	WithQuerier(config.PgxIface) FactUsageService
+
+	// Counts a read of each of the facts by the identity,
+	// empty if the reader was not authenticated.
+	RecordReads(identity string, factIds []uuid.UUID) error
+	GetById(uuid.UUID) (domain.FactUsage, error)
+	// Returns the usage of the top-level fields of facts created since the given time.
+	GetFields(since time.Time) ([]domain.FactFieldUsage, error)
+}
+
+type factUsageService struct {
+	logger              zerolog.Logger
+	factUsageRepository repository.FactUsageRepository
+}
+
+func NewFactUsageService(db config.PgxIface, logger *zerolog.Logger) FactUsageService {
+	return &factUsageService{
+		logger:              logger.With().Str("component", "FactUsageService").Logger(),
+		factUsageRepository: persistence.NewFactUsageRepository(db),
+	}
+}
+
+func (self factUsageService) WithQuerier(querier config.PgxIface) FactUsageService {
+	return &factUsageService{
+		logger:              self.logger,
+		factUsageRepository: self.factUsageRepository.WithQuerier(querier),
+	}
+}
+
+func (self factUsageService) RecordReads(identity string, factIds []uuid.UUID) error {
+	if len(factIds) == 0 {
+		return nil
+	}
+
+	self.logger.Trace().Str("identity", identity).Int("facts", len(factIds)).Msg("Recording reads of Facts")
+	if err := self.factUsageRepository.SaveReads(identity, factIds); err != nil {
+		return errors.WithMessagef(err, "Could not record reads of %d Facts by %q", len(factIds), identity)
+	}
+	return nil
+}
+
+func (self factUsageService) GetById(id uuid.UUID) (usage domain.FactUsage, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting usage of Fact")
+	usage, err = self.factUsageRepository.GetById(id)
+	err = errors.WithMessagef(err, "Could not select usage of Fact %q", id)
+	return
+}
+
+func (self factUsageService) GetFields(since time.Time) (fields []domain.FactFieldUsage, err error) {
+	self.logger.Trace().Time("since", since).Msg("Getting usage of Fact fields")
+	fields, err = self.factUsageRepository.GetFields(since)
+	err = errors.WithMessage(err, "Could not select usage of Fact fields")
+	return
+}
@@ -25,6 +25,8 @@ type InvocationService interface {
 	GetByInputFactIds([]*uuid.UUID, bool, *bool, *repository.Page) ([]domain.Invocation, error)
 	GetInputFactIdsById(uuid.UUID) (map[string]uuid.UUID, error)
 	GetOutputById(uuid.UUID) (*domain.OutputDefinition, error)
+	// Like `GetOutputById()` but decoded into typed fields.
+	GetRunOutputById(uuid.UUID) (*domain.RunOutput, error)
 	Save(*domain.Invocation, map[string]domain.Fact) error
 	End(uuid.UUID) error
 	Retry(uuid.UUID) (*domain.Invocation, InvokeRunFunc, error)
@@ -149,6 +151,21 @@ func (self invocationService) GetOutputById(id uuid.UUID) (*domain.OutputDefinit
 	}
 }
 
+func (self invocationService) GetRunOutputById(id uuid.UUID) (*domain.RunOutput, error) {
+	self.logger.Trace().Str("id", id.String()).Msg("Evaluating typed output for ID")
+	if action, err := (*self.actionService).GetByInvocationId(id); err != nil {
+		return nil, err
+	} else if inputFactIds, err := self.GetInputFactIdsById(id); err != nil {
+		return nil, err
+	} else if inputs, err := (*self.factService).GetInvocationInputFacts(inputFactIds); err != nil {
+		return nil, err
+	} else if output, err := action.InOut.RunOutput(inputs); err != nil {
+		return nil, errors.WithMessagef(err, "Could not evaluate output of Invocation %q", id)
+	} else {
+		return &output, nil
+	}
+}
+
 func (self invocationService) Retry(id uuid.UUID) (*domain.Invocation, InvokeRunFunc, error) {
 	self.logger.Trace().Str("id", id.String()).Msg("Retrying")
 
@@ -167,6 +184,14 @@ func (self invocationService) Retry(id uuid.UUID) (*domain.Invocation, InvokeRun
 		return nil, nil, err
 	}
 
+	if original, err := self.GetById(id); err != nil {
+		return nil, nil, err
+	} else if original != nil {
+		for name, value := range original.InputDefaults {
+			inputs[name] = domain.Fact{Value: value}
+		}
+	}
+
 	invocation := &domain.Invocation{ActionId: action.ID}
 	if err := self.Save(invocation, inputs); err != nil {
 		return nil, nil, err
@@ -175,9 +200,23 @@ func (self invocationService) Retry(id uuid.UUID) (*domain.Invocation, InvokeRun
 	return invocation, (*self.actionService).NewInvokeRunFunc(action, invocation, inputs), nil
 }
 
+// Inputs that were satisfied by their default are recorded as `Invocation.InputDefaults`.
 func (self invocationService) Save(invocation *domain.Invocation, inputs map[string]domain.Fact) error {
 	self.logger.Trace().Msg("Saving new Invocation")
-	if err := self.invocationRepository.Save(invocation, inputs); err != nil {
+
+	facts := map[string]domain.Fact{}
+	for name, fact := range inputs {
+		if !fact.IsInputDefault() {
+			facts[name] = fact
+			continue
+		}
+		if invocation.InputDefaults == nil {
+			invocation.InputDefaults = map[string]interface{}{}
+		}
+		invocation.InputDefaults[name] = fact.Value
+	}
+
+	if err := self.invocationRepository.Save(invocation, facts); err != nil {
 		return errors.WithMessagef(err, "Could not insert Invocation")
 	}
 	self.logger.Trace().Str("id", invocation.Id.String()).Msg("Created Invocation")
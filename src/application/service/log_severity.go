@@ -0,0 +1,111 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// How severe a log line is according to the level it was logged at,
+// as parsed from JSON, logfmt, and glog lines.
+type LogSeverity int
+
+const (
+	// The line has no level or one in a format that is not recognized.
+	LogSeverityUnknown LogSeverity = iota
+	LogSeverityDebug
+	LogSeverityInfo
+	LogSeverityWarning
+	LogSeverityError
+)
+
+var logSeverityNames = []string{"", "debug", "info", "warning", "error"}
+
+func ParseLogSeverityName(name string) (LogSeverity, error) {
+	for severity, severityName := range logSeverityNames {
+		if name == severityName {
+			return LogSeverity(severity), nil
+		}
+	}
+	return LogSeverityUnknown, errors.Errorf("Unknown severity %q, must be one of: %s", name, strings.Join(logSeverityNames[1:], ", "))
+}
+
+func (self LogSeverity) String() string {
+	return logSeverityNames[self]
+}
+
+func (self LogSeverity) MarshalText() ([]byte, error) {
+	return []byte(self.String()), nil
+}
+
+func (self *LogSeverity) UnmarshalText(text []byte) (err error) {
+	*self, err = ParseLogSeverityName(string(text))
+	return
+}
+
+// Names of levels that common loggers use for each severity.
+var logSeverityLevels = map[LogSeverity]string{
+	LogSeverityDebug:   `trace|trce|debug|dbug|dbg`,
+	LogSeverityInfo:    `info|inf|notice`,
+	LogSeverityWarning: `warning|warn|wrn`,
+	LogSeverityError:   `error|eror|err|fatal|critical|crit|panic|alert|emergency|emerg`,
+}
+
+// Letters that glog lines start with for each severity.
+var logSeverityGlogLetters = map[LogSeverity]string{
+	LogSeverityInfo:    `I`,
+	LogSeverityWarning: `W`,
+	LogSeverityError:   `E|F`,
+}
+
+// Returns a regular expression that matches lines logged at the severity.
+// Loki uses the same syntax so that it can filter lines the same way.
+func (self LogSeverity) pattern() string {
+	levels := logSeverityLevels[self]
+	patterns := []string{
+		// JSON like `{"level": "error", …}`
+		`"(?i:level|lvl|severity)"\s*:\s*"(?i:` + levels + `)"`,
+		// logfmt like `level=error …`
+		`(?:^|\s)(?i:level|lvl|severity)="?(?i:` + levels + `)\b`,
+	}
+	if letters, ok := logSeverityGlogLetters[self]; ok {
+		// glog like `E1020 12:34:56.789012 …`
+		patterns = append(patterns, `^(?:`+letters+`)\d{4} \d{2}:\d{2}:\d{2}`)
+	}
+	return strings.Join(patterns, "|")
+}
+
+// Returns a regular expression that matches lines logged
+// at the severity or a higher one.
+func (self LogSeverity) atLeastPattern() string {
+	patterns := []string{}
+	for severity := self; severity <= LogSeverityError; severity++ {
+		patterns = append(patterns, severity.pattern())
+	}
+	return strings.Join(patterns, "|")
+}
+
+// Returns a LogQL line filter that only keeps lines logged
+// at the severity or a higher one.
+func (self LogSeverity) lokiFilter() string {
+	return " |~ `" + self.atLeastPattern() + "`"
+}
+
+var logSeverityRegexps = func() map[LogSeverity]*regexp.Regexp {
+	regexps := map[LogSeverity]*regexp.Regexp{}
+	for severity := range logSeverityLevels {
+		regexps[severity] = regexp.MustCompile(severity.pattern())
+	}
+	return regexps
+}()
+
+// Returns the severity the line was logged at.
+func ParseLogSeverity(text string) LogSeverity {
+	for severity := LogSeverityError; severity > LogSeverityUnknown; severity-- {
+		if logSeverityRegexps[severity].MatchString(text) {
+			return severity
+		}
+	}
+	return LogSeverityUnknown
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -16,19 +18,166 @@ import (
 	"github.com/pkg/errors"
 	prometheus "github.com/prometheus/client_golang/api"
 	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
 )
 
 type LokiService interface {
 	QueryRangeLog(string, time.Time, *time.Time) (LokiLog, error)
+	QueryRangeLogPage(string, time.Time, *time.Time, LokiPage) (LokiLogPage, error)
+	// Like `QueryRangeLogPage()` but runs several queries in parallel
+	// and merges their lines by time.
+	QueryRangeLogPageMerged([]string, time.Time, *time.Time, LokiPage) (LokiLogPage, error)
 	QueryRange(string, time.Time, *time.Time, func(loghttp.Stream) (bool, error)) error
 }
 
+// TODO: figure out the correct value for our infra, 5000 is the default configuration in loki
+const LokiMaxLimit = 5000
+
+// How many queries of `LokiService.QueryRangeLogPageMerged()` may run at once
+// so that jobs with many allocations do not flood Loki.
+const lokiMaxParallelQueries = 8
+
+type LokiDirection string
+
+const (
+	LokiForward  LokiDirection = "FORWARD"
+	LokiBackward LokiDirection = "BACKWARD"
+)
+
+// Position after the last line of a page.
+// Several lines may share the same timestamp so Index
+// counts how many lines at Time were already returned.
+type LokiCursor struct {
+	Time  time.Time
+	Index int
+}
+
+func (self LokiCursor) String() string {
+	return strconv.FormatInt(self.Time.UnixNano(), 10) + "-" + strconv.Itoa(self.Index)
+}
+
+func (self LokiCursor) MarshalText() ([]byte, error) {
+	return []byte(self.String()), nil
+}
+
+func (self *LokiCursor) UnmarshalText(text []byte) error {
+	timeStr, indexStr, ok := strings.Cut(string(text), "-")
+	if !ok {
+		return errors.Errorf("Invalid cursor %q", text)
+	}
+	nanos, err := strconv.ParseInt(timeStr, 10, 64)
+	if err != nil {
+		return errors.WithMessagef(err, "Invalid time in cursor %q", text)
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 {
+		return errors.Errorf("Invalid index in cursor %q", text)
+	}
+	self.Time = time.Unix(0, nanos).UTC()
+	self.Index = index
+	return nil
+}
+
+// Identifies a line so that permalinks can point to it.
+// The time locates the line and the hash of its text
+// tells it apart from other lines at the same time.
+type LokiAnchor struct {
+	Time time.Time
+	Hash string
+}
+
+func NewLokiAnchor(line LokiLine) LokiAnchor {
+	sum := sha256.Sum256([]byte(line.Text))
+	return LokiAnchor{Time: line.Time, Hash: hex.EncodeToString(sum[:4])}
+}
+
+func (self LokiAnchor) String() string {
+	return strconv.FormatInt(self.Time.UnixNano(), 10) + "-" + self.Hash
+}
+
+func (self LokiAnchor) MarshalText() ([]byte, error) {
+	return []byte(self.String()), nil
+}
+
+func (self *LokiAnchor) UnmarshalText(text []byte) error {
+	timeStr, hash, ok := strings.Cut(string(text), "-")
+	if !ok || hash == "" {
+		return errors.Errorf("Invalid anchor %q", text)
+	}
+	nanos, err := strconv.ParseInt(timeStr, 10, 64)
+	if err != nil {
+		return errors.WithMessagef(err, "Invalid time in anchor %q", text)
+	}
+	self.Time = time.Unix(0, nanos).UTC()
+	self.Hash = hash
+	return nil
+}
+
+// Which lines to fetch. Backward pages start at the end of the log
+// so that older lines can be loaded as needed.
+type LokiPage struct {
+	Direction LokiDirection
+	// Continues after this position if given.
+	Cursor *LokiCursor
+	// Starts at the anchored line's time if no cursor is given
+	// and reports where the line is in the page.
+	Anchor *LokiAnchor
+	Limit  int
+	// Collapses consecutive identical lines, see `LokiLog.Collapse()`.
+	Collapse bool
+	// Only returns lines logged at this severity or a higher one if given.
+	Severity LogSeverity
+}
+
+// Returns the position to continue from, if any.
+func (self LokiPage) cursor() *LokiCursor {
+	if self.Cursor == nil && self.Anchor != nil {
+		return &LokiCursor{Time: self.Anchor.Time}
+	}
+	return self.Cursor
+}
+
+type LokiLogPage struct {
+	// Sorted by time regardless of direction.
+	Log LokiLog `json:"log"`
+	// Nil if there are no more lines in this direction.
+	Next *LokiCursor `json:"next"`
+	// Index of the anchored line in the log.
+	// Nil if no anchor was given or the line is not in this page.
+	Anchored *int `json:"anchored,omitempty"`
+}
+
+// Sets the anchor of each line and finds the anchored one, if any.
+func (self *LokiLogPage) anchor(anchor *LokiAnchor) {
+	for i := range self.Log {
+		lineAnchor := NewLokiAnchor(self.Log[i])
+		self.Log[i].Anchor = &lineAnchor
+
+		if anchor != nil && self.Anchored == nil &&
+			lineAnchor.Time.Equal(anchor.Time) && lineAnchor.Hash == anchor.Hash {
+			anchored := i
+			self.Anchored = &anchored
+		}
+	}
+}
+
 type LokiLog []LokiLine
 
 type LokiLine struct {
 	Time   time.Time
 	Text   string
 	Labels map[string]string
+	// Of the allocation and task that logged the line, see `LokiLine.setTask()`.
+	AllocId   string `json:",omitempty"`
+	TaskGroup string `json:",omitempty"`
+	Task      string `json:",omitempty"`
+	// How often the line was repeated right after itself
+	// if the log was collapsed.
+	Repeated int `json:",omitempty"`
+	// As parsed from the text, see `ParseLogSeverity()`.
+	Severity LogSeverity `json:",omitempty"`
+	// Only set on pages, see `NewLokiAnchor()`.
+	Anchor *LokiAnchor `json:",omitempty"`
 }
 
 type lokiService struct {
@@ -64,61 +213,157 @@ func (self lokiService) QueryRangeLog(query string, start time.Time, end *time.T
 	return log, nil
 }
 
-func (self lokiService) QueryRange(query string, start time.Time, end *time.Time, callback func(loghttp.Stream) (bool, error)) error {
-	const timeout time.Duration = 2 * time.Second
-	// TODO: figure out the correct value for our infra, 5000 is the default configuration in loki
-	const limit int64 = 5000
+func (self lokiService) QueryRangeLogPage(query string, start time.Time, end *time.Time, page LokiPage) (LokiLogPage, error) {
+	return self.QueryRangeLogPageMerged([]string{query}, start, end, page)
+}
 
-	if end == nil {
-		now := time.Now().UTC()
-		end = &now
+func (self lokiService) QueryRangeLogPageMerged(queries []string, start time.Time, end *time.Time, page LokiPage) (LokiLogPage, error) {
+	result := LokiLogPage{Log: LokiLog{}}
+
+	if page.Limit <= 0 || page.Limit > LokiMaxLimit {
+		page.Limit = LokiMaxLimit
+	}
+	if page.Direction == "" {
+		page.Direction = LokiForward
 	}
 
-	endLater := end.Add(1 * time.Minute)
+	endLater := lokiEnd(end)
 	end = &endLater
 
-Page:
-	for {
-		self.logger.Trace().Str("query", query).Stringer("start", start).Stringer("end", end).Msg("Fetching from query_range endpoint")
+	cursor := page.cursor()
+
+	skip := 0
+	if cursor != nil {
+		// Refetch the lines at the cursor's time as the limit may have cut them off.
+		skip = cursor.Index
+		switch page.Direction {
+		case LokiForward:
+			start = cursor.Time
+		case LokiBackward:
+			cursorEnd := cursor.Time.Add(time.Nanosecond)
+			end = &cursorEnd
+		}
+	}
 
-		req, err := http.NewRequest(
-			"GET",
-			self.prometheus.URL("/loki/api/v1/query_range", nil).String(),
-			http.NoBody,
-		)
-		if err != nil {
-			return err
+	if page.Severity != LogSeverityUnknown {
+		filtered := make([]string, len(queries))
+		for i, query := range queries {
+			filtered[i] = query + page.Severity.lokiFilter()
 		}
+		queries = filtered
+	}
 
-		q := req.URL.Query()
-		q.Set("query", query)
-		q.Set("limit", strconv.FormatInt(limit, 10))
-		q.Set("start", strconv.FormatInt(start.UnixNano(), 10))
-		q.Set("end", strconv.FormatInt(end.UnixNano(), 10))
-		q.Set("direction", "FORWARD")
-		req.URL.RawQuery = q.Encode()
+	results := make([]loghttp.Streams, len(queries))
+	semaphore := make(chan struct{}, lokiMaxParallelQueries)
+	group := errgroup.Group{}
+	for i, query := range queries {
+		i, query := i, query
+		group.Go(func() (err error) {
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i], err = self.queryRange(query, start, *end, page.Limit+skip, page.Direction)
+			return
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return result, err
+	}
 
-		ctxTimeout, ctxTimeoutCancel := context.WithTimeout(context.Background(), timeout)
-		done, body, err := self.prometheus.Do(ctxTimeout, req)
-		ctxTimeoutCancel()
-		if err != nil {
-			return errors.WithMessage(err, "Failed to talk with loki")
+	type entry struct {
+		loghttp.Entry
+		labels loghttp.LabelSet
+	}
+
+	// There may be more lines if any query hit the limit.
+	more := false
+	entries := []entry{}
+	for _, streams := range results {
+		numEntries := 0
+		for _, stream := range streams {
+			for _, e := range stream.Entries {
+				entries = append(entries, entry{e, stream.Labels})
+			}
+			numEntries += len(stream.Entries)
+		}
+		if numEntries >= page.Limit+skip {
+			more = true
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp) == (page.Direction == LokiForward)
 		}
+		// Make the order of lines from different streams stable across pages.
+		return a.labels.String() < b.labels.String()
+	})
+
+	if len(entries) > page.Limit+skip {
+		more = true
+	}
 
-		if done.StatusCode/100 != 2 {
-			return fmt.Errorf("Error response %d from Loki: %s", done.StatusCode, string(body))
+	if cursor != nil {
+		for skip > 0 && len(entries) > 0 && entries[0].Timestamp.Equal(cursor.Time) {
+			entries = entries[1:]
+			skip--
 		}
+	}
+	if len(entries) > page.Limit {
+		entries = entries[:page.Limit]
+	}
 
-		response := loghttp.QueryResponse{}
+	if more && len(entries) > 0 {
+		last := entries[len(entries)-1].Timestamp
+		next := LokiCursor{Time: last}
+		if cursor != nil && cursor.Time.Equal(last) {
+			next.Index = cursor.Index
+		}
+		for _, e := range entries {
+			if e.Timestamp.Equal(last) {
+				next.Index++
+			}
+		}
+		result.Next = &next
+	}
 
-		err = json.Unmarshal(body, &response)
-		if err != nil {
-			return err
+	if page.Direction == LokiBackward {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
 		}
+	}
+
+	for _, e := range entries {
+		result.Log.appendEntry(e.labels.Map(), e.Entry)
+	}
+
+	if page.Collapse {
+		result.Log.Collapse()
+	}
+
+	result.anchor(page.Anchor)
+
+	return result, nil
+}
+
+// Fetches a bit after the end as log lines may arrive late.
+func lokiEnd(end *time.Time) time.Time {
+	if end == nil {
+		return time.Now().UTC().Add(1 * time.Minute)
+	}
+	return end.Add(1 * time.Minute)
+}
+
+func (self lokiService) QueryRange(query string, start time.Time, end *time.Time, callback func(loghttp.Stream) (bool, error)) error {
+	const limit int64 = LokiMaxLimit
+
+	endLater := lokiEnd(end)
 
-		streams, ok := response.Data.Result.(loghttp.Streams)
-		if !ok {
-			return fmt.Errorf("Unexpected loki result type: %s", response.Data.Result.Type())
+Page:
+	for {
+		streams, err := self.queryRange(query, start, endLater, int(limit), LokiForward)
+		if err != nil {
+			return err
 		}
 
 		if len(streams) == 0 {
@@ -148,22 +393,77 @@ Page:
 	return nil
 }
 
+func (self lokiService) queryRange(query string, start, end time.Time, limit int, direction LokiDirection) (loghttp.Streams, error) {
+	const timeout time.Duration = 2 * time.Second
+
+	self.logger.Trace().Str("query", query).Stringer("start", start).Stringer("end", end).Int("limit", limit).Str("direction", string(direction)).Msg("Fetching from query_range endpoint")
+
+	req, err := http.NewRequest(
+		"GET",
+		self.prometheus.URL("/loki/api/v1/query_range", nil).String(),
+		http.NoBody,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Set("query", query)
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	q.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	q.Set("direction", string(direction))
+	req.URL.RawQuery = q.Encode()
+
+	ctxTimeout, ctxTimeoutCancel := context.WithTimeout(context.Background(), timeout)
+	done, body, err := self.prometheus.Do(ctxTimeout, req)
+	ctxTimeoutCancel()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to talk with loki")
+	}
+
+	if done.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("Error response %d from Loki: %s", done.StatusCode, string(body))
+	}
+
+	response := loghttp.QueryResponse{}
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	streams, ok := response.Data.Result.(loghttp.Streams)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected loki result type: %s", response.Data.Result.Type())
+	}
+
+	return streams, nil
+}
+
 func (self *LokiLog) FromStream(stream loghttp.Stream) {
+	labels := stream.Labels.Map()
 	for _, entry := range stream.Entries {
-		line := LokiLine{
-			Time:   entry.Timestamp,
-			Text:   entry.Line,
-			Labels: stream.Labels.Map(),
-		}
-		lines := strings.Split(entry.Line, "\r")
-		for _, l := range lines {
-			if sane, err := ansi.Strip([]byte(l)); err == nil {
-				line.Text = string(sane)
-			} else {
-				line.Text = l
-			}
-			*self = append(*self, line)
+		self.appendEntry(labels, entry)
+	}
+}
+
+func (self *LokiLog) appendEntry(labels map[string]string, entry loghttp.Entry) {
+	line := LokiLine{
+		Time:   entry.Timestamp,
+		Text:   entry.Line,
+		Labels: labels,
+	}
+	line.setTask()
+	lines := strings.Split(entry.Line, "\r")
+	for _, l := range lines {
+		if sane, err := ansi.Strip([]byte(l)); err == nil {
+			line.Text = string(sane)
+		} else {
+			line.Text = l
 		}
+		line.Severity = ParseLogSeverity(line.Text)
+		*self = append(*self, line)
 	}
 }
 
@@ -186,6 +486,132 @@ func (self *LokiLog) Deduplicate() {
 	*self = deduped
 }
 
+// Replaces runs of consecutive lines with the same text and labels
+// by their first line, counting the others in `LokiLine.Repeated`.
+// Assumes the log is already sorted.
+func (self *LokiLog) Collapse() {
+	collapsed := make(LokiLog, 0, len(*self))
+	for _, l := range *self {
+		if last := len(collapsed) - 1; last >= 0 &&
+			l.Text == collapsed[last].Text &&
+			reflect.DeepEqual(l.Labels, collapsed[last].Labels) {
+			collapsed[last].Repeated += 1 + l.Repeated
+			continue
+		}
+		collapsed = append(collapsed, l)
+	}
+	*self = collapsed
+}
+
+// Returns the lines with the given labels one page at a time
+// like `LokiService.QueryRangeLogPage()` does for logs that are still in Loki.
+// Assumes the log is already sorted.
+func (self LokiLog) Page(labels map[string]string, start time.Time, end *time.Time, page LokiPage) LokiLogPage {
+	result := LokiLogPage{Log: LokiLog{}}
+
+	if page.Limit <= 0 || page.Limit > LokiMaxLimit {
+		page.Limit = LokiMaxLimit
+	}
+	backward := page.Direction == LokiBackward
+
+	endLater := lokiEnd(end)
+
+	lines := LokiLog{}
+Line:
+	for _, line := range self {
+		if line.Time.Before(start) || line.Time.After(endLater) {
+			continue
+		}
+		for k, v := range labels {
+			if line.Labels[k] != v {
+				continue Line
+			}
+		}
+		if page.Severity != LogSeverityUnknown && ParseLogSeverity(line.Text) < page.Severity {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if backward {
+		lines.reverse()
+	}
+
+	cursor := page.cursor()
+
+	if cursor != nil {
+		skip := cursor.Index
+		for len(lines) > 0 {
+			t := lines[0].Time
+			if (backward && t.After(cursor.Time)) || (!backward && t.Before(cursor.Time)) {
+				lines = lines[1:]
+			} else if t.Equal(cursor.Time) && skip > 0 {
+				lines = lines[1:]
+				skip--
+			} else {
+				break
+			}
+		}
+	}
+
+	if len(lines) > page.Limit {
+		lines = lines[:page.Limit]
+
+		last := lines[len(lines)-1].Time
+		next := LokiCursor{Time: last}
+		if cursor != nil && cursor.Time.Equal(last) {
+			next.Index = cursor.Index
+		}
+		for _, line := range lines {
+			if line.Time.Equal(last) {
+				next.Index++
+			}
+		}
+		result.Next = &next
+	}
+
+	if backward {
+		lines.reverse()
+	}
+
+	result.Log = append(result.Log, lines...)
+
+	// Lines archived before they had these fields.
+	for i := range result.Log {
+		result.Log[i].setTask()
+		if result.Log[i].Severity == LogSeverityUnknown {
+			result.Log[i].Severity = ParseLogSeverity(result.Log[i].Text)
+		}
+	}
+
+	if page.Collapse {
+		result.Log.Collapse()
+	}
+
+	result.anchor(page.Anchor)
+
+	return result
+}
+
+func (self LokiLog) reverse() {
+	for i, j := 0, len(self)-1; i < j; i, j = i+1, j-1 {
+		self[i], self[j] = self[j], self[i]
+	}
+}
+
+// Sets the allocation and task from the labels that promtail adds.
+func (self *LokiLine) setTask() {
+	if self.AllocId == "" {
+		self.AllocId = self.Labels["nomad_alloc_id"]
+	}
+	if self.TaskGroup == "" {
+		self.TaskGroup = self.Labels["nomad_task_group"]
+	}
+	if self.Task == "" {
+		self.Task = self.Labels["nomad_task_name"]
+	}
+}
+
 func (self LokiLine) Equal(o LokiLine) bool {
 	return self.Time.Equal(o.Time) &&
 		self.Text == o.Text &&
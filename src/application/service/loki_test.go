@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	prometheus "github.com/prometheus/client_golang/api"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLokiLogPageAnchor(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(100, 0).UTC()
+	log := LokiLog{
+		{Time: start, Text: "a"},
+		{Time: start.Add(time.Second), Text: "b"},
+		{Time: start.Add(time.Second), Text: "c"},
+		{Time: start.Add(2 * time.Second), Text: "d"},
+	}
+
+	// given
+	anchor := LokiAnchor{}
+	assert.Nil(t, anchor.UnmarshalText([]byte(NewLokiAnchor(log[2]).String())))
+
+	// when
+	page := log.Page(nil, start, nil, LokiPage{Anchor: &anchor, Limit: 2})
+
+	// then
+	assert.Equal(t, []string{"b", "c"}, []string{page.Log[0].Text, page.Log[1].Text}, "page starts at the anchored line's time")
+	if assert.NotNil(t, page.Anchored) {
+		assert.Equal(t, 1, *page.Anchored)
+	}
+	assert.Equal(t, &anchor, page.Log[1].Anchor)
+	assert.NotNil(t, page.Next)
+
+	// when
+	page = log.Page(nil, start, nil, LokiPage{Anchor: &anchor, Direction: LokiBackward})
+
+	// then
+	assert.Len(t, page.Log, 3, "page ends with the lines at the anchored line's time")
+	if assert.NotNil(t, page.Anchored) {
+		assert.Equal(t, 2, *page.Anchored)
+	}
+
+	// given
+	anchor.Hash = "00000000"
+
+	// when
+	page = log.Page(nil, start, nil, LokiPage{Anchor: &anchor})
+
+	// then
+	assert.Nil(t, page.Anchored, "no line has the anchor")
+	assert.Len(t, page.Log, 3)
+}
+
+func TestQueryRangeLogPageMerged(t *testing.T) {
+	t.Parallel()
+
+	// given
+	streams := map[string][][2]string{
+		"a": {{"1000000000", "a1"}, {"3000000000", "a3"}},
+		"b": {{"2000000000", "b2"}, {"4000000000", "b4"}},
+	}
+	loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var task string
+		if _, err := fmt.Sscanf(req.URL.Query().Get("query"), `{nomad_task_name=%q}`, &task); err != nil {
+			t.Error(err)
+		}
+		start, err := strconv.ParseInt(req.URL.Query().Get("start"), 10, 64)
+		if err != nil {
+			t.Error(err)
+		}
+
+		values := [][2]string{}
+		for _, value := range streams[task] {
+			if nanos, _ := strconv.ParseInt(value[0], 10, 64); nanos >= start {
+				values = append(values, value)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "streams",
+				"result": []interface{}{map[string]interface{}{
+					"stream": map[string]string{"nomad_alloc_id": "alloc", "nomad_task_name": task},
+					"values": values,
+				}},
+			},
+		}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer loki.Close()
+
+	client, err := prometheus.NewClient(prometheus.Config{Address: loki.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zerolog.Nop()
+	lokiService := NewLokiService(client, &logger)
+	queries := []string{`{nomad_task_name="a"}`, `{nomad_task_name="b"}`}
+
+	// when
+	page, err := lokiService.QueryRangeLogPageMerged(queries, time.Unix(0, 0), nil, LokiPage{Limit: 3})
+
+	// then
+	assert.NoError(t, err)
+	if assert.Len(t, page.Log, 3) {
+		assert.Equal(t, []string{"a1", "b2", "a3"}, []string{page.Log[0].Text, page.Log[1].Text, page.Log[2].Text}, "lines are merged by time")
+		assert.Equal(t, "alloc", page.Log[1].AllocId)
+		assert.Equal(t, "b", page.Log[1].Task)
+	}
+	assert.NotNil(t, page.Next)
+
+	// when
+	page, err = lokiService.QueryRangeLogPageMerged(queries, time.Unix(0, 0), nil, LokiPage{Limit: 3, Cursor: page.Next})
+
+	// then
+	assert.NoError(t, err)
+	if assert.Len(t, page.Log, 1) {
+		assert.Equal(t, "b4", page.Log[0].Text)
+	}
+	assert.Nil(t, page.Next)
+}
+
+func TestParseLogSeverity(t *testing.T) {
+	t.Parallel()
+
+	for text, severity := range map[string]LogSeverity{
+		`{"time": "2022-10-20T12:00:00Z", "level": "ERROR", "msg": "oops"}`: LogSeverityError,
+		`{"severity":"warning","message":"careful"}`:                        LogSeverityWarning,
+		`ts=2022-10-20T12:00:00Z level=info msg="started"`:                  LogSeverityInfo,
+		`ts=2022-10-20T12:00:00Z lvl=dbug msg="details"`:                    LogSeverityDebug,
+		`level="warn" msg=x`:                             LogSeverityWarning,
+		`E1020 12:00:00.123456    1 main.go:42] oops`:    LogSeverityError,
+		`W1020 12:00:00.123456    1 main.go:42] careful`: LogSeverityWarning,
+		`level=errors are fine`:                          LogSeverityUnknown,
+		`building /nix/store/…-error-pages.drv`:          LogSeverityUnknown,
+	} {
+		assert.Equal(t, severity, ParseLogSeverity(text), text)
+	}
+}
+
+func TestLokiLogPageSeverity(t *testing.T) {
+	t.Parallel()
+
+	// given
+	start := time.Unix(100, 0).UTC()
+	log := LokiLog{
+		{Time: start, Text: "level=info msg=a"},
+		{Time: start.Add(time.Second), Text: "level=warn msg=b"},
+		{Time: start.Add(2 * time.Second), Text: "c"},
+		{Time: start.Add(3 * time.Second), Text: "level=error msg=d"},
+	}
+
+	// when
+	page := log.Page(nil, start, nil, LokiPage{Severity: LogSeverityWarning})
+
+	// then
+	assert.Len(t, page.Log, 2)
+	assert.Equal(t, LogSeverityWarning, page.Log[0].Severity)
+	assert.Equal(t, LogSeverityError, page.Log[1].Severity)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type MaintenanceService interface {
+	// Returns why Runs of the action of the project are held now,
+	// nil if they are not.
+	Hold(project, action string) *domain.RunQueueHold
+	// Returns the configured windows and whether they are open now.
+	GetAll() []domain.MaintenanceWindowState
+	// Fetches the events of the windows' iCalendar feeds.
+	// Windows keep the events they had if their feed cannot be fetched.
+	Refresh(context.Context) error
+}
+
+type maintenanceService struct {
+	logger  zerolog.Logger
+	runtime *config.RuntimeConfig
+	client  *http.Client
+
+	mutex sync.RWMutex
+	// Events of the iCalendar feeds by window name.
+	events map[string][]domain.MaintenanceEvent
+}
+
+func NewMaintenanceService(runtime *config.RuntimeConfig, logger *zerolog.Logger) MaintenanceService {
+	return &maintenanceService{
+		logger:  logger.With().Str("component", "MaintenanceService").Logger(),
+		runtime: runtime,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		events:  map[string][]domain.MaintenanceEvent{},
+	}
+}
+
+func (self *maintenanceService) Hold(project, action string) *domain.RunQueueHold {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	return self.runtime.Get().MaintenanceWindows.Hold(project, action, time.Now(), self.events)
+}
+
+func (self *maintenanceService) GetAll() []domain.MaintenanceWindowState {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	now := time.Now()
+	windows := self.runtime.Get().MaintenanceWindows
+	states := make([]domain.MaintenanceWindowState, len(windows))
+	for i, window := range windows {
+		states[i] = domain.MaintenanceWindowState{
+			Window:    window,
+			OpenUntil: window.OpenUntil(now, self.events[window.Name]),
+			Events:    self.events[window.Name],
+		}
+	}
+	return states
+}
+
+func (self *maintenanceService) Refresh(ctx context.Context) error {
+	windows := self.runtime.Get().MaintenanceWindows
+
+	events := make(map[string][]domain.MaintenanceEvent, len(windows))
+	var err error
+	for _, window := range windows {
+		if window.ICal == "" {
+			continue
+		}
+
+		self.logger.Trace().Str("window", window.Name).Str("url", window.ICal).Msg("Fetching iCalendar feed")
+
+		if windowEvents, fetchErr := self.fetch(ctx, window.ICal); fetchErr != nil {
+			self.logger.Err(fetchErr).Str("window", window.Name).Msg("Could not fetch iCalendar feed")
+			err = errors.WithMessagef(fetchErr, "Could not fetch iCalendar feed of maintenance window %q", window.Name)
+
+			self.mutex.RLock()
+			events[window.Name] = self.events[window.Name]
+			self.mutex.RUnlock()
+		} else {
+			self.logger.Debug().Str("window", window.Name).Int("events", len(windowEvents)).Msg("Fetched iCalendar feed")
+			events[window.Name] = windowEvents
+		}
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.events = events
+
+	return err
+}
+
+func (self *maintenanceService) fetch(ctx context.Context, url string) ([]domain.MaintenanceEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := self.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Unexpected status %s", res.Status)
+	}
+
+	return domain.ParseICal(res.Body)
+}
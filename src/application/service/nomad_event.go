@@ -1,6 +1,8 @@
 package service
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/pkg/errors"
@@ -17,10 +19,17 @@ type NomadEventService interface {
 
 	Save(*domain.NomadEvent) error
 	Update(*domain.NomadEvent) error
-	GetByHandled(bool) ([]domain.NomadEvent, error)
-	GetLastNomadEventIndex() (uint64, error)
+	GetByHandled(handled bool, clusters []string) ([]domain.NomadEvent, error)
+	GetLastNomadEventIndex(clusters []string) (uint64, error)
+	GetLastNomadEventIndexByTopic(topic nomad.Topic, clusters []string) (uint64, error)
+	GetByJobId(uuid.UUID) ([]domain.NomadEvent, error)
 	GetEventAllocationByJobId(uuid.UUID) ([]nomad.Allocation, error)
 	GetLatestEventAllocationByJobId(uuid.UUID) ([]nomad.Allocation, error)
+	GetLatestEventEvaluationByJobId(uuid.UUID) (*nomad.Evaluation, error)
+	GetAfterId(id int64, before time.Time, limit int) ([]domain.NomadEvent, error)
+	GetLastId() (int64, error)
+	GetCheckpoint(handler string) (*int64, error)
+	SaveCheckpoint(handler string, id int64) error
 }
 
 type nomadEventService struct {
@@ -44,10 +53,14 @@ func (n nomadEventService) WithQuerier(querier config.PgxIface) NomadEventServic
 
 func (n nomadEventService) Save(event *domain.NomadEvent) (err error) {
 	n.logger.Trace().Bytes("uid", event.Uid[:]).Uint64("index", event.Index).Msg("Saving nomad event")
-	if err = n.nomadEventRepository.Save(event); err != nil {
+	// The event is still processed with the full payload.
+	redacted := *event
+	redacted.Payload = event.RedactedPayload()
+	if err = n.nomadEventRepository.Save(&redacted); err != nil {
 		err = errors.WithMessagef(err, "Could not save nomad event")
 		return
 	}
+	event.Uid, event.Handled, event.ID, event.CreatedAt = redacted.Uid, redacted.Handled, redacted.ID, redacted.CreatedAt
 	n.logger.Trace().Bytes("uid", event.Uid[:]).Uint64("index", event.Index).Msg("Saved nomad event")
 	return
 }
@@ -62,9 +75,9 @@ func (n nomadEventService) Update(event *domain.NomadEvent) (err error) {
 	return
 }
 
-func (n nomadEventService) GetByHandled(handled bool) (events []domain.NomadEvent, err error) {
-	n.logger.Trace().Bool("handled", handled).Msg("Get nomad events by handled flag")
-	if events, err = n.nomadEventRepository.GetByHandled(handled); err != nil {
+func (n nomadEventService) GetByHandled(handled bool, clusters []string) (events []domain.NomadEvent, err error) {
+	n.logger.Trace().Bool("handled", handled).Strs("clusters", clusters).Msg("Get nomad events by handled flag")
+	if events, err = n.nomadEventRepository.GetByHandled(handled, clusters); err != nil {
 		err = errors.WithMessagef(err, "Could not get nomad events by handled flag %t", handled)
 		return
 	}
@@ -72,9 +85,24 @@ func (n nomadEventService) GetByHandled(handled bool) (events []domain.NomadEven
 	return
 }
 
-func (n nomadEventService) GetLastNomadEventIndex() (uint64, error) {
-	n.logger.Trace().Msg("Get last nomad event index")
-	return n.nomadEventRepository.GetLastNomadEventIndex()
+func (n nomadEventService) GetLastNomadEventIndex(clusters []string) (uint64, error) {
+	n.logger.Trace().Strs("clusters", clusters).Msg("Get last nomad event index")
+	return n.nomadEventRepository.GetLastNomadEventIndex(clusters)
+}
+
+func (n nomadEventService) GetLastNomadEventIndexByTopic(topic nomad.Topic, clusters []string) (uint64, error) {
+	n.logger.Trace().Str("topic", string(topic)).Strs("clusters", clusters).Msg("Get last nomad event index by topic")
+	return n.nomadEventRepository.GetLastNomadEventIndexByTopic(topic, clusters)
+}
+
+func (n nomadEventService) GetByJobId(jobId uuid.UUID) (events []domain.NomadEvent, err error) {
+	n.logger.Trace().Stringer("job-id", jobId).Msg("Get nomad events by job ID")
+	if events, err = n.nomadEventRepository.GetByJobId(jobId); err != nil {
+		err = errors.WithMessagef(err, "Could not get nomad events by job ID %q", jobId)
+		return
+	}
+	n.logger.Trace().Stringer("job-id", jobId).Int("count", len(events)).Msg("Got nomad events by job ID")
+	return
 }
 
 func (n nomadEventService) GetEventAllocationByJobId(jobId uuid.UUID) (results []nomad.Allocation, err error) {
@@ -96,3 +124,49 @@ func (n nomadEventService) GetLatestEventAllocationByJobId(jobId uuid.UUID) (res
 	n.logger.Trace().Stringer("job-id", jobId).Msg("Got latest AllocationUpdated event's Allocation by job ID")
 	return
 }
+
+func (n nomadEventService) GetLatestEventEvaluationByJobId(jobId uuid.UUID) (result *nomad.Evaluation, err error) {
+	n.logger.Trace().Stringer("job-id", jobId).Msg("Get latest Evaluation event's Evaluation by job ID")
+	if result, err = n.nomadEventRepository.GetLatestEventEvaluationByJobId(jobId); err != nil {
+		err = errors.WithMessagef(err, "Could not get latest evaluation by job ID %q", jobId)
+		return
+	}
+	n.logger.Trace().Stringer("job-id", jobId).Msg("Got latest Evaluation event's Evaluation by job ID")
+	return
+}
+
+func (n nomadEventService) GetAfterId(id int64, before time.Time, limit int) (events []domain.NomadEvent, err error) {
+	n.logger.Trace().Int64("id", id).Time("before", before).Int("limit", limit).Msg("Get nomad events after ID")
+	if events, err = n.nomadEventRepository.GetAfterId(id, before, limit); err != nil {
+		err = errors.WithMessagef(err, "Could not get nomad events after ID %d", id)
+		return
+	}
+	n.logger.Trace().Int64("id", id).Int("count", len(events)).Msg("Got nomad events after ID")
+	return
+}
+
+func (n nomadEventService) GetLastId() (id int64, err error) {
+	n.logger.Trace().Msg("Get last nomad event ID")
+	if id, err = n.nomadEventRepository.GetLastId(); err != nil {
+		err = errors.WithMessage(err, "Could not get last nomad event ID")
+	}
+	return
+}
+
+func (n nomadEventService) GetCheckpoint(handler string) (id *int64, err error) {
+	n.logger.Trace().Str("handler", handler).Msg("Get nomad event checkpoint")
+	if id, err = n.nomadEventRepository.GetCheckpoint(handler); err != nil {
+		err = errors.WithMessagef(err, "Could not get nomad event checkpoint of handler %q", handler)
+	}
+	return
+}
+
+func (n nomadEventService) SaveCheckpoint(handler string, id int64) (err error) {
+	n.logger.Trace().Str("handler", handler).Int64("id", id).Msg("Saving nomad event checkpoint")
+	if err = n.nomadEventRepository.SaveCheckpoint(handler, id); err != nil {
+		err = errors.WithMessagef(err, "Could not save nomad event checkpoint of handler %q", handler)
+		return
+	}
+	n.logger.Trace().Str("handler", handler).Int64("id", id).Msg("Saved nomad event checkpoint")
+	return
+}
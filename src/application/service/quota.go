@@ -0,0 +1,238 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+// Returned when publishing a fact would exceed a quota.
+type QuotaExceededError struct {
+	Reasons []string
+}
+
+func (self *QuotaExceededError) Error() string {
+	return "Quota exceeded: " + strings.Join(self.Reasons, "; ")
+}
+
+type QuotaService interface {
+	WithQuerier(config.PgxIface) QuotaService
+
+	// Returns the usage of all subjects that have a quota.
+	GetAll() ([]domain.QuotaReport, error)
+	// Returns the usage of the subject even if it has no quota.
+	Get(domain.QuotaSubject, string) (domain.QuotaReport, error)
+	Save(*domain.Quota) error
+	Delete(domain.QuotaSubject, string) error
+	// Lifts the quota until the given time.
+	// Returns nil if the subject has no quota.
+	Override(subject domain.QuotaSubject, name string, until time.Time, reason, by string) (*domain.Quota, error)
+	// Returns a *QuotaExceededError if the fact may not be published
+	// by the given API token, if any, or the Run it belongs to.
+	CheckFact(fact domain.Fact, tokenName *string) error
+	// Returns the reasons another Run of the action with the inputs
+	// would exceed the quotas of its project or the API tokens
+	// that published the inputs.
+	CheckRun(action domain.Action, inputs map[string]domain.Fact) ([]string, error)
+}
+
+type quotaService struct {
+	logger          zerolog.Logger
+	quotaRepository repository.QuotaRepository
+}
+
+func NewQuotaService(db config.PgxIface, logger *zerolog.Logger) QuotaService {
+	return &quotaService{
+		logger:          logger.With().Str("component", "QuotaService").Logger(),
+		quotaRepository: persistence.NewQuotaRepository(db),
+	}
+}
+
+func (self quotaService) WithQuerier(querier config.PgxIface) QuotaService {
+	return &quotaService{
+		logger:          self.logger,
+		quotaRepository: self.quotaRepository.WithQuerier(querier),
+	}
+}
+
+func (self quotaService) getUsage(subject domain.QuotaSubject, name string) (usage domain.QuotaUsage, err error) {
+	self.logger.Trace().Str("subject", string(subject)).Str("name", name).Msg("Getting quota usage")
+	usage, err = self.quotaRepository.GetUsage(subject, name, time.Now().UTC().Add(-time.Hour))
+	err = errors.WithMessagef(err, "Could not select usage of %s %q", subject, name)
+	return
+}
+
+func (self quotaService) GetAll() ([]domain.QuotaReport, error) {
+	self.logger.Trace().Msg("Getting all quotas")
+	quotas, err := self.quotaRepository.GetAll()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select quotas")
+	}
+
+	reports := make([]domain.QuotaReport, len(quotas))
+	for i := range quotas {
+		quota := quotas[i]
+		usage, err := self.getUsage(quota.Subject, quota.Name)
+		if err != nil {
+			return nil, err
+		}
+		reports[i] = domain.QuotaReport{Subject: quota.Subject, Name: quota.Name, Quota: &quota, Usage: usage}
+	}
+	return reports, nil
+}
+
+func (self quotaService) get(subject domain.QuotaSubject, name string) (quota *domain.Quota, err error) {
+	self.logger.Trace().Str("subject", string(subject)).Str("name", name).Msg("Getting quota")
+	quota, err = self.quotaRepository.Get(subject, name)
+	err = errors.WithMessagef(err, "Could not select quota of %s %q", subject, name)
+	return
+}
+
+func (self quotaService) Get(subject domain.QuotaSubject, name string) (domain.QuotaReport, error) {
+	report := domain.QuotaReport{Subject: subject, Name: name}
+
+	if quota, err := self.get(subject, name); err != nil {
+		return report, err
+	} else {
+		report.Quota = quota
+	}
+
+	if usage, err := self.getUsage(subject, name); err != nil {
+		return report, err
+	} else {
+		report.Usage = usage
+	}
+
+	return report, nil
+}
+
+func (self quotaService) Save(quota *domain.Quota) error {
+	self.logger.Trace().Str("subject", string(quota.Subject)).Str("name", quota.Name).Msg("Saving quota")
+	if err := self.quotaRepository.Save(quota); err != nil {
+		return errors.WithMessagef(err, "Could not save quota of %s %q", quota.Subject, quota.Name)
+	}
+	self.logger.Info().
+		Str("subject", string(quota.Subject)).
+		Str("name", quota.Name).
+		Str("by", quota.UpdatedBy).
+		Msg("Saved quota")
+	return nil
+}
+
+func (self quotaService) Delete(subject domain.QuotaSubject, name string) error {
+	self.logger.Trace().Str("subject", string(subject)).Str("name", name).Msg("Deleting quota")
+	if err := self.quotaRepository.Delete(subject, name); err != nil {
+		return errors.WithMessagef(err, "Could not delete quota of %s %q", subject, name)
+	}
+	return nil
+}
+
+func (self quotaService) Override(subject domain.QuotaSubject, name string, until time.Time, reason, by string) (*domain.Quota, error) {
+	quota, err := self.get(subject, name)
+	if err != nil || quota == nil {
+		return nil, err
+	}
+
+	until = until.UTC()
+	quota.OverrideUntil = &until
+	quota.OverrideReason = reason
+	quota.UpdatedBy = by
+
+	self.logger.Info().
+		Str("subject", string(subject)).
+		Str("name", name).
+		Time("until", until).
+		Str("reason", reason).
+		Str("by", by).
+		Msg("Overriding quota")
+
+	return quota, self.Save(quota)
+}
+
+// Returns the reasons to reject whatever the given function checks.
+func (self quotaService) check(subject domain.QuotaSubject, name string, exceeded func(domain.Quota, domain.QuotaUsage, time.Time) []string) ([]string, error) {
+	quota, err := self.get(subject, name)
+	if err != nil || quota == nil {
+		return nil, err
+	}
+
+	usage, err := self.getUsage(subject, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return exceeded(*quota, usage, time.Now().UTC()), nil
+}
+
+func (self quotaService) CheckFact(fact domain.Fact, tokenName *string) error {
+	reasons := []string{}
+
+	if tokenName != nil {
+		if tokenReasons, err := self.check(domain.QuotaSubjectToken, *tokenName, domain.Quota.ExceededByFact); err != nil {
+			return err
+		} else {
+			reasons = append(reasons, tokenReasons...)
+		}
+	}
+
+	if fact.RunId != nil {
+		if project, err := self.quotaRepository.GetProjectByRunId(*fact.RunId); err != nil {
+			return errors.WithMessagef(err, "Could not select project of Run with ID %q", *fact.RunId)
+		} else if project != nil {
+			if projectReasons, err := self.check(domain.QuotaSubjectProject, *project, domain.Quota.ExceededByFact); err != nil {
+				return err
+			} else {
+				reasons = append(reasons, projectReasons...)
+			}
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &QuotaExceededError{reasons}
+	}
+	return nil
+}
+
+func (self quotaService) CheckRun(action domain.Action, inputs map[string]domain.Fact) ([]string, error) {
+	reasons, err := self.check(domain.QuotaSubjectProject, action.Project(), domain.Quota.ExceededByRun)
+	if err != nil {
+		return nil, err
+	}
+
+	factIds := make([]uuid.UUID, 0, len(inputs))
+	for _, fact := range inputs {
+		factIds = append(factIds, fact.ID)
+	}
+
+	tokenNames, err := self.quotaRepository.GetTokenNamesByFactIds(factIds)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select API tokens of input facts")
+	}
+
+	for _, tokenName := range tokenNames {
+		if tokenReasons, err := self.check(domain.QuotaSubjectToken, tokenName, domain.Quota.ExceededByRun); err != nil {
+			return nil, err
+		} else {
+			reasons = append(reasons, tokenReasons...)
+		}
+	}
+
+	return reasons, nil
+}
+
+// Denies Runs that would exceed a quota.
+type QuotaAdmissionHook struct {
+	QuotaService QuotaService
+}
+
+func (self QuotaAdmissionHook) Admit(request AdmissionRequest) ([]string, error) {
+	return self.QuotaService.CheckRun(request.Action, request.Inputs)
+}
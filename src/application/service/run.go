@@ -1,14 +1,19 @@
 package service
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"math"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +21,7 @@ import (
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jackc/pgx/v4"
 	"github.com/pkg/errors"
+	prometheus "github.com/prometheus/client_golang/api"
 	"github.com/rs/zerolog"
 
 	"github.com/input-output-hk/cicero/src/application"
@@ -33,55 +39,169 @@ type RunService interface {
 	GetByInvocationId(uuid.UUID) (*domain.Run, error)
 	GetByActionId(uuid.UUID, *repository.Page) ([]domain.Run, error)
 	GetLatestByActionId(uuid.UUID) (*domain.Run, error)
+	// Returns the latest Run of any version of the action.
+	GetLatestByActionName(string) (*domain.Run, error)
+	// Returns the latest Run of any version of the Run's action
+	// that succeeded and was created before it.
+	GetPreviousSucceeded(domain.Run) (*domain.Run, error)
+	GetChainedFrom(uuid.UUID) ([]domain.Run, error)
+	GetDownstream(uuid.UUID) ([]domain.Run, error)
 	GetAll(*repository.Page) ([]domain.Run, error)
+	GetByFilter(domain.RunFilter, *repository.Page) ([]domain.Run, error)
 	Save(*domain.Run) error
 	Update(*domain.Run) error
+	UpdateNomadCluster(*domain.Run) error
+	// Records that the Run's output was not published because it did not change.
+	UpdateOutputUnchanged(*domain.Run) error
+	UpdateDeployment(*domain.Run) error
+	// Records whether Nomad could place the Run's allocations and why not.
+	UpdateBlocked(*domain.Run) error
+	// Ends the Run as failed because its job was not admitted.
+	Deny(run *domain.Run, reasons []string) error
 	End(*domain.Run) error
 	Cancel(*domain.Run) error
-	JobLog(id uuid.UUID, start time.Time, end *time.Time) (LokiLog, error)
-	RunLog(allocId, taskGroup, taskName string, start time.Time, end *time.Time) (LokiLog, error)
+	// Logs are served from the archive once the Run's log was archived.
+	JobLog(id uuid.UUID, start time.Time, end *time.Time, page LokiPage) (LokiLogPage, error)
+	RunLog(id uuid.UUID, allocId, taskGroup, taskName string, start time.Time, end *time.Time, page LokiPage) (LokiLogPage, error)
+	// Compares the log of the Run with that of a previous one
+	// with `context` unchanged lines around each change.
+	LogDiff(previous, run domain.Run, context int) (domain.LogDiff, error)
+	GetFinishedWithoutLogArchive(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	// Copies the whole log of the Run from Loki to the database
+	// so that it is still available after Loki's retention period.
+	ArchiveLog(domain.Run) error
+	// Returns the snapshot of the allocations taken when the Run ended
+	// or their latest state as seen in Nomad events.
+	GetAllocations(domain.Run) ([]nomad.Allocation, error)
+	GetPlacement(domain.Run) (domain.RunPlacement, error)
 	GetRunAllocationsWithLogs(domain.Run) ([]AllocationWithLogs, error)
+	GetTimeline(domain.Run) ([]domain.RunTimelineEntry, error)
+	GetStateAt(domain.Run, time.Time) (domain.RunState, error)
+	GetTasks(domain.Run) ([]domain.RunTask, error)
+	// Returns nil if the Run's job was never submitted
+	// or was submitted before manifests were recorded.
+	GetManifest(uuid.UUID) (*domain.RunManifest, error)
+	SaveManifest(*domain.RunManifest) error
+	// Returns the secret of a new token with which
+	// the Run's job can report its progress.
+	CreateToken(uuid.UUID) (string, error)
+	// Returns nil if the secret does not belong to a Run that is still running.
+	AuthenticateToken(secret string) (*domain.Run, error)
+	GetProgress(uuid.UUID) ([]domain.RunProgress, error)
+	// Returns nil if the Run did not report any progress.
+	GetLatestProgress(uuid.UUID) (*domain.RunProgress, error)
+	SaveProgress(*domain.RunProgress) error
+	// Returns the jobs to dispatch from the Run's parameterized job in order.
+	GetDispatches(uuid.UUID) ([]domain.RunDispatch, error)
+	SaveDispatches([]domain.RunDispatch) error
+	// Dispatches the Run's parameterized job for each of its dispatches
+	// that were not dispatched yet. If that fails the rest are failed
+	// and the Run ends, without publishing its output, if none are running.
+	Dispatch(*domain.Run) error
+	// Ends the dispatch with the given Nomad job ID unless it already ended.
+	// Returns whether it did.
+	EndDispatch(nomadJobId string, status domain.RunStatus, finishedAt time.Time) (bool, error)
+	// Runs waiting for a mutex or an approval have no job yet and are left out.
+	GetRunning() ([]domain.Run, error)
+	// Returns when the Run's allocations last changed in Nomad or logged a line.
+	// Log lines are only looked for after the given time
+	// so this returns the Run's creation time if there was no newer activity.
+	GetLastActivity(run domain.Run, since time.Time) (time.Time, error)
+	UpdateSuspect(*domain.Run) error
+	// Ends running Runs created before the given time
+	// whose Nomad job vanished or died without its end being noticed
+	// and returns them. Only returns them if `dryRun`.
+	Reconcile(createdBefore time.Time, dryRun bool) ([]domain.Run, error)
+	GetFinishedWithoutNomadJobGC(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	MarkNomadJobGCed(*domain.Run) error
+	SnapshotAllocations(*domain.Run) error
+	// Remembers that an allocation of the Run ran on the node.
+	SaveNode(runId uuid.UUID, nodeId string) error
+	// Returns the nodes that ran the latest Runs of any version of the action, most recent first.
+	GetLatestNodesByActionName(name string, limit int) ([]string, error)
+	// Returns when the annotations, timing, or usage of any of the Runs
+	// were last saved. Returns nil if none were.
+	GetLastMeasuredAt(...uuid.UUID) (*time.Time, error)
+	PurgeNomadJob(*domain.Run) error
+	Exec(context.Context, domain.Run, ExecOptions) (int, error)
 	CPUMetrics(allocs []*nomad.Allocation, end *time.Time) (map[string][]*VMMetric, error)
 	MemMetrics(allocs []*nomad.Allocation, end *time.Time) (map[string][]*VMMetric, error)
+	// Returns the resource usage of the allocations that logged the lines
+	// in windows that span the log's time range.
+	LogMetrics(LokiLog) ([]LogMetricsWindow, error)
 	GrafanaUrls(allocs []*nomad.Allocation, end *time.Time) (map[string]*url.URL, error)
 	GrafanaLokiUrls(allocs []*nomad.Allocation, end *time.Time) (map[string]*url.URL, error)
 }
 
+// Number of lines at the end of each task's log
+// that `GetRunAllocationsWithLogs()` fetches and collapses.
+const RunLogTail = 500
+
 type AllocationWithLogs struct {
 	*nomad.Allocation
-	TaskLogs map[string]LokiLog
+	TaskLogs map[string]LokiLogPage
+}
+
+// What to run in which task of a Run's allocation.
+// If AllocId or Task are empty and there is only one
+// running allocation or task that one is used.
+type ExecOptions struct {
+	AllocId      string
+	Task         string
+	Command      []string
+	Tty          bool
+	Stdin        io.Reader
+	Stdout       io.Writer
+	Stderr       io.Writer
+	TerminalSize <-chan nomad.TerminalSize
+}
+
+// A Run with where its allocations were placed.
+type RunDetail struct {
+	domain.Run
+	Placement domain.RunPlacement `json:"placement"`
 }
 
 type runService struct {
-	logger              zerolog.Logger
-	runRepository       repository.RunRepository
-	lokiService         LokiService
-	victoriaMetricsAddr string
-	nomadEventService   NomadEventService
-	nomadClient         application.NomadClient
-	db                  config.PgxIface
+	logger             zerolog.Logger
+	runRepository      repository.RunRepository
+	manifestRepository repository.RunManifestRepository
+	progressRepository repository.RunProgressRepository
+	dispatchRepository repository.RunDispatchRepository
+	lokiService        LokiService
+	victoriaMetrics    prometheus.Client
+	nomadEventService  NomadEventService
+	nomadClusters      application.NomadClusters
+	db                 config.PgxIface
 }
 
-func NewRunService(db config.PgxIface, lokiService LokiService, nomadEventService NomadEventService, victoriaMetricsAddr string, nomadClient application.NomadClient, logger *zerolog.Logger) RunService {
+func NewRunService(db config.PgxIface, lokiService LokiService, nomadEventService NomadEventService, victoriaMetrics prometheus.Client, nomadClusters application.NomadClusters, logger *zerolog.Logger) RunService {
 	return &runService{
-		logger:              logger.With().Str("component", "RunService").Logger(),
-		runRepository:       persistence.NewRunRepository(db),
-		nomadClient:         nomadClient,
-		nomadEventService:   nomadEventService,
-		lokiService:         lokiService,
-		victoriaMetricsAddr: victoriaMetricsAddr,
-		db:                  db,
+		logger:             logger.With().Str("component", "RunService").Logger(),
+		runRepository:      persistence.NewRunRepository(db),
+		manifestRepository: persistence.NewRunManifestRepository(db),
+		progressRepository: persistence.NewRunProgressRepository(db),
+		dispatchRepository: persistence.NewRunDispatchRepository(db),
+		nomadClusters:      nomadClusters,
+		nomadEventService:  nomadEventService,
+		lokiService:        lokiService,
+		victoriaMetrics:    victoriaMetrics,
+		db:                 db,
 	}
 }
 
 func (self runService) WithQuerier(querier config.PgxIface) RunService {
 	return &runService{
-		logger:            self.logger,
-		runRepository:     self.runRepository.WithQuerier(querier),
-		nomadEventService: self.nomadEventService.WithQuerier(querier),
-		lokiService:       self.lokiService,
-		nomadClient:       self.nomadClient,
-		db:                querier,
+		logger:             self.logger,
+		runRepository:      self.runRepository.WithQuerier(querier),
+		manifestRepository: self.manifestRepository.WithQuerier(querier),
+		progressRepository: self.progressRepository.WithQuerier(querier),
+		dispatchRepository: self.dispatchRepository.WithQuerier(querier),
+		nomadEventService:  self.nomadEventService.WithQuerier(querier),
+		lokiService:        self.lokiService,
+		victoriaMetrics:    self.victoriaMetrics,
+		nomadClusters:      self.nomadClusters,
+		db:                 querier,
 	}
 }
 
@@ -120,6 +240,34 @@ func (self runService) GetLatestByActionId(id uuid.UUID) (run *domain.Run, err e
 	return
 }
 
+func (self runService) GetLatestByActionName(name string) (run *domain.Run, err error) {
+	self.logger.Trace().Str("action-name", name).Msg("Getting latest Run by Action name")
+	run, err = self.runRepository.GetLatestByActionName(name)
+	err = errors.WithMessagef(err, "Could not select latest Run by Action name %q", name)
+	return
+}
+
+func (self runService) GetPreviousSucceeded(of domain.Run) (run *domain.Run, err error) {
+	self.logger.Trace().Stringer("id", of.NomadJobID).Msg("Getting previous succeeded Run")
+	run, err = self.runRepository.GetPreviousSucceeded(of)
+	err = errors.WithMessagef(err, "Could not select previous succeeded Run of Run with ID %q", of.NomadJobID)
+	return
+}
+
+func (self runService) GetChainedFrom(id uuid.UUID) (runs []domain.Run, err error) {
+	self.logger.Trace().Str("id", id.String()).Msg("Getting Runs chained from Run")
+	runs, err = self.runRepository.GetChainedFrom(id)
+	err = errors.WithMessagef(err, "Could not select Runs chained from Run with ID %q", id)
+	return
+}
+
+func (self runService) GetDownstream(id uuid.UUID) (runs []domain.Run, err error) {
+	self.logger.Trace().Str("id", id.String()).Msg("Getting Runs downstream of Run")
+	runs, err = self.runRepository.GetDownstream(id)
+	err = errors.WithMessagef(err, "Could not select Runs downstream of Run with ID %q", id)
+	return
+}
+
 func (self runService) GetAll(page *repository.Page) (runs []domain.Run, err error) {
 	self.logger.Trace().Int("offset", page.Offset).Int("limit", page.Limit).Msg("Getting all Runs")
 	runs, err = self.runRepository.GetAll(page)
@@ -127,6 +275,13 @@ func (self runService) GetAll(page *repository.Page) (runs []domain.Run, err err
 	return
 }
 
+func (self runService) GetByFilter(filter domain.RunFilter, page *repository.Page) (runs []domain.Run, err error) {
+	self.logger.Trace().Str("filter", filter.Query().Encode()).Int("offset", page.Offset).Int("limit", page.Limit).Msg("Getting Runs by filter")
+	runs, err = self.runRepository.GetByFilter(filter, page)
+	err = errors.WithMessagef(err, "Could not select Runs by filter %q with offset %d and limit %d", filter.Query().Encode(), page.Offset, page.Limit)
+	return
+}
+
 func (self runService) Save(run *domain.Run) error {
 	self.logger.Trace().Msg("Saving new Run")
 	if err := self.runRepository.Save(run); err != nil {
@@ -145,13 +300,77 @@ func (self runService) Update(run *domain.Run) error {
 	return nil
 }
 
+func (self runService) UpdateNomadCluster(run *domain.Run) error {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Str("nomad-cluster", run.NomadCluster).Msg("Updating Nomad cluster of Run")
+	if err := self.runRepository.UpdateNomadCluster(run); err != nil {
+		return errors.WithMessagef(err, "Could not update Nomad cluster of Run with ID %q", run.NomadJobID)
+	}
+	return nil
+}
+
+func (self runService) UpdateOutputUnchanged(run *domain.Run) error {
+	self.logger.Trace().Stringer("id", run.NomadJobID).Msg("Updating unchanged output of Run")
+	if err := self.runRepository.UpdateOutputUnchanged(run); err != nil {
+		return errors.WithMessagef(err, "Could not update unchanged output of Run with ID %q", run.NomadJobID)
+	}
+	return nil
+}
+
+func (self runService) UpdateDeployment(run *domain.Run) error {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Str("deployment-status", run.DeploymentStatus).Msg("Updating deployment status of Run")
+	if err := self.runRepository.UpdateDeployment(run); err != nil {
+		return errors.WithMessagef(err, "Could not update deployment status of Run with ID %q", run.NomadJobID)
+	}
+	return nil
+}
+
+func (self runService) UpdateBlocked(run *domain.Run) error {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Interface("blocked-since", run.BlockedSince).Strs("reasons", run.BlockedReasons).Msg("Updating blocked state of Run")
+	if err := self.runRepository.UpdateBlocked(run); err != nil {
+		return errors.WithMessagef(err, "Could not update blocked state of Run with ID %q", run.NomadJobID)
+	}
+	return nil
+}
+
+func (self runService) Deny(run *domain.Run, reasons []string) error {
+	self.logger.Debug().Str("id", run.NomadJobID.String()).Strs("reasons", reasons).Msg("Denying Run")
+
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Status = domain.RunStatusFailed
+	run.AdmissionDenials = reasons
+
+	if err := self.runRepository.Update(run); err != nil {
+		return errors.WithMessagef(err, "Could not update denied Run with ID %q", run.NomadJobID)
+	}
+	if err := self.runRepository.UpdateAdmissionDenials(run); err != nil {
+		return errors.WithMessagef(err, "Could not update admission denials of Run with ID %q", run.NomadJobID)
+	}
+	return nil
+}
+
+// Returns the client of the Nomad cluster the Run's job was registered with.
+func (self runService) nomadClient(run *domain.Run) (application.NomadClient, error) {
+	if cluster, ok := self.nomadClusters.Get(run.NomadCluster); ok {
+		return cluster.NomadClient, nil
+	}
+	return nil, errors.Errorf("Run with ID %q is on unknown Nomad cluster %q", run.NomadJobID, run.NomadCluster)
+}
+
 func (self runService) End(run *domain.Run) error {
 	self.logger.Debug().Str("id", run.NomadJobID.String()).Msg("Ending Run")
 	if err := self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
 		if err := self.runRepository.WithQuerier(tx).Update(run); err != nil {
 			return errors.WithMessagef(err, "Could not update Run with ID %q", run.NomadJobID)
 		}
-		if _, _, err := self.nomadClient.JobsDeregister(run.NomadJobID.String(), false, &nomad.WriteOptions{}); err != nil {
+		// Keep the final state of the allocations around
+		// for when Nomad garbage collects the job.
+		if err := self.snapshotAllocations(self.runRepository.WithQuerier(tx), run); err != nil {
+			return err
+		}
+		if nomadClient, err := self.nomadClient(run); err != nil {
+			return err
+		} else if _, _, err := nomadClient.JobsDeregister(run.NomadJobID.String(), false, &nomad.WriteOptions{}); err != nil {
 			return errors.WithMessagef(err, "Could not deregister Nomad job with ID %q", run.NomadJobID)
 		}
 		return nil
@@ -168,29 +387,198 @@ func (self runService) Cancel(run *domain.Run) error {
 	if err := self.Update(run); err != nil {
 		return err
 	}
-	if _, _, err := self.nomadClient.JobsDeregister(run.NomadJobID.String(), false, &nomad.WriteOptions{}); err != nil {
+	if nomadClient, err := self.nomadClient(run); err != nil {
+		return err
+	} else if _, _, err := nomadClient.JobsDeregister(run.NomadJobID.String(), false, &nomad.WriteOptions{}); err != nil {
 		return errors.WithMessagef(err, "Failed to deregister job %q", run.NomadJobID)
 	}
+	// Stopping a parameterized job does not stop the jobs dispatched from it.
+	if err := self.stopDispatches(run); err != nil {
+		return err
+	}
 	self.logger.Debug().Str("id", run.NomadJobID.String()).Msg("Stopped Run")
 	return nil
 }
 
-func (self runService) JobLog(nomadJobID uuid.UUID, start time.Time, end *time.Time) (LokiLog, error) {
-	return self.lokiService.QueryRangeLog(
-		fmt.Sprintf(`{nomad_job_id=%q}`, nomadJobID.String()),
-		start, end,
-	)
+func (self runService) JobLog(nomadJobID uuid.UUID, start time.Time, end *time.Time, page LokiPage) (LokiLogPage, error) {
+	if archive, err := self.getLogArchive(nomadJobID); err != nil {
+		return LokiLogPage{}, err
+	} else if archive != nil {
+		return archive.Page(nil, start, end, page), nil
+	}
+
+	allocs, err := self.getAllocations(nomadJobID)
+	if err != nil {
+		return LokiLogPage{}, err
+	}
+
+	// A single query for the whole job would interleave the streams
+	// and its limit would cut off some of them.
+	queries := []string{}
+	for _, alloc := range allocs {
+		for _, task := range allocationTaskNames(alloc) {
+			queries = append(queries, fmt.Sprintf(`{nomad_alloc_id=%q,nomad_task_group=%q,nomad_task_name=%q}`, alloc.ID, alloc.TaskGroup, task))
+		}
+	}
+	if len(queries) == 0 {
+		queries = append(queries, fmt.Sprintf(`{nomad_job_id=%q}`, nomadJobID.String()))
+	}
+
+	return self.lokiService.QueryRangeLogPageMerged(queries, start, end, page)
+}
+
+// Returns the names of the allocation's tasks in order.
+func allocationTaskNames(alloc nomad.Allocation) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for name := range alloc.TaskStates {
+		names = append(names, name)
+		seen[name] = true
+	}
+	for name := range alloc.TaskResources {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
-func (self runService) RunLog(allocID, taskGroup, taskName string, start time.Time, end *time.Time) (LokiLog, error) {
-	return self.lokiService.QueryRangeLog(
+func (self runService) RunLog(nomadJobID uuid.UUID, allocID, taskGroup, taskName string, start time.Time, end *time.Time, page LokiPage) (LokiLogPage, error) {
+	if archive, err := self.getLogArchive(nomadJobID); err != nil {
+		return LokiLogPage{}, err
+	} else if archive != nil {
+		return archive.Page(map[string]string{
+			"nomad_alloc_id":   allocID,
+			"nomad_task_group": taskGroup,
+			"nomad_task_name":  taskName,
+		}, start, end, page), nil
+	}
+
+	return self.lokiService.QueryRangeLogPage(
 		fmt.Sprintf(`{nomad_alloc_id=%q,nomad_task_group=%q,nomad_task_name=%q}`, allocID, taskGroup, taskName),
-		start, end,
+		start, end, page,
 	)
 }
 
+// Returns nil if the Run's log was not archived.
+func (self runService) getLogArchive(nomadJobID uuid.UUID) (LokiLog, error) {
+	var log LokiLog
+	if err := self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		archive, err := self.runRepository.GetLogArchive(tx, nomadJobID)
+		if err != nil || archive == nil {
+			return err
+		}
+		defer archive.Close()
+
+		reader, err := gzip.NewReader(archive)
+		if err != nil {
+			return err
+		}
+
+		log = LokiLog{}
+		decoder := json.NewDecoder(reader)
+		for {
+			line := LokiLine{}
+			if err := decoder.Decode(&line); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			log = append(log, line)
+		}
+	}); err != nil {
+		return nil, errors.WithMessagef(err, "Could not read archived log of Run with ID %q", nomadJobID)
+	}
+	return log, nil
+}
+
+func (self runService) GetFinishedWithoutLogArchive(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	self.logger.Trace().Time("finished-before", finishedBefore).Int("limit", limit).Msg("Getting finished Runs whose log was not archived")
+	runs, err = self.runRepository.GetFinishedWithoutLogArchive(finishedBefore, limit)
+	err = errors.WithMessagef(err, "Could not select finished Runs whose log was not archived")
+	return
+}
+
+func (self runService) ArchiveLog(run domain.Run) error {
+	self.logger.Debug().Str("id", run.NomadJobID.String()).Msg("Archiving log of Run")
+
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	encoder := json.NewEncoder(writer)
+
+	lines := 0
+	page := LokiPage{Direction: LokiForward, Limit: LokiMaxLimit}
+	for {
+		log, err := self.lokiService.QueryRangeLogPage(
+			fmt.Sprintf(`{nomad_job_id=%q}`, run.NomadJobID.String()),
+			run.CreatedAt, run.FinishedAt, page,
+		)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not get log of Run with ID %q", run.NomadJobID)
+		}
+
+		for _, line := range log.Log {
+			// Anchors are derived from the line so they need not be stored.
+			line.Anchor = nil
+			if err := encoder.Encode(line); err != nil {
+				return err
+			}
+		}
+		lines += len(log.Log)
+
+		if log.Next == nil {
+			break
+		}
+		page.Cursor = log.Next
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := self.runRepository.SaveLogArchive(run.NomadJobID, buf, lines); err != nil {
+		return errors.WithMessagef(err, "Could not save archived log of Run with ID %q", run.NomadJobID)
+	}
+
+	self.logger.Debug().Str("id", run.NomadJobID.String()).Int("lines", lines).Int("bytes", buf.Len()).Msg("Archived log of Run")
+	return nil
+}
+
+func (self runService) GetAllocations(run domain.Run) ([]nomad.Allocation, error) {
+	return self.getAllocations(run.NomadJobID)
+}
+
+func (self runService) getAllocations(nomadJobID uuid.UUID) ([]nomad.Allocation, error) {
+	allocs, err := self.runRepository.GetAllocations(nomadJobID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not get allocation snapshots of Run with ID %q", nomadJobID)
+	}
+	if len(allocs) == 0 {
+		// There are no snapshots until the Run has ended.
+		if allocs, err = self.nomadEventService.GetLatestEventAllocationByJobId(nomadJobID); err != nil {
+			return nil, err
+		}
+	}
+	return allocs, nil
+}
+
+func (self runService) GetPlacement(run domain.Run) (domain.RunPlacement, error) {
+	allocs, err := self.GetAllocations(run)
+	if err != nil {
+		return domain.RunPlacement{}, err
+	}
+
+	eval, err := self.nomadEventService.GetLatestEventEvaluationByJobId(run.NomadJobID)
+	if err != nil {
+		return domain.RunPlacement{}, err
+	}
+
+	return domain.NewRunPlacement(allocs, eval), nil
+}
+
 func (self runService) GetRunAllocationsWithLogs(run domain.Run) ([]AllocationWithLogs, error) {
-	allocs, err := self.nomadEventService.GetLatestEventAllocationByJobId(run.NomadJobID)
+	allocs, err := self.GetAllocations(run)
 	if err != nil {
 		return nil, err
 	}
@@ -200,7 +588,7 @@ func (self runService) GetRunAllocationsWithLogs(run domain.Run) ([]AllocationWi
 	type logsMsg struct {
 		idx      int
 		taskName string
-		log      LokiLog
+		log      LokiLogPage
 		err      error
 	}
 
@@ -214,7 +602,7 @@ func (self runService) GetRunAllocationsWithLogs(run domain.Run) ([]AllocationWi
 
 		allocsWithLog[i] = AllocationWithLogs{
 			Allocation: &alloc,
-			TaskLogs:   map[string]LokiLog{},
+			TaskLogs:   map[string]LokiLogPage{},
 		}
 
 		numMsgs += uint(len(alloc.TaskResources))
@@ -222,7 +610,11 @@ func (self runService) GetRunAllocationsWithLogs(run domain.Run) ([]AllocationWi
 		for taskName := range alloc.TaskResources {
 			go func(i int, taskName string) {
 				defer wg.Done()
-				log, err := self.RunLog(alloc.ID, alloc.TaskGroup, taskName, run.CreatedAt, nil)
+				log, err := self.RunLog(run.NomadJobID, alloc.ID, alloc.TaskGroup, taskName, run.CreatedAt, run.FinishedAt, LokiPage{
+					Direction: LokiBackward,
+					Limit:     RunLogTail,
+					Collapse:  true,
+				})
 				logs <- logsMsg{
 					idx:      i,
 					taskName: taskName,
@@ -246,6 +638,402 @@ func (self runService) GetRunAllocationsWithLogs(run domain.Run) ([]AllocationWi
 	return allocsWithLog, nil
 }
 
+func (self runService) GetTimeline(run domain.Run) ([]domain.RunTimelineEntry, error) {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Msg("Getting timeline of Run")
+	events, err := self.nomadEventService.GetByJobId(run.NomadJobID)
+	if err != nil {
+		return nil, err
+	}
+	timeline, err := domain.NewRunTimeline(run, events)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not build timeline of Run with ID %q", run.NomadJobID)
+	}
+	return timeline, nil
+}
+
+func (self runService) GetStateAt(run domain.Run, at time.Time) (domain.RunState, error) {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Time("at", at).Msg("Getting state of Run")
+	events, err := self.nomadEventService.GetByJobId(run.NomadJobID)
+	if err != nil {
+		return domain.RunState{}, err
+	}
+	state, err := domain.NewRunState(run, events, at)
+	if err != nil {
+		return state, errors.WithMessagef(err, "Could not reconstruct state of Run with ID %q at %s", run.NomadJobID, at)
+	}
+	return state, nil
+}
+
+func (self runService) GetTasks(run domain.Run) ([]domain.RunTask, error) {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Msg("Getting tasks of Run")
+	allocs, err := self.GetAllocations(run)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewRunTasks(allocs, time.Now().UTC()), nil
+}
+
+func (self runService) GetManifest(id uuid.UUID) (manifest *domain.RunManifest, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting manifest of Run")
+	manifest, err = self.manifestRepository.GetByRunId(id)
+	err = errors.WithMessagef(err, "Could not select manifest of Run %q", id)
+	return
+}
+
+func (self runService) SaveManifest(manifest *domain.RunManifest) error {
+	self.logger.Trace().Stringer("id", manifest.RunId).Msg("Saving manifest of Run")
+	if err := self.manifestRepository.Save(manifest); err != nil {
+		return errors.WithMessagef(err, "Could not insert manifest of Run %q", manifest.RunId)
+	}
+	self.logger.Trace().Stringer("id", manifest.RunId).Msg("Saved manifest of Run")
+	return nil
+}
+
+func (self runService) CreateToken(id uuid.UUID) (string, error) {
+	secret, err := newTokenSecret(domain.RunTokenPrefix)
+	if err != nil {
+		return "", errors.WithMessage(err, "Could not generate Run token secret")
+	}
+
+	self.logger.Trace().Stringer("id", id).Msg("Saving token of Run")
+	if err := self.progressRepository.SaveToken(id, apiTokenHash(secret)); err != nil {
+		return "", errors.WithMessagef(err, "Could not insert token of Run %q", id)
+	}
+
+	return secret, nil
+}
+
+func (self runService) AuthenticateToken(secret string) (*domain.Run, error) {
+	if !strings.HasPrefix(secret, domain.RunTokenPrefix) {
+		return nil, nil
+	}
+
+	id, err := self.progressRepository.GetRunIdByTokenHash(apiTokenHash(secret))
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select Run token by hash")
+	} else if id == nil {
+		return nil, nil
+	}
+
+	run, err := self.GetByNomadJobId(*id)
+	switch {
+	case err != nil:
+		return nil, err
+	case run == nil:
+		return nil, nil
+	case run.FinishedAt != nil:
+		self.logger.Debug().Stringer("id", run.NomadJobID).Msg("Rejecting token of finished Run")
+		return nil, nil
+	}
+
+	return run, nil
+}
+
+func (self runService) GetProgress(id uuid.UUID) (progress []domain.RunProgress, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting progress of Run")
+	progress, err = self.progressRepository.GetByRunId(id)
+	err = errors.WithMessagef(err, "Could not select progress of Run %q", id)
+	return
+}
+
+func (self runService) GetLatestProgress(id uuid.UUID) (progress *domain.RunProgress, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting latest progress of Run")
+	progress, err = self.progressRepository.GetLatestByRunId(id)
+	err = errors.WithMessagef(err, "Could not select latest progress of Run %q", id)
+	return
+}
+
+func (self runService) SaveProgress(progress *domain.RunProgress) error {
+	self.logger.Trace().Stringer("id", progress.RunId).Float64("done", progress.Done).Msg("Saving progress of Run")
+	if err := self.progressRepository.Save(progress); err != nil {
+		return errors.WithMessagef(err, "Could not insert progress of Run %q", progress.RunId)
+	}
+	return nil
+}
+
+func (self runService) GetRunning() (runs []domain.Run, err error) {
+	self.logger.Trace().Msg("Getting running Runs")
+	runs, err = self.runRepository.GetRunning()
+	err = errors.WithMessage(err, "Could not select running Runs")
+	return
+}
+
+func (self runService) GetLastActivity(run domain.Run, since time.Time) (time.Time, error) {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Time("since", since).Msg("Getting last activity of Run")
+
+	last := run.CreatedAt
+
+	allocs, err := self.GetAllocations(run)
+	if err != nil {
+		return last, err
+	}
+	for _, alloc := range allocs {
+		if t := time.Unix(0, alloc.ModifyTime); t.After(last) {
+			last = t
+		}
+		for _, state := range alloc.TaskStates {
+			for _, event := range state.Events {
+				if t := time.Unix(0, event.Time); t.After(last) {
+					last = t
+				}
+			}
+		}
+	}
+
+	if last.Before(since) {
+		page, err := self.JobLog(run.NomadJobID, since, nil, LokiPage{Direction: LokiBackward, Limit: 1})
+		if err != nil {
+			return last, errors.WithMessagef(err, "Could not get last log line of Run with ID %q", run.NomadJobID)
+		}
+		for _, line := range page.Log {
+			if line.Time.After(last) {
+				last = line.Time
+			}
+		}
+	}
+
+	return last, nil
+}
+
+func (self runService) UpdateSuspect(run *domain.Run) error {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Interface("suspect-since", run.SuspectSince).Msg("Updating suspicion of Run")
+	if err := self.runRepository.UpdateSuspect(run); err != nil {
+		return errors.WithMessagef(err, "Could not update suspicion of Run with ID %q", run.NomadJobID)
+	}
+	return nil
+}
+
+func (self runService) Reconcile(createdBefore time.Time, dryRun bool) ([]domain.Run, error) {
+	runs, err := self.GetRunning()
+	if err != nil {
+		return nil, err
+	}
+
+	self.logger.Debug().Int("runs", len(runs)).Time("created-before", createdBefore).Bool("dry-run", dryRun).Msg("Reconciling Runs with Nomad")
+
+	reconciled := []domain.Run{}
+	for _, run := range runs {
+		run := run
+
+		if !run.CreatedAt.Before(createdBefore) {
+			continue
+		}
+
+		nomadClient, err := self.nomadClient(&run)
+		if err != nil {
+			self.logger.Warn().Err(err).Msg("Cannot reconcile Run")
+			continue
+		}
+
+		var note string
+		finishedAt := time.Now().UTC()
+
+		if job, _, err := nomadClient.JobsInfo(run.NomadJobID.String(), &nomad.QueryOptions{}); err != nil {
+			if !application.IsNomadNotFound(err) {
+				return reconciled, errors.WithMessagef(err, "Could not get Nomad job with ID %q", run.NomadJobID)
+			}
+			note = "Nomad job vanished"
+		} else if job.Status != nil && *job.Status == "dead" {
+			note = "Nomad job is dead but its end was missed"
+
+			// The job ended when its last allocation did.
+			if allocs, _, err := nomadClient.JobsAllocations(run.NomadJobID.String(), true, &nomad.QueryOptions{}); err != nil {
+				return reconciled, errors.WithMessagef(err, "Could not get allocations of Nomad job with ID %q", run.NomadJobID)
+			} else if len(allocs) > 0 {
+				latest := int64(0)
+				for _, alloc := range allocs {
+					if alloc.ModifyTime > latest {
+						latest = alloc.ModifyTime
+					}
+				}
+				finishedAt = time.Unix(0, latest).UTC()
+			}
+		} else {
+			continue
+		}
+
+		run.FinishedAt = &finishedAt
+		run.Status = domain.RunStatusFailed
+		run.ReconcileNote = &note
+
+		if !dryRun {
+			self.logger.Debug().Str("id", run.NomadJobID.String()).Str("note", note).Msg("Reconciling Run")
+			if ok, err := self.runRepository.Reconcile(&run); err != nil {
+				return reconciled, errors.WithMessagef(err, "Could not reconcile Run with ID %q", run.NomadJobID)
+			} else if !ok {
+				// It ended in the meantime.
+				continue
+			}
+		}
+
+		reconciled = append(reconciled, run)
+	}
+
+	return reconciled, nil
+}
+
+func (self runService) GetFinishedWithoutNomadJobGC(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	self.logger.Trace().Time("finished-before", finishedBefore).Int("limit", limit).Msg("Getting finished Runs whose Nomad job was not garbage collected")
+	runs, err = self.runRepository.GetFinishedWithoutNomadJobGC(finishedBefore, limit)
+	err = errors.WithMessagef(err, "Could not select finished Runs whose Nomad job was not garbage collected")
+	return
+}
+
+func (self runService) MarkNomadJobGCed(run *domain.Run) error {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Msg("Marking Nomad job of Run as garbage collected")
+	if err := self.runRepository.MarkNomadJobGCed(run); err != nil {
+		return errors.WithMessagef(err, "Could not mark Nomad job of Run with ID %q as garbage collected", run.NomadJobID)
+	}
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Msg("Marked Nomad job of Run as garbage collected")
+	return nil
+}
+
+func (self runService) SaveNode(runId uuid.UUID, nodeId string) error {
+	self.logger.Trace().Stringer("id", runId).Str("node", nodeId).Msg("Saving node of Run")
+	if err := self.runRepository.SaveNode(runId, nodeId); err != nil {
+		return errors.WithMessagef(err, "Could not insert node %q of Run %q", nodeId, runId)
+	}
+	return nil
+}
+
+func (self runService) GetLatestNodesByActionName(name string, limit int) (nodeIds []string, err error) {
+	self.logger.Trace().Str("name", name).Int("limit", limit).Msg("Getting nodes of latest Runs by action name")
+	nodeIds, err = self.runRepository.GetLatestNodesByActionName(name, limit)
+	err = errors.WithMessagef(err, "Could not select nodes of latest Runs of action %q", name)
+	return
+}
+
+func (self runService) GetLastMeasuredAt(ids ...uuid.UUID) (measuredAt *time.Time, err error) {
+	self.logger.Trace().Int("runs", len(ids)).Msg("Getting when Runs were last measured")
+	measuredAt, err = self.runRepository.GetLastMeasuredAt(ids)
+	err = errors.WithMessage(err, "Could not select when Runs were last measured")
+	return
+}
+
+func (self runService) SnapshotAllocations(run *domain.Run) error {
+	return self.snapshotAllocations(self.runRepository, run)
+}
+
+func (self runService) snapshotAllocations(runRepository repository.RunRepository, run *domain.Run) error {
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Msg("Taking snapshot of Run's allocations")
+
+	nomadClient, err := self.nomadClient(run)
+	if err != nil {
+		return err
+	}
+
+	stubs, _, err := nomadClient.JobsAllocations(run.NomadJobID.String(), true, &nomad.QueryOptions{})
+	if err != nil {
+		if application.IsNomadNotFound(err) {
+			self.logger.Debug().Str("id", run.NomadJobID.String()).Msg("Cannot take snapshot of Run's allocations because its Nomad job is gone")
+			return nil
+		}
+		return errors.WithMessagef(err, "Could not list allocations of Nomad job with ID %q", run.NomadJobID)
+	}
+
+	allocs := make([]*nomad.Allocation, len(stubs))
+	for i, stub := range stubs {
+		if allocs[i], _, err = nomadClient.AllocationsInfo(stub.ID, &nomad.QueryOptions{}); err != nil {
+			return errors.WithMessagef(err, "Could not get allocation %q", stub.ID)
+		}
+	}
+
+	if err := runRepository.SaveAllocations(run.NomadJobID, allocs); err != nil {
+		return errors.WithMessagef(err, "Could not save allocations of Run with ID %q", run.NomadJobID)
+	}
+
+	self.logger.Trace().Str("id", run.NomadJobID.String()).Int("allocations", len(allocs)).Msg("Took snapshot of Run's allocations")
+	return nil
+}
+
+func (self runService) PurgeNomadJob(run *domain.Run) error {
+	self.logger.Debug().Str("id", run.NomadJobID.String()).Msg("Purging Nomad job of Run")
+	if nomadClient, err := self.nomadClient(run); err != nil {
+		return err
+	} else if _, _, err := nomadClient.JobsDeregister(run.NomadJobID.String(), true, &nomad.WriteOptions{}); err != nil && !application.IsNomadNotFound(err) {
+		return errors.WithMessagef(err, "Could not purge Nomad job with ID %q", run.NomadJobID)
+	}
+	if err := self.MarkNomadJobGCed(run); err != nil {
+		return err
+	}
+	self.logger.Debug().Str("id", run.NomadJobID.String()).Msg("Purged Nomad job of Run")
+	return nil
+}
+
+func (self runService) Exec(ctx context.Context, run domain.Run, opts ExecOptions) (int, error) {
+	if run.FinishedAt != nil {
+		return 0, errors.New("Run has already finished")
+	}
+	if len(opts.Command) == 0 {
+		return 0, errors.New("No command given")
+	}
+
+	nomadClient, err := self.nomadClient(&run)
+	if err != nil {
+		return 0, err
+	}
+
+	allocId := opts.AllocId
+	if allocId == "" {
+		allocs, _, err := nomadClient.JobsAllocations(run.NomadJobID.String(), false, &nomad.QueryOptions{})
+		if err != nil {
+			return 0, errors.WithMessagef(err, "Could not get allocations of Run %q", run.NomadJobID)
+		}
+		for _, alloc := range allocs {
+			if alloc.ClientStatus != nomad.AllocClientStatusRunning {
+				continue
+			}
+			if allocId != "" {
+				return 0, errors.New("Run has more than one running allocation, please choose one")
+			}
+			allocId = alloc.ID
+		}
+		if allocId == "" {
+			return 0, errors.New("Run has no running allocation")
+		}
+	}
+
+	alloc, _, err := nomadClient.AllocationsInfo(allocId, &nomad.QueryOptions{})
+	if err != nil {
+		return 0, errors.WithMessagef(err, "Could not get allocation %q", allocId)
+	}
+	// Do not allow to exec into allocations of other jobs.
+	if alloc.JobID != run.NomadJobID.String() {
+		return 0, errors.Errorf("Allocation %q does not belong to Run %q", allocId, run.NomadJobID)
+	}
+	if alloc.ClientStatus != nomad.AllocClientStatusRunning {
+		return 0, errors.Errorf("Allocation %q is not running", allocId)
+	}
+
+	task := opts.Task
+	if task == "" {
+		for name, state := range alloc.TaskStates {
+			if state.State != "running" {
+				continue
+			}
+			if task != "" {
+				return 0, errors.Errorf("Allocation %q has more than one running task, please choose one", allocId)
+			}
+			task = name
+		}
+		if task == "" {
+			return 0, errors.Errorf("Allocation %q has no running task", allocId)
+		}
+	} else if state, exists := alloc.TaskStates[task]; !exists || state.State != "running" {
+		return 0, errors.Errorf("Task %q of allocation %q is not running", task, allocId)
+	}
+
+	self.logger.Info().
+		Str("id", run.NomadJobID.String()).
+		Str("allocation", allocId).
+		Str("task", task).
+		Strs("command", opts.Command).
+		Msg("Executing command in Run's task")
+
+	exitCode, err := nomadClient.AllocationsExec(ctx, alloc, task, opts.Tty, opts.Command, opts.Stdin, opts.Stdout, opts.Stderr, opts.TerminalSize, &nomad.QueryOptions{})
+	return exitCode, errors.WithMessagef(err, "Could not execute command in task %q of allocation %q", task, allocId)
+}
+
 func (self runService) GrafanaUrls(allocs []*nomad.Allocation, to *time.Time) (map[string]*url.URL, error) {
 	grafanaUrls := map[string]*url.URL{}
 
@@ -339,11 +1127,24 @@ type grafanaExploreRange struct {
 	To   string `json:"to"`
 }
 
-func (self runService) metrics(allocs []*nomad.Allocation, to *time.Time, queryPattern string, labelFunc func(float64) template.HTML) (map[string][]*VMMetric, error) {
-	vmUrl, err := url.Parse(self.victoriaMetricsAddr + "/api/v1/query_range")
+// How long to wait for VictoriaMetrics so that pages do not hang on it.
+const victoriaMetricsTimeout = 10 * time.Second
+
+func (self runService) queryVictoriaMetrics(vmUrl *url.URL) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, vmUrl.String(), http.NoBody)
 	if err != nil {
 		return nil, err
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), victoriaMetricsTimeout)
+	defer cancel()
+
+	_, body, err := self.victoriaMetrics.Do(ctx, req)
+	return body, errors.WithMessage(err, "Failed to talk with VictoriaMetrics")
+}
+
+func (self runService) metrics(allocs []*nomad.Allocation, to *time.Time, queryPattern string, labelFunc func(float64) template.HTML) (map[string][]*VMMetric, error) {
+	vmUrl := self.victoriaMetrics.URL("/api/v1/query_range", nil)
 	query := vmUrl.Query()
 
 	metrics := map[string][]*VMMetric{}
@@ -368,13 +1169,13 @@ func (self runService) metrics(allocs []*nomad.Allocation, to *time.Time, queryP
 		query.Set("end", strconv.FormatInt(to.Unix()+60, 10))
 		query.Set("query", fmt.Sprintf(queryPattern, alloc.ID))
 		vmUrl.RawQuery = query.Encode()
-		res, err := http.Get(vmUrl.String())
+		body, err := self.queryVictoriaMetrics(vmUrl)
 		if err != nil {
 			return nil, err
 		}
 
 		metric := vmResponse{}
-		if err := json.NewDecoder(res.Body).Decode(&metric); err != nil {
+		if err := json.Unmarshal(body, &metric); err != nil {
 			return nil, err
 		}
 		if metric.Status != "success" {
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type RunAnnotationService interface {
+	WithQuerier(config.PgxIface) RunAnnotationService
+
+	// Returns nil if the Run's log was not scanned yet.
+	GetByRunId(uuid.UUID) (*domain.RunAnnotations, error)
+	GetFinishedWithoutAnnotations(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	// Scans the log of the finished Run with the log matchers of its action
+	// and saves the annotations they made.
+	Annotate(domain.Run) (*domain.RunAnnotations, error)
+}
+
+type runAnnotationService struct {
+	logger                  zerolog.Logger
+	runAnnotationRepository repository.RunAnnotationRepository
+	runService              RunService
+	actionService           *ActionService
+}
+
+func NewRunAnnotationService(db config.PgxIface, runService RunService, actionService *ActionService, logger *zerolog.Logger) RunAnnotationService {
+	return &runAnnotationService{
+		logger:                  logger.With().Str("component", "RunAnnotationService").Logger(),
+		runAnnotationRepository: persistence.NewRunAnnotationRepository(db),
+		runService:              runService,
+		actionService:           actionService,
+	}
+}
+
+func (self runAnnotationService) WithQuerier(querier config.PgxIface) RunAnnotationService {
+	return &runAnnotationService{
+		logger:                  self.logger,
+		runAnnotationRepository: self.runAnnotationRepository.WithQuerier(querier),
+		runService:              self.runService.WithQuerier(querier),
+		actionService:           self.actionService,
+	}
+}
+
+func (self runAnnotationService) GetByRunId(id uuid.UUID) (annotations *domain.RunAnnotations, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting annotations of Run")
+	annotations, err = self.runAnnotationRepository.GetByRunId(id)
+	err = errors.WithMessagef(err, "Could not select annotations of Run with ID %q", id)
+	return
+}
+
+func (self runAnnotationService) GetFinishedWithoutAnnotations(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	self.logger.Trace().Time("finished-before", finishedBefore).Int("limit", limit).Msg("Getting Runs to annotate")
+	runs, err = self.runAnnotationRepository.GetFinishedWithoutAnnotations(finishedBefore, limit)
+	err = errors.WithMessage(err, "Could not select Runs to annotate")
+	return
+}
+
+func (self runAnnotationService) Annotate(run domain.Run) (*domain.RunAnnotations, error) {
+	self.logger.Debug().Stringer("id", run.NomadJobID).Msg("Annotating Run")
+
+	action, err := (*self.actionService).GetByRunId(run.NomadJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := domain.RunAnnotations{RunId: run.NomadJobID, Annotations: []domain.RunAnnotation{}}
+
+	// Runs whose matchers are invalid are marked as scanned
+	// so that they are not tried again and again.
+	matchers, err := action.LogMatchers()
+	if err != nil {
+		self.logger.Warn().Err(err).Stringer("id", run.NomadJobID).Msg("Not annotating Run")
+	}
+
+	annotator, err := domain.NewLogAnnotator(matchers, run.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	page := LokiPage{Direction: LokiForward, Limit: LokiMaxLimit}
+	for !annotator.Done() {
+		log, err := self.runService.JobLog(run.NomadJobID, run.CreatedAt, run.FinishedAt, page)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not get log of Run with ID %q", run.NomadJobID)
+		}
+
+		for _, line := range log.Log {
+			for _, annotation := range annotator.Annotate(line.Text) {
+				annotation.Time = line.Time
+				annotation.AllocId = line.AllocId
+				annotation.TaskGroup = line.TaskGroup
+				annotation.Task = line.Task
+				annotation.Line = NewLokiAnchor(line).String()
+				annotations.Annotations = append(annotations.Annotations, annotation)
+			}
+		}
+
+		if log.Next == nil {
+			break
+		}
+		page.Cursor = log.Next
+	}
+
+	if err := self.runAnnotationRepository.Save(&annotations); err != nil {
+		return nil, errors.WithMessagef(err, "Could not save annotations of Run with ID %q", run.NomadJobID)
+	}
+
+	self.logger.Debug().Stringer("id", run.NomadJobID).Int("annotations", len(annotations.Annotations)).Msg("Annotated Run")
+	return &annotations, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type RunApprovalService interface {
+	WithQuerier(config.PgxIface) RunApprovalService
+
+	// Returns the approvals that running Runs wait for in the order they were requested.
+	GetPending() ([]domain.RunApproval, error)
+	// Returns the pending approvals that expired before the given time.
+	GetExpired(time.Time) ([]domain.RunApproval, error)
+	// Returns nil if the Run did not need approval.
+	GetByRunId(uuid.UUID) (*domain.RunApproval, error)
+	// Keeps the Run's job until someone decides whether to submit it.
+	// Must be called in a transaction.
+	Request(domain.Run, domain.ActionApproval, *nomad.Job) (*domain.RunApproval, error)
+	// Records the decision and returns the Run's job to submit if it was approved.
+	// A rejected Run is denied. Returns nil if the Run does not wait for approval.
+	// Must be called in a transaction.
+	Decide(run *domain.Run, approved bool, by, comment string) (*domain.RunApproval, *nomad.Job, error)
+	// Cancels the Run if it waits for approval.
+	// Returns false if it does not.
+	Withdraw(*domain.Run) (bool, error)
+}
+
+type runApprovalService struct {
+	logger                zerolog.Logger
+	runApprovalRepository repository.RunApprovalRepository
+	runService            RunService
+	db                    config.PgxIface
+}
+
+func NewRunApprovalService(db config.PgxIface, runService RunService, logger *zerolog.Logger) RunApprovalService {
+	return &runApprovalService{
+		logger:                logger.With().Str("component", "RunApprovalService").Logger(),
+		runApprovalRepository: persistence.NewRunApprovalRepository(db),
+		runService:            runService,
+		db:                    db,
+	}
+}
+
+func (self runApprovalService) WithQuerier(querier config.PgxIface) RunApprovalService {
+	return &runApprovalService{
+		logger:                self.logger,
+		runApprovalRepository: self.runApprovalRepository.WithQuerier(querier),
+		runService:            self.runService.WithQuerier(querier),
+		db:                    querier,
+	}
+}
+
+func (self runApprovalService) GetPending() (approvals []domain.RunApproval, err error) {
+	self.logger.Trace().Msg("Getting pending approvals")
+	approvals, err = self.runApprovalRepository.GetPending()
+	err = errors.WithMessage(err, "Could not select pending approvals")
+	return
+}
+
+func (self runApprovalService) GetExpired(at time.Time) (approvals []domain.RunApproval, err error) {
+	self.logger.Trace().Time("at", at).Msg("Getting expired approvals")
+	approvals, err = self.runApprovalRepository.GetExpired(at)
+	err = errors.WithMessage(err, "Could not select expired approvals")
+	return
+}
+
+func (self runApprovalService) GetByRunId(id uuid.UUID) (approval *domain.RunApproval, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting approval of Run")
+	approval, err = self.runApprovalRepository.GetByRunId(id)
+	err = errors.WithMessagef(err, "Could not select approval of Run %q", id)
+	return
+}
+
+func (self runApprovalService) Request(run domain.Run, actionApproval domain.ActionApproval, job *nomad.Job) (*domain.RunApproval, error) {
+	approval := domain.NewRunApproval(run, actionApproval)
+	if err := self.runApprovalRepository.Save(&approval, job); err != nil {
+		return nil, errors.WithMessagef(err, "Could not insert approval of Run %q", run.NomadJobID)
+	}
+
+	self.logger.Debug().
+		Stringer("run", run.NomadJobID).
+		Strs("approvers", approval.Approvers).
+		Msg("Requested approval")
+
+	return &approval, nil
+}
+
+func (self runApprovalService) Decide(run *domain.Run, approved bool, by, comment string) (*domain.RunApproval, *nomad.Job, error) {
+	approval, err := self.runApprovalRepository.GetByRunId(run.NomadJobID)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "Could not select approval of Run %q", run.NomadJobID)
+	}
+	if approval == nil || !approval.Pending() || run.FinishedAt != nil {
+		return nil, nil, nil
+	}
+
+	job, err := self.runApprovalRepository.GetJob(run.NomadJobID)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "Could not select job of Run %q waiting for approval", run.NomadJobID)
+	}
+
+	now := time.Now().UTC()
+	approval.Approved = &approved
+	approval.DecidedBy = &by
+	approval.DecidedAt = &now
+	if comment != "" {
+		approval.Comment = &comment
+	}
+
+	// Someone else may have decided in the meantime.
+	if decided, err := self.runApprovalRepository.Decide(approval); err != nil {
+		return nil, nil, errors.WithMessagef(err, "Could not update approval of Run %q", run.NomadJobID)
+	} else if !decided {
+		return nil, nil, nil
+	}
+
+	self.logger.Debug().
+		Stringer("run", run.NomadJobID).
+		Bool("approved", approved).
+		Str("by", by).
+		Msg("Decided on approval")
+
+	if !approved {
+		// There is no Nomad job yet so the Run ends right away.
+		return approval, nil, self.runService.Deny(run, []string{approval.Denial()})
+	}
+
+	return approval, job, nil
+}
+
+func (self runApprovalService) Withdraw(run *domain.Run) (withdrawn bool, err error) {
+	approval, err := self.runApprovalRepository.GetByRunId(run.NomadJobID)
+	if err != nil {
+		return false, errors.WithMessagef(err, "Could not select approval of Run %q", run.NomadJobID)
+	}
+	if approval == nil || !approval.Pending() {
+		return false, nil
+	}
+
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*runApprovalService)
+
+		// Nobody decided, so there is no decider.
+		now := time.Now().UTC()
+		approved := false
+		approval.Approved = &approved
+		approval.DecidedAt = &now
+
+		// The Run may have been approved in the meantime.
+		if decided, err := txSelf.runApprovalRepository.Decide(approval); err != nil {
+			return errors.WithMessagef(err, "Could not update approval of Run %q", run.NomadJobID)
+		} else if !decided {
+			return nil
+		}
+
+		self.logger.Debug().Stringer("run", run.NomadJobID).Msg("Withdrawing Run waiting for approval")
+
+		run.Status = domain.RunStatusCanceled
+		run.FinishedAt = &now
+		if err := txSelf.runService.Update(run); err != nil {
+			return err
+		}
+
+		withdrawn = true
+		return nil
+	})
+	return
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func (self runService) GetDispatches(id uuid.UUID) (dispatches []domain.RunDispatch, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting dispatches of Run")
+	dispatches, err = self.dispatchRepository.GetByRunId(id)
+	err = errors.WithMessagef(err, "Could not select dispatches of Run %q", id)
+	return
+}
+
+func (self runService) SaveDispatches(dispatches []domain.RunDispatch) error {
+	for i := range dispatches {
+		dispatch := &dispatches[i]
+		self.logger.Trace().Stringer("id", dispatch.RunId).Int("number", dispatch.Number).Msg("Saving dispatch of Run")
+		if err := self.dispatchRepository.Save(dispatch); err != nil {
+			return errors.WithMessagef(err, "Could not insert dispatch %d of Run %q", dispatch.Number, dispatch.RunId)
+		}
+	}
+	return nil
+}
+
+func (self runService) Dispatch(run *domain.Run) error {
+	self.logger.Debug().Stringer("id", run.NomadJobID).Msg("Dispatching Run's job")
+
+	dispatches, err := self.GetDispatches(run.NomadJobID)
+	if err != nil {
+		return err
+	}
+
+	nomadClient, err := self.nomadClient(run)
+	if err != nil {
+		return err
+	}
+
+	for i := range dispatches {
+		dispatch := &dispatches[i]
+		if dispatch.NomadJobID != nil {
+			continue
+		}
+
+		response, _, err := nomadClient.JobsDispatch(run.NomadJobID.String(), dispatch.Meta, dispatch.Payload, &nomad.WriteOptions{})
+		if err != nil {
+			err = errors.WithMessagef(err, "Could not dispatch Nomad job %q", run.NomadJobID)
+			if err2 := self.failUndispatched(run); err2 != nil {
+				return errors.WithMessagef(err2, "While failing undispatched jobs due to error %q", err.Error())
+			}
+			return err
+		}
+
+		dispatch.NomadJobID = &response.DispatchedJobID
+		if err := self.dispatchRepository.UpdateNomadJobId(dispatch); err != nil {
+			return errors.WithMessagef(err, "Could not update job ID of dispatch %d of Run %q", dispatch.Number, run.NomadJobID)
+		}
+
+		self.logger.Trace().
+			Stringer("id", run.NomadJobID).
+			Int("number", dispatch.Number).
+			Str("nomad-job-id", response.DispatchedJobID).
+			Msg("Dispatched Run's job")
+	}
+
+	return nil
+}
+
+// Fails the dispatches that were not dispatched yet
+// and ends the Run if none of the others are still running.
+func (self runService) failUndispatched(run *domain.Run) error {
+	if err := self.dispatchRepository.FailUndispatched(run.NomadJobID, time.Now().UTC()); err != nil {
+		return errors.WithMessagef(err, "Could not fail undispatched dispatches of Run %q", run.NomadJobID)
+	}
+
+	dispatches, err := self.GetDispatches(run.NomadJobID)
+	if err != nil {
+		return err
+	}
+
+	// No Nomad events will end the Run so end it here.
+	if status, finishedAt, done := domain.AggregateRunDispatches(dispatches); done {
+		run.Status = status
+		run.FinishedAt = &finishedAt
+		return self.End(run)
+	}
+
+	return nil
+}
+
+func (self runService) EndDispatch(nomadJobId string, status domain.RunStatus, finishedAt time.Time) (ended bool, err error) {
+	self.logger.Trace().Str("nomad-job-id", nomadJobId).Stringer("status", status).Msg("Ending dispatch")
+	ended, err = self.dispatchRepository.End(nomadJobId, status, finishedAt)
+	err = errors.WithMessagef(err, "Could not end dispatch with Nomad job ID %q", nomadJobId)
+	return
+}
+
+// Stops the dispatched jobs that are still running.
+func (self runService) stopDispatches(run *domain.Run) error {
+	dispatches, err := self.GetDispatches(run.NomadJobID)
+	if err != nil || len(dispatches) == 0 {
+		return err
+	}
+
+	nomadClient, err := self.nomadClient(run)
+	if err != nil {
+		return err
+	}
+
+	for _, dispatch := range dispatches {
+		if dispatch.NomadJobID == nil || dispatch.FinishedAt != nil {
+			continue
+		}
+		if _, _, err := nomadClient.JobsDeregister(*dispatch.NomadJobID, false, &nomad.WriteOptions{}); err != nil {
+			return errors.WithMessagef(err, "Failed to deregister dispatched job %q", *dispatch.NomadJobID)
+		}
+	}
+
+	return nil
+}
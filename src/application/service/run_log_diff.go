@@ -0,0 +1,77 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Logs are only compared up to this many lines.
+const LogDiffLineLimit = 20_000
+
+func (self runService) LogDiff(previous, run domain.Run, context int) (domain.LogDiff, error) {
+	self.logger.Trace().
+		Stringer("id", run.NomadJobID).
+		Stringer("previous", previous.NomadJobID).
+		Msg("Comparing logs of Runs")
+
+	previousLog, previousTruncated, err := self.logDiffLines(previous)
+	if err != nil {
+		return domain.LogDiff{}, err
+	}
+
+	log, truncated, err := self.logDiffLines(run)
+	if err != nil {
+		return domain.LogDiff{}, err
+	}
+
+	diff := domain.DiffLogs(previousLog, log, context)
+	diff.Truncated = previousTruncated || truncated
+	return diff, nil
+}
+
+// Returns the lines of the Run's log grouped by task
+// as those of different tasks may interleave differently each time.
+func (self runService) logDiffLines(run domain.Run) ([]string, bool, error) {
+	log := LokiLog{}
+	truncated := false
+
+	page := LokiPage{Direction: LokiForward, Limit: LokiMaxLimit}
+	for {
+		logPage, err := self.JobLog(run.NomadJobID, run.CreatedAt, run.FinishedAt, page)
+		if err != nil {
+			return nil, false, errors.WithMessagef(err, "Could not get log of Run with ID %q", run.NomadJobID)
+		}
+		log = append(log, logPage.Log...)
+
+		if len(log) >= LogDiffLineLimit {
+			truncated = len(log) > LogDiffLineLimit || logPage.Next != nil
+			log = log[:LogDiffLineLimit]
+			break
+		}
+		if logPage.Next == nil {
+			break
+		}
+		page.Cursor = logPage.Next
+	}
+
+	sort.SliceStable(log, func(i, j int) bool {
+		if log[i].TaskGroup != log[j].TaskGroup {
+			return log[i].TaskGroup < log[j].TaskGroup
+		}
+		return log[i].Task < log[j].Task
+	})
+
+	lines := make([]string, len(log))
+	for i, line := range log {
+		if line.Task != "" {
+			lines[i] = line.TaskGroup + "." + line.Task + ": " + line.Text
+		} else {
+			lines[i] = line.Text
+		}
+	}
+
+	return lines, truncated, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Resource usage of an allocation during a window of time.
+type LogMetricsWindow struct {
+	AllocId string    `json:"alloc_id"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	// Average number of cores used, nil if there were no samples.
+	CPU *float64 `json:"cpu"`
+	// Maximum number of bytes used, nil if there were no samples.
+	Memory *float64 `json:"memory"`
+}
+
+const (
+	// Maximum number of windows the time range of a log is split into.
+	logMetricsWindows = 50
+	// Metrics are scraped every 10 seconds so shorter windows would mostly be empty.
+	logMetricsMinWindow = 10 * time.Second
+)
+
+func (self runService) LogMetrics(log LokiLog) ([]LogMetricsWindow, error) {
+	windows := []LogMetricsWindow{}
+	if len(log) == 0 {
+		return windows, nil
+	}
+
+	allocIdSet := map[string]struct{}{}
+	for _, line := range log {
+		allocId := line.AllocId
+		if allocId == "" {
+			allocId = line.Labels["nomad_alloc_id"]
+		}
+		if allocId != "" {
+			allocIdSet[allocId] = struct{}{}
+		}
+	}
+	allocIds := make([]string, 0, len(allocIdSet))
+	for allocId := range allocIdSet {
+		allocIds = append(allocIds, allocId)
+	}
+	sort.Strings(allocIds)
+
+	// The log is sorted by time.
+	start := log[0].Time.Truncate(time.Second)
+	end := log[len(log)-1].Time
+
+	// Range selectors only take whole seconds.
+	step := (end.Sub(start) / logMetricsWindows).Round(time.Second)
+	if step < logMetricsMinWindow {
+		step = logMetricsMinWindow
+	}
+	numWindows := int(end.Sub(start)/step) + 1
+
+	for _, allocId := range allocIds {
+		selector := fmt.Sprintf(`{cgroup=~".*%s.*payload"}[%.fs]`, allocId, step.Seconds())
+
+		cpu, err := self.querySamples(`sum(rate(host_cgroup_cpu_usage_seconds_total`+selector+`))`, start, end, step)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not query CPU usage of allocation %q", allocId)
+		}
+
+		memory, err := self.querySamples(`sum(max_over_time(host_cgroup_memory_current_bytes`+selector+`))`, start, end, step)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not query memory usage of allocation %q", allocId)
+		}
+
+		allocWindows := make([]LogMetricsWindow, numWindows)
+		for i := range allocWindows {
+			window := &allocWindows[i]
+			window.AllocId = allocId
+			window.Start = start.Add(time.Duration(i) * step)
+			window.End = window.Start.Add(step)
+		}
+
+		// A sample covers the range that ends at its time
+		// so it belongs to the window its middle falls in.
+		place := func(samples map[time.Time]float64, field func(*LogMetricsWindow) **float64) {
+			for t, value := range samples {
+				i := int(math.Floor(float64(t.Add(-step/2).Sub(start)) / float64(step)))
+				if i < 0 || i >= numWindows {
+					continue
+				}
+				value := value
+				*field(&allocWindows[i]) = &value
+			}
+		}
+		place(cpu, func(window *LogMetricsWindow) **float64 { return &window.CPU })
+		place(memory, func(window *LogMetricsWindow) **float64 { return &window.Memory })
+
+		windows = append(windows, allocWindows...)
+	}
+
+	return windows, nil
+}
+
+// Returns the samples of a query that results in a single series.
+func (self runService) querySamples(query string, start, end time.Time, step time.Duration) (map[time.Time]float64, error) {
+	vmUrl := self.victoriaMetrics.URL("/api/v1/query_range", nil)
+	params := vmUrl.Query()
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Add(step).Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Add(step).Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', 0, 64))
+	vmUrl.RawQuery = params.Encode()
+
+	self.logger.Trace().Str("query", query).Time("start", start).Time("end", end).Dur("step", step).Msg("Querying metrics")
+
+	body, err := self.queryVictoriaMetrics(vmUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	response := vmResponse{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "success" {
+		return nil, errors.Errorf("Query failed with status %q", response.Status)
+	}
+
+	samples := map[time.Time]float64{}
+	for _, result := range response.Data.Result {
+		for _, v := range result.Values {
+			t, ok := v[0].(float64)
+			if !ok {
+				return nil, errors.Errorf("Expected the time of a sample to be a number but got %T", v[0])
+			}
+			str, ok := v[1].(string)
+			if !ok {
+				return nil, errors.Errorf("Expected the value of a sample to be a string but got %T", v[1])
+			}
+			f, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return nil, err
+			}
+			if math.IsNaN(f) {
+				continue
+			}
+			samples[time.Unix(int64(t), 0)] += f
+		}
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type RunMutexService interface {
+	WithQuerier(config.PgxIface) RunMutexService
+
+	GetAll() ([]domain.RunMutexState, error)
+	Get(name string) (domain.RunMutexState, error)
+	// Takes the mutex for the Run if it is free and nobody waits for it.
+	// Otherwise the job is kept until the Run's turn comes.
+	// Must be called in a transaction.
+	Acquire(run domain.Run, name string, job *nomad.Job) (bool, error)
+	// Gives the mutex to the next waiting Run if it is free.
+	// Returns that Run's job to register or nil if there is none.
+	Pass(name string) (*domain.RunMutex, *nomad.Job, error)
+	// Cancels the Run if it is waiting for a mutex.
+	// Returns false if it is not.
+	Withdraw(*domain.Run) (bool, error)
+}
+
+type runMutexService struct {
+	logger             zerolog.Logger
+	runMutexRepository repository.RunMutexRepository
+	runService         RunService
+	db                 config.PgxIface
+}
+
+func NewRunMutexService(db config.PgxIface, runService RunService, logger *zerolog.Logger) RunMutexService {
+	return &runMutexService{
+		logger:             logger.With().Str("component", "RunMutexService").Logger(),
+		runMutexRepository: persistence.NewRunMutexRepository(db),
+		runService:         runService,
+		db:                 db,
+	}
+}
+
+func (self runMutexService) WithQuerier(querier config.PgxIface) RunMutexService {
+	return &runMutexService{
+		logger:             self.logger,
+		runMutexRepository: self.runMutexRepository.WithQuerier(querier),
+		runService:         self.runService.WithQuerier(querier),
+		db:                 querier,
+	}
+}
+
+func (self runMutexService) GetAll() ([]domain.RunMutexState, error) {
+	self.logger.Trace().Msg("Getting all mutexes")
+	mutexes, err := self.runMutexRepository.GetRunning()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select mutexes")
+	}
+	return domain.NewRunMutexStates(mutexes), nil
+}
+
+func (self runMutexService) Get(name string) (domain.RunMutexState, error) {
+	self.logger.Trace().Str("name", name).Msg("Getting mutex")
+	mutexes, err := self.runMutexRepository.GetRunningByName(name)
+	if err != nil {
+		return domain.RunMutexState{}, errors.WithMessagef(err, "Could not select mutex %q", name)
+	}
+	if states := domain.NewRunMutexStates(mutexes); len(states) > 0 {
+		return states[0], nil
+	}
+	return domain.RunMutexState{Name: name, Waiting: []domain.RunMutex{}}, nil
+}
+
+func (self runMutexService) Acquire(run domain.Run, name string, job *nomad.Job) (bool, error) {
+	if err := self.runMutexRepository.Lock(name); err != nil {
+		return false, errors.WithMessagef(err, "Could not lock mutex %q", name)
+	}
+
+	mutexes, err := self.runMutexRepository.GetRunningByName(name)
+	if err != nil {
+		return false, errors.WithMessagef(err, "Could not select mutex %q", name)
+	}
+
+	mutex := domain.RunMutex{RunId: run.NomadJobID, Name: name}
+	free := len(mutexes) == 0
+	if free {
+		now := time.Now().UTC()
+		mutex.AcquiredAt = &now
+		job = nil
+	}
+
+	if err := self.runMutexRepository.Save(&mutex, job); err != nil {
+		return false, errors.WithMessagef(err, "Could not insert mutex %q of Run %q", name, run.NomadJobID)
+	}
+
+	self.logger.Debug().
+		Str("name", name).
+		Stringer("run", run.NomadJobID).
+		Bool("acquired", free).
+		Int("waiting", len(mutexes)).
+		Msg("Requested mutex")
+
+	return free, nil
+}
+
+func (self runMutexService) Pass(name string) (mutex *domain.RunMutex, job *nomad.Job, err error) {
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*runMutexService)
+
+		if err := txSelf.runMutexRepository.Lock(name); err != nil {
+			return errors.WithMessagef(err, "Could not lock mutex %q", name)
+		}
+
+		mutexes, err := txSelf.runMutexRepository.GetRunningByName(name)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not select mutex %q", name)
+		}
+		// Holders are sorted first.
+		if len(mutexes) == 0 || mutexes[0].Held() {
+			return nil
+		}
+		next := mutexes[0]
+
+		if job, err = txSelf.runMutexRepository.GetJob(next.RunId); err != nil {
+			return errors.WithMessagef(err, "Could not select job of Run %q waiting for mutex %q", next.RunId, name)
+		}
+
+		now := time.Now().UTC()
+		if err := txSelf.runMutexRepository.Acquire(next.RunId, now); err != nil {
+			return errors.WithMessagef(err, "Could not pass mutex %q to Run %q", name, next.RunId)
+		}
+		next.AcquiredAt = &now
+		mutex = &next
+
+		return nil
+	})
+
+	if mutex != nil {
+		self.logger.Debug().Str("name", name).Stringer("run", mutex.RunId).Msg("Passed mutex")
+	}
+
+	return
+}
+
+func (self runMutexService) Withdraw(run *domain.Run) (withdrawn bool, err error) {
+	mutex, err := self.runMutexRepository.GetByRunId(run.NomadJobID)
+	if err != nil {
+		return false, errors.WithMessagef(err, "Could not select mutex of Run %q", run.NomadJobID)
+	}
+	if mutex == nil || mutex.Held() {
+		return false, nil
+	}
+
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*runMutexService)
+
+		// The mutex may have been passed to the Run in the meantime.
+		if err := txSelf.runMutexRepository.Lock(mutex.Name); err != nil {
+			return errors.WithMessagef(err, "Could not lock mutex %q", mutex.Name)
+		}
+		if mutex, err := txSelf.runMutexRepository.GetByRunId(run.NomadJobID); err != nil {
+			return errors.WithMessagef(err, "Could not select mutex of Run %q", run.NomadJobID)
+		} else if mutex == nil || mutex.Held() {
+			return nil
+		}
+
+		self.logger.Debug().Str("name", mutex.Name).Stringer("run", run.NomadJobID).Msg("Withdrawing Run waiting for mutex")
+
+		if err := txSelf.runMutexRepository.Delete(run.NomadJobID); err != nil {
+			return errors.WithMessagef(err, "Could not delete mutex of Run %q", run.NomadJobID)
+		}
+
+		// There is no Nomad job yet so the Run ends right away.
+		now := time.Now().UTC()
+		run.Status = domain.RunStatusCanceled
+		run.FinishedAt = &now
+		if err := txSelf.runService.Update(run); err != nil {
+			return err
+		}
+
+		withdrawn = true
+		return nil
+	})
+	return
+}
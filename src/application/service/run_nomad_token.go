@@ -0,0 +1,158 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/application"
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type RunNomadTokenService interface {
+	WithQuerier(config.PgxIface) RunNomadTokenService
+
+	// Returns the tokens minted for the Run in the order they were minted.
+	GetByRunId(uuid.UUID) ([]domain.RunNomadToken, error)
+	// Creates a Nomad ACL token for the Run on the cluster
+	// and returns it along with its secret.
+	Mint(domain.Run, application.NomadCluster, domain.ActionNomadToken) (*domain.RunNomadToken, string, error)
+	// Mints another token for the Run on the cluster it is placed on
+	// so that it can keep working after its first token expired.
+	// Previous tokens stay valid until they expire
+	// as other tasks of the Run may still use them.
+	Renew(domain.Run, domain.ActionNomadToken) (*domain.RunNomadToken, string, error)
+	// Revokes the tokens that expired or whose Run ended.
+	// Returns how many were revoked.
+	RevokeExpired() (int, error)
+}
+
+type runNomadTokenService struct {
+	logger                  zerolog.Logger
+	runNomadTokenRepository repository.RunNomadTokenRepository
+	nomadClusters           application.NomadClusters
+	// How long tokens are valid unless their action says otherwise.
+	ttl time.Duration
+}
+
+func NewRunNomadTokenService(db config.PgxIface, nomadClusters application.NomadClusters, ttl time.Duration, logger *zerolog.Logger) RunNomadTokenService {
+	return &runNomadTokenService{
+		logger:                  logger.With().Str("component", "RunNomadTokenService").Logger(),
+		runNomadTokenRepository: persistence.NewRunNomadTokenRepository(db),
+		nomadClusters:           nomadClusters,
+		ttl:                     ttl,
+	}
+}
+
+func (self runNomadTokenService) WithQuerier(querier config.PgxIface) RunNomadTokenService {
+	return &runNomadTokenService{
+		logger:                  self.logger,
+		runNomadTokenRepository: self.runNomadTokenRepository.WithQuerier(querier),
+		nomadClusters:           self.nomadClusters,
+		ttl:                     self.ttl,
+	}
+}
+
+func (self runNomadTokenService) GetByRunId(id uuid.UUID) (tokens []domain.RunNomadToken, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting Nomad tokens of Run")
+	tokens, err = self.runNomadTokenRepository.GetByRunId(id)
+	err = errors.WithMessagef(err, "Could not select Nomad tokens of Run %q", id)
+	return
+}
+
+func (self runNomadTokenService) Mint(run domain.Run, cluster application.NomadCluster, actionToken domain.ActionNomadToken) (*domain.RunNomadToken, string, error) {
+	ttl := actionToken.TTL
+	if ttl == 0 {
+		ttl = self.ttl
+	}
+
+	// Not global so that it only works on the cluster the Run is placed on.
+	nomadToken, _, err := cluster.ACLTokensCreate(&nomad.ACLToken{
+		Name:     domain.RunNomadTokenName(run.NomadJobID),
+		Type:     "client",
+		Policies: actionToken.Policies,
+	}, &nomad.WriteOptions{})
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "Could not create Nomad token for Run %q on Nomad cluster %q", run.NomadJobID, cluster.Name)
+	}
+
+	token := domain.RunNomadToken{
+		AccessorId:   nomadToken.AccessorID,
+		RunId:        run.NomadJobID,
+		NomadCluster: cluster.Name,
+		Policies:     actionToken.Policies,
+		ExpiresAt:    time.Now().Add(ttl).UTC(),
+	}
+	if err := self.runNomadTokenRepository.Save(&token); err != nil {
+		// Do not leave a token behind that would never be revoked.
+		if _, deleteErr := cluster.ACLTokensDelete(nomadToken.AccessorID, &nomad.WriteOptions{}); deleteErr != nil {
+			self.logger.Err(deleteErr).Str("accessor-id", nomadToken.AccessorID).Msg("Could not delete Nomad token that could not be saved")
+		}
+		return nil, "", errors.WithMessagef(err, "Could not insert Nomad token of Run %q", run.NomadJobID)
+	}
+
+	self.logger.Debug().
+		Stringer("run", run.NomadJobID).
+		Str("nomad-cluster", cluster.Name).
+		Str("accessor-id", token.AccessorId).
+		Time("expires-at", token.ExpiresAt).
+		Msg("Minted Nomad token")
+
+	return &token, nomadToken.SecretID, nil
+}
+
+func (self runNomadTokenService) Renew(run domain.Run, actionToken domain.ActionNomadToken) (*domain.RunNomadToken, string, error) {
+	cluster, ok := self.nomadClusters.Get(run.NomadCluster)
+	if !ok {
+		return nil, "", errors.Errorf("Run %q is placed on unknown Nomad cluster %q", run.NomadJobID, run.NomadCluster)
+	}
+	return self.Mint(run, cluster, actionToken)
+}
+
+func (self runNomadTokenService) RevokeExpired() (int, error) {
+	now := time.Now().UTC()
+
+	tokens, err := self.runNomadTokenRepository.GetRevocable(now)
+	if err != nil {
+		return 0, errors.WithMessage(err, "Could not select revocable Nomad tokens")
+	}
+
+	revoked := 0
+	for _, token := range tokens {
+		logger := self.logger.With().
+			Stringer("run", token.RunId).
+			Str("nomad-cluster", token.NomadCluster).
+			Str("accessor-id", token.AccessorId).
+			Logger()
+
+		cluster, ok := self.nomadClusters.Get(token.NomadCluster)
+		if !ok {
+			logger.Warn().Msg("Not revoking Nomad token on unknown Nomad cluster")
+			continue
+		}
+
+		if _, err := cluster.ACLTokensDelete(token.AccessorId, &nomad.WriteOptions{}); err != nil && !application.IsNomadNotFound(err) {
+			if application.IsNomadUnreachable(err) {
+				// Try again next time.
+				logger.Warn().Err(err).Msg("Could not revoke Nomad token")
+				continue
+			}
+			return revoked, errors.WithMessagef(err, "Could not delete Nomad token %q on Nomad cluster %q", token.AccessorId, token.NomadCluster)
+		}
+
+		if err := self.runNomadTokenRepository.Revoke(token.AccessorId, now); err != nil {
+			return revoked, errors.WithMessagef(err, "Could not update Nomad token %q", token.AccessorId)
+		}
+
+		logger.Debug().Msg("Revoked Nomad token")
+		revoked++
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type RunQueueService interface {
+	WithQuerier(config.PgxIface) RunQueueService
+
+	// Whether Runs of the action wait in the queue,
+	// either because the number of Runs is limited
+	// or because a maintenance window holds them.
+	Queues(domain.Action) bool
+	// Returns the queued Runs in the order their jobs would be submitted
+	// if there were enough room, with the maintenance windows that hold them.
+	GetAll() ([]domain.RunQueueEntry, error)
+	// Keeps the job until the Run's turn comes.
+	// Must be called in a transaction.
+	Enqueue(run domain.Run, job *nomad.Job) error
+	// Takes as many Runs off the queue as there is room for,
+	// except those held by maintenance windows,
+	// and returns their jobs to register in the same order.
+	Schedule() ([]domain.RunQueueEntry, []*nomad.Job, error)
+	// Cancels the Run if it is queued.
+	// Returns false if it is not.
+	Withdraw(*domain.Run) (bool, error)
+	// Lets the Run skip maintenance windows, like for an emergency.
+	// Returns false if it is not queued.
+	Override(run uuid.UUID, by, reason string) (bool, error)
+}
+
+type runQueueService struct {
+	logger             zerolog.Logger
+	runQueueRepository repository.RunQueueRepository
+	runService         RunService
+	maintenanceService MaintenanceService
+	// Zero if the number of Runs is not limited.
+	limit   int
+	weights domain.RunQueueWeights
+	db      config.PgxIface
+}
+
+func NewRunQueueService(db config.PgxIface, runService RunService, maintenanceService MaintenanceService, limit int, weights domain.RunQueueWeights, logger *zerolog.Logger) RunQueueService {
+	return &runQueueService{
+		logger:             logger.With().Str("component", "RunQueueService").Logger(),
+		runQueueRepository: persistence.NewRunQueueRepository(db),
+		runService:         runService,
+		maintenanceService: maintenanceService,
+		limit:              limit,
+		weights:            weights,
+		db:                 db,
+	}
+}
+
+func (self runQueueService) WithQuerier(querier config.PgxIface) RunQueueService {
+	return &runQueueService{
+		logger:             self.logger,
+		runQueueRepository: self.runQueueRepository.WithQuerier(querier),
+		runService:         self.runService.WithQuerier(querier),
+		maintenanceService: self.maintenanceService,
+		limit:              self.limit,
+		weights:            self.weights,
+		db:                 querier,
+	}
+}
+
+func (self runQueueService) GetAll() ([]domain.RunQueueEntry, error) {
+	self.logger.Trace().Msg("Getting queue")
+	queue, err := self.runQueueRepository.GetAll()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select queue")
+	}
+	usage, err := self.runQueueRepository.GetUsage()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not select running Runs")
+	}
+	queue = domain.ScheduleRunQueue(queue, usage, self.weights, len(queue))
+	for i := range queue {
+		queue[i].Hold = self.hold(queue[i])
+	}
+	return queue, nil
+}
+
+func (self runQueueService) Queues(action domain.Action) bool {
+	return self.limit > 0 || self.maintenanceService.Hold(action.Project(), action.Name) != nil
+}
+
+// Returns nil if the Run may be submitted.
+func (self runQueueService) hold(entry domain.RunQueueEntry) *domain.RunQueueHold {
+	if entry.OverriddenBy != nil {
+		return nil
+	}
+	return self.maintenanceService.Hold(entry.Project, entry.ActionName)
+}
+
+func (self runQueueService) Enqueue(run domain.Run, job *nomad.Job) error {
+	entry := domain.RunQueueEntry{RunId: run.NomadJobID}
+	if err := self.runQueueRepository.Save(&entry, job); err != nil {
+		return errors.WithMessagef(err, "Could not insert Run %q into queue", run.NomadJobID)
+	}
+
+	self.logger.Debug().Stringer("run", run.NomadJobID).Msg("Queued Run")
+
+	return nil
+}
+
+func (self runQueueService) Schedule() (entries []domain.RunQueueEntry, jobs []*nomad.Job, err error) {
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*runQueueService)
+
+		if err := txSelf.runQueueRepository.Lock(); err != nil {
+			return errors.WithMessage(err, "Could not lock queue")
+		}
+
+		all, err := txSelf.runQueueRepository.GetAll()
+		if err != nil {
+			return errors.WithMessage(err, "Could not select queue")
+		}
+
+		queue := []domain.RunQueueEntry{}
+		for _, entry := range all {
+			if self.hold(entry) == nil {
+				queue = append(queue, entry)
+			}
+		}
+		if len(queue) == 0 {
+			return nil
+		}
+
+		usage, err := txSelf.runQueueRepository.GetUsage()
+		if err != nil {
+			return errors.WithMessage(err, "Could not select running Runs")
+		}
+
+		slots := len(queue)
+		if self.limit > 0 {
+			slots = self.limit
+			for _, u := range usage {
+				slots -= u.Running
+			}
+		}
+
+		self.logger.Trace().Int("queued", len(all)).Int("held", len(all)-len(queue)).Int("slots", slots).Msg("Scheduling queue")
+
+		for _, entry := range domain.ScheduleRunQueue(queue, usage, self.weights, slots) {
+			job, err := txSelf.runQueueRepository.Take(entry.RunId)
+			if err != nil {
+				return errors.WithMessagef(err, "Could not take Run %q off the queue", entry.RunId)
+			}
+			if job == nil {
+				continue
+			}
+
+			entries = append(entries, entry)
+			jobs = append(jobs, job)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		self.logger.Debug().
+			Stringer("run", entry.RunId).
+			Str("project", entry.Project).
+			Str("action", entry.ActionName).
+			Msg("Took Run off the queue")
+	}
+
+	return
+}
+
+func (self runQueueService) Withdraw(run *domain.Run) (withdrawn bool, err error) {
+	err = self.db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		txSelf := self.WithQuerier(tx).(*runQueueService)
+
+		// The Run may be taken off the queue in the meantime.
+		if err := txSelf.runQueueRepository.Lock(); err != nil {
+			return errors.WithMessage(err, "Could not lock queue")
+		}
+		if job, err := txSelf.runQueueRepository.Take(run.NomadJobID); err != nil {
+			return errors.WithMessagef(err, "Could not take Run %q off the queue", run.NomadJobID)
+		} else if job == nil {
+			return nil
+		}
+
+		self.logger.Debug().Stringer("run", run.NomadJobID).Msg("Withdrawing queued Run")
+
+		// There is no Nomad job yet so the Run ends right away.
+		now := time.Now().UTC()
+		run.Status = domain.RunStatusCanceled
+		run.FinishedAt = &now
+		if err := txSelf.runService.Update(run); err != nil {
+			return err
+		}
+
+		withdrawn = true
+		return nil
+	})
+	return
+}
+
+func (self runQueueService) Override(run uuid.UUID, by, reason string) (bool, error) {
+	overridden, err := self.runQueueRepository.Override(run, by, reason)
+	if err != nil {
+		return false, errors.WithMessagef(err, "Could not override maintenance windows for Run %q", run)
+	}
+	if overridden {
+		self.logger.Info().Stringer("run", run).Str("by", by).Str("reason", reason).Msg("Overrode maintenance windows for Run")
+	}
+	return overridden, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/infrastructure/memory/fixture"
+)
+
+func newTestRunService(f *fixture.Fixture) runService {
+	return runService{
+		logger:        zerolog.Nop(),
+		runRepository: f.Runs,
+		nomadEventService: &nomadEventService{
+			logger:               zerolog.Nop(),
+			nomadEventRepository: f.NomadEvents,
+		},
+	}
+}
+
+func TestGetLastActivity(t *testing.T) {
+	t.Parallel()
+
+	f := fixture.New()
+	run := f.ActionRun(f.Action("build"))
+	service := newTestRunService(f)
+
+	// given
+	modified := run.CreatedAt.Add(time.Minute)
+	f.AllocationEvent(run, nomad.Allocation{ID: "a", ModifyTime: modified.UnixNano()})
+
+	// when
+	last, err := service.GetLastActivity(run, run.CreatedAt)
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, modified.UnixNano(), last.UnixNano())
+
+	// given
+	event := run.CreatedAt.Add(time.Hour)
+	f.AllocationEvent(run, nomad.Allocation{
+		ID:         "a",
+		ModifyTime: modified.UnixNano(),
+		TaskStates: map[string]*nomad.TaskState{
+			"task": {Events: []*nomad.TaskEvent{{Time: event.UnixNano()}}},
+		},
+	})
+
+	// when
+	last, err = service.GetLastActivity(run, run.CreatedAt)
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, event.UnixNano(), last.UnixNano(), "task events of the latest allocation count")
+}
+
+func TestGetAllocationsPrefersSnapshots(t *testing.T) {
+	t.Parallel()
+
+	f := fixture.New()
+	run := f.ActionRun(f.Action("build"))
+	service := newTestRunService(f)
+
+	// given
+	f.AllocationEvent(run, nomad.Allocation{ID: "event"})
+
+	// when
+	allocs, err := service.GetAllocations(run)
+
+	// then
+	assert.Nil(t, err)
+	assert.Len(t, allocs, 1)
+	assert.Equal(t, "event", allocs[0].ID)
+
+	// given
+	assert.Nil(t, f.Runs.SaveAllocations(run.NomadJobID, []*nomad.Allocation{{ID: "snapshot"}}))
+
+	// when
+	allocs, err = service.GetAllocations(run)
+
+	// then
+	assert.Nil(t, err)
+	assert.Len(t, allocs, 1)
+	assert.Equal(t, "snapshot", allocs[0].ID)
+}
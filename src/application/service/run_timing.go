@@ -0,0 +1,90 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type RunTimingService interface {
+	WithQuerier(config.PgxIface) RunTimingService
+
+	GetByRunId(uuid.UUID) (*domain.RunTiming, error)
+	GetUnmeasured(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	// Derives how long the Run spent in each phase
+	// from the Nomad events of its job and saves it.
+	Measure(domain.Run) (*domain.RunTiming, error)
+	// Aggregates the phases of the Runs of actions
+	// with the given name that finished since then.
+	GetAggregates(actionName string, since time.Time) ([]domain.RunTimingAggregate, error)
+}
+
+type runTimingService struct {
+	logger              zerolog.Logger
+	runTimingRepository repository.RunTimingRepository
+	nomadEventService   NomadEventService
+}
+
+func NewRunTimingService(db config.PgxIface, nomadEventService NomadEventService, logger *zerolog.Logger) RunTimingService {
+	return &runTimingService{
+		logger:              logger.With().Str("component", "RunTimingService").Logger(),
+		runTimingRepository: persistence.NewRunTimingRepository(db),
+		nomadEventService:   nomadEventService,
+	}
+}
+
+func (self runTimingService) WithQuerier(querier config.PgxIface) RunTimingService {
+	return &runTimingService{
+		logger:              self.logger,
+		runTimingRepository: self.runTimingRepository.WithQuerier(querier),
+		nomadEventService:   self.nomadEventService.WithQuerier(querier),
+	}
+}
+
+func (self runTimingService) GetByRunId(runId uuid.UUID) (timing *domain.RunTiming, err error) {
+	self.logger.Trace().Stringer("run-id", runId).Msg("Getting timing of Run")
+	timing, err = self.runTimingRepository.GetByRunId(runId)
+	err = errors.WithMessagef(err, "Could not select timing of Run with ID %q", runId)
+	return
+}
+
+func (self runTimingService) GetUnmeasured(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	self.logger.Trace().Time("finished-before", finishedBefore).Int("limit", limit).Msg("Getting Runs without timing")
+	runs, err = self.runTimingRepository.GetUnmeasured(finishedBefore, limit)
+	err = errors.WithMessage(err, "Could not select Runs without timing")
+	return
+}
+
+func (self runTimingService) Measure(run domain.Run) (*domain.RunTiming, error) {
+	events, err := self.nomadEventService.GetByJobId(run.NomadJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	timing, err := domain.NewRunTiming(run, events)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not derive timing of Run %q", run.NomadJobID)
+	}
+
+	self.logger.Trace().Stringer("run-id", run.NomadJobID).Msg("Saving timing of Run")
+	if err := self.runTimingRepository.Save(&timing); err != nil {
+		return nil, errors.WithMessagef(err, "Could not save timing of Run %q", run.NomadJobID)
+	}
+	self.logger.Trace().Stringer("run-id", run.NomadJobID).Msg("Saved timing of Run")
+
+	return &timing, nil
+}
+
+func (self runTimingService) GetAggregates(actionName string, since time.Time) (aggregates []domain.RunTimingAggregate, err error) {
+	self.logger.Trace().Str("action-name", actionName).Time("since", since).Msg("Aggregating timing of Runs")
+	aggregates, err = self.runTimingRepository.GetAggregates(actionName, since)
+	err = errors.WithMessagef(err, "Could not aggregate timing of Runs of action %q", actionName)
+	return
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type SavedQueryService interface {
+	WithQuerier(config.PgxIface) SavedQueryService
+
+	// Returns the saved queries of the kind or of any kind if it is empty.
+	GetAll(domain.SavedQueryKind) ([]domain.SavedQuery, error)
+	// Returns nil if there is no saved query with the name.
+	Get(string) (*domain.SavedQuery, error)
+	Save(*domain.SavedQuery) error
+	Delete(string) error
+}
+
+type savedQueryService struct {
+	logger               zerolog.Logger
+	savedQueryRepository repository.SavedQueryRepository
+}
+
+func NewSavedQueryService(db config.PgxIface, logger *zerolog.Logger) SavedQueryService {
+	return &savedQueryService{
+		logger:               logger.With().Str("component", "SavedQueryService").Logger(),
+		savedQueryRepository: persistence.NewSavedQueryRepository(db),
+	}
+}
+
+func (self savedQueryService) WithQuerier(querier config.PgxIface) SavedQueryService {
+	return &savedQueryService{
+		logger:               self.logger,
+		savedQueryRepository: self.savedQueryRepository.WithQuerier(querier),
+	}
+}
+
+func (self savedQueryService) GetAll(kind domain.SavedQueryKind) (queries []domain.SavedQuery, err error) {
+	self.logger.Trace().Str("kind", string(kind)).Msg("Getting saved queries")
+	queries, err = self.savedQueryRepository.GetAll(kind)
+	err = errors.WithMessage(err, "Could not select saved queries")
+	return
+}
+
+func (self savedQueryService) Get(name string) (query *domain.SavedQuery, err error) {
+	self.logger.Trace().Str("name", name).Msg("Getting saved query")
+	query, err = self.savedQueryRepository.Get(name)
+	err = errors.WithMessagef(err, "Could not select saved query %q", name)
+	return
+}
+
+func (self savedQueryService) Save(query *domain.SavedQuery) error {
+	self.logger.Trace().Str("name", query.Name).Msg("Saving query")
+	if err := self.savedQueryRepository.Save(query); err != nil {
+		return errors.WithMessagef(err, "Could not save query %q", query.Name)
+	}
+	self.logger.Info().
+		Str("name", query.Name).
+		Str("kind", string(query.Kind)).
+		Str("by", query.UpdatedBy).
+		Msg("Saved query")
+	return nil
+}
+
+func (self savedQueryService) Delete(name string) error {
+	self.logger.Trace().Str("name", name).Msg("Deleting saved query")
+	if err := self.savedQueryRepository.Delete(name); err != nil {
+		return errors.WithMessagef(err, "Could not delete saved query %q", name)
+	}
+	return nil
+}
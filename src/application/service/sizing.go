@@ -0,0 +1,103 @@
+package service
+
+import (
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type SizingService interface {
+	WithQuerier(config.PgxIface) SizingService
+
+	// Returns the resources that the task groups of the action's jobs
+	// should ask for by the peak usage of its recent Runs.
+	// Task groups with too few measured Runs are left out.
+	GetRecommendations(actionName string) ([]domain.ResourceRecommendation, error)
+	// Applies the recommendations to the job if the action asks for it.
+	// Returns the names of the task groups that were resized.
+	Resize(*domain.Action, *nomad.Job) ([]string, error)
+}
+
+// How far back Runs are taken into account.
+const sizingWindow = 30 * 24 * time.Hour
+
+// How many measured Runs a task group needs for a recommendation.
+const sizingMinRuns = 5
+
+type sizingService struct {
+	logger             zerolog.Logger
+	runUsageRepository repository.RunUsageRepository
+	// Fraction of the peak usage added to recommendations.
+	headroom float64
+	// Speed of a CPU core in MHz.
+	cpuMHz int
+}
+
+func NewSizingService(db config.PgxIface, headroom float64, cpuMHz int, logger *zerolog.Logger) SizingService {
+	return &sizingService{
+		logger:             logger.With().Str("component", "SizingService").Logger(),
+		runUsageRepository: persistence.NewRunUsageRepository(db),
+		headroom:           headroom,
+		cpuMHz:             cpuMHz,
+	}
+}
+
+func (self sizingService) WithQuerier(querier config.PgxIface) SizingService {
+	return &sizingService{
+		logger:             self.logger,
+		runUsageRepository: self.runUsageRepository.WithQuerier(querier),
+		headroom:           self.headroom,
+		cpuMHz:             self.cpuMHz,
+	}
+}
+
+func (self sizingService) GetRecommendations(actionName string) ([]domain.ResourceRecommendation, error) {
+	self.logger.Trace().Str("action", actionName).Msg("Getting resource recommendations")
+
+	percentiles, err := self.runUsageRepository.GetPeakPercentiles(actionName, time.Now().Add(-sizingWindow), domain.ResourceRecommendationPercentile)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not select peak usage of action %q", actionName)
+	}
+
+	recommendations := []domain.ResourceRecommendation{}
+	for _, percentile := range percentiles {
+		if percentile.Runs < sizingMinRuns {
+			continue
+		}
+		recommendations = append(recommendations, domain.NewResourceRecommendation(percentile, self.headroom, self.cpuMHz))
+	}
+
+	return recommendations, nil
+}
+
+func (self sizingService) Resize(action *domain.Action, job *nomad.Job) ([]string, error) {
+	sizing, err := action.Sizing()
+	if err != nil {
+		return nil, err
+	}
+	if sizing == nil || !sizing.Apply {
+		return nil, nil
+	}
+
+	recommendations, err := self.GetRecommendations(action.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	resized := sizing.Resize(job, recommendations)
+	if len(resized) > 0 {
+		self.logger.Debug().
+			Str("action", action.Name).
+			Strs("task-groups", resized).
+			Msg("Resized job")
+	}
+
+	return resized, nil
+}
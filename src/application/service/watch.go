@@ -0,0 +1,145 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+	"github.com/input-output-hk/cicero/src/infrastructure/persistence"
+)
+
+type WatchService interface {
+	WithQuerier(config.PgxIface) WatchService
+
+	GetById(uuid.UUID) (*domain.Watch, error)
+	GetByUser(user string) ([]domain.Watch, error)
+	// Returns the user's watches with the watched Run
+	// or the latest Runs of the watched action.
+	GetWatchList(user string) ([]domain.WatchListEntry, error)
+	Watch(*domain.Watch) error
+	Unwatch(uuid.UUID) error
+	GetNotifiable() ([]domain.Watch, error)
+	// Returns the watched Runs that ended since the last notification.
+	GetEndedRuns(watch domain.Watch, now time.Time) ([]domain.WatchedRun, error)
+	MarkNotified(watch domain.Watch, at time.Time) error
+}
+
+type watchService struct {
+	logger          zerolog.Logger
+	watchRepository repository.WatchRepository
+	runService      RunService
+}
+
+func NewWatchService(db config.PgxIface, runService RunService, logger *zerolog.Logger) WatchService {
+	return &watchService{
+		logger:          logger.With().Str("component", "WatchService").Logger(),
+		watchRepository: persistence.NewWatchRepository(db),
+		runService:      runService,
+	}
+}
+
+func (self watchService) WithQuerier(querier config.PgxIface) WatchService {
+	return &watchService{
+		logger:          self.logger,
+		watchRepository: self.watchRepository.WithQuerier(querier),
+		runService:      self.runService.WithQuerier(querier),
+	}
+}
+
+// How many of the latest Runs of a watched action the watch list shows.
+const watchListActionRuns = 5
+
+func (self watchService) GetById(id uuid.UUID) (watch *domain.Watch, err error) {
+	self.logger.Trace().Stringer("id", id).Msg("Getting watch by ID")
+	watch, err = self.watchRepository.GetById(id)
+	err = errors.WithMessagef(err, "Could not select watch by ID %q", id)
+	return
+}
+
+func (self watchService) GetByUser(user string) (watches []domain.Watch, err error) {
+	self.logger.Trace().Str("user", user).Msg("Getting watches of user")
+	watches, err = self.watchRepository.GetByUser(user)
+	err = errors.WithMessagef(err, "Could not select watches of %q", user)
+	return
+}
+
+func (self watchService) GetWatchList(user string) ([]domain.WatchListEntry, error) {
+	watches, err := self.GetByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.WatchListEntry, len(watches))
+	for i, watch := range watches {
+		entries[i].Watch = watch
+
+		if watch.RunId != nil {
+			run, err := self.runService.GetByNomadJobId(*watch.RunId)
+			if err != nil {
+				return nil, err
+			}
+			entries[i].Runs = []domain.Run{}
+			if run != nil {
+				entries[i].Runs = append(entries[i].Runs, *run)
+			}
+		} else {
+			if entries[i].Runs, err = self.runService.GetByFilter(
+				domain.RunFilter{Action: *watch.ActionName},
+				&repository.Page{Limit: watchListActionRuns},
+			); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+func (self watchService) Watch(watch *domain.Watch) error {
+	if err := watch.Validate(); err != nil {
+		return err
+	}
+
+	self.logger.Trace().Str("user", watch.User).Msg("Saving watch")
+	if err := self.watchRepository.Save(watch); err != nil {
+		return errors.WithMessagef(err, "Could not insert watch of %q", watch.User)
+	}
+	self.logger.Trace().Stringer("id", watch.ID).Msg("Created watch")
+	return nil
+}
+
+func (self watchService) Unwatch(id uuid.UUID) error {
+	self.logger.Trace().Stringer("id", id).Msg("Deleting watch")
+	if err := self.watchRepository.Delete(id); err != nil {
+		return errors.WithMessagef(err, "Could not delete watch %q", id)
+	}
+	return nil
+}
+
+func (self watchService) GetNotifiable() (watches []domain.Watch, err error) {
+	self.logger.Trace().Msg("Getting watches to notify")
+	watches, err = self.watchRepository.GetNotifiable()
+	err = errors.WithMessage(err, "Could not select watches to notify")
+	return
+}
+
+func (self watchService) GetEndedRuns(watch domain.Watch, now time.Time) (runs []domain.WatchedRun, err error) {
+	from := watch.Since()
+
+	self.logger.Trace().Stringer("id", watch.ID).Time("from", from).Time("to", now).Msg("Getting ended Runs of watch")
+	runs, err = self.watchRepository.GetEndedRuns(watch, from, now)
+	err = errors.WithMessagef(err, "Could not select ended Runs of watch %q", watch.ID)
+	return
+}
+
+func (self watchService) MarkNotified(watch domain.Watch, at time.Time) error {
+	if err := self.watchRepository.UpdateLastNotified(watch.ID, at); err != nil {
+		return errors.WithMessagef(err, "Could not update last notification of watch %q", watch.ID)
+	}
+	return nil
+}
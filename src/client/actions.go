@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Returns all versions of all actions.
+func (self Client) GetActions(ctx context.Context) ([]domain.Action, error) {
+	actions := []domain.Action{}
+	err := self.Request(ctx, http.MethodGet, apiPath+"/action", nil, &actions)
+	return actions, errors.WithMessage(err, "Could not get actions")
+}
+
+// Returns the latest version of each action
+// or, if active is true, only of those that are active.
+func (self Client) GetCurrentActions(ctx context.Context, active bool) ([]domain.Action, error) {
+	path := apiPath + "/action/current"
+	if active {
+		path += "?active"
+	}
+
+	actions := []domain.Action{}
+	err := self.Request(ctx, http.MethodGet, path, nil, &actions)
+	return actions, errors.WithMessage(err, "Could not get current actions")
+}
+
+// Returns nil if there is no action with that name.
+func (self Client) GetCurrentAction(ctx context.Context, name string) (*domain.Action, error) {
+	var action *domain.Action
+	err := self.Request(ctx, http.MethodGet, apiPath+"/action/current/"+url.PathEscape(name), nil, &action)
+	return action, errors.WithMessagef(err, "Could not get current action %q", name)
+}
+
+// Returns nil if there is no such action.
+func (self Client) GetAction(ctx context.Context, id uuid.UUID) (*domain.Action, error) {
+	var action *domain.Action
+	err := self.Request(ctx, http.MethodGet, apiPath+"/action/"+id.String(), nil, &action)
+	return action, errors.WithMessagef(err, "Could not get action %q", id)
+}
+
+// Creates a new version of the action with the name from the source.
+func (self Client) CreateAction(ctx context.Context, source, name string) (*domain.Action, error) {
+	action := domain.Action{}
+	err := self.Request(ctx, http.MethodPost, apiPath+"/action", map[string]interface{}{
+		"source": source,
+		"name":   name,
+	}, &action)
+	return &action, errors.WithMessagef(err, "Could not create action %q from %q", name, source)
+}
+
+// Creates a new version of each action in the source.
+func (self Client) CreateActions(ctx context.Context, source string) ([]domain.Action, error) {
+	actions := []domain.Action{}
+	err := self.Request(ctx, http.MethodPost, apiPath+"/action", map[string]interface{}{
+		"source": source,
+	}, &actions)
+	return actions, errors.WithMessagef(err, "Could not create actions from %q", source)
+}
@@ -0,0 +1,213 @@
+// Package client is a typed client for the REST API of Cicero
+// so that integrators do not have to make the HTTP requests themselves.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The version of the API this client talks to.
+const ApiVersion = "v1"
+
+const apiPath = "/api/" + ApiVersion
+
+type Client struct {
+	// Base URL of Cicero, like `http://127.0.0.1:8080`.
+	Url string
+	// Used for bearer authentication if given.
+	Token string
+	// Used for basic authentication if given and there is no token.
+	User string
+	Pass string
+
+	// Defaults to `http.DefaultClient`.
+	HttpClient *http.Client
+
+	// How often requests that can safely be repeated are retried
+	// if Cicero cannot be reached or is temporarily unavailable.
+	Retries int
+	// How long to wait before the first retry.
+	// Doubled for each one after that.
+	RetryWait time.Duration
+}
+
+// Returns a client with sensible defaults for retries.
+func New(url string) *Client {
+	return &Client{
+		Url:       url,
+		Retries:   3,
+		RetryWait: 500 * time.Millisecond,
+	}
+}
+
+// An unsuccessful response.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (self ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", self.Status, self.Body)
+}
+
+// Whether the error is a response with the given status code.
+func IsStatus(err error, statusCode int) bool {
+	var resErr ResponseError
+	return errors.As(err, &resErr) && resErr.StatusCode == statusCode
+}
+
+// Whether the error is a response telling that the resource does not exist.
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+// The path may have a query.
+func (self Client) url(path string) (*url.URL, error) {
+	u, err := url.Parse(self.Url)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Invalid URL")
+	}
+	path, u.RawQuery, _ = strings.Cut(path, "?")
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u, nil
+}
+
+func (self Client) httpClient() *http.Client {
+	if self.HttpClient != nil {
+		return self.HttpClient
+	}
+	return http.DefaultClient
+}
+
+// Sends the body as JSON and decodes the response into result if not nil.
+func (self Client) Request(ctx context.Context, method, path string, body, result interface{}) error {
+	var bodyReader io.Reader
+	header := http.Header{}
+	if body != nil {
+		bodyJson, err := json.Marshal(body)
+		if err != nil {
+			return errors.WithMessage(err, "Could not encode request body")
+		}
+		bodyReader = bytes.NewReader(bodyJson)
+		header.Set("Content-Type", "application/json")
+	}
+
+	res, err := self.Do(ctx, method, path, header, bodyReader)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if result != nil && res.StatusCode != http.StatusNoContent {
+		return errors.WithMessage(json.NewDecoder(res.Body).Decode(result), "Could not decode response body")
+	}
+	return nil
+}
+
+// Sends the body as is with the given header in addition to authentication
+// and returns the response whose body must be closed if there is no error.
+// Responses with a status of 300 or above are returned as `ResponseError`.
+// Requests are retried if their method is idempotent
+// and their body is nil or can be rewound.
+func (self Client) Do(ctx context.Context, method, path string, header http.Header, body io.Reader) (*http.Response, error) {
+	u, err := self.url(path)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := 0
+	if _, seekable := body.(io.Seeker); isIdempotent(method) && (body == nil || seekable) {
+		retries = self.Retries
+	}
+
+	wait := self.RetryWait
+	for attempt := 0; ; attempt++ {
+		res, err := self.do(ctx, method, u, header, body)
+		if attempt >= retries || !isRetryable(err) {
+			return res, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+
+		if seeker, ok := body.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, errors.WithMessage(err, "Could not rewind request body to retry")
+			}
+		}
+	}
+}
+
+func (self Client) do(ctx context.Context, method string, u *url.URL, header http.Header, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if self.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+self.Token)
+	} else if self.User != "" {
+		req.SetBasicAuth(self.User, self.Pass)
+	}
+
+	res, err := self.httpClient().Do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not connect")
+	}
+
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		resBody, _ := io.ReadAll(res.Body)
+		return nil, ResponseError{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Body:       strings.TrimSpace(string(resBody)),
+		}
+	}
+
+	return res, nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Whether the request may succeed if it is tried again.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var resErr ResponseError
+	if !errors.As(err, &resErr) {
+		// Could not connect.
+		return true
+	}
+
+	switch resErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
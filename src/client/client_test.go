@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func newTestClient(handler http.HandlerFunc) (*Client, func()) {
+	server := httptest.NewServer(handler)
+	client := New(server.URL)
+	client.RetryWait = time.Millisecond
+	return client, server.Close
+}
+
+func TestClientRetries(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	requests := 0
+	client, closeServer := newTestClient(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "/api/v1/run/"+id.String(), req.URL.Path)
+		assert.NoError(t, json.NewEncoder(w).Encode(domain.Run{NomadJobID: id}))
+	})
+	defer closeServer()
+
+	run, err := client.GetRun(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, id, run.NomadJobID)
+	assert.Equal(t, 3, requests)
+
+	requests = 0
+	_, err = client.CreateActions(context.Background(), "github.com/example/repo")
+	assert.True(t, IsStatus(err, http.StatusServiceUnavailable))
+	assert.Equal(t, 1, requests, "POST must not be retried")
+}
+
+func TestClientNotFound(t *testing.T) {
+	t.Parallel()
+
+	client, closeServer := newTestClient(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "no such Run", http.StatusNotFound)
+	})
+	defer closeServer()
+
+	run, err := client.GetRun(context.Background(), uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, run)
+
+	err = client.CancelRun(context.Background(), uuid.New(), false)
+	assert.True(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), "no such Run")
+}
+
+func TestClientEachRun(t *testing.T) {
+	t.Parallel()
+
+	const total = 7
+	client, closeServer := newTestClient(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		assert.Equal(t, "foo", query.Get("action"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		runs := []domain.Run{}
+		for i := offset; i < offset+limit && i < total; i++ {
+			runs = append(runs, domain.Run{NomadJobID: uuid.New()})
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(runs))
+	})
+	defer closeServer()
+
+	n := 0
+	assert.NoError(t, client.EachRun(context.Background(), domain.RunFilter{Action: "foo"}, 3, func(domain.Run) error {
+		n++
+		return nil
+	}))
+	assert.Equal(t, total, n)
+}
+
+func TestClientFollowFacts(t *testing.T) {
+	t.Parallel()
+
+	client, closeServer := newTestClient(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/api/v1/fact/feed", req.URL.Path)
+		since, _ := strconv.ParseInt(req.URL.Query().Get("since"), 10, 64)
+
+		feed := domain.FactFeed{Facts: []domain.Fact{}, Cursor: since}
+		if since < 2 {
+			feed.Facts = append(feed.Facts, domain.Fact{ID: uuid.New()})
+			feed.Cursor = since + 1
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(feed))
+	})
+	defer closeServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	n := 0
+	cursor, err := client.FollowFacts(ctx, 0, time.Millisecond, func(domain.Fact) error {
+		n++
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(2), cursor)
+	assert.Equal(t, 2, n)
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Returns up to limit facts created after the cursor,
+// zero for the first ones, oldest first.
+// The limit is the server's default if zero.
+func (self Client) GetFactFeed(ctx context.Context, since int64, limit int) (*domain.FactFeed, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since, 10))
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	feed := domain.FactFeed{}
+	err := self.Request(ctx, http.MethodGet, apiPath+"/fact/feed?"+query.Encode(), nil, &feed)
+	return &feed, errors.WithMessage(err, "Could not get fact feed")
+}
+
+// Calls fn for each fact created after the cursor as it is created,
+// checking for new ones every interval until the context is done.
+// Returns the cursor after the last fact given to fn
+// so that following can be resumed from there.
+func (self Client) FollowFacts(ctx context.Context, since int64, interval time.Duration, fn func(domain.Fact) error) (int64, error) {
+	for {
+		feed, err := self.GetFactFeed(ctx, since, 0)
+		if err != nil {
+			return since, err
+		}
+
+		for _, fact := range feed.Facts {
+			if err := fn(fact); err != nil {
+				return since, err
+			}
+		}
+		since = feed.Cursor
+
+		if len(feed.Facts) != 0 {
+			// There may be more right away.
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return since, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
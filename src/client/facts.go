@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func factLabelsQuery(labels domain.FactLabels) url.Values {
+	query := url.Values{}
+	if labels.Namespace != "" {
+		query.Set("namespace", labels.Namespace)
+	}
+	if labels.Name != "" {
+		query.Set("name", labels.Name)
+	}
+	for _, tag := range labels.Tags {
+		query.Add("tag", tag)
+	}
+	return query
+}
+
+// Returns the latest facts with the labels, which may be empty.
+func (self Client) GetFacts(ctx context.Context, labels domain.FactLabels, page Page) ([]domain.Fact, error) {
+	facts := []domain.Fact{}
+	err := self.Request(ctx, http.MethodGet, apiPath+"/fact?"+page.query(factLabelsQuery(labels)).Encode(), nil, &facts)
+	return facts, errors.WithMessage(err, "Could not get facts")
+}
+
+// Calls fn for each fact with the labels, latest first,
+// fetching pageSize of them at once.
+func (self Client) EachFact(ctx context.Context, labels domain.FactLabels, pageSize int, fn func(domain.Fact) error) error {
+	return eachPage(pageSize, func(page Page) (int, error) {
+		facts, err := self.GetFacts(ctx, labels, page)
+		if err != nil {
+			return 0, err
+		}
+		for _, fact := range facts {
+			if err := fn(fact); err != nil {
+				return 0, err
+			}
+		}
+		return len(facts), nil
+	})
+}
+
+// Returns the facts published by the Run.
+func (self Client) GetFactsByRun(ctx context.Context, runId uuid.UUID) ([]domain.Fact, error) {
+	facts := []domain.Fact{}
+	err := self.Request(ctx, http.MethodGet, apiPath+"/fact?"+url.Values{"run": {runId.String()}}.Encode(), nil, &facts)
+	return facts, errors.WithMessagef(err, "Could not get facts of Run %q", runId)
+}
+
+// Returns the facts that match the CUE expression, like `{ name: "foo" }`.
+func (self Client) MatchFacts(ctx context.Context, match string) ([]domain.Fact, error) {
+	res, err := self.Do(ctx, http.MethodPost, apiPath+"/fact/match", http.Header{"Content-Type": {"text/plain"}}, strings.NewReader(match))
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not match facts")
+	}
+	defer res.Body.Close()
+
+	facts := []domain.Fact{}
+	return facts, errors.WithMessage(json.NewDecoder(res.Body).Decode(&facts), "Could not decode matching facts")
+}
+
+// Returns nil if there is no such fact.
+func (self Client) GetFact(ctx context.Context, id uuid.UUID) (*domain.Fact, error) {
+	var fact *domain.Fact
+	err := self.Request(ctx, http.MethodGet, apiPath+"/fact/"+id.String(), nil, &fact)
+	return fact, errors.WithMessagef(err, "Could not get fact %q", id)
+}
+
+// Returns the binary of the fact, which must be closed.
+func (self Client) GetFactBinary(ctx context.Context, id uuid.UUID) (io.ReadCloser, error) {
+	res, err := self.Do(ctx, http.MethodGet, apiPath+"/fact/"+id.String()+"/binary", nil, nil)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not get binary of fact %q", id)
+	}
+	return res.Body, nil
+}
+
+// Publishes a fact with the value and binary, which may be nil,
+// and returns it as it was saved.
+// It is not retried as that could publish the fact twice.
+func (self Client) PublishFact(ctx context.Context, labels domain.FactLabels, value interface{}, binary io.Reader) (*domain.Fact, error) {
+	body, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bodyWriter.CloseWithError(func() error {
+			if part, err := form.CreateFormField("value"); err != nil {
+				return err
+			} else if err := json.NewEncoder(part).Encode(value); err != nil {
+				return err
+			}
+			if binary != nil {
+				if part, err := form.CreateFormFile("binary", "binary"); err != nil {
+					return err
+				} else if _, err := io.Copy(part, binary); err != nil {
+					return err
+				}
+			}
+			return form.Close()
+		}())
+	}()
+
+	res, err := self.Do(ctx, http.MethodPost, apiPath+"/fact?"+factLabelsQuery(labels).Encode(), http.Header{"Content-Type": {form.FormDataContentType()}}, body)
+	// Let the writer finish if the request ended early
+	// so that it does not read from the binary anymore.
+	body.Close()
+	<-done
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not publish fact")
+	}
+	defer res.Body.Close()
+
+	fact := domain.Fact{}
+	return &fact, errors.WithMessage(json.NewDecoder(res.Body).Decode(&fact), "Could not decode published fact")
+}
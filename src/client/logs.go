@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/application/service"
+)
+
+// Which lines of a Run's log to get.
+type LogQuery struct {
+	// Of a single task if all three are given, of the whole Run otherwise.
+	AllocId   string
+	TaskGroup string
+	Task      string
+
+	// Forward if empty.
+	Direction service.LokiDirection
+	// The server's default if zero.
+	Limit int
+	// Starts at the beginning or end of the log if nil,
+	// depending on the direction.
+	Cursor *service.LokiCursor
+	// Only lines at this severity or a higher one if not unknown.
+	Severity service.LogSeverity
+	// Collapses consecutive identical lines.
+	Collapse bool
+}
+
+func (self LogQuery) query() url.Values {
+	query := url.Values{}
+	if self.Task != "" {
+		query.Set("alloc", self.AllocId)
+		query.Set("group", self.TaskGroup)
+		query.Set("task", self.Task)
+	}
+	if self.Direction != "" {
+		query.Set("direction", strings.ToLower(string(self.Direction)))
+	}
+	if self.Limit != 0 {
+		query.Set("limit", strconv.Itoa(self.Limit))
+	}
+	if self.Cursor != nil {
+		query.Set("cursor", self.Cursor.String())
+	}
+	if self.Severity != service.LogSeverityUnknown {
+		query.Set("severity", self.Severity.String())
+	}
+	if self.Collapse {
+		query.Set("collapse", "true")
+	}
+	return query
+}
+
+// Returns one page of the Run's log.
+func (self Client) GetRunLog(ctx context.Context, runId uuid.UUID, query LogQuery) (*service.LokiLogPage, error) {
+	page := service.LokiLogPage{}
+	err := self.Request(ctx, http.MethodGet, apiPath+"/run/"+runId.String()+"/log?"+query.query().Encode(), nil, &page)
+	return &page, errors.WithMessagef(err, "Could not get log of Run %q", runId)
+}
+
+// Calls fn for each line of the Run's log starting at the query's cursor,
+// fetching one page after another in the query's direction.
+func (self Client) EachRunLogLine(ctx context.Context, runId uuid.UUID, query LogQuery, fn func(service.LokiLine) error) error {
+	for {
+		page, err := self.GetRunLog(ctx, runId, query)
+		if err != nil {
+			return err
+		}
+
+		// Pages are sorted by time regardless of direction.
+		for i := range page.Log {
+			line := page.Log[i]
+			if query.Direction == service.LokiBackward {
+				line = page.Log[len(page.Log)-1-i]
+			}
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+
+		if page.Next == nil {
+			return nil
+		}
+		query.Cursor = page.Next
+	}
+}
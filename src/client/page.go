@@ -0,0 +1,41 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// A page of a list that is paginated by offset.
+type Page struct {
+	Offset int
+	// The server's default if zero.
+	Limit int
+}
+
+// How many items are fetched at once when iterating over all pages.
+const DefaultPageSize = 100
+
+func (self Page) query(query url.Values) url.Values {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("offset", strconv.Itoa(self.Offset))
+	if self.Limit != 0 {
+		query.Set("limit", strconv.Itoa(self.Limit))
+	}
+	return query
+}
+
+// Calls fetch for each page starting at the first one
+// until it returns fewer items than the page size.
+func eachPage(pageSize int, fetch func(Page) (int, error)) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	for page := (Page{Limit: pageSize}); ; page.Offset += pageSize {
+		if n, err := fetch(page); err != nil || n < pageSize {
+			return err
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+// Returns the latest Runs that match the filter, which may be empty.
+func (self Client) GetRuns(ctx context.Context, filter domain.RunFilter, page Page) ([]domain.Run, error) {
+	runs := []domain.Run{}
+	err := self.Request(ctx, http.MethodGet, apiPath+"/run?"+page.query(filter.Query()).Encode(), nil, &runs)
+	return runs, errors.WithMessage(err, "Could not get Runs")
+}
+
+// Calls fn for each Run that matches the filter, latest first,
+// fetching pageSize of them at once.
+func (self Client) EachRun(ctx context.Context, filter domain.RunFilter, pageSize int, fn func(domain.Run) error) error {
+	return eachPage(pageSize, func(page Page) (int, error) {
+		runs, err := self.GetRuns(ctx, filter, page)
+		if err != nil {
+			return 0, err
+		}
+		for _, run := range runs {
+			if err := fn(run); err != nil {
+				return 0, err
+			}
+		}
+		return len(runs), nil
+	})
+}
+
+// Returns nil if there is no such Run.
+func (self Client) GetRun(ctx context.Context, id uuid.UUID) (*domain.Run, error) {
+	run := domain.Run{}
+	if err := self.Request(ctx, http.MethodGet, apiPath+"/run/"+id.String(), nil, &run); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessagef(err, "Could not get Run %q", id)
+	}
+	return &run, nil
+}
+
+// Cancels the Run and, if cascade is true, the Runs downstream of it.
+func (self Client) CancelRun(ctx context.Context, id uuid.UUID, cascade bool) error {
+	path := apiPath + "/run/" + id.String()
+	if cascade {
+		path += "?" + url.Values{"cascade": {"true"}}.Encode()
+	}
+	return errors.WithMessagef(self.Request(ctx, http.MethodDelete, path, nil, nil), "Could not cancel Run %q", id)
+}
@@ -0,0 +1,46 @@
+package cicero
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+type ConfigCmd struct {
+	Print *ConfigPrintCmd `arg:"subcommand:print" help:"print the effective configuration of a command in the format of the configuration file"`
+}
+
+// Parses the arguments of another command with all configuration layers applied.
+type ParseFunc func(args []string) (interface{}, error)
+
+func (cmd *ConfigCmd) Run(parse ParseFunc) error {
+	switch {
+	case cmd.Print != nil:
+		return cmd.Print.Run(parse)
+	}
+	return errors.New("No subcommand given")
+}
+
+type ConfigPrintCmd struct {
+	Redacted bool     `arg:"--redacted" help:"hide secrets like passwords and tokens"`
+	Args     []string `arg:"positional" help:"arguments of the command, give them after -- if they contain flags, defaults to start"`
+}
+
+func (cmd *ConfigPrintCmd) Run(parse ParseFunc) error {
+	args := cmd.Args
+	if len(args) == 0 {
+		args = []string{"start"}
+	}
+
+	dest, err := parse(args)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config.Effective(dest, cmd.Redacted))
+}
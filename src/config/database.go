@@ -3,6 +3,7 @@ package config
 import (
 	"context"
 	"errors"
+	"net/url"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgtype"
@@ -10,6 +11,8 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/db"
 )
 
 type PgxIface interface {
@@ -27,12 +30,25 @@ var (
 )
 
 func DBConnection(logger *zerolog.Logger, logDb bool) (PgxIface, error) {
-	url := GetenvStr("DATABASE_URL")
-	if url == "" {
+	dbUrl := GetenvStr("DATABASE_URL")
+	if dbUrl == "" {
 		return nil, errors.New("Environment variable DATABASE_URL not set or empty")
 	}
 
-	dbconfig, err := pgxpool.ParseConfig(url)
+	// `embedded:<dir>` runs a local server for development.
+	embedded := false
+	if parsed, err := url.Parse(dbUrl); err == nil && parsed.Scheme == "embedded" {
+		dir := parsed.Opaque
+		if dir == "" {
+			dir = parsed.Path
+		}
+		if dbUrl, err = startEmbeddedPostgres(logger, dir); err != nil {
+			return nil, err
+		}
+		embedded = true
+	}
+
+	dbconfig, err := pgxpool.ParseConfig(dbUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +66,55 @@ func DBConnection(logger *zerolog.Logger, logDb bool) (PgxIface, error) {
 		return nil
 	}
 
-	return pgxpool.ConnectConfig(context.Background(), dbconfig)
+	pool, err := pgxpool.ConnectConfig(context.Background(), dbconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// There is nobody to run dbmate for an embedded database.
+	if embedded {
+		if err := Migrate(context.Background(), pool, db.Migrations, "migrations", logger); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	return pool, nil
+}
+
+// Calls the function with each notification on the channels
+// until the context ends or the connection is lost.
+// The database must be a pool to take a connection of its own from.
+func DBListen(ctx context.Context, db PgxIface, channels []string, fn func(*pgconn.Notification)) error {
+	pool, ok := db.(*pgxpool.Pool)
+	if !ok {
+		return errors.New("Can only listen for notifications on a connection pool")
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection still listens so it must not be reused.
+	defer conn.Release()
+	defer conn.Conn().Close(context.Background())
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, `LISTEN `+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		fn(notification)
+	}
 }
 
 func wrapLogger(original *zerolog.Logger) pgLogger {
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Runs a PostgreSQL server using the binaries on PATH with its data
+// and socket in the given directory, initializing it if necessary.
+// This is meant for development so that no database server
+// needs to be set up. The server keeps running when Cicero exits
+// so that it can be reused. Stop it with `pg_ctl --pgdata <dir> stop`.
+// Returns the URL to connect to it.
+func startEmbeddedPostgres(logger *zerolog.Logger, dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	log := logger.With().Str("dir", dir).Logger()
+
+	if _, err := os.Stat(filepath.Join(dir, "PG_VERSION")); os.IsNotExist(err) {
+		log.Info().Msg("Initializing embedded PostgreSQL")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+		if output, err := exec.Command(
+			"initdb",
+			"--pgdata", dir,
+			"--username", "cicero",
+			"--auth", "trust",
+			"--encoding", "UTF8",
+		).CombinedOutput(); err != nil {
+			return "", errors.WithMessagef(err, "Could not initialize embedded PostgreSQL: %s", output)
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	// Exits non-zero if the server is not running.
+	if err := exec.Command("pg_ctl", "status", "--pgdata", dir).Run(); err != nil {
+		log.Info().Msg("Starting embedded PostgreSQL")
+		if output, err := exec.Command(
+			"pg_ctl", "start",
+			"--wait",
+			"--pgdata", dir,
+			"--log", filepath.Join(dir, "postgres.log"),
+			// Only listen on a socket in the data directory.
+			"--options", "-c listen_addresses='' -k '"+dir+"'",
+		).CombinedOutput(); err != nil {
+			return "", errors.WithMessagef(err, "Could not start embedded PostgreSQL: %s", output)
+		}
+	}
+
+	return "postgres://cicero@/postgres?host=" + url.QueryEscape(dir), nil
+}
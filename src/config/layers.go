@@ -0,0 +1,365 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/alexflint/go-scalar"
+	"github.com/pkg/errors"
+)
+
+// Options are layered from lowest to highest precedence:
+// defaults, the configuration file, environment variables, and flags.
+// go-arg takes care of all but the file, which `ApplyFile()`
+// fills in for the options that were given neither way.
+//
+// The file is a JSON object with options by their long flag name.
+// Options may be nested in objects named after subcommands
+// to only apply to those, otherwise they apply to all that have them.
+//
+//	{
+//		"log-level": "debug",
+//		"url": "https://cicero.example",
+//		"start": {"web-listen": ":8000"}
+//	}
+
+// An option that is invalid, named by its key in the configuration file.
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (self KeyError) Error() string {
+	return fmt.Sprintf("Invalid option %q: %s", self.Key, self.Err)
+}
+
+func (self KeyError) Unwrap() error {
+	return self.Err
+}
+
+// Implemented by commands that check their options after parsing.
+// They should return a `KeyError`.
+type Validator interface {
+	Validate() error
+}
+
+type option struct {
+	long   string
+	short  string
+	env    string
+	secret bool
+	// Invalid if the command was not given.
+	value reflect.Value
+}
+
+type command struct {
+	path        []string
+	options     []option
+	subcommands map[string]*command
+	// Nil if the command was not given.
+	dest interface{}
+}
+
+// Builds the tree of commands from a struct annotated for go-arg.
+// The value may be a nil pointer for commands that were not given.
+func newCommand(value reflect.Value, path []string) *command {
+	cmd := &command{path: path, subcommands: map[string]*command{}}
+	t := value.Type().Elem()
+	if value.IsNil() {
+		value = reflect.Value{}
+	} else {
+		cmd.dest = value.Interface()
+		value = value.Elem()
+	}
+	cmd.addFields(value, t)
+	return cmd
+}
+
+func (self *command) addFields(value reflect.Value, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		var fieldValue reflect.Value
+		if value.IsValid() {
+			fieldValue = value.Field(i)
+		}
+
+		tag := field.Tag.Get("arg")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			self.addFields(fieldValue, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		opt := option{
+			long:   strings.ToLower(field.Name),
+			secret: field.Tag.Get("secret") == "true",
+			value:  fieldValue,
+		}
+		var subcommand, positional bool
+		for _, key := range strings.Split(tag, ",") {
+			switch {
+			case key == "positional":
+				positional = true
+			case strings.HasPrefix(key, "--"):
+				opt.long = key[2:]
+			case strings.HasPrefix(key, "-"):
+				opt.short = key[1:]
+			case key == "env":
+				opt.env = strings.ToUpper(field.Name)
+			case strings.HasPrefix(key, "env:"):
+				opt.env = key[4:]
+			case strings.HasPrefix(key, "subcommand:"):
+				subcommand = true
+				name := key[len("subcommand:"):]
+				subValue := reflect.Zero(field.Type)
+				if fieldValue.IsValid() {
+					subValue = fieldValue
+				}
+				self.subcommands[name] = newCommand(subValue, append(append([]string{}, self.path...), name))
+			}
+		}
+		if subcommand || positional {
+			continue
+		}
+
+		self.options = append(self.options, opt)
+	}
+}
+
+func (self *command) key(name string) string {
+	return strings.Join(append(append([]string{}, self.path...), name), ".")
+}
+
+// Whether this command or any of its subcommands has the option.
+func (self *command) hasOption(long string) bool {
+	for _, opt := range self.options {
+		if opt.long == long {
+			return true
+		}
+	}
+	for _, sub := range self.subcommands {
+		if sub.hasOption(long) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collects the options from the file by their key.
+func (self *command) settings(file map[string]json.RawMessage, settings map[string]json.RawMessage) error {
+	for name, raw := range file {
+		// Some subcommands are named like options, like token.
+		subFile := map[string]json.RawMessage{}
+		sub, isSubcommand := self.subcommands[name]
+		if isSubcommand && json.Unmarshal(raw, &subFile) == nil {
+			if err := sub.settings(subFile, settings); err != nil {
+				return err
+			}
+		} else if self.hasOption(name) {
+			settings[self.key(name)] = raw
+		} else if isSubcommand {
+			return KeyError{self.key(name), errors.New("must be an object with the options of this subcommand")}
+		} else {
+			return KeyError{self.key(name), errors.New("no such option")}
+		}
+	}
+	return nil
+}
+
+// Calls the function for each command that was given.
+func (self *command) walk(f func(*command) error) error {
+	if self.dest == nil {
+		return nil
+	}
+	if err := f(self); err != nil {
+		return err
+	}
+	for _, sub := range self.subcommands {
+		if err := sub.walk(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the configuration file at the path, if any, into dest,
+// which must have been parsed by go-arg from the given arguments already.
+func ApplyFile(dest interface{}, args []string, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithMessage(err, "Could not read configuration file")
+	}
+	file := map[string]json.RawMessage{}
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return errors.WithMessagef(err, "Could not parse configuration file %q", path)
+	}
+
+	root := newCommand(reflect.ValueOf(dest), nil)
+
+	settings := map[string]json.RawMessage{}
+	if err := root.settings(file, settings); err != nil {
+		return errors.WithMessagef(err, "In configuration file %q", path)
+	}
+
+	given := givenFlags(args)
+
+	return root.walk(func(cmd *command) error {
+		for _, opt := range cmd.options {
+			if given["--"+opt.long] || (opt.short != "" && given["-"+opt.short]) {
+				continue
+			}
+			if _, ok := os.LookupEnv(opt.env); ok && opt.env != "" {
+				continue
+			}
+
+			// The most specific setting wins.
+			for i := len(cmd.path); i >= 0; i-- {
+				key := strings.Join(append(append([]string{}, cmd.path[:i]...), opt.long), ".")
+				if raw, ok := settings[key]; ok {
+					if err := setOption(opt.value, raw); err != nil {
+						return errors.WithMessagef(KeyError{key, err}, "In configuration file %q", path)
+					}
+					break
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Returns the flags in the arguments without their values.
+func givenFlags(args []string) map[string]bool {
+	given := map[string]bool{}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || strings.TrimLeft(arg, "-") == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		given[name] = true
+	}
+	return given
+}
+
+func setOption(value reflect.Value, raw json.RawMessage) error {
+	if string(raw) == "null" {
+		return nil
+	}
+
+	if value.Kind() == reflect.Slice {
+		elems := []json.RawMessage{}
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return errors.New("must be a list")
+		}
+		slice := reflect.MakeSlice(value.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			str, err := scalarString(elem)
+			if err != nil {
+				return err
+			}
+			if err := scalar.ParseValue(slice.Index(i), str); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+
+	str, err := scalarString(raw)
+	if err != nil {
+		return err
+	}
+	return scalar.ParseValue(value, str)
+}
+
+// Returns strings unquoted and numbers and booleans as they are.
+func scalarString(raw json.RawMessage) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case float64, bool:
+		return string(raw), nil
+	default:
+		return "", errors.New("must be a string, number, or boolean")
+	}
+}
+
+// Calls `Validate()` on all given commands that implement `Validator`.
+func Validate(dest interface{}) error {
+	return newCommand(reflect.ValueOf(dest), nil).walk(func(cmd *command) error {
+		if validator, ok := cmd.dest.(Validator); ok {
+			return validator.Validate()
+		}
+		return nil
+	})
+}
+
+// Returns the options of all given commands
+// in the format of the configuration file.
+// Options tagged with `secret:"true"` are hidden if redacted.
+func Effective(dest interface{}, redacted bool) map[string]interface{} {
+	result := map[string]interface{}{}
+	_ = newCommand(reflect.ValueOf(dest), nil).walk(func(cmd *command) error {
+		section := result
+		for _, name := range cmd.path {
+			sub, ok := section[name].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				section[name] = sub
+			}
+			section = sub
+		}
+
+		for _, opt := range cmd.options {
+			if redacted && opt.secret && !opt.value.IsZero() {
+				section[opt.long] = "REDACTED"
+			} else {
+				section[opt.long] = printable(opt.value)
+			}
+		}
+		return nil
+	})
+	return result
+}
+
+func printable(value reflect.Value) interface{} {
+	switch v := value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case encoding.TextMarshaler:
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	if value.Kind() == reflect.Slice {
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = printable(value.Index(i))
+		}
+		return items
+	}
+
+	return value.Interface()
+}
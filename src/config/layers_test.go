@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSubCmd struct {
+	Listen   string        `arg:"--listen,env:TEST_LISTEN" default:":8080"`
+	Interval time.Duration `arg:"--interval" default:"10m"`
+	Names    []string      `arg:"--name"`
+	Url      string        `arg:"--url"`
+	Token    string        `arg:"--token" secret:"true"`
+}
+
+func (self testSubCmd) Validate() error {
+	if self.Interval < 0 {
+		return KeyError{"sub.interval", errors.New("must not be negative")}
+	}
+	return nil
+}
+
+type testCmd struct {
+	Level string      `arg:"--level,env:TEST_LEVEL" default:"info"`
+	Sub   *testSubCmd `arg:"subcommand:sub"`
+	Other *testSubCmd `arg:"subcommand:other"`
+}
+
+func writeTestFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApplyFile(t *testing.T) {
+	path := writeTestFile(t, `{
+		"level": "debug",
+		"url": "http://global",
+		"listen": ":1",
+		"sub": {"listen": ":2", "interval": "1h", "name": ["a", "b"]}
+	}`)
+
+	t.Setenv("TEST_LEVEL", "warn")
+
+	// as parsed by go-arg from the arguments
+	cmd := testCmd{Level: "warn", Sub: &testSubCmd{Listen: ":3", Interval: 10 * time.Minute, Url: "http://flag"}}
+	args := []string{"sub", "--listen", ":3"}
+
+	if err := ApplyFile(&cmd, args, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Level != "warn" {
+		t.Errorf("expected environment to override file but got level %q", cmd.Level)
+	}
+	if cmd.Sub.Listen != ":3" {
+		t.Errorf("expected flag to override file but got listen %q", cmd.Sub.Listen)
+	}
+	if cmd.Sub.Interval != time.Hour {
+		t.Errorf("expected file to override default but got interval %s", cmd.Sub.Interval)
+	}
+	if !reflect.DeepEqual(cmd.Sub.Names, []string{"a", "b"}) {
+		t.Errorf("expected names from file but got %v", cmd.Sub.Names)
+	}
+	if cmd.Sub.Url != "http://global" {
+		t.Errorf("expected top-level option to apply to subcommand but got url %q", cmd.Sub.Url)
+	}
+}
+
+func TestApplyFileInvalid(t *testing.T) {
+	for contents, key := range map[string]string{
+		`{"sub": {"lisen": ":1"}}`:      "sub.lisen",
+		`{"sub": {"interval": "soon"}}`: "sub.interval",
+		`{"sub": {"name": "a"}}`:        "sub.name",
+		`{"sub": 1}`:                    "sub",
+	} {
+		cmd := testCmd{Sub: &testSubCmd{}}
+		err := ApplyFile(&cmd, nil, writeTestFile(t, contents))
+
+		keyErr := KeyError{}
+		if !errors.As(err, &keyErr) {
+			t.Errorf("%s: expected KeyError but got %v", contents, err)
+		} else if keyErr.Key != key {
+			t.Errorf("%s: expected key %q but got %q", contents, key, keyErr.Key)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(&testCmd{Sub: &testSubCmd{Interval: -1}}); err == nil {
+		t.Error("expected invalid subcommand options to be an error")
+	}
+	if err := Validate(&testCmd{Other: &testSubCmd{}}); err != nil {
+		t.Errorf("expected valid options but got %s", err)
+	}
+}
+
+func TestEffective(t *testing.T) {
+	cmd := testCmd{Level: "info", Sub: &testSubCmd{Interval: time.Minute, Token: "secret"}}
+
+	expected := map[string]interface{}{
+		"level": "info",
+		"sub": map[string]interface{}{
+			"listen":   "",
+			"interval": "1m0s",
+			"name":     []interface{}{},
+			"url":      "",
+			"token":    "REDACTED",
+		},
+	}
+	if effective := Effective(&cmd, true); !reflect.DeepEqual(effective, expected) {
+		t.Errorf("expected %v but got %v", expected, effective)
+	}
+
+	if effective := Effective(&cmd, false); effective["sub"].(map[string]interface{})["token"] != "secret" {
+		t.Error("expected secret not to be redacted")
+	}
+}
@@ -1,13 +1,16 @@
 package config
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -23,16 +26,17 @@ func (l *SupervisorLogger) Println(v ...interface{}) {
 }
 
 type LoggerConfig struct {
-	// Print human-readable output to console
+	Level zerolog.Level
+	// Levels of components that differ from Level
+	// by the value of their loggers' "component" field.
+	ComponentLevels map[string]zerolog.Level
+
+	// Print human-readable output to console instead of JSON
 	ConsoleLoggingEnabled bool
 
-	// FileLoggingEnabled makes the framework log to a file
-	// the fields below can be skipped if this value is false!
-	FileLoggingEnabled bool
-	// Directory to log to to when filelogging is enabled
-	Directory string
-	// Filename is the name of the logfile which will be placed inside the directory
-	Filename string
+	// Also log to this file if not empty,
+	// the fields below can be skipped otherwise!
+	File string
 	// MaxSize the max size in MB of the logfile before it's rolled
 	MaxSize int
 	// MaxBackups the max number of rolled files to keep
@@ -41,68 +45,24 @@ type LoggerConfig struct {
 	MaxAge int
 }
 
-func buildLoggerConfig() (*LoggerConfig, error) {
-	var conf LoggerConfig
-
-	if v, err := GetenvBool("CONSOLE_LOGGING_ENABLED"); err != nil {
-		return nil, err
-	} else if v != nil {
-		conf.ConsoleLoggingEnabled = *v
-	}
-
-	if v, err := GetenvBool("FILE_LOGGING_ENABLED"); err != nil {
-		return nil, err
-	} else if v != nil && *v {
-		if v := GetenvStr("LOGS_DIRECTORY"); v == "" {
-			conf.Directory = "logs"
-		} else {
-			conf.Directory = v
+// Parses levels given as component=level.
+func ParseComponentLevels(strs []string) (map[string]zerolog.Level, error) {
+	levels := map[string]zerolog.Level{}
+	for _, str := range strs {
+		component, levelStr, ok := strings.Cut(str, "=")
+		if !ok || component == "" {
+			return nil, errors.Errorf("Invalid component log level %q, must be component=level", str)
 		}
-
-		if v := GetenvStr("LOGS_FILE_NAME"); v == "" {
-			conf.Filename = "cicero.log"
-		} else {
-			conf.Filename = v
-		}
-
-		switch v, err := GetenvInt("LOGS_MAX_SIZE"); {
-		case err != nil:
-			return nil, err
-		case v != nil:
-			conf.MaxSize = *v
-		default:
-			conf.MaxSize = 10
-		}
-
-		switch v, err := GetenvInt("LOGS_MAX_BACKUPS"); {
-		case err != nil:
-			return nil, err
-		case v != nil:
-			conf.MaxBackups = *v
-		default:
-			conf.MaxBackups = 10
-		}
-
-		switch v, err := GetenvInt("LOGS_MAX_AGE"); {
-		case err != nil:
-			return nil, err
-		case v != nil:
-			conf.MaxAge = *v
-		default:
-			conf.MaxAge = 10
+		level, err := zerolog.ParseLevel(levelStr)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid log level for component %q", component)
 		}
+		levels[component] = level
 	}
-
-	return &conf, nil
+	return levels, nil
 }
 
-func ConfigureLogger(level zerolog.Level) *zerolog.Logger {
-	config, err := buildLoggerConfig()
-	if err != nil {
-		log.Fatal().Err(err).Msg("can't get logger config")
-		return nil
-	}
-
+func ConfigureLogger(config LoggerConfig) (*zerolog.Logger, *LogLevels, error) {
 	var writers []io.Writer
 	if config.ConsoleLoggingEnabled {
 		writers = append(writers, zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
@@ -112,39 +72,148 @@ func ConfigureLogger(level zerolog.Level) *zerolog.Logger {
 	} else {
 		writers = append(writers, os.Stderr)
 	}
-	if config.FileLoggingEnabled {
-		writers = append(writers, newRollingFile(config))
+	if config.File != "" {
+		if writer, err := newRollingFile(config); err != nil {
+			return nil, nil, err
+		} else {
+			writers = append(writers, writer)
+		}
 	}
 
-	logger := zerolog.New(zerolog.SyncWriter(zerolog.MultiLevelWriter(writers...))).
-		With().Timestamp().Logger()
+	levels := &LogLevels{}
+	levels.Set(config.Level, config.ComponentLevels)
+
+	logger := zerolog.New(zerolog.SyncWriter(&componentLevelWriter{
+		LevelWriter: zerolog.MultiLevelWriter(writers...),
+		levels:      levels,
+	})).With().Timestamp().Logger()
 
-	zerolog.SetGlobalLevel(level)
+	componentLevels := zerolog.Dict()
+	for component, level := range config.ComponentLevels {
+		componentLevels.Str(component, level.String())
+	}
 
 	logger.Info().
-		Str("logLevel", zerolog.GlobalLevel().String()).
+		Str("logLevel", config.Level.String()).
+		Dict("componentLogLevels", componentLevels).
 		Bool("consoleLogging", config.ConsoleLoggingEnabled).
-		Bool("fileLogging", config.FileLoggingEnabled).
-		Str("logDirectory", config.Directory).
-		Str("fileName", config.Filename).
+		Str("file", config.File).
 		Int("maxSizeMB", config.MaxSize).
 		Int("maxBackups", config.MaxBackups).
 		Int("maxAgeInDays", config.MaxAge).
 		Msg("logging configured")
 
-	return &logger
+	return &logger, levels, nil
 }
 
-func newRollingFile(config *LoggerConfig) io.Writer {
-	if err := os.MkdirAll(config.Directory, 0o744); err != nil {
-		log.Fatal().Err(err).Str("path", config.Directory).Msg("can't create log directory")
-		return nil
+func newRollingFile(config LoggerConfig) (io.Writer, error) {
+	if err := os.MkdirAll(path.Dir(config.File), 0o744); err != nil {
+		return nil, errors.WithMessagef(err, "Could not create log directory for %q", config.File)
 	}
 
 	return &lumberjack.Logger{
-		Filename:   path.Join(config.Directory, config.Filename),
+		Filename:   config.File,
 		MaxBackups: config.MaxBackups, // files
 		MaxSize:    config.MaxSize,    // megabytes
 		MaxAge:     config.MaxAge,     // days
+	}, nil
+}
+
+// Log levels by component that can be changed while running.
+type LogLevels struct {
+	mutex      sync.RWMutex
+	level      zerolog.Level
+	components map[string]zerolog.Level
+}
+
+// Returns the level of components that have none of their own
+// and a copy of the levels of those that do.
+func (self *LogLevels) Get() (zerolog.Level, map[string]zerolog.Level) {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	components := make(map[string]zerolog.Level, len(self.components))
+	for component, level := range self.components {
+		components[component] = level
+	}
+	return self.level, components
+}
+
+// Replaces all levels.
+func (self *LogLevels) Set(level zerolog.Level, components map[string]zerolog.Level) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.level = level
+	self.components = make(map[string]zerolog.Level, len(components))
+	for component, level := range components {
+		self.components[component] = level
+	}
+
+	self.updateGlobalLevel()
+}
+
+// Sets the level of one component, or of those
+// that have none of their own if component is empty.
+// A nil level makes the component use the default level again.
+func (self *LogLevels) SetComponent(component string, level *zerolog.Level) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	switch {
+	case component == "" && level != nil:
+		self.level = *level
+	case level == nil:
+		delete(self.components, component)
+	default:
+		self.components[component] = *level
+	}
+
+	self.updateGlobalLevel()
+}
+
+func (self *LogLevels) of(component string) zerolog.Level {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	if level, ok := self.components[component]; ok {
+		return level
+	}
+	return self.level
+}
+
+// Events are only built for the lowest level in use,
+// the rest is filtered by the componentLevelWriter.
+func (self *LogLevels) updateGlobalLevel() {
+	min := self.level
+	for _, level := range self.components {
+		if level < min {
+			min = level
+		}
+	}
+	zerolog.SetGlobalLevel(min)
+}
+
+// Drops events below the level of the component they were logged by.
+type componentLevelWriter struct {
+	zerolog.LevelWriter
+	levels *LogLevels
+}
+
+var componentField = []byte(`"component":"`)
+
+func (self *componentLevelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	// Events are JSON objects so the field cannot occur unescaped in a string.
+	component := ""
+	if i := bytes.Index(p, componentField); i != -1 {
+		rest := p[i+len(componentField):]
+		if end := bytes.IndexByte(rest, '"'); end != -1 {
+			component = string(rest[:end])
+		}
+	}
+
+	if level != zerolog.NoLevel && level < self.levels.of(component) {
+		return len(p), nil
 	}
+	return self.LevelWriter.WriteLevel(level, p)
 }
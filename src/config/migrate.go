@@ -0,0 +1,195 @@
+package config
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Applies the up sections of dbmate migrations that have not been applied yet.
+// Uses the same table as dbmate to keep track so both can be used interchangeably.
+func Migrate(ctx context.Context, db PgxIface, migrations fs.FS, dir string, logger *zerolog.Logger) error {
+	if _, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(255) PRIMARY KEY)`); err != nil {
+		return errors.WithMessage(err, "Could not create migrations table")
+	}
+
+	entries, err := migrationEntries(migrations, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		version := migrationVersion(entry)
+
+		var applied bool
+		if err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+			return errors.WithMessagef(err, "Could not check whether migration %s was applied", version)
+		} else if applied {
+			continue
+		}
+
+		content, err := fs.ReadFile(migrations, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		up, err := migrationUp(string(content))
+		if err != nil {
+			return errors.WithMessagef(err, "Invalid migration %s", entry.Name())
+		}
+
+		logger.Info().Str("migration", entry.Name()).Msg("Applying migration")
+
+		if err := db.BeginFunc(ctx, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+			return err
+		}); err != nil {
+			return errors.WithMessagef(err, "Could not apply migration %s", entry.Name())
+		}
+	}
+
+	return nil
+}
+
+// How the applied migrations compare to those this binary has.
+type SchemaStatus struct {
+	// Latest migration this binary has.
+	Expected string `json:"expected"`
+	// Latest migration that was applied.
+	Applied string `json:"applied"`
+	// Migrations this binary has that were not applied.
+	Missing []string `json:"missing,omitempty"`
+	// Migrations that were applied that this binary does not have,
+	// most likely by a newer version of it.
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+func (self SchemaStatus) Older() bool { return len(self.Missing) > 0 }
+func (self SchemaStatus) Newer() bool { return len(self.Unknown) > 0 }
+
+// Returns why the schema cannot be used, if so.
+// A newer schema usually only adds to what this binary expects
+// so it may be allowed, for example during a rolling upgrade.
+func (self SchemaStatus) Check(allowNewer bool) error {
+	switch {
+	case self.Older():
+		return errors.Errorf("Database schema is older than expected: %d migrations up to %s were not applied", len(self.Missing), self.Expected)
+	case self.Newer() && !allowNewer:
+		return errors.Errorf("Database schema is newer than expected: %d unknown migrations up to %s were applied", len(self.Unknown), self.Applied)
+	}
+	return nil
+}
+
+// Compares the applied migrations to those this binary has.
+func GetSchemaStatus(ctx context.Context, db PgxIface, migrations fs.FS, dir string) (status SchemaStatus, err error) {
+	entries, err := migrationEntries(migrations, dir)
+	if err != nil {
+		return
+	}
+	known := make([]string, len(entries))
+	for i, entry := range entries {
+		known[i] = migrationVersion(entry)
+	}
+
+	var exists bool
+	if err = db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		err = errors.WithMessage(err, "Could not check for migrations table")
+		return
+	}
+
+	applied := []string{}
+	if exists {
+		rows, err := db.Query(ctx, `SELECT version FROM schema_migrations ORDER BY version`)
+		if err != nil {
+			return status, errors.WithMessage(err, "Could not get applied migrations")
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version string
+			if err := rows.Scan(&version); err != nil {
+				return status, errors.WithMessage(err, "Could not get applied migrations")
+			}
+			applied = append(applied, version)
+		}
+		if err := rows.Err(); err != nil {
+			return status, errors.WithMessage(err, "Could not get applied migrations")
+		}
+	}
+
+	return compareSchema(known, applied), nil
+}
+
+// Both must be sorted.
+func compareSchema(known, applied []string) (status SchemaStatus) {
+	if len(known) > 0 {
+		status.Expected = known[len(known)-1]
+	}
+	if len(applied) > 0 {
+		status.Applied = applied[len(applied)-1]
+	}
+
+	isApplied := make(map[string]bool, len(applied))
+	for _, version := range applied {
+		isApplied[version] = true
+	}
+	isKnown := make(map[string]bool, len(known))
+	for _, version := range known {
+		isKnown[version] = true
+		if !isApplied[version] {
+			status.Missing = append(status.Missing, version)
+		}
+	}
+	for _, version := range applied {
+		if !isKnown[version] {
+			status.Unknown = append(status.Unknown, version)
+		}
+	}
+
+	return
+}
+
+// Returns the migration files sorted by name, and thereby by version.
+func migrationEntries(migrations fs.FS, dir string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	files := entries[:0]
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+func migrationVersion(entry fs.DirEntry) string {
+	return strings.SplitN(entry.Name(), "_", 2)[0]
+}
+
+// Returns the part between the `-- migrate:up` and `-- migrate:down` markers.
+func migrationUp(content string) (string, error) {
+	const upMarker, downMarker = "-- migrate:up", "-- migrate:down"
+
+	start := strings.Index(content, upMarker)
+	if start == -1 {
+		return "", errors.New("Missing " + upMarker)
+	}
+	up := content[start+len(upMarker):]
+
+	if end := strings.Index(up, downMarker); end != -1 {
+		up = up[:end]
+	}
+
+	return up, nil
+}
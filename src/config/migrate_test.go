@@ -0,0 +1,60 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareSchema(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		known, applied   []string
+		expected         SchemaStatus
+		older, newer     bool
+		allowedWithNewer bool
+	}{
+		{
+			name:             "equal",
+			known:            []string{"1", "2"},
+			applied:          []string{"1", "2"},
+			expected:         SchemaStatus{Expected: "2", Applied: "2"},
+			allowedWithNewer: true,
+		},
+		{
+			name:     "older",
+			known:    []string{"1", "2", "3"},
+			applied:  []string{"1"},
+			expected: SchemaStatus{Expected: "3", Applied: "1", Missing: []string{"2", "3"}},
+			older:    true,
+		},
+		{
+			name:             "newer",
+			known:            []string{"1"},
+			applied:          []string{"1", "2"},
+			expected:         SchemaStatus{Expected: "1", Applied: "2", Unknown: []string{"2"}},
+			newer:            true,
+			allowedWithNewer: true,
+		},
+		{
+			name:     "empty",
+			known:    []string{"1"},
+			applied:  []string{},
+			expected: SchemaStatus{Expected: "1", Missing: []string{"1"}},
+			older:    true,
+		},
+	} {
+		status := compareSchema(tc.known, tc.applied)
+		if !reflect.DeepEqual(status, tc.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tc.name, tc.expected, status)
+		}
+		if status.Older() != tc.older || status.Newer() != tc.newer {
+			t.Errorf("%s: expected older=%v newer=%v", tc.name, tc.older, tc.newer)
+		}
+		if err := status.Check(false); (err == nil) != (!tc.older && !tc.newer) {
+			t.Errorf("%s: unexpected check result %v", tc.name, err)
+		}
+		if err := status.Check(true); (err == nil) != tc.allowedWithNewer {
+			t.Errorf("%s: unexpected check result with newer allowed %v", tc.name, err)
+		}
+	}
+}
@@ -2,8 +2,13 @@ package config
 
 import nomad "github.com/hashicorp/nomad/api"
 
-func NewNomadClient() (client *nomad.Client, err error) {
+// Connects to the Nomad cluster at the given address
+// or the one configured by the environment if empty.
+func NewNomadClient(address string) (client *nomad.Client, err error) {
 	config := nomad.DefaultConfig()
+	if address != "" {
+		config.Address = address
+	}
 	//TODO: log configuration
 	client, err = nomad.NewClient(config)
 	return
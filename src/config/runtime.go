@@ -0,0 +1,172 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+// Settings that can be changed without restarting
+// by reloading the runtime config file.
+type Runtime struct {
+	LogLevel          string   `json:"log_level"`
+	FactValueLimit    int64    `json:"fact_value_limit"`
+	FactBinaryLimit   int64    `json:"fact_binary_limit"`
+	NomadGCPurgeAfter Duration `json:"nomad_gc_purge_after"`
+
+	// Levels of components that differ from LogLevel.
+	LogLevels map[string]string `json:"log_levels"`
+
+	// Unit costs that Runs' usage is charged at.
+	CostCPUHour       float64 `json:"cost_cpu_hour"`
+	CostMemoryGiBHour float64 `json:"cost_memory_gib_hour"`
+
+	// Applied to facts' values when they are shown.
+	// Also applied to the stored values if there is a seal key
+	// to store the original encrypted, see `FactService`.
+	FactRedactions util.Redactions `json:"fact_redactions"`
+
+	// Applied to facts' values before they are saved,
+	// except to signed facts as that would break their signature.
+	FactIngest util.IngestPipeline `json:"fact_ingest"`
+
+	// How long ingested facts are remembered to drop duplicates
+	// that are delivered again, zero to keep them.
+	FactDedupWindow Duration `json:"fact_dedup_window"`
+
+	// Times during which Runs of some actions wait in the queue.
+	MaintenanceWindows domain.MaintenanceWindows `json:"maintenance_windows"`
+
+	// When fact binaries move to cheaper storage tiers.
+	FactBinaryTiers domain.FactBinaryTierPolicies `json:"fact_binary_tiers"`
+}
+
+func (self Runtime) Validate() error {
+	if _, err := zerolog.ParseLevel(self.LogLevel); err != nil {
+		return KeyError{"log_level", err}
+	}
+	if _, err := self.ComponentLogLevels(); err != nil {
+		return KeyError{"log_levels", err}
+	}
+	if self.FactValueLimit < 0 {
+		return KeyError{"fact_value_limit", errors.New("must not be negative")}
+	}
+	if self.FactBinaryLimit < 0 {
+		return KeyError{"fact_binary_limit", errors.New("must not be negative")}
+	}
+	if self.FactDedupWindow < 0 {
+		return KeyError{"fact_dedup_window", errors.New("must not be negative")}
+	}
+	if self.NomadGCPurgeAfter < 0 {
+		return KeyError{"nomad_gc_purge_after", errors.New("must not be negative")}
+	}
+	if self.CostCPUHour < 0 {
+		return KeyError{"cost_cpu_hour", errors.New("must not be negative")}
+	}
+	if self.CostMemoryGiBHour < 0 {
+		return KeyError{"cost_memory_gib_hour", errors.New("must not be negative")}
+	}
+	if err := self.MaintenanceWindows.Validate(); err != nil {
+		return KeyError{"maintenance_windows", err}
+	}
+	if err := self.FactBinaryTiers.Validate(); err != nil {
+		return KeyError{"fact_binary_tiers", err}
+	}
+	return nil
+}
+
+func (self Runtime) ComponentLogLevels() (map[string]zerolog.Level, error) {
+	levels := make(map[string]zerolog.Level, len(self.LogLevels))
+	for component, str := range self.LogLevels {
+		level, err := zerolog.ParseLevel(str)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid log level for component %q", component)
+		}
+		levels[component] = level
+	}
+	return levels, nil
+}
+
+// A `time.Duration` that is written as a string like "1h30m" in JSON.
+type Duration time.Duration
+
+func (self Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(self).String())
+}
+
+func (self *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(str)
+	*self = Duration(d)
+	return err
+}
+
+// Holds the current runtime settings.
+// The settings given on startup are the defaults
+// that the optional file is applied on top of.
+type RuntimeConfig struct {
+	path     string
+	defaults Runtime
+
+	current  atomic.Value
+	mutex    sync.Mutex
+	onReload []func(Runtime)
+}
+
+func NewRuntimeConfig(path string, defaults Runtime) (*RuntimeConfig, error) {
+	self := &RuntimeConfig{path: path, defaults: defaults}
+	return self, self.Reload()
+}
+
+func (self *RuntimeConfig) Get() Runtime {
+	return self.current.Load().(Runtime)
+}
+
+// Registers a function to apply settings. It is called right away
+// with the current settings and again after every reload.
+func (self *RuntimeConfig) OnReload(f func(Runtime)) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.onReload = append(self.onReload, f)
+	f(self.Get())
+}
+
+// Reads the file again and swaps in the new settings if they are valid.
+// On error the current settings remain in effect.
+func (self *RuntimeConfig) Reload() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	runtime := self.defaults
+	if self.path != "" {
+		if data, err := os.ReadFile(self.path); err != nil {
+			return errors.WithMessagef(err, "Could not read runtime config file %q", self.path)
+		} else if err := json.Unmarshal(data, &runtime); err != nil {
+			return errors.WithMessagef(err, "Could not parse runtime config file %q", self.path)
+		}
+	}
+
+	if err := runtime.Validate(); err != nil {
+		return err
+	}
+
+	self.current.Store(runtime)
+
+	for _, f := range self.onReload {
+		f(runtime)
+	}
+
+	return nil
+}
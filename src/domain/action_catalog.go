@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"cuelang.org/go/cue"
+	cueformat "cuelang.org/go/cue/format"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+// Meta attribute of an action with a description of what it does.
+const ActionMetaDescription = "description"
+
+// Meta attribute of an action with a list of strings to find it by.
+const ActionMetaTags = "tags"
+
+// Returns the description from the action's meta attribute, if any.
+func (self ActionDefinition) Description() string {
+	description, _ := self.Meta[ActionMetaDescription].(string)
+	return description
+}
+
+// Returns the tags from the action's meta attribute, if any.
+func (self ActionDefinition) Tags() ([]string, error) {
+	var tags []string
+	if _, err := self.decodeMeta(ActionMetaTags, &tags); err != nil {
+		return nil, errors.WithMessagef(err, "Action meta %q must be a list of strings", ActionMetaTags)
+	}
+	return tags, nil
+}
+
+// An action as listed in the catalog of current actions.
+type ActionCatalogEntry struct {
+	ID          uuid.UUID            `json:"id"`
+	Name        string               `json:"name"`
+	Source      string               `json:"source"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Active      bool                 `json:"active"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Owner       *ActionOwner         `json:"owner,omitempty"`
+	Inputs      []ActionCatalogInput `json:"inputs"`
+	// CUE of the output as written in the definition, empty if there is none.
+	Output string `json:"output,omitempty"`
+	// Of the latest Run of any version of the action, nil if it never ran.
+	LastRunStatus *RunStatus `json:"last_run_status,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+}
+
+type ActionCatalogInput struct {
+	Name     string   `json:"name"`
+	Not      bool     `json:"not,omitempty"`
+	Optional bool     `json:"optional,omitempty"`
+	SignedBy []string `json:"signed_by,omitempty"`
+	// The value if no fact satisfies the input.
+	Default interface{} `json:"default,omitempty"`
+	// CUE of the match expression.
+	Match string `json:"match"`
+	// Comments on the input in the definition.
+	Doc string `json:"doc,omitempty"`
+}
+
+// Describes the action and its latest Run, which may be nil.
+func NewActionCatalogEntry(action Action, lastRun *Run) (entry ActionCatalogEntry, err error) {
+	entry = ActionCatalogEntry{
+		ID:          action.ID,
+		Name:        action.Name,
+		Source:      action.Source,
+		CreatedAt:   action.CreatedAt,
+		Active:      action.Active,
+		Description: action.Description(),
+		Inputs:      []ActionCatalogInput{},
+	}
+
+	if entry.Tags, err = action.Tags(); err != nil {
+		return
+	}
+
+	if owner, err := action.Owner(); err != nil {
+		return entry, err
+	} else if !owner.IsZero() {
+		entry.Owner = &owner
+	}
+
+	if lastRun != nil {
+		entry.LastRunStatus = &lastRun.Status
+		entry.LastRunAt = &lastRun.CreatedAt
+	}
+
+	inputs, err := action.InOut.Inputs(nil)
+	if err != nil {
+		return entry, errors.WithMessagef(err, "Could not get inputs of action %q", action.Name)
+	}
+
+	value := util.CUEString(action.InOut).Value(nil, nil)
+
+	for name, input := range inputs {
+		catalogInput := ActionCatalogInput{
+			Name:     name,
+			Not:      input.Not,
+			Optional: input.Optional,
+			SignedBy: input.SignedBy,
+			Default:  input.Default,
+			Doc:      cueDoc(value.LookupPath(cue.MakePath(cue.Str("inputs"), cue.Str(name)))),
+		}
+		if catalogInput.Match, err = cueSource(input.Match); err != nil {
+			return entry, errors.WithMessagef(err, "Could not format match of input %q", name)
+		}
+		entry.Inputs = append(entry.Inputs, catalogInput)
+	}
+	sort.Slice(entry.Inputs, func(i, j int) bool {
+		return entry.Inputs[i].Name < entry.Inputs[j].Name
+	})
+
+	if output := value.LookupPath(cue.MakePath(cue.Str("output"))); output.Exists() {
+		if entry.Output, err = cueSource(output); err != nil {
+			return entry, errors.WithMessage(err, "Could not format output")
+		}
+	}
+
+	return
+}
+
+func cueDoc(value cue.Value) string {
+	docs := []string{}
+	for _, doc := range value.Doc() {
+		docs = append(docs, strings.TrimSpace(doc.Text()))
+	}
+	return strings.Join(docs, "\n")
+}
+
+func cueSource(value cue.Value) (string, error) {
+	source, err := cueformat.Node(value.Syntax(cue.Docs(true), cue.Optional(true)))
+	return string(source), err
+}
+
+// Whether the entry's name, description, tags, owner's team,
+// or names of inputs contain the query, ignoring case.
+func (self ActionCatalogEntry) Matches(query string) bool {
+	query = strings.ToLower(query)
+
+	texts := append([]string{self.Name, self.Description}, self.Tags...)
+	if self.Owner != nil {
+		texts = append(texts, self.Owner.Team)
+	}
+	for _, input := range self.Inputs {
+		texts = append(texts, input.Name)
+	}
+
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// What the catalog can be sorted by.
+type ActionCatalogSort string
+
+const (
+	ActionCatalogSortName      ActionCatalogSort = "name"
+	ActionCatalogSortCreatedAt ActionCatalogSort = "created_at"
+	ActionCatalogSortLastRun   ActionCatalogSort = "last_run"
+)
+
+func ParseActionCatalogSort(str string) (ActionCatalogSort, error) {
+	switch sort := ActionCatalogSort(str); sort {
+	case "":
+		return ActionCatalogSortName, nil
+	case ActionCatalogSortName, ActionCatalogSortCreatedAt, ActionCatalogSortLastRun:
+		return sort, nil
+	default:
+		return "", errors.Errorf("Unknown sort %q, must be one of: name, created_at, last_run", str)
+	}
+}
+
+// Sorts by name, or newest first by creation or latest Run.
+// Actions that never ran come last when sorted by latest Run.
+func (self ActionCatalogSort) Sort(entries []ActionCatalogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch self {
+		case ActionCatalogSortCreatedAt:
+			return a.CreatedAt.After(b.CreatedAt)
+		case ActionCatalogSortLastRun:
+			switch {
+			case a.LastRunAt == nil:
+				return false
+			case b.LastRunAt == nil:
+				return true
+			default:
+				return a.LastRunAt.After(*b.LastRunAt)
+			}
+		default:
+			return a.Name < b.Name
+		}
+	})
+}
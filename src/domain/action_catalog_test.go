@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionCatalogEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	action := Action{
+		Name: "deploy",
+		ActionDefinition: ActionDefinition{
+			Meta: map[string]interface{}{
+				ActionMetaDescription: "Deploys releases to production.",
+				ActionMetaTags:        []interface{}{"prod", "nix"},
+				ActionMetaOwner:       map[string]interface{}{"team": "ops"},
+			},
+			InOut: `
+				inputs: {
+					// A release that passed its tests.
+					release: {
+						match: version: string
+					}
+					freeze: {
+						not: true
+						match: frozen: true
+					}
+				}
+				output: success: deployed: true
+			`,
+		},
+	}
+	run := Run{Status: RunStatusFailed, CreatedAt: time.Now()}
+
+	// when
+	entry, err := NewActionCatalogEntry(action, &run)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "Deploys releases to production.", entry.Description)
+	assert.Equal(t, []string{"prod", "nix"}, entry.Tags)
+	if assert.NotNil(t, entry.Owner) {
+		assert.Equal(t, "ops", entry.Owner.Team)
+	}
+	if assert.NotNil(t, entry.LastRunStatus) {
+		assert.Equal(t, RunStatusFailed, *entry.LastRunStatus)
+	}
+	if assert.Len(t, entry.Inputs, 2) {
+		assert.Equal(t, "freeze", entry.Inputs[0].Name)
+		assert.True(t, entry.Inputs[0].Not)
+		assert.Equal(t, "release", entry.Inputs[1].Name)
+		assert.Equal(t, "A release that passed its tests.", entry.Inputs[1].Doc)
+		assert.Contains(t, entry.Inputs[1].Match, "version: string")
+	}
+	assert.Contains(t, entry.Output, "deployed: true")
+
+	assert.True(t, entry.Matches("PROD"))
+	assert.True(t, entry.Matches("release"), "must match names of inputs")
+	assert.False(t, entry.Matches("build"))
+}
+
+func TestActionCatalogSort(t *testing.T) {
+	t.Parallel()
+
+	// given
+	now := time.Now()
+	entries := []ActionCatalogEntry{
+		{Name: "b", CreatedAt: now},
+		{Name: "c", CreatedAt: now.Add(-time.Hour), LastRunAt: &now},
+		{Name: "a", CreatedAt: now.Add(time.Hour)},
+	}
+	names := func() (names []string) {
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		return
+	}
+
+	// when
+	sort, err := ParseActionCatalogSort("")
+
+	// then
+	assert.NoError(t, err)
+	sort.Sort(entries)
+	assert.Equal(t, []string{"a", "b", "c"}, names())
+
+	ActionCatalogSortCreatedAt.Sort(entries)
+	assert.Equal(t, []string{"a", "b", "c"}, names())
+
+	ActionCatalogSortLastRun.Sort(entries)
+	assert.Equal(t, "c", entries[0].Name, "Runs that never ran must come last")
+
+	// when
+	_, err = ParseActionCatalogSort("popularity")
+
+	// then
+	assert.Error(t, err)
+}
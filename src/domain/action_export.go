@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// An action as exported by one instance of Cicero to be imported by another.
+// Its definition is evaluated from the source again on import.
+type ActionExport struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Active bool   `json:"active"`
+	// Of the current version for reference,
+	// like its Nomad scheduling, mutex, or approval.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+	// All versions of the action, oldest first, for reference.
+	Versions []ActionExportVersion `json:"versions,omitempty"`
+}
+
+type ActionExportVersion struct {
+	ID        uuid.UUID `json:"id"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Returns the current versions of the actions in order of name.
+// All versions of each action must be given.
+func NewActionExports(actions []Action) []ActionExport {
+	byName := map[string][]Action{}
+	for _, action := range actions {
+		byName[action.Name] = append(byName[action.Name], action)
+	}
+
+	exports := make([]ActionExport, 0, len(byName))
+	for name, versions := range byName {
+		sort.SliceStable(versions, func(i, j int) bool {
+			return versions[i].CreatedAt.Before(versions[j].CreatedAt)
+		})
+		current := versions[len(versions)-1]
+
+		export := ActionExport{
+			Name:     name,
+			Source:   current.Source,
+			Active:   current.Active,
+			Meta:     current.Meta,
+			Versions: make([]ActionExportVersion, len(versions)),
+		}
+		for i, version := range versions {
+			export.Versions[i] = ActionExportVersion{
+				ID:        version.ID,
+				Source:    version.Source,
+				CreatedAt: version.CreatedAt,
+			}
+		}
+		exports = append(exports, export)
+	}
+
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].Name < exports[j].Name
+	})
+
+	return exports
+}
+
+type ActionImportChangeKind string
+
+const (
+	// There is no action of this name yet.
+	ActionImportCreate ActionImportChangeKind = "create"
+	// A new version is created because the source differs.
+	ActionImportUpdate     ActionImportChangeKind = "update"
+	ActionImportActivate   ActionImportChangeKind = "activate"
+	ActionImportDeactivate ActionImportChangeKind = "deactivate"
+	ActionImportUnchanged  ActionImportChangeKind = "unchanged"
+)
+
+type ActionImportChange struct {
+	Name   string                 `json:"name"`
+	Kind   ActionImportChangeKind `json:"kind"`
+	Source string                 `json:"source"`
+	// Set if a new version is created from a different source.
+	PreviousSource *string `json:"previous_source,omitempty"`
+	// Whether the action is active afterwards.
+	Active bool `json:"active"`
+}
+
+// Returns the changes that make the current versions of actions
+// match the exports in order of name.
+// If `prune`, current actions that are not exported are deactivated.
+func PlanActionImport(current []Action, exports []ActionExport, prune bool) ([]ActionImportChange, error) {
+	currentByName := make(map[string]Action, len(current))
+	for _, action := range current {
+		currentByName[action.Name] = action
+	}
+
+	changes := []ActionImportChange{}
+	exported := make(map[string]bool, len(exports))
+	for _, export := range exports {
+		if export.Name == "" || export.Source == "" {
+			return nil, errors.Errorf("Exported action %q must have a name and a source", export.Name)
+		}
+		if exported[export.Name] {
+			return nil, errors.Errorf("Action %q is exported more than once", export.Name)
+		}
+		exported[export.Name] = true
+
+		change := ActionImportChange{
+			Name:   export.Name,
+			Kind:   ActionImportUnchanged,
+			Source: export.Source,
+			Active: export.Active,
+		}
+
+		if action, ok := currentByName[export.Name]; !ok {
+			change.Kind = ActionImportCreate
+		} else if action.Source != export.Source {
+			change.Kind = ActionImportUpdate
+			change.PreviousSource = &action.Source
+		} else if action.Active != export.Active {
+			if export.Active {
+				change.Kind = ActionImportActivate
+			} else {
+				change.Kind = ActionImportDeactivate
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	if prune {
+		for _, action := range current {
+			if !exported[action.Name] && action.Active {
+				changes = append(changes, ActionImportChange{
+					Name:   action.Name,
+					Kind:   ActionImportDeactivate,
+					Source: action.Source,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes, nil
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewActionExports(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	v1 := Action{ID: uuid.New(), Name: "build", Source: "github:org/repo?rev=1", CreatedAt: now.Add(-time.Hour)}
+	v2 := Action{ID: uuid.New(), Name: "build", Source: "github:org/repo?rev=2", CreatedAt: now, Active: true}
+	deploy := Action{ID: uuid.New(), Name: "deploy", Source: "github:org/repo?rev=2", CreatedAt: now}
+
+	exports := NewActionExports([]Action{deploy, v2, v1})
+	assert.Equal(t, []ActionExport{
+		{
+			Name:   "build",
+			Source: v2.Source,
+			Active: true,
+			Versions: []ActionExportVersion{
+				{ID: v1.ID, Source: v1.Source, CreatedAt: v1.CreatedAt},
+				{ID: v2.ID, Source: v2.Source, CreatedAt: v2.CreatedAt},
+			},
+		},
+		{
+			Name:     "deploy",
+			Source:   deploy.Source,
+			Versions: []ActionExportVersion{{ID: deploy.ID, Source: deploy.Source, CreatedAt: deploy.CreatedAt}},
+		},
+	}, exports)
+}
+
+func TestPlanActionImport(t *testing.T) {
+	t.Parallel()
+
+	current := []Action{
+		{Name: "build", Source: "a", Active: true},
+		{Name: "deploy", Source: "a", Active: true},
+		{Name: "lint", Source: "a", Active: false},
+		{Name: "old", Source: "a", Active: true},
+	}
+	exports := []ActionExport{
+		{Name: "build", Source: "a", Active: true},
+		{Name: "deploy", Source: "b", Active: true},
+		{Name: "lint", Source: "a", Active: true},
+		{Name: "new", Source: "b", Active: true},
+	}
+
+	changes, err := PlanActionImport(current, exports, false)
+	assert.NoError(t, err)
+	previous := "a"
+	assert.Equal(t, []ActionImportChange{
+		{Name: "build", Kind: ActionImportUnchanged, Source: "a", Active: true},
+		{Name: "deploy", Kind: ActionImportUpdate, Source: "b", PreviousSource: &previous, Active: true},
+		{Name: "lint", Kind: ActionImportActivate, Source: "a", Active: true},
+		{Name: "new", Kind: ActionImportCreate, Source: "b", Active: true},
+	}, changes)
+
+	changes, err = PlanActionImport(current, exports, true)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 5)
+	assert.Equal(t, ActionImportChange{Name: "old", Kind: ActionImportDeactivate, Source: "a"}, changes[4])
+
+	_, err = PlanActionImport(current, append(exports, ActionExport{Name: "new", Source: "c"}), false)
+	assert.Error(t, err)
+	_, err = PlanActionImport(current, []ActionExport{{Name: "build"}}, false)
+	assert.Error(t, err)
+}
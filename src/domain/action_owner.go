@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action with who is responsible for it.
+// Like `{team: "ops", contact: "ops@example.com", escalation: "#ops-oncall"}`.
+const ActionMetaOwner = "owner"
+
+// File in an action's source with owners of actions by name
+// for those that do not declare one in their meta attribute.
+const ActionOwnersFile = ".cicero/OWNERS"
+
+type ActionOwner struct {
+	Team    string `json:"team,omitempty"`
+	Contact string `json:"contact,omitempty"`
+	// Where to escalate to, like a chat channel or pager.
+	Escalation string `json:"escalation,omitempty"`
+	// Identities that may execute commands in the action's Runs.
+	Members []string `json:"members,omitempty"`
+}
+
+func (self ActionOwner) IsZero() bool {
+	return self.Team == "" && self.Contact == "" && self.Escalation == "" && len(self.Members) == 0
+}
+
+func (self ActionOwner) HasMember(name string) bool {
+	for _, member := range self.Members {
+		if member == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the owner from the action's meta attribute, if any.
+func (self ActionDefinition) Owner() (owner ActionOwner, err error) {
+	_, err = self.decodeMeta(ActionMetaOwner, &owner)
+	return owner, errors.WithMessagef(err, "Action meta %q is invalid", ActionMetaOwner)
+}
+
+// Sets the owner in the meta attribute
+// unless the action declares one already.
+func (self *ActionDefinition) DefaultOwner(owner ActionOwner) error {
+	if _, ok := self.Meta[ActionMetaOwner]; ok || owner.IsZero() {
+		return nil
+	}
+
+	// Keep the meta attribute generic as if it was decoded from CUE.
+	var meta interface{}
+	if encoded, err := json.Marshal(owner); err != nil {
+		return err
+	} else if err := json.Unmarshal(encoded, &meta); err != nil {
+		return err
+	}
+
+	if self.Meta == nil {
+		self.Meta = map[string]interface{}{}
+	}
+	self.Meta[ActionMetaOwner] = meta
+
+	return nil
+}
+
+// Rules of an ActionOwnersFile in order.
+type ActionOwners []ActionOwnersRule
+
+type ActionOwnersRule struct {
+	// Matches action names like `path.Match()`.
+	Pattern string
+	Owner   ActionOwner
+}
+
+// Parses lines like CODEOWNERS but with action name patterns
+// and the owner's attributes as key=value pairs:
+//
+//	# pattern  attributes
+//	*          team=platform contact=platform@example.com
+//	deploy/*   team=ops escalation=#ops-oncall members=alice,bob
+func ParseActionOwners(reader io.Reader) (ActionOwners, error) {
+	owners := ActionOwners{}
+
+	scanner := bufio.NewScanner(reader)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		rule := ActionOwnersRule{Pattern: fields[0]}
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return nil, errors.WithMessagef(err, "Invalid pattern %q on line %d", rule.Pattern, number)
+		}
+
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, errors.Errorf("Invalid attribute %q on line %d, must be key=value", field, number)
+			}
+			switch key {
+			case "team":
+				rule.Owner.Team = value
+			case "contact":
+				rule.Owner.Contact = value
+			case "escalation":
+				rule.Owner.Escalation = value
+			case "members":
+				rule.Owner.Members = strings.Split(value, ",")
+			default:
+				return nil, errors.Errorf("Unknown attribute %q on line %d", key, number)
+			}
+		}
+
+		owners = append(owners, rule)
+	}
+
+	return owners, scanner.Err()
+}
+
+// Returns the owner of the last rule that matches the action's name
+// so that more specific rules can follow general ones.
+func (self ActionOwners) Match(name string) (ActionOwner, bool) {
+	for i := len(self) - 1; i >= 0; i-- {
+		if matched, _ := path.Match(self[i].Pattern, name); matched {
+			return self[i].Owner, true
+		}
+	}
+	return ActionOwner{}, false
+}
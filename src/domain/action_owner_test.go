@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionOwners(t *testing.T) {
+	t.Parallel()
+
+	// given
+	owners, err := ParseActionOwners(strings.NewReader(`
+		# pattern  attributes
+		*          team=platform contact=platform@example.com
+		deploy/*   team=ops escalation=#ops-oncall members=alice,bob
+	`))
+
+	// then
+	assert.NoError(t, err)
+
+	// when
+	owner, ok := owners.Match("deploy/prod")
+
+	// then
+	assert.True(t, ok)
+	assert.Equal(t, ActionOwner{Team: "ops", Escalation: "#ops-oncall", Members: []string{"alice", "bob"}}, owner, "the last matching rule wins")
+	assert.True(t, owner.HasMember("bob"))
+
+	// when
+	owner, ok = owners.Match("build")
+
+	// then
+	assert.True(t, ok)
+	assert.Equal(t, "platform", owner.Team)
+
+	// given
+	def := ActionDefinition{Meta: map[string]interface{}{}}
+
+	// when
+	err = def.DefaultOwner(owner)
+
+	// then
+	assert.NoError(t, err)
+	if owner, err := def.Owner(); assert.NoError(t, err) {
+		assert.Equal(t, "platform", owner.Team)
+	}
+
+	// given
+	def = ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaOwner: map[string]interface{}{"team": "web"},
+	}}
+
+	// when
+	err = def.DefaultOwner(owner)
+
+	// then
+	assert.NoError(t, err)
+	if owner, err := def.Owner(); assert.NoError(t, err) {
+		assert.Equal(t, "web", owner.Team, "the action's own owner is kept")
+	}
+
+	for _, invalid := range []string{"deploy/[ team=ops", "* team", "* role=admin"} {
+		_, err := ParseActionOwners(strings.NewReader(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
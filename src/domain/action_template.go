@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// A skeleton of an action's source that is instantiated
+// with parameters to help writing common actions.
+type ActionTemplate struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Parameters  []ActionTemplateParameter `json:"parameters"`
+	// A Go text/template that has the parameters as fields of `.`.
+	Source string `json:"source"`
+}
+
+type ActionTemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// The parameter is required if there is no default.
+	Default *string `json:"default,omitempty"`
+}
+
+var actionTemplateFuncs = template.FuncMap{
+	// Quotes a string for use in Nix.
+	"nix": func(s string) string {
+		s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `${`, `\${`).Replace(s)
+		return `"` + s + `"`
+	},
+}
+
+// Renders the source with the given parameters,
+// using defaults for those that are not given.
+func (self ActionTemplate) Instantiate(params map[string]string) (string, error) {
+	tmpl, err := template.New(self.Name).
+		Funcs(actionTemplateFuncs).
+		Option("missingkey=error").
+		Parse(self.Source)
+	if err != nil {
+		return "", errors.WithMessagef(err, "Invalid action template %q", self.Name)
+	}
+
+	data := make(map[string]string, len(self.Parameters))
+	missing := []string{}
+	for _, param := range self.Parameters {
+		if value, ok := params[param.Name]; ok {
+			data[param.Name] = value
+		} else if param.Default != nil {
+			data[param.Name] = *param.Default
+		} else {
+			missing = append(missing, param.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", errors.Errorf("Missing parameters of action template %q: %s", self.Name, strings.Join(missing, ", "))
+	}
+
+	unknown := []string{}
+	for name := range params {
+		if _, ok := data[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", errors.Errorf("Unknown parameters of action template %q: %s", self.Name, strings.Join(unknown, ", "))
+	}
+
+	source := &strings.Builder{}
+	if err := tmpl.Execute(source, data); err != nil {
+		return "", errors.WithMessagef(err, "Could not instantiate action template %q", self.Name)
+	}
+	return source.String(), nil
+}
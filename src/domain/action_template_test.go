@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionTemplateInstantiate(t *testing.T) {
+	t.Parallel()
+
+	def := "main"
+	tmpl := ActionTemplate{
+		Name: "test",
+		Parameters: []ActionTemplateParameter{
+			{Name: "url"},
+			{Name: "ref", Default: &def},
+		},
+		Source: `{ url = {{nix .url}}; ref = {{nix .ref}}; }`,
+	}
+
+	source, err := tmpl.Instantiate(map[string]string{"url": `https://example.com/${x}"`})
+	assert.NoError(t, err)
+	assert.Equal(t, `{ url = "https://example.com/\${x}\""; ref = "main"; }`, source)
+
+	source, err = tmpl.Instantiate(map[string]string{"url": "u", "ref": "dev"})
+	assert.NoError(t, err)
+	assert.Contains(t, source, `ref = "dev"`)
+
+	_, err = tmpl.Instantiate(map[string]string{})
+	assert.ErrorContains(t, err, "Missing parameters of action template \"test\": url")
+
+	_, err = tmpl.Instantiate(map[string]string{"url": "u", "foo": "bar"})
+	assert.ErrorContains(t, err, "Unknown parameters of action template \"test\": foo")
+}
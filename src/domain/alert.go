@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The Runs of an action that failed since its last successful Run.
+type RunFailureStreak struct {
+	ActionName string    `json:"action_name" db:"action_name"`
+	ActionId   uuid.UUID `json:"action_id" db:"action_id"`
+	Project    string    `json:"project"`
+	Failures   int       `json:"failures"`
+	// When the first failed Run of the streak was created.
+	Since     time.Time `json:"since"`
+	LastRunId uuid.UUID `json:"last_run_id" db:"last_run_id"`
+	// Of the action's owner, see `ActionDefinition.Owner()`.
+	Team       string `json:"team,omitempty"`
+	Contact    string `json:"contact,omitempty"`
+	Escalation string `json:"escalation,omitempty"`
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A mutating API call.
+type AuditLogEntry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Nil if the request was not authenticated.
+	Identity   *string    `json:"identity,omitempty"`
+	AuthMethod *string    `json:"auth_method,omitempty" db:"auth_method"`
+	ApiTokenId *uuid.UUID `json:"api_token_id,omitempty" db:"api_token_id"`
+	// The address the request came from
+	// or was forwarded for by a trusted proxy.
+	ClientIP *string `json:"client_ip,omitempty" db:"client_ip"`
+	Method   string  `json:"method"`
+	Path     string  `json:"path"`
+	Query    string  `json:"query,omitempty"`
+	// As given by the client, nil if unknown.
+	BodySize   *int64 `json:"body_size,omitempty" db:"body_size"`
+	Status     int    `json:"status"`
+	DurationMs int64  `json:"duration_ms" db:"duration_ms"`
+}
+
+// Narrows down the audit log. Zero values match all entries.
+type AuditLogFilter struct {
+	Identity   string
+	ApiTokenId *uuid.UUID
+	ClientIP   string
+	Method     string
+	// Matches entries whose path starts with it.
+	PathPrefix string
+	Since      *time.Time
+	Until      *time.Time
+	// Only entries whose status is at least 400 if true, below if false.
+	Failed *bool
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// What Cicero is made to have on startup, declared in files
+// so that the instance itself can be managed from a repository.
+// Applying it again changes nothing unless the files changed.
+type Bootstrap struct {
+	Actions            []BootstrapAction  `json:"actions,omitempty"`
+	MaintenanceWindows MaintenanceWindows `json:"maintenance_windows,omitempty"`
+	Tokens             []BootstrapToken   `json:"tokens,omitempty"`
+	Facts              []BootstrapFact    `json:"facts,omitempty"`
+}
+
+// Actions are created or updated to this source
+// but actions that are not declared are left alone.
+type BootstrapAction struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	// Defaults to true.
+	Active *bool `json:"active,omitempty"`
+}
+
+// Tokens have a fixed secret so that clients can be configured
+// before the token exists. Tokens that were bootstrapped before
+// but are not declared anymore are revoked.
+type BootstrapToken struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Where to read the secret from so that it is not in the files:
+	// the name of an environment variable or a file
+	// relative to the file the token is declared in.
+	SecretEnv  string `json:"secret_env,omitempty"`
+	SecretFile string `json:"secret_file,omitempty"`
+	// Resolved from the above.
+	Secret string `json:"-"`
+}
+
+// Facts are published once. Their ID is derived from
+// the labels and value unless given, so changing them
+// publishes a new fact and the old one remains.
+type BootstrapFact struct {
+	ID    *uuid.UUID  `json:"id,omitempty"`
+	Value interface{} `json:"value"`
+	FactLabels
+}
+
+// Who tokens are created by so that they can be told apart.
+const BootstrapCreatedBy = "bootstrap"
+
+var bootstrapFactNamespace = uuid.MustParse("1f6f2a8e-0c1b-4f5e-9a57-6a0e4b8f3c2d")
+
+// Parses one file, as YAML unless its extension is `.json`.
+func ParseBootstrap(name string, content []byte) (bootstrap Bootstrap, err error) {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&bootstrap)
+		return
+	}
+
+	// Decoded generically first so that the JSON field names
+	// and `MaintenanceWindow.UnmarshalJSON()` apply.
+	var decoded interface{}
+	if err = yaml.Unmarshal(content, &decoded); err != nil || decoded == nil {
+		return
+	}
+	var encoded []byte
+	if encoded, err = json.Marshal(decoded); err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&bootstrap)
+	return
+}
+
+// Adds what is declared in the other one.
+func (self *Bootstrap) Merge(other Bootstrap) {
+	self.Actions = append(self.Actions, other.Actions...)
+	self.MaintenanceWindows = append(self.MaintenanceWindows, other.MaintenanceWindows...)
+	self.Tokens = append(self.Tokens, other.Tokens...)
+	self.Facts = append(self.Facts, other.Facts...)
+}
+
+func (self Bootstrap) Validate() error {
+	actions := map[string]struct{}{}
+	for _, action := range self.Actions {
+		if action.Name == "" {
+			return errors.New("Actions must have a name")
+		}
+		if action.Source == "" {
+			return errors.Errorf("Action %q must have a source", action.Name)
+		}
+		if _, exists := actions[action.Name]; exists {
+			return errors.Errorf("Action %q is declared more than once", action.Name)
+		}
+		actions[action.Name] = struct{}{}
+	}
+
+	if err := self.MaintenanceWindows.Validate(); err != nil {
+		return err
+	}
+
+	tokens := map[string]struct{}{}
+	for _, token := range self.Tokens {
+		if token.Name == "" {
+			return errors.New("Tokens must have a name")
+		}
+		if (token.SecretEnv == "") == (token.SecretFile == "") {
+			return errors.Errorf("Token %q must have either secret_env or secret_file", token.Name)
+		}
+		if token.Secret != "" && !strings.HasPrefix(token.Secret, ApiTokenPrefix) {
+			return errors.Errorf("Secret of token %q must start with %q", token.Name, ApiTokenPrefix)
+		}
+		if _, exists := tokens[token.Name]; exists {
+			return errors.Errorf("Token %q is declared more than once", token.Name)
+		}
+		tokens[token.Name] = struct{}{}
+	}
+
+	facts := map[uuid.UUID]struct{}{}
+	for i, fact := range self.Facts {
+		if err := fact.FactLabels.Validate(); err != nil {
+			return errors.WithMessagef(err, "Invalid labels of fact %d", i)
+		}
+		id, err := fact.GetId()
+		if err != nil {
+			return errors.WithMessagef(err, "Invalid fact %d", i)
+		}
+		if _, exists := facts[id]; exists {
+			return errors.Errorf("Fact %q is declared more than once", id)
+		}
+		facts[id] = struct{}{}
+	}
+
+	return nil
+}
+
+func (self BootstrapAction) Export() ActionExport {
+	return ActionExport{
+		Name:   self.Name,
+		Source: self.Source,
+		Active: self.Active == nil || *self.Active,
+	}
+}
+
+// Returns the given ID or one derived from the labels and value.
+func (self BootstrapFact) GetId() (uuid.UUID, error) {
+	if self.ID != nil {
+		return *self.ID, nil
+	}
+
+	// Keys of maps are sorted so this is stable.
+	content, err := json.Marshal(struct {
+		Value interface{} `json:"value"`
+		FactLabels
+	}{self.Value, self.FactLabels})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return uuid.NewSHA1(bootstrapFactNamespace, content), nil
+}
+
+func (self BootstrapFact) Fact() (Fact, error) {
+	id, err := self.GetId()
+	return Fact{
+		ID:         id,
+		Value:      self.Value,
+		FactLabels: self.FactLabels,
+	}, err
+}
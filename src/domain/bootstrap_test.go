@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBootstrap(t *testing.T) {
+	t.Parallel()
+
+	bootstrap, err := ParseBootstrap("cicero.yaml", []byte(`
+actions:
+  - name: ci
+    source: github:input-output-hk/cicero#ci
+  - name: release
+    source: github:input-output-hk/cicero#release
+    active: false
+maintenance_windows:
+  - name: weekend
+    cron: "0 0 * * SAT"
+    duration: 48h
+tokens:
+  - name: deployer
+    scopes: [fact]
+    secret_env: DEPLOYER_TOKEN
+facts:
+  - name: environments
+    value: {production: true}
+`))
+	if assert.NoError(t, err) {
+		if assert.Len(t, bootstrap.Actions, 2) {
+			assert.True(t, bootstrap.Actions[0].Export().Active)
+			assert.False(t, bootstrap.Actions[1].Export().Active)
+		}
+		if assert.Len(t, bootstrap.MaintenanceWindows, 1) {
+			assert.Equal(t, 48*time.Hour, bootstrap.MaintenanceWindows[0].Duration)
+		}
+		if assert.Len(t, bootstrap.Tokens, 1) {
+			assert.Equal(t, []string{"fact"}, bootstrap.Tokens[0].Scopes)
+			assert.Equal(t, "DEPLOYER_TOKEN", bootstrap.Tokens[0].SecretEnv)
+		}
+		if assert.Len(t, bootstrap.Facts, 1) {
+			assert.Equal(t, "environments", bootstrap.Facts[0].Name)
+			assert.Equal(t, map[string]interface{}{"production": true}, bootstrap.Facts[0].Value)
+		}
+		assert.NoError(t, bootstrap.Validate())
+	}
+
+	fromJson, err := ParseBootstrap("cicero.json", []byte(`{"facts": [{"name": "environments", "value": {"production": true}}]}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, bootstrap.Facts, fromJson.Facts)
+	}
+
+	_, err = ParseBootstrap("cicero.yaml", []byte(`action: []`))
+	assert.Error(t, err, "unknown fields must be rejected")
+
+	empty, err := ParseBootstrap("empty.yml", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, Bootstrap{}, empty)
+	}
+}
+
+func TestBootstrapValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, Bootstrap{}.Validate())
+
+	twice := Bootstrap{Actions: []BootstrapAction{{Name: "ci", Source: "a"}}}
+	twice.Merge(Bootstrap{Actions: []BootstrapAction{{Name: "ci", Source: "b"}}})
+	assert.Error(t, twice.Validate())
+
+	assert.Error(t, Bootstrap{Actions: []BootstrapAction{{Name: "ci"}}}.Validate())
+
+	assert.Error(t, Bootstrap{Tokens: []BootstrapToken{{Name: "deployer"}}}.Validate(), "secret source is missing")
+	assert.Error(t, Bootstrap{Tokens: []BootstrapToken{{Name: "deployer", SecretEnv: "A", SecretFile: "b"}}}.Validate())
+	assert.Error(t, Bootstrap{Tokens: []BootstrapToken{{Name: "deployer", SecretEnv: "A", Secret: "secret"}}}.Validate(), "secret lacks the prefix")
+	assert.NoError(t, Bootstrap{Tokens: []BootstrapToken{{Name: "deployer", SecretEnv: "A", Secret: ApiTokenPrefix + "secret"}}}.Validate())
+
+	fact := BootstrapFact{Value: 1}
+	assert.Error(t, Bootstrap{Facts: []BootstrapFact{fact, fact}}.Validate())
+	labeled := fact
+	labeled.Name = "one"
+	assert.NoError(t, Bootstrap{Facts: []BootstrapFact{fact, labeled}}.Validate())
+}
+
+func TestBootstrapFactGetId(t *testing.T) {
+	t.Parallel()
+
+	a := BootstrapFact{Value: map[string]interface{}{"a": 1, "b": 2}}
+	b := BootstrapFact{Value: map[string]interface{}{"b": 2, "a": 1}}
+
+	idA, err := a.GetId()
+	assert.NoError(t, err)
+	idB, err := b.GetId()
+	assert.NoError(t, err)
+	assert.Equal(t, idA, idB)
+
+	b.Value = map[string]interface{}{"a": 1, "b": 3}
+	idB, err = b.GetId()
+	assert.NoError(t, err)
+	assert.NotEqual(t, idA, idB)
+
+	b.ID = &idA
+	idB, err = b.GetId()
+	assert.NoError(t, err)
+	assert.Equal(t, idA, idB)
+}
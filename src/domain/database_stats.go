@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// An overview of the database for operators without access to it.
+type DatabaseStats struct {
+	// Largest first.
+	Tables               []DatabaseTableStats       `json:"tables"`
+	UnhandledNomadEvents []UnhandledNomadEventStats `json:"unhandled_nomad_events"`
+	FactBytesByProject   []ProjectFactBytes         `json:"fact_bytes_by_project"`
+	FactBinaryTiers      []FactBinaryTierStats      `json:"fact_binary_tiers"`
+	Retention            DatabaseRetentionStats     `json:"retention"`
+}
+
+type DatabaseTableStats struct {
+	Name string `json:"name"`
+	// Estimated from the statistics Postgres keeps
+	// as counting would take long for big tables.
+	Rows       int64 `json:"rows" db:"live_rows"`
+	DeadRows   int64 `json:"dead_rows" db:"dead_rows"`
+	TableBytes int64 `json:"table_bytes" db:"table_bytes"`
+	IndexBytes int64 `json:"index_bytes" db:"index_bytes"`
+	// Rough estimate of how much smaller the B-tree indexes on columns
+	// would be if they were rebuilt. Indexes on expressions are left out.
+	IndexBloatBytes int64      `json:"index_bloat_bytes" db:"index_bloat_bytes"`
+	LastVacuum      *time.Time `json:"last_vacuum,omitempty" db:"last_vacuum"`
+	LastAnalyze     *time.Time `json:"last_analyze,omitempty" db:"last_analyze"`
+}
+
+// Nomad events are not timestamped so the oldest is the one with the lowest index.
+type UnhandledNomadEventStats struct {
+	NomadCluster string `json:"nomad_cluster" db:"nomad_cluster"`
+	Count        int64  `json:"count"`
+	OldestIndex  int64  `json:"oldest_index" db:"oldest_index"`
+}
+
+// How much facts take, counted like for quotas.
+type ProjectFactBytes struct {
+	// Empty for facts that were not published by a Run.
+	Project string `json:"project"`
+	Facts   int64  `json:"facts"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// How far Cicero's own cleanup is behind.
+type DatabaseRetentionStats struct {
+	// Finished Runs whose Nomad job was not seen garbage collected yet.
+	RunsWithoutNomadJobGC int64 `json:"runs_without_nomad_job_gc" db:"runs_without_nomad_job_gc"`
+	// When the oldest of them finished.
+	OldestWithoutNomadJobGC *time.Time `json:"oldest_without_nomad_job_gc,omitempty" db:"oldest_without_nomad_job_gc"`
+	// Finished Runs whose log was not archived yet,
+	// only meaningful if logs are archived.
+	RunsWithoutLogArchive   int64      `json:"runs_without_log_archive" db:"runs_without_log_archive"`
+	OldestWithoutLogArchive *time.Time `json:"oldest_without_log_archive,omitempty" db:"oldest_without_log_archive"`
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// How often a digest is sent.
+type DigestPeriod string
+
+const (
+	DigestPeriodDaily  DigestPeriod = "daily"
+	DigestPeriodWeekly DigestPeriod = "weekly"
+)
+
+func ParseDigestPeriod(str string) (DigestPeriod, error) {
+	switch period := DigestPeriod(str); period {
+	case DigestPeriodDaily, DigestPeriodWeekly:
+		return period, nil
+	default:
+		return "", errors.Errorf("Invalid digest period %q, must be %q or %q", str, DigestPeriodDaily, DigestPeriodWeekly)
+	}
+}
+
+func (self DigestPeriod) Duration() time.Duration {
+	if self == DigestPeriodWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Someone who is emailed a digest of failed Runs.
+type DigestSubscription struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	// Only Runs of actions of this project are included, all if nil.
+	Project    *string      `json:"project,omitempty"`
+	Period     DigestPeriod `json:"period"`
+	CreatedBy  string       `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastSentAt *time.Time   `json:"last_sent_at,omitempty" db:"last_sent_at"`
+}
+
+// Returns the start of the time the next digest covers.
+func (self DigestSubscription) Since(now time.Time) time.Time {
+	if self.LastSentAt != nil {
+		return *self.LastSentAt
+	}
+	return now.Add(-self.Period.Duration())
+}
+
+func (self DigestSubscription) Due(now time.Time) bool {
+	return self.LastSentAt == nil || !now.Before(self.LastSentAt.Add(self.Period.Duration()))
+}
+
+// A Run that failed with what is needed to report it.
+type FailedRun struct {
+	Run
+	ActionName string `json:"action_name" db:"action_name"`
+	Project    string `json:"project"`
+	// Last lines of the Run's log or the reasons it was denied.
+	Excerpt []string `json:"excerpt" db:"-"`
+}
+
+// The Runs that failed between From and To grouped by action.
+type Digest struct {
+	Subscription DigestSubscription `json:"subscription"`
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Actions      []DigestAction     `json:"actions"`
+}
+
+type DigestAction struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	// Latest first.
+	Runs []FailedRun `json:"runs"`
+}
+
+// Groups the Runs by action ordered by name.
+func NewDigest(subscription DigestSubscription, from, to time.Time, runs []FailedRun) Digest {
+	digest := Digest{Subscription: subscription, From: from, To: to, Actions: []DigestAction{}}
+
+	indices := map[string]int{}
+	for _, run := range runs {
+		i, ok := indices[run.ActionName]
+		if !ok {
+			i = len(digest.Actions)
+			indices[run.ActionName] = i
+			digest.Actions = append(digest.Actions, DigestAction{Name: run.ActionName, Project: run.Project})
+		}
+		digest.Actions[i].Runs = append(digest.Actions[i].Runs, run)
+	}
+
+	sort.Slice(digest.Actions, func(i, j int) bool {
+		return digest.Actions[i].Name < digest.Actions[j].Name
+	})
+	for _, action := range digest.Actions {
+		runs := action.Runs
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].CreatedAt.After(runs[j].CreatedAt)
+		})
+	}
+
+	return digest
+}
+
+func (self Digest) Runs() (runs int) {
+	for _, action := range self.Actions {
+		runs += len(action.Runs)
+	}
+	return
+}
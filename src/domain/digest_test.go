@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDigest(t *testing.T) {
+	t.Parallel()
+
+	// given
+	from := time.Date(2022, time.October, 18, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	runs := []FailedRun{
+		{Run: Run{CreatedAt: from.Add(1 * time.Hour)}, ActionName: "test", Project: "cicero"},
+		{Run: Run{CreatedAt: from.Add(2 * time.Hour)}, ActionName: "build", Project: "cicero"},
+		{Run: Run{CreatedAt: from.Add(3 * time.Hour)}, ActionName: "test", Project: "cicero"},
+	}
+
+	// when
+	digest := NewDigest(DigestSubscription{Period: DigestPeriodDaily}, from, to, runs)
+
+	// then
+	assert.Equal(t, 3, digest.Runs())
+	if assert.Len(t, digest.Actions, 2) {
+		assert.Equal(t, "build", digest.Actions[0].Name)
+		assert.Len(t, digest.Actions[0].Runs, 1)
+
+		assert.Equal(t, "test", digest.Actions[1].Name)
+		if assert.Len(t, digest.Actions[1].Runs, 2) {
+			assert.Equal(t, runs[2].CreatedAt, digest.Actions[1].Runs[0].CreatedAt)
+			assert.Equal(t, runs[0].CreatedAt, digest.Actions[1].Runs[1].CreatedAt)
+		}
+	}
+}
+
+func TestDigestSubscriptionDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, time.October, 19, 12, 0, 0, 0, time.UTC)
+	subscription := DigestSubscription{Period: DigestPeriodWeekly}
+
+	assert.True(t, subscription.Due(now))
+	assert.Equal(t, now.AddDate(0, 0, -7), subscription.Since(now))
+
+	lastSentAt := now.AddDate(0, 0, -6)
+	subscription.LastSentAt = &lastSentAt
+	assert.False(t, subscription.Due(now))
+	assert.Equal(t, lastSentAt, subscription.Since(now))
+
+	assert.True(t, subscription.Due(now.AddDate(0, 0, 1)))
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+// Meta attribute of an action whose successful Runs create a preview environment,
+// like `{teardown: "preview-teardown", ttl: "72h"}`.
+// The Run's output must have the environment's name as var `environment`
+// and may have its URL as var `environment_url`
+// and a CUE value or string that closes it as var `environment_closed_by`.
+const ActionMetaEnvironment = "environment"
+
+// Names of the output vars of Runs that create an environment.
+const (
+	EnvironmentVarName     = "environment"
+	EnvironmentVarURL      = "environment_url"
+	EnvironmentVarClosedBy = "environment_closed_by"
+)
+
+// Name of the field of the fact published to tear down an environment.
+const EnvironmentTeardownFact = "cicero_environment_teardown"
+
+type ActionEnvironment struct {
+	// Name of the action to tear the environment down.
+	// Its inputs should match the fact published to tear it down.
+	Teardown string `json:"teardown"`
+	// How long the environment lives after it was last updated.
+	// Zero if it only goes away when closed or torn down manually.
+	TTL time.Duration `json:"-"`
+}
+
+// Returns nil if the action does not create environments.
+func (self Action) Environment() (*ActionEnvironment, error) {
+	var decoded struct {
+		ActionEnvironment
+		TTL string `json:"ttl"`
+	}
+
+	if ok, err := self.decodeMeta(ActionMetaEnvironment, &decoded); err != nil || !ok {
+		return nil, errors.WithMessagef(err, "Action meta %q must be a struct", ActionMetaEnvironment)
+	}
+
+	if decoded.Teardown == "" {
+		return nil, errors.Errorf("Action meta %q must have the name of the teardown action", ActionMetaEnvironment)
+	}
+
+	env := decoded.ActionEnvironment
+	if decoded.TTL != "" {
+		ttl, err := time.ParseDuration(decoded.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, errors.Errorf("Action meta %q has an invalid ttl, must be a positive duration: %q", ActionMetaEnvironment, decoded.TTL)
+		}
+		env.TTL = ttl
+	}
+
+	return &env, nil
+}
+
+type EnvironmentTeardownReason string
+
+const (
+	EnvironmentTeardownReasonExpired EnvironmentTeardownReason = "expired"
+	EnvironmentTeardownReasonClosed  EnvironmentTeardownReason = "closed"
+	EnvironmentTeardownReasonManual  EnvironmentTeardownReason = "manual"
+)
+
+// A preview environment created by a Run.
+// Later Runs of the same action that create one of the same name update it.
+type Environment struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	URL        *string   `json:"url,omitempty"`
+	ActionName string    `json:"action_name" db:"action_name"`
+	// The latest Run that created or updated the environment.
+	RunId          uuid.UUID `json:"run_id" db:"run_id"`
+	TeardownAction string    `json:"teardown_action" db:"teardown_action"`
+	// CUE that a fact must match to close the environment.
+	ClosedBy *string `json:"closed_by,omitempty" db:"closed_by"`
+	// The facts up to this one were checked against ClosedBy.
+	FactSeq        int64                      `json:"-" db:"fact_seq"`
+	CreatedAt      time.Time                  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time                  `json:"updated_at" db:"updated_at"`
+	ExpiresAt      *time.Time                 `json:"expires_at,omitempty" db:"expires_at"`
+	TornDownAt     *time.Time                 `json:"torn_down_at,omitempty" db:"torn_down_at"`
+	TeardownReason *EnvironmentTeardownReason `json:"teardown_reason,omitempty" db:"teardown_reason"`
+}
+
+// Returns the environment that the successful Run of the action created
+// as described by its output, nil if the action does not create environments.
+func NewEnvironment(action Action, run Run, output RunOutput) (*Environment, error) {
+	actionEnv, err := action.Environment()
+	if err != nil || actionEnv == nil {
+		return nil, err
+	}
+
+	env := Environment{
+		ActionName:     action.Name,
+		RunId:          run.NomadJobID,
+		TeardownAction: actionEnv.Teardown,
+	}
+
+	if env.Name, err = output.VarString(EnvironmentVarName); err != nil {
+		return nil, err
+	} else if env.Name == "" {
+		return nil, errors.Errorf("Output var %q must not be empty", EnvironmentVarName)
+	}
+
+	if _, ok := output.Var(EnvironmentVarURL); ok {
+		url, err := output.VarString(EnvironmentVarURL)
+		if err != nil {
+			return nil, err
+		}
+		env.URL = &url
+	}
+
+	switch closedBy := output.Vars[EnvironmentVarClosedBy].(type) {
+	case nil:
+	case string:
+		env.ClosedBy = &closedBy
+	default:
+		// Concrete values are matched as they are, JSON is valid CUE.
+		encoded, err := json.Marshal(closedBy)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not encode output var %q", EnvironmentVarClosedBy)
+		}
+		closedByStr := string(encoded)
+		env.ClosedBy = &closedByStr
+	}
+	if env.ClosedBy != nil {
+		if err := util.CUEString(*env.ClosedBy).Value(nil, nil).Err(); err != nil {
+			return nil, errors.WithMessagef(err, "Output var %q is not valid CUE", EnvironmentVarClosedBy)
+		}
+	}
+
+	if actionEnv.TTL > 0 {
+		expiresAt := time.Now().Add(actionEnv.TTL).UTC()
+		env.ExpiresAt = &expiresAt
+	}
+
+	return &env, nil
+}
+
+func (self Environment) Open() bool {
+	return self.TornDownAt == nil
+}
+
+// Returns the value of the fact that is published
+// to invoke the teardown action.
+func (self Environment) TeardownFact(reason EnvironmentTeardownReason) map[string]interface{} {
+	teardown := map[string]interface{}{
+		"action": self.TeardownAction,
+		"name":   self.Name,
+		"run":    self.RunId.String(),
+		"reason": string(reason),
+	}
+	if self.URL != nil {
+		teardown["url"] = *self.URL
+	}
+	return map[string]interface{}{EnvironmentTeardownFact: teardown}
+}
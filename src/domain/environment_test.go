@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionEnvironment(t *testing.T) {
+	t.Parallel()
+
+	action := Action{}
+
+	env, err := action.Environment()
+	assert.NoError(t, err)
+	assert.Nil(t, env)
+
+	action.Meta = map[string]interface{}{ActionMetaEnvironment: map[string]interface{}{
+		"teardown": "preview-teardown",
+		"ttl":      "72h",
+	}}
+	env, err = action.Environment()
+	assert.NoError(t, err)
+	assert.Equal(t, &ActionEnvironment{Teardown: "preview-teardown", TTL: 72 * time.Hour}, env)
+
+	for _, invalid := range []interface{}{
+		"preview-teardown",
+		map[string]interface{}{},
+		map[string]interface{}{"teardown": "preview-teardown", "ttl": "forever"},
+		map[string]interface{}{"teardown": "preview-teardown", "ttl": "-1h"},
+	} {
+		action.Meta[ActionMetaEnvironment] = invalid
+		_, err = action.Environment()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNewEnvironment(t *testing.T) {
+	t.Parallel()
+
+	// given
+	action := Action{
+		Name: "preview",
+		ActionDefinition: ActionDefinition{
+			Meta: map[string]interface{}{ActionMetaEnvironment: map[string]interface{}{
+				"teardown": "preview-teardown",
+				"ttl":      "1h",
+			}},
+		},
+	}
+	run := Run{NomadJobID: uuid.New()}
+	output := RunOutput{Vars: map[string]interface{}{
+		EnvironmentVarName:     "pr-42",
+		EnvironmentVarURL:      "https://pr-42.example.com",
+		EnvironmentVarClosedBy: map[string]interface{}{"pr": 42.0, "action": "closed"},
+	}}
+
+	// when
+	env, err := NewEnvironment(action, run, output)
+
+	// then
+	if assert.NoError(t, err) {
+		assert.Equal(t, "pr-42", env.Name)
+		assert.Equal(t, "https://pr-42.example.com", *env.URL)
+		assert.Equal(t, "preview", env.ActionName)
+		assert.Equal(t, run.NomadJobID, env.RunId)
+		assert.Equal(t, "preview-teardown", env.TeardownAction)
+		assert.Equal(t, `{"action":"closed","pr":42}`, *env.ClosedBy)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *env.ExpiresAt, time.Minute)
+
+		assert.Equal(t, map[string]interface{}{EnvironmentTeardownFact: map[string]interface{}{
+			"action": "preview-teardown",
+			"name":   "pr-42",
+			"url":    "https://pr-42.example.com",
+			"run":    run.NomadJobID.String(),
+			"reason": "closed",
+		}}, env.TeardownFact(EnvironmentTeardownReasonClosed))
+	}
+
+	output.Vars[EnvironmentVarClosedBy] = `{pr: 42, action: "closed"`
+	_, err = NewEnvironment(action, run, output)
+	assert.Error(t, err, "closed_by is not valid CUE")
+
+	delete(output.Vars, EnvironmentVarName)
+	_, err = NewEnvironment(action, run, output)
+	assert.Error(t, err, "the name is missing")
+
+	env, err = NewEnvironment(Action{}, run, output)
+	assert.NoError(t, err)
+	assert.Nil(t, env)
+}
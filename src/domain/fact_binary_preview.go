@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// What a fact's binary was recognized as.
+type FactBinaryPreviewKind string
+
+const (
+	FactBinaryPreviewKindText    FactBinaryPreviewKind = "text"
+	FactBinaryPreviewKindJSON    FactBinaryPreviewKind = "json"
+	FactBinaryPreviewKindImage   FactBinaryPreviewKind = "image"
+	FactBinaryPreviewKindArchive FactBinaryPreviewKind = "archive"
+	// Anything that cannot be previewed.
+	FactBinaryPreviewKindOther FactBinaryPreviewKind = "other"
+)
+
+const (
+	// Maximum number of bytes of text shown.
+	FactBinaryPreviewTextLimit = 256 * 1024
+	// Larger images are not decoded.
+	FactBinaryPreviewImageLimit = 16 * 1024 * 1024
+	// Images with more pixels are not decoded
+	// as they would take too much memory.
+	FactBinaryPreviewImagePixels = 40_000_000
+	// Maximum width and height of thumbnails.
+	FactBinaryPreviewThumbnailSize = 256
+	// Maximum number of entries listed of an archive.
+	FactBinaryPreviewArchiveEntries = 1000
+	// Maximum number of bytes decompressed to list an archive.
+	FactBinaryPreviewArchiveLimit = 256 * 1024 * 1024
+)
+
+// A size-limited preview of a fact's binary.
+type FactBinaryPreview struct {
+	Kind FactBinaryPreviewKind `json:"kind"`
+	// As sniffed from the first bytes.
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Whether the preview only shows the beginning of the text
+	// or some of the entries of the archive.
+	Truncated bool `json:"truncated,omitempty"`
+	// Text, or JSON indented with tabs if it is valid and was not truncated.
+	Text string `json:"text,omitempty"`
+	// PNG that fits into a square of FactBinaryPreviewThumbnailSize pixels.
+	Thumbnail []byte `json:"thumbnail,omitempty"`
+	// Of the image, not its thumbnail.
+	Width   int                      `json:"width,omitempty"`
+	Height  int                      `json:"height,omitempty"`
+	Entries []FactBinaryPreviewEntry `json:"entries,omitempty"`
+}
+
+// An entry of an archive.
+type FactBinaryPreviewEntry struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Mode     int64     `json:"mode"`
+	ModTime  time.Time `json:"mod_time"`
+	Linkname string    `json:"linkname,omitempty"`
+}
+
+// Recognizes the binary by its first bytes and previews it.
+func NewFactBinaryPreview(binary io.ReadSeeker) (preview FactBinaryPreview, err error) {
+	if preview.Size, err = binary.Seek(0, io.SeekEnd); err != nil {
+		return
+	}
+	if _, err = binary.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(binary, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return
+	}
+	err = nil
+	head = head[:n]
+
+	preview.ContentType = http.DetectContentType(head)
+	preview.Kind = FactBinaryPreviewKindOther
+
+	if _, err = binary.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	switch {
+	case isTar(head):
+		preview.ContentType = "application/x-tar"
+		err = preview.listArchive(binary)
+	case preview.ContentType == "application/x-gzip":
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(binary); err != nil {
+			// Not actually gzip so there is nothing to preview.
+			return preview, nil
+		}
+		defer gz.Close()
+
+		head = make([]byte, 512)
+		n, _ := io.ReadFull(gz, head)
+		head = head[:n]
+		if isTar(head) {
+			err = preview.listArchive(io.MultiReader(bytes.NewReader(head), io.LimitReader(gz, FactBinaryPreviewArchiveLimit)))
+		}
+	case preview.ContentType == "image/png", preview.ContentType == "image/jpeg", preview.ContentType == "image/gif":
+		err = preview.thumbnail(binary)
+	case strings.HasPrefix(preview.ContentType, "text/plain"):
+		err = preview.text(binary)
+	}
+
+	return
+}
+
+// Tar archives have a magic string at offset 257 of their first header.
+func isTar(head []byte) bool {
+	return len(head) >= 262 && string(head[257:262]) == "ustar"
+}
+
+func (self *FactBinaryPreview) text(binary io.Reader) error {
+	text, err := io.ReadAll(io.LimitReader(binary, FactBinaryPreviewTextLimit+1))
+	if err != nil {
+		return err
+	}
+
+	if len(text) > FactBinaryPreviewTextLimit {
+		self.Truncated = true
+		text = text[:FactBinaryPreviewTextLimit]
+		// Do not cut a character in half.
+		for i := 0; i < utf8.UTFMax && len(text) > 0 && !utf8.Valid(text); i++ {
+			text = text[:len(text)-1]
+		}
+	}
+
+	if !utf8.Valid(text) {
+		return nil
+	}
+
+	self.Kind = FactBinaryPreviewKindText
+	self.Text = string(text)
+
+	if !self.Truncated && json.Valid(text) {
+		buf := &bytes.Buffer{}
+		if err := json.Indent(buf, text, "", "\t"); err != nil {
+			return err
+		}
+		self.Kind = FactBinaryPreviewKindJSON
+		self.Text = buf.String()
+	}
+
+	return nil
+}
+
+func (self *FactBinaryPreview) thumbnail(binary io.ReadSeeker) error {
+	config, _, err := image.DecodeConfig(binary)
+	if err != nil {
+		// Not actually an image.
+		return nil
+	}
+	self.Kind = FactBinaryPreviewKindImage
+	self.Width = config.Width
+	self.Height = config.Height
+
+	if self.Size > FactBinaryPreviewImageLimit || config.Width*config.Height > FactBinaryPreviewImagePixels {
+		return nil
+	}
+
+	if _, err := binary.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(binary)
+	if err != nil {
+		return errors.WithMessage(err, "Could not decode image")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, scaleImage(img, FactBinaryPreviewThumbnailSize)); err != nil {
+		return errors.WithMessage(err, "Could not encode thumbnail")
+	}
+	self.Thumbnail = buf.Bytes()
+
+	return nil
+}
+
+// Scales the image down to fit into a square of the given size
+// by picking the nearest pixel, which is good enough for thumbnails.
+func scaleImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	scaledWidth, scaledHeight := size, size
+	if width > height {
+		scaledHeight = height * size / width
+	} else {
+		scaledWidth = width * size / height
+	}
+	if scaledWidth == 0 {
+		scaledWidth = 1
+	}
+	if scaledHeight == 0 {
+		scaledHeight = 1
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	for y := 0; y < scaledHeight; y++ {
+		for x := 0; x < scaledWidth; x++ {
+			scaled.Set(x, y, img.At(
+				bounds.Min.X+x*width/scaledWidth,
+				bounds.Min.Y+y*height/scaledHeight,
+			))
+		}
+	}
+	return scaled
+}
+
+func (self *FactBinaryPreview) listArchive(archive io.Reader) error {
+	self.Kind = FactBinaryPreviewKindArchive
+	self.Entries = []FactBinaryPreviewEntry{}
+
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			// List what could be read of a broken archive.
+			self.Truncated = true
+			break
+		}
+
+		if len(self.Entries) == FactBinaryPreviewArchiveEntries {
+			self.Truncated = true
+			break
+		}
+
+		self.Entries = append(self.Entries, FactBinaryPreviewEntry{
+			Name:     header.Name,
+			Type:     tarEntryType(header.Typeflag),
+			Size:     header.Size,
+			Mode:     header.Mode,
+			ModTime:  header.ModTime.UTC(),
+			Linkname: header.Linkname,
+		})
+	}
+
+	return nil
+}
+
+func tarEntryType(typeflag byte) string {
+	switch typeflag {
+	case tar.TypeReg, tar.TypeRegA:
+		return "file"
+	case tar.TypeDir:
+		return "dir"
+	case tar.TypeSymlink:
+		return "symlink"
+	case tar.TypeLink:
+		return "hardlink"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFactBinaryPreview(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json", func(t *testing.T) {
+		preview, err := NewFactBinaryPreview(strings.NewReader(`{"a":[1,2]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, FactBinaryPreviewKindJSON, preview.Kind)
+		assert.Equal(t, int64(11), preview.Size)
+		assert.Equal(t, "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t]\n}", preview.Text)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		preview, err := NewFactBinaryPreview(strings.NewReader(strings.Repeat("ä", FactBinaryPreviewTextLimit)))
+		assert.NoError(t, err)
+		assert.Equal(t, FactBinaryPreviewKindText, preview.Kind)
+		assert.True(t, preview.Truncated)
+		assert.Len(t, preview.Text, FactBinaryPreviewTextLimit)
+	})
+
+	t.Run("image", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, png.Encode(buf, image.NewGray(image.Rect(0, 0, 1024, 512))))
+
+		preview, err := NewFactBinaryPreview(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+		assert.Equal(t, FactBinaryPreviewKindImage, preview.Kind)
+		assert.Equal(t, "image/png", preview.ContentType)
+		assert.Equal(t, 1024, preview.Width)
+
+		thumbnail, err := png.Decode(bytes.NewReader(preview.Thumbnail))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, FactBinaryPreviewThumbnailSize, FactBinaryPreviewThumbnailSize/2), thumbnail.Bounds())
+	})
+
+	t.Run("tarball", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		archive := tar.NewWriter(gz)
+		assert.NoError(t, archive.WriteHeader(&tar.Header{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0o755}))
+		assert.NoError(t, archive.WriteHeader(&tar.Header{Name: "bin/hello", Typeflag: tar.TypeReg, Mode: 0o755, Size: 5}))
+		_, err := archive.Write([]byte("hello"))
+		assert.NoError(t, err)
+		assert.NoError(t, archive.Close())
+		assert.NoError(t, gz.Close())
+
+		preview, err := NewFactBinaryPreview(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+		assert.Equal(t, FactBinaryPreviewKindArchive, preview.Kind)
+		assert.Equal(t, "application/x-gzip", preview.ContentType)
+		assert.Len(t, preview.Entries, 2)
+		assert.Equal(t, "file", preview.Entries[1].Type)
+		assert.Equal(t, int64(5), preview.Entries[1].Size)
+	})
+
+	t.Run("other", func(t *testing.T) {
+		preview, err := NewFactBinaryPreview(bytes.NewReader([]byte{0, 1, 2, 0xff}))
+		assert.NoError(t, err)
+		assert.Equal(t, FactBinaryPreviewKindOther, preview.Kind)
+	})
+}
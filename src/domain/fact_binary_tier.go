@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Where a fact binary is stored, from fastest to cheapest.
+type FactBinaryTier string
+
+const (
+	// In Postgres as it was published.
+	FactBinaryTierHot FactBinaryTier = "hot"
+	// In Postgres compressed with gzip.
+	FactBinaryTierWarm FactBinaryTier = "warm"
+	// In the cold storage directory compressed with gzip.
+	FactBinaryTierCold FactBinaryTier = "cold"
+)
+
+func (self FactBinaryTier) rank() int {
+	switch self {
+	case FactBinaryTierWarm:
+		return 1
+	case FactBinaryTierCold:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func ParseFactBinaryTier(str string) (FactBinaryTier, error) {
+	switch tier := FactBinaryTier(str); tier {
+	case FactBinaryTierHot, FactBinaryTierWarm, FactBinaryTierCold:
+		return tier, nil
+	default:
+		return "", errors.Errorf("Unknown fact binary tier %q, must be one of: hot, warm, cold", str)
+	}
+}
+
+// When fact binaries of some projects move to cheaper tiers.
+// Binaries move back to the hot tier when they are read again.
+type FactBinaryTierPolicy struct {
+	// Regular expressions that match the whole name of the projects
+	// whose Runs published the facts. Matches all if empty,
+	// which is the only way to match facts not published by a Run.
+	Projects []string `json:"projects,omitempty"`
+	// How long after binaries were last accessed they become warm or cold.
+	// Zero for never.
+	WarmAfter time.Duration `json:"-"`
+	ColdAfter time.Duration `json:"-"`
+}
+
+func (self FactBinaryTierPolicy) MarshalJSON() ([]byte, error) {
+	type plain FactBinaryTierPolicy
+	encoded := struct {
+		plain
+		WarmAfter string `json:"warm_after,omitempty"`
+		ColdAfter string `json:"cold_after,omitempty"`
+	}{plain: plain(self)}
+	if self.WarmAfter != 0 {
+		encoded.WarmAfter = self.WarmAfter.String()
+	}
+	if self.ColdAfter != 0 {
+		encoded.ColdAfter = self.ColdAfter.String()
+	}
+	return json.Marshal(encoded)
+}
+
+func (self *FactBinaryTierPolicy) UnmarshalJSON(data []byte) error {
+	type plain FactBinaryTierPolicy
+	var decoded struct {
+		plain
+		WarmAfter string `json:"warm_after"`
+		ColdAfter string `json:"cold_after"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*self = FactBinaryTierPolicy(decoded.plain)
+	for _, d := range []struct {
+		str string
+		ptr *time.Duration
+	}{
+		{decoded.WarmAfter, &self.WarmAfter},
+		{decoded.ColdAfter, &self.ColdAfter},
+	} {
+		if d.str == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.str)
+		if err != nil {
+			return errors.WithMessage(err, "Invalid duration of fact binary tier policy")
+		}
+		*d.ptr = duration
+	}
+	return nil
+}
+
+func (self FactBinaryTierPolicy) Validate() error {
+	if self.WarmAfter < 0 || self.ColdAfter < 0 {
+		return errors.New("Fact binary tier policies must not have negative durations")
+	}
+	if self.WarmAfter != 0 && self.ColdAfter != 0 && self.ColdAfter < self.WarmAfter {
+		return errors.New("Fact binary tier policies must not make binaries cold before they are warm")
+	}
+	for _, pattern := range self.Projects {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.WithMessage(err, "Invalid project pattern of fact binary tier policy")
+		}
+	}
+	return nil
+}
+
+// Whether the policy applies to facts published by Runs of the project,
+// which is empty for facts not published by a Run.
+func (self FactBinaryTierPolicy) Matches(project string) bool {
+	return len(self.Projects) == 0 || (project != "" && matchesAnyWhole(self.Projects, project))
+}
+
+func (self FactBinaryTierPolicy) Tier(lastAccessedAt, now time.Time) FactBinaryTier {
+	idle := now.Sub(lastAccessedAt)
+	switch {
+	case self.ColdAfter != 0 && idle >= self.ColdAfter:
+		return FactBinaryTierCold
+	case self.WarmAfter != 0 && idle >= self.WarmAfter:
+		return FactBinaryTierWarm
+	default:
+		return FactBinaryTierHot
+	}
+}
+
+type FactBinaryTierPolicies []FactBinaryTierPolicy
+
+func (self FactBinaryTierPolicies) Validate() error {
+	for _, policy := range self {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the tier a binary belongs in. The first policy that matches
+// a project of the binary's facts decides for that project, and if
+// the facts are of several projects the fastest of their tiers wins.
+// Binaries stay hot if no policy matches.
+func (self FactBinaryTierPolicies) Tier(binary FactBinaryAccess, now time.Time) FactBinaryTier {
+	projects := binary.Projects
+	if len(projects) == 0 {
+		projects = []string{""}
+	}
+
+	var tier *FactBinaryTier
+	for _, project := range projects {
+		projectTier := FactBinaryTierHot
+		for _, policy := range self {
+			if policy.Matches(project) {
+				projectTier = policy.Tier(binary.LastAccessedAt, now)
+				break
+			}
+		}
+		if tier == nil || projectTier.rank() < tier.rank() {
+			tier = &projectTier
+		}
+	}
+	return *tier
+}
+
+// A fact binary with what decides its tier.
+type FactBinaryAccess struct {
+	Hash string         `json:"hash"`
+	Tier FactBinaryTier `json:"tier"`
+	Size int64          `json:"size"`
+	// When a fact with the binary was last published or read.
+	LastAccessedAt time.Time `json:"last_accessed_at" db:"last_accessed_at"`
+	// Of the Runs that published facts with the binary,
+	// with an empty string for facts not published by a Run.
+	Projects []string `json:"projects"`
+}
+
+// How many binaries and how many of their bytes are in a tier.
+type FactBinaryTierStats struct {
+	Tier     FactBinaryTier `json:"tier"`
+	Binaries int64          `json:"binaries"`
+	Bytes    int64          `json:"bytes"`
+}
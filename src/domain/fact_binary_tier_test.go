@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactBinaryTierPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := FactBinaryTierPolicy{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"projects": ["infra"],
+		"warm_after": "24h",
+		"cold_after": "720h"
+	}`), &policy))
+	assert.Equal(t, 24*time.Hour, policy.WarmAfter)
+	assert.Equal(t, 720*time.Hour, policy.ColdAfter)
+	assert.NoError(t, policy.Validate())
+
+	if encoded, err := json.Marshal(policy); assert.NoError(t, err) {
+		assert.JSONEq(t, `{"projects": ["infra"], "warm_after": "24h0m0s", "cold_after": "720h0m0s"}`, string(encoded))
+	}
+
+	assert.True(t, policy.Matches("infra"))
+	assert.False(t, policy.Matches("infra-staging"))
+	assert.False(t, policy.Matches(""))
+	assert.True(t, FactBinaryTierPolicy{}.Matches(""))
+
+	now := time.Date(2022, 10, 22, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, FactBinaryTierHot, policy.Tier(now.Add(-time.Hour), now))
+	assert.Equal(t, FactBinaryTierWarm, policy.Tier(now.Add(-24*time.Hour), now))
+	assert.Equal(t, FactBinaryTierCold, policy.Tier(now.Add(-720*time.Hour), now))
+
+	assert.Error(t, FactBinaryTierPolicy{WarmAfter: time.Hour, ColdAfter: time.Minute}.Validate())
+	assert.Error(t, FactBinaryTierPolicy{WarmAfter: -time.Hour}.Validate())
+	assert.Error(t, FactBinaryTierPolicy{Projects: []string{"("}}.Validate())
+	assert.Error(t, json.Unmarshal([]byte(`{"warm_after": "a day"}`), &policy))
+}
+
+func TestFactBinaryTierPolicies(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 10, 22, 0, 0, 0, 0, time.UTC)
+	policies := FactBinaryTierPolicies{
+		{Projects: []string{"infra"}, WarmAfter: time.Hour},
+		{WarmAfter: time.Hour, ColdAfter: 24 * time.Hour},
+	}
+
+	idle := func(d time.Duration, projects ...string) FactBinaryAccess {
+		return FactBinaryAccess{LastAccessedAt: now.Add(-d), Projects: projects}
+	}
+
+	assert.Equal(t, FactBinaryTierCold, policies.Tier(idle(48*time.Hour, "web"), now))
+	assert.Equal(t, FactBinaryTierCold, policies.Tier(idle(48*time.Hour, ""), now))
+	assert.Equal(t, FactBinaryTierCold, policies.Tier(idle(48*time.Hour), now))
+
+	// The first matching policy decides.
+	assert.Equal(t, FactBinaryTierWarm, policies.Tier(idle(48*time.Hour, "infra"), now))
+
+	// The fastest tier of the projects wins.
+	assert.Equal(t, FactBinaryTierWarm, policies.Tier(idle(48*time.Hour, "infra", "web"), now))
+	assert.Equal(t, FactBinaryTierHot, policies.Tier(idle(time.Minute, "infra", "web"), now))
+
+	assert.Equal(t, FactBinaryTierHot, FactBinaryTierPolicies{}.Tier(idle(48*time.Hour, "web"), now))
+	assert.Equal(t, FactBinaryTierHot, FactBinaryTierPolicies{policies[0]}.Tier(idle(48*time.Hour, "web"), now))
+}
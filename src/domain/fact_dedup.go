@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Hash of what makes an ingested fact a duplicate of another:
+// its value, binary, labels, and where it came from,
+// so that redeliveries of the same event can be recognized.
+// The binary hash must already be known.
+func (self Fact) DedupHash() (string, error) {
+	value, err := self.SignedValue()
+	if err != nil {
+		return "", errors.WithMessage(err, "Could not encode fact value")
+	}
+
+	tags := append([]string{}, self.Tags...)
+	sort.Strings(tags)
+
+	parts := append([]string{string(value), self.Namespace, self.Name}, tags...)
+	// Ends the tags.
+	parts = append(parts, "")
+	if self.BinaryHash != nil {
+		parts = append(parts, *self.BinaryHash)
+	} else {
+		parts = append(parts, "")
+	}
+	for _, id := range []*uuid.UUID{self.ApiTokenId, self.RunId} {
+		if id != nil {
+			parts = append(parts, id.String())
+		} else {
+			parts = append(parts, "")
+		}
+	}
+
+	hash := sha256.New()
+	for _, part := range parts {
+		// Terminated so that parts cannot run into each other.
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactDedupHash(t *testing.T) {
+	t.Parallel()
+
+	hash := func(fact Fact) string {
+		h, err := fact.DedupHash()
+		assert.NoError(t, err)
+		return h
+	}
+
+	binaryHash := "sha256-abc"
+	tokenId := uuid.New()
+	fact := Fact{
+		Value:      map[string]interface{}{"a": 1, "b": "x"},
+		BinaryHash: &binaryHash,
+		ApiTokenId: &tokenId,
+		FactLabels: FactLabels{Name: "push", Tags: []string{"b", "a"}},
+	}
+
+	same := fact
+	same.ID = uuid.New()
+	same.Value = map[string]interface{}{"b": "x", "a": 1}
+	same.Tags = []string{"a", "b"}
+	assert.Equal(t, hash(fact), hash(same), "IDs, key order, and tag order do not matter")
+
+	for name, modify := range map[string]func(*Fact){
+		"value":     func(f *Fact) { f.Value = map[string]interface{}{"a": 2, "b": "x"} },
+		"binary":    func(f *Fact) { f.BinaryHash = nil },
+		"token":     func(f *Fact) { id := uuid.New(); f.ApiTokenId = &id },
+		"run":       func(f *Fact) { id := uuid.New(); f.RunId = &id },
+		"namespace": func(f *Fact) { f.Namespace = "ci" },
+		"name":      func(f *Fact) { f.Name = "pull" },
+		"tags":      func(f *Fact) { f.Tags = []string{"a"} },
+		"boundary":  func(f *Fact) { f.Name = ""; f.Tags = []string{"push", "b", "a"} },
+	} {
+		other := fact
+		modify(&other)
+		assert.NotEqual(t, hash(fact), hash(other), name)
+	}
+}
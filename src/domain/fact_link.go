@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// How a fact relates to the fact it links to.
+type FactLinkKind string
+
+const (
+	// The fact replaces the target, like a corrected build result.
+	FactLinkSupersedes FactLinkKind = "supersedes"
+	// The fact happened because of the target,
+	// like a build because of a pull request.
+	FactLinkCausedBy FactLinkKind = "caused-by"
+	// The fact is about the same thing as the target
+	// without one causing the other.
+	FactLinkCorrelatesWith FactLinkKind = "correlates-with"
+)
+
+func ParseFactLinkKind(str string) (FactLinkKind, error) {
+	switch kind := FactLinkKind(str); kind {
+	case FactLinkSupersedes, FactLinkCausedBy, FactLinkCorrelatesWith:
+		return kind, nil
+	default:
+		return "", errors.Errorf("Invalid fact link kind %q, must be %q, %q, or %q", str, FactLinkSupersedes, FactLinkCausedBy, FactLinkCorrelatesWith)
+	}
+}
+
+// A reference from one fact to another.
+type FactLink struct {
+	FactId    uuid.UUID    `json:"fact_id" db:"fact_id"`
+	Kind      FactLinkKind `json:"kind"`
+	TargetId  uuid.UUID    `json:"target_id" db:"target_id"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+func (self FactLink) Validate() error {
+	if _, err := ParseFactLinkKind(string(self.Kind)); err != nil {
+		return err
+	}
+	if self.FactId == self.TargetId {
+		return errors.Errorf("Fact %q cannot link to itself", self.FactId)
+	}
+	return nil
+}
+
+// The links of a fact to others and from others to it.
+type FactLinks struct {
+	Outgoing []FactLink `json:"outgoing"`
+	Incoming []FactLink `json:"incoming"`
+}
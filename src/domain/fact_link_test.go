@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactLinkValidate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	link := FactLink{FactId: uuid.New(), Kind: FactLinkCausedBy, TargetId: uuid.New()}
+
+	// then
+	assert.Nil(t, link.Validate())
+
+	// when
+	link.Kind = "caused_by"
+
+	// then
+	assert.EqualError(t, link.Validate(), `Invalid fact link kind "caused_by", must be "supersedes", "caused-by", or "correlates-with"`)
+
+	// when
+	link.Kind = FactLinkSupersedes
+	link.TargetId = link.FactId
+
+	// then
+	assert.Error(t, link.Validate(), "a fact cannot link to itself")
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+// Replaces the value with the redacted one and seals the original
+// in ValueSealed so that it is not stored in plaintext.
+// Returns the original value, which is the value itself
+// and left alone if the redactions hide nothing of it.
+func (self *Fact) SealRedacted(redactions util.Redactions, unsealer *Unsealer) (interface{}, error) {
+	original := self.Value
+
+	redacted := redactions.Apply(original)
+	if reflect.DeepEqual(redacted, original) {
+		return original, nil
+	}
+
+	encoded, err := json.Marshal(original)
+	if err != nil {
+		return original, errors.WithMessage(err, "Could not encode fact value")
+	}
+
+	sealed, err := unsealer.SealBytes(encoded)
+	if err != nil {
+		return original, err
+	}
+
+	self.Value = redacted
+	self.ValueSealed = sealed
+	return original, nil
+}
+
+// Restores the original value if it was sealed by `SealRedacted()`.
+func (self *Fact) UnsealRedacted(unsealer *Unsealer) error {
+	if self.ValueSealed == nil {
+		return nil
+	}
+
+	opened, err := unsealer.UnsealBytes(self.ValueSealed)
+	if err != nil {
+		return errors.WithMessagef(err, "Could not unseal value of fact %q", self.ID)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(opened, &value); err != nil {
+		return errors.WithMessagef(err, "Could not decode value of fact %q", self.ID)
+	}
+	self.Value = value
+
+	return nil
+}
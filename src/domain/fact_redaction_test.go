@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+func TestFactSealRedacted(t *testing.T) {
+	t.Parallel()
+
+	private, _, err := GenerateSealKey()
+	assert.NoError(t, err)
+	unsealer, err := NewUnsealer(*private)
+	assert.NoError(t, err)
+
+	redaction, err := util.NewRedaction("$.token", "")
+	assert.NoError(t, err)
+	redactions := util.Redactions{redaction}
+
+	value := map[string]interface{}{"token": "hunter2", "branch": "main"}
+	fact := Fact{Value: value}
+
+	original, err := fact.SealRedacted(redactions, unsealer)
+	assert.NoError(t, err)
+	assert.Equal(t, value, original)
+	assert.Equal(t, map[string]interface{}{"token": util.Redacted, "branch": "main"}, fact.Value)
+	assert.NotEmpty(t, fact.ValueSealed)
+	assert.NotContains(t, string(fact.ValueSealed), "hunter2")
+
+	assert.NoError(t, fact.UnsealRedacted(unsealer))
+	assert.Equal(t, value, fact.Value)
+
+	assert.Error(t, (&Fact{ValueSealed: fact.ValueSealed}).UnsealRedacted(nil))
+
+	// Nothing to redact, nothing to seal.
+	plain := Fact{Value: map[string]interface{}{"branch": "main"}}
+	_, err = plain.SealRedacted(redactions, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, plain.ValueSealed)
+	assert.NoError(t, plain.UnsealRedacted(nil))
+
+	_, err = (&Fact{Value: value}).SealRedacted(redactions, nil)
+	assert.Error(t, err, "cannot seal without a key")
+}
@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+)
+
+// A historical fact that was passed to an input of an action
+// as if it had just been published.
+type FactReplay struct {
+	FactId     uuid.UUID `json:"fact_id"`
+	ActionId   uuid.UUID `json:"action_id"`
+	ActionName string    `json:"action_name"`
+	Input      string    `json:"input"`
+	// Nil if the replay was only planned.
+	InvocationId *uuid.UUID `json:"invocation_id,omitempty"`
+}
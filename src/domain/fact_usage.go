@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How often a fact was read through the API and how often it invoked actions.
+type FactUsage struct {
+	FactId uuid.UUID `json:"fact_id"`
+	Reads  int64     `json:"reads"`
+	// Most reads first.
+	Readers []FactReader `json:"readers"`
+	// How many invocations had the fact as input.
+	Invocations   int64      `json:"invocations"`
+	LastInvokedAt *time.Time `json:"last_invoked_at,omitempty" db:"last_invoked_at"`
+}
+
+// Who read a fact through the API.
+type FactReader struct {
+	// Empty if the reader was not authenticated.
+	Identity    string    `json:"identity"`
+	Reads       int64     `json:"reads"`
+	FirstReadAt time.Time `json:"first_read_at" db:"first_read_at"`
+	LastReadAt  time.Time `json:"last_read_at" db:"last_read_at"`
+}
+
+// Usage of the facts whose value has a top-level field,
+// which tells what kind of fact it is by convention.
+// Fields of facts that are published but never read
+// and never invoke an action point to producers nobody needs anymore.
+type FactFieldUsage struct {
+	Field         string     `json:"field"`
+	Facts         int64      `json:"facts"`
+	LastCreatedAt time.Time  `json:"last_created_at" db:"last_created_at"`
+	Reads         int64      `json:"reads"`
+	Readers       int64      `json:"readers"`
+	LastReadAt    *time.Time `json:"last_read_at,omitempty" db:"last_read_at"`
+	// How many of the facts were input of an invocation.
+	InvokingFacts int64 `json:"invoking_facts" db:"invoking_facts"`
+	Invocations   int64 `json:"invocations"`
+}
@@ -0,0 +1,162 @@
+package domain
+
+import (
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action with the Nomad constraints,
+// affinities, and spreads to add to the jobs of its Runs.
+// Like `{constraints: [{attribute: "${attr.cpu.arch}", value: "amd64"}]}`.
+const ActionMetaNomadScheduling = "nomad_scheduling"
+
+// Where Nomad may and should place the allocations of a job,
+// added to the job on top of what the evaluator rendered.
+type JobScheduling struct {
+	Constraints []JobConstraint `json:"constraints,omitempty"`
+	Affinities  []JobAffinity   `json:"affinities,omitempty"`
+	Spreads     []JobSpread     `json:"spreads,omitempty"`
+}
+
+type JobConstraint struct {
+	Attribute string `json:"attribute,omitempty"`
+	// Defaults to "=".
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+type JobAffinity struct {
+	Attribute string `json:"attribute"`
+	// Defaults to "=".
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value"`
+	// From -100 to 100 except 0, negative to avoid nodes.
+	Weight int8 `json:"weight"`
+}
+
+type JobSpread struct {
+	Attribute string `json:"attribute"`
+	// From 1 to 100, defaults to 50.
+	Weight  int8              `json:"weight,omitempty"`
+	Targets []JobSpreadTarget `json:"targets,omitempty"`
+}
+
+type JobSpreadTarget struct {
+	Value   string `json:"value"`
+	Percent uint8  `json:"percent"`
+}
+
+var jobConstraintOperators = map[string]bool{
+	"=": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true,
+	nomad.ConstraintDistinctHosts:     true,
+	nomad.ConstraintDistinctProperty:  true,
+	nomad.ConstraintRegex:             true,
+	nomad.ConstraintVersion:           true,
+	nomad.ConstraintSemver:            true,
+	nomad.ConstraintSetContains:       true,
+	nomad.ConstraintSetContainsAll:    true,
+	nomad.ConstraintSetContainsAny:    true,
+	nomad.ConstraintAttributeIsSet:    true,
+	nomad.ConstraintAttributeIsNotSet: true,
+}
+
+// Affinities do not support the operators that only make sense as constraints.
+var jobAffinityOperators = map[string]bool{
+	"=": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true,
+	nomad.ConstraintRegex:          true,
+	nomad.ConstraintVersion:        true,
+	nomad.ConstraintSemver:         true,
+	nomad.ConstraintSetContainsAll: true,
+	nomad.ConstraintSetContainsAny: true,
+}
+
+func (self JobScheduling) Validate() error {
+	for i, constraint := range self.Constraints {
+		if constraint.Operator != "" && !jobConstraintOperators[constraint.Operator] {
+			return errors.Errorf("constraints[%d] has unknown operator %q", i, constraint.Operator)
+		}
+		if constraint.Attribute == "" && constraint.Operator != nomad.ConstraintDistinctHosts {
+			return errors.Errorf("constraints[%d] must have an attribute", i)
+		}
+	}
+
+	for i, affinity := range self.Affinities {
+		if affinity.Operator != "" && !jobAffinityOperators[affinity.Operator] {
+			return errors.Errorf("affinities[%d] has unknown operator %q", i, affinity.Operator)
+		}
+		if affinity.Attribute == "" {
+			return errors.Errorf("affinities[%d] must have an attribute", i)
+		}
+		if affinity.Weight == 0 || affinity.Weight < -100 || affinity.Weight > 100 {
+			return errors.Errorf("affinities[%d] must have a weight from -100 to 100 except 0", i)
+		}
+	}
+
+	for i, spread := range self.Spreads {
+		if spread.Attribute == "" {
+			return errors.Errorf("spreads[%d] must have an attribute", i)
+		}
+		if spread.Weight < 0 || spread.Weight > 100 {
+			return errors.Errorf("spreads[%d] must have a weight from 1 to 100", i)
+		}
+		sum := 0
+		for _, target := range spread.Targets {
+			sum += int(target.Percent)
+		}
+		if sum > 100 {
+			return errors.Errorf("spreads[%d] has targets with more than 100 percent in total", i)
+		}
+	}
+
+	return nil
+}
+
+// Returns the scheduling with the other's stanzas added.
+func (self JobScheduling) Merge(other JobScheduling) JobScheduling {
+	return JobScheduling{
+		Constraints: append(append([]JobConstraint{}, self.Constraints...), other.Constraints...),
+		Affinities:  append(append([]JobAffinity{}, self.Affinities...), other.Affinities...),
+		Spreads:     append(append([]JobSpread{}, self.Spreads...), other.Spreads...),
+	}
+}
+
+// Adds the stanzas to the job.
+func (self JobScheduling) Apply(job *nomad.Job) {
+	for _, constraint := range self.Constraints {
+		operator := constraint.Operator
+		if operator == "" {
+			operator = "="
+		}
+		job.Constrain(nomad.NewConstraint(constraint.Attribute, operator, constraint.Value))
+	}
+
+	for _, affinity := range self.Affinities {
+		operator := affinity.Operator
+		if operator == "" {
+			operator = "="
+		}
+		job.AddAffinity(nomad.NewAffinity(affinity.Attribute, operator, affinity.Value, affinity.Weight))
+	}
+
+	for _, spread := range self.Spreads {
+		weight := spread.Weight
+		if weight == 0 {
+			weight = 50
+		}
+		targets := make([]*nomad.SpreadTarget, len(spread.Targets))
+		for i, target := range spread.Targets {
+			targets[i] = nomad.NewSpreadTarget(target.Value, target.Percent)
+		}
+		job.AddSpread(nomad.NewSpread(spread.Attribute, weight, targets))
+	}
+}
+
+// Returns the scheduling the action's jobs get in addition to the defaults.
+func (self Action) JobScheduling() (scheduling JobScheduling, err error) {
+	if ok, err := self.decodeMeta(ActionMetaNomadScheduling, &scheduling); err != nil || !ok {
+		return scheduling, errors.WithMessagef(err, "Action meta %q is invalid", ActionMetaNomadScheduling)
+	}
+
+	err = errors.WithMessagef(scheduling.Validate(), "Action meta %q is invalid", ActionMetaNomadScheduling)
+	return
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionJobScheduling(t *testing.T) {
+	t.Parallel()
+
+	// given
+	action := Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaNomadScheduling: map[string]interface{}{
+			"constraints": []interface{}{
+				map[string]interface{}{"attribute": "${attr.cpu.arch}", "value": "amd64"},
+			},
+			"affinities": []interface{}{
+				map[string]interface{}{"attribute": "${node.datacenter}", "value": "eu", "weight": 50},
+			},
+			"spreads": []interface{}{
+				map[string]interface{}{"attribute": "${node.datacenter}"},
+			},
+		},
+	}}}
+
+	// when
+	scheduling, err := action.JobScheduling()
+
+	// then
+	assert.Nil(t, err)
+
+	// when
+	job := &nomad.Job{}
+	JobScheduling{Constraints: []JobConstraint{{Operator: nomad.ConstraintDistinctHosts}}}.Merge(scheduling).Apply(job)
+
+	// then
+	assert.Equal(t, []*nomad.Constraint{
+		nomad.NewConstraint("", nomad.ConstraintDistinctHosts, ""),
+		nomad.NewConstraint("${attr.cpu.arch}", "=", "amd64"),
+	}, job.Constraints, "defaults come first")
+	assert.Equal(t, []*nomad.Affinity{nomad.NewAffinity("${node.datacenter}", "=", "eu", 50)}, job.Affinities)
+	assert.Equal(t, []*nomad.Spread{nomad.NewSpread("${node.datacenter}", 50, []*nomad.SpreadTarget{})}, job.Spreads)
+}
+
+func TestJobSchedulingValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, JobScheduling{}.Validate())
+	assert.EqualError(t, JobScheduling{Constraints: []JobConstraint{{Attribute: "a", Operator: "~"}}}.Validate(), `constraints[0] has unknown operator "~"`)
+	assert.EqualError(t, JobScheduling{Affinities: []JobAffinity{{Attribute: "a", Value: "b"}}}.Validate(), "affinities[0] must have a weight from -100 to 100 except 0")
+	assert.EqualError(t, JobScheduling{Spreads: []JobSpread{{Attribute: "a", Targets: []JobSpreadTarget{{"x", 60}, {"y", 50}}}}}.Validate(), "spreads[0] has targets with more than 100 percent in total")
+}
@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action whose jobs are resized by the resources
+// its previous Runs used, like `{apply: true, max_cpu: 4000, max_memory: 8192}`.
+// Recommendations are available for all actions whether or not they have it.
+const ActionMetaSizing = "sizing"
+
+type ActionSizing struct {
+	// Whether to apply recommendations to the action's jobs.
+	Apply bool `json:"apply"`
+	// Upper bounds of the resources of each task group in MHz and MB.
+	// Default to what the task group asks for so that it only shrinks.
+	MaxCPU    int `json:"max_cpu,omitempty"`
+	MaxMemory int `json:"max_memory,omitempty"`
+}
+
+// Returns nil if the action's jobs are not resized.
+func (self Action) Sizing() (*ActionSizing, error) {
+	var sizing ActionSizing
+
+	if ok, err := self.decodeMeta(ActionMetaSizing, &sizing); err != nil || !ok {
+		return nil, errors.WithMessagef(err, "Action meta %q must be a struct", ActionMetaSizing)
+	}
+
+	if sizing.MaxCPU < 0 || sizing.MaxMemory < 0 {
+		return nil, errors.Errorf("Action meta %q must not have negative maximums", ActionMetaSizing)
+	}
+
+	return &sizing, nil
+}
+
+// Peak resources used by an allocation of a task group of a Run.
+// If the task group had several allocations this is the largest.
+type RunUsagePeak struct {
+	RunId       uuid.UUID `json:"run_id" db:"run_id"`
+	TaskGroup   string    `json:"task_group" db:"task_group"`
+	CPUCores    float64   `json:"cpu_cores" db:"cpu_cores"`
+	MemoryBytes float64   `json:"memory_bytes" db:"memory_bytes"`
+}
+
+// Percentile of the peaks that recommendations are based on.
+const ResourceRecommendationPercentile = 0.95
+
+// A percentile of the peak resources that a task group of an action's Runs used.
+type RunUsagePercentile struct {
+	TaskGroup   string  `json:"task_group" db:"task_group"`
+	Runs        int     `json:"runs"`
+	CPUCores    float64 `json:"cpu_cores" db:"cpu_cores"`
+	MemoryBytes float64 `json:"memory_bytes" db:"memory_bytes"`
+}
+
+// Resources that a task group of an action's jobs should ask for.
+type ResourceRecommendation struct {
+	RunUsagePercentile
+	// In MHz and MB like Nomad's resources.
+	CPU    int `json:"cpu"`
+	Memory int `json:"memory"`
+}
+
+// Nomad's minimum resources of a task.
+const (
+	nomadMinCPU    = 1
+	nomadMinMemory = 10
+)
+
+// Nomad's default resources of a task that does not ask for any.
+const (
+	nomadDefaultCPU    = 100
+	nomadDefaultMemory = 300
+)
+
+// Recommends the percentile plus a fraction of it as headroom
+// with CPU cores converted to MHz at the given speed.
+func NewResourceRecommendation(percentile RunUsagePercentile, headroom float64, mhzPerCore int) ResourceRecommendation {
+	factor := 1 + headroom
+	recommendation := ResourceRecommendation{
+		RunUsagePercentile: percentile,
+		CPU:                int(math.Ceil(percentile.CPUCores * float64(mhzPerCore) * factor)),
+		Memory:             int(math.Ceil(percentile.MemoryBytes / 1024 / 1024 * factor)),
+	}
+	if recommendation.CPU < nomadMinCPU {
+		recommendation.CPU = nomadMinCPU
+	}
+	if recommendation.Memory < nomadMinMemory {
+		recommendation.Memory = nomadMinMemory
+	}
+	return recommendation
+}
+
+// Sets the resources of the job's task groups to the recommendations for them
+// but no more than the maximums. A task group's resources are split
+// among its tasks in proportion to what they asked for.
+// Returns the names of the task groups that were resized in order.
+func (self ActionSizing) Resize(job *nomad.Job, recommendations []ResourceRecommendation) []string {
+	byTaskGroup := make(map[string]ResourceRecommendation, len(recommendations))
+	for _, recommendation := range recommendations {
+		byTaskGroup[recommendation.TaskGroup] = recommendation
+	}
+
+	resized := []string{}
+	for _, group := range job.TaskGroups {
+		if group.Name == nil || len(group.Tasks) == 0 {
+			continue
+		}
+		recommendation, ok := byTaskGroup[*group.Name]
+		if !ok {
+			continue
+		}
+
+		cpus := make([]int, len(group.Tasks))
+		memories := make([]int, len(group.Tasks))
+		var cpu, memory int
+		for i, task := range group.Tasks {
+			cpus[i], memories[i] = nomadDefaultCPU, nomadDefaultMemory
+			if task.Resources != nil && task.Resources.CPU != nil {
+				cpus[i] = *task.Resources.CPU
+			}
+			if task.Resources != nil && task.Resources.MemoryMB != nil {
+				memories[i] = *task.Resources.MemoryMB
+			}
+			cpu += cpus[i]
+			memory += memories[i]
+		}
+
+		targetCPU := recommendation.CPU
+		if limit := self.MaxCPU; limit == 0 && targetCPU > cpu {
+			targetCPU = cpu
+		} else if limit != 0 && targetCPU > limit {
+			targetCPU = limit
+		}
+
+		targetMemory := recommendation.Memory
+		if limit := self.MaxMemory; limit == 0 && targetMemory > memory {
+			targetMemory = memory
+		} else if limit != 0 && targetMemory > limit {
+			targetMemory = limit
+		}
+
+		if targetCPU == cpu && targetMemory == memory {
+			continue
+		}
+
+		for i, task := range group.Tasks {
+			if task.Resources == nil {
+				task.Resources = &nomad.Resources{}
+			}
+			taskCPU := proportion(targetCPU, cpus[i], cpu, nomadMinCPU)
+			taskMemory := proportion(targetMemory, memories[i], memory, nomadMinMemory)
+			task.Resources.CPU = &taskCPU
+			task.Resources.MemoryMB = &taskMemory
+			// Nomad rejects tasks that may use less memory than they reserve.
+			if task.Resources.MemoryMaxMB != nil && *task.Resources.MemoryMaxMB != 0 && *task.Resources.MemoryMaxMB < taskMemory {
+				task.Resources.MemoryMaxMB = &taskMemory
+			}
+		}
+
+		resized = append(resized, *group.Name)
+	}
+
+	sort.Strings(resized)
+	return resized
+}
+
+func proportion(total, part, whole, least int) int {
+	result := least
+	if whole > 0 {
+		result = int(math.Round(float64(total) * float64(part) / float64(whole)))
+	}
+	if result < least {
+		result = least
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResourceRecommendation(t *testing.T) {
+	t.Parallel()
+
+	recommendation := NewResourceRecommendation(RunUsagePercentile{
+		TaskGroup:   "build",
+		Runs:        10,
+		CPUCores:    0.5,
+		MemoryBytes: 100 * 1024 * 1024,
+	}, 0.2, 2000)
+	assert.Equal(t, 1200, recommendation.CPU)
+	assert.Equal(t, 120, recommendation.Memory)
+
+	recommendation = NewResourceRecommendation(RunUsagePercentile{TaskGroup: "idle"}, 0.2, 2000)
+	assert.Equal(t, nomadMinCPU, recommendation.CPU)
+	assert.Equal(t, nomadMinMemory, recommendation.Memory)
+}
+
+func TestActionSizingResize(t *testing.T) {
+	t.Parallel()
+
+	newJob := func() *nomad.Job {
+		cpu, memory, memoryMax := 3000, 4000, 500
+		job := nomad.NewServiceJob("job", "job", "global", 50)
+		job.AddTaskGroup(nomad.NewTaskGroup("build", 1).
+			AddTask(&nomad.Task{Name: "main", Resources: &nomad.Resources{CPU: &cpu, MemoryMB: &memory}}).
+			AddTask(&nomad.Task{Name: "sidecar", Resources: &nomad.Resources{MemoryMaxMB: &memoryMax}}))
+		job.AddTaskGroup(nomad.NewTaskGroup("other", 1).
+			AddTask(&nomad.Task{Name: "main"}))
+		return job
+	}
+
+	recommendations := []ResourceRecommendation{
+		{RunUsagePercentile: RunUsagePercentile{TaskGroup: "build"}, CPU: 1550, Memory: 8600},
+	}
+
+	t.Run("capped at what the job asks for", func(t *testing.T) {
+		job := newJob()
+		assert.Equal(t, []string{"build"}, ActionSizing{Apply: true}.Resize(job, recommendations))
+
+		tasks := job.TaskGroups[0].Tasks
+		assert.Equal(t, 1500, *tasks[0].Resources.CPU)
+		assert.Equal(t, 50, *tasks[1].Resources.CPU)
+		assert.Equal(t, 4000, *tasks[0].Resources.MemoryMB)
+		assert.Equal(t, 300, *tasks[1].Resources.MemoryMB)
+
+		assert.Nil(t, job.TaskGroups[1].Tasks[0].Resources)
+	})
+
+	t.Run("capped at the maximum", func(t *testing.T) {
+		job := newJob()
+		assert.Equal(t, []string{"build"}, ActionSizing{Apply: true, MaxMemory: 8600}.Resize(job, recommendations))
+
+		tasks := job.TaskGroups[0].Tasks
+		assert.Equal(t, 8000, *tasks[0].Resources.MemoryMB)
+		assert.Equal(t, 600, *tasks[1].Resources.MemoryMB)
+		assert.Equal(t, 600, *tasks[1].Resources.MemoryMaxMB)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		job := newJob()
+		assert.Empty(t, ActionSizing{Apply: true}.Resize(job, []ResourceRecommendation{
+			{RunUsagePercentile: RunUsagePercentile{TaskGroup: "build"}, CPU: 3100, Memory: 4300},
+		}))
+	})
+}
+
+func TestActionSizing(t *testing.T) {
+	t.Parallel()
+
+	sizing, err := Action{}.Sizing()
+	assert.NoError(t, err)
+	assert.Nil(t, sizing)
+
+	sizing, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaSizing: map[string]interface{}{"apply": true, "max_cpu": 4000},
+	}}}.Sizing()
+	assert.NoError(t, err)
+	assert.Equal(t, &ActionSizing{Apply: true, MaxCPU: 4000}, sizing)
+
+	_, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaSizing: map[string]interface{}{"max_memory": -1},
+	}}}.Sizing()
+	assert.Error(t, err)
+}
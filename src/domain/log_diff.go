@@ -0,0 +1,299 @@
+package domain
+
+import (
+	"regexp"
+)
+
+type LogDiffOp string
+
+const (
+	LogDiffOpEqual   LogDiffOp = "equal"
+	LogDiffOpRemoved LogDiffOp = "removed"
+	LogDiffOpAdded   LogDiffOp = "added"
+)
+
+// Logs whose differing middle parts have more lines multiplied
+// are not compared line by line as that would take too much memory.
+// All lines of those parts are reported as changed instead.
+const LogDiffCellLimit = 4_000_000
+
+// Lines with more words are not compared word by word.
+const logDiffWordLimit = 500
+
+// The differences between the log of a previous Run and that of a later one.
+type LogDiff struct {
+	// Sorted, so the first hunk is where the logs first diverge.
+	// Empty if the logs are the same apart from what is ignored.
+	Hunks []LogDiffHunk `json:"hunks"`
+	// Whether only the beginning of either log was compared
+	// because it was too long.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Consecutive changed lines with some unchanged lines around them.
+type LogDiffHunk struct {
+	// Index of the hunk's first line in the old and new log.
+	OldStart int           `json:"old_start"`
+	NewStart int           `json:"new_start"`
+	Lines    []LogDiffLine `json:"lines"`
+}
+
+type LogDiffLine struct {
+	Op LogDiffOp `json:"op"`
+	// Of the old log if removed, otherwise of the new one.
+	Text string `json:"text"`
+	// Of a line that was replaced by another one or that replaced another one,
+	// with the words that differ between both marked as changed.
+	Words []LogDiffWord `json:"words,omitempty"`
+}
+
+type LogDiffWord struct {
+	Text    string `json:"text"`
+	Changed bool   `json:"changed,omitempty"`
+}
+
+var logDiffNormalizations = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`), "<time>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<id>"},
+	{regexp.MustCompile(`/nix/store/[0-9a-z]{32}-`), "/nix/store/<hash>-"},
+	{regexp.MustCompile(`\b[0-9a-f]{12,}\b`), "<id>"},
+	{regexp.MustCompile(`\b\d+(\.\d+)?(ns|µs|us|ms|s|m|h)\b`), "<duration>"},
+}
+
+// Replaces what usually differs between Runs even if they did the same,
+// like timestamps, durations, IDs, and hashes, with placeholders.
+func NormalizeLogLine(line string) string {
+	for _, n := range logDiffNormalizations {
+		line = n.pattern.ReplaceAllString(line, n.replacement)
+	}
+	return line
+}
+
+// Compares the logs line by line after normalizing them with `NormalizeLogLine()`
+// and returns the changes with up to `context` unchanged lines around them.
+func DiffLogs(oldLog, newLog []string, context int) LogDiff {
+	oldKeys := make([]string, len(oldLog))
+	for i, line := range oldLog {
+		oldKeys[i] = NormalizeLogLine(line)
+	}
+	newKeys := make([]string, len(newLog))
+	for i, line := range newLog {
+		newKeys[i] = NormalizeLogLine(line)
+	}
+
+	ops := diffOps(oldKeys, newKeys)
+
+	diff := LogDiff{Hunks: []LogDiffHunk{}}
+
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].op == LogDiffOpEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while the next change is close enough
+		// that the context between them would touch or overlap.
+		end := start
+		for i := start; i < len(ops) && i <= end+2*context+1; i++ {
+			if ops[i].op != LogDiffOpEqual {
+				end = i
+			}
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		hunk := LogDiffHunk{
+			OldStart: ops[from].old,
+			NewStart: ops[from].new,
+			Lines:    make([]LogDiffLine, 0, to-from),
+		}
+		for _, op := range ops[from:to] {
+			line := LogDiffLine{Op: op.op}
+			if op.op == LogDiffOpRemoved {
+				line.Text = oldLog[op.old]
+			} else {
+				line.Text = newLog[op.new]
+			}
+			hunk.Lines = append(hunk.Lines, line)
+		}
+		diffWords(hunk.Lines)
+
+		diff.Hunks = append(diff.Hunks, hunk)
+
+		start = to
+	}
+
+	return diff
+}
+
+type logDiffOp struct {
+	op LogDiffOp
+	// Index of the line in the old and new log
+	// or where it would be if it is not in that log.
+	old, new int
+}
+
+// Returns the shortest edit script that turns a into b.
+// Lines that both start or end with are trimmed first
+// as logs of the same action usually only differ in the middle.
+func diffOps(a, b []string) []logDiffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]logDiffOp, 0, len(a)+len(b)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, logDiffOp{LogDiffOpEqual, i, i})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	for _, op := range lcsOps(midA, midB, LogDiffCellLimit) {
+		op.old += prefix
+		op.new += prefix
+		ops = append(ops, op)
+	}
+
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, logDiffOp{LogDiffOpEqual, len(a) - suffix + i, len(b) - suffix + i})
+	}
+
+	return ops
+}
+
+// Finds the longest common subsequence by dynamic programming.
+// If that would take more than `cellLimit` cells
+// all of a is removed and all of b is added.
+func lcsOps(a, b []string, cellLimit int) []logDiffOp {
+	ops := make([]logDiffOp, 0, len(a)+len(b))
+
+	if len(a)*len(b) > cellLimit {
+		for i := range a {
+			ops = append(ops, logDiffOp{LogDiffOpRemoved, i, 0})
+		}
+		for j := range b {
+			ops = append(ops, logDiffOp{LogDiffOpAdded, len(a), j})
+		}
+		return ops
+	}
+
+	// lengths[i][j] is the length of the LCS of a[i:] and b[j:].
+	width := len(b) + 1
+	lengths := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+				lengths[i*width+j] = lengths[(i+1)*width+j]
+			default:
+				lengths[i*width+j] = lengths[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, logDiffOp{LogDiffOpEqual, i, j})
+			i++
+			j++
+		case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+			ops = append(ops, logDiffOp{LogDiffOpRemoved, i, j})
+			i++
+		default:
+			ops = append(ops, logDiffOp{LogDiffOpAdded, i, j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, logDiffOp{LogDiffOpRemoved, i, j})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, logDiffOp{LogDiffOpAdded, i, j})
+	}
+
+	return ops
+}
+
+var logDiffWordPattern = regexp.MustCompile(`\s+|\S+`)
+
+// Pairs removed lines with the added lines that follow them in order
+// and marks the words that differ between each pair.
+func diffWords(lines []LogDiffLine) {
+	for i := 0; i < len(lines); {
+		removed := i
+		for i < len(lines) && lines[i].Op == LogDiffOpRemoved {
+			i++
+		}
+		added := i
+		for i < len(lines) && lines[i].Op == LogDiffOpAdded {
+			i++
+		}
+		if removed == added && added == i {
+			i++
+			continue
+		}
+
+		for k := 0; removed+k < added && added+k < i; k++ {
+			oldLine, newLine := &lines[removed+k], &lines[added+k]
+			oldLine.Words, newLine.Words = diffLineWords(oldLine.Text, newLine.Text)
+		}
+	}
+}
+
+func diffLineWords(oldLine, newLine string) (oldWords, newWords []LogDiffWord) {
+	oldTokens := logDiffWordPattern.FindAllString(oldLine, -1)
+	newTokens := logDiffWordPattern.FindAllString(newLine, -1)
+
+	oldWords = make([]LogDiffWord, len(oldTokens))
+	for i, token := range oldTokens {
+		oldWords[i] = LogDiffWord{Text: token, Changed: true}
+	}
+	newWords = make([]LogDiffWord, len(newTokens))
+	for i, token := range newTokens {
+		newWords[i] = LogDiffWord{Text: token, Changed: true}
+	}
+
+	if len(oldTokens) > logDiffWordLimit || len(newTokens) > logDiffWordLimit {
+		return
+	}
+
+	oldKeys := make([]string, len(oldTokens))
+	for i, token := range oldTokens {
+		oldKeys[i] = NormalizeLogLine(token)
+	}
+	newKeys := make([]string, len(newTokens))
+	for i, token := range newTokens {
+		newKeys[i] = NormalizeLogLine(token)
+	}
+
+	for _, op := range lcsOps(oldKeys, newKeys, logDiffWordLimit*logDiffWordLimit) {
+		if op.op == LogDiffOpEqual {
+			oldWords[op.old].Changed = false
+			newWords[op.new].Changed = false
+		}
+	}
+
+	return
+}
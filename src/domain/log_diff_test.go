@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLogLine(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		"<time> building /nix/store/<hash>-hello-2.12 for job <id> took <duration>",
+		NormalizeLogLine("2022-10-20T08:00:00.123Z building /nix/store/0c7c96gikmzv87i7lv3vq5s1cmfjd6zf-hello-2.12 for job 8f1e5b36-4c1e-4a0e-9d4b-2c3f4e5d6a7b took 1.5s"),
+	)
+	assert.Equal(t, "[<time>] commit <id>", NormalizeLogLine("[12:34:56] commit 3e38188a1b2c4d5e"))
+	assert.Equal(t, "exit code 1", NormalizeLogLine("exit code 1"))
+}
+
+func TestDiffLogs(t *testing.T) {
+	t.Parallel()
+
+	// given
+	oldLog := []string{
+		"10:00:00 fetching",
+		"10:00:01 building",
+		"10:00:02 running 3 tests",
+		"10:00:03 all tests passed",
+		"10:00:04 uploading",
+		"10:00:05 done",
+	}
+	newLog := []string{
+		"11:00:00 fetching",
+		"11:00:01 building",
+		"11:00:02 running 4 tests",
+		"11:00:03 test foo failed",
+		"11:00:05 done",
+	}
+
+	// when
+	diff := DiffLogs(oldLog, newLog, 1)
+
+	// then
+	if assert.Len(t, diff.Hunks, 1) {
+		hunk := diff.Hunks[0]
+		assert.Equal(t, 1, hunk.OldStart)
+		assert.Equal(t, 1, hunk.NewStart)
+
+		ops := []LogDiffOp{}
+		texts := []string{}
+		for _, line := range hunk.Lines {
+			ops = append(ops, line.Op)
+			texts = append(texts, line.Text)
+		}
+		assert.Equal(t, []LogDiffOp{
+			LogDiffOpEqual,
+			LogDiffOpRemoved, LogDiffOpRemoved, LogDiffOpRemoved,
+			LogDiffOpAdded, LogDiffOpAdded,
+			LogDiffOpEqual,
+		}, ops)
+		assert.Equal(t, []string{
+			"11:00:01 building",
+			"10:00:02 running 3 tests",
+			"10:00:03 all tests passed",
+			"10:00:04 uploading",
+			"11:00:02 running 4 tests",
+			"11:00:03 test foo failed",
+			"11:00:05 done",
+		}, texts)
+
+		changed := []string{}
+		for _, word := range hunk.Lines[4].Words {
+			if word.Changed {
+				changed = append(changed, word.Text)
+			}
+		}
+		assert.Equal(t, []string{"4"}, changed, "the timestamp is ignored")
+		assert.Nil(t, hunk.Lines[0].Words)
+		assert.Nil(t, hunk.Lines[3].Words, "there is no added line left to pair with")
+	}
+
+	assert.Empty(t, DiffLogs(oldLog, oldLog, 3).Hunks)
+}
+
+func TestDiffLogsHunks(t *testing.T) {
+	t.Parallel()
+
+	// given
+	oldLog := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	newLog := []string{"a", "B", "c", "d", "e", "f", "G", "h"}
+
+	// when
+	far := DiffLogs(oldLog, newLog, 1)
+	near := DiffLogs(oldLog, newLog, 2)
+
+	// then
+	if assert.Len(t, far.Hunks, 2) {
+		assert.Equal(t, 0, far.Hunks[0].NewStart)
+		assert.Len(t, far.Hunks[0].Lines, 4)
+		assert.Equal(t, 5, far.Hunks[1].NewStart)
+		assert.Len(t, far.Hunks[1].Lines, 4)
+	}
+	if assert.Len(t, near.Hunks, 1) {
+		assert.Len(t, near.Hunks[0].Lines, 10)
+	}
+}
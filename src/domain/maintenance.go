@@ -0,0 +1,311 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+	"github.com/pkg/errors"
+)
+
+// A time during which Runs of some actions are held in the queue
+// instead of being submitted, like no production deployments on weekends.
+// Windows either start by `Cron` and last `Duration`
+// or are the events of the iCalendar feed at `ICal`.
+type MaintenanceWindow struct {
+	Name string `json:"name"`
+	Cron string `json:"cron,omitempty"`
+	// Time zone that `Cron` is in, UTC if empty.
+	Timezone string        `json:"timezone,omitempty"`
+	Duration time.Duration `json:"-"`
+	ICal     string        `json:"ical,omitempty"`
+	// Regular expressions that match the whole name of the actions or projects
+	// whose Runs are held. Runs of all actions are held if both are empty.
+	Actions  []string `json:"actions,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+}
+
+func (self MaintenanceWindow) MarshalJSON() ([]byte, error) {
+	type plain MaintenanceWindow
+	encoded := struct {
+		plain
+		Duration string `json:"duration,omitempty"`
+	}{plain: plain(self)}
+	if self.Duration != 0 {
+		encoded.Duration = self.Duration.String()
+	}
+	return json.Marshal(encoded)
+}
+
+func (self *MaintenanceWindow) UnmarshalJSON(data []byte) error {
+	type plain MaintenanceWindow
+	var decoded struct {
+		plain
+		Duration string `json:"duration"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*self = MaintenanceWindow(decoded.plain)
+	if decoded.Duration != "" {
+		duration, err := time.ParseDuration(decoded.Duration)
+		if err != nil {
+			return errors.WithMessagef(err, "Invalid duration of maintenance window %q", self.Name)
+		}
+		self.Duration = duration
+	}
+	return nil
+}
+
+func (self MaintenanceWindow) Validate() error {
+	if self.Name == "" {
+		return errors.New("Maintenance windows must have a name")
+	}
+
+	switch {
+	case self.Cron != "" && self.ICal != "":
+		return errors.Errorf("Maintenance window %q must have either a cron expression or an iCalendar feed, not both", self.Name)
+	case self.Cron != "":
+		if _, err := cronexpr.Parse(self.Cron); err != nil {
+			return errors.WithMessagef(err, "Invalid cron expression of maintenance window %q", self.Name)
+		}
+		if self.Duration <= 0 {
+			return errors.Errorf("Maintenance window %q must have a positive duration", self.Name)
+		}
+		if _, err := time.LoadLocation(self.Timezone); err != nil {
+			return errors.WithMessagef(err, "Invalid time zone of maintenance window %q", self.Name)
+		}
+	case self.ICal != "":
+	default:
+		return errors.Errorf("Maintenance window %q must have a cron expression or an iCalendar feed", self.Name)
+	}
+
+	for _, pattern := range append(append([]string{}, self.Actions...), self.Projects...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.WithMessagef(err, "Invalid pattern of maintenance window %q", self.Name)
+		}
+	}
+
+	return nil
+}
+
+// Whether the window holds Runs of the action of the project.
+func (self MaintenanceWindow) Matches(project, action string) bool {
+	if len(self.Actions) == 0 && len(self.Projects) == 0 {
+		return true
+	}
+	return matchesAnyWhole(self.Actions, action) || matchesAnyWhole(self.Projects, project)
+}
+
+func matchesAnyWhole(patterns []string, str string) bool {
+	for _, pattern := range patterns {
+		if matched, err := regexp.MatchString(`^(?:`+pattern+`)$`, str); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns when the window that is open at the given time closes,
+// nil if it is not open. Windows of a feed are given its events.
+func (self MaintenanceWindow) OpenUntil(at time.Time, events []MaintenanceEvent) *time.Time {
+	var until *time.Time
+
+	if self.Cron != "" {
+		expr, err := cronexpr.Parse(self.Cron)
+		if err != nil {
+			return nil
+		}
+		location, err := time.LoadLocation(self.Timezone)
+		if err != nil {
+			return nil
+		}
+
+		// Windows may overlap so this follows starts until there is a gap.
+		// The number of starts is limited in case the windows never close.
+		from := at.In(location).Add(-self.Duration)
+		for i := 0; i < 1000; i++ {
+			start := expr.Next(from)
+			if start.IsZero() || start.After(at) && (until == nil || start.After(*until)) {
+				break
+			}
+			end := start.Add(self.Duration)
+			if end.After(at) && (until == nil || end.After(*until)) {
+				until = &end
+			}
+			from = start
+		}
+	}
+
+	for _, event := range events {
+		if !event.Start.After(at) && event.End.After(at) && (until == nil || event.End.After(*until)) {
+			end := event.End
+			until = &end
+		}
+	}
+
+	if until != nil {
+		utc := until.UTC()
+		until = &utc
+	}
+	return until
+}
+
+type MaintenanceWindows []MaintenanceWindow
+
+func (self MaintenanceWindows) Validate() error {
+	names := map[string]struct{}{}
+	for _, window := range self {
+		if err := window.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[window.Name]; ok {
+			return errors.Errorf("Maintenance window name %q is not unique", window.Name)
+		}
+		names[window.Name] = struct{}{}
+	}
+	return nil
+}
+
+// Returns why Runs of the action of the project are held at the given time,
+// nil if they are not. Events are given by window name.
+// Of the open windows the one that closes last holds the Runs.
+func (self MaintenanceWindows) Hold(project, action string, at time.Time, events map[string][]MaintenanceEvent) *RunQueueHold {
+	var hold *RunQueueHold
+	for _, window := range self {
+		if !window.Matches(project, action) {
+			continue
+		}
+		if until := window.OpenUntil(at, events[window.Name]); until != nil && (hold == nil || until.After(hold.Until)) {
+			hold = &RunQueueHold{Window: window.Name, Until: *until}
+		}
+	}
+	return hold
+}
+
+// A maintenance window and whether it is open.
+type MaintenanceWindowState struct {
+	Window MaintenanceWindow `json:"window"`
+	// When it closes, nil if it is not open.
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+	// Events of its iCalendar feed as last fetched.
+	Events []MaintenanceEvent `json:"events,omitempty"`
+}
+
+// Why a queued Run is not submitted.
+type RunQueueHold struct {
+	// Name of the maintenance window.
+	Window string `json:"window"`
+	// When the window closes, as far as is known now.
+	Until time.Time `json:"until"`
+}
+
+// An event of an iCalendar feed.
+type MaintenanceEvent struct {
+	Summary string    `json:"summary,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// Parses the events of an iCalendar feed as in RFC 5545.
+// Only DTSTART, DTEND, and SUMMARY are read so events
+// without an end are skipped and recurrences are not expanded.
+func ParseICal(r io.Reader) ([]MaintenanceEvent, error) {
+	events := []MaintenanceEvent{}
+
+	var event *MaintenanceEvent
+	var hasEnd bool
+
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		nameAndParams, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, paramsStr, _ := strings.Cut(nameAndParams, ";")
+		name = strings.ToUpper(name)
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &MaintenanceEvent{}
+			hasEnd = false
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event != nil && hasEnd && !event.Start.IsZero() && event.End.After(event.Start) {
+				events = append(events, *event)
+			}
+			event = nil
+		case event == nil:
+		case name == "SUMMARY":
+			event.Summary = unescapeICal(value)
+		case name == "DTSTART" || name == "DTEND":
+			params := map[string]string{}
+			for _, param := range strings.Split(paramsStr, ";") {
+				if k, v, ok := strings.Cut(param, "="); ok {
+					params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+				}
+			}
+
+			t, err := parseICalTime(value, params)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "Invalid %s", name)
+			}
+
+			if name == "DTSTART" {
+				event.Start = t
+			} else {
+				event.End = t
+				hasEnd = true
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// Returns the lines with those that continue them joined.
+func unfoldICal(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	return lines, errors.WithMessage(scanner.Err(), "Could not read iCalendar feed")
+}
+
+func unescapeICal(str string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(str)
+}
+
+// Times without a zone are taken to be in UTC.
+func parseICalTime(value string, params map[string]string) (time.Time, error) {
+	location := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		var err error
+		if location, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == len("20060102"):
+		return time.ParseInLocation("20060102", value, location)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	default:
+		return time.ParseInLocation("20060102T150405", value, location)
+	}
+}
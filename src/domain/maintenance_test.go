@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	t.Parallel()
+
+	window := MaintenanceWindow{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"name": "weekend",
+		"cron": "0 0 * * 6",
+		"duration": "48h",
+		"actions": ["deploy-.*"],
+		"projects": ["infra"]
+	}`), &window))
+	assert.Equal(t, 48*time.Hour, window.Duration)
+	assert.NoError(t, window.Validate())
+
+	assert.True(t, window.Matches("web", "deploy-prod"))
+	assert.True(t, window.Matches("infra", "build"))
+	assert.False(t, window.Matches("web", "build"))
+	assert.False(t, window.Matches("web", "pre-deploy-prod"))
+
+	// 2022-10-22 is a Saturday.
+	saturday := time.Date(2022, 10, 22, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, window.OpenUntil(saturday.Add(-time.Minute), nil))
+	if until := window.OpenUntil(saturday.Add(time.Hour), nil); assert.NotNil(t, until) {
+		assert.Equal(t, saturday.Add(48*time.Hour), *until)
+	}
+	assert.Nil(t, window.OpenUntil(saturday.Add(48*time.Hour), nil))
+
+	window.Timezone = "Europe/Berlin"
+	assert.NoError(t, window.Validate())
+	assert.Nil(t, window.OpenUntil(saturday.Add(-3*time.Hour), nil))
+	assert.NotNil(t, window.OpenUntil(saturday.Add(-time.Hour), nil))
+
+	encoded, err := json.Marshal(window)
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), `"duration":"48h0m0s"`)
+
+	assert.Error(t, MaintenanceWindow{Name: "no-duration", Cron: "0 0 * * 6"}.Validate())
+	assert.Error(t, MaintenanceWindow{Name: "nothing"}.Validate())
+	assert.Error(t, MaintenanceWindow{Name: "both", Cron: "0 0 * * 6", Duration: time.Hour, ICal: "https://example.com/cal.ics"}.Validate())
+	assert.Error(t, MaintenanceWindow{Name: "pattern", ICal: "https://example.com/cal.ics", Actions: []string{"("}}.Validate())
+	assert.Error(t, MaintenanceWindows{
+		{Name: "twice", ICal: "https://example.com/cal.ics"},
+		{Name: "twice", ICal: "https://example.com/cal.ics"},
+	}.Validate())
+}
+
+func TestMaintenanceWindowsHold(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 10, 21, 12, 0, 0, 0, time.UTC)
+	windows := MaintenanceWindows{
+		{Name: "hourly", Cron: "0 * * * *", Duration: 30 * time.Minute, Actions: []string{"deploy"}},
+		{Name: "freeze", ICal: "https://example.com/cal.ics"},
+	}
+	events := map[string][]MaintenanceEvent{
+		"freeze": {{Start: now.Add(-time.Hour), End: now.Add(10 * time.Minute)}},
+	}
+
+	if hold := windows.Hold("web", "deploy", now, events); assert.NotNil(t, hold) {
+		assert.Equal(t, RunQueueHold{Window: "hourly", Until: now.Add(30 * time.Minute)}, *hold)
+	}
+	if hold := windows.Hold("web", "build", now, events); assert.NotNil(t, hold) {
+		assert.Equal(t, RunQueueHold{Window: "freeze", Until: now.Add(10 * time.Minute)}, *hold)
+	}
+	assert.Nil(t, windows.Hold("web", "build", now.Add(10*time.Minute), events))
+	assert.Nil(t, windows.Hold("web", "build", now, nil))
+}
+
+func TestParseICal(t *testing.T) {
+	t.Parallel()
+
+	events, err := ParseICal(strings.NewReader(strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Release\\, freeze",
+		"DTSTART:20221221T000000Z",
+		"DTEND:20221227T000000Z",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Long",
+		" er",
+		"DTSTART;TZID=Europe/Berlin:20221022T100000",
+		"DTEND;TZID=Europe/Berlin:20221022T120000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20221031",
+		"DTEND;VALUE=DATE:20221101",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:No end",
+		"DTSTART:20221221T000000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")))
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	assert.Equal(t, "Release, freeze", events[0].Summary)
+	assert.Equal(t, time.Date(2022, 12, 21, 0, 0, 0, 0, time.UTC), events[0].Start)
+
+	assert.Equal(t, "Longer", events[1].Summary)
+	assert.Equal(t, time.Date(2022, 10, 22, 8, 0, 0, 0, time.UTC), events[1].Start.UTC())
+
+	assert.Equal(t, time.Date(2022, 10, 31, 0, 0, 0, 0, time.UTC), events[2].Start)
+	assert.Equal(t, time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC), events[2].End)
+
+	_, err = ParseICal(strings.NewReader("BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT"))
+	assert.Error(t, err)
+}
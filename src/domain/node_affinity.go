@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action with the weight, from 1 to 100,
+// of its jobs' affinity to the nodes that ran its latest Runs
+// so that they can reuse caches local to the nodes.
+const ActionMetaNodeAffinity = "node_affinity"
+
+// Returns the weight of the action's affinity
+// to the nodes of its latest Runs, 0 if it has none.
+func (self Action) NodeAffinity() (weight int8, err error) {
+	if ok, err := self.decodeMeta(ActionMetaNodeAffinity, &weight); err != nil || (ok && (weight < 1 || weight > 100)) {
+		return 0, errors.Errorf("Action meta %q must be a weight from 1 to 100", ActionMetaNodeAffinity)
+	}
+
+	return
+}
+
+// Returns the affinity to the nodes with the given IDs.
+func NewNodeAffinity(nodeIds []string, weight int8) JobAffinity {
+	return JobAffinity{
+		Attribute: "${node.unique.id}",
+		Operator:  nomad.ConstraintSetContainsAny,
+		Value:     strings.Join(nodeIds, ","),
+		Weight:    weight,
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionNodeAffinity(t *testing.T) {
+	t.Parallel()
+
+	action := Action{}
+
+	weight, err := action.NodeAffinity()
+	assert.NoError(t, err)
+	assert.Zero(t, weight)
+
+	action.Meta = map[string]interface{}{ActionMetaNodeAffinity: 50.0}
+	weight, err = action.NodeAffinity()
+	assert.NoError(t, err)
+	assert.Equal(t, int8(50), weight)
+
+	for _, invalid := range []interface{}{0.0, 101.0, 1000.0, 2.5, "50", true} {
+		action.Meta[ActionMetaNodeAffinity] = invalid
+		_, err = action.NodeAffinity()
+		assert.Error(t, err, invalid)
+	}
+
+	affinity := NewNodeAffinity([]string{"a", "b"}, 50)
+	assert.NoError(t, JobScheduling{Affinities: []JobAffinity{affinity}}.Validate())
+	assert.Equal(t, "a,b", affinity.Value)
+}
@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+)
+
+// Decides which Nomad events are saved and handled.
+// An event passes if it matches any of Include and none of Exclude.
+type NomadEventFilter struct {
+	Include []NomadEventFilterRule
+	Exclude []NomadEventFilterRule
+}
+
+// Matches events of a topic and type, `*` matches any.
+type NomadEventFilterRule struct {
+	Topic nomad.Topic
+	Type  string
+}
+
+// The topics Cicero always subscribed to.
+var DefaultNomadEventFilter = NomadEventFilter{
+	Include: []NomadEventFilterRule{
+		{nomad.TopicAllocation, string(nomad.TopicAll)},
+		{nomad.TopicJob, string(nomad.TopicAll)},
+		{nomad.TopicDeployment, string(nomad.TopicAll)},
+		{nomad.TopicEvaluation, string(nomad.TopicAll)},
+	},
+}
+
+// Events that Runs cannot end without.
+var RequiredNomadEvents = []NomadEventFilterRule{
+	{nomad.TopicAllocation, "AllocationUpdated"},
+	{nomad.TopicJob, "AllocationUpdated"},
+	{nomad.TopicJob, "JobDeregistered"},
+	{nomad.TopicDeployment, "PlanResult"},
+	{nomad.TopicDeployment, "DeploymentStatusUpdate"},
+	{nomad.TopicDeployment, "DeploymentAllocHealth"},
+}
+
+// Parses a rule like `Topic` or `Topic:Type`.
+func ParseNomadEventFilterRule(str string) (rule NomadEventFilterRule, err error) {
+	topic, typ, ok := strings.Cut(str, ":")
+	if topic == "" || (ok && typ == "") {
+		err = errors.Errorf("Invalid Nomad event filter %q, must be Topic or Topic:Type", str)
+		return
+	}
+	if !ok {
+		typ = string(nomad.TopicAll)
+	}
+	rule.Topic = nomad.Topic(topic)
+	rule.Type = typ
+	return
+}
+
+// Parses the rules of a filter.
+// Without rules to include it includes the DefaultNomadEventFilter.
+func ParseNomadEventFilter(include, exclude []string) (filter NomadEventFilter, err error) {
+	if len(include) == 0 {
+		filter.Include = DefaultNomadEventFilter.Include
+	}
+	for _, str := range include {
+		if rule, err := ParseNomadEventFilterRule(str); err != nil {
+			return filter, err
+		} else {
+			filter.Include = append(filter.Include, rule)
+		}
+	}
+	for _, str := range exclude {
+		if rule, err := ParseNomadEventFilterRule(str); err != nil {
+			return filter, err
+		} else {
+			filter.Exclude = append(filter.Exclude, rule)
+		}
+	}
+	return
+}
+
+func (self NomadEventFilterRule) Match(topic nomad.Topic, typ string) bool {
+	return (self.Topic == nomad.TopicAll || self.Topic == topic) &&
+		(self.Type == string(nomad.TopicAll) || self.Type == typ)
+}
+
+func (self NomadEventFilterRule) String() string {
+	return string(self.Topic) + ":" + self.Type
+}
+
+func (self NomadEventFilter) Match(topic nomad.Topic, typ string) bool {
+	included := false
+	for _, rule := range self.Include {
+		if rule.Match(topic, typ) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, rule := range self.Exclude {
+		if rule.Match(topic, typ) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns an error if any of the RequiredNomadEvents does not pass.
+func (self NomadEventFilter) Validate() error {
+	for _, rule := range RequiredNomadEvents {
+		if !self.Match(rule.Topic, rule.Type) {
+			return errors.Errorf("Nomad events %s must not be filtered, they are needed to end Runs", rule)
+		}
+	}
+	return nil
+}
+
+// Returns the topics to subscribe to on Nomad's event stream.
+// Types cannot be filtered by Nomad so that is left to `Match()`
+// but topics that are excluded entirely are not subscribed to.
+func (self NomadEventFilter) Topics() map[nomad.Topic][]string {
+	topics := map[nomad.Topic][]string{}
+
+	for _, rule := range self.Include {
+		if rule.Topic == nomad.TopicAll {
+			// Nomad cannot subscribe to all but some topics.
+			return map[nomad.Topic][]string{nomad.TopicAll: {string(nomad.TopicAll)}}
+		}
+
+		excluded := false
+		for _, exclude := range self.Exclude {
+			if exclude.Type == string(nomad.TopicAll) && exclude.Match(rule.Topic, "") {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			topics[rule.Topic] = []string{string(nomad.TopicAll)}
+		}
+	}
+
+	return topics
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNomadEventFilter(t *testing.T) {
+	t.Parallel()
+
+	// when
+	filter, err := ParseNomadEventFilter(nil, []string{"Evaluation", "Job:JobRegistered"})
+
+	// then
+	assert.Nil(t, err)
+	assert.Nil(t, filter.Validate())
+	assert.True(t, filter.Match(nomad.TopicAllocation, "AllocationUpdated"))
+	assert.True(t, filter.Match(nomad.TopicJob, "JobDeregistered"))
+	assert.False(t, filter.Match(nomad.TopicJob, "JobRegistered"))
+	assert.False(t, filter.Match(nomad.TopicEvaluation, "EvaluationUpdated"))
+	assert.False(t, filter.Match(nomad.TopicNode, "NodeRegistration"))
+	assert.Equal(t, map[nomad.Topic][]string{
+		nomad.TopicAllocation: {"*"},
+		nomad.TopicJob:        {"*"},
+		nomad.TopicDeployment: {"*"},
+	}, filter.Topics())
+
+	// when
+	filter, err = ParseNomadEventFilter([]string{"*"}, []string{"Node"})
+
+	// then
+	assert.Nil(t, err)
+	assert.Nil(t, filter.Validate())
+	assert.False(t, filter.Match(nomad.TopicNode, "NodeRegistration"))
+	assert.Equal(t, map[nomad.Topic][]string{nomad.TopicAll: {"*"}}, filter.Topics())
+
+	// when
+	filter, err = ParseNomadEventFilter(nil, []string{"Deployment:PlanResult"})
+
+	// then
+	assert.Nil(t, err)
+	assert.NotNil(t, filter.Validate())
+
+	// when
+	_, err = ParseNomadEventFilter([]string{"Job:"}, nil)
+
+	// then
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// What a Quota limits.
+type QuotaSubject string
+
+const (
+	// Runs of actions of the project and facts they published.
+	QuotaSubjectProject QuotaSubject = "project"
+	// Runs invoked with facts published with the API token
+	// and those facts. Named by the token's name so it survives rotation.
+	QuotaSubjectToken QuotaSubject = "token"
+)
+
+func ParseQuotaSubject(str string) (QuotaSubject, error) {
+	switch subject := QuotaSubject(str); subject {
+	case QuotaSubjectProject, QuotaSubjectToken:
+		return subject, nil
+	default:
+		return "", errors.Errorf("Invalid quota subject %q, must be %q or %q", str, QuotaSubjectProject, QuotaSubjectToken)
+	}
+}
+
+// Limits on what a project or API token may use.
+// Nil limits are unlimited.
+type Quota struct {
+	Subject           QuotaSubject `json:"subject"`
+	Name              string       `json:"name"`
+	MaxRunsPerHour    *int         `json:"max_runs_per_hour,omitempty" db:"max_runs_per_hour"`
+	MaxConcurrentRuns *int         `json:"max_concurrent_runs,omitempty" db:"max_concurrent_runs"`
+	// Size of the values and binaries of facts.
+	MaxFactBytes *int64 `json:"max_fact_bytes,omitempty" db:"max_fact_bytes"`
+	// Admins may lift the quota for a while.
+	OverrideUntil  *time.Time `json:"override_until,omitempty" db:"override_until"`
+	OverrideReason string     `json:"override_reason,omitempty" db:"override_reason"`
+	UpdatedBy      string     `json:"updated_by" db:"updated_by"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type QuotaUsage struct {
+	// Runs created in the hour before now.
+	RunsLastHour   int   `json:"runs_last_hour" db:"runs_last_hour"`
+	ConcurrentRuns int   `json:"concurrent_runs" db:"concurrent_runs"`
+	FactBytes      int64 `json:"fact_bytes" db:"fact_bytes"`
+}
+
+// The usage of a project or API token and its quota, if any.
+type QuotaReport struct {
+	Subject QuotaSubject `json:"subject"`
+	Name    string       `json:"name"`
+	Quota   *Quota       `json:"quota,omitempty"`
+	Usage   QuotaUsage   `json:"usage"`
+}
+
+func (self Quota) Overridden(now time.Time) bool {
+	return self.OverrideUntil != nil && now.Before(*self.OverrideUntil)
+}
+
+// Returns why another Run would exceed the quota, if it would.
+func (self Quota) ExceededByRun(usage QuotaUsage, now time.Time) []string {
+	reasons := []string{}
+	if self.Overridden(now) {
+		return reasons
+	}
+	if self.MaxRunsPerHour != nil && usage.RunsLastHour >= *self.MaxRunsPerHour {
+		reasons = append(reasons, fmt.Sprintf("%s %q may start %d Runs per hour", self.Subject, self.Name, *self.MaxRunsPerHour))
+	}
+	if self.MaxConcurrentRuns != nil && usage.ConcurrentRuns >= *self.MaxConcurrentRuns {
+		reasons = append(reasons, fmt.Sprintf("%s %q may have %d Runs at once", self.Subject, self.Name, *self.MaxConcurrentRuns))
+	}
+	return reasons
+}
+
+// Returns why another fact would exceed the quota, if it would.
+// Facts are rejected once the storage is used up.
+func (self Quota) ExceededByFact(usage QuotaUsage, now time.Time) []string {
+	reasons := []string{}
+	if self.Overridden(now) {
+		return reasons
+	}
+	if self.MaxFactBytes != nil && usage.FactBytes >= *self.MaxFactBytes {
+		reasons = append(reasons, fmt.Sprintf("%s %q may store %d bytes of facts", self.Subject, self.Name, *self.MaxFactBytes))
+	}
+	return reasons
+}
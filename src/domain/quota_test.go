@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	// given
+	now := time.Date(2022, time.October, 19, 12, 0, 0, 0, time.UTC)
+	runsPerHour, concurrentRuns := 10, 2
+	factBytes := int64(1024)
+	quota := Quota{
+		Subject:           QuotaSubjectProject,
+		Name:              "cicero",
+		MaxRunsPerHour:    &runsPerHour,
+		MaxConcurrentRuns: &concurrentRuns,
+		MaxFactBytes:      &factBytes,
+	}
+
+	// then
+	assert.Empty(t, quota.ExceededByRun(QuotaUsage{RunsLastHour: 9, ConcurrentRuns: 1}, now))
+	assert.Equal(t, []string{
+		`project "cicero" may start 10 Runs per hour`,
+		`project "cicero" may have 2 Runs at once`,
+	}, quota.ExceededByRun(QuotaUsage{RunsLastHour: 10, ConcurrentRuns: 2}, now))
+
+	assert.Empty(t, quota.ExceededByFact(QuotaUsage{FactBytes: 1023}, now))
+	assert.Equal(t, []string{
+		`project "cicero" may store 1024 bytes of facts`,
+	}, quota.ExceededByFact(QuotaUsage{FactBytes: 1024}, now))
+
+	// when
+	until := now.Add(time.Hour)
+	quota.OverrideUntil = &until
+
+	// then
+	assert.Empty(t, quota.ExceededByRun(QuotaUsage{RunsLastHour: 10, ConcurrentRuns: 2}, now))
+	assert.Empty(t, quota.ExceededByFact(QuotaUsage{FactBytes: 1024}, now))
+	assert.NotEmpty(t, quota.ExceededByRun(QuotaUsage{RunsLastHour: 10}, until))
+}
+
+func TestParseQuotaSubject(t *testing.T) {
+	t.Parallel()
+
+	subject, err := ParseQuotaSubject("token")
+	assert.NoError(t, err)
+	assert.Equal(t, QuotaSubjectToken, subject)
+
+	_, err = ParseQuotaSubject("user")
+	assert.Error(t, err)
+}
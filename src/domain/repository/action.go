@@ -18,6 +18,10 @@ type ActionRepository interface {
 	GetAll() ([]domain.Action, error)
 	GetCurrent() ([]domain.Action, error)
 	GetCurrentActive() ([]domain.Action, error)
+	// Returns actions whose meta was not saved with them,
+	// which were created before it was.
+	GetWithoutMeta() ([]domain.Action, error)
 	Save(*domain.Action) error
 	Update(*domain.Action) error
+	UpdateMeta(*domain.Action) error
 }
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type AlertRepository interface {
+	WithQuerier(config.PgxIface) AlertRepository
+
+	// Returns the actions whose last Runs failed at least the given number of times
+	// without a successful Run in between. Canceled Runs are ignored.
+	GetRunFailureStreaks(min int) ([]domain.RunFailureStreak, error)
+}
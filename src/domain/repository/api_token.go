@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type ApiTokenRepository interface {
+	WithQuerier(config.PgxIface) ApiTokenRepository
+
+	GetAll() ([]domain.ApiToken, error)
+	GetById(uuid.UUID) (*domain.ApiToken, error)
+	GetByHash([]byte) (*domain.ApiToken, error)
+	Save(*domain.ApiToken) error
+	// Updates the name, scopes, and expiry.
+	Update(*domain.ApiToken) error
+	Revoke(uuid.UUID) error
+	UpdateLastUsed(uuid.UUID) error
+}
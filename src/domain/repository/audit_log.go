@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type AuditLogRepository interface {
+	WithQuerier(config.PgxIface) AuditLogRepository
+
+	// Returns matching entries, newest first.
+	Get(domain.AuditLogFilter, *Page) ([]domain.AuditLogEntry, error)
+	Save(*domain.AuditLogEntry) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type DatabaseStatsRepository interface {
+	WithQuerier(config.PgxIface) DatabaseStatsRepository
+
+	GetTables() ([]domain.DatabaseTableStats, error)
+	GetUnhandledNomadEvents() ([]domain.UnhandledNomadEventStats, error)
+	// Reads all facts so it may take a while.
+	GetFactBytesByProject() ([]domain.ProjectFactBytes, error)
+	GetRetention() (domain.DatabaseRetentionStats, error)
+	GetFactBinaryTiers() ([]domain.FactBinaryTierStats, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type DigestRepository interface {
+	WithQuerier(config.PgxIface) DigestRepository
+
+	GetAll() ([]domain.DigestSubscription, error)
+	GetById(uuid.UUID) (*domain.DigestSubscription, error)
+	Save(*domain.DigestSubscription) error
+	Delete(uuid.UUID) error
+	UpdateLastSent(id uuid.UUID, at time.Time) error
+	// Returns the Runs that failed in the time, of the project's actions if given.
+	GetFailedRuns(project *string, from, to time.Time) ([]domain.FailedRun, error)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type EnvironmentRepository interface {
+	WithQuerier(config.PgxIface) EnvironmentRepository
+
+	GetById(uuid.UUID) (*domain.Environment, error)
+	// Returns the newest environments first.
+	// Environments that were torn down are only included if `tornDown` is true.
+	Get(tornDown bool, page *Page) ([]domain.Environment, error)
+	// Returns the open environments that expired by the given time.
+	GetExpired(time.Time) ([]domain.Environment, error)
+	// Returns the open environments that are closed by a fact.
+	GetClosable() ([]domain.Environment, error)
+	// Creates the environment or updates the open one
+	// of the same action and name if there is one.
+	// New environments only check facts created afterwards.
+	Save(*domain.Environment) error
+	UpdateFactSeq(id uuid.UUID, seq int64) error
+	TearDown(*domain.Environment, domain.EnvironmentTeardownReason) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "github.com/pkg/errors"
+
+// Errors that repositories return, usually wrapped,
+// so that callers can tell them apart from failures of the database
+// with `errors.Is()` instead of looking for driver specific errors.
+var (
+	// Nothing matched, for example the record to update.
+	ErrNotFound = errors.New("Not found")
+	// A record with the same unique key already exists.
+	ErrConflict = errors.New("Conflicts with an existing record")
+	// A record refers to one that does not exist
+	// or one that is deleted is still referred to.
+	ErrForeignKey = errors.New("Refers to a missing record or is still referred to")
+)
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+type EvaluationCacheRepository interface {
+	WithQuerier(config.PgxIface) EvaluationCacheRepository
+
+	// The job is nil for actions that are decisions.
+	Get(actionId uuid.UUID, inputsHash []byte) (job *nomad.Job, found bool, err error)
+	Save(actionId uuid.UUID, inputsHash []byte, job *nomad.Job) error
+}
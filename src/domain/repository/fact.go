@@ -2,10 +2,10 @@ package repository
 
 import (
 	"io"
+	"time"
 
 	"cuelang.org/go/cue"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4"
 
 	"github.com/input-output-hk/cicero/src/config"
 	"github.com/input-output-hk/cicero/src/domain"
@@ -16,8 +16,20 @@ type FactRepository interface {
 
 	GetById(uuid.UUID) (*domain.Fact, error)
 	GetByRunId(uuid.UUID) ([]domain.Fact, error)
-	GetBinaryById(pgx.Tx, uuid.UUID) (io.ReadSeekCloser, error)
+	// Returns the latest output fact published by a Run
+	// of an action with the given name other than the given Run.
+	GetLatestOutput(actionName string, exceptRunId uuid.UUID) (*domain.Fact, error)
 	GetLatestByCue(cue.Value) (*domain.Fact, error)
+	// Only returns facts signed by one of the given FactPublishers.
+	GetLatestByCueSignedBy(cue.Value, []string) (*domain.Fact, error)
 	GetByCue(cue.Value) ([]domain.Fact, error)
+	// Labels that are empty match any fact.
+	GetByLabels(domain.FactLabels, *Page) ([]domain.Fact, error)
+	// Returns at most limit facts created after the cursor.
+	GetFeed(since int64, limit int) (domain.FactFeed, error)
 	Save(*domain.Fact, io.Reader) error
+	// Like `Save()` unless a fact with the same `Fact.DedupHash()`
+	// was saved after the given time, in which case the fact
+	// is filled in with that one and true is returned.
+	SaveUnlessDuplicate(*domain.Fact, io.Reader, time.Time) (bool, error)
 }
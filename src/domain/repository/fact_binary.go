@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type FactBinaryRepository interface {
+	WithQuerier(config.PgxIface) FactBinaryRepository
+
+	// Returns the hash and tier of the fact's binary.
+	GetTierByFactId(uuid.UUID) (hash string, tier domain.FactBinaryTier, err error)
+	GetAccesses() ([]domain.FactBinaryAccess, error)
+	// Opens the binary as it is stored in the database,
+	// compressed if it is warm. Cold binaries are not in the database.
+	Open(tx pgx.Tx, hash string) (io.ReadSeekCloser, error)
+	// Stores what `write` writes as the binary in the tier
+	// unless it is cold, which is not stored in the database.
+	// Returns whether the binary was still in the tier it was moved from.
+	Move(tx pgx.Tx, hash string, from, to domain.FactBinaryTier, write func(io.Writer) error) (bool, error)
+	// Returns the hashes of the cold binaries.
+	GetColdHashes() ([]string, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type FactLinkRepository interface {
+	WithQuerier(config.PgxIface) FactLinkRepository
+
+	// Returns the links from and to the fact.
+	GetByFactId(uuid.UUID) (domain.FactLinks, error)
+	// Does nothing if the link exists already.
+	Save(*domain.FactLink) error
+	Delete(domain.FactLink) error
+}
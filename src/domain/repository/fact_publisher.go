@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type FactPublisherRepository interface {
+	WithQuerier(config.PgxIface) FactPublisherRepository
+
+	GetAll() ([]domain.FactPublisher, error)
+	GetByName(string) (*domain.FactPublisher, error)
+	GetByKeyId(string) (*domain.FactPublisher, error)
+	Save(*domain.FactPublisher) error
+	Delete(string) error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type FactUsageRepository interface {
+	WithQuerier(config.PgxIface) FactUsageRepository
+
+	// Counts a read of each of the facts by the identity.
+	SaveReads(identity string, factIds []uuid.UUID) error
+	GetById(uuid.UUID) (domain.FactUsage, error)
+	// Returns the usage of the top-level fields of facts created since the given time,
+	// those of the most facts first.
+	GetFields(since time.Time) ([]domain.FactFieldUsage, error)
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	nomad "github.com/hashicorp/nomad/api"
 
@@ -13,8 +15,22 @@ type NomadEventRepository interface {
 
 	Save(*domain.NomadEvent) error
 	Update(*domain.NomadEvent) error
-	GetByHandled(bool) ([]domain.NomadEvent, error)
-	GetLastNomadEventIndex() (uint64, error)
+	// Clusters are given by all names that refer to them.
+	GetByHandled(handled bool, clusters []string) ([]domain.NomadEvent, error)
+	GetLastNomadEventIndex(clusters []string) (uint64, error)
+	GetLastNomadEventIndexByTopic(topic nomad.Topic, clusters []string) (uint64, error)
+	GetByJobId(uuid.UUID) ([]domain.NomadEvent, error)
 	GetEventAllocationByJobId(uuid.UUID) ([]nomad.Allocation, error)
 	GetLatestEventAllocationByJobId(uuid.UUID) ([]nomad.Allocation, error)
+	// Returns nil if there is none.
+	GetLatestEventEvaluationByJobId(uuid.UUID) (*nomad.Evaluation, error)
+
+	// Returns up to `limit` events with a greater ID
+	// that were stored before the given time, ordered by ID.
+	GetAfterId(id int64, before time.Time, limit int) ([]domain.NomadEvent, error)
+	// Returns 0 if there are no events.
+	GetLastId() (int64, error)
+	// Returns nil if the handler has no checkpoint yet.
+	GetCheckpoint(handler string) (*int64, error)
+	SaveCheckpoint(handler string, id int64) error
 }
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type QuotaRepository interface {
+	WithQuerier(config.PgxIface) QuotaRepository
+
+	GetAll() ([]domain.Quota, error)
+	Get(domain.QuotaSubject, string) (*domain.Quota, error)
+	// Inserts or replaces the quota.
+	Save(*domain.Quota) error
+	Delete(domain.QuotaSubject, string) error
+	// Counts Runs created since the given time.
+	GetUsage(subject domain.QuotaSubject, name string, since time.Time) (domain.QuotaUsage, error)
+	// Returns the project of the Run's action or nil if there is no such Run.
+	GetProjectByRunId(uuid.UUID) (*string, error)
+	// Returns the names of the API tokens that published the facts.
+	GetTokenNamesByFactIds([]uuid.UUID) ([]string, error)
+}
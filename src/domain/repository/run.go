@@ -1,7 +1,12 @@
 package repository
 
 import (
+	"io"
+	"time"
+
 	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx/v4"
 
 	"github.com/input-output-hk/cicero/src/config"
 	"github.com/input-output-hk/cicero/src/domain"
@@ -15,7 +20,43 @@ type RunRepository interface {
 	GetByInvocationId(uuid.UUID) (*domain.Run, error)
 	GetByActionId(uuid.UUID, *Page) ([]domain.Run, error)
 	GetLatestByActionId(uuid.UUID) (*domain.Run, error)
+	GetLatestByActionName(string) (*domain.Run, error)
+	// Returns the latest Run of any version of the Run's action
+	// that succeeded and was created before it.
+	GetPreviousSucceeded(domain.Run) (*domain.Run, error)
+	GetChainedFrom(uuid.UUID) ([]domain.Run, error)
+	// Returns the unfinished Runs that were chained from the Run
+	// or invoked by facts it published, and theirs recursively.
+	GetDownstream(uuid.UUID) ([]domain.Run, error)
 	GetAll(*Page) ([]domain.Run, error)
+	GetByFilter(domain.RunFilter, *Page) ([]domain.Run, error)
 	Save(*domain.Run) error
 	Update(*domain.Run) error
+	UpdateNomadCluster(*domain.Run) error
+	UpdateOutputUnchanged(*domain.Run) error
+	UpdateDeployment(*domain.Run) error
+	UpdateAdmissionDenials(*domain.Run) error
+	UpdateBlocked(*domain.Run) error
+	// Runs waiting for a mutex have no job yet and are left out.
+	GetRunning() ([]domain.Run, error)
+	UpdateSuspect(*domain.Run) error
+	// Saves the end and reconcile note of the Run unless it already ended.
+	// Returns whether it did.
+	Reconcile(*domain.Run) (bool, error)
+	GetFinishedWithoutNomadJobGC(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	MarkNomadJobGCed(*domain.Run) error
+	GetFinishedWithoutLogArchive(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	SaveLogArchive(id uuid.UUID, log io.Reader, lines int) error
+	// Returns nil if the log of the Run was not archived.
+	GetLogArchive(pgx.Tx, uuid.UUID) (io.ReadSeekCloser, error)
+	GetAllocations(uuid.UUID) ([]nomad.Allocation, error)
+	SaveAllocations(uuid.UUID, []*nomad.Allocation) error
+	// Does nothing if there is no such Run.
+	SaveNode(runId uuid.UUID, nodeId string) error
+	// Returns the nodes that ran the latest Runs of any version of the action, most recent first.
+	GetLatestNodesByActionName(name string, limit int) ([]string, error)
+	// Returns when the annotations, timing, or usage of any of the Runs
+	// were last saved, which happens after they ended.
+	// Returns nil if none were.
+	GetLastMeasuredAt([]uuid.UUID) (*time.Time, error)
 }
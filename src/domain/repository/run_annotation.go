@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunAnnotationRepository interface {
+	WithQuerier(config.PgxIface) RunAnnotationRepository
+
+	// Returns nil if the Run's log was not scanned yet.
+	GetByRunId(uuid.UUID) (*domain.RunAnnotations, error)
+	Save(*domain.RunAnnotations) error
+	// Returns the Runs that finished before the given time
+	// whose action has log matchers and whose log was not scanned yet.
+	GetFinishedWithoutAnnotations(finishedBefore time.Time, limit int) ([]domain.Run, error)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunApprovalRepository interface {
+	WithQuerier(config.PgxIface) RunApprovalRepository
+
+	// Returns the approvals that running Runs wait for in the order they were requested.
+	GetPending() ([]domain.RunApproval, error)
+	// Returns the pending approvals that expired before the given time.
+	GetExpired(time.Time) ([]domain.RunApproval, error)
+	GetByRunId(uuid.UUID) (*domain.RunApproval, error)
+	// Returns the job of the Run waiting for approval.
+	GetJob(runId uuid.UUID) (*nomad.Job, error)
+	Save(*domain.RunApproval, *nomad.Job) error
+	// Records the decision and drops the job unless it was already decided.
+	// Returns whether it was not.
+	Decide(*domain.RunApproval) (bool, error)
+}
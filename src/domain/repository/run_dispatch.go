@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunDispatchRepository interface {
+	WithQuerier(config.PgxIface) RunDispatchRepository
+
+	// Returns the Run's dispatches in order.
+	GetByRunId(uuid.UUID) ([]domain.RunDispatch, error)
+	Save(*domain.RunDispatch) error
+	// Remembers the ID of the job dispatched for the dispatch.
+	UpdateNomadJobId(*domain.RunDispatch) error
+	// Ends the dispatch whose job has the given ID unless it already ended.
+	// Returns whether it did.
+	End(nomadJobId string, status domain.RunStatus, finishedAt time.Time) (bool, error)
+	// Ends the Run's dispatches whose job could not be dispatched as failed.
+	FailUndispatched(runId uuid.UUID, finishedAt time.Time) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunManifestRepository interface {
+	WithQuerier(config.PgxIface) RunManifestRepository
+
+	GetByRunId(uuid.UUID) (*domain.RunManifest, error)
+	Save(*domain.RunManifest) error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunMutexRepository interface {
+	WithQuerier(config.PgxIface) RunMutexRepository
+
+	// Blocks other transactions that lock the same mutex
+	// until the current one ends.
+	Lock(name string) error
+	// Returns the mutexes of running Runs
+	// sorted by name, then holders first, then by creation.
+	GetRunning() ([]domain.RunMutex, error)
+	GetRunningByName(string) ([]domain.RunMutex, error)
+	GetByRunId(uuid.UUID) (*domain.RunMutex, error)
+	// Returns the job of the waiting Run.
+	GetJob(runId uuid.UUID) (*nomad.Job, error)
+	// The job is only stored if the Run waits for the mutex.
+	Save(*domain.RunMutex, *nomad.Job) error
+	Acquire(runId uuid.UUID, at time.Time) error
+	Delete(runId uuid.UUID) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunNomadTokenRepository interface {
+	WithQuerier(config.PgxIface) RunNomadTokenRepository
+
+	// Returns the tokens of the Run in the order they were minted.
+	GetByRunId(uuid.UUID) ([]domain.RunNomadToken, error)
+	// Returns the tokens that are not revoked yet
+	// but expired before the given time or whose Run ended.
+	GetRevocable(time.Time) ([]domain.RunNomadToken, error)
+	Save(*domain.RunNomadToken) error
+	Revoke(accessorId string, at time.Time) error
+}
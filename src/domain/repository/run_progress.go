@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunProgressRepository interface {
+	WithQuerier(config.PgxIface) RunProgressRepository
+
+	// Returns all progress of the Run in the order it was reported.
+	GetByRunId(uuid.UUID) ([]domain.RunProgress, error)
+	GetLatestByRunId(uuid.UUID) (*domain.RunProgress, error)
+	Save(*domain.RunProgress) error
+	// Replaces the token of the Run if it already has one.
+	SaveToken(runId uuid.UUID, hash []byte) error
+	// Returns nil if there is no token with the hash.
+	GetRunIdByTokenHash(hash []byte) (*uuid.UUID, error)
+}
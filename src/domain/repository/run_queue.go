@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunQueueRepository interface {
+	WithQuerier(config.PgxIface) RunQueueRepository
+
+	// Blocks other transactions that lock the queue until the current one ends.
+	Lock() error
+	// Returns the queued Runs that are still running in the order they were queued.
+	GetAll() ([]domain.RunQueueEntry, error)
+	// Returns how many Runs of each action are running
+	// that do not wait in the queue or for a mutex.
+	GetUsage() ([]domain.RunQueueUsage, error)
+	Save(*domain.RunQueueEntry, *nomad.Job) error
+	// Removes the Run from the queue and returns its job,
+	// nil if it was not queued.
+	Take(runId uuid.UUID) (*nomad.Job, error)
+	// Lets the Run skip maintenance windows.
+	// Returns false if it is not queued.
+	Override(runId uuid.UUID, by, reason string) (bool, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunTimingRepository interface {
+	WithQuerier(config.PgxIface) RunTimingRepository
+
+	GetByRunId(uuid.UUID) (*domain.RunTiming, error)
+	GetUnmeasured(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	Save(*domain.RunTiming) error
+	// Returns an aggregate of each phase that the Runs of actions
+	// with the given name that finished since then reached.
+	GetAggregates(actionName string, since time.Time) ([]domain.RunTimingAggregate, error)
+}
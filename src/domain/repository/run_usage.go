@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunUsageRepository interface {
+	WithQuerier(config.PgxIface) RunUsageRepository
+
+	GetByRunId(uuid.UUID) (*domain.RunUsage, error)
+	GetUnmeasured(finishedBefore time.Time, limit int) ([]domain.Run, error)
+	GetMonthlySums(by domain.RunUsageGroup, from, to time.Time) ([]domain.RunUsageSum, error)
+	Save(*domain.RunUsage) error
+	SavePeaks([]domain.RunUsagePeak) error
+	// Returns a percentile of the peaks of each task group
+	// of the Runs of actions with the given name that finished since then.
+	GetPeakPercentiles(actionName string, since time.Time, percentile float64) ([]domain.RunUsagePercentile, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type SavedQueryRepository interface {
+	WithQuerier(config.PgxIface) SavedQueryRepository
+
+	// Returns the saved queries of the kind or of any kind if it is empty.
+	GetAll(domain.SavedQueryKind) ([]domain.SavedQuery, error)
+	// Returns nil if there is no saved query with the name.
+	Get(string) (*domain.SavedQuery, error)
+	// Inserts or replaces the saved query.
+	Save(*domain.SavedQuery) error
+	Delete(string) error
+}
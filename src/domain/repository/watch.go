@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type WatchRepository interface {
+	WithQuerier(config.PgxIface) WatchRepository
+
+	GetById(uuid.UUID) (*domain.Watch, error)
+	GetByUser(user string) ([]domain.Watch, error)
+	// Returns the watches that have an email address to notify.
+	GetNotifiable() ([]domain.Watch, error)
+	// Updates the email address if the user already watches the same Run or action.
+	Save(*domain.Watch) error
+	Delete(uuid.UUID) error
+	UpdateLastNotified(id uuid.UUID, at time.Time) error
+	// Returns the watched Run or the Runs of the watched action
+	// that finished in the time, oldest first.
+	GetEndedRuns(watch domain.Watch, from, to time.Time) ([]domain.WatchedRun, error)
+}
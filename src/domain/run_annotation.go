@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const ActionMetaLogMatchers = "log_matchers"
+
+// Turns lines of a Run's log that match a pattern into annotations
+// so that the gist of a long log, like the error that failed it, is seen at once.
+type LogMatcher struct {
+	// A regular expression. The annotation is the text of its first group,
+	// or of the whole match if it has no groups.
+	Pattern string `json:"pattern"`
+	// What the annotations are, like "failure".
+	Label string `json:"label"`
+	// Only Runs that ended with this status are scanned, all if empty.
+	Status string `json:"status,omitempty"`
+}
+
+// How many annotations a matcher makes per Run at most.
+// The first lines that match are kept as they usually tell the cause.
+const LogMatcherMaxAnnotations = 10
+
+// Returns nil if the action has no log matchers.
+func (self Action) LogMatchers() ([]LogMatcher, error) {
+	var matchers []LogMatcher
+
+	if ok, err := self.decodeMeta(ActionMetaLogMatchers, &matchers); err != nil || !ok {
+		return nil, errors.WithMessagef(err, "Action meta %q must be a list of structs", ActionMetaLogMatchers)
+	}
+
+	for i, matcher := range matchers {
+		if _, err := regexp.Compile(matcher.Pattern); err != nil || matcher.Pattern == "" {
+			return nil, errors.Errorf("Action meta %q has an invalid pattern at index %d: %q", ActionMetaLogMatchers, i, matcher.Pattern)
+		}
+		if matcher.Label == "" {
+			return nil, errors.Errorf("Action meta %q has no label at index %d", ActionMetaLogMatchers, i)
+		}
+		if matcher.Status != "" {
+			var status RunStatus
+			if err := status.FromString(matcher.Status); err != nil {
+				return nil, errors.Errorf("Action meta %q has an invalid status at index %d: %q", ActionMetaLogMatchers, i, matcher.Status)
+			}
+		}
+	}
+
+	return matchers, nil
+}
+
+// Applies the matchers that scan the log of a Run with the given status.
+type LogAnnotator struct {
+	matchers []LogMatcher
+	regexps  []*regexp.Regexp
+	// Annotations made per matcher.
+	counts []int
+}
+
+func NewLogAnnotator(matchers []LogMatcher, status RunStatus) (*LogAnnotator, error) {
+	annotator := LogAnnotator{}
+	for _, matcher := range matchers {
+		if matcher.Status != "" && matcher.Status != status.String() {
+			continue
+		}
+
+		re, err := regexp.Compile(matcher.Pattern)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid pattern of log matcher %q", matcher.Label)
+		}
+
+		annotator.matchers = append(annotator.matchers, matcher)
+		annotator.regexps = append(annotator.regexps, re)
+		annotator.counts = append(annotator.counts, 0)
+	}
+	return &annotator, nil
+}
+
+// Returns the annotations of the line's text.
+// Only their label and text are set.
+func (self *LogAnnotator) Annotate(text string) []RunAnnotation {
+	var annotations []RunAnnotation
+	for i, re := range self.regexps {
+		if self.counts[i] >= LogMatcherMaxAnnotations {
+			continue
+		}
+
+		match := re.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		annotation := match[0]
+		if len(match) > 1 {
+			annotation = match[1]
+		}
+
+		annotations = append(annotations, RunAnnotation{Label: self.matchers[i].Label, Text: annotation})
+		self.counts[i]++
+	}
+	return annotations
+}
+
+// Whether no line could be annotated anymore.
+func (self *LogAnnotator) Done() bool {
+	for _, count := range self.counts {
+		if count < LogMatcherMaxAnnotations {
+			return false
+		}
+	}
+	return true
+}
+
+// A summary of a line of a Run's log.
+type RunAnnotation struct {
+	Label string `json:"label"`
+	Text  string `json:"text"`
+	// When the line was logged.
+	Time time.Time `json:"time"`
+	// The task that logged the line and its anchor to link to it.
+	AllocId   string `json:"alloc_id,omitempty"`
+	TaskGroup string `json:"task_group,omitempty"`
+	Task      string `json:"task,omitempty"`
+	Line      string `json:"line,omitempty"`
+}
+
+// The annotations made from a Run's log once it ended.
+type RunAnnotations struct {
+	RunId       uuid.UUID       `json:"run_id" db:"run_id"`
+	Annotations []RunAnnotation `json:"annotations"`
+	ScannedAt   time.Time       `json:"scanned_at" db:"scanned_at"`
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionLogMatchers(t *testing.T) {
+	t.Parallel()
+
+	matchers, err := Action{}.LogMatchers()
+	assert.NoError(t, err)
+	assert.Nil(t, matchers)
+
+	matchers, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaLogMatchers: []interface{}{
+			map[string]interface{}{"pattern": "ERROR: (.*)", "label": "failure", "status": "failed"},
+		},
+	}}}.LogMatchers()
+	assert.NoError(t, err)
+	assert.Equal(t, []LogMatcher{{Pattern: "ERROR: (.*)", Label: "failure", Status: "failed"}}, matchers)
+
+	for _, invalid := range []interface{}{
+		"ERROR",
+		[]interface{}{map[string]interface{}{"pattern": "(", "label": "failure"}},
+		[]interface{}{map[string]interface{}{"pattern": "ERROR"}},
+		[]interface{}{map[string]interface{}{"pattern": "ERROR", "label": "failure", "status": "broken"}},
+	} {
+		_, err := Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+			ActionMetaLogMatchers: invalid,
+		}}}.LogMatchers()
+		assert.Error(t, err)
+	}
+}
+
+func TestLogAnnotator(t *testing.T) {
+	t.Parallel()
+
+	matchers := []LogMatcher{
+		{Pattern: "ERROR: (.*)", Label: "failure", Status: "failed"},
+		{Pattern: `\d+ tests passed`, Label: "tests"},
+	}
+
+	annotator, err := NewLogAnnotator(matchers, RunStatusSucceeded)
+	assert.NoError(t, err)
+	assert.Empty(t, annotator.Annotate("ERROR: disk full"))
+	assert.Equal(t, []RunAnnotation{{Label: "tests", Text: "42 tests passed"}}, annotator.Annotate("ok: 42 tests passed"))
+
+	annotator, err = NewLogAnnotator(matchers, RunStatusFailed)
+	assert.NoError(t, err)
+	assert.Equal(t, []RunAnnotation{{Label: "failure", Text: "disk full"}}, annotator.Annotate("ERROR: disk full"))
+
+	for i := 1; i < LogMatcherMaxAnnotations; i++ {
+		assert.Len(t, annotator.Annotate("ERROR: again"), 1)
+	}
+	assert.Empty(t, annotator.Annotate("ERROR: too often"))
+	assert.False(t, annotator.Done())
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action whose Runs wait for a human to approve them
+// before their job is submitted, like `{approvers: ["alice"], timeout: "24h"}`.
+const ActionMetaApproval = "approval"
+
+type ActionApproval struct {
+	// Names of the identities that may approve or reject Runs.
+	// "*" allows everyone who may write approvals.
+	// Defaults to the members of the action's owner.
+	Approvers []string `json:"approvers,omitempty"`
+	// How long a Run waits before it is rejected.
+	// Zero if it waits until someone decides.
+	Timeout time.Duration `json:"-"`
+}
+
+// Returns nil if the action's Runs need no approval.
+func (self Action) Approval() (*ActionApproval, error) {
+	var decoded struct {
+		ActionApproval
+		Timeout string `json:"timeout"`
+	}
+
+	if ok, err := self.decodeMeta(ActionMetaApproval, &decoded); err != nil || !ok {
+		return nil, errors.WithMessagef(err, "Action meta %q must be a struct", ActionMetaApproval)
+	}
+
+	approval := decoded.ActionApproval
+	if decoded.Timeout != "" {
+		timeout, err := time.ParseDuration(decoded.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, errors.Errorf("Action meta %q has an invalid timeout, must be a positive duration: %q", ActionMetaApproval, decoded.Timeout)
+		}
+		approval.Timeout = timeout
+	}
+
+	if len(approval.Approvers) == 0 {
+		owner, err := self.Owner()
+		if err != nil {
+			return nil, err
+		}
+		approval.Approvers = owner.Members
+	}
+	if len(approval.Approvers) == 0 {
+		return nil, errors.Errorf("Action meta %q must name approvers if the action's owner has no members", ActionMetaApproval)
+	}
+
+	return &approval, nil
+}
+
+// A Run that waits or waited for a human to approve it.
+type RunApproval struct {
+	RunId     uuid.UUID `json:"run_id" db:"run_id"`
+	Approvers []string  `json:"approvers"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Nil if the Run waits until someone decides.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// Nil while the Run waits.
+	Approved  *bool      `json:"approved,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	// Nil if the Run was canceled while it waited.
+	DecidedBy *string `json:"decided_by,omitempty" db:"decided_by"`
+	Comment   *string `json:"comment,omitempty"`
+}
+
+// Returns a pending approval of the Run.
+func NewRunApproval(run Run, approval ActionApproval) RunApproval {
+	result := RunApproval{
+		RunId:     run.NomadJobID,
+		Approvers: approval.Approvers,
+	}
+	if approval.Timeout > 0 {
+		expiresAt := run.CreatedAt.Add(approval.Timeout).UTC()
+		result.ExpiresAt = &expiresAt
+	}
+	return result
+}
+
+func (self RunApproval) Pending() bool {
+	return self.DecidedAt == nil
+}
+
+// Whether the identity of the given name may decide.
+func (self RunApproval) Allows(name string) bool {
+	for _, approver := range self.Approvers {
+		if approver == "*" || approver == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Name of who decides on approvals that expire.
+const RunApprovalTimeoutDecider = "cicero"
+
+// Returns the reason the Run was denied for
+// if its approval was rejected, empty otherwise.
+func (self RunApproval) Denial() string {
+	if self.Approved == nil || *self.Approved || self.DecidedBy == nil {
+		return ""
+	}
+	reason := "Rejected by " + *self.DecidedBy
+	if self.Comment != nil && *self.Comment != "" {
+		reason += ": " + *self.Comment
+	}
+	return reason
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionApproval(t *testing.T) {
+	t.Parallel()
+
+	approval, err := Action{}.Approval()
+	assert.NoError(t, err)
+	assert.Nil(t, approval)
+
+	approval, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaApproval: map[string]interface{}{"approvers": []interface{}{"alice"}, "timeout": "24h"},
+	}}}.Approval()
+	assert.NoError(t, err)
+	assert.Equal(t, &ActionApproval{Approvers: []string{"alice"}, Timeout: 24 * time.Hour}, approval)
+
+	approval, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaApproval: map[string]interface{}{},
+		ActionMetaOwner:    map[string]interface{}{"members": []interface{}{"bob"}},
+	}}}.Approval()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, approval.Approvers)
+
+	for _, invalid := range []interface{}{
+		"alice",
+		map[string]interface{}{},
+		map[string]interface{}{"approvers": []interface{}{"alice"}, "timeout": "soon"},
+	} {
+		_, err := Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+			ActionMetaApproval: invalid,
+		}}}.Approval()
+		assert.Error(t, err)
+	}
+}
+
+func TestRunApproval(t *testing.T) {
+	t.Parallel()
+
+	run := Run{CreatedAt: time.Date(2022, 10, 21, 12, 0, 0, 0, time.UTC)}
+
+	approval := NewRunApproval(run, ActionApproval{Approvers: []string{"alice"}})
+	assert.True(t, approval.Pending())
+	assert.Nil(t, approval.ExpiresAt)
+	assert.True(t, approval.Allows("alice"))
+	assert.False(t, approval.Allows("bob"))
+	assert.Empty(t, approval.Denial())
+
+	approval = NewRunApproval(run, ActionApproval{Approvers: []string{"*"}, Timeout: time.Hour})
+	assert.Equal(t, run.CreatedAt.Add(time.Hour), *approval.ExpiresAt)
+	assert.True(t, approval.Allows("bob"))
+
+	approved, by, comment, at := false, "bob", "not today", time.Now()
+	approval.Approved, approval.DecidedBy, approval.Comment, approval.DecidedAt = &approved, &by, &comment, &at
+	assert.False(t, approval.Pending())
+	assert.Equal(t, "Rejected by bob: not today", approval.Denial())
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// Why Nomad could not place all allocations of a Run's job
+// according to an evaluation of it, or nil if it placed them.
+// Not ok if the evaluation does not tell yet, like while it is pending.
+func RunBlockedReasons(eval nomad.Evaluation) (reasons []string, ok bool) {
+	switch eval.Status {
+	case nomad.EvalStatusComplete, nomad.EvalStatusBlocked, nomad.EvalStatusFailed:
+	default:
+		return nil, false
+	}
+
+	for taskGroup, metric := range eval.FailedTGAllocs {
+		if metric == nil {
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("task group %q: %s", taskGroup, NewPlacementFailure(*metric)))
+	}
+	sort.Strings(reasons)
+
+	switch eval.Status {
+	case nomad.EvalStatusFailed:
+		description := eval.StatusDescription
+		if description == "" {
+			description = "no reason given"
+		}
+		reasons = append(reasons, "evaluation failed: "+description)
+	case nomad.EvalStatusBlocked:
+		if len(reasons) == 0 {
+			reasons = append(reasons, "waiting for resources")
+		}
+	}
+
+	return reasons, true
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBlockedReasons(t *testing.T) {
+	t.Parallel()
+
+	_, ok := RunBlockedReasons(nomad.Evaluation{Status: nomad.EvalStatusPending})
+	assert.False(t, ok)
+
+	reasons, ok := RunBlockedReasons(nomad.Evaluation{Status: nomad.EvalStatusComplete})
+	assert.True(t, ok)
+	assert.Nil(t, reasons)
+
+	reasons, ok = RunBlockedReasons(nomad.Evaluation{
+		Status: nomad.EvalStatusComplete,
+		FailedTGAllocs: map[string]*nomad.AllocationMetric{
+			"build": {
+				NodesEvaluated:     5,
+				NodesFiltered:      2,
+				NodesExhausted:     3,
+				ConstraintFiltered: map[string]int{"${attr.kernel.name} = linux": 2},
+				DimensionExhausted: map[string]int{"memory": 2, "cpu": 1},
+			},
+			"test": {NodesEvaluated: 5, CoalescedFailures: 1},
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`task group "build": evaluated 5 nodes, 2 filtered (constraint ${attr.kernel.name} = linux: 2), 3 exhausted (cpu: 1, memory: 2)`,
+		`task group "test": evaluated 5 nodes, 1 more allocations failed the same way`,
+	}, reasons)
+
+	reasons, _ = RunBlockedReasons(nomad.Evaluation{Status: nomad.EvalStatusBlocked})
+	assert.Equal(t, []string{"waiting for resources"}, reasons)
+
+	reasons, _ = RunBlockedReasons(nomad.Evaluation{Status: nomad.EvalStatusFailed, StatusDescription: "maximum attempts reached (5)"})
+	assert.Equal(t, []string{"evaluation failed: maximum attempts reached (5)"}, reasons)
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action with parameterized jobs
+// naming the input whose value is a list to dispatch
+// the job once for each of its items.
+const ActionMetaDispatchEach = "dispatch_each"
+
+// Returns the name of the input to dispatch the action's job
+// once for each item of, empty to dispatch it once.
+func (self Action) DispatchEach() (string, error) {
+	switch each := self.Meta[ActionMetaDispatchEach].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return each, nil
+	default:
+		return "", errors.Errorf("Action meta %q must be a string but is %T", ActionMetaDispatchEach, each)
+	}
+}
+
+// A job dispatched from the parameterized job of a Run.
+type RunDispatch struct {
+	RunId uuid.UUID `json:"run_id" db:"run_id"`
+	// Position among the Run's dispatches.
+	Number int `json:"number"`
+	// Nil until the job was dispatched.
+	NomadJobID *string           `json:"nomad_job_id,omitempty" db:"nomad_job_id"`
+	Meta       map[string]string `json:"meta,omitempty"`
+	Payload    []byte            `json:"-"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty" db:"finished_at"`
+	Status     RunStatus         `json:"status"`
+}
+
+// Returns the ID of the parameterized job that the job
+// with the given ID was dispatched from, if it was.
+// Nomad names dispatched jobs `<parent>/dispatch-<time>-<random>`.
+func RunDispatchParentJobId(nomadJobId string) (string, bool) {
+	parentId, _, found := strings.Cut(nomadJobId, "/dispatch-")
+	return parentId, found
+}
+
+// Derives the dispatches of a Run's parameterized job from the values of the input facts.
+// The payload is a JSON object of the values by input name unless the job forbids one.
+// Meta keys the job takes are set to the value of the input with the same name,
+// which must be a string, number, or bool.
+// If `each` names an input whose value is a list, there is one dispatch per item
+// in which that input's value is the item instead.
+func NewRunDispatches(runId uuid.UUID, job *nomad.Job, inputs map[string]Fact, each string) ([]RunDispatch, error) {
+	if !job.IsParameterized() {
+		return nil, errors.New("Job is not parameterized")
+	}
+
+	values := make(map[string]interface{}, len(inputs))
+	for name, input := range inputs {
+		values[name] = input.Value
+	}
+
+	valueSets := []map[string]interface{}{values}
+	if each != "" {
+		items, ok := values[each].([]interface{})
+		if !ok {
+			return nil, errors.Errorf("Input %q to dispatch the job for each item of must be a list but is %T", each, values[each])
+		}
+
+		if len(items) == 0 {
+			return nil, errors.Errorf("Input %q to dispatch the job for each item of is an empty list", each)
+		}
+
+		valueSets = make([]map[string]interface{}, len(items))
+		for i, item := range items {
+			itemValues := make(map[string]interface{}, len(values))
+			for name, value := range values {
+				itemValues[name] = value
+			}
+			itemValues[each] = item
+			valueSets[i] = itemValues
+		}
+	}
+
+	params := job.ParameterizedJob
+	keys := append(append([]string{}, params.MetaRequired...), params.MetaOptional...)
+	required := map[string]bool{}
+	for _, key := range params.MetaRequired {
+		required[key] = true
+	}
+
+	dispatches := make([]RunDispatch, len(valueSets))
+	for i, values := range valueSets {
+		dispatch := &dispatches[i]
+		dispatch.RunId = runId
+		dispatch.Number = i
+		dispatch.Status = RunStatusRunning
+
+		if params.Payload != "forbidden" {
+			if payload, err := json.Marshal(values); err != nil {
+				return nil, errors.WithMessage(err, "Could not encode payload")
+			} else {
+				dispatch.Payload = payload
+			}
+		}
+
+		for _, key := range keys {
+			value, ok := values[key]
+			if !ok {
+				if required[key] {
+					return nil, errors.Errorf("Job requires meta key %q but there is no input with that name", key)
+				}
+				continue
+			}
+
+			switch value.(type) {
+			case string, bool, float64, json.Number:
+			default:
+				return nil, errors.Errorf("Input %q for meta key %q must be a string, number, or bool but is %T", key, key, value)
+			}
+
+			if dispatch.Meta == nil {
+				dispatch.Meta = map[string]string{}
+			}
+			dispatch.Meta[key] = fmt.Sprint(value)
+		}
+	}
+
+	return dispatches, nil
+}
+
+// Returns the status a Run ends with once all its dispatches finished
+// and when the last of them did, or false if some are still running.
+// The Run failed if any of them did not succeed.
+func AggregateRunDispatches(dispatches []RunDispatch) (status RunStatus, finishedAt time.Time, done bool) {
+	if len(dispatches) == 0 {
+		return
+	}
+
+	status = RunStatusSucceeded
+	for _, dispatch := range dispatches {
+		if dispatch.FinishedAt == nil || dispatch.Status == RunStatusRunning {
+			return RunStatusRunning, time.Time{}, false
+		}
+		if dispatch.FinishedAt.After(finishedAt) {
+			finishedAt = *dispatch.FinishedAt
+		}
+		if dispatch.Status != RunStatusSucceeded {
+			status = RunStatusFailed
+		}
+	}
+
+	return status, finishedAt, true
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunDispatches(t *testing.T) {
+	t.Parallel()
+
+	// given
+	runId := uuid.New()
+	job := &nomad.Job{ParameterizedJob: &nomad.ParameterizedJobConfig{
+		Payload:      "optional",
+		MetaRequired: []string{"branch"},
+		MetaOptional: []string{"attempt"},
+	}}
+	inputs := map[string]Fact{
+		"branch":  {Value: "main"},
+		"systems": {Value: []interface{}{"x86_64-linux", "aarch64-darwin"}},
+	}
+
+	// when
+	dispatches, err := NewRunDispatches(runId, job, inputs, "systems")
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, dispatches, 2)
+	assert.Equal(t, runId, dispatches[1].RunId)
+	assert.Equal(t, 1, dispatches[1].Number)
+	assert.Equal(t, map[string]string{"branch": "main"}, dispatches[1].Meta)
+	assert.JSONEq(t, `{"branch": "main", "systems": "aarch64-darwin"}`, string(dispatches[1].Payload))
+
+	// when
+	delete(inputs, "branch")
+	_, err = NewRunDispatches(runId, job, inputs, "")
+
+	// then
+	assert.Error(t, err, "required meta key has no input")
+}
+
+func TestRunDispatchParentJobId(t *testing.T) {
+	t.Parallel()
+
+	parentId, found := RunDispatchParentJobId("2ac5a81b-cbd6-4e3c-8d79-4f3b1e2b8c0f/dispatch-1666249200-3f9a2c1e")
+	assert.True(t, found)
+	assert.Equal(t, "2ac5a81b-cbd6-4e3c-8d79-4f3b1e2b8c0f", parentId)
+
+	_, found = RunDispatchParentJobId("2ac5a81b-cbd6-4e3c-8d79-4f3b1e2b8c0f")
+	assert.False(t, found)
+}
+
+func TestAggregateRunDispatches(t *testing.T) {
+	t.Parallel()
+
+	// given
+	now := time.Date(2022, time.October, 20, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Minute)
+	dispatches := []RunDispatch{
+		{Number: 0, Status: RunStatusSucceeded, FinishedAt: &later},
+		{Number: 1, Status: RunStatusRunning},
+	}
+
+	// when
+	_, _, done := AggregateRunDispatches(dispatches)
+
+	// then
+	assert.False(t, done)
+
+	// when
+	dispatches[1].Status = RunStatusFailed
+	dispatches[1].FinishedAt = &now
+	status, finishedAt, done := AggregateRunDispatches(dispatches)
+
+	// then
+	assert.True(t, done)
+	assert.Equal(t, RunStatusFailed, status)
+	assert.Equal(t, later, finishedAt)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"time"
+)
+
+const RunHeartbeatFact = "cicero_run_heartbeat"
+
+// How a running Run is doing at some point in time.
+// Heartbeats are published as facts so that monitors outside of Cicero,
+// or actions, can restart what hangs.
+type RunHeartbeat struct {
+	Run    Run
+	Action string
+	At     time.Time
+	// When the Run last logged a line, nil if it did not log yet.
+	LastLog *time.Time
+	// Nil if the Run did not report any.
+	Progress *RunProgress
+}
+
+// Returns how long the Run has not logged anything,
+// since its creation if it did not log yet.
+func (self RunHeartbeat) Quiet() time.Duration {
+	if self.LastLog != nil {
+		return self.At.Sub(*self.LastLog)
+	}
+	return self.At.Sub(self.Run.CreatedAt)
+}
+
+// Durations are given in whole seconds
+// so that inputs can match them with comparisons like `quiet_seconds: >3600`.
+func (self RunHeartbeat) Fact() map[string]interface{} {
+	heartbeat := map[string]interface{}{
+		"run":             self.Run.NomadJobID.String(),
+		"action":          self.Action,
+		"at":              self.At.UTC().Format(time.RFC3339),
+		"created_at":      self.Run.CreatedAt.UTC().Format(time.RFC3339),
+		"running_seconds": int64(self.At.Sub(self.Run.CreatedAt) / time.Second),
+		"quiet_seconds":   int64(self.Quiet() / time.Second),
+		"suspect":         self.Run.SuspectSince != nil,
+	}
+	if self.LastLog != nil {
+		heartbeat["last_log_at"] = self.LastLog.UTC().Format(time.RFC3339)
+	}
+	if self.Progress != nil {
+		progress := map[string]interface{}{
+			"done":       self.Progress.Done,
+			"created_at": self.Progress.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if self.Progress.Total != nil {
+			progress["total"] = *self.Progress.Total
+		}
+		if percent := self.Progress.Percent(); percent != nil {
+			progress["percent"] = *percent
+		}
+		if self.Progress.Message != "" {
+			progress["message"] = self.Progress.Message
+		}
+		heartbeat["progress"] = progress
+	}
+	return map[string]interface{}{RunHeartbeatFact: heartbeat}
+}
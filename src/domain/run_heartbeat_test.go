@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2022, 10, 21, 12, 0, 0, 0, time.UTC)
+	heartbeat := RunHeartbeat{
+		Run:    Run{NomadJobID: uuid.New(), CreatedAt: created},
+		Action: "foo",
+		At:     created.Add(2 * time.Hour),
+	}
+
+	assert.Equal(t, 2*time.Hour, heartbeat.Quiet())
+
+	fact := heartbeat.Fact()[RunHeartbeatFact].(map[string]interface{})
+	assert.Equal(t, heartbeat.Run.NomadJobID.String(), fact["run"])
+	assert.Equal(t, "2022-10-21T14:00:00Z", fact["at"])
+	assert.Equal(t, int64(7200), fact["running_seconds"])
+	assert.Equal(t, int64(7200), fact["quiet_seconds"])
+	assert.Equal(t, false, fact["suspect"])
+	assert.NotContains(t, fact, "last_log_at")
+	assert.NotContains(t, fact, "progress")
+
+	lastLog := created.Add(90 * time.Minute)
+	total := 4.0
+	heartbeat.LastLog = &lastLog
+	heartbeat.Progress = &RunProgress{Done: 1, Total: &total, CreatedAt: lastLog}
+	heartbeat.Run.SuspectSince = &lastLog
+
+	assert.Equal(t, 30*time.Minute, heartbeat.Quiet())
+
+	fact = heartbeat.Fact()[RunHeartbeatFact].(map[string]interface{})
+	assert.Equal(t, "2022-10-21T13:30:00Z", fact["last_log_at"])
+	assert.Equal(t, int64(1800), fact["quiet_seconds"])
+	assert.Equal(t, true, fact["suspect"])
+	assert.Equal(t, map[string]interface{}{
+		"done":       1.0,
+		"total":      4.0,
+		"percent":    25.0,
+		"created_at": "2022-10-21T13:30:00Z",
+	}, fact["progress"])
+}
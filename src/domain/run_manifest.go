@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+)
+
+// What a Run's job was submitted with
+// so that it can be submitted again exactly as it was.
+type RunManifest struct {
+	RunId uuid.UUID `json:"run_id" db:"run_id"`
+	// The Nomad job as it was submitted, byte for byte,
+	// except for the Run's token that is added afterwards.
+	Job json.RawMessage `json:"job"`
+	// The evaluator given in the action's source
+	// or the default ones in the order they are tried.
+	Evaluators    []RunManifestEvaluator `json:"evaluators"`
+	CiceroVersion string                 `json:"cicero_version" db:"cicero_version"`
+	CiceroCommit  string                 `json:"cicero_commit" db:"cicero_commit"`
+	// Images used by the job's tasks.
+	Images    []RunManifestImage `json:"images"`
+	CreatedAt time.Time          `json:"created_at" db:"created_at"`
+}
+
+type RunManifestEvaluator struct {
+	Name string `json:"name"`
+	// Path of the executable with symlinks resolved
+	// which identifies its version if it was installed by Nix.
+	// Empty if it could not be found.
+	Path string `json:"path"`
+}
+
+type RunManifestImage struct {
+	TaskGroup string `json:"task_group"`
+	Task      string `json:"task"`
+	Driver    string `json:"driver"`
+	Image     string `json:"image"`
+	// Only known if the image is pinned like `name@sha256:…`.
+	Digest string `json:"digest,omitempty"`
+}
+
+func NewRunManifest(runId uuid.UUID, job *nomad.Job, evaluators []RunManifestEvaluator) (RunManifest, error) {
+	manifest := RunManifest{
+		RunId:         runId,
+		Evaluators:    evaluators,
+		CiceroVersion: Build.Version,
+		CiceroCommit:  Build.Commit,
+		Images:        RunManifestImages(job),
+	}
+
+	if jobJson, err := json.Marshal(job); err != nil {
+		return manifest, errors.WithMessage(err, "Could not marshal job")
+	} else {
+		manifest.Job = jobJson
+	}
+
+	return manifest, nil
+}
+
+// Returns the images of the job's tasks whose driver has an image option
+// ordered by task group and task.
+func RunManifestImages(job *nomad.Job) []RunManifestImage {
+	images := []RunManifestImage{}
+
+	for _, group := range job.TaskGroups {
+		groupName := ""
+		if group.Name != nil {
+			groupName = *group.Name
+		}
+
+		for _, task := range group.Tasks {
+			image, ok := task.Config["image"].(string)
+			if !ok || image == "" {
+				continue
+			}
+
+			entry := RunManifestImage{
+				TaskGroup: groupName,
+				Task:      task.Name,
+				Driver:    task.Driver,
+				Image:     image,
+			}
+			if _, digest, ok := strings.Cut(image, "@"); ok {
+				entry.Digest = digest
+			}
+			images = append(images, entry)
+		}
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].TaskGroup == images[j].TaskGroup {
+			return images[i].Task < images[j].Task
+		}
+		return images[i].TaskGroup < images[j].TaskGroup
+	})
+
+	return images
+}
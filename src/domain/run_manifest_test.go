@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunManifest(t *testing.T) {
+	t.Parallel()
+
+	// given
+	job := nomad.NewBatchJob("build", "build", "global", 50)
+	job.AddTaskGroup(nomad.NewTaskGroup("test", 1).
+		AddTask(nomad.NewTask("unit", "podman").SetConfig("image", "docker.io/library/golang:1.18")).
+		AddTask(nomad.NewTask("lint", "docker").SetConfig("image", "docker.io/golangci/golangci-lint@sha256:0123abcd")).
+		AddTask(nomad.NewTask("check", "nix").SetConfig("flake", "github:input-output-hk/cicero#check")))
+	evaluators := []RunManifestEvaluator{{Name: "nix", Path: "/nix/store/abc-cicero-evaluator-nix/bin/cicero-evaluator-nix"}}
+
+	// when
+	manifest, err := NewRunManifest(uuid.New(), job, evaluators)
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, evaluators, manifest.Evaluators)
+	assert.Equal(t, []RunManifestImage{
+		{TaskGroup: "test", Task: "lint", Driver: "docker", Image: "docker.io/golangci/golangci-lint@sha256:0123abcd", Digest: "sha256:0123abcd"},
+		{TaskGroup: "test", Task: "unit", Driver: "podman", Image: "docker.io/library/golang:1.18"},
+	}, manifest.Images)
+
+	// when
+	submitted := nomad.Job{}
+	err = json.Unmarshal(manifest.Job, &submitted)
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, *job, submitted)
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action with the name of a mutex.
+// Only one Run of all actions with the same mutex
+// runs at a time, the others wait in the order they were created.
+const ActionMetaMutex = "mutex"
+
+// Returns the name of the action's mutex, empty if it has none.
+func (self Action) Mutex() (string, error) {
+	switch mutex := self.Meta[ActionMetaMutex].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return mutex, nil
+	default:
+		return "", errors.Errorf("Action meta %q must be a string but is %T", ActionMetaMutex, mutex)
+	}
+}
+
+// A Run that holds or waits for a mutex.
+type RunMutex struct {
+	RunId     uuid.UUID `json:"run_id" db:"run_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Nil while the Run waits for the mutex.
+	AcquiredAt *time.Time `json:"acquired_at,omitempty" db:"acquired_at"`
+}
+
+func (self RunMutex) Held() bool {
+	return self.AcquiredAt != nil
+}
+
+// Who holds a mutex and who waits for it.
+type RunMutexState struct {
+	Name string `json:"name"`
+	// Nil if the mutex is free.
+	Holder *RunMutex `json:"holder,omitempty"`
+	// In the order the mutex is passed on.
+	Waiting []RunMutex `json:"waiting"`
+}
+
+// Groups the mutexes of running Runs by name.
+// They must be sorted by name and creation.
+func NewRunMutexStates(mutexes []RunMutex) []RunMutexState {
+	states := []RunMutexState{}
+	for i := range mutexes {
+		mutex := mutexes[i]
+
+		if len(states) == 0 || states[len(states)-1].Name != mutex.Name {
+			states = append(states, RunMutexState{Name: mutex.Name, Waiting: []RunMutex{}})
+		}
+		state := &states[len(states)-1]
+
+		if mutex.Held() {
+			state.Holder = &mutex
+		} else {
+			state.Waiting = append(state.Waiting, mutex)
+		}
+	}
+	return states
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionMutex(t *testing.T) {
+	t.Parallel()
+
+	action := Action{}
+
+	mutex, err := action.Mutex()
+	assert.NoError(t, err)
+	assert.Empty(t, mutex)
+
+	action.Meta = map[string]interface{}{ActionMetaMutex: "deploy-prod"}
+	mutex, err = action.Mutex()
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy-prod", mutex)
+
+	action.Meta[ActionMetaMutex] = []interface{}{"deploy-prod"}
+	_, err = action.Mutex()
+	assert.Error(t, err)
+}
+
+func TestNewRunMutexStates(t *testing.T) {
+	t.Parallel()
+
+	// given
+	now := time.Date(2022, time.October, 19, 12, 0, 0, 0, time.UTC)
+	mutexes := []RunMutex{
+		{RunId: uuid.New(), Name: "deploy-prod", CreatedAt: now, AcquiredAt: &now},
+		{RunId: uuid.New(), Name: "deploy-prod", CreatedAt: now.Add(time.Minute)},
+		{RunId: uuid.New(), Name: "deploy-prod", CreatedAt: now.Add(2 * time.Minute)},
+		{RunId: uuid.New(), Name: "deploy-staging", CreatedAt: now},
+	}
+
+	// when
+	states := NewRunMutexStates(mutexes)
+
+	// then
+	if assert.Len(t, states, 2) {
+		assert.Equal(t, "deploy-prod", states[0].Name)
+		if assert.NotNil(t, states[0].Holder) {
+			assert.Equal(t, mutexes[0].RunId, states[0].Holder.RunId)
+		}
+		assert.Equal(t, mutexes[1:3], states[0].Waiting)
+
+		assert.Equal(t, "deploy-staging", states[1].Name)
+		assert.Nil(t, states[1].Holder)
+		assert.Equal(t, mutexes[3:], states[1].Waiting)
+	}
+}
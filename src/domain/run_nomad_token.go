@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action whose Runs get a Nomad ACL token
+// with the given policies in `NOMAD_TOKEN`, like `{policies: ["deploy"], ttl: "1h"}`.
+const ActionMetaNomadToken = "nomad_token"
+
+type ActionNomadToken struct {
+	// Names of the Nomad ACL policies of the token.
+	Policies []string `json:"policies"`
+	// How long a token is valid before it is revoked.
+	// Zero for the configured default.
+	TTL time.Duration `json:"-"`
+}
+
+// Returns nil if the action's Runs get no Nomad token.
+func (self Action) NomadToken() (*ActionNomadToken, error) {
+	var decoded struct {
+		ActionNomadToken
+		TTL string `json:"ttl"`
+	}
+
+	if ok, err := self.decodeMeta(ActionMetaNomadToken, &decoded); err != nil || !ok {
+		return nil, errors.WithMessagef(err, "Action meta %q must be a struct", ActionMetaNomadToken)
+	}
+
+	token := decoded.ActionNomadToken
+	if len(token.Policies) == 0 {
+		return nil, errors.Errorf("Action meta %q must name at least one policy", ActionMetaNomadToken)
+	}
+	if decoded.TTL != "" {
+		ttl, err := time.ParseDuration(decoded.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, errors.Errorf("Action meta %q has an invalid ttl, must be a positive duration: %q", ActionMetaNomadToken, decoded.TTL)
+		}
+		token.TTL = ttl
+	}
+
+	return &token, nil
+}
+
+// A Nomad ACL token minted for a Run.
+// Its secret is only handed to the Run and never saved.
+type RunNomadToken struct {
+	AccessorId   string    `json:"accessor_id" db:"accessor_id"`
+	RunId        uuid.UUID `json:"run_id" db:"run_id"`
+	NomadCluster string    `json:"nomad_cluster" db:"nomad_cluster"`
+	Policies     []string  `json:"policies"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	// Nil until the token expired or its Run ended.
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// Name of the Nomad token of a Run so that it can be recognized in Nomad.
+func RunNomadTokenName(runId uuid.UUID) string {
+	return "cicero-run-" + runId.String()
+}
+
+// The scope a Run's token needs to renew the Run's Nomad token.
+func RunNomadTokenScope(runId uuid.UUID) string {
+	return "runs:nomad-token:" + runId.String()
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionNomadToken(t *testing.T) {
+	t.Parallel()
+
+	token, err := Action{}.NomadToken()
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	token, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaNomadToken: map[string]interface{}{"policies": []interface{}{"deploy"}, "ttl": "30m"},
+	}}}.NomadToken()
+	assert.NoError(t, err)
+	assert.Equal(t, &ActionNomadToken{Policies: []string{"deploy"}, TTL: 30 * time.Minute}, token)
+
+	_, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaNomadToken: map[string]interface{}{},
+	}}}.NomadToken()
+	assert.Error(t, err, "policies are required")
+
+	_, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaNomadToken: map[string]interface{}{"policies": []interface{}{"deploy"}, "ttl": "-1h"},
+	}}}.NomadToken()
+	assert.Error(t, err, "ttl must be positive")
+}
@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// The fields an action's output may have.
+var runOutputFields = map[string]bool{
+	// Value of the fact published if the Run succeeded.
+	"success": true,
+	// Value of the fact published if the Run failed.
+	"failure": true,
+	// Values captured from the inputs, like a version,
+	// so that consumers do not have to dig them out of the facts.
+	"vars": true,
+	// References to binaries of the input facts, like `{input: "build"}`.
+	"artifacts": true,
+}
+
+// The output of an action evaluated with the inputs of a Run.
+type RunOutput struct {
+	// Nil if no fact is published.
+	Success interface{} `json:"success,omitempty"`
+	Failure interface{} `json:"failure,omitempty"`
+
+	Vars      map[string]interface{} `json:"vars"`
+	Artifacts map[string]ArtifactRef `json:"artifacts"`
+}
+
+// The binary of an input fact.
+type ArtifactRef struct {
+	// Name of the input whose fact has the binary.
+	Input string `json:"input"`
+	// Nil if the input is optional and was not satisfied.
+	FactId     *uuid.UUID `json:"fact_id,omitempty"`
+	BinaryHash *string    `json:"binary_hash,omitempty"`
+}
+
+// Returns the value of the fact published for a Run with the given status.
+// Nil if there is none or the status is not final.
+func (self RunOutput) Fact(status RunStatus) interface{} {
+	switch status {
+	case RunStatusSucceeded:
+		return self.Success
+	case RunStatusFailed:
+		return self.Failure
+	default:
+		return nil
+	}
+}
+
+func (self RunOutput) Var(name string) (interface{}, bool) {
+	value, ok := self.Vars[name]
+	return value, ok
+}
+
+func (self RunOutput) VarString(name string) (string, error) {
+	value, ok := self.Var(name)
+	if !ok {
+		return "", errors.Errorf("Output has no var %q", name)
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	return "", errors.Errorf("Output var %q is %T, not a string", name, value)
+}
+
+func (self RunOutput) VarNumber(name string) (float64, error) {
+	value, ok := self.Var(name)
+	if !ok {
+		return 0, errors.Errorf("Output has no var %q", name)
+	}
+	if number, ok := value.(float64); ok {
+		return number, nil
+	}
+	return 0, errors.Errorf("Output var %q is %T, not a number", name, value)
+}
+
+// Returns nil if there is no such artifact.
+func (self RunOutput) Artifact(name string) *ArtifactRef {
+	if artifact, ok := self.Artifacts[name]; ok {
+		return &artifact
+	}
+	return nil
+}
+
+// Checks the output against the schema without inputs
+// so that mistakes are found when the action is saved
+// and not only when its first Run ends.
+func (self InOutCUEString) ValidateOutput() error {
+	value := self.valueWithInputs(nil)
+
+	output := value.LookupPath(cue.MakePath(cue.Str("output")))
+	if !output.Exists() {
+		return nil
+	}
+	if output.IncompleteKind() != cue.StructKind {
+		return errors.New("output must be a struct")
+	}
+
+	fields, err := output.Fields(cue.Optional(true))
+	if err != nil {
+		return errors.WithMessage(err, "Could not iterate output fields")
+	}
+	for fields.Next() {
+		if !runOutputFields[fields.Label()] {
+			return errors.Errorf("output has unknown field %q, must be one of: %s", fields.Label(), runOutputFieldNames())
+		}
+	}
+
+	if vars := output.LookupPath(cue.MakePath(cue.Str("vars"))); vars.Exists() && vars.IncompleteKind() != cue.StructKind {
+		return errors.New("output.vars must be a struct")
+	}
+
+	if artifacts := output.LookupPath(cue.MakePath(cue.Str("artifacts"))); artifacts.Exists() {
+		inputs, err := self.Inputs(nil)
+		if err != nil {
+			return err
+		}
+
+		artifactInputs, err := decodeArtifactInputs(artifacts)
+		if err != nil {
+			return err
+		}
+		for name, input := range artifactInputs {
+			if _, ok := inputs[input]; !ok {
+				return errors.Errorf("output.artifacts.%s refers to undefined input %q", name, input)
+			}
+		}
+	}
+
+	return nil
+}
+
+func runOutputFieldNames() string {
+	names := make([]string, 0, len(runOutputFields))
+	for name := range runOutputFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// Returns the names of the inputs the artifacts refer to.
+func decodeArtifactInputs(artifacts cue.Value) (map[string]string, error) {
+	if artifacts.IncompleteKind() != cue.StructKind {
+		return nil, errors.New("output.artifacts must be a struct")
+	}
+
+	inputs := map[string]string{}
+	fields, err := artifacts.Fields()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not iterate output artifacts")
+	}
+	for fields.Next() {
+		input, err := fields.Value().LookupPath(cue.MakePath(cue.Str("input"))).String()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "output.artifacts.%s.input must be the name of an input", fields.Label())
+		}
+		inputs[fields.Label()] = input
+	}
+	return inputs, nil
+}
+
+// Evaluates the output with the inputs of a Run.
+func (self InOutCUEString) RunOutput(inputs map[string]Fact) (RunOutput, error) {
+	runOutput := RunOutput{
+		Vars:      map[string]interface{}{},
+		Artifacts: map[string]ArtifactRef{},
+	}
+
+	definition := self.Output(inputs)
+	if definition.Success.Exists() {
+		if err := definition.Success.Decode(&runOutput.Success); err != nil {
+			return runOutput, errors.WithMessage(err, "Could not decode output.success")
+		}
+	}
+	if definition.Failure.Exists() {
+		if err := definition.Failure.Decode(&runOutput.Failure); err != nil {
+			return runOutput, errors.WithMessage(err, "Could not decode output.failure")
+		}
+	}
+
+	output := self.valueWithInputs(inputs).LookupPath(cue.MakePath(cue.Str("output")))
+
+	if vars := output.LookupPath(cue.MakePath(cue.Str("vars"))); vars.Exists() {
+		if err := vars.Decode(&runOutput.Vars); err != nil {
+			return runOutput, errors.WithMessage(err, "Could not decode output.vars")
+		}
+	}
+
+	if artifacts := output.LookupPath(cue.MakePath(cue.Str("artifacts"))); artifacts.Exists() {
+		artifactInputs, err := decodeArtifactInputs(artifacts)
+		if err != nil {
+			return runOutput, err
+		}
+		for name, input := range artifactInputs {
+			artifact := ArtifactRef{Input: input}
+			if fact, ok := inputs[input]; ok {
+				id := fact.ID
+				artifact.FactId = &id
+				artifact.BinaryHash = fact.BinaryHash
+			}
+			runOutput.Artifacts[name] = artifact
+		}
+	}
+
+	return runOutput, nil
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOutput(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, InOutCUEString(`inputs: a: match: _`).ValidateOutput())
+	assert.NoError(t, InOutCUEString(`
+		inputs: a: match: _
+		output: {
+			success: inputs.a.value
+			vars: version: inputs.a.value.version
+			artifacts: tarball: input: "a"
+		}
+	`).ValidateOutput())
+
+	assert.Error(t, InOutCUEString(`output: 1`).ValidateOutput())
+	assert.Error(t, InOutCUEString(`output: sucess: true`).ValidateOutput())
+	assert.Error(t, InOutCUEString(`output: vars: 1`).ValidateOutput())
+	assert.Error(t, InOutCUEString(`
+		inputs: a: match: _
+		output: artifacts: tarball: input: "b"
+	`).ValidateOutput())
+}
+
+func TestRunOutput(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := InOutCUEString(`
+		inputs: {
+			a: match: version: string
+			b: {
+				optional: true
+				match: _
+			}
+		}
+		output: {
+			success: ok: true
+			vars: version: inputs.a.value.version
+			artifacts: {
+				tarball: input: "a"
+				extra: input: "b"
+			}
+		}
+	`)
+	hash := "sha256-abc"
+	inputs := map[string]Fact{
+		"a": {ID: uuid.New(), Value: map[string]interface{}{"version": "1.0"}, BinaryHash: &hash},
+	}
+
+	// when
+	output, err := s.RunOutput(inputs)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ok": true}, output.Fact(RunStatusSucceeded))
+	assert.Nil(t, output.Fact(RunStatusFailed))
+
+	version, err := output.VarString("version")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", version)
+	_, err = output.VarNumber("version")
+	assert.Error(t, err)
+
+	if tarball := output.Artifact("tarball"); assert.NotNil(t, tarball) {
+		assert.Equal(t, "a", tarball.Input)
+		assert.Equal(t, inputs["a"].ID, *tarball.FactId)
+		assert.Equal(t, &hash, tarball.BinaryHash)
+	}
+	if extra := output.Artifact("extra"); assert.NotNil(t, extra) {
+		assert.Nil(t, extra.FactId)
+	}
+	assert.Nil(t, output.Artifact("missing"))
+}
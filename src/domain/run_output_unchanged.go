@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Meta attribute of an action whose Runs do not publish their output
+// if it is identical to the previous output of the action, like `true`.
+const ActionMetaOutputOnChange = "output_on_change"
+
+// Whether Runs of the action only publish output that changed.
+func (self Action) OutputOnChange() (bool, error) {
+	switch onChange := self.Meta[ActionMetaOutputOnChange].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return onChange, nil
+	default:
+		return false, errors.Errorf("Action meta %q must be a bool but is %T", ActionMetaOutputOnChange, onChange)
+	}
+}
+
+// Whether both facts have the same value and binary.
+// Values are compared as JSON so that the order of keys does not matter.
+func (self Fact) SameValue(other Fact) (bool, error) {
+	if (self.BinaryHash == nil) != (other.BinaryHash == nil) ||
+		self.BinaryHash != nil && *self.BinaryHash != *other.BinaryHash {
+		return false, nil
+	}
+
+	decode := func(fact Fact) (value interface{}, err error) {
+		encoded, err := fact.SignedValue()
+		if err != nil {
+			return nil, errors.WithMessage(err, "Could not encode fact value")
+		}
+		err = errors.WithMessage(json.Unmarshal(encoded, &value), "Could not decode fact value")
+		return
+	}
+
+	value, err := decode(self)
+	if err != nil {
+		return false, err
+	}
+	otherValue, err := decode(other)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(value, otherValue), nil
+}
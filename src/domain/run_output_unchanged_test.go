@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactSameValue(t *testing.T) {
+	t.Parallel()
+
+	hash := "sha256-abc"
+	otherHash := "sha256-def"
+
+	for _, test := range []struct {
+		name   string
+		a, b   Fact
+		expect bool
+	}{
+		{
+			name:   "equal",
+			a:      Fact{Value: map[string]interface{}{"a": 1, "b": []interface{}{"x"}}},
+			b:      Fact{Value: map[string]interface{}{"b": []interface{}{"x"}, "a": 1.0}},
+			expect: true,
+		},
+		{
+			name: "different",
+			a:    Fact{Value: map[string]interface{}{"a": 1}},
+			b:    Fact{Value: map[string]interface{}{"a": 2}},
+		},
+		{
+			name:   "same binary",
+			a:      Fact{Value: true, BinaryHash: &hash},
+			b:      Fact{Value: true, BinaryHash: &hash},
+			expect: true,
+		},
+		{
+			name: "different binary",
+			a:    Fact{Value: true, BinaryHash: &hash},
+			b:    Fact{Value: true, BinaryHash: &otherHash},
+		},
+		{
+			name: "one binary",
+			a:    Fact{Value: true, BinaryHash: &hash},
+			b:    Fact{Value: true},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			same, err := test.a.SameValue(test.b)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, same)
+		})
+	}
+}
+
+func TestActionOutputOnChange(t *testing.T) {
+	t.Parallel()
+
+	onChange, err := Action{}.OutputOnChange()
+	assert.NoError(t, err)
+	assert.False(t, onChange)
+
+	onChange, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaOutputOnChange: true,
+	}}}.OutputOnChange()
+	assert.NoError(t, err)
+	assert.True(t, onChange)
+
+	_, err = Action{ActionDefinition: ActionDefinition{Meta: map[string]interface{}{
+		ActionMetaOutputOnChange: "yes",
+	}}}.OutputOnChange()
+	assert.Error(t, err)
+}
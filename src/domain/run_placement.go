@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// Where a Run's allocations were placed and why
+// task groups could not be placed, as far as the recorded
+// allocation and evaluation events tell.
+type RunPlacement struct {
+	Allocations []AllocationPlacement `json:"allocations"`
+	// By task group. Empty unless the latest evaluation
+	// of the Run's job failed to place some of them.
+	Failures map[string]PlacementFailure `json:"failures,omitempty"`
+}
+
+type AllocationPlacement struct {
+	ID           string `json:"id"`
+	TaskGroup    string `json:"task_group"`
+	ClientStatus string `json:"client_status"`
+	NodeID       string `json:"node_id"`
+	NodeName     string `json:"node_name"`
+	// Allocations do not say which datacenter their node is in
+	// so this is only known if the job allows just one.
+	Datacenter string `json:"datacenter,omitempty"`
+	// Driver of each task by name.
+	Drivers map[string]string `json:"drivers"`
+	// What the allocation was given in MHz and MB.
+	CPU      int64 `json:"cpu"`
+	MemoryMB int64 `json:"memory_mb"`
+	DiskMB   int64 `json:"disk_mb"`
+}
+
+// Why the scheduler could not place a task group.
+// Maps are keyed by node class, constraint, or exhausted dimension.
+type PlacementFailure struct {
+	NodesEvaluated     int            `json:"nodes_evaluated"`
+	NodesFiltered      int            `json:"nodes_filtered"`
+	NodesExhausted     int            `json:"nodes_exhausted"`
+	NodesAvailable     map[string]int `json:"nodes_available,omitempty"`
+	ClassFiltered      map[string]int `json:"class_filtered,omitempty"`
+	ConstraintFiltered map[string]int `json:"constraint_filtered,omitempty"`
+	DimensionExhausted map[string]int `json:"dimension_exhausted,omitempty"`
+	QuotaExhausted     []string       `json:"quota_exhausted,omitempty"`
+	// How many more allocations of the task group failed the same way.
+	CoalescedFailures int `json:"coalesced_failures"`
+}
+
+func NewPlacementFailure(metric nomad.AllocationMetric) PlacementFailure {
+	return PlacementFailure{
+		NodesEvaluated:     metric.NodesEvaluated,
+		NodesFiltered:      metric.NodesFiltered,
+		NodesExhausted:     metric.NodesExhausted,
+		NodesAvailable:     metric.NodesAvailable,
+		ClassFiltered:      metric.ClassFiltered,
+		ConstraintFiltered: metric.ConstraintFiltered,
+		DimensionExhausted: metric.DimensionExhausted,
+		QuotaExhausted:     metric.QuotaExhausted,
+		CoalescedFailures:  metric.CoalescedFailures,
+	}
+}
+
+// Like `evaluated 5 nodes, 2 filtered (constraint ${attr.kernel.name} = linux: 2), 3 exhausted (memory: 3)`.
+func (self PlacementFailure) String() string {
+	str := fmt.Sprintf("evaluated %d nodes", self.NodesEvaluated)
+
+	if self.NodesFiltered != 0 {
+		counts := []string{}
+		counts = append(counts, placementFailureCounts("class ", self.ClassFiltered)...)
+		counts = append(counts, placementFailureCounts("constraint ", self.ConstraintFiltered)...)
+		str += fmt.Sprintf(", %d filtered", self.NodesFiltered)
+		if len(counts) != 0 {
+			str += " (" + strings.Join(counts, ", ") + ")"
+		}
+	}
+
+	if self.NodesExhausted != 0 {
+		str += fmt.Sprintf(", %d exhausted", self.NodesExhausted)
+		if counts := placementFailureCounts("", self.DimensionExhausted); len(counts) != 0 {
+			str += " (" + strings.Join(counts, ", ") + ")"
+		}
+	}
+
+	if len(self.QuotaExhausted) != 0 {
+		str += ", quota exhausted: " + strings.Join(self.QuotaExhausted, ", ")
+	}
+
+	if self.CoalescedFailures != 0 {
+		str += fmt.Sprintf(", %d more allocations failed the same way", self.CoalescedFailures)
+	}
+
+	return str
+}
+
+func placementFailureCounts(prefix string, counts map[string]int) []string {
+	strs := make([]string, 0, len(counts))
+	for key, count := range counts {
+		strs = append(strs, fmt.Sprintf("%s%s: %d", prefix, key, count))
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// The evaluation is the latest one of the Run's job, nil if there is none.
+func NewRunPlacement(allocs []nomad.Allocation, eval *nomad.Evaluation) RunPlacement {
+	placement := RunPlacement{Allocations: make([]AllocationPlacement, 0, len(allocs))}
+
+	for _, alloc := range allocs {
+		placement.Allocations = append(placement.Allocations, NewAllocationPlacement(alloc))
+	}
+
+	if eval != nil && len(eval.FailedTGAllocs) != 0 {
+		placement.Failures = make(map[string]PlacementFailure, len(eval.FailedTGAllocs))
+		for taskGroup, metric := range eval.FailedTGAllocs {
+			if metric == nil {
+				continue
+			}
+			placement.Failures[taskGroup] = NewPlacementFailure(*metric)
+		}
+	}
+
+	return placement
+}
+
+func NewAllocationPlacement(alloc nomad.Allocation) AllocationPlacement {
+	placement := AllocationPlacement{
+		ID:           alloc.ID,
+		TaskGroup:    alloc.TaskGroup,
+		ClientStatus: alloc.ClientStatus,
+		NodeID:       alloc.NodeID,
+		NodeName:     alloc.NodeName,
+		Drivers:      map[string]string{},
+	}
+
+	if alloc.Job != nil {
+		if len(alloc.Job.Datacenters) == 1 {
+			placement.Datacenter = alloc.Job.Datacenters[0]
+		}
+
+		for _, group := range alloc.Job.TaskGroups {
+			if group.Name == nil || *group.Name != alloc.TaskGroup {
+				continue
+			}
+			for _, task := range group.Tasks {
+				placement.Drivers[task.Name] = task.Driver
+			}
+		}
+	}
+
+	if alloc.AllocatedResources != nil {
+		for _, resources := range alloc.AllocatedResources.Tasks {
+			if resources != nil {
+				placement.CPU += resources.Cpu.CpuShares
+				placement.MemoryMB += resources.Memory.MemoryMB
+			}
+		}
+		placement.DiskMB = alloc.AllocatedResources.Shared.DiskMB
+	} else if alloc.Resources != nil {
+		// Allocations of older Nomad versions have no allocated resources.
+		if alloc.Resources.CPU != nil {
+			placement.CPU = int64(*alloc.Resources.CPU)
+		}
+		if alloc.Resources.MemoryMB != nil {
+			placement.MemoryMB = int64(*alloc.Resources.MemoryMB)
+		}
+		if alloc.Resources.DiskMB != nil {
+			placement.DiskMB = int64(*alloc.Resources.DiskMB)
+		}
+	}
+
+	return placement
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunPlacement(t *testing.T) {
+	t.Parallel()
+
+	str := func(s string) *string { return &s }
+	integer := func(i int) *int { return &i }
+
+	job := &nomad.Job{
+		Datacenters: []string{"dc1"},
+		TaskGroups: []*nomad.TaskGroup{
+			{Name: str("other"), Tasks: []*nomad.Task{{Name: "foo", Driver: "docker"}}},
+			{Name: str("group"), Tasks: []*nomad.Task{
+				{Name: "main", Driver: "exec"},
+				{Name: "sidecar", Driver: "docker"},
+			}},
+		},
+	}
+
+	placement := NewRunPlacement([]nomad.Allocation{
+		{
+			ID:           "a",
+			TaskGroup:    "group",
+			ClientStatus: "running",
+			NodeID:       "n1",
+			NodeName:     "node-1",
+			Job:          job,
+			AllocatedResources: &nomad.AllocatedResources{
+				Tasks: map[string]*nomad.AllocatedTaskResources{
+					"main":    {Cpu: nomad.AllocatedCpuResources{CpuShares: 500}, Memory: nomad.AllocatedMemoryResources{MemoryMB: 256}},
+					"sidecar": {Cpu: nomad.AllocatedCpuResources{CpuShares: 100}, Memory: nomad.AllocatedMemoryResources{MemoryMB: 64}},
+				},
+				Shared: nomad.AllocatedSharedResources{DiskMB: 300},
+			},
+		},
+		{
+			ID:        "b",
+			TaskGroup: "group",
+			Resources: &nomad.Resources{CPU: integer(100), MemoryMB: integer(128)},
+		},
+	}, &nomad.Evaluation{
+		FailedTGAllocs: map[string]*nomad.AllocationMetric{
+			"other": {
+				NodesEvaluated:     3,
+				NodesFiltered:      2,
+				ConstraintFiltered: map[string]int{"${attr.kernel.name} = darwin": 2},
+				CoalescedFailures:  1,
+			},
+		},
+	})
+
+	assert.Equal(t, RunPlacement{
+		Allocations: []AllocationPlacement{
+			{
+				ID:           "a",
+				TaskGroup:    "group",
+				ClientStatus: "running",
+				NodeID:       "n1",
+				NodeName:     "node-1",
+				Datacenter:   "dc1",
+				Drivers:      map[string]string{"main": "exec", "sidecar": "docker"},
+				CPU:          600,
+				MemoryMB:     320,
+				DiskMB:       300,
+			},
+			{
+				ID:        "b",
+				TaskGroup: "group",
+				Drivers:   map[string]string{},
+				CPU:       100,
+				MemoryMB:  128,
+			},
+		},
+		Failures: map[string]PlacementFailure{
+			"other": {
+				NodesEvaluated:     3,
+				NodesFiltered:      2,
+				ConstraintFiltered: map[string]int{"${attr.kernel.name} = darwin": 2},
+				CoalescedFailures:  1,
+			},
+		},
+	}, placement)
+
+	assert.Equal(t, RunPlacement{Allocations: []AllocationPlacement{}}, NewRunPlacement(nil, nil))
+}
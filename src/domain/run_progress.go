@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Prefix of the secrets of Run tokens.
+// It extends `ApiTokenPrefix` so that both are verified the same way.
+const RunTokenPrefix = ApiTokenPrefix + "run_"
+
+// The scope a Run's token needs to report the progress of that Run.
+func RunTokenScope(runId uuid.UUID) string {
+	return "runs:progress:" + runId.String()
+}
+
+// How far a running job got, as reported by the job itself.
+type RunProgress struct {
+	RunId uuid.UUID `json:"run_id" db:"run_id"`
+	// Like the number of tests that ran.
+	Done float64 `json:"done"`
+	// Like the number of tests to run.
+	// Nil if unknown, then no percentage can be given.
+	Total   *float64 `json:"total,omitempty"`
+	Message string   `json:"message,omitempty"`
+	// Partial output, like the number of failed tests.
+	Value     map[string]interface{} `json:"value,omitempty"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+func (self RunProgress) Validate() error {
+	switch {
+	case math.IsNaN(self.Done) || math.IsInf(self.Done, 0) || self.Done < 0:
+		return errors.New("done must be a number that is not negative")
+	case self.Total == nil:
+	case math.IsNaN(*self.Total) || math.IsInf(*self.Total, 0) || *self.Total <= 0:
+		return errors.New("total must be a positive number")
+	case self.Done > *self.Total:
+		return errors.New("done must not be greater than total")
+	}
+	return nil
+}
+
+// Returns nil if the total is unknown.
+func (self RunProgress) Percent() *float64 {
+	if self.Total == nil {
+		return nil
+	}
+	percent := math.Floor(self.Done / *self.Total * 100)
+	return &percent
+}
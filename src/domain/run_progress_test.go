@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunProgress(t *testing.T) {
+	t.Parallel()
+
+	total := 300.0
+	progress := RunProgress{Done: 120, Total: &total}
+
+	assert.Nil(t, progress.Validate())
+	assert.Equal(t, 40.0, *progress.Percent())
+
+	progress.Total = nil
+	assert.Nil(t, progress.Validate())
+	assert.Nil(t, progress.Percent())
+
+	progress.Done = -1
+	assert.NotNil(t, progress.Validate())
+
+	progress.Done = math.NaN()
+	assert.NotNil(t, progress.Validate())
+
+	total = 100
+	progress = RunProgress{Done: 120, Total: &total}
+	assert.NotNil(t, progress.Validate())
+
+	total = 0
+	progress = RunProgress{Done: 0, Total: &total}
+	assert.NotNil(t, progress.Validate())
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// A Run whose job waits to be submitted to Nomad.
+type RunQueueEntry struct {
+	RunId      uuid.UUID `json:"run_id" db:"run_id"`
+	Project    string    `json:"project"`
+	ActionName string    `json:"action_name" db:"action_name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	// Who let the Run skip maintenance windows and why.
+	OverriddenBy   *string `json:"overridden_by,omitempty" db:"overridden_by"`
+	OverrideReason *string `json:"override_reason,omitempty" db:"override_reason"`
+	// Why the Run is not submitted even if there is room,
+	// nil unless a maintenance window holds it.
+	Hold *RunQueueHold `json:"hold,omitempty" db:"-"`
+}
+
+// How many Runs of an action are running,
+// not counting those that wait in the queue or for a mutex.
+type RunQueueUsage struct {
+	Project    string `json:"project"`
+	ActionName string `json:"action_name" db:"action_name"`
+	Running    int    `json:"running"`
+}
+
+// Weights of the projects' shares of the Runs that run at once.
+// Projects that are not listed have a weight of 1.
+type RunQueueWeights map[string]int
+
+// Parses weights given as project=weight.
+func ParseRunQueueWeights(strs []string) (RunQueueWeights, error) {
+	weights := RunQueueWeights{}
+	for _, str := range strs {
+		project, weightStr, ok := strings.Cut(str, "=")
+		if !ok || project == "" {
+			return nil, errors.Errorf("Invalid weight %q, must be project=weight", str)
+		}
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil || weight < 1 {
+			return nil, errors.Errorf("Invalid weight of project %q, must be a positive integer: %q", project, weightStr)
+		}
+		weights[project] = weight
+	}
+	return weights, nil
+}
+
+func (self RunQueueWeights) Of(project string) int {
+	if weight, ok := self[project]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Returns up to `slots` entries of the queue in the order their jobs should be submitted.
+// Each next one is of the project that runs the fewest Runs relative to its weight
+// and of the action of that project that runs the fewest Runs,
+// so that projects take turns by weight and a project's actions take turns
+// no matter how many Runs of one action are queued.
+// Ties go to whoever has waited the longest. The queue must be sorted by creation.
+func ScheduleRunQueue(queue []RunQueueEntry, usage []RunQueueUsage, weights RunQueueWeights, slots int) []RunQueueEntry {
+	type action struct {
+		project, name string
+	}
+
+	runningByProject := map[string]int{}
+	runningByAction := map[action]int{}
+	for _, u := range usage {
+		runningByProject[u.Project] += u.Running
+		runningByAction[action{u.Project, u.ActionName}] += u.Running
+	}
+
+	// The remaining entries of each action in order.
+	queueByAction := map[action][]RunQueueEntry{}
+	actions := []action{}
+	for _, entry := range queue {
+		a := action{entry.Project, entry.ActionName}
+		if _, ok := queueByAction[a]; !ok {
+			actions = append(actions, a)
+		}
+		queueByAction[a] = append(queueByAction[a], entry)
+	}
+
+	scheduled := []RunQueueEntry{}
+	for len(scheduled) < slots && len(actions) > 0 {
+		sort.SliceStable(actions, func(i, j int) bool {
+			a, b := actions[i], actions[j]
+
+			if a.project != b.project {
+				// Compare running/weight without dividing.
+				shareA := runningByProject[a.project] * weights.Of(b.project)
+				shareB := runningByProject[b.project] * weights.Of(a.project)
+				if shareA != shareB {
+					return shareA < shareB
+				}
+			}
+
+			if runningByAction[a] != runningByAction[b] {
+				return runningByAction[a] < runningByAction[b]
+			}
+
+			return queueByAction[a][0].CreatedAt.Before(queueByAction[b][0].CreatedAt)
+		})
+
+		next := actions[0]
+		scheduled = append(scheduled, queueByAction[next][0])
+		runningByProject[next.project]++
+		runningByAction[next]++
+
+		if queueByAction[next] = queueByAction[next][1:]; len(queueByAction[next]) == 0 {
+			actions = actions[1:]
+		}
+	}
+
+	return scheduled
+}
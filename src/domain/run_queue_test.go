@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleRunQueue(t *testing.T) {
+	t.Parallel()
+
+	// given
+	start := time.Unix(100, 0).UTC()
+	queue := []RunQueueEntry{}
+	// A noisy action queues many Runs first.
+	for i := 0; i < 10; i++ {
+		queue = append(queue, RunQueueEntry{Project: "noisy", ActionName: "build", CreatedAt: start.Add(time.Duration(i) * time.Second)})
+	}
+	queue = append(queue,
+		RunQueueEntry{Project: "quiet", ActionName: "test", CreatedAt: start.Add(20 * time.Second)},
+		RunQueueEntry{Project: "quiet", ActionName: "test", CreatedAt: start.Add(21 * time.Second)},
+		RunQueueEntry{Project: "quiet", ActionName: "deploy", CreatedAt: start.Add(22 * time.Second)},
+	)
+	usage := []RunQueueUsage{{Project: "quiet", ActionName: "test", Running: 1}}
+	weights := RunQueueWeights{"noisy": 2}
+
+	// when
+	scheduled := ScheduleRunQueue(queue, usage, weights, 6)
+
+	// then
+	projects := []string{}
+	actions := []string{}
+	for _, entry := range scheduled {
+		projects = append(projects, entry.Project)
+		actions = append(actions, entry.ActionName)
+	}
+	assert.Equal(t, []string{"noisy", "noisy", "quiet", "noisy", "noisy", "quiet"}, projects)
+	assert.Equal(t, []string{"build", "build", "deploy", "build", "build", "test"}, actions)
+	assert.Equal(t, queue[0].CreatedAt, scheduled[0].CreatedAt)
+}
+
+func TestParseRunQueueWeights(t *testing.T) {
+	t.Parallel()
+
+	weights, err := ParseRunQueueWeights([]string{"cicero=3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, weights.Of("cicero"))
+	assert.Equal(t, 1, weights.Of("other"))
+
+	_, err = ParseRunQueueWeights([]string{"cicero=0"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// What a Run's allocations and their tasks looked like at a moment
+// as far as the recorded Nomad events tell.
+type RunState struct {
+	Time time.Time `json:"time"`
+	// Running unless the Run had finished by then.
+	Status      RunStatus            `json:"status"`
+	Allocations []RunStateAllocation `json:"allocations"`
+}
+
+type RunStateAllocation struct {
+	Id            string `json:"id"`
+	TaskGroup     string `json:"task_group"`
+	NodeName      string `json:"node_name"`
+	ClientStatus  string `json:"client_status"`
+	DesiredStatus string `json:"desired_status"`
+	// When Nomad last reported the allocation before the moment.
+	// Anything that happened in between is not known.
+	ReportedAt time.Time `json:"reported_at"`
+	Tasks      []RunTask `json:"tasks"`
+}
+
+// Reconstructs the state of the Run at the given time
+// from the last update of each allocation before it.
+// The events must be ordered by their index.
+func NewRunState(run Run, events []NomadEvent, at time.Time) (RunState, error) {
+	state := RunState{
+		Time:        at,
+		Status:      RunStatusRunning,
+		Allocations: []RunStateAllocation{},
+	}
+	if run.FinishedAt != nil && !run.FinishedAt.After(at) {
+		state.Status = run.Status
+	}
+
+	nanos := at.UnixNano()
+
+	latest := map[string]*nomad.Allocation{}
+	for _, event := range events {
+		if event.Topic != nomad.TopicAllocation || event.Type != "AllocationUpdated" {
+			continue
+		}
+
+		alloc, err := event.Allocation()
+		if err != nil {
+			return state, err
+		}
+		if alloc.ModifyTime > nanos {
+			continue
+		}
+		if previous, ok := latest[alloc.ID]; !ok || previous.ModifyTime <= alloc.ModifyTime {
+			latest[alloc.ID] = alloc
+		}
+	}
+
+	allocs := make([]nomad.Allocation, 0, len(latest))
+	for _, alloc := range latest {
+		allocs = append(allocs, rewindAllocation(*alloc, at))
+	}
+	sort.SliceStable(allocs, func(i, j int) bool {
+		if allocs[i].CreateTime == allocs[j].CreateTime {
+			return allocs[i].ID < allocs[j].ID
+		}
+		return allocs[i].CreateTime < allocs[j].CreateTime
+	})
+
+	for _, alloc := range allocs {
+		state.Allocations = append(state.Allocations, RunStateAllocation{
+			Id:            alloc.ID,
+			TaskGroup:     alloc.TaskGroup,
+			NodeName:      alloc.NodeName,
+			ClientStatus:  alloc.ClientStatus,
+			DesiredStatus: alloc.DesiredStatus,
+			ReportedAt:    time.Unix(0, alloc.ModifyTime).UTC(),
+			Tasks:         NewRunTasks([]nomad.Allocation{alloc}, at),
+		})
+	}
+
+	return state, nil
+}
+
+// Drops task events after the given time in case
+// Nomad reported them with an earlier modify time.
+func rewindAllocation(alloc nomad.Allocation, at time.Time) nomad.Allocation {
+	nanos := at.UnixNano()
+
+	states := make(map[string]*nomad.TaskState, len(alloc.TaskStates))
+	for name, original := range alloc.TaskStates {
+		state := *original
+
+		state.Events = make([]*nomad.TaskEvent, 0, len(original.Events))
+		for _, event := range original.Events {
+			if event.Time <= nanos {
+				state.Events = append(state.Events, event)
+			}
+		}
+
+		if state.StartedAt.After(at) {
+			state.StartedAt = time.Time{}
+		}
+		if state.FinishedAt.After(at) {
+			state.FinishedAt = time.Time{}
+		}
+
+		states[name] = &state
+	}
+	alloc.TaskStates = states
+
+	return alloc
+}
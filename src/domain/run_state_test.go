@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunState(t *testing.T) {
+	t.Parallel()
+
+	// given
+	finishedAt := time.Unix(200, 0).UTC()
+	run := Run{
+		CreatedAt:  time.Unix(100, 0).UTC(),
+		FinishedAt: &finishedAt,
+		Status:     RunStatusFailed,
+	}
+
+	allocEvent := func(modifyTime int64, clientStatus, taskState, events string) NomadEvent {
+		event := NomadEvent{Event: nomad.Event{Topic: nomad.TopicAllocation, Type: "AllocationUpdated"}}
+		if err := json.Unmarshal([]byte(`{"Allocation": {
+			"ID": "alloc",
+			"TaskGroup": "group",
+			"CreateTime": 110000000000,
+			"ModifyTime": `+strconv.FormatInt(modifyTime*int64(time.Second), 10)+`,
+			"ClientStatus": "`+clientStatus+`",
+			"TaskStates": {"task": {"State": "`+taskState+`", "Events": [`+events+`]}}
+		}}`), &event.Payload); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	started := `{"Type": "Started", "Time": 120000000000}`
+	terminated := `{"Type": "Terminated", "Time": 150000000000, "ExitCode": 1}`
+	events := []NomadEvent{
+		allocEvent(120, "running", "running", started),
+		allocEvent(150, "failed", "dead", started+","+terminated),
+	}
+
+	// when
+	state, err := NewRunState(run, events, time.Unix(105, 0))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, RunStatusRunning, state.Status)
+	assert.Empty(t, state.Allocations, "not placed yet")
+
+	// when
+	state, err = NewRunState(run, events, time.Unix(130, 0))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, RunStatusRunning, state.Status)
+	if assert.Len(t, state.Allocations, 1) {
+		alloc := state.Allocations[0]
+		assert.Equal(t, "running", alloc.ClientStatus)
+		assert.Equal(t, time.Unix(120, 0).UTC(), alloc.ReportedAt)
+		if assert.Len(t, alloc.Tasks, 1) {
+			assert.Equal(t, "running", alloc.Tasks[0].State)
+			assert.Len(t, alloc.Tasks[0].Transitions, 1)
+			assert.Nil(t, alloc.Tasks[0].ExitCode)
+		}
+	}
+
+	// when
+	state, err = NewRunState(run, events, time.Unix(300, 0))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, RunStatusFailed, state.Status)
+	if assert.Len(t, state.Allocations, 1) && assert.Len(t, state.Allocations[0].Tasks, 1) {
+		task := state.Allocations[0].Tasks[0]
+		assert.Equal(t, "dead", task.State)
+		if assert.NotNil(t, task.ExitCode) {
+			assert.Equal(t, 1, *task.ExitCode)
+		}
+	}
+}
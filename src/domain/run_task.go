@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// A task of one of a Run's allocations.
+type RunTask struct {
+	AllocationId string `json:"allocation_id"`
+	TaskGroup    string `json:"task_group"`
+	Task         string `json:"task"`
+	// As reported by Nomad: pending, running, or dead.
+	State    string `json:"state"`
+	Failed   bool   `json:"failed"`
+	Restarts uint64 `json:"restarts"`
+	// Of the last time the task terminated.
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Until now if the task is still running.
+	DurationSeconds *float64            `json:"duration_seconds,omitempty"`
+	Transitions     []RunTaskTransition `json:"transitions"`
+}
+
+// An event of a task like "Started" or "Terminated".
+type RunTaskTransition struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Breaks the allocations of a Run down into their tasks
+// ordered by allocation creation, task group, and task name.
+func NewRunTasks(allocs []nomad.Allocation, now time.Time) []RunTask {
+	sorted := append([]nomad.Allocation{}, allocs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreateTime < sorted[j].CreateTime
+	})
+
+	tasks := []RunTask{}
+	for _, alloc := range sorted {
+		names := make([]string, 0, len(alloc.TaskStates))
+		for name := range alloc.TaskStates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			tasks = append(tasks, newRunTask(alloc, name, alloc.TaskStates[name], now))
+		}
+	}
+
+	return tasks
+}
+
+func newRunTask(alloc nomad.Allocation, name string, state *nomad.TaskState, now time.Time) RunTask {
+	task := RunTask{
+		AllocationId: alloc.ID,
+		TaskGroup:    alloc.TaskGroup,
+		Task:         name,
+		State:        state.State,
+		Failed:       state.Failed,
+		Restarts:     state.Restarts,
+		Transitions:  make([]RunTaskTransition, 0, len(state.Events)),
+	}
+
+	if !state.StartedAt.IsZero() {
+		startedAt := state.StartedAt.UTC()
+		task.StartedAt = &startedAt
+
+		end := now
+		if !state.FinishedAt.IsZero() {
+			finishedAt := state.FinishedAt.UTC()
+			task.FinishedAt = &finishedAt
+			end = finishedAt
+		}
+		duration := end.Sub(startedAt).Seconds()
+		task.DurationSeconds = &duration
+	}
+
+	for _, event := range state.Events {
+		task.Transitions = append(task.Transitions, RunTaskTransition{
+			Time:    time.Unix(0, event.Time).UTC(),
+			Type:    event.Type,
+			Message: event.DisplayMessage,
+		})
+
+		if event.Type == nomad.TaskTerminated {
+			exitCode := event.ExitCode
+			task.ExitCode = &exitCode
+		}
+	}
+
+	return task
+}
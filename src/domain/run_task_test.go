@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunTasks(t *testing.T) {
+	t.Parallel()
+
+	// given
+	allocs := []nomad.Allocation{}
+	if err := json.Unmarshal([]byte(`[{
+		"ID": "second",
+		"TaskGroup": "group",
+		"CreateTime": 200,
+		"TaskStates": {"task": {"State": "running", "StartedAt": "1970-01-01T00:03:00Z"}}
+	}, {
+		"ID": "first",
+		"TaskGroup": "group",
+		"CreateTime": 100,
+		"TaskStates": {
+			"main": {
+				"State": "dead",
+				"Failed": true,
+				"Restarts": 1,
+				"StartedAt": "1970-01-01T00:01:00Z",
+				"FinishedAt": "1970-01-01T00:02:30Z",
+				"Events": [
+					{"Type": "Started", "Time": 60000000000},
+					{"Type": "Terminated", "Time": 90000000000, "ExitCode": 1, "DisplayMessage": "Exit Code: 1"},
+					{"Type": "Restarting", "Time": 100000000000},
+					{"Type": "Terminated", "Time": 150000000000, "ExitCode": 2, "DisplayMessage": "Exit Code: 2"}
+				]
+			},
+			"init": {"State": "pending"}
+		}
+	}]`), &allocs); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	tasks := NewRunTasks(allocs, time.Unix(240, 0))
+
+	// then
+	names := make([]string, len(tasks))
+	for i, task := range tasks {
+		names[i] = task.AllocationId + "/" + task.Task
+	}
+	assert.Equal(t, []string{"first/init", "first/main", "second/task"}, names)
+
+	assert.Nil(t, tasks[0].StartedAt)
+	assert.Nil(t, tasks[0].DurationSeconds)
+	assert.Empty(t, tasks[0].Transitions)
+
+	main := tasks[1]
+	assert.True(t, main.Failed)
+	assert.Equal(t, uint64(1), main.Restarts)
+	if assert.NotNil(t, main.ExitCode) {
+		assert.Equal(t, 2, *main.ExitCode, "exit code of the last termination")
+	}
+	if assert.NotNil(t, main.DurationSeconds) {
+		assert.Equal(t, 90.0, *main.DurationSeconds)
+	}
+	assert.Len(t, main.Transitions, 4)
+	assert.Equal(t, "Exit Code: 1", main.Transitions[1].Message)
+
+	if assert.NotNil(t, tasks[2].DurationSeconds) {
+		assert.Equal(t, 60.0, *tasks[2].DurationSeconds, "running tasks last until now")
+	}
+	assert.Nil(t, tasks[2].ExitCode)
+}
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// How long a finished Run spent in each phase, in seconds.
+// Phases the Run never reached are nil.
+type RunTiming struct {
+	RunId uuid.UUID `json:"run_id"`
+	// From when the Run was created until its job was submitted to Nomad,
+	// including waiting for approval, a mutex, or its turn in the queue.
+	// Until the Run ended if its job was never submitted.
+	QueuedSeconds float64 `json:"queued_seconds"`
+	// Until the first task started.
+	PendingSeconds *float64 `json:"pending_seconds,omitempty"`
+	// Until the last task finished.
+	ExecutingSeconds *float64 `json:"executing_seconds,omitempty"`
+	// Until Cicero ended the Run, publishing its output.
+	FinalizingSeconds *float64  `json:"finalizing_seconds,omitempty"`
+	MeasuredAt        time.Time `json:"measured_at"`
+}
+
+type RunTimingPhase string
+
+const (
+	RunTimingPhaseQueued     RunTimingPhase = "queued"
+	RunTimingPhasePending    RunTimingPhase = "pending"
+	RunTimingPhaseExecuting  RunTimingPhase = "executing"
+	RunTimingPhaseFinalizing RunTimingPhase = "finalizing"
+)
+
+// How long the Runs of an action spent in a phase.
+type RunTimingAggregate struct {
+	Phase RunTimingPhase `json:"phase"`
+	// How many Runs reached the phase.
+	Runs       int     `json:"runs"`
+	AvgSeconds float64 `json:"avg_seconds"`
+	P50Seconds float64 `json:"p50_seconds" db:"p50_seconds"`
+	P95Seconds float64 `json:"p95_seconds" db:"p95_seconds"`
+	MaxSeconds float64 `json:"max_seconds"`
+}
+
+// Derives the phases of a finished Run from the Nomad events of its job.
+// The times come from Cicero and Nomad whose clocks may differ slightly
+// so phases are never negative.
+func NewRunTiming(run Run, events []NomadEvent) (RunTiming, error) {
+	timing := RunTiming{RunId: run.NomadJobID}
+
+	var submitted, started, finished time.Time
+	earliest := func(t *time.Time, candidate time.Time) {
+		if !candidate.IsZero() && (t.IsZero() || candidate.Before(*t)) {
+			*t = candidate
+		}
+	}
+	// Nomad leaves times it does not know at 0.
+	unixNano := func(nsec int64) time.Time {
+		if nsec <= 0 {
+			return time.Time{}
+		}
+		return time.Unix(0, nsec).UTC()
+	}
+
+	// Every event for an allocation contains its task states so far
+	// so the latest one of each allocation tells when its tasks finished.
+	latestAllocs := map[string]*nomad.Allocation{}
+	allocOrder := []string{}
+
+	for _, event := range events {
+		switch event.Topic {
+		case nomad.TopicJob:
+			if event.Type != "JobRegistered" {
+				continue
+			}
+
+			job, err := event.Job()
+			if err != nil {
+				return timing, err
+			}
+			if job.SubmitTime != nil {
+				earliest(&submitted, unixNano(*job.SubmitTime))
+			}
+		case nomad.TopicEvaluation:
+			eval, err := event.Evaluation()
+			if err != nil {
+				return timing, err
+			}
+			// In case JobRegistered events are skipped.
+			earliest(&submitted, unixNano(eval.CreateTime))
+		case nomad.TopicAllocation:
+			if event.Type != "AllocationUpdated" {
+				continue
+			}
+
+			alloc, err := event.Allocation()
+			if err != nil {
+				return timing, err
+			}
+
+			earliest(&submitted, unixNano(alloc.CreateTime))
+
+			if _, seen := latestAllocs[alloc.ID]; !seen {
+				allocOrder = append(allocOrder, alloc.ID)
+			}
+			latestAllocs[alloc.ID] = alloc
+		}
+	}
+
+	running := false
+	for _, allocId := range allocOrder {
+		for _, state := range latestAllocs[allocId].TaskStates {
+			if state == nil {
+				continue
+			}
+			earliest(&started, state.StartedAt.UTC())
+			if state.FinishedAt.IsZero() {
+				running = running || !state.StartedAt.IsZero()
+			} else if state.FinishedAt.After(finished) {
+				finished = state.FinishedAt.UTC()
+			}
+		}
+	}
+
+	ended := time.Now().UTC()
+	if run.FinishedAt != nil {
+		ended = *run.FinishedAt
+	}
+
+	seconds := func(from, to time.Time) float64 {
+		if to.Before(from) {
+			return 0
+		}
+		return to.Sub(from).Seconds()
+	}
+	phase := func(from, to time.Time) *float64 {
+		s := seconds(from, to)
+		return &s
+	}
+
+	if submitted.IsZero() {
+		timing.QueuedSeconds = seconds(run.CreatedAt, ended)
+		return timing, nil
+	}
+	timing.QueuedSeconds = seconds(run.CreatedAt, submitted)
+
+	if started.IsZero() {
+		timing.PendingSeconds = phase(submitted, ended)
+		return timing, nil
+	}
+	timing.PendingSeconds = phase(submitted, started)
+
+	// Tasks still seem to be running if the Run was canceled
+	// or the events of its end were not saved.
+	if running || finished.IsZero() {
+		timing.ExecutingSeconds = phase(started, ended)
+		return timing, nil
+	}
+	timing.ExecutingSeconds = phase(started, finished)
+	timing.FinalizingSeconds = phase(finished, ended)
+
+	return timing, nil
+}
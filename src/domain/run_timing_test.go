@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunTiming(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Unix(100, 0).UTC()
+	finishedAt := time.Unix(200, 0).UTC()
+	run := Run{CreatedAt: createdAt, FinishedAt: &finishedAt}
+
+	event := func(topic nomad.Topic, typ, payload string) NomadEvent {
+		event := NomadEvent{Event: nomad.Event{Topic: topic, Type: typ}}
+		if err := json.Unmarshal([]byte(payload), &event.Payload); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+	registered := event(nomad.TopicJob, "JobRegistered", `{"Job": {"SubmitTime": 110000000000}}`)
+	evaluated := event(nomad.TopicEvaluation, "EvaluationUpdated", `{"Evaluation": {"CreateTime": 111000000000}}`)
+	alloc := func(id, states string) NomadEvent {
+		return event(nomad.TopicAllocation, "AllocationUpdated", `{"Allocation": {
+			"ID": "`+id+`",
+			"CreateTime": 112000000000,
+			"TaskStates": {`+states+`}
+		}}`)
+	}
+
+	seconds := func(s float64) *float64 { return &s }
+
+	timing, err := NewRunTiming(run, []NomadEvent{
+		registered,
+		evaluated,
+		alloc("a", `"task": {"StartedAt": "1970-01-01T00:02:00Z"}`),
+		alloc("b", `"task": {"StartedAt": "1970-01-01T00:02:10Z"}`),
+		alloc("a", `"task": {"StartedAt": "1970-01-01T00:02:00Z", "FinishedAt": "1970-01-01T00:02:30Z"}`),
+		alloc("b", `"task": {"StartedAt": "1970-01-01T00:02:10Z", "FinishedAt": "1970-01-01T00:03:00Z"}`),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10.0, timing.QueuedSeconds)
+		assert.Equal(t, seconds(10), timing.PendingSeconds)
+		assert.Equal(t, seconds(60), timing.ExecutingSeconds)
+		assert.Equal(t, seconds(20), timing.FinalizingSeconds)
+	}
+
+	// Without JobRegistered the first evaluation tells when the job was submitted.
+	timing, err = NewRunTiming(run, []NomadEvent{evaluated})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 11.0, timing.QueuedSeconds)
+		assert.Equal(t, seconds(89), timing.PendingSeconds)
+		assert.Nil(t, timing.ExecutingSeconds)
+	}
+
+	// Canceled while a task was still running.
+	timing, err = NewRunTiming(run, []NomadEvent{
+		registered,
+		alloc("a", `"task": {"StartedAt": "1970-01-01T00:02:00Z", "FinishedAt": "1970-01-01T00:02:30Z"}, "other": {"StartedAt": "1970-01-01T00:02:00Z"}`),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, seconds(80), timing.ExecutingSeconds)
+		assert.Nil(t, timing.FinalizingSeconds)
+	}
+
+	// Never submitted, for example because it was denied.
+	timing, err = NewRunTiming(run, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 100.0, timing.QueuedSeconds)
+		assert.Nil(t, timing.PendingSeconds)
+	}
+
+	// Clocks of Nomad and Cicero may differ.
+	early := time.Unix(105, 0).UTC()
+	timing, err = NewRunTiming(Run{CreatedAt: time.Unix(115, 0).UTC(), FinishedAt: &early}, []NomadEvent{registered})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0.0, timing.QueuedSeconds)
+		assert.Equal(t, seconds(0), timing.PendingSeconds)
+	}
+}
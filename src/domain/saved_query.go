@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/util"
+)
+
+// Filters of the list of Runs. Empty fields match any Run.
+type RunFilter struct {
+	// Runs with any of these statuses.
+	Statuses []RunStatus
+	// Name of the Runs' action.
+	Action string
+	// The project of the Runs' action, see `Action.Project()`.
+	Project string
+}
+
+// Parses the filters from URL query parameters
+// `status` (repeatable), `action`, and `project`.
+func ParseRunFilter(query url.Values) (filter RunFilter, err error) {
+	for _, str := range query["status"] {
+		var status RunStatus
+		if err = status.FromString(str); err != nil {
+			return filter, errors.Errorf("Invalid status %q, must be running, succeeded, failed, or canceled", str)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	filter.Action = query.Get("action")
+	filter.Project = query.Get("project")
+	return
+}
+
+// Returns the filters as URL query parameters.
+func (self RunFilter) Query() url.Values {
+	query := url.Values{}
+	for _, status := range self.Statuses {
+		query.Add("status", status.String())
+	}
+	if self.Action != "" {
+		query.Set("action", self.Action)
+	}
+	if self.Project != "" {
+		query.Set("project", self.Project)
+	}
+	return query
+}
+
+func (self RunFilter) Empty() bool {
+	return len(self.Statuses) == 0 && self.Action == "" && self.Project == ""
+}
+
+func (self RunFilter) Match(run Run, action Action) bool {
+	if len(self.Statuses) > 0 {
+		found := false
+		for _, status := range self.Statuses {
+			if run.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return (self.Action == "" || action.Name == self.Action) &&
+		(self.Project == "" || action.Project() == self.Project)
+}
+
+// What a saved query lists.
+type SavedQueryKind string
+
+const (
+	// The query is the URL query of the Run list's filters, see `ParseRunFilter()`.
+	SavedQueryKindRun SavedQueryKind = "run"
+	// The query is CUE that facts must match.
+	SavedQueryKindFact SavedQueryKind = "fact"
+)
+
+func ParseSavedQueryKind(str string) (SavedQueryKind, error) {
+	switch kind := SavedQueryKind(str); kind {
+	case SavedQueryKindRun, SavedQueryKindFact:
+		return kind, nil
+	default:
+		return "", errors.Errorf("Invalid saved query kind %q, must be %q or %q", str, SavedQueryKindRun, SavedQueryKindFact)
+	}
+}
+
+// Names are used in URLs so they are limited to what needs no escaping.
+var savedQueryNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// A filter of Runs or facts that is shared by name.
+type SavedQuery struct {
+	Name        string         `json:"name"`
+	Kind        SavedQueryKind `json:"kind"`
+	Query       string         `json:"query"`
+	Description string         `json:"description"`
+	UpdatedBy   string         `json:"updated_by" db:"updated_by"`
+	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+func (self SavedQuery) Validate() error {
+	if !savedQueryNamePattern.MatchString(self.Name) {
+		return errors.Errorf("Invalid name %q, must start with a letter or digit followed by letters, digits, '.', '_', or '-'", self.Name)
+	}
+
+	if _, err := ParseSavedQueryKind(string(self.Kind)); err != nil {
+		return err
+	}
+
+	switch self.Kind {
+	case SavedQueryKindRun:
+		_, err := self.RunFilter()
+		return err
+	case SavedQueryKindFact:
+		if err := util.CUEString(self.Query).Value(nil, nil).Err(); err != nil {
+			return errors.WithMessage(err, "Invalid CUE")
+		}
+	}
+
+	return nil
+}
+
+// Returns the filters of a query of Runs.
+func (self SavedQuery) RunFilter() (RunFilter, error) {
+	if self.Kind != SavedQueryKindRun {
+		return RunFilter{}, errors.Errorf("Saved query %q is not of Runs", self.Name)
+	}
+	query, err := url.ParseQuery(self.Query)
+	if err != nil {
+		return RunFilter{}, errors.WithMessage(err, "Invalid URL query")
+	}
+	return ParseRunFilter(query)
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRunFilter(t *testing.T) {
+	t.Parallel()
+
+	query, _ := url.ParseQuery("status=failed&status=canceled&action=deploy&project=cicero")
+	filter, err := ParseRunFilter(query)
+	assert.NoError(t, err)
+	assert.Equal(t, RunFilter{
+		Statuses: []RunStatus{RunStatusFailed, RunStatusCanceled},
+		Action:   "deploy",
+		Project:  "cicero",
+	}, filter)
+	assert.False(t, filter.Empty())
+	assert.Equal(t, query, filter.Query())
+
+	filter, err = ParseRunFilter(url.Values{})
+	assert.NoError(t, err)
+	assert.True(t, filter.Empty())
+
+	_, err = ParseRunFilter(url.Values{"status": {"broken"}})
+	assert.Error(t, err)
+}
+
+func TestRunFilterMatch(t *testing.T) {
+	t.Parallel()
+
+	action := Action{Name: "deploy", Source: "cicero"}
+	run := Run{Status: RunStatusFailed}
+
+	assert.True(t, RunFilter{}.Match(run, action))
+	assert.True(t, RunFilter{Statuses: []RunStatus{RunStatusSucceeded, RunStatusFailed}}.Match(run, action))
+	assert.False(t, RunFilter{Statuses: []RunStatus{RunStatusSucceeded}}.Match(run, action))
+	assert.True(t, RunFilter{Action: "deploy", Project: "cicero"}.Match(run, action))
+	assert.False(t, RunFilter{Action: "build"}.Match(run, action))
+	assert.False(t, RunFilter{Project: "other"}.Match(run, action))
+}
+
+func TestSavedQueryValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, SavedQuery{Name: "failed-deploys", Kind: SavedQueryKindRun, Query: "status=failed&action=deploy"}.Validate())
+	assert.NoError(t, SavedQuery{Name: "releases", Kind: SavedQueryKindFact, Query: "{ release: string }"}.Validate())
+
+	for _, invalid := range []SavedQuery{
+		{Name: "", Kind: SavedQueryKindRun},
+		{Name: "-deploys", Kind: SavedQueryKindRun},
+		{Name: "failed deploys", Kind: SavedQueryKindRun},
+		{Name: "failed/deploys", Kind: SavedQueryKindRun},
+		{Name: "deploys", Kind: "action"},
+		{Name: "deploys", Kind: SavedQueryKindRun, Query: "status=broken"},
+		{Name: "deploys", Kind: SavedQueryKindRun, Query: "status=%zz"},
+		{Name: "releases", Kind: SavedQueryKindFact, Query: "{ release: "},
+	} {
+		assert.Error(t, invalid.Validate(), invalid.Name)
+	}
+}
@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// Sealed values are encrypted against Cicero's public key
+// so that facts can carry secrets without revealing them.
+// Only the action they were sealed for can use them
+// and they are decrypted just before its Runs' jobs are submitted to Nomad.
+
+const sealedPrefix = "cicero-sealed:"
+
+var sealedPattern = regexp.MustCompile(sealedPrefix + `[A-Za-z0-9_-]+`)
+
+type SealKey [32]byte
+
+func ParseSealKey(str string) (*SealKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not decode seal key")
+	}
+	if len(decoded) != len(SealKey{}) {
+		return nil, errors.Errorf("Seal key must be %d bytes, not %d", len(SealKey{}), len(decoded))
+	}
+
+	key := SealKey{}
+	copy(key[:], decoded)
+	return &key, nil
+}
+
+func (self SealKey) String() string {
+	return base64.StdEncoding.EncodeToString(self[:])
+}
+
+// Returns a new private key and its public key.
+func GenerateSealKey() (private, public *SealKey, err error) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "Could not generate seal key")
+	}
+	return (*SealKey)(privateKey), (*SealKey)(publicKey), nil
+}
+
+// What is encrypted. The action is encrypted along with the value
+// so that it cannot be changed to use the value in another action.
+type sealedPayload struct {
+	Action string `json:"action"`
+	Value  []byte `json:"value"`
+}
+
+// Returns the value encrypted against the public key for the action.
+func Seal(publicKey SealKey, action string, value []byte) (string, error) {
+	if action == "" {
+		return "", errors.New("Values must be sealed for an action")
+	}
+
+	payload, err := json.Marshal(sealedPayload{Action: action, Value: value})
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := box.SealAnonymous(nil, payload, (*[32]byte)(&publicKey), rand.Reader)
+	if err != nil {
+		return "", errors.WithMessage(err, "Could not seal value")
+	}
+
+	return sealedPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypts sealed values. A nil Unsealer has no key and can unseal nothing.
+type Unsealer struct {
+	private SealKey
+	public  SealKey
+}
+
+func NewUnsealer(private SealKey) (*Unsealer, error) {
+	public, err := curve25519.X25519(private[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Invalid seal key")
+	}
+
+	unsealer := Unsealer{private: private}
+	copy(unsealer.public[:], public)
+	return &unsealer, nil
+}
+
+// The key to seal values against.
+func (self *Unsealer) PublicKey() SealKey {
+	return self.public
+}
+
+// Returns the value that was sealed for the action.
+func (self *Unsealer) Unseal(action, sealed string) ([]byte, error) {
+	if self == nil {
+		return nil, errors.New("Cannot unseal values as Cicero has no seal key")
+	}
+
+	encoded := strings.TrimPrefix(sealed, sealedPrefix)
+	if encoded == sealed {
+		return nil, errors.New("Not a sealed value")
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not decode sealed value")
+	}
+
+	opened, ok := box.OpenAnonymous(nil, decoded, (*[32]byte)(&self.public), (*[32]byte)(&self.private))
+	if !ok {
+		return nil, errors.New("Could not decrypt sealed value, it may have been sealed against another key")
+	}
+
+	payload := sealedPayload{}
+	if err := json.Unmarshal(opened, &payload); err != nil {
+		return nil, errors.WithMessage(err, "Could not unmarshal sealed value")
+	}
+
+	if payload.Action != action {
+		return nil, errors.Errorf("Value was sealed for action %q, not %q", payload.Action, action)
+	}
+
+	return payload.Value, nil
+}
+
+// Returns the bytes encrypted against the public key.
+// Unlike sealed values they are not bound to an action
+// as only Cicero itself unseals them.
+func (self *Unsealer) SealBytes(value []byte) ([]byte, error) {
+	if self == nil {
+		return nil, errors.New("Cannot seal values as Cicero has no seal key")
+	}
+
+	sealed, err := box.SealAnonymous(nil, value, (*[32]byte)(&self.public), rand.Reader)
+	return sealed, errors.WithMessage(err, "Could not seal value")
+}
+
+// Returns the bytes that were sealed with `SealBytes()`.
+func (self *Unsealer) UnsealBytes(sealed []byte) ([]byte, error) {
+	if self == nil {
+		return nil, errors.New("Cannot unseal values as Cicero has no seal key")
+	}
+
+	opened, ok := box.OpenAnonymous(nil, sealed, (*[32]byte)(&self.public), (*[32]byte)(&self.private))
+	if !ok {
+		return nil, errors.New("Could not decrypt sealed value, it may have been sealed against another key")
+	}
+	return opened, nil
+}
+
+// Returns the reasons the sealed values in the job cannot be unsealed for the action.
+func (self *Unsealer) CheckJob(action string, job *nomad.Job) []string {
+	reasons := []string{}
+	seen := map[string]struct{}{}
+	_ = replaceStrings(reflect.ValueOf(job), func(str string) (string, error) {
+		for _, sealed := range sealedPattern.FindAllString(str, -1) {
+			if _, err := self.Unseal(action, sealed); err != nil {
+				if _, ok := seen[err.Error()]; !ok {
+					seen[err.Error()] = struct{}{}
+					reasons = append(reasons, err.Error())
+				}
+			}
+		}
+		return str, nil
+	})
+	return reasons
+}
+
+// Replaces the sealed values in all of the job's strings
+// with the values they were sealed from.
+func (self *Unsealer) UnsealJob(action string, job *nomad.Job) error {
+	return replaceStrings(reflect.ValueOf(job), func(str string) (string, error) {
+		var err error
+		str = sealedPattern.ReplaceAllStringFunc(str, func(sealed string) string {
+			if err != nil {
+				return sealed
+			}
+			var value []byte
+			value, err = self.Unseal(action, sealed)
+			return string(value)
+		})
+		return str, err
+	})
+}
+
+// Calls the function with every string the value contains
+// and replaces them with its results.
+func replaceStrings(value reflect.Value, replace func(string) (string, error)) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return replaceStrings(value.Elem(), replace)
+	case reflect.Interface:
+		if value.IsNil() || !value.CanSet() {
+			return nil
+		}
+		// The value in the interface cannot be set so it is replaced by a copy.
+		elem := reflect.New(value.Elem().Type()).Elem()
+		elem.Set(value.Elem())
+		if err := replaceStrings(elem, replace); err != nil {
+			return err
+		}
+		value.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if field := value.Field(i); field.CanSet() {
+				if err := replaceStrings(field, replace); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := replaceStrings(value.Index(i), replace); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			// Map values cannot be set so they are replaced by a copy.
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := replaceStrings(elem, replace); err != nil {
+				return err
+			}
+			value.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.String:
+		if !value.CanSet() {
+			return nil
+		}
+		if str, err := replace(value.String()); err != nil {
+			return err
+		} else {
+			value.SetString(str)
+		}
+	}
+	return nil
+}
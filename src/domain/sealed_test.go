@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeal(t *testing.T) {
+	t.Parallel()
+
+	private, public, err := GenerateSealKey()
+	assert.NoError(t, err)
+
+	parsed, err := ParseSealKey(public.String())
+	assert.NoError(t, err)
+	assert.Equal(t, public, parsed)
+
+	unsealer, err := NewUnsealer(*private)
+	assert.NoError(t, err)
+	assert.Equal(t, *public, unsealer.PublicKey())
+
+	sealed, err := Seal(*public, "deploy", []byte("hunter2"))
+	assert.NoError(t, err)
+	assert.Regexp(t, sealedPattern, sealed)
+	assert.NotContains(t, sealed, "hunter2")
+
+	value, err := unsealer.Unseal("deploy", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hunter2"), value)
+
+	_, err = unsealer.Unseal("build", sealed)
+	assert.Error(t, err)
+
+	_, err = (*Unsealer)(nil).Unseal("deploy", sealed)
+	assert.Error(t, err)
+
+	otherPrivate, _, err := GenerateSealKey()
+	assert.NoError(t, err)
+	other, err := NewUnsealer(*otherPrivate)
+	assert.NoError(t, err)
+	_, err = other.Unseal("deploy", sealed)
+	assert.Error(t, err)
+
+	_, err = Seal(*public, "", []byte("hunter2"))
+	assert.Error(t, err)
+}
+
+func TestUnsealJob(t *testing.T) {
+	t.Parallel()
+
+	private, public, err := GenerateSealKey()
+	assert.NoError(t, err)
+	unsealer, err := NewUnsealer(*private)
+	assert.NoError(t, err)
+
+	sealed, err := Seal(*public, "deploy", []byte("hunter2"))
+	assert.NoError(t, err)
+
+	newJob := func() *nomad.Job {
+		data := "password = " + sealed
+		return &nomad.Job{TaskGroups: []*nomad.TaskGroup{{Tasks: []*nomad.Task{{
+			Env:       map[string]string{"PASSWORD": sealed, "USER": "cicero"},
+			Config:    map[string]interface{}{"args": []interface{}{"--password", sealed}},
+			Templates: []*nomad.Template{{EmbeddedTmpl: &data}},
+		}}}}}
+	}
+
+	job := newJob()
+	assert.Empty(t, unsealer.CheckJob("deploy", job))
+	assert.Len(t, unsealer.CheckJob("build", job), 1)
+	assert.Len(t, (*Unsealer)(nil).CheckJob("deploy", job), 1)
+	assert.Empty(t, (*Unsealer)(nil).CheckJob("deploy", &nomad.Job{}))
+	assert.Equal(t, newJob(), job, "checking must not unseal")
+
+	assert.Error(t, unsealer.UnsealJob("build", job))
+
+	job = newJob()
+	assert.NoError(t, unsealer.UnsealJob("deploy", job))
+	task := job.TaskGroups[0].Tasks[0]
+	assert.Equal(t, map[string]string{"PASSWORD": "hunter2", "USER": "cicero"}, task.Env)
+	assert.Equal(t, map[string]interface{}{"args": []interface{}{"--password", "hunter2"}}, task.Config)
+	assert.Equal(t, "password = hunter2", *task.Templates[0].EmbeddedTmpl)
+}
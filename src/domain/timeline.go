@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+type RunTimelineKind string
+
+const (
+	RunTimelineKindQueued         RunTimelineKind = "queued"
+	RunTimelineKindEvaluation     RunTimelineKind = "evaluation"
+	RunTimelineKindPlacement      RunTimelineKind = "placement"
+	RunTimelineKindTaskStarted    RunTimelineKind = "task-started"
+	RunTimelineKindTaskRestarted  RunTimelineKind = "task-restarted"
+	RunTimelineKindTaskTerminated RunTimelineKind = "task-terminated"
+	RunTimelineKindTaskFailed     RunTimelineKind = "task-failed"
+	RunTimelineKindOOMKilled      RunTimelineKind = "oom-killed"
+	RunTimelineKindEnded          RunTimelineKind = "ended"
+)
+
+type RunTimelineEntry struct {
+	Time         time.Time       `json:"time"`
+	Kind         RunTimelineKind `json:"kind"`
+	Message      string          `json:"message"`
+	AllocationId string          `json:"allocation_id,omitempty"`
+	TaskGroup    string          `json:"task_group,omitempty"`
+	Task         string          `json:"task,omitempty"`
+}
+
+// Translates the raw Nomad events of a Run's job into
+// a time-ordered list of human-readable entries.
+// Events that are not meaningful to users are skipped.
+func NewRunTimeline(run Run, events []NomadEvent) ([]RunTimelineEntry, error) {
+	timeline := []RunTimelineEntry{{
+		Time:    run.CreatedAt,
+		Kind:    RunTimelineKindQueued,
+		Message: "Run created",
+	}}
+
+	// Every event for an allocation contains all task events
+	// that happened so far so we have to skip those we have already seen.
+	type taskEventKey struct {
+		allocId, task, eventType string
+		time                     int64
+	}
+	seenTaskEvents := map[taskEventKey]struct{}{}
+	seenAllocs := map[string]struct{}{}
+	seenEvals := map[string]struct{}{}
+
+	for _, event := range events {
+		switch event.Topic {
+		case nomad.TopicJob:
+			if event.Type != "JobRegistered" {
+				continue
+			}
+
+			job, err := event.Job()
+			if err != nil {
+				return nil, err
+			}
+			if job.SubmitTime == nil {
+				continue
+			}
+
+			timeline = append(timeline, RunTimelineEntry{
+				Time:    time.Unix(0, *job.SubmitTime).UTC(),
+				Kind:    RunTimelineKindQueued,
+				Message: "Job submitted to Nomad",
+			})
+		case nomad.TopicEvaluation:
+			eval, err := event.Evaluation()
+			if err != nil {
+				return nil, err
+			}
+
+			key := eval.ID + eval.Status
+			if _, seen := seenEvals[key]; seen {
+				continue
+			}
+			seenEvals[key] = struct{}{}
+
+			timeline = append(timeline, RunTimelineEntry{
+				Time:    time.Unix(0, eval.ModifyTime).UTC(),
+				Kind:    RunTimelineKindEvaluation,
+				Message: fmt.Sprintf("Evaluation %s triggered by %s is %s", shortId(eval.ID), eval.TriggeredBy, eval.Status),
+			})
+
+			for taskGroup, metric := range eval.FailedTGAllocs {
+				timeline = append(timeline, RunTimelineEntry{
+					Time:      time.Unix(0, eval.ModifyTime).UTC(),
+					Kind:      RunTimelineKindPlacement,
+					Message:   fmt.Sprintf("Placement failed: %d nodes evaluated, %d filtered, %d exhausted", metric.NodesEvaluated, metric.NodesFiltered, metric.NodesExhausted),
+					TaskGroup: taskGroup,
+				})
+			}
+		case nomad.TopicAllocation:
+			if event.Type != "AllocationUpdated" {
+				continue
+			}
+
+			alloc, err := event.Allocation()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, seen := seenAllocs[alloc.ID]; !seen {
+				seenAllocs[alloc.ID] = struct{}{}
+				timeline = append(timeline, RunTimelineEntry{
+					Time:         time.Unix(0, alloc.CreateTime).UTC(),
+					Kind:         RunTimelineKindPlacement,
+					Message:      fmt.Sprintf("Allocation %s placed on node %s", shortId(alloc.ID), alloc.NodeName),
+					AllocationId: alloc.ID,
+					TaskGroup:    alloc.TaskGroup,
+				})
+			}
+
+			for task, state := range alloc.TaskStates {
+				for _, taskEvent := range state.Events {
+					key := taskEventKey{alloc.ID, task, taskEvent.Type, taskEvent.Time}
+					if _, seen := seenTaskEvents[key]; seen {
+						continue
+					}
+					seenTaskEvents[key] = struct{}{}
+
+					entry := RunTimelineEntry{
+						Time:         time.Unix(0, taskEvent.Time).UTC(),
+						AllocationId: alloc.ID,
+						TaskGroup:    alloc.TaskGroup,
+						Task:         task,
+					}
+
+					switch taskEvent.Type {
+					case nomad.TaskStarted:
+						entry.Kind = RunTimelineKindTaskStarted
+						entry.Message = fmt.Sprintf("Task %s started", task)
+					case nomad.TaskRestarting:
+						entry.Kind = RunTimelineKindTaskRestarted
+						entry.Message = fmt.Sprintf("Task %s is restarting: %s", task, taskEvent.DisplayMessage)
+					case nomad.TaskTerminated:
+						if taskEvent.Details["oom_killed"] == "true" {
+							entry.Kind = RunTimelineKindOOMKilled
+							entry.Message = fmt.Sprintf("Task %s was killed because it ran out of memory", task)
+						} else {
+							entry.Kind = RunTimelineKindTaskTerminated
+							entry.Message = fmt.Sprintf("Task %s terminated: %s", task, taskEvent.DisplayMessage)
+						}
+					case nomad.TaskNotRestarting, nomad.TaskSetupFailure, nomad.TaskDriverFailure, nomad.TaskKilled:
+						entry.Kind = RunTimelineKindTaskFailed
+						entry.Message = fmt.Sprintf("Task %s: %s", task, taskEvent.DisplayMessage)
+					default:
+						continue
+					}
+
+					timeline = append(timeline, entry)
+				}
+			}
+		}
+	}
+
+	if run.FinishedAt != nil {
+		timeline = append(timeline, RunTimelineEntry{
+			Time:    *run.FinishedAt,
+			Kind:    RunTimelineKindEnded,
+			Message: "Run " + run.Status.String(),
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+
+	return timeline, nil
+}
+
+func shortId(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
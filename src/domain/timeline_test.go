@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunTimeline(t *testing.T) {
+	t.Parallel()
+
+	// given
+	createdAt := time.Unix(100, 0).UTC()
+	finishedAt := time.Unix(200, 0).UTC()
+	run := Run{
+		CreatedAt:  createdAt,
+		FinishedAt: &finishedAt,
+		Status:     RunStatusFailed,
+	}
+
+	allocEvent := func(events string) NomadEvent {
+		event := NomadEvent{Event: nomad.Event{Topic: nomad.TopicAllocation, Type: "AllocationUpdated"}}
+		if err := json.Unmarshal([]byte(`{"Allocation": {
+			"ID": "0123456789abcdef",
+			"NodeName": "node-a",
+			"TaskGroup": "group",
+			"CreateTime": 110000000000,
+			"TaskStates": {"task": {"Events": [`+events+`]}}
+		}}`), &event.Payload); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	started := `{"Type": "Started", "Time": 120000000000}`
+	oom := `{"Type": "Terminated", "Time": 130000000000, "Details": {"oom_killed": "true"}}`
+	received := `{"Type": "Received", "Time": 115000000000}`
+
+	// when
+	timeline, err := NewRunTimeline(run, []NomadEvent{
+		allocEvent(received + "," + started),
+		allocEvent(received + "," + started + "," + oom),
+	})
+
+	// then
+	assert.NoError(t, err)
+
+	kinds := make([]RunTimelineKind, len(timeline))
+	for i, entry := range timeline {
+		kinds[i] = entry.Kind
+	}
+	assert.Equal(t, []RunTimelineKind{
+		RunTimelineKindQueued,
+		RunTimelineKindPlacement,
+		RunTimelineKindTaskStarted,
+		RunTimelineKindOOMKilled,
+		RunTimelineKindEnded,
+	}, kinds)
+	assert.Equal(t, "Allocation 01234567 placed on node node-a", timeline[1].Message)
+	assert.Equal(t, "task", timeline[3].Task)
+	assert.Equal(t, "Run failed", timeline[4].Message)
+}
@@ -1,10 +1,13 @@
 package domain
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 	"time"
+	"unicode"
 
 	"cuelang.org/go/cue"
 	cueliteral "cuelang.org/go/cue/literal"
@@ -25,9 +28,63 @@ type Action struct {
 	ActionDefinition
 }
 
+// Meta attribute of an action with the namespace
+// of facts published by its Runs.
+const ActionMetaFactNamespace = "fact_namespace"
+
+// Returns the namespace that facts published by Runs
+// of this action are given unless they have one already.
+func (self Action) FactNamespace() string {
+	namespace, _ := self.Meta[ActionMetaFactNamespace].(string)
+	return namespace
+}
+
+// Meta attribute of an action with the project
+// its usage is charged to. Defaults to the action's source.
+const ActionMetaProject = "project"
+
+// Returns the project that the usage of the action is charged to.
+func (self Action) Project() string {
+	if project, ok := self.Meta[ActionMetaProject].(string); ok && project != "" {
+		return project
+	}
+	return self.Source
+}
+
 type ActionDefinition struct {
 	Meta  map[string]interface{} `json:"meta"`
 	InOut InOutCUEString         `json:"io" db:"io"`
+	Chain ActionChain            `json:"chain,omitempty" db:"chain"`
+}
+
+// Decodes the value of a key of the meta attribute, which is
+// decoded from CUE into generic values, like `json.Unmarshal()`.
+// Returns false if there is no such key or its value is null.
+func (self ActionDefinition) decodeMeta(key string, out interface{}) (bool, error) {
+	meta, ok := self.Meta[key]
+	if !ok || meta == nil {
+		return false, nil
+	}
+
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		return true, errors.WithMessagef(err, "Could not encode action meta %q", key)
+	}
+	return true, json.Unmarshal(encoded, out)
+}
+
+// Actions to invoke after a Run of an action ended.
+type ActionChain []ActionChainLink
+
+type ActionChainLink struct {
+	// Name of the action to invoke. Its current version is used.
+	Action string `json:"action"`
+	// Name of the input of the invoked action
+	// that is satisfied by the ended Run's output.
+	// If empty all inputs are matched as usual.
+	Input string `json:"input,omitempty"`
+	// Also invoke the action if the Run failed.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
 }
 
 type InOutCUEString util.CUEString
@@ -38,6 +95,32 @@ type InputDefinition struct {
 	Not      bool
 	Optional bool
 	Match    cue.Value
+	// Only facts signed by one of these FactPublishers match if not empty.
+	SignedBy []string
+	// The value of the input if no fact satisfies it, nil if it has none.
+	// Inputs with a default are optional.
+	Default interface{}
+}
+
+// Stands in for a fact when none satisfies the input.
+// It has no ID as it is not saved, see `Fact.IsInputDefault()`.
+func (self InputDefinition) DefaultFact() Fact {
+	return Fact{Value: self.Default}
+}
+
+func (self InputDefinition) AcceptsSigner(fact Fact) bool {
+	if len(self.SignedBy) == 0 {
+		return true
+	}
+	if fact.SignedBy == nil {
+		return false
+	}
+	for _, name := range self.SignedBy {
+		if name == *fact.SignedBy {
+			return true
+		}
+	}
+	return false
 }
 
 type OutputDefinition struct {
@@ -50,6 +133,10 @@ type Invocation struct {
 	ActionId   uuid.UUID  `json:"action_id"`
 	CreatedAt  time.Time  `json:"created_at"`
 	FinishedAt *time.Time `json:"finished_at"`
+	// The Run whose action's chain caused this Invocation.
+	ChainedFrom *uuid.UUID `json:"chained_from,omitempty" db:"chained_from"`
+	// Values of the optional inputs that no fact satisfied by input name.
+	InputDefaults map[string]interface{} `json:"input_defaults,omitempty" db:"input_defaults"`
 }
 
 type Run struct {
@@ -58,6 +145,62 @@ type Run struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	FinishedAt   *time.Time `json:"finished_at"`
 	Status       RunStatus  `json:"status"`
+	// When Cicero noticed that the Nomad job was garbage collected.
+	NomadJobGCedAt *time.Time `json:"nomad_job_gced_at,omitempty" db:"nomad_job_gced_at"`
+	// Name of the Nomad cluster the job was registered with.
+	// Empty for the default cluster.
+	NomadCluster string `json:"nomad_cluster,omitempty"`
+	// Status of the latest deployment of the Nomad job as reported by Nomad
+	// or RunDeploymentDegraded. Empty unless it is a service job.
+	DeploymentStatus            string `json:"deployment_status,omitempty"`
+	DeploymentStatusDescription string `json:"deployment_status_description,omitempty"`
+	// Why the job was not submitted to Nomad.
+	AdmissionDenials []string `json:"admission_denials,omitempty"`
+	// When the watchdog noticed that the Run's allocations
+	// stopped changing and logging. Nil unless it seems stuck.
+	SuspectSince *time.Time `json:"suspect_since,omitempty" db:"suspect_since"`
+	// Why the Run was ended by reconciling it with Nomad
+	// instead of by Nomad's events. Nil unless it was.
+	ReconcileNote *string `json:"reconcile_note,omitempty" db:"reconcile_note"`
+	// ID of the previous output fact of the action that was identical
+	// to the Run's output, which was therefore not published.
+	// Nil unless that happened.
+	OutputUnchangedFactId *uuid.UUID `json:"output_unchanged_fact_id,omitempty" db:"output_unchanged_fact_id"`
+	// Since when Nomad could not place all of the Run's allocations
+	// and is waiting to try again. Nil unless it is blocked.
+	BlockedSince *time.Time `json:"blocked_since,omitempty" db:"blocked_since"`
+	// Why the latest evaluation of the Run's job failed to place it.
+	BlockedReasons []string `json:"blocked_reasons,omitempty" db:"blocked_reasons"`
+}
+
+// Deployment status of a Run whose deployment
+// has not failed yet but has unhealthy allocations.
+const RunDeploymentDegraded = "degraded"
+
+// Resources consumed by a Run's allocations.
+type RunUsage struct {
+	RunId             uuid.UUID `json:"run_id"`
+	CPUSeconds        float64   `json:"cpu_seconds" db:"cpu_seconds"`
+	MemoryByteSeconds float64   `json:"memory_byte_seconds"`
+	MeasuredAt        time.Time `json:"measured_at"`
+}
+
+// What to sum up usage by.
+type RunUsageGroup string
+
+const (
+	RunUsageByAction  RunUsageGroup = "action"
+	RunUsageByProject RunUsageGroup = "project"
+)
+
+// Usage of the Runs that finished in a month
+// summed up by action or project.
+type RunUsageSum struct {
+	Month             time.Time `json:"month"`
+	Group             string    `json:"group"`
+	Runs              int       `json:"runs"`
+	CPUSeconds        float64   `json:"cpu_seconds" db:"cpu_seconds"`
+	MemoryByteSeconds float64   `json:"memory_byte_seconds"`
 }
 
 type RunStatus int8
@@ -70,18 +213,155 @@ const (
 )
 
 type Fact struct {
-	ID         uuid.UUID   `json:"id"`
-	RunId      *uuid.UUID  `json:"run_id,omitempty"`
-	CreatedAt  time.Time   `json:"created_at"`
-	Value      interface{} `json:"value"`
-	BinaryHash *string     `json:"binary_hash,omitempty"`
+	ID        uuid.UUID   `json:"id"`
+	RunId     *uuid.UUID  `json:"run_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Value     interface{} `json:"value"`
+	// The original value, sealed, if the value is stored redacted.
+	// See `Fact.SealRedacted()`.
+	ValueSealed []byte  `json:"-" db:"value_sealed"`
+	BinaryHash  *string `json:"binary_hash,omitempty"`
+	// Minisign signature of the SignedValue.
+	Signature *string `json:"signature,omitempty"`
+	// Name of the FactPublisher whose key made the signature.
+	SignedBy *string `json:"signed_by,omitempty"`
+	// ID of the ApiToken the fact was published with.
+	ApiTokenId *uuid.UUID `json:"api_token_id,omitempty" db:"api_token_id"`
+	// Links to other facts given when it was published.
+	// Use `FactService.GetLinks()` to get all links of a fact.
+	Links []FactLink `json:"links,omitempty" db:"-"`
 	// TODO nyi: unique key over (value, binary_hash)?
+	FactLabels
+}
+
+// Facts in the order they were created.
+type FactFeed struct {
+	Facts []Fact `json:"facts"`
+	// Give this as `since` to get the facts created afterwards.
+	Cursor int64 `json:"cursor"`
+}
+
+// Whether the fact is the default value of an input rather than a published fact.
+func (self Fact) IsInputDefault() bool {
+	return self.ID == uuid.Nil
+}
+
+// Returns the value as compact JSON with sorted keys,
+// like `jq --compact-output --join-output --sort-keys`,
+// which is what a fact's signature is made of.
+func (self Fact) SignedValue() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(self.Value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Optional labels to find facts by without matching their value.
+// By convention the namespace names where a fact comes from,
+// like a repository, and the name says what it is in that namespace.
+type FactLabels struct {
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+func (self FactLabels) Validate() error {
+	const maxLen = 255
+
+	check := func(what, str string) error {
+		switch {
+		case len(str) > maxLen:
+			return errors.Errorf("%s %q is longer than %d bytes", what, str, maxLen)
+		case strings.IndexFunc(str, func(r rune) bool { return !unicode.IsPrint(r) }) != -1:
+			return errors.Errorf("%s %q must not contain control characters", what, str)
+		}
+		return nil
+	}
+
+	if err := check("Namespace", self.Namespace); err != nil {
+		return err
+	}
+	if err := check("Name", self.Name); err != nil {
+		return err
+	}
+	for _, tag := range self.Tags {
+		if tag == "" {
+			return errors.New("Tags must not be empty")
+		}
+		if err := check("Tag", tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns "namespace/name" or just the name if there is no namespace.
+func (self FactLabels) FullName() string {
+	if self.Namespace == "" {
+		return self.Name
+	}
+	return self.Namespace + "/" + self.Name
+}
+
+// Prefix of the secrets of API tokens.
+const ApiTokenPrefix = "cicero_"
+
+// A token to authenticate against the API that is managed through the API.
+// Only a hash of its secret is stored.
+type ApiToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Hash       []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// A key of someone who signs facts.
+type FactPublisher struct {
+	Name string `json:"name"`
+	// Minisign public key.
+	PublicKey string    `json:"public_key"`
+	KeyId     string    `json:"key_id"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type NomadEvent struct {
 	nomad.Event
 	Uid     util.MD5Sum
 	Handled bool
+	// Name of the Nomad cluster the event came from.
+	NomadCluster string
+	// Increases in the order events are stored, see NomadEventHandler.
+	ID        int64
+	CreatedAt time.Time
+}
+
+// The environment of tasks in jobs that events carry,
+// for example those of the Job topic or refetched allocations.
+// Tasks get unsealed values and tokens there in plaintext.
+var nomadEventPayloadRedactions = func() util.Redactions {
+	redaction, err := util.NewRedaction(`$..Tasks[*].Env.*`, "")
+	if err != nil {
+		panic(err)
+	}
+	return util.Redactions{redaction}
+}()
+
+// Returns a copy of the payload without what must not be stored.
+func (self NomadEvent) RedactedPayload() map[string]interface{} {
+	if self.Payload == nil {
+		return nil
+	}
+	return nomadEventPayloadRedactions.Apply(self.Payload).(map[string]interface{})
 }
 
 func (self InOutCUEString) Inputs(inputs map[string]Fact) (InputDefinitions, error) {
@@ -129,6 +409,28 @@ func (self InOutCUEString) Input(name string, inputs map[string]Fact) (*InputDef
 		def.Optional = false
 	}
 
+	if v := value.LookupPath(cue.MakePath(cue.Str("signed_by"))); v.Exists() {
+		if err := v.Decode(&def.SignedBy); err != nil {
+			return nil, errors.WithMessagef(err, `"signed_by" of input %q must be a list of strings`, name)
+		}
+	}
+
+	if v := value.LookupPath(cue.MakePath(cue.Str("default"))); v.Exists() {
+		if def.Not {
+			return nil, fmt.Errorf(`negated input %q cannot have a "default"`, name)
+		}
+		// Decoded from JSON like the values of facts.
+		if valueJson, err := v.MarshalJSON(); err != nil {
+			return nil, errors.WithMessagef(err, `"default" of input %q must be concrete`, name)
+		} else if err := json.Unmarshal(valueJson, &def.Default); err != nil {
+			return nil, errors.WithMessagef(err, `Could not unmarshal "default" of input %q`, name)
+		}
+		if def.Default == nil {
+			return nil, fmt.Errorf(`"default" of input %q must not be null`, name)
+		}
+		def.Optional = true
+	}
+
 	if v := value.LookupPath(cue.MakePath(cue.Str("match"))); !v.Exists() {
 		return nil, fmt.Errorf(`input %q must have a "match" field`, name)
 	} else {
@@ -251,15 +553,32 @@ func (self *RunStatus) Scan(value interface{}) error {
 }
 
 // Sets the value from JSON and returns the rest of the buffer as binary.
-func (f *Fact) FromReader(reader io.Reader, trimWhitespace bool) (io.Reader, error) {
-	factDecoder := json.NewDecoder(reader)
+// Fails with a `*util.SizeLimitExceededError` as soon as
+// the value or binary exceed their limit. Limits of zero mean unlimited.
+func (f *Fact) FromReader(reader io.Reader, trimWhitespace bool, valueLimit, binaryLimit int64) (io.Reader, error) {
+	// The decoder reads ahead so we allow it one byte more than the limit
+	// and afterwards check how much of that it actually consumed.
+	// Whatever it did not consume is returned as part of the binary.
+	valueReader := reader
+	var limited *io.LimitedReader
+	if valueLimit > 0 {
+		limited = &io.LimitedReader{R: reader, N: valueLimit + 1}
+		valueReader = limited
+	}
+
+	factDecoder := json.NewDecoder(valueReader)
 	if err := factDecoder.Decode(&f.Value); err != nil {
+		if limited != nil && limited.N == 0 {
+			err = &util.SizeLimitExceededError{Limit: valueLimit}
+		}
 		return nil, errors.WithMessage(err, "Could not unmarshal json body")
+	} else if valueLimit > 0 && factDecoder.InputOffset() > valueLimit {
+		return nil, errors.WithMessage(&util.SizeLimitExceededError{Limit: valueLimit}, "Could not unmarshal json body")
 	} else {
 		binary := io.MultiReader(factDecoder.Buffered(), reader)
 		if trimWhitespace {
 			binary = util.SkipLeadingWhitespaceReader(binary)
 		}
-		return binary, nil
+		return util.SizeLimitReader(binary, binaryLimit), nil
 	}
 }
@@ -1,10 +1,14 @@
 package domain
 
 import (
+	"io"
+	"strings"
 	"testing"
 
 	"cuelang.org/go/cue"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/util"
 )
 
 func TestValueWithInputs(t *testing.T) {
@@ -27,3 +31,143 @@ func TestValueWithInputs(t *testing.T) {
 	assert.NoError(t, e)
 	assert.Equal(t, int64(1), i)
 }
+
+func TestFactFromReader(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct {
+		name        string
+		given       string
+		valueLimit  int64
+		binaryLimit int64
+		value       interface{}
+		binary      string
+		valueErr    bool
+		binaryErr   bool
+	}{
+		{name: "unlimited", given: `{"a":1} binary`, value: map[string]interface{}{"a": 1.0}, binary: "binary"},
+		{name: "value at limit", given: `"12345"`, valueLimit: 7, value: "12345"},
+		{name: "value at limit with binary", given: `12345XYZ`, valueLimit: 5, value: 12345.0, binary: "XYZ"},
+		{name: "value over limit", given: `"123456"`, valueLimit: 7, valueErr: true},
+		{name: "value over limit with binary", given: `123456XYZ`, valueLimit: 5, valueErr: true},
+		{name: "binary at limit", given: `1 XYZ`, binaryLimit: 3, value: 1.0, binary: "XYZ"},
+		{name: "binary over limit", given: `1 WXYZ`, binaryLimit: 3, value: 1.0, binary: "WXY", binaryErr: true},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			// given
+			fact := Fact{}
+
+			// when
+			binary, err := fact.FromReader(strings.NewReader(c.given), true, c.valueLimit, c.binaryLimit)
+
+			// then
+			var sizeErr *util.SizeLimitExceededError
+			if c.valueErr {
+				assert.ErrorAs(t, err, &sizeErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.value, fact.Value)
+
+			b, err := io.ReadAll(binary)
+			if c.binaryErr {
+				assert.ErrorAs(t, err, &sizeErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.binary, string(b))
+		})
+	}
+}
+
+func TestFactLabelsValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, FactLabels{}.Validate())
+	assert.NoError(t, FactLabels{
+		Namespace: "github.com/input-output-hk/cicero",
+		Name:      "cicero/ci",
+		Tags:      []string{"main", "nightly build"},
+	}.Validate())
+
+	assert.Error(t, FactLabels{Name: "a\nb"}.Validate())
+	assert.Error(t, FactLabels{Namespace: strings.Repeat("a", 256)}.Validate())
+	assert.Error(t, FactLabels{Tags: []string{""}}.Validate())
+}
+
+func TestFactSignedValue(t *testing.T) {
+	t.Parallel()
+
+	fact := Fact{Value: map[string]interface{}{
+		"z": []interface{}{float64(1), "<a>"},
+		"a": map[string]interface{}{"c": nil, "b": true},
+	}}
+
+	signed, err := fact.SignedValue()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":{"b":true,"c":null},"z":[1,"<a>"]}`, string(signed))
+}
+
+func TestInputSignedBy(t *testing.T) {
+	t.Parallel()
+
+	input, err := InOutCUEString(`inputs: a: { match: _, signed_by: ["alice", "bob"] }`).Input("a", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, input.SignedBy)
+
+	alice, eve := "alice", "eve"
+	assert.True(t, input.AcceptsSigner(Fact{SignedBy: &alice}))
+	assert.False(t, input.AcceptsSigner(Fact{SignedBy: &eve}))
+	assert.False(t, input.AcceptsSigner(Fact{}))
+	assert.True(t, InputDefinition{}.AcceptsSigner(Fact{}))
+}
+
+func TestInputDefault(t *testing.T) {
+	t.Parallel()
+
+	input, err := InOutCUEString(`inputs: a: { match: x: int, default: x: 1 }`).Input("a", nil)
+	assert.NoError(t, err)
+	assert.True(t, input.Optional, "a default makes the input optional")
+	assert.Equal(t, map[string]interface{}{"x": float64(1)}, input.Default)
+
+	fact := input.DefaultFact()
+	assert.True(t, fact.IsInputDefault())
+	assert.Equal(t, input.Default, fact.Value)
+
+	input, err = InOutCUEString(`inputs: a: match: _`).Input("a", nil)
+	assert.NoError(t, err)
+	assert.False(t, input.Optional)
+	assert.Nil(t, input.Default)
+
+	_, err = InOutCUEString(`inputs: a: { match: _, default: int }`).Input("a", nil)
+	assert.Error(t, err, "defaults must be concrete")
+
+	_, err = InOutCUEString(`inputs: a: { match: _, not: true, default: 1 }`).Input("a", nil)
+	assert.Error(t, err, "negated inputs cannot have a default")
+}
+
+func TestNomadEventRedactedPayload(t *testing.T) {
+	t.Parallel()
+
+	task := func(value string) map[string]interface{} {
+		return map[string]interface{}{"Name": "a", "Env": map[string]interface{}{"SECRET": value}}
+	}
+	job := func(value string) map[string]interface{} {
+		return map[string]interface{}{"TaskGroups": []interface{}{
+			map[string]interface{}{"Tasks": []interface{}{task(value)}},
+		}}
+	}
+
+	event := NomadEvent{}
+	event.Payload = map[string]interface{}{"Job": job("plaintext")}
+	assert.Equal(t, map[string]interface{}{"Job": job(util.Redacted)}, event.RedactedPayload())
+	assert.Equal(t, map[string]interface{}{"Job": job("plaintext")}, event.Payload, "must not be modified")
+
+	event.Payload = map[string]interface{}{"Allocation": map[string]interface{}{"Job": job("plaintext")}}
+	assert.Equal(t, map[string]interface{}{"Allocation": map[string]interface{}{"Job": job(util.Redacted)}}, event.RedactedPayload())
+
+	assert.Nil(t, NomadEvent{}.RedactedPayload())
+}
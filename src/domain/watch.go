@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// A Run or action that a user starred to see it in their watch list
+// and, if they gave an email address, to be told when its Runs end.
+type Watch struct {
+	ID uuid.UUID `json:"id"`
+	// Name of the identity that watches, see `auth.Identity`.
+	User string `json:"user"`
+	// Exactly one of RunId and ActionName is set.
+	RunId *uuid.UUID `json:"run_id,omitempty" db:"run_id"`
+	// Runs of all versions of the action are watched.
+	ActionName *string `json:"action_name,omitempty" db:"action_name"`
+	// Where to send notifications to, none are sent if nil.
+	Email          *string    `json:"email,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	LastNotifiedAt *time.Time `json:"last_notified_at,omitempty" db:"last_notified_at"`
+}
+
+func (self Watch) Validate() error {
+	if self.User == "" {
+		return errors.New("Watches must belong to a user")
+	}
+	if (self.RunId == nil) == (self.ActionName == nil) {
+		return errors.New("Either a Run or an action must be watched")
+	}
+	if self.ActionName != nil && *self.ActionName == "" {
+		return errors.New("The name of the watched action must not be empty")
+	}
+	return nil
+}
+
+// Returns the start of the time the next notification covers.
+func (self Watch) Since() time.Time {
+	if self.LastNotifiedAt != nil {
+		return *self.LastNotifiedAt
+	}
+	return self.CreatedAt
+}
+
+// A watch with the Runs to show for it in the watch list:
+// the watched Run or the latest Runs of the watched action.
+type WatchListEntry struct {
+	Watch
+	Runs []Run `json:"runs"`
+}
+
+// A Run of a watch that ended with the name of its action.
+type WatchedRun struct {
+	Run
+	ActionName string `json:"action_name" db:"action_name"`
+}
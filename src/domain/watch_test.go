@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchValidate(t *testing.T) {
+	t.Parallel()
+
+	runId := uuid.New()
+	action := "foo"
+	empty := ""
+
+	assert.NoError(t, Watch{User: "alice", RunId: &runId}.Validate())
+	assert.NoError(t, Watch{User: "alice", ActionName: &action}.Validate())
+
+	assert.Error(t, Watch{RunId: &runId}.Validate())
+	assert.Error(t, Watch{User: "alice"}.Validate())
+	assert.Error(t, Watch{User: "alice", RunId: &runId, ActionName: &action}.Validate())
+	assert.Error(t, Watch{User: "alice", ActionName: &empty}.Validate())
+}
+
+func TestWatchSince(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	watch := Watch{CreatedAt: createdAt}
+	assert.Equal(t, createdAt, watch.Since())
+
+	notifiedAt := createdAt.Add(time.Hour)
+	watch.LastNotifiedAt = &notifiedAt
+	assert.Equal(t, notifiedAt, watch.Since())
+}
@@ -0,0 +1,289 @@
+package cicero
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type FactsCmd struct {
+	Export *FactsExportCmd `arg:"subcommand:export" help:"write facts with their binaries to a bundle"`
+	Import *FactsImportCmd `arg:"subcommand:import" help:"publish the facts of a bundle"`
+}
+
+func (cmd *FactsCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.Export != nil:
+		return cmd.Export.Run(logger)
+	case cmd.Import != nil:
+		return cmd.Import.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+// A bundle is a tar archive with a directory per fact
+// that contains the fact as JSON and its binary, if any.
+const (
+	factBundleFact   = "fact.json"
+	factBundleBinary = "binary"
+)
+
+type FactsExportCmd struct {
+	Match  string `arg:"--match,required" help:"CUE the facts must match, like '{ name: \"foo\" }'"`
+	Output string `arg:"--output,-o" help:"file to write the bundle to, stdout if not given"`
+
+	ApiFlags
+}
+
+func (cmd *FactsExportCmd) Run(logger *zerolog.Logger) error {
+	res, err := cmd.do(http.MethodPost, "/api/v1/fact/match", http.Header{"Content-Type": {"text/plain"}}, strings.NewReader(cmd.Match))
+	if err != nil {
+		return errors.WithMessage(err, "Could not get matching facts")
+	}
+	defer res.Body.Close()
+
+	facts := []domain.Fact{}
+	if err := json.NewDecoder(res.Body).Decode(&facts); err != nil {
+		return errors.WithMessage(err, "Could not decode facts")
+	}
+
+	// Import facts in the order they were published.
+	sort.SliceStable(facts, func(i, j int) bool {
+		return facts[i].CreatedAt.Before(facts[j].CreatedAt)
+	})
+
+	output := os.Stdout
+	if cmd.Output != "" {
+		if output, err = os.Create(cmd.Output); err != nil {
+			return errors.WithMessagef(err, "Could not create %q", cmd.Output)
+		}
+		defer output.Close()
+	}
+
+	bundle := tar.NewWriter(output)
+
+	for _, fact := range facts {
+		factJson, err := json.Marshal(fact)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not marshal fact %q", fact.ID)
+		}
+		if err := writeFactBundleEntry(bundle, path.Join(fact.ID.String(), factBundleFact), fact.CreatedAt, int64(len(factJson)), bytes.NewReader(factJson)); err != nil {
+			return err
+		}
+
+		if fact.BinaryHash != nil {
+			if err := cmd.exportBinary(bundle, fact); err != nil {
+				return err
+			}
+		}
+
+		logger.Debug().Stringer("id", fact.ID).Msg("Exported fact")
+	}
+
+	if err := bundle.Close(); err != nil {
+		return errors.WithMessage(err, "Could not finish bundle")
+	}
+
+	logger.Info().Int("facts", len(facts)).Msg("Exported facts")
+	return nil
+}
+
+func (cmd *FactsExportCmd) exportBinary(bundle *tar.Writer, fact domain.Fact) error {
+	res, err := cmd.do(http.MethodGet, "/api/v1/fact/"+fact.ID.String()+"/binary", nil, nil)
+	if err != nil {
+		return errors.WithMessagef(err, "Could not get binary of fact %q", fact.ID)
+	}
+	defer res.Body.Close()
+
+	// The size must be known before the content is written.
+	binary := io.Reader(res.Body)
+	size := res.ContentLength
+	if size < 0 {
+		buf, err := io.ReadAll(res.Body)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not read binary of fact %q", fact.ID)
+		}
+		binary = bytes.NewReader(buf)
+		size = int64(len(buf))
+	}
+
+	return writeFactBundleEntry(bundle, path.Join(fact.ID.String(), factBundleBinary), fact.CreatedAt, size, binary)
+}
+
+func writeFactBundleEntry(bundle *tar.Writer, name string, modTime time.Time, size int64, content io.Reader) error {
+	if err := bundle.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modTime,
+	}); err != nil {
+		return errors.WithMessagef(err, "Could not write header of %q", name)
+	}
+	if _, err := io.Copy(bundle, content); err != nil {
+		return errors.WithMessagef(err, "Could not write %q", name)
+	}
+	return nil
+}
+
+type FactsImportCmd struct {
+	Bundle      string `arg:"positional,required" help:"file to read the bundle from, - for stdin"`
+	PreserveIds bool   `arg:"--preserve-ids" help:"publish facts with their IDs from the bundle and skip those that exist already"`
+
+	ApiFlags
+}
+
+func (cmd *FactsImportCmd) Run(logger *zerolog.Logger) error {
+	input := os.Stdin
+	if cmd.Bundle != "-" {
+		var err error
+		if input, err = os.Open(cmd.Bundle); err != nil {
+			return errors.WithMessagef(err, "Could not open %q", cmd.Bundle)
+		}
+		defer input.Close()
+	}
+
+	bundle := tar.NewReader(input)
+
+	imported, skipped := 0, 0
+	for {
+		header, err := bundle.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.WithMessage(err, "Could not read bundle")
+		}
+
+		if path.Base(header.Name) != factBundleFact {
+			return errors.Errorf("Expected a %s in the bundle but got %q", factBundleFact, header.Name)
+		}
+
+		var fact domain.Fact
+		if err := json.NewDecoder(bundle).Decode(&fact); err != nil {
+			return errors.WithMessagef(err, "Could not decode %q", header.Name)
+		}
+
+		var binary io.Reader
+		if fact.BinaryHash != nil {
+			header, err := bundle.Next()
+			if err != nil {
+				return errors.WithMessagef(err, "Could not read binary of fact %q", fact.ID)
+			}
+			if header.Name != path.Join(fact.ID.String(), factBundleBinary) {
+				return errors.Errorf("Expected the binary of fact %q in the bundle but got %q", fact.ID, header.Name)
+			}
+			binary = bundle
+		}
+
+		if cmd.PreserveIds {
+			if exists, err := cmd.exists(fact.ID); err != nil {
+				return err
+			} else if exists {
+				logger.Debug().Stringer("id", fact.ID).Msg("Skipping fact that exists already")
+				skipped++
+				continue
+			}
+		}
+
+		published, err := cmd.publish(fact, binary)
+		if err != nil {
+			return errors.WithMessagef(err, "Could not publish fact %q", fact.ID)
+		}
+
+		if (published.BinaryHash == nil) != (fact.BinaryHash == nil) ||
+			(published.BinaryHash != nil && *published.BinaryHash != *fact.BinaryHash) {
+			return errors.Errorf("Binary of fact %q was published with a different hash, the bundle may be corrupt", fact.ID)
+		}
+
+		logger.Debug().Stringer("id", fact.ID).Stringer("new-id", published.ID).Msg("Imported fact")
+		imported++
+	}
+
+	logger.Info().Int("imported", imported).Int("skipped", skipped).Msg("Imported facts")
+	return nil
+}
+
+func (cmd *FactsImportCmd) exists(id uuid.UUID) (bool, error) {
+	var fact *domain.Fact
+	if err := cmd.request(http.MethodGet, "/api/v1/fact/"+id.String(), nil, &fact); err != nil {
+		return false, errors.WithMessagef(err, "Could not check whether fact %q exists", id)
+	}
+	return fact != nil, nil
+}
+
+// Publishes the fact's value and binary as multipart form
+// so that the binary is kept exactly.
+// The Run the fact came from is dropped as it does not exist here.
+func (cmd *FactsImportCmd) publish(fact domain.Fact, binary io.Reader) (*domain.Fact, error) {
+	query := url.Values{}
+	if cmd.PreserveIds {
+		query.Set("id", fact.ID.String())
+	}
+	if fact.Namespace != "" {
+		query.Set("namespace", fact.Namespace)
+	}
+	if fact.Name != "" {
+		query.Set("name", fact.Name)
+	}
+	for _, tag := range fact.Tags {
+		query.Add("tag", tag)
+	}
+
+	body, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bodyWriter.CloseWithError(func() error {
+			if part, err := form.CreateFormField("value"); err != nil {
+				return err
+			} else if err := json.NewEncoder(part).Encode(fact.Value); err != nil {
+				return err
+			}
+			if binary != nil {
+				if part, err := form.CreateFormFile("binary", fact.ID.String()); err != nil {
+					return err
+				} else if _, err := io.Copy(part, binary); err != nil {
+					return err
+				}
+			}
+			return form.Close()
+		}())
+	}()
+
+	header := http.Header{}
+	header.Set("Content-Type", form.FormDataContentType())
+	// Signed facts stay signed if their publisher is known here.
+	if fact.Signature != nil {
+		header.Set("Cicero-Signature", base64.StdEncoding.EncodeToString([]byte(*fact.Signature)))
+	}
+
+	res, err := cmd.do(http.MethodPost, "/api/v1/fact?"+query.Encode(), header, body)
+	// Let the writer finish if the request ended early
+	// so that it does not read from the bundle anymore.
+	body.Close()
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	published := domain.Fact{}
+	return &published, json.NewDecoder(res.Body).Decode(&published)
+}
@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"cuelang.org/go/cue"
+	"github.com/direnv/direnv/v2/sri"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type storedFact struct {
+	domain.Fact
+	binary    []byte
+	seq       int64
+	dedupHash string
+}
+
+type FactRepository struct {
+	mutex sync.Mutex
+	facts []storedFact
+}
+
+var _ repository.FactRepository = &FactRepository{}
+
+func NewFactRepository() *FactRepository {
+	return &FactRepository{}
+}
+
+// Queries are not transactional so the querier is ignored.
+func (self *FactRepository) WithQuerier(config.PgxIface) repository.FactRepository {
+	return self
+}
+
+// Stores the Fact as it is, unlike `Save()`.
+// The binary may be nil.
+func (self *FactRepository) Add(fact domain.Fact, binary []byte) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.add(fact, binary, "")
+}
+
+// Must be called with the mutex locked.
+func (self *FactRepository) add(fact domain.Fact, binary []byte, dedupHash string) {
+	self.facts = append(self.facts, storedFact{fact, binary, int64(len(self.facts) + 1), dedupHash})
+}
+
+// Returns copies of the Facts that match
+// ordered by creation, newest first.
+func (self *FactRepository) filter(match func(storedFact) bool) []domain.Fact {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	facts := []domain.Fact{}
+	for i := len(self.facts) - 1; i >= 0; i-- {
+		if match(self.facts[i]) {
+			facts = append(facts, self.facts[i].Fact)
+		}
+	}
+	sort.SliceStable(facts, func(i, j int) bool {
+		return facts[i].CreatedAt.After(facts[j].CreatedAt)
+	})
+	return facts
+}
+
+// Like `FactService.Match()`.
+func matchCue(value cue.Value, fact domain.Fact) bool {
+	factCue := value.Context().Encode(fact.Value)
+	if factCue.Err() != nil {
+		return false
+	}
+	return value.Unify(factCue).Validate(cue.Concrete(true)) == nil
+}
+
+func firstFact(facts []domain.Fact) *domain.Fact {
+	if len(facts) == 0 {
+		return nil
+	}
+	return &facts[0]
+}
+
+func (self *FactRepository) GetById(id uuid.UUID) (*domain.Fact, error) {
+	return firstFact(self.filter(func(fact storedFact) bool {
+		return fact.ID == id
+	})), nil
+}
+
+func (self *FactRepository) GetByRunId(id uuid.UUID) ([]domain.Fact, error) {
+	return self.filter(func(fact storedFact) bool {
+		return fact.RunId != nil && *fact.RunId == id
+	}), nil
+}
+
+// Facts do not know the action of their Run here
+// so output facts are recognized by their name alone.
+func (self *FactRepository) GetLatestOutput(actionName string, exceptRunId uuid.UUID) (*domain.Fact, error) {
+	return firstFact(self.filter(func(fact storedFact) bool {
+		return fact.Name == actionName && fact.RunId != nil && *fact.RunId != exceptRunId
+	})), nil
+}
+
+func (self *FactRepository) GetLatestByCue(value cue.Value) (*domain.Fact, error) {
+	return firstFact(self.filter(func(fact storedFact) bool {
+		return matchCue(value, fact.Fact)
+	})), nil
+}
+
+func (self *FactRepository) GetLatestByCueSignedBy(value cue.Value, signers []string) (*domain.Fact, error) {
+	return firstFact(self.filter(func(fact storedFact) bool {
+		if fact.SignedBy == nil {
+			return false
+		}
+		for _, signer := range signers {
+			if *fact.SignedBy == signer {
+				return matchCue(value, fact.Fact)
+			}
+		}
+		return false
+	})), nil
+}
+
+func (self *FactRepository) GetByCue(value cue.Value) ([]domain.Fact, error) {
+	return self.filter(func(fact storedFact) bool {
+		return matchCue(value, fact.Fact)
+	}), nil
+}
+
+func (self *FactRepository) GetByLabels(labels domain.FactLabels, page *repository.Page) ([]domain.Fact, error) {
+	facts := self.filter(func(fact storedFact) bool {
+		if labels.Namespace != "" && fact.Namespace != labels.Namespace {
+			return false
+		}
+		if labels.Name != "" && fact.Name != labels.Name {
+			return false
+		}
+	Tags:
+		for _, tag := range labels.Tags {
+			for _, factTag := range fact.Tags {
+				if factTag == tag {
+					continue Tags
+				}
+			}
+			return false
+		}
+		return true
+	})
+
+	start, end := pageBounds(len(facts), page)
+	return facts[start:end], nil
+}
+
+func (self *FactRepository) GetFeed(since int64, limit int) (domain.FactFeed, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	feed := domain.FactFeed{Facts: []domain.Fact{}, Cursor: since}
+	for _, fact := range self.facts {
+		if len(feed.Facts) == limit {
+			break
+		}
+		if fact.seq > since {
+			feed.Facts = append(feed.Facts, fact.Fact)
+			feed.Cursor = fact.seq
+		}
+	}
+	return feed, nil
+}
+
+func (self *FactRepository) Save(fact *domain.Fact, binary io.Reader) error {
+	return self.save(fact, binary, nil)
+}
+
+func (self *FactRepository) SaveUnlessDuplicate(fact *domain.Fact, binary io.Reader, since time.Time) (bool, error) {
+	err := self.save(fact, binary, &since)
+	if err == errFactDuplicate {
+		return true, nil
+	}
+	return false, err
+}
+
+var errFactDuplicate = errors.New("Fact is a duplicate")
+
+// Looks for duplicates saved after dedupSince if not nil.
+func (self *FactRepository) save(fact *domain.Fact, binary io.Reader, dedupSince *time.Time) error {
+	var contents []byte
+	if binary != nil {
+		var err error
+		if contents, err = io.ReadAll(binary); err != nil {
+			return err
+		}
+	}
+
+	// Nothing was written because the read stream was empty.
+	// We treat that case as if we had `binary == nil`.
+	if len(contents) == 0 {
+		contents = nil
+	} else {
+		hash := sri.NewWriter(io.Discard, sri.SHA256)
+		if _, err := hash.Write(contents); err != nil {
+			return err
+		}
+		sum := hash.Sum()
+		if fact.BinaryHash != nil && *fact.BinaryHash != sum {
+			return errors.Errorf("Binary has hash %q instead of expected %q", sum, *fact.BinaryHash)
+		}
+		fact.BinaryHash = &sum
+
+		// Facts with the same binary share it like in the database.
+		self.mutex.Lock()
+		for _, stored := range self.facts {
+			if stored.BinaryHash != nil && *stored.BinaryHash == sum {
+				contents = stored.binary
+				break
+			}
+		}
+		self.mutex.Unlock()
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	var dedupHash string
+	if dedupSince != nil {
+		var err error
+		if dedupHash, err = fact.DedupHash(); err != nil {
+			return err
+		}
+		for i := len(self.facts) - 1; i >= 0; i-- {
+			if stored := self.facts[i]; stored.dedupHash == dedupHash && stored.CreatedAt.After(*dedupSince) {
+				*fact = stored.Fact
+				return errFactDuplicate
+			}
+		}
+	}
+
+	// A new ID is generated unless one is given.
+	if fact.ID == uuid.Nil {
+		fact.ID = uuid.New()
+	}
+	fact.CreatedAt = time.Now().UTC()
+
+	self.add(*fact, contents, dedupHash)
+	return nil
+}
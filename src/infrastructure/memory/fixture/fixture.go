@@ -0,0 +1,147 @@
+// Package fixture builds domain objects for tests
+// and stores them in in-memory repositories.
+package fixture
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/infrastructure/memory"
+)
+
+type Fixture struct {
+	Runs        *memory.RunRepository
+	Facts       *memory.FactRepository
+	NomadEvents *memory.NomadEventRepository
+
+	// Creation time of the next object.
+	// Each object is created a second after the previous one
+	// so that their order is predictable.
+	Now time.Time
+
+	nomadEventIndex uint64
+}
+
+func New() *Fixture {
+	return &Fixture{
+		Runs:        memory.NewRunRepository(),
+		Facts:       memory.NewFactRepository(),
+		NomadEvents: memory.NewNomadEventRepository(),
+		Now:         time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (self *Fixture) tick() time.Time {
+	now := self.Now
+	self.Now = self.Now.Add(time.Second)
+	return now
+}
+
+func (self *Fixture) Action(name string, modify ...func(*domain.Action)) domain.Action {
+	action := domain.Action{
+		ID:        uuid.New(),
+		Name:      name,
+		Source:    "github.com/input-output-hk/cicero",
+		CreatedAt: self.tick(),
+		Active:    true,
+	}
+	for _, f := range modify {
+		f(&action)
+	}
+	self.Runs.AddAction(action)
+	return action
+}
+
+func (self *Fixture) Invocation(action domain.Action, modify ...func(*domain.Invocation)) domain.Invocation {
+	invocation := domain.Invocation{
+		Id:        uuid.New(),
+		ActionId:  action.ID,
+		CreatedAt: self.tick(),
+	}
+	for _, f := range modify {
+		f(&invocation)
+	}
+	self.Runs.AddInvocation(invocation)
+	return invocation
+}
+
+// The Run is running unless modified.
+func (self *Fixture) Run(invocation domain.Invocation, modify ...func(*domain.Run)) domain.Run {
+	run := domain.Run{
+		NomadJobID:   uuid.New(),
+		InvocationId: invocation.Id,
+		CreatedAt:    self.tick(),
+		Status:       domain.RunStatusRunning,
+	}
+	for _, f := range modify {
+		f(&run)
+	}
+	self.Runs.Add(run)
+	return run
+}
+
+// Finishes the Run with the given status at the given time.
+func Finished(status domain.RunStatus, at time.Time) func(*domain.Run) {
+	return func(run *domain.Run) {
+		run.Status = status
+		run.FinishedAt = &at
+	}
+}
+
+// Invokes the action and runs the invocation.
+func (self *Fixture) ActionRun(action domain.Action, modify ...func(*domain.Run)) domain.Run {
+	return self.Run(self.Invocation(action), modify...)
+}
+
+// The binary may be nil.
+func (self *Fixture) Fact(value interface{}, binary []byte, modify ...func(*domain.Fact)) domain.Fact {
+	fact := domain.Fact{
+		ID:        uuid.New(),
+		CreatedAt: self.tick(),
+		Value:     value,
+	}
+	for _, f := range modify {
+		f(&fact)
+	}
+	self.Facts.Add(fact, binary)
+	return fact
+}
+
+// Saves an AllocationUpdated event for an allocation of the Run's Nomad job.
+func (self *Fixture) AllocationEvent(run domain.Run, alloc nomad.Allocation) domain.NomadEvent {
+	alloc.JobID = run.NomadJobID.String()
+	if alloc.ID == "" {
+		alloc.ID = uuid.NewString()
+	}
+	if alloc.CreateTime == 0 {
+		alloc.CreateTime = self.tick().UnixNano()
+	}
+
+	// Payloads are decoded from JSON like those from Nomad's event stream.
+	payload := map[string]interface{}{}
+	if encoded, err := json.Marshal(map[string]interface{}{"Allocation": alloc}); err != nil {
+		panic(err)
+	} else if err := json.Unmarshal(encoded, &payload); err != nil {
+		panic(err)
+	}
+
+	self.nomadEventIndex++
+	event := domain.NomadEvent{
+		Event: nomad.Event{
+			Topic:   "Allocation",
+			Type:    "AllocationUpdated",
+			Key:     alloc.ID,
+			Index:   self.nomadEventIndex,
+			Payload: payload,
+		},
+		NomadCluster: run.NomadCluster,
+	}
+	if err := self.NomadEvents.Save(&event); err != nil {
+		panic(err)
+	}
+	return event
+}
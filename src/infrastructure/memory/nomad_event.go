@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type NomadEventRepository struct {
+	mutex       sync.Mutex
+	events      []domain.NomadEvent
+	checkpoints map[string]int64
+}
+
+var _ repository.NomadEventRepository = &NomadEventRepository{}
+
+func NewNomadEventRepository() *NomadEventRepository {
+	return &NomadEventRepository{checkpoints: map[string]int64{}}
+}
+
+// Queries are not transactional so the querier is ignored.
+func (self *NomadEventRepository) WithQuerier(config.PgxIface) repository.NomadEventRepository {
+	return self
+}
+
+// Returns copies of the events that match ordered by index.
+func (self *NomadEventRepository) filter(match func(domain.NomadEvent) bool) []domain.NomadEvent {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	events := []domain.NomadEvent{}
+	for _, event := range self.events {
+		if match(event) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Index < events[j].Index
+	})
+	return events
+}
+
+// Looks up a string in the event's payload like `payload#>>path` in SQL.
+func payloadString(event domain.NomadEvent, path ...string) string {
+	var value interface{} = event.Payload
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = object[key]
+	}
+	str, _ := value.(string)
+	return str
+}
+
+// Saving an event that exists already does not change it
+// but returns whether it was handled.
+func (self *NomadEventRepository) Save(event *domain.NomadEvent) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return errors.WithMessage(err, "Could not marshal payload of nomad event")
+	}
+	event.Uid = md5.Sum(append([]byte(string(event.Topic)+event.Type), payload...))
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, stored := range self.events {
+		if stored.Uid == event.Uid {
+			event.Handled = stored.Handled
+			event.ID = stored.ID
+			event.CreatedAt = stored.CreatedAt
+			return nil
+		}
+	}
+
+	event.Handled = false
+	event.ID = int64(len(self.events) + 1)
+	event.CreatedAt = time.Now().UTC()
+	self.events = append(self.events, *event)
+	return nil
+}
+
+func (self *NomadEventRepository) Update(event *domain.NomadEvent) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for i := range self.events {
+		if self.events[i].Uid == event.Uid {
+			self.events[i].Handled = event.Handled
+		}
+	}
+	return nil
+}
+
+func (self *NomadEventRepository) GetByHandled(handled bool, clusters []string) ([]domain.NomadEvent, error) {
+	return self.filter(func(event domain.NomadEvent) bool {
+		if event.Handled != handled {
+			return false
+		}
+		for _, cluster := range clusters {
+			if event.NomadCluster == cluster {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (self *NomadEventRepository) GetLastNomadEventIndex(clusters []string) (uint64, error) {
+	events := self.filter(func(event domain.NomadEvent) bool {
+		for _, cluster := range clusters {
+			if event.NomadCluster == cluster {
+				return true
+			}
+		}
+		return false
+	})
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+	return events[len(events)-1].Index, nil
+}
+
+func (self *NomadEventRepository) GetLastNomadEventIndexByTopic(topic nomad.Topic, clusters []string) (uint64, error) {
+	events := self.filter(func(event domain.NomadEvent) bool {
+		if event.Topic != topic {
+			return false
+		}
+		for _, cluster := range clusters {
+			if event.NomadCluster == cluster {
+				return true
+			}
+		}
+		return false
+	})
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+	return events[len(events)-1].Index, nil
+}
+
+func (self *NomadEventRepository) GetByJobId(id uuid.UUID) ([]domain.NomadEvent, error) {
+	return self.filter(func(event domain.NomadEvent) bool {
+		switch event.Topic {
+		case "Allocation":
+			return payloadString(event, "Allocation", "JobID") == id.String()
+		case "Evaluation":
+			return payloadString(event, "Evaluation", "JobID") == id.String()
+		case "Job":
+			return payloadString(event, "Job", "ID") == id.String()
+		default:
+			return false
+		}
+	}), nil
+}
+
+func (self *NomadEventRepository) getEventAllocationByJobId(id uuid.UUID, latest bool) ([]nomad.Allocation, error) {
+	events := self.filter(func(event domain.NomadEvent) bool {
+		return event.Topic == "Allocation" &&
+			event.Type == "AllocationUpdated" &&
+			payloadString(event, "Allocation", "JobID") == id.String()
+	})
+
+	allocs := []nomad.Allocation{}
+	for i, event := range events {
+		if latest {
+			newer := false
+			for _, later := range events[i+1:] {
+				if later.Index > event.Index && payloadString(later, "Allocation", "ID") == payloadString(event, "Allocation", "ID") {
+					newer = true
+					break
+				}
+			}
+			if newer {
+				continue
+			}
+		}
+
+		alloc := nomad.Allocation{}
+		if payload, err := json.Marshal(event.Payload["Allocation"]); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(payload, &alloc); err != nil {
+			return nil, err
+		}
+		allocs = append(allocs, alloc)
+	}
+
+	sort.SliceStable(allocs, func(i, j int) bool {
+		return allocs[i].CreateTime < allocs[j].CreateTime
+	})
+
+	return allocs, nil
+}
+
+func (self *NomadEventRepository) GetEventAllocationByJobId(id uuid.UUID) ([]nomad.Allocation, error) {
+	return self.getEventAllocationByJobId(id, false)
+}
+
+func (self *NomadEventRepository) GetLatestEventAllocationByJobId(id uuid.UUID) ([]nomad.Allocation, error) {
+	return self.getEventAllocationByJobId(id, true)
+}
+
+func (self *NomadEventRepository) GetLatestEventEvaluationByJobId(id uuid.UUID) (*nomad.Evaluation, error) {
+	events := self.filter(func(event domain.NomadEvent) bool {
+		return event.Topic == "Evaluation" &&
+			payloadString(event, "Evaluation", "JobID") == id.String()
+	})
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	eval := nomad.Evaluation{}
+	if payload, err := json.Marshal(events[len(events)-1].Payload["Evaluation"]); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(payload, &eval); err != nil {
+		return nil, err
+	}
+	return &eval, nil
+}
+
+func (self *NomadEventRepository) GetAfterId(id int64, before time.Time, limit int) ([]domain.NomadEvent, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// Events are stored in the order of their ID.
+	events := []domain.NomadEvent{}
+	for _, event := range self.events {
+		if len(events) == limit {
+			break
+		}
+		if event.ID > id && event.CreatedAt.Before(before) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (self *NomadEventRepository) GetLastId() (int64, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return int64(len(self.events)), nil
+}
+
+func (self *NomadEventRepository) GetCheckpoint(handler string) (*int64, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if id, ok := self.checkpoints[handler]; ok {
+		return &id, nil
+	}
+	return nil, nil
+}
+
+func (self *NomadEventRepository) SaveCheckpoint(handler string, id int64) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.checkpoints[handler] = id
+	return nil
+}
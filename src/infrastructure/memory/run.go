@@ -0,0 +1,444 @@
+package memory
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type RunRepository struct {
+	mutex       sync.Mutex
+	runs        []domain.Run
+	invocations map[uuid.UUID]domain.Invocation
+	actions     map[uuid.UUID]domain.Action
+	logs        map[uuid.UUID][]byte
+	allocations map[uuid.UUID][]nomad.Allocation
+	// When a Run was last seen on each node.
+	nodes map[uuid.UUID]map[string]time.Time
+}
+
+var _ repository.RunRepository = &RunRepository{}
+
+func NewRunRepository() *RunRepository {
+	return &RunRepository{
+		invocations: map[uuid.UUID]domain.Invocation{},
+		actions:     map[uuid.UUID]domain.Action{},
+		logs:        map[uuid.UUID][]byte{},
+		allocations: map[uuid.UUID][]nomad.Allocation{},
+		nodes:       map[uuid.UUID]map[string]time.Time{},
+	}
+}
+
+// Queries are not transactional so the querier is ignored.
+func (self *RunRepository) WithQuerier(config.PgxIface) repository.RunRepository {
+	return self
+}
+
+// Stores the Run as it is, unlike `Save()`.
+func (self *RunRepository) Add(run domain.Run) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.runs = append(self.runs, run)
+}
+
+// Makes the Invocation known to queries that look at it,
+// like those by action or chain.
+func (self *RunRepository) AddInvocation(invocation domain.Invocation) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.invocations[invocation.Id] = invocation
+}
+
+// Makes the Action known to queries by its name.
+func (self *RunRepository) AddAction(action domain.Action) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.actions[action.ID] = action
+}
+
+// Returns a pointer into the stored Runs. The mutex must be held.
+func (self *RunRepository) find(id uuid.UUID) *domain.Run {
+	for i := range self.runs {
+		if self.runs[i].NomadJobID == id {
+			return &self.runs[i]
+		}
+	}
+	return nil
+}
+
+// Returns copies of the Runs that match ordered by creation, newest first.
+func (self *RunRepository) filter(match func(domain.Run) bool) []domain.Run {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	runs := []domain.Run{}
+	for _, run := range self.runs {
+		if match(run) {
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	return runs
+}
+
+func firstRun(runs []domain.Run) *domain.Run {
+	if len(runs) == 0 {
+		return nil
+	}
+	return &runs[0]
+}
+
+func reverseRuns(runs []domain.Run) {
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+}
+
+func paginateRuns(runs []domain.Run, page *repository.Page) []domain.Run {
+	start, end := pageBounds(len(runs), page)
+	return runs[start:end]
+}
+
+func (self *RunRepository) actionId(run domain.Run) (uuid.UUID, bool) {
+	invocation, ok := self.invocations[run.InvocationId]
+	return invocation.ActionId, ok
+}
+
+func (self *RunRepository) GetByNomadJobId(id uuid.UUID) (*domain.Run, error) {
+	return self.GetByNomadJobIdWithLock(id, "")
+}
+
+func (self *RunRepository) GetByNomadJobIdWithLock(id uuid.UUID, _ string) (*domain.Run, error) {
+	return firstRun(self.filter(func(run domain.Run) bool {
+		return run.NomadJobID == id
+	})), nil
+}
+
+func (self *RunRepository) GetByInvocationId(id uuid.UUID) (*domain.Run, error) {
+	return firstRun(self.filter(func(run domain.Run) bool {
+		return run.InvocationId == id
+	})), nil
+}
+
+func (self *RunRepository) GetByActionId(id uuid.UUID, page *repository.Page) ([]domain.Run, error) {
+	return paginateRuns(self.filter(func(run domain.Run) bool {
+		actionId, ok := self.actionId(run)
+		return ok && actionId == id
+	}), page), nil
+}
+
+func (self *RunRepository) GetLatestByActionId(id uuid.UUID) (*domain.Run, error) {
+	return firstRun(self.filter(func(run domain.Run) bool {
+		actionId, ok := self.actionId(run)
+		return ok && actionId == id
+	})), nil
+}
+
+func (self *RunRepository) GetLatestByActionName(name string) (*domain.Run, error) {
+	return firstRun(self.filter(func(run domain.Run) bool {
+		actionId, ok := self.actionId(run)
+		return ok && self.actions[actionId].Name == name
+	})), nil
+}
+
+func (self *RunRepository) GetPreviousSucceeded(of domain.Run) (*domain.Run, error) {
+	self.mutex.Lock()
+	actionId, ok := self.actionId(of)
+	name := self.actions[actionId].Name
+	self.mutex.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	return firstRun(self.filter(func(run domain.Run) bool {
+		actionId, ok := self.actionId(run)
+		return ok && self.actions[actionId].Name == name &&
+			run.Status == domain.RunStatusSucceeded &&
+			run.CreatedAt.Before(of.CreatedAt)
+	})), nil
+}
+
+func (self *RunRepository) GetChainedFrom(id uuid.UUID) ([]domain.Run, error) {
+	runs := self.filter(func(run domain.Run) bool {
+		chainedFrom := self.invocations[run.InvocationId].ChainedFrom
+		return chainedFrom != nil && *chainedFrom == id
+	})
+	reverseRuns(runs)
+	return runs, nil
+}
+
+// The inputs of Invocations are not known here so only chains are followed.
+func (self *RunRepository) GetDownstream(id uuid.UUID) ([]domain.Run, error) {
+	downstream := map[uuid.UUID]bool{id: true}
+	for {
+		found := self.filter(func(run domain.Run) bool {
+			chainedFrom := self.invocations[run.InvocationId].ChainedFrom
+			return !downstream[run.NomadJobID] && chainedFrom != nil && downstream[*chainedFrom]
+		})
+		if len(found) == 0 {
+			break
+		}
+		for _, run := range found {
+			downstream[run.NomadJobID] = true
+		}
+	}
+
+	runs := self.filter(func(run domain.Run) bool {
+		return run.NomadJobID != id && downstream[run.NomadJobID] && run.FinishedAt == nil
+	})
+	reverseRuns(runs)
+	return runs, nil
+}
+
+func (self *RunRepository) GetAll(page *repository.Page) ([]domain.Run, error) {
+	return paginateRuns(self.filter(func(domain.Run) bool { return true }), page), nil
+}
+
+func (self *RunRepository) GetByFilter(filter domain.RunFilter, page *repository.Page) ([]domain.Run, error) {
+	return paginateRuns(self.filter(func(run domain.Run) bool {
+		actionId, ok := self.actionId(run)
+		return ok && filter.Match(run, self.actions[actionId])
+	}), page), nil
+}
+
+func (self *RunRepository) Save(run *domain.Run) error {
+	run.NomadJobID = uuid.New()
+	run.CreatedAt = time.Now().UTC()
+	self.Add(*run)
+	return nil
+}
+
+// Calls the function with the stored Run
+// or returns an error if there is none.
+func (self *RunRepository) update(id uuid.UUID, f func(*domain.Run)) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	stored := self.find(id)
+	if stored == nil {
+		return errors.Errorf("No Run with ID %q", id)
+	}
+	f(stored)
+	return nil
+}
+
+func (self *RunRepository) Update(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.FinishedAt = run.FinishedAt
+		stored.Status = run.Status
+	})
+}
+
+func (self *RunRepository) UpdateNomadCluster(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.NomadCluster = run.NomadCluster
+	})
+}
+
+func (self *RunRepository) UpdateDeployment(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.DeploymentStatus = run.DeploymentStatus
+		stored.DeploymentStatusDescription = run.DeploymentStatusDescription
+	})
+}
+
+func (self *RunRepository) UpdateAdmissionDenials(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.AdmissionDenials = run.AdmissionDenials
+	})
+}
+
+func (self *RunRepository) UpdateBlocked(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.BlockedSince = run.BlockedSince
+		stored.BlockedReasons = run.BlockedReasons
+	})
+}
+
+func (self *RunRepository) UpdateOutputUnchanged(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.OutputUnchangedFactId = run.OutputUnchangedFactId
+	})
+}
+
+func (self *RunRepository) GetRunning() ([]domain.Run, error) {
+	runs := self.filter(func(run domain.Run) bool {
+		return run.FinishedAt == nil && run.Status == domain.RunStatusRunning
+	})
+	reverseRuns(runs)
+	return runs, nil
+}
+
+func (self *RunRepository) UpdateSuspect(run *domain.Run) error {
+	return self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.SuspectSince = run.SuspectSince
+	})
+}
+
+func (self *RunRepository) Reconcile(run *domain.Run) (reconciled bool, err error) {
+	err = self.update(run.NomadJobID, func(stored *domain.Run) {
+		if stored.FinishedAt != nil {
+			return
+		}
+		stored.FinishedAt = run.FinishedAt
+		stored.Status = run.Status
+		stored.ReconcileNote = run.ReconcileNote
+		reconciled = true
+	})
+	return
+}
+
+// Returns the Runs that finished before the given time
+// ordered by when they finished, oldest first.
+func (self *RunRepository) getFinished(finishedBefore time.Time, limit int, match func(domain.Run) bool) []domain.Run {
+	runs := self.filter(func(run domain.Run) bool {
+		return run.FinishedAt != nil && run.FinishedAt.Before(finishedBefore) && match(run)
+	})
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].FinishedAt.Before(*runs[j].FinishedAt)
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+func (self *RunRepository) GetFinishedWithoutNomadJobGC(finishedBefore time.Time, limit int) ([]domain.Run, error) {
+	return self.getFinished(finishedBefore, limit, func(run domain.Run) bool {
+		return run.NomadJobGCedAt == nil
+	}), nil
+}
+
+func (self *RunRepository) MarkNomadJobGCed(run *domain.Run) error {
+	now := time.Now().UTC()
+	if err := self.update(run.NomadJobID, func(stored *domain.Run) {
+		stored.NomadJobGCedAt = &now
+	}); err != nil {
+		return err
+	}
+	run.NomadJobGCedAt = &now
+	return nil
+}
+
+func (self *RunRepository) GetFinishedWithoutLogArchive(finishedBefore time.Time, limit int) ([]domain.Run, error) {
+	return self.getFinished(finishedBefore, limit, func(run domain.Run) bool {
+		_, archived := self.logs[run.NomadJobID]
+		return !archived
+	}), nil
+}
+
+func (self *RunRepository) SaveLogArchive(id uuid.UUID, log io.Reader, _ int) error {
+	contents, err := io.ReadAll(log)
+	if err != nil {
+		return err
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if _, exists := self.logs[id]; exists {
+		return errors.Errorf("Log of Run with ID %q was archived already", id)
+	}
+	self.logs[id] = contents
+	return nil
+}
+
+func (self *RunRepository) GetLogArchive(_ pgx.Tx, id uuid.UUID) (io.ReadSeekCloser, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if log, ok := self.logs[id]; ok {
+		return readSeekNopCloser{bytes.NewReader(log)}, nil
+	}
+	return nil, nil
+}
+
+func (self *RunRepository) GetAllocations(id uuid.UUID) ([]nomad.Allocation, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	allocs := append([]nomad.Allocation{}, self.allocations[id]...)
+	sort.SliceStable(allocs, func(i, j int) bool {
+		return allocs[i].CreateTime < allocs[j].CreateTime
+	})
+	return allocs, nil
+}
+
+func (self *RunRepository) SaveAllocations(id uuid.UUID, allocs []*nomad.Allocation) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	saved := make([]nomad.Allocation, len(allocs))
+	for i, alloc := range allocs {
+		saved[i] = *alloc
+	}
+	self.allocations[id] = saved
+	return nil
+}
+
+func (self *RunRepository) SaveNode(runId uuid.UUID, nodeId string) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if self.find(runId) == nil {
+		return nil
+	}
+	if self.nodes[runId] == nil {
+		self.nodes[runId] = map[string]time.Time{}
+	}
+	if _, exists := self.nodes[runId][nodeId]; !exists {
+		self.nodes[runId][nodeId] = time.Now().UTC()
+	}
+	return nil
+}
+
+func (self *RunRepository) GetLatestNodesByActionName(name string, limit int) ([]string, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	latest := map[string]time.Time{}
+	for runId, nodes := range self.nodes {
+		run := self.find(runId)
+		if actionId, ok := self.actionId(*run); !ok || self.actions[actionId].Name != name {
+			continue
+		}
+		for nodeId, at := range nodes {
+			if at.After(latest[nodeId]) {
+				latest[nodeId] = at
+			}
+		}
+	}
+
+	nodeIds := make([]string, 0, len(latest))
+	for nodeId := range latest {
+		nodeIds = append(nodeIds, nodeId)
+	}
+	sort.Slice(nodeIds, func(i, j int) bool {
+		return latest[nodeIds[i]].After(latest[nodeIds[j]])
+	})
+	if len(nodeIds) > limit {
+		nodeIds = nodeIds[:limit]
+	}
+	return nodeIds, nil
+}
+
+// Annotations, timing, and usage are not stored here.
+func (self *RunRepository) GetLastMeasuredAt([]uuid.UUID) (*time.Time, error) {
+	return nil, nil
+}
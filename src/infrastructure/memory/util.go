@@ -0,0 +1,31 @@
+// Package memory implements repositories that keep everything in memory
+// so that services can be tested without a database.
+// They behave like those in package persistence as far as tests care.
+package memory
+
+import (
+	"bytes"
+
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+// Sets the page's total and returns the bounds of its items.
+func pageBounds(total int, page *repository.Page) (start, end int) {
+	page.Total = total
+	start, end = page.Offset, page.Offset+page.Limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	return
+}
+
+type readSeekNopCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekNopCloser) Close() error {
+	return nil
+}
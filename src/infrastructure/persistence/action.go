@@ -16,11 +16,11 @@ type actionRepository struct {
 }
 
 func NewActionRepository(db config.PgxIface) repository.ActionRepository {
-	return &actionRepository{db}
+	return &actionRepository{mapErrors(db)}
 }
 
 func (a *actionRepository) WithQuerier(querier config.PgxIface) repository.ActionRepository {
-	return &actionRepository{querier}
+	return &actionRepository{mapErrors(querier)}
 }
 
 func (a *actionRepository) GetById(id uuid.UUID) (*domain.Action, error) {
@@ -102,20 +102,47 @@ func (a *actionRepository) GetAll() (actions []domain.Action, err error) {
 	return
 }
 
+func (a *actionRepository) GetWithoutMeta() (actions []domain.Action, err error) {
+	actions = []domain.Action{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &actions,
+		`SELECT * FROM action WHERE meta IS NULL ORDER BY created_at ASC`,
+	)
+	return
+}
+
+// Actions without meta are saved with an empty one
+// so that they can be told apart from those without it saved.
+func actionMeta(action *domain.Action) map[string]interface{} {
+	if action.Meta == nil {
+		return map[string]interface{}{}
+	}
+	return action.Meta
+}
+
 func (a *actionRepository) Save(action *domain.Action) error {
 	var sql string
 	if action.ID == (uuid.UUID{}) {
-		sql = `INSERT INTO action (    name, source, io) VALUES (    $2, $3, $4) RETURNING id, created_at`
+		sql = `INSERT INTO action (    name, source, io, chain, meta) VALUES (    $2, $3, $4, $5, $6) RETURNING id, created_at`
 	} else {
-		sql = `INSERT INTO action (id, name, source, io) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+		sql = `INSERT INTO action (id, name, source, io, chain, meta) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
 	}
 	return a.DB.QueryRow(
 		context.Background(),
 		sql,
-		action.ID, action.Name, action.Source, action.InOut,
+		action.ID, action.Name, action.Source, action.InOut, action.Chain, actionMeta(action),
 	).Scan(&action.ID, &action.CreatedAt)
 }
 
+func (a *actionRepository) UpdateMeta(action *domain.Action) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE action SET meta = $2 WHERE id = $1`,
+		action.ID, actionMeta(action),
+	)
+	return
+}
+
 func (a *actionRepository) Update(action *domain.Action) (err error) {
 	_, err = a.DB.Exec(
 		context.Background(),
@@ -65,7 +65,7 @@ func TestShouldSaveAction(t *testing.T) {
 	// given
 	mock, _ := mocks.BuildTransaction(context.Background(), t)
 	rows := mock.NewRows([]string{"id", "created_at"}).AddRow(actionId, dateTime)
-	mock.ExpectQuery("INSERT INTO action").WithArgs(action.ID, action.Name, action.Source, action.InOut).WillReturnRows(rows)
+	mock.ExpectQuery("INSERT INTO action").WithArgs(action.ID, action.Name, action.Source, action.InOut, action.Chain, action.Meta).WillReturnRows(rows)
 	mock.ExpectCommit()
 	repository := NewActionRepository(mock)
 
@@ -77,3 +77,61 @@ func TestShouldSaveAction(t *testing.T) {
 	assert.Equal(t, actionId, action.ID)
 	assert.Equal(t, dateTime, action.CreatedAt)
 }
+
+func TestShouldRoundTripActionMeta(t *testing.T) {
+	t.Parallel()
+	action := domain.Action{
+		ID:     uuid.New(),
+		Name:   "Name",
+		Source: "Source",
+		ActionDefinition: domain.ActionDefinition{
+			Meta: map[string]interface{}{"owner": "team"},
+		},
+	}
+
+	// given
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mock.Close(context.Background())
+	mock.ExpectQuery("INSERT INTO action").
+		WithArgs(action.ID, action.Name, action.Source, action.InOut, action.Chain, action.Meta).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(action.ID, time.Now().UTC()))
+	mock.ExpectQuery("SELECT(.*)").WithArgs(action.ID).
+		WillReturnRows(mock.NewRows([]string{"id", "name", "source", "meta"}).AddRow(action.ID, action.Name, action.Source, action.Meta))
+
+	repository := NewActionRepository(mock)
+
+	// when
+	err = repository.Save(&action)
+	assert.Nil(t, err)
+	result, err := repository.GetById(action.ID)
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, action.Meta, result.Meta)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestShouldSaveActionWithoutMeta(t *testing.T) {
+	t.Parallel()
+	action := domain.Action{ID: uuid.New(), Name: "Name", Source: "Source"}
+
+	// given
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mock.Close(context.Background())
+	mock.ExpectQuery("INSERT INTO action").
+		WithArgs(action.ID, action.Name, action.Source, action.InOut, action.Chain, map[string]interface{}{}).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(action.ID, time.Now().UTC()))
+
+	// when
+	err = NewActionRepository(mock).Save(&action)
+
+	// then
+	assert.Nil(t, err, "meta is saved as empty so that it is not backfilled")
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type alertRepository struct {
+	DB config.PgxIface
+}
+
+func NewAlertRepository(db config.PgxIface) repository.AlertRepository {
+	return alertRepository{mapErrors(db)}
+}
+
+func (a alertRepository) WithQuerier(querier config.PgxIface) repository.AlertRepository {
+	return alertRepository{mapErrors(querier)}
+}
+
+func (a alertRepository) GetRunFailureStreaks(min int) (streaks []domain.RunFailureStreak, err error) {
+	streaks = []domain.RunFailureStreak{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &streaks,
+		`WITH finished AS (
+			SELECT
+				run.nomad_job_id,
+				run.status,
+				run.created_at,
+				action.id AS action_id,
+				action.name AS action_name,
+				COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source) AS project,
+				COALESCE(action.meta#>>'{`+domain.ActionMetaOwner+`,team}', '') AS team,
+				COALESCE(action.meta#>>'{`+domain.ActionMetaOwner+`,contact}', '') AS contact,
+				COALESCE(action.meta#>>'{`+domain.ActionMetaOwner+`,escalation}', '') AS escalation
+			FROM run
+			JOIN invocation ON invocation.id = run.invocation_id
+			JOIN action ON action.id = invocation.action_id
+			WHERE run.finished_at IS NOT NULL AND run.status IN ('succeeded', 'failed')
+		), last_success AS (
+			SELECT action_name, MAX(created_at) AS created_at
+			FROM finished
+			WHERE status = 'succeeded'
+			GROUP BY action_name
+		)
+		SELECT * FROM (
+			SELECT DISTINCT ON (finished.action_name)
+				finished.action_name,
+				finished.action_id,
+				finished.project,
+				finished.team,
+				finished.contact,
+				finished.escalation,
+				finished.nomad_job_id AS last_run_id,
+				COUNT(*) OVER (PARTITION BY finished.action_name) AS failures,
+				MIN(finished.created_at) OVER (PARTITION BY finished.action_name) AS since
+			FROM finished
+			LEFT JOIN last_success USING (action_name)
+			WHERE finished.status = 'failed' AND (last_success.created_at IS NULL OR finished.created_at > last_success.created_at)
+			ORDER BY finished.action_name, finished.created_at DESC
+		) AS streak
+		WHERE failures >= $1
+		ORDER BY action_name`,
+		min,
+	)
+	return
+}
@@ -0,0 +1,92 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type apiTokenRepository struct {
+	DB config.PgxIface
+}
+
+func NewApiTokenRepository(db config.PgxIface) repository.ApiTokenRepository {
+	return apiTokenRepository{mapErrors(db)}
+}
+
+func (a apiTokenRepository) WithQuerier(querier config.PgxIface) repository.ApiTokenRepository {
+	return apiTokenRepository{mapErrors(querier)}
+}
+
+func (a apiTokenRepository) GetAll() (tokens []domain.ApiToken, err error) {
+	tokens = []domain.ApiToken{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &tokens,
+		`SELECT * FROM api_token ORDER BY created_at DESC`,
+	)
+	return
+}
+
+func (a apiTokenRepository) GetById(id uuid.UUID) (*domain.ApiToken, error) {
+	token, err := get(
+		a.DB, &domain.ApiToken{},
+		`SELECT * FROM api_token WHERE id = $1`,
+		id,
+	)
+	if token == nil {
+		return nil, err
+	}
+	return token.(*domain.ApiToken), err
+}
+
+func (a apiTokenRepository) GetByHash(hash []byte) (*domain.ApiToken, error) {
+	token, err := get(
+		a.DB, &domain.ApiToken{},
+		`SELECT * FROM api_token WHERE hash = $1`,
+		hash,
+	)
+	if token == nil {
+		return nil, err
+	}
+	return token.(*domain.ApiToken), err
+}
+
+func (a apiTokenRepository) Save(token *domain.ApiToken) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO api_token (name, hash, scopes, created_by, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		token.Name, token.Hash, token.Scopes, token.CreatedBy, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (a apiTokenRepository) Update(token *domain.ApiToken) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE api_token SET name = $2, scopes = $3, expires_at = $4 WHERE id = $1`,
+		token.ID, token.Name, token.Scopes, token.ExpiresAt,
+	)
+	return
+}
+
+func (a apiTokenRepository) Revoke(id uuid.UUID) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE api_token SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`,
+		id,
+	)
+	return
+}
+
+func (a apiTokenRepository) UpdateLastUsed(id uuid.UUID) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE api_token SET last_used_at = NOW() WHERE id = $1`,
+		id,
+	)
+	return
+}
@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type auditLogRepository struct {
+	DB config.PgxIface
+}
+
+func NewAuditLogRepository(db config.PgxIface) repository.AuditLogRepository {
+	return auditLogRepository{mapErrors(db)}
+}
+
+func (a auditLogRepository) WithQuerier(querier config.PgxIface) repository.AuditLogRepository {
+	return auditLogRepository{mapErrors(querier)}
+}
+
+func (a auditLogRepository) Get(filter domain.AuditLogFilter, page *repository.Page) ([]domain.AuditLogEntry, error) {
+	where := []string{}
+	args := []interface{}{}
+	if filter.Identity != "" {
+		args = append(args, filter.Identity)
+		where = append(where, `identity = $`+strconv.Itoa(len(args)))
+	}
+	if filter.ApiTokenId != nil {
+		args = append(args, *filter.ApiTokenId)
+		where = append(where, `api_token_id = $`+strconv.Itoa(len(args)))
+	}
+	if filter.ClientIP != "" {
+		args = append(args, filter.ClientIP)
+		where = append(where, `client_ip = $`+strconv.Itoa(len(args)))
+	}
+	if filter.Method != "" {
+		args = append(args, strings.ToUpper(filter.Method))
+		where = append(where, `method = $`+strconv.Itoa(len(args)))
+	}
+	if filter.PathPrefix != "" {
+		args = append(args, filter.PathPrefix)
+		where = append(where, `starts_with(path, $`+strconv.Itoa(len(args))+`)`)
+	}
+	if filter.Since != nil {
+		args = append(args, *filter.Since)
+		where = append(where, `created_at >= $`+strconv.Itoa(len(args)))
+	}
+	if filter.Until != nil {
+		args = append(args, *filter.Until)
+		where = append(where, `created_at < $`+strconv.Itoa(len(args)))
+	}
+	if filter.Failed != nil {
+		if *filter.Failed {
+			where = append(where, `status >= 400`)
+		} else {
+			where = append(where, `status < 400`)
+		}
+	}
+
+	from := `audit_log`
+	if len(where) > 0 {
+		from += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+
+	entries := make([]domain.AuditLogEntry, page.Limit)
+	return entries, fetchPage(
+		a.DB, page, &entries,
+		`*`, from, `created_at DESC, id DESC`,
+		args...,
+	)
+}
+
+func (a auditLogRepository) Save(entry *domain.AuditLogEntry) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO audit_log (identity, auth_method, api_token_id, client_ip, method, path, query, body_size, status, duration_ms) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`,
+		entry.Identity, entry.AuthMethod, entry.ApiTokenId, entry.ClientIP, entry.Method, entry.Path, entry.Query, entry.BodySize, entry.Status, entry.DurationMs,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
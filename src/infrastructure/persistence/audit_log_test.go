@@ -0,0 +1,49 @@
+package persistence
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+func TestShouldSaveAuditLogEntry(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UTC()
+	identity := "ci"
+	clientIP := "192.0.2.1"
+	entry := domain.AuditLogEntry{
+		Identity:   &identity,
+		ClientIP:   &clientIP,
+		Method:     http.MethodPost,
+		Path:       "/api/fact",
+		Status:     http.StatusOK,
+		DurationMs: 12,
+	}
+
+	// given
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error %q was not expected when opening a stub database connection", err)
+	}
+	defer mock.Close(context.Background())
+	rows := mock.NewRows([]string{"id", "created_at"}).AddRow(int64(42), now)
+	mock.ExpectQuery("INSERT INTO audit_log").
+		WithArgs(entry.Identity, entry.AuthMethod, entry.ApiTokenId, entry.ClientIP, entry.Method, entry.Path, entry.Query, entry.BodySize, entry.Status, entry.DurationMs).
+		WillReturnRows(rows)
+	repository := NewAuditLogRepository(mock)
+
+	// when
+	err = repository.Save(&entry)
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), entry.ID)
+	assert.Equal(t, now, entry.CreatedAt)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
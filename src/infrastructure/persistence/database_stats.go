@@ -0,0 +1,128 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type databaseStatsRepository struct {
+	DB config.PgxIface
+}
+
+func NewDatabaseStatsRepository(db config.PgxIface) repository.DatabaseStatsRepository {
+	return databaseStatsRepository{mapErrors(db)}
+}
+
+func (a databaseStatsRepository) WithQuerier(querier config.PgxIface) repository.DatabaseStatsRepository {
+	return databaseStatsRepository{mapErrors(querier)}
+}
+
+func (a databaseStatsRepository) GetTables() (tables []domain.DatabaseTableStats, err error) {
+	tables = []domain.DatabaseTableStats{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &tables,
+		// The bloat of an index is estimated as its size minus the size
+		// of its entries, made of the average width of the indexed columns
+		// plus tuple header and item pointer, in pages filled to 90%.
+		`WITH index_bloat AS (
+			SELECT
+				i.indrelid AS relid,
+				sum(GREATEST(
+					pg_relation_size(c.oid) -
+					ceil(GREATEST(c.reltuples, 0) * (12 + COALESCE(w.width, 0)) / (current_setting('block_size')::numeric * 0.9)) *
+					current_setting('block_size')::numeric,
+					0
+				))::bigint AS bytes
+			FROM pg_index i
+			JOIN pg_class c ON c.oid = i.indexrelid
+			JOIN pg_am am ON am.oid = c.relam AND am.amname = 'btree'
+			LEFT JOIN LATERAL (
+				SELECT sum(st.avg_width) AS width
+				FROM pg_attribute a
+				JOIN pg_class t ON t.oid = a.attrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+				JOIN pg_stats st ON st.schemaname = n.nspname AND st.tablename = t.relname AND st.attname = a.attname
+				WHERE a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+			) w ON TRUE
+			WHERE i.indexprs IS NULL
+			GROUP BY i.indrelid
+		)
+		SELECT
+			s.relname AS name,
+			s.n_live_tup AS live_rows,
+			s.n_dead_tup AS dead_rows,
+			pg_table_size(s.relid) AS table_bytes,
+			pg_indexes_size(s.relid) AS index_bytes,
+			COALESCE(index_bloat.bytes, 0) AS index_bloat_bytes,
+			GREATEST(s.last_vacuum, s.last_autovacuum) AS last_vacuum,
+			GREATEST(s.last_analyze, s.last_autoanalyze) AS last_analyze
+		FROM pg_stat_user_tables s
+		LEFT JOIN index_bloat ON index_bloat.relid = s.relid
+		WHERE s.schemaname = 'public'
+		ORDER BY pg_total_relation_size(s.relid) DESC`,
+	)
+	return
+}
+
+func (a databaseStatsRepository) GetUnhandledNomadEvents() (events []domain.UnhandledNomadEventStats, err error) {
+	events = []domain.UnhandledNomadEventStats{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &events,
+		`SELECT nomad_cluster, count(*) AS count, min("index")::bigint AS oldest_index
+		FROM nomad_event
+		WHERE NOT handled
+		GROUP BY nomad_cluster
+		ORDER BY nomad_cluster`,
+	)
+	return
+}
+
+func (a databaseStatsRepository) GetFactBytesByProject() (projects []domain.ProjectFactBytes, err error) {
+	projects = []domain.ProjectFactBytes{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &projects,
+		`SELECT
+			COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source, '') AS project,
+			count(*) AS facts,
+			COALESCE(sum(octet_length(fact.value::text) + COALESCE(fact.binary_size, 0)), 0)::bigint AS bytes
+		FROM fact
+		LEFT JOIN run ON run.nomad_job_id = fact.run_id
+		LEFT JOIN invocation ON invocation.id = run.invocation_id
+		LEFT JOIN action ON action.id = invocation.action_id
+		GROUP BY 1
+		ORDER BY bytes DESC`,
+	)
+	return
+}
+
+func (a databaseStatsRepository) GetRetention() (retention domain.DatabaseRetentionStats, err error) {
+	err = pgxscan.Get(
+		context.Background(), a.DB, &retention,
+		`SELECT
+			count(*) FILTER (WHERE nomad_job_gced_at IS NULL) AS runs_without_nomad_job_gc,
+			min(finished_at) FILTER (WHERE nomad_job_gced_at IS NULL) AS oldest_without_nomad_job_gc,
+			count(*) FILTER (WHERE run_log.run_id IS NULL) AS runs_without_log_archive,
+			min(finished_at) FILTER (WHERE run_log.run_id IS NULL) AS oldest_without_log_archive
+		FROM run
+		LEFT JOIN run_log ON run_log.run_id = run.nomad_job_id
+		WHERE run.finished_at IS NOT NULL`,
+	)
+	return
+}
+
+func (a databaseStatsRepository) GetFactBinaryTiers() (tiers []domain.FactBinaryTierStats, err error) {
+	tiers = []domain.FactBinaryTierStats{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &tiers,
+		`SELECT tier, count(*) AS binaries, COALESCE(sum(size), 0)::bigint AS bytes
+		FROM fact_binary
+		GROUP BY tier
+		ORDER BY tier`,
+	)
+	return
+}
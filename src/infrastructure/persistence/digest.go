@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type digestRepository struct {
+	DB config.PgxIface
+}
+
+func NewDigestRepository(db config.PgxIface) repository.DigestRepository {
+	return digestRepository{mapErrors(db)}
+}
+
+func (a digestRepository) WithQuerier(querier config.PgxIface) repository.DigestRepository {
+	return digestRepository{mapErrors(querier)}
+}
+
+func (a digestRepository) GetAll() (subscriptions []domain.DigestSubscription, err error) {
+	subscriptions = []domain.DigestSubscription{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &subscriptions,
+		`SELECT * FROM digest_subscription ORDER BY created_at`,
+	)
+	return
+}
+
+func (a digestRepository) GetById(id uuid.UUID) (*domain.DigestSubscription, error) {
+	subscription, err := get(
+		a.DB, &domain.DigestSubscription{},
+		`SELECT * FROM digest_subscription WHERE id = $1`,
+		id,
+	)
+	if subscription == nil {
+		return nil, err
+	}
+	return subscription.(*domain.DigestSubscription), err
+}
+
+func (a digestRepository) Save(subscription *domain.DigestSubscription) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO digest_subscription (email, project, period, created_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		subscription.Email, subscription.Project, subscription.Period, subscription.CreatedBy,
+	).Scan(&subscription.ID, &subscription.CreatedAt)
+}
+
+func (a digestRepository) Delete(id uuid.UUID) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM digest_subscription WHERE id = $1`,
+		id,
+	)
+	return
+}
+
+func (a digestRepository) UpdateLastSent(id uuid.UUID, at time.Time) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE digest_subscription SET last_sent_at = $2 WHERE id = $1`,
+		id, at,
+	)
+	return
+}
+
+func (a digestRepository) GetFailedRuns(project *string, from, to time.Time) (runs []domain.FailedRun, err error) {
+	runs = []domain.FailedRun{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT * FROM (
+			SELECT
+				run.*,
+				action.name AS action_name,
+				COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source) AS project
+			FROM run
+			JOIN invocation ON invocation.id = run.invocation_id
+			JOIN action ON action.id = invocation.action_id
+			WHERE run.status = 'failed' AND run.finished_at >= $1 AND run.finished_at < $2
+		) AS run
+		WHERE $3::text IS NULL OR project = $3
+		ORDER BY created_at DESC`,
+		from, to, project,
+	)
+	return
+}
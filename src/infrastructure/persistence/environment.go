@@ -0,0 +1,110 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type environmentRepository struct {
+	DB config.PgxIface
+}
+
+func NewEnvironmentRepository(db config.PgxIface) repository.EnvironmentRepository {
+	return environmentRepository{mapErrors(db)}
+}
+
+func (a environmentRepository) WithQuerier(querier config.PgxIface) repository.EnvironmentRepository {
+	return environmentRepository{mapErrors(querier)}
+}
+
+func (a environmentRepository) GetById(id uuid.UUID) (*domain.Environment, error) {
+	env, err := get(
+		a.DB, &domain.Environment{},
+		`SELECT * FROM environment WHERE id = $1`,
+		id,
+	)
+	if env == nil {
+		return nil, err
+	}
+	return env.(*domain.Environment), err
+}
+
+func (a environmentRepository) Get(tornDown bool, page *repository.Page) ([]domain.Environment, error) {
+	from := `environment`
+	if !tornDown {
+		from += ` WHERE torn_down_at IS NULL`
+	}
+
+	envs := make([]domain.Environment, page.Limit)
+	return envs, fetchPage(
+		a.DB, page, &envs,
+		`*`, from, `updated_at DESC, id`,
+	)
+}
+
+func (a environmentRepository) GetExpired(at time.Time) (envs []domain.Environment, err error) {
+	envs = []domain.Environment{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &envs,
+		`SELECT * FROM environment WHERE torn_down_at IS NULL AND expires_at <= $1 ORDER BY expires_at`,
+		at,
+	)
+	return
+}
+
+func (a environmentRepository) GetClosable() (envs []domain.Environment, err error) {
+	envs = []domain.Environment{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &envs,
+		`SELECT * FROM environment WHERE torn_down_at IS NULL AND closed_by IS NOT NULL ORDER BY fact_seq`,
+	)
+	return
+}
+
+func (a environmentRepository) Save(env *domain.Environment) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO environment (name, url, action_name, run_id, teardown_action, closed_by, expires_at, fact_seq)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT COALESCE(MAX(seq), 0) FROM fact))
+		ON CONFLICT (action_name, name) WHERE torn_down_at IS NULL DO UPDATE SET
+			url = EXCLUDED.url,
+			run_id = EXCLUDED.run_id,
+			teardown_action = EXCLUDED.teardown_action,
+			closed_by = EXCLUDED.closed_by,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = STATEMENT_TIMESTAMP()
+		RETURNING id, fact_seq, created_at, updated_at`,
+		env.Name, env.URL, env.ActionName, env.RunId, env.TeardownAction, env.ClosedBy, env.ExpiresAt,
+	).Scan(&env.ID, &env.FactSeq, &env.CreatedAt, &env.UpdatedAt)
+}
+
+func (a environmentRepository) UpdateFactSeq(id uuid.UUID, seq int64) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE environment SET fact_seq = $2 WHERE id = $1 AND fact_seq < $2`,
+		id, seq,
+	)
+	return
+}
+
+// Returns `repository.ErrNotFound` if the environment was torn down already.
+func (a environmentRepository) TearDown(env *domain.Environment, reason domain.EnvironmentTeardownReason) error {
+	if err := a.DB.QueryRow(
+		context.Background(),
+		`UPDATE environment SET torn_down_at = STATEMENT_TIMESTAMP(), teardown_reason = $2
+		WHERE id = $1 AND torn_down_at IS NULL
+		RETURNING torn_down_at`,
+		env.ID, string(reason),
+	).Scan(&env.TornDownAt); err != nil {
+		return err
+	}
+	env.TeardownReason = &reason
+	return nil
+}
@@ -0,0 +1,143 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+// An error of the database with the repository error it maps to.
+// Both are matched by `errors.Is()` and `errors.As()`.
+type dbError struct {
+	kind error
+	err  error
+}
+
+func (self *dbError) Error() string {
+	return self.kind.Error() + ": " + self.err.Error()
+}
+
+func (self *dbError) Is(target error) bool {
+	return target == self.kind
+}
+
+func (self *dbError) Unwrap() error {
+	return self.err
+}
+
+// Maps errors of the database to repository errors
+// and returns other errors as they are.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var mapped *dbError
+	if errors.As(err, &mapped) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &dbError{repository.ErrNotFound, err}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505", "23P01": // unique_violation, exclusion_violation
+			return &dbError{repository.ErrConflict, err}
+		case "23503": // foreign_key_violation
+			return &dbError{repository.ErrForeignKey, err}
+		}
+	}
+
+	return err
+}
+
+// Returns a querier whose errors are mapped by `mapError()`.
+// Repositories wrap the querier they are given with this.
+func mapErrors(db config.PgxIface) config.PgxIface {
+	if _, ok := db.(errorMapper); ok {
+		return db
+	}
+	return errorMapper{db}
+}
+
+type errorMapper struct {
+	db config.PgxIface
+}
+
+func (self errorMapper) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := self.db.Query(ctx, sql, args...)
+	if err != nil {
+		return rows, mapError(err)
+	}
+	return errorMappingRows{rows}, nil
+}
+
+func (self errorMapper) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return errorMappingRow{self.db.QueryRow(ctx, sql, args...)}
+}
+
+func (self errorMapper) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := self.db.Exec(ctx, sql, args...)
+	return tag, mapError(err)
+}
+
+func (self errorMapper) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
+	return mapError(self.db.BeginFunc(ctx, f))
+}
+
+func (self errorMapper) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return errorMappingBatchResults{self.db.SendBatch(ctx, batch)}
+}
+
+type errorMappingRows struct {
+	pgx.Rows
+}
+
+func (self errorMappingRows) Err() error {
+	return mapError(self.Rows.Err())
+}
+
+func (self errorMappingRows) Scan(dest ...interface{}) error {
+	return mapError(self.Rows.Scan(dest...))
+}
+
+type errorMappingRow struct {
+	row pgx.Row
+}
+
+func (self errorMappingRow) Scan(dest ...interface{}) error {
+	return mapError(self.row.Scan(dest...))
+}
+
+type errorMappingBatchResults struct {
+	pgx.BatchResults
+}
+
+func (self errorMappingBatchResults) Exec() (pgconn.CommandTag, error) {
+	tag, err := self.BatchResults.Exec()
+	return tag, mapError(err)
+}
+
+func (self errorMappingBatchResults) Query() (pgx.Rows, error) {
+	rows, err := self.BatchResults.Query()
+	if err != nil {
+		return rows, mapError(err)
+	}
+	return errorMappingRows{rows}, nil
+}
+
+func (self errorMappingBatchResults) QueryRow() pgx.Row {
+	return errorMappingRow{self.BatchResults.QueryRow()}
+}
+
+func (self errorMappingBatchResults) Close() error {
+	return mapError(self.BatchResults.Close())
+}
@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+func TestShouldMapForeignKeyViolation(t *testing.T) {
+	t.Parallel()
+	link := domain.FactLink{FactId: uuid.New(), Kind: domain.FactLinkSupersedes, TargetId: uuid.New()}
+
+	// given
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error %q was not expected when opening a stub database connection", err)
+	}
+	defer mock.Close(context.Background())
+	mock.ExpectQuery("INSERT INTO fact_link").
+		WithArgs(link.FactId, link.Kind, link.TargetId).
+		WillReturnError(&pgconn.PgError{Code: "23503"})
+	repo := NewFactLinkRepository(mock)
+
+	// when
+	err = repo.Save(&link)
+
+	// then
+	assert.True(t, errors.Is(err, repository.ErrForeignKey))
+	assert.False(t, errors.Is(err, repository.ErrNotFound))
+	var pgErr *pgconn.PgError
+	assert.True(t, errors.As(err, &pgErr), "the original error must be kept")
+}
+
+func TestShouldMapNoRows(t *testing.T) {
+	t.Parallel()
+
+	// when
+	err := mapError(errors.WithMessage(pgx.ErrNoRows, "Could not get run"))
+
+	// then
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	assert.True(t, pgxscan.NotFound(err), "the original error must be kept")
+	assert.Same(t, err, mapError(err), "must not map twice")
+}
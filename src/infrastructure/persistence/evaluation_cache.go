@@ -0,0 +1,67 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type evaluationCacheRepository struct {
+	DB config.PgxIface
+}
+
+func NewEvaluationCacheRepository(db config.PgxIface) repository.EvaluationCacheRepository {
+	return evaluationCacheRepository{mapErrors(db)}
+}
+
+func (e evaluationCacheRepository) WithQuerier(querier config.PgxIface) repository.EvaluationCacheRepository {
+	return evaluationCacheRepository{mapErrors(querier)}
+}
+
+func (e evaluationCacheRepository) Get(actionId uuid.UUID, inputsHash []byte) (*nomad.Job, bool, error) {
+	var jobJson *string
+	if err := pgxscan.Get(
+		context.Background(), e.DB, &jobJson,
+		`SELECT job::text FROM evaluation_cache WHERE action_id = $1 AND inputs_hash = $2`,
+		actionId, inputsHash,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if jobJson == nil {
+		return nil, true, nil
+	}
+
+	job := &nomad.Job{}
+	if err := json.Unmarshal([]byte(*jobJson), job); err != nil {
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+func (e evaluationCacheRepository) Save(actionId uuid.UUID, inputsHash []byte, job *nomad.Job) error {
+	var jobJson []byte
+	if job != nil {
+		var err error
+		if jobJson, err = json.Marshal(job); err != nil {
+			return err
+		}
+	}
+
+	_, err := e.DB.Exec(
+		context.Background(),
+		`INSERT INTO evaluation_cache (action_id, inputs_hash, job) VALUES ($1, $2, $3)
+		ON CONFLICT (action_id, inputs_hash) DO UPDATE SET job = EXCLUDED.job, created_at = EXCLUDED.created_at`,
+		actionId, inputsHash, jobJson,
+	)
+	return err
+}
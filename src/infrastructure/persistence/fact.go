@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"time"
 
 	"cuelang.org/go/cue"
 	"github.com/direnv/direnv/v2/sri"
@@ -23,17 +25,17 @@ type factRepository struct {
 }
 
 func NewFactRepository(db config.PgxIface) repository.FactRepository {
-	return &factRepository{db}
+	return &factRepository{mapErrors(db)}
 }
 
 func (a *factRepository) WithQuerier(querier config.PgxIface) repository.FactRepository {
-	return &factRepository{querier}
+	return &factRepository{mapErrors(querier)}
 }
 
 func (a *factRepository) GetById(id uuid.UUID) (*domain.Fact, error) {
 	fact, err := get(
 		a.DB, &domain.Fact{},
-		`SELECT id, run_id, value, created_at, binary_hash FROM fact WHERE id = $1`,
+		`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id FROM fact WHERE id = $1`,
 		id,
 	)
 	if fact == nil {
@@ -46,7 +48,7 @@ func (a *factRepository) GetByRunId(id uuid.UUID) (facts []domain.Fact, err erro
 	facts = []domain.Fact{}
 	err = pgxscan.Select(
 		context.Background(), a.DB, &facts,
-		`SELECT id, run_id, value, created_at, binary_hash
+		`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id
 		FROM fact WHERE run_id = $1
 		ORDER BY created_at DESC`,
 		id,
@@ -54,29 +56,45 @@ func (a *factRepository) GetByRunId(id uuid.UUID) (facts []domain.Fact, err erro
 	return
 }
 
-func (a *factRepository) GetBinaryById(tx pgx.Tx, id uuid.UUID) (binary io.ReadSeekCloser, err error) {
-	var oid uint32
-	err = pgxscan.Get(
-		context.Background(), tx, &oid,
-		`SELECT "binary" FROM fact WHERE id = $1`,
-		id,
+func (a *factRepository) GetLatestOutput(actionName string, exceptRunId uuid.UUID) (*domain.Fact, error) {
+	// Output facts are named after the action that published them.
+	fact, err := get(
+		a.DB, &domain.Fact{},
+		`SELECT fact.id, fact.run_id, fact.value, fact.value_sealed, fact.created_at, fact.binary_hash, fact.signature, fact.signed_by, fact.namespace, fact.name, fact.tags, fact.api_token_id
+		FROM fact
+		JOIN run ON run.nomad_job_id = fact.run_id
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE action.name = $1 AND fact.name = $1 AND fact.run_id <> $2
+		ORDER BY fact.created_at DESC
+		LIMIT 1`,
+		actionName, exceptRunId,
 	)
-	if err != nil {
-		return
+	if fact == nil {
+		return nil, err
 	}
-
-	los := tx.LargeObjects()
-	binary, err = los.Open(context.Background(), oid, pgx.LargeObjectModeRead)
-	err = errors.WithMessagef(err, "Failed to open large object with OID %d", oid)
-
-	return
+	return fact.(*domain.Fact), err
 }
 
 func (a *factRepository) GetLatestByCue(value cue.Value) (*domain.Fact, error) {
 	where, args := sqlWhereCue(value, nil, 0)
 	fact, err := get(
 		a.DB, &domain.Fact{},
-		`SELECT id, run_id, value, created_at, binary_hash FROM fact WHERE `+where+` ORDER BY created_at DESC FETCH FIRST ROW ONLY`,
+		`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id FROM fact WHERE `+where+` ORDER BY created_at DESC FETCH FIRST ROW ONLY`,
+		args...,
+	)
+	if fact == nil {
+		return nil, err
+	}
+	return fact.(*domain.Fact), err
+}
+
+func (a *factRepository) GetLatestByCueSignedBy(value cue.Value, signers []string) (*domain.Fact, error) {
+	where, args := sqlWhereCue(value, nil, 0)
+	args = append(args, signers)
+	fact, err := get(
+		a.DB, &domain.Fact{},
+		`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id FROM fact WHERE (`+where+`) AND signed_by = ANY($`+strconv.Itoa(len(args))+`) ORDER BY created_at DESC FETCH FIRST ROW ONLY`,
 		args...,
 	)
 	if fact == nil {
@@ -90,12 +108,66 @@ func (a *factRepository) GetByCue(value cue.Value) (facts []domain.Fact, err err
 	facts = []domain.Fact{}
 	err = pgxscan.Select(
 		context.Background(), a.DB, &facts,
-		`SELECT id, run_id, value, created_at, binary_hash FROM fact WHERE `+where,
+		`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id FROM fact WHERE `+where,
 		args...,
 	)
 	return
 }
 
+func (a *factRepository) GetByLabels(labels domain.FactLabels, page *repository.Page) ([]domain.Fact, error) {
+	where := []string{}
+	args := []interface{}{}
+	if labels.Namespace != "" {
+		args = append(args, labels.Namespace)
+		where = append(where, `namespace = $`+strconv.Itoa(len(args)))
+	}
+	if labels.Name != "" {
+		args = append(args, labels.Name)
+		where = append(where, `name = $`+strconv.Itoa(len(args)))
+	}
+	if len(labels.Tags) > 0 {
+		args = append(args, labels.Tags)
+		where = append(where, `tags @> $`+strconv.Itoa(len(args)))
+	}
+
+	from := `fact`
+	if len(where) > 0 {
+		from += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+
+	facts := make([]domain.Fact, page.Limit)
+	return facts, fetchPage(
+		a.DB, page, &facts,
+		`id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id`,
+		from, `created_at DESC`,
+		args...,
+	)
+}
+
+func (a *factRepository) GetFeed(since int64, limit int) (domain.FactFeed, error) {
+	rows := []struct {
+		domain.Fact
+		Seq int64
+	}{}
+	if err := pgxscan.Select(
+		context.Background(), a.DB, &rows,
+		`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id, seq
+		FROM fact WHERE seq > $1
+		ORDER BY seq
+		LIMIT $2`,
+		since, limit,
+	); err != nil {
+		return domain.FactFeed{}, err
+	}
+
+	feed := domain.FactFeed{Facts: make([]domain.Fact, len(rows)), Cursor: since}
+	for i, row := range rows {
+		feed.Facts[i] = row.Fact
+		feed.Cursor = row.Seq
+	}
+	return feed, nil
+}
+
 func sqlWhereCue(value cue.Value, path []string, argNum int) (clause string, args []interface{}) {
 	appendPath := func() {
 		clause += `value`
@@ -148,6 +220,17 @@ func sqlWhereCue(value cue.Value, path []string, argNum int) (clause string, arg
 		args = append(args, v)
 	}
 
+	// PostgreSQL's regular expressions are not RE2
+	// but they agree on the common syntax.
+	appendTextMatch := func(op string, arg cue.Value) {
+		pattern, _ := arg.String()
+		clause = `jsonb_extract_path_text(`
+		appendPath()
+		argNum += 1
+		clause += `) ` + op + ` $` + strconv.Itoa(argNum)
+		args = append(args, pattern)
+	}
+
 	appendComparision := func(cmp string, arg cue.Value) {
 		clause = `jsonb_extract_path(`
 		appendPath()
@@ -256,6 +339,16 @@ Kind:
 				appendComparision("<>", val)
 				break Kind
 			}
+		case cue.RegexMatchOp:
+			if val := vals[0]; val.IsConcrete() && val.Kind() == cue.StringKind {
+				appendTextMatch("~", val)
+				break Kind
+			}
+		case cue.NotRegexMatchOp:
+			if val := vals[0]; val.IsConcrete() && val.Kind() == cue.StringKind {
+				appendTextMatch("!~", val)
+				break Kind
+			}
 		}
 
 		clause = `jsonb_extract_path(`
@@ -269,9 +362,25 @@ Kind:
 }
 
 func (a *factRepository) Save(fact *domain.Fact, binary io.Reader) error {
+	return a.save(fact, binary, nil)
+}
+
+// Rolls back saving a fact that turned out to be a duplicate.
+var errFactDuplicate = errors.New("Fact is a duplicate")
+
+func (a *factRepository) SaveUnlessDuplicate(fact *domain.Fact, binary io.Reader, since time.Time) (bool, error) {
+	if err := a.save(fact, binary, &since); errors.Is(err, errFactDuplicate) {
+		return true, nil
+	} else {
+		return false, err
+	}
+}
+
+// Looks for duplicates saved after dedupSince if not nil.
+func (a *factRepository) save(fact *domain.Fact, binary io.Reader, dedupSince *time.Time) error {
 	ctx := context.Background()
 	return a.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
-		var binaryOid *uint32
+		var binarySize *int64
 		if binary != nil {
 			los := tx.LargeObjects()
 			if oid, err := los.Create(ctx, 0); err != nil {
@@ -295,15 +404,77 @@ func (a *factRepository) Save(fact *domain.Fact, binary io.Reader) error {
 						return fmt.Errorf("Binary has hash %q instead of expected %q", sum, *fact.BinaryHash)
 					}
 					fact.BinaryHash = &sum
-					binaryOid = &oid
+					binarySize = &written
+
+					// Binaries are stored once per content so this copy
+					// is dropped again if another fact has the same one.
+					if tag, err := tx.Exec(
+						ctx,
+						`INSERT INTO fact_binary (hash, "binary", size) VALUES ($1, $2, $3) ON CONFLICT (hash) DO NOTHING`,
+						sum, oid, written,
+					); err != nil {
+						return errors.WithMessagef(err, "Failed to save binary with hash %q", sum)
+					} else if tag.RowsAffected() == 0 {
+						if err := los.Unlink(ctx, oid); err != nil {
+							return errors.WithMessagef(err, "Failed to unlink large object with OID %d", oid)
+						}
+					}
 				}
 			}
 		}
 
+		// The column is not nullable.
+		tags := fact.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+
+		// A new ID is generated unless one is given.
+		var id *uuid.UUID
+		if fact.ID != uuid.Nil {
+			id = &fact.ID
+		}
+
+		var dedupHash *string
+		if dedupSince != nil {
+			hash, err := fact.DedupHash()
+			if err != nil {
+				return err
+			}
+			dedupHash = &hash
+
+			// Concurrent deliveries of the same fact must not both be saved.
+			if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('fact_dedup:' || $1))`, hash); err != nil {
+				return errors.WithMessage(err, "Failed to lock fact deduplication hash")
+			}
+
+			if duplicate, err := get(
+				tx, &domain.Fact{},
+				`SELECT id, run_id, value, value_sealed, created_at, binary_hash, signature, signed_by, namespace, name, tags, api_token_id
+				FROM fact
+				WHERE dedup_hash = $1 AND created_at > $2
+				ORDER BY created_at DESC
+				LIMIT 1`,
+				hash, *dedupSince,
+			); err != nil {
+				return errors.WithMessage(err, "Failed to look for duplicate fact")
+			} else if duplicate != nil {
+				*fact = *duplicate.(*domain.Fact)
+				// Also drops the binary if it was saved.
+				return errFactDuplicate
+			}
+		}
+
+		// Facts must become visible in the order of their seq
+		// or the feed could skip those committed late.
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock('fact_seq'::regclass::oid::bigint)`); err != nil {
+			return errors.WithMessage(err, "Failed to lock fact sequence")
+		}
+
 		return pgxscan.Get(
 			ctx, tx, fact,
-			`INSERT INTO fact (run_id, value, binary_hash, "binary") VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
-			fact.RunId, fact.Value, fact.BinaryHash, binaryOid,
+			`INSERT INTO fact (id, run_id, value, value_sealed, binary_hash, binary_size, signature, signed_by, namespace, name, tags, api_token_id, dedup_hash) VALUES (COALESCE($1, public.gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id, created_at`,
+			id, fact.RunId, fact.Value, fact.ValueSealed, fact.BinaryHash, binarySize, fact.Signature, fact.SignedBy, fact.Namespace, fact.Name, tags, fact.ApiTokenId, dedupHash,
 		)
 	})
 }
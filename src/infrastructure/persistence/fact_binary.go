@@ -0,0 +1,126 @@
+package persistence
+
+import (
+	"context"
+	"io"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type factBinaryRepository struct {
+	DB config.PgxIface
+}
+
+func NewFactBinaryRepository(db config.PgxIface) repository.FactBinaryRepository {
+	return factBinaryRepository{mapErrors(db)}
+}
+
+func (a factBinaryRepository) WithQuerier(querier config.PgxIface) repository.FactBinaryRepository {
+	return factBinaryRepository{mapErrors(querier)}
+}
+
+func (a factBinaryRepository) GetTierByFactId(id uuid.UUID) (hash string, tier domain.FactBinaryTier, err error) {
+	err = a.DB.QueryRow(
+		context.Background(),
+		`SELECT fact_binary.hash, fact_binary.tier FROM fact JOIN fact_binary ON fact_binary.hash = fact.binary_hash WHERE fact.id = $1`,
+		id,
+	).Scan(&hash, &tier)
+	return
+}
+
+func (a factBinaryRepository) GetAccesses() (binaries []domain.FactBinaryAccess, err error) {
+	binaries = []domain.FactBinaryAccess{}
+	// Reads of facts are recorded per identity so only the latest counts.
+	err = pgxscan.Select(
+		context.Background(), a.DB, &binaries,
+		`SELECT
+			fact_binary.hash,
+			fact_binary.tier,
+			COALESCE(fact_binary.size, 0) AS size,
+			max(GREATEST(fact.created_at, fact_access.last_read_at)) AS last_accessed_at,
+			array_agg(DISTINCT COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source, '')) AS projects
+		FROM fact_binary
+		JOIN fact ON fact.binary_hash = fact_binary.hash
+		LEFT JOIN (
+			SELECT fact_id, max(last_read_at) AS last_read_at
+			FROM fact_access
+			GROUP BY fact_id
+		) AS fact_access ON fact_access.fact_id = fact.id
+		LEFT JOIN run ON run.nomad_job_id = fact.run_id
+		LEFT JOIN invocation ON invocation.id = run.invocation_id
+		LEFT JOIN action ON action.id = invocation.action_id
+		GROUP BY fact_binary.hash
+		ORDER BY last_accessed_at`,
+	)
+	return
+}
+
+func (a factBinaryRepository) Open(tx pgx.Tx, hash string) (binary io.ReadSeekCloser, err error) {
+	var oid uint32
+	err = pgxscan.Get(
+		context.Background(), tx, &oid,
+		`SELECT "binary" FROM fact_binary WHERE hash = $1 AND "binary" IS NOT NULL`,
+		hash,
+	)
+	if err != nil {
+		err = mapError(err)
+		return
+	}
+
+	los := tx.LargeObjects()
+	binary, err = los.Open(context.Background(), oid, pgx.LargeObjectModeRead)
+	err = errors.WithMessagef(err, "Failed to open large object with OID %d", oid)
+
+	return
+}
+
+func (a factBinaryRepository) Move(tx pgx.Tx, hash string, from, to domain.FactBinaryTier, write func(io.Writer) error) (bool, error) {
+	ctx := context.Background()
+
+	var oid *uint32
+	if to != domain.FactBinaryTierCold {
+		los := tx.LargeObjects()
+		if created, err := los.Create(ctx, 0); err != nil {
+			return false, errors.WithMessage(err, "Failed to create large object")
+		} else if lo, err := los.Open(ctx, created, pgx.LargeObjectModeWrite); err != nil {
+			return false, errors.WithMessagef(err, "Failed to open large object with OID %d", created)
+		} else if err := write(lo); err != nil {
+			return false, errors.WithMessagef(err, "Failed to write to large object with OID %d", created)
+		} else {
+			oid = &created
+		}
+	}
+
+	// The previous large object is unlinked by the trigger.
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE fact_binary SET "binary" = $3, tier = $4 WHERE hash = $1 AND tier = $2`,
+		hash, from, oid, to,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 && oid != nil {
+		los := tx.LargeObjects()
+		if err := los.Unlink(ctx, *oid); err != nil {
+			return false, errors.WithMessagef(err, "Failed to unlink large object with OID %d", *oid)
+		}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (a factBinaryRepository) GetColdHashes() (hashes []string, err error) {
+	hashes = []string{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &hashes,
+		`SELECT hash FROM fact_binary WHERE tier = 'cold'`,
+	)
+	return
+}
@@ -0,0 +1,62 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type factLinkRepository struct {
+	DB config.PgxIface
+}
+
+func NewFactLinkRepository(db config.PgxIface) repository.FactLinkRepository {
+	return factLinkRepository{mapErrors(db)}
+}
+
+func (a factLinkRepository) WithQuerier(querier config.PgxIface) repository.FactLinkRepository {
+	return factLinkRepository{mapErrors(querier)}
+}
+
+func (a factLinkRepository) GetByFactId(id uuid.UUID) (links domain.FactLinks, err error) {
+	links.Outgoing = []domain.FactLink{}
+	if err = pgxscan.Select(
+		context.Background(), a.DB, &links.Outgoing,
+		`SELECT * FROM fact_link WHERE fact_id = $1 ORDER BY created_at`,
+		id,
+	); err != nil {
+		return
+	}
+
+	links.Incoming = []domain.FactLink{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &links.Incoming,
+		`SELECT * FROM fact_link WHERE target_id = $1 ORDER BY created_at`,
+		id,
+	)
+	return
+}
+
+func (a factLinkRepository) Save(link *domain.FactLink) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO fact_link (fact_id, kind, target_id) VALUES ($1, $2, $3)
+		ON CONFLICT (fact_id, kind, target_id) DO UPDATE SET created_at = fact_link.created_at
+		RETURNING created_at`,
+		link.FactId, link.Kind, link.TargetId,
+	).Scan(&link.CreatedAt)
+}
+
+func (a factLinkRepository) Delete(link domain.FactLink) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM fact_link WHERE fact_id = $1 AND kind = $2 AND target_id = $3`,
+		link.FactId, link.Kind, link.TargetId,
+	)
+	return
+}
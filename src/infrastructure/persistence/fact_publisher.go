@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type factPublisherRepository struct {
+	DB config.PgxIface
+}
+
+func NewFactPublisherRepository(db config.PgxIface) repository.FactPublisherRepository {
+	return factPublisherRepository{mapErrors(db)}
+}
+
+func (a factPublisherRepository) WithQuerier(querier config.PgxIface) repository.FactPublisherRepository {
+	return factPublisherRepository{mapErrors(querier)}
+}
+
+func (a factPublisherRepository) GetAll() (publishers []domain.FactPublisher, err error) {
+	publishers = []domain.FactPublisher{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &publishers,
+		`SELECT * FROM fact_publisher ORDER BY name`,
+	)
+	return
+}
+
+func (a factPublisherRepository) GetByName(name string) (*domain.FactPublisher, error) {
+	publisher, err := get(
+		a.DB, &domain.FactPublisher{},
+		`SELECT * FROM fact_publisher WHERE name = $1`,
+		name,
+	)
+	if publisher == nil {
+		return nil, err
+	}
+	return publisher.(*domain.FactPublisher), err
+}
+
+func (a factPublisherRepository) GetByKeyId(keyId string) (*domain.FactPublisher, error) {
+	publisher, err := get(
+		a.DB, &domain.FactPublisher{},
+		`SELECT * FROM fact_publisher WHERE key_id = $1`,
+		keyId,
+	)
+	if publisher == nil {
+		return nil, err
+	}
+	return publisher.(*domain.FactPublisher), err
+}
+
+// Replaces the key of a publisher with the same name.
+func (a factPublisherRepository) Save(publisher *domain.FactPublisher) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO fact_publisher (name, public_key, key_id, created_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET public_key = excluded.public_key, key_id = excluded.key_id, created_by = excluded.created_by, created_at = NOW()
+		RETURNING created_at`,
+		publisher.Name, publisher.PublicKey, publisher.KeyId, publisher.CreatedBy,
+	).Scan(&publisher.CreatedAt)
+}
+
+func (a factPublisherRepository) Delete(name string) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM fact_publisher WHERE name = $1`,
+		name,
+	)
+	return
+}
@@ -0,0 +1,60 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldGetFactFeed(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UTC()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	// given
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error %q was not expected when opening a stub database connection", err)
+	}
+	defer mock.Close(context.Background())
+	rows := mock.NewRows([]string{"id", "created_at", "seq"}).
+		AddRow(ids[0], now, int64(4)).
+		AddRow(ids[1], now, int64(7))
+	mock.ExpectQuery("SELECT (.+) FROM fact WHERE seq > (.+) ORDER BY seq").WithArgs(int64(3), 2).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.+) FROM fact WHERE seq > (.+) ORDER BY seq").WithArgs(int64(7), 2).WillReturnRows(mock.NewRows([]string{"id", "created_at", "seq"}))
+	repository := NewFactRepository(mock)
+
+	// when
+	feed, err := repository.GetFeed(3, 2)
+	// then
+	assert.Nil(t, err)
+	assert.Len(t, feed.Facts, 2)
+	assert.Equal(t, ids[1], feed.Facts[1].ID)
+	assert.Equal(t, int64(7), feed.Cursor)
+
+	// when
+	feed, err = repository.GetFeed(feed.Cursor, 2)
+	// then
+	assert.Nil(t, err)
+	assert.Empty(t, feed.Facts)
+	assert.Equal(t, int64(7), feed.Cursor, "cursor must not move without new facts")
+}
+
+func TestShouldMatchFactsByRegex(t *testing.T) {
+	t.Parallel()
+
+	// given
+	value := cuecontext.New().CompileString(`{version: =~"^1\\.", branch: !~"^wip/"}`)
+
+	// when
+	where, args := sqlWhereCue(value, nil, 0)
+
+	// then
+	assert.Equal(t, `jsonb_extract_path_text(value, $1) ~ $2 AND jsonb_extract_path_text(value, $3) !~ $4`, where)
+	assert.Equal(t, []interface{}{"version", `^1\.`, "branch", "^wip/"}, args)
+}
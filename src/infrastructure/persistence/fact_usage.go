@@ -0,0 +1,118 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type factUsageRepository struct {
+	DB config.PgxIface
+}
+
+func NewFactUsageRepository(db config.PgxIface) repository.FactUsageRepository {
+	return factUsageRepository{mapErrors(db)}
+}
+
+func (a factUsageRepository) WithQuerier(querier config.PgxIface) repository.FactUsageRepository {
+	return factUsageRepository{mapErrors(querier)}
+}
+
+func (a factUsageRepository) SaveReads(identity string, factIds []uuid.UUID) (err error) {
+	// Selecting the facts skips those that were deleted in the meantime.
+	_, err = a.DB.Exec(
+		context.Background(),
+		`INSERT INTO fact_access (fact_id, identity)
+		SELECT id, $1 FROM fact WHERE id = ANY($2)
+		ON CONFLICT (fact_id, identity) DO UPDATE SET
+			reads = fact_access.reads + 1,
+			last_read_at = STATEMENT_TIMESTAMP()`,
+		identity, factIds,
+	)
+	return
+}
+
+func (a factUsageRepository) GetById(id uuid.UUID) (usage domain.FactUsage, err error) {
+	usage.FactId = id
+
+	usage.Readers = []domain.FactReader{}
+	if err = pgxscan.Select(
+		context.Background(), a.DB, &usage.Readers,
+		`SELECT identity, reads, first_read_at, last_read_at
+		FROM fact_access
+		WHERE fact_id = $1
+		ORDER BY reads DESC, identity`,
+		id,
+	); err != nil {
+		return
+	}
+	for _, reader := range usage.Readers {
+		usage.Reads += reader.Reads
+	}
+
+	err = pgxscan.Get(
+		context.Background(), a.DB, &usage,
+		`SELECT
+			count(DISTINCT invocation.id) AS invocations,
+			MAX(invocation.created_at) AS last_invoked_at
+		FROM invocation_inputs
+		JOIN invocation ON invocation.id = invocation_inputs.invocation_id
+		WHERE invocation_inputs.fact_id = $1`,
+		id,
+	)
+	return
+}
+
+func (a factUsageRepository) GetFields(since time.Time) (fields []domain.FactFieldUsage, err error) {
+	fields = []domain.FactFieldUsage{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &fields,
+		`WITH fields AS (
+			SELECT fact.id, fact.created_at, jsonb_object_keys(fact.value) AS field
+			FROM fact
+			WHERE jsonb_typeof(fact.value) = 'object' AND fact.created_at >= $1
+		), reads AS (
+			SELECT
+				fields.field,
+				sum(fact_access.reads)::bigint AS reads,
+				count(DISTINCT fact_access.identity) AS readers,
+				MAX(fact_access.last_read_at) AS last_read_at
+			FROM fields
+			JOIN fact_access ON fact_access.fact_id = fields.id
+			GROUP BY fields.field
+		), invocations AS (
+			SELECT
+				fields.field,
+				count(DISTINCT fields.id) AS invoking_facts,
+				count(DISTINCT invocation_inputs.invocation_id) AS invocations
+			FROM fields
+			JOIN invocation_inputs ON invocation_inputs.fact_id = fields.id
+			GROUP BY fields.field
+		), facts AS (
+			SELECT field, count(*) AS facts, MAX(created_at) AS last_created_at
+			FROM fields
+			GROUP BY field
+		)
+		SELECT
+			facts.field,
+			facts.facts,
+			facts.last_created_at,
+			COALESCE(reads.reads, 0) AS reads,
+			COALESCE(reads.readers, 0) AS readers,
+			reads.last_read_at,
+			COALESCE(invocations.invoking_facts, 0) AS invoking_facts,
+			COALESCE(invocations.invocations, 0) AS invocations
+		FROM facts
+		LEFT JOIN reads ON reads.field = facts.field
+		LEFT JOIN invocations ON invocations.field = facts.field
+		ORDER BY facts.facts DESC, facts.field`,
+		since,
+	)
+	return
+}
@@ -17,11 +17,11 @@ type invocationRepository struct {
 }
 
 func NewInvocationRepository(db config.PgxIface) repository.InvocationRepository {
-	return &invocationRepository{db}
+	return &invocationRepository{mapErrors(db)}
 }
 
 func (self *invocationRepository) WithQuerier(querier config.PgxIface) repository.InvocationRepository {
-	return &invocationRepository{querier}
+	return &invocationRepository{mapErrors(querier)}
 }
 
 func (self *invocationRepository) GetById(id uuid.UUID) (*domain.Invocation, error) {
@@ -167,8 +167,8 @@ func (self *invocationRepository) Save(invocation *domain.Invocation, inputs map
 	if err := self.db.BeginFunc(ctx, func(tx pgx.Tx) error {
 		if err := tx.QueryRow(
 			ctx,
-			`INSERT INTO invocation (action_id) VALUES ($1) RETURNING id, created_at`,
-			invocation.ActionId,
+			`INSERT INTO invocation (action_id, chained_from, input_defaults) VALUES ($1, $2, $3) RETURNING id, created_at`,
+			invocation.ActionId, invocation.ChainedFrom, invocation.InputDefaults,
 		).Scan(&invocation.Id, &invocation.CreatedAt); err != nil {
 			return err
 		}
@@ -3,6 +3,7 @@ package persistence
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/georgysavva/scany/pgxscan"
 	"github.com/google/uuid"
@@ -18,24 +19,24 @@ type nomadEventRepository struct {
 }
 
 func NewNomadEventRepository(db config.PgxIface) repository.NomadEventRepository {
-	return nomadEventRepository{db}
+	return nomadEventRepository{mapErrors(db)}
 }
 
 func (n nomadEventRepository) WithQuerier(querier config.PgxIface) repository.NomadEventRepository {
-	return nomadEventRepository{querier}
+	return nomadEventRepository{mapErrors(querier)}
 }
 
 func (n nomadEventRepository) Save(event *domain.NomadEvent) error {
 	return n.DB.QueryRow(
 		context.Background(),
-		`INSERT INTO nomad_event (topic, "type", "key", filter_keys, "index", payload)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		`INSERT INTO nomad_event (topic, "type", "key", filter_keys, "index", payload, nomad_cluster)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (uid) DO UPDATE
 			-- just for RETURNING to work, would otherwise DO NOTHING
 			SET topic = EXCLUDED.topic
-		RETURNING uid, handled`,
-		event.Topic, event.Type, event.Key, event.FilterKeys, event.Index, event.Payload,
-	).Scan(&event.Uid, &event.Handled)
+		RETURNING uid, handled, id, created_at`,
+		event.Topic, event.Type, event.Key, event.FilterKeys, event.Index, event.Payload, event.NomadCluster,
+	).Scan(&event.Uid, &event.Handled, &event.ID, &event.CreatedAt)
 }
 
 func (n nomadEventRepository) Update(event *domain.NomadEvent) (err error) {
@@ -47,21 +48,46 @@ func (n nomadEventRepository) Update(event *domain.NomadEvent) (err error) {
 	return
 }
 
-func (n nomadEventRepository) GetByHandled(handled bool) (events []domain.NomadEvent, err error) {
+func (n nomadEventRepository) GetByHandled(handled bool, clusters []string) (events []domain.NomadEvent, err error) {
 	events = []domain.NomadEvent{}
 	err = pgxscan.Select(
 		context.Background(),
 		n.DB, &events,
-		`SELECT * FROM nomad_event WHERE handled = $1`,
-		handled,
+		`SELECT * FROM nomad_event WHERE handled = $1 AND nomad_cluster = ANY($2)`,
+		handled, clusters,
 	)
 	return
 }
 
-func (n nomadEventRepository) GetLastNomadEventIndex() (index uint64, err error) {
+func (n nomadEventRepository) GetLastNomadEventIndex(clusters []string) (index uint64, err error) {
 	err = pgxscan.Get(
 		context.Background(), n.DB, &index,
-		`SELECT COALESCE(MAX("index"), 0) FROM nomad_event`,
+		`SELECT COALESCE(MAX("index"), 0) FROM nomad_event WHERE nomad_cluster = ANY($1)`,
+		clusters,
+	)
+	return
+}
+
+func (n nomadEventRepository) GetLastNomadEventIndexByTopic(topic nomad.Topic, clusters []string) (index uint64, err error) {
+	err = pgxscan.Get(
+		context.Background(), n.DB, &index,
+		`SELECT COALESCE(MAX("index"), 0) FROM nomad_event WHERE topic = $1 AND nomad_cluster = ANY($2)`,
+		topic, clusters,
+	)
+	return
+}
+
+func (n nomadEventRepository) GetByJobId(id uuid.UUID) (events []domain.NomadEvent, err error) {
+	events = []domain.NomadEvent{}
+	err = pgxscan.Select(
+		context.Background(),
+		n.DB, &events,
+		`SELECT * FROM nomad_event
+		WHERE (topic = 'Allocation' AND payload#>>'{Allocation,JobID}' = $1)
+			OR (topic = 'Evaluation' AND payload#>>'{Evaluation,JobID}' = $1)
+			OR (topic = 'Job' AND payload#>>'{Job,ID}' = $1)
+		ORDER BY "index" ASC`,
+		id,
 	)
 	return
 }
@@ -109,3 +135,69 @@ func (n nomadEventRepository) GetLatestEventAllocationByJobId(id uuid.UUID) ([]n
 		)
 	`)
 }
+
+func (n nomadEventRepository) GetLatestEventEvaluationByJobId(id uuid.UUID) (*nomad.Evaluation, error) {
+	var evals []string
+	if err := pgxscan.Select(context.Background(), n.DB, &evals, `
+		SELECT payload->>'Evaluation'
+		FROM nomad_event
+		WHERE payload#>>'{Evaluation,JobID}' = $1
+			AND topic = 'Evaluation'
+		ORDER BY "index" DESC
+		LIMIT 1
+	`, id); err != nil {
+		return nil, err
+	} else if len(evals) == 0 {
+		return nil, nil
+	}
+
+	eval := nomad.Evaluation{}
+	if err := json.Unmarshal([]byte(evals[0]), &eval); err != nil {
+		return nil, err
+	}
+	return &eval, nil
+}
+
+func (n nomadEventRepository) GetAfterId(id int64, before time.Time, limit int) (events []domain.NomadEvent, err error) {
+	events = []domain.NomadEvent{}
+	err = pgxscan.Select(
+		context.Background(),
+		n.DB, &events,
+		`SELECT * FROM nomad_event WHERE id > $1 AND created_at < $2 ORDER BY id LIMIT $3`,
+		id, before, limit,
+	)
+	return
+}
+
+func (n nomadEventRepository) GetLastId() (id int64, err error) {
+	err = pgxscan.Get(
+		context.Background(), n.DB, &id,
+		`SELECT COALESCE(MAX(id), 0) FROM nomad_event`,
+	)
+	return
+}
+
+func (n nomadEventRepository) GetCheckpoint(handler string) (*int64, error) {
+	var id int64
+	if err := pgxscan.Get(
+		context.Background(), n.DB, &id,
+		`SELECT event_id FROM nomad_event_checkpoint WHERE handler = $1`,
+		handler,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (n nomadEventRepository) SaveCheckpoint(handler string, id int64) (err error) {
+	_, err = n.DB.Exec(
+		context.Background(),
+		`INSERT INTO nomad_event_checkpoint (handler, event_id) VALUES ($1, $2)
+		ON CONFLICT (handler) DO UPDATE SET event_id = EXCLUDED.event_id, updated_at = STATEMENT_TIMESTAMP()`,
+		handler, id,
+	)
+	return
+}
@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type quotaRepository struct {
+	DB config.PgxIface
+}
+
+func NewQuotaRepository(db config.PgxIface) repository.QuotaRepository {
+	return quotaRepository{mapErrors(db)}
+}
+
+func (a quotaRepository) WithQuerier(querier config.PgxIface) repository.QuotaRepository {
+	return quotaRepository{mapErrors(querier)}
+}
+
+func (a quotaRepository) GetAll() (quotas []domain.Quota, err error) {
+	quotas = []domain.Quota{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &quotas,
+		`SELECT * FROM quota ORDER BY subject, name`,
+	)
+	return
+}
+
+func (a quotaRepository) Get(subject domain.QuotaSubject, name string) (*domain.Quota, error) {
+	quota, err := get(
+		a.DB, &domain.Quota{},
+		`SELECT * FROM quota WHERE subject = $1 AND name = $2`,
+		subject, name,
+	)
+	if quota == nil {
+		return nil, err
+	}
+	return quota.(*domain.Quota), err
+}
+
+func (a quotaRepository) Save(quota *domain.Quota) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO quota (subject, name, max_runs_per_hour, max_concurrent_runs, max_fact_bytes, override_until, override_reason, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (subject, name) DO UPDATE SET
+			max_runs_per_hour = EXCLUDED.max_runs_per_hour,
+			max_concurrent_runs = EXCLUDED.max_concurrent_runs,
+			max_fact_bytes = EXCLUDED.max_fact_bytes,
+			override_until = EXCLUDED.override_until,
+			override_reason = EXCLUDED.override_reason,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		quota.Subject, quota.Name, quota.MaxRunsPerHour, quota.MaxConcurrentRuns, quota.MaxFactBytes, quota.OverrideUntil, quota.OverrideReason, quota.UpdatedBy,
+	).Scan(&quota.UpdatedAt)
+}
+
+func (a quotaRepository) Delete(subject domain.QuotaSubject, name string) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM quota WHERE subject = $1 AND name = $2`,
+		subject, name,
+	)
+	return
+}
+
+func (a quotaRepository) GetUsage(subject domain.QuotaSubject, name string, since time.Time) (usage domain.QuotaUsage, err error) {
+	// The Runs and facts a subject is charged for.
+	var runs, facts string
+	switch subject {
+	case domain.QuotaSubjectProject:
+		runs = `SELECT run.* FROM run
+			JOIN invocation ON invocation.id = run.invocation_id
+			JOIN action ON action.id = invocation.action_id
+			WHERE COALESCE(action.meta->>'` + domain.ActionMetaProject + `', action.source) = $1`
+		facts = `SELECT fact.* FROM fact
+			JOIN run ON run.nomad_job_id = fact.run_id
+			JOIN invocation ON invocation.id = run.invocation_id
+			JOIN action ON action.id = invocation.action_id
+			WHERE COALESCE(action.meta->>'` + domain.ActionMetaProject + `', action.source) = $1`
+	case domain.QuotaSubjectToken:
+		runs = `SELECT run.* FROM run
+			WHERE EXISTS (
+				SELECT FROM invocation_inputs
+				JOIN fact ON fact.id = invocation_inputs.fact_id
+				JOIN api_token ON api_token.id = fact.api_token_id
+				WHERE invocation_inputs.invocation_id = run.invocation_id AND api_token.name = $1
+			)`
+		facts = `SELECT fact.* FROM fact
+			JOIN api_token ON api_token.id = fact.api_token_id
+			WHERE api_token.name = $1`
+	default:
+		return usage, errors.Errorf("Cannot get usage of quota subject %q", subject)
+	}
+
+	// Denied Runs never ran so they are not counted.
+	err = pgxscan.Get(
+		context.Background(), a.DB, &usage,
+		`SELECT
+			(SELECT count(*) FROM (`+runs+`) AS run WHERE created_at >= $2 AND admission_denials = '{}') AS runs_last_hour,
+			(SELECT count(*) FROM (`+runs+`) AS run WHERE status = 'running') AS concurrent_runs,
+			(SELECT COALESCE(sum(octet_length(value::text) + COALESCE(binary_size, 0)), 0) FROM (`+facts+`) AS fact) AS fact_bytes`,
+		name, since,
+	)
+	return
+}
+
+func (a quotaRepository) GetProjectByRunId(id uuid.UUID) (*string, error) {
+	project, err := get(
+		a.DB, new(string),
+		`SELECT COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source)
+		FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE run.nomad_job_id = $1`,
+		id,
+	)
+	if project == nil {
+		return nil, err
+	}
+	return project.(*string), err
+}
+
+func (a quotaRepository) GetTokenNamesByFactIds(ids []uuid.UUID) (names []string, err error) {
+	names = []string{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &names,
+		`SELECT DISTINCT api_token.name FROM fact
+		JOIN api_token ON api_token.id = fact.api_token_id
+		WHERE fact.id = ANY($1)
+		ORDER BY 1`,
+		ids,
+	)
+	return
+}
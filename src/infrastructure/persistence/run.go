@@ -2,8 +2,17 @@ package persistence
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/georgysavva/scany/pgxscan"
 	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
 
 	"github.com/input-output-hk/cicero/src/config"
 	"github.com/input-output-hk/cicero/src/domain"
@@ -15,11 +24,11 @@ type runRepository struct {
 }
 
 func NewRunRepository(db config.PgxIface) repository.RunRepository {
-	return &runRepository{db}
+	return &runRepository{mapErrors(db)}
 }
 
 func (a runRepository) WithQuerier(querier config.PgxIface) repository.RunRepository {
-	return &runRepository{querier}
+	return &runRepository{mapErrors(querier)}
 }
 
 func (a runRepository) GetByNomadJobId(id uuid.UUID) (*domain.Run, error) {
@@ -78,6 +87,46 @@ func (a runRepository) GetLatestByActionId(id uuid.UUID) (*domain.Run, error) {
 	return run.(*domain.Run), err
 }
 
+func (a runRepository) GetLatestByActionName(name string) (*domain.Run, error) {
+	run, err := get(
+		a.DB, &domain.Run{},
+		`SELECT run.*
+		FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id AND action.name = $1
+		ORDER BY run.created_at DESC
+		FETCH FIRST ROW ONLY`,
+		name,
+	)
+	if run == nil {
+		return nil, err
+	}
+	return run.(*domain.Run), err
+}
+
+func (a runRepository) GetPreviousSucceeded(of domain.Run) (*domain.Run, error) {
+	run, err := get(
+		a.DB, &domain.Run{},
+		`SELECT run.*
+		FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE action.name = (
+			SELECT action.name
+			FROM invocation
+			JOIN action ON action.id = invocation.action_id
+			WHERE invocation.id = $1
+		) AND run.status = 'succeeded' AND run.created_at < $2
+		ORDER BY run.created_at DESC
+		FETCH FIRST ROW ONLY`,
+		of.InvocationId, of.CreatedAt,
+	)
+	if run == nil {
+		return nil, err
+	}
+	return run.(*domain.Run), err
+}
+
 func (a runRepository) GetAll(page *repository.Page) ([]domain.Run, error) {
 	runs := make([]domain.Run, page.Limit)
 	return runs, fetchPage(
@@ -86,11 +135,46 @@ func (a runRepository) GetAll(page *repository.Page) ([]domain.Run, error) {
 	)
 }
 
+func (a runRepository) GetByFilter(filter domain.RunFilter, page *repository.Page) ([]domain.Run, error) {
+	where := []string{}
+	args := []interface{}{}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = status.String()
+		}
+		args = append(args, statuses)
+		where = append(where, `run.status = ANY($`+strconv.Itoa(len(args))+`::run_status[])`)
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		where = append(where, `action.name = $`+strconv.Itoa(len(args)))
+	}
+	if filter.Project != "" {
+		args = append(args, filter.Project)
+		where = append(where, `COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source) = $`+strconv.Itoa(len(args)))
+	}
+
+	from := `run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id`
+	if len(where) > 0 {
+		from += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+
+	runs := make([]domain.Run, page.Limit)
+	return runs, fetchPage(
+		a.DB, page, &runs,
+		`run.*`, from, `run.created_at DESC`,
+		args...,
+	)
+}
+
 func (a runRepository) Save(run *domain.Run) error {
 	return a.DB.QueryRow(
 		context.Background(),
-		`INSERT INTO run (invocation_id, status) VALUES ($1, $2) RETURNING nomad_job_id, created_at`,
-		run.InvocationId, run.Status.String(),
+		`INSERT INTO run (invocation_id, status, nomad_cluster) VALUES ($1, $2, $3) RETURNING nomad_job_id, created_at`,
+		run.InvocationId, run.Status.String(), run.NomadCluster,
 	).Scan(&run.NomadJobID, &run.CreatedAt)
 }
 
@@ -102,3 +186,291 @@ func (a runRepository) Update(run *domain.Run) (err error) {
 	)
 	return
 }
+
+func (a runRepository) UpdateNomadCluster(run *domain.Run) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET nomad_cluster = $2 WHERE nomad_job_id = $1`,
+		run.NomadJobID, run.NomadCluster,
+	)
+	return
+}
+
+func (a runRepository) UpdateOutputUnchanged(run *domain.Run) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET output_unchanged_fact_id = $2 WHERE nomad_job_id = $1`,
+		run.NomadJobID, run.OutputUnchangedFactId,
+	)
+	return
+}
+
+func (a runRepository) UpdateDeployment(run *domain.Run) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET deployment_status = $2, deployment_status_description = $3 WHERE nomad_job_id = $1`,
+		run.NomadJobID, run.DeploymentStatus, run.DeploymentStatusDescription,
+	)
+	return
+}
+
+func (a runRepository) UpdateAdmissionDenials(run *domain.Run) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET admission_denials = $2 WHERE nomad_job_id = $1`,
+		run.NomadJobID, run.AdmissionDenials,
+	)
+	return
+}
+
+func (a runRepository) UpdateBlocked(run *domain.Run) (err error) {
+	reasons := run.BlockedReasons
+	if reasons == nil {
+		reasons = []string{}
+	}
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET blocked_since = $2, blocked_reasons = $3 WHERE nomad_job_id = $1`,
+		run.NomadJobID, run.BlockedSince, reasons,
+	)
+	return
+}
+
+func (a runRepository) GetChainedFrom(id uuid.UUID) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT run.* FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		WHERE invocation.chained_from = $1
+		ORDER BY run.created_at ASC`,
+		id,
+	)
+	return
+}
+
+func (a runRepository) GetDownstream(id uuid.UUID) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	// Finished Runs are followed as well because Runs they invoked may still be going.
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`WITH RECURSIVE downstream AS (
+			SELECT $1::uuid AS nomad_job_id
+			UNION
+			SELECT run.nomad_job_id
+			FROM downstream
+			LEFT JOIN fact ON fact.run_id = downstream.nomad_job_id
+			LEFT JOIN invocation_inputs ON invocation_inputs.fact_id = fact.id
+			JOIN invocation ON
+				invocation.id = invocation_inputs.invocation_id OR
+				invocation.chained_from = downstream.nomad_job_id
+			JOIN run ON run.invocation_id = invocation.id
+		)
+		SELECT run.* FROM run
+		JOIN downstream ON downstream.nomad_job_id = run.nomad_job_id
+		WHERE run.nomad_job_id <> $1 AND run.finished_at IS NULL
+		ORDER BY run.created_at ASC`,
+		id,
+	)
+	return
+}
+
+func (a runRepository) GetRunning() (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT * FROM run
+		WHERE finished_at IS NULL AND status = 'running' AND NOT EXISTS (
+			SELECT FROM run_mutex
+			WHERE run_id = run.nomad_job_id AND acquired_at IS NULL
+		) AND NOT EXISTS (
+			SELECT FROM run_approval
+			WHERE run_id = run.nomad_job_id AND decided_at IS NULL
+		)
+		ORDER BY created_at ASC`,
+	)
+	return
+}
+
+func (a runRepository) UpdateSuspect(run *domain.Run) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET suspect_since = $2 WHERE nomad_job_id = $1`,
+		run.NomadJobID, run.SuspectSince,
+	)
+	return
+}
+
+func (a runRepository) Reconcile(run *domain.Run) (bool, error) {
+	tag, err := a.DB.Exec(
+		context.Background(),
+		`UPDATE run SET finished_at = $2, status = $3, reconcile_note = $4 WHERE nomad_job_id = $1 AND finished_at IS NULL`,
+		run.NomadJobID, run.FinishedAt, run.Status.String(), run.ReconcileNote,
+	)
+	return tag.RowsAffected() > 0, err
+}
+
+func (a runRepository) GetFinishedWithoutNomadJobGC(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT * FROM run
+		WHERE finished_at < $1 AND nomad_job_gced_at IS NULL
+		ORDER BY finished_at ASC
+		LIMIT $2`,
+		finishedBefore, limit,
+	)
+	return
+}
+
+func (a runRepository) MarkNomadJobGCed(run *domain.Run) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`UPDATE run SET nomad_job_gced_at = STATEMENT_TIMESTAMP() WHERE nomad_job_id = $1 RETURNING nomad_job_gced_at`,
+		run.NomadJobID,
+	).Scan(&run.NomadJobGCedAt)
+}
+
+func (a runRepository) GetFinishedWithoutLogArchive(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT run.* FROM run
+		WHERE finished_at < $1 AND NOT EXISTS (
+			SELECT FROM run_log WHERE run_log.run_id = run.nomad_job_id
+		)
+		ORDER BY finished_at ASC
+		LIMIT $2`,
+		finishedBefore, limit,
+	)
+	return
+}
+
+func (a runRepository) SaveLogArchive(id uuid.UUID, log io.Reader, lines int) error {
+	ctx := context.Background()
+	return a.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		los := tx.LargeObjects()
+		oid, err := los.Create(ctx, 0)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to create large object")
+		}
+		lo, err := los.Open(ctx, oid, pgx.LargeObjectModeWrite)
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to open large object with OID %d", oid)
+		}
+		if _, err := io.Copy(lo, log); err != nil {
+			return errors.WithMessagef(err, "Failed to write to large object with OID %d", oid)
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO run_log (run_id, log, lines) VALUES ($1, $2, $3)`,
+			id, oid, lines,
+		)
+		return err
+	})
+}
+
+func (a runRepository) GetLogArchive(tx pgx.Tx, id uuid.UUID) (io.ReadSeekCloser, error) {
+	var oid uint32
+	if err := pgxscan.Get(
+		context.Background(), tx, &oid,
+		`SELECT log FROM run_log WHERE run_id = $1`,
+		id,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	los := tx.LargeObjects()
+	log, err := los.Open(context.Background(), oid, pgx.LargeObjectModeRead)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to open large object with OID %d", oid)
+	}
+	return log, nil
+}
+
+func (a runRepository) GetAllocations(id uuid.UUID) ([]nomad.Allocation, error) {
+	var rows []string
+	if err := pgxscan.Select(
+		context.Background(), a.DB, &rows,
+		`SELECT allocation::text FROM run_allocation
+		WHERE run_id = $1
+		ORDER BY (allocation->>'CreateTime')::bigint ASC`,
+		id,
+	); err != nil {
+		return nil, err
+	}
+
+	allocs := make([]nomad.Allocation, len(rows))
+	for i, row := range rows {
+		if err := json.Unmarshal([]byte(row), &allocs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return allocs, nil
+}
+
+func (a runRepository) SaveAllocations(id uuid.UUID, allocs []*nomad.Allocation) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM run_allocation WHERE run_id = $1`, id)
+	for _, alloc := range allocs {
+		batch.Queue(`INSERT INTO run_allocation (run_id, allocation) VALUES ($1, $2)`, id, alloc)
+	}
+
+	br := a.DB.SendBatch(context.Background(), batch)
+	defer br.Close()
+
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a runRepository) SaveNode(runId uuid.UUID, nodeId string) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`INSERT INTO run_node (run_id, node_id)
+		SELECT nomad_job_id, $2 FROM run WHERE nomad_job_id = $1
+		ON CONFLICT DO NOTHING`,
+		runId, nodeId,
+	)
+	return
+}
+
+func (a runRepository) GetLatestNodesByActionName(name string, limit int) (nodeIds []string, err error) {
+	nodeIds = []string{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &nodeIds,
+		`SELECT run_node.node_id
+		FROM run_node
+		JOIN run ON run.nomad_job_id = run_node.run_id
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id AND action.name = $1
+		GROUP BY run_node.node_id
+		ORDER BY MAX(run_node.created_at) DESC
+		LIMIT $2`,
+		name, limit,
+	)
+	return
+}
+
+func (a runRepository) GetLastMeasuredAt(ids []uuid.UUID) (measuredAt *time.Time, err error) {
+	// GREATEST ignores nulls.
+	err = pgxscan.Get(
+		context.Background(), a.DB, &measuredAt,
+		`SELECT GREATEST(
+			(SELECT MAX(scanned_at) FROM run_annotation WHERE run_id = ANY($1)),
+			(SELECT MAX(measured_at) FROM run_timing WHERE run_id = ANY($1)),
+			(SELECT MAX(measured_at) FROM run_usage WHERE run_id = ANY($1))
+		)`,
+		ids,
+	)
+	return
+}
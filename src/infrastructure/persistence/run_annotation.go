@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runAnnotationRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunAnnotationRepository(db config.PgxIface) repository.RunAnnotationRepository {
+	return runAnnotationRepository{mapErrors(db)}
+}
+
+func (a runAnnotationRepository) WithQuerier(querier config.PgxIface) repository.RunAnnotationRepository {
+	return runAnnotationRepository{mapErrors(querier)}
+}
+
+func (a runAnnotationRepository) GetByRunId(id uuid.UUID) (*domain.RunAnnotations, error) {
+	annotations, err := get(
+		a.DB, &domain.RunAnnotations{},
+		`SELECT * FROM run_annotation WHERE run_id = $1`,
+		id,
+	)
+	if annotations == nil {
+		return nil, err
+	}
+	return annotations.(*domain.RunAnnotations), err
+}
+
+func (a runAnnotationRepository) Save(annotations *domain.RunAnnotations) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_annotation (run_id, annotations) VALUES ($1, $2)
+		ON CONFLICT (run_id) DO UPDATE SET annotations = EXCLUDED.annotations, scanned_at = DEFAULT
+		RETURNING scanned_at`,
+		annotations.RunId, annotations.Annotations,
+	).Scan(&annotations.ScannedAt)
+}
+
+func (a runAnnotationRepository) GetFinishedWithoutAnnotations(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT run.* FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE run.finished_at < $1
+			AND action.meta->'log_matchers' IS NOT NULL
+			AND NOT EXISTS (
+				SELECT FROM run_annotation WHERE run_annotation.run_id = run.nomad_job_id
+			)
+		ORDER BY run.finished_at ASC
+		LIMIT $2`,
+		finishedBefore, limit,
+	)
+	return
+}
@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runApprovalRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunApprovalRepository(db config.PgxIface) repository.RunApprovalRepository {
+	return runApprovalRepository{mapErrors(db)}
+}
+
+func (a runApprovalRepository) WithQuerier(querier config.PgxIface) repository.RunApprovalRepository {
+	return runApprovalRepository{mapErrors(querier)}
+}
+
+const runApprovalColumns = `run_approval.run_id, run_approval.approvers, run_approval.created_at, run_approval.expires_at,
+	run_approval.approved, run_approval.decided_by, run_approval.decided_at, run_approval.comment`
+
+// Approvals of Runs that were canceled while waiting are not pending.
+const runApprovalPendingSelect = `
+	SELECT ` + runApprovalColumns + `
+	FROM run_approval
+	JOIN run ON run.nomad_job_id = run_approval.run_id
+	WHERE run_approval.decided_at IS NULL AND run.finished_at IS NULL`
+
+func (a runApprovalRepository) GetPending() (approvals []domain.RunApproval, err error) {
+	approvals = []domain.RunApproval{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &approvals,
+		runApprovalPendingSelect+` ORDER BY run_approval.created_at`,
+	)
+	return
+}
+
+func (a runApprovalRepository) GetExpired(at time.Time) (approvals []domain.RunApproval, err error) {
+	approvals = []domain.RunApproval{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &approvals,
+		runApprovalPendingSelect+` AND run_approval.expires_at < $1 ORDER BY run_approval.expires_at`,
+		at,
+	)
+	return
+}
+
+func (a runApprovalRepository) GetByRunId(runId uuid.UUID) (*domain.RunApproval, error) {
+	approval, err := get(
+		a.DB, &domain.RunApproval{},
+		`SELECT `+runApprovalColumns+` FROM run_approval WHERE run_id = $1`,
+		runId,
+	)
+	if approval == nil {
+		return nil, err
+	}
+	return approval.(*domain.RunApproval), err
+}
+
+func (a runApprovalRepository) GetJob(runId uuid.UUID) (*nomad.Job, error) {
+	var jobJson *string
+	if err := pgxscan.Get(
+		context.Background(), a.DB, &jobJson,
+		`SELECT job::text FROM run_approval WHERE run_id = $1`,
+		runId,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if jobJson == nil {
+		return nil, nil
+	}
+
+	job := &nomad.Job{}
+	if err := json.Unmarshal([]byte(*jobJson), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (a runApprovalRepository) Save(approval *domain.RunApproval, job *nomad.Job) error {
+	jobJson, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_approval (run_id, approvers, job, expires_at) VALUES ($1, $2, $3, $4) RETURNING created_at`,
+		approval.RunId, approval.Approvers, jobJson, approval.ExpiresAt,
+	).Scan(&approval.CreatedAt)
+}
+
+func (a runApprovalRepository) Decide(approval *domain.RunApproval) (bool, error) {
+	tag, err := a.DB.Exec(
+		context.Background(),
+		`UPDATE run_approval SET approved = $2, decided_by = $3, decided_at = $4, comment = $5, job = NULL
+		WHERE run_id = $1 AND decided_at IS NULL`,
+		approval.RunId, approval.Approved, approval.DecidedBy, approval.DecidedAt, approval.Comment,
+	)
+	return tag.RowsAffected() > 0, err
+}
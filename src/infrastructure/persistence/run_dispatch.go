@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runDispatchRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunDispatchRepository(db config.PgxIface) repository.RunDispatchRepository {
+	return runDispatchRepository{mapErrors(db)}
+}
+
+func (a runDispatchRepository) WithQuerier(querier config.PgxIface) repository.RunDispatchRepository {
+	return runDispatchRepository{mapErrors(querier)}
+}
+
+func (a runDispatchRepository) GetByRunId(id uuid.UUID) (dispatches []domain.RunDispatch, err error) {
+	dispatches = []domain.RunDispatch{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &dispatches,
+		`SELECT * FROM run_dispatch WHERE run_id = $1 ORDER BY number`,
+		id,
+	)
+	return
+}
+
+func (a runDispatchRepository) Save(dispatch *domain.RunDispatch) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_dispatch (run_id, number, meta, payload, status) VALUES ($1, $2, $3, $4, $5) RETURNING created_at`,
+		dispatch.RunId, dispatch.Number, dispatch.Meta, dispatch.Payload, dispatch.Status.String(),
+	).Scan(&dispatch.CreatedAt)
+}
+
+func (a runDispatchRepository) UpdateNomadJobId(dispatch *domain.RunDispatch) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run_dispatch SET nomad_job_id = $3 WHERE run_id = $1 AND number = $2`,
+		dispatch.RunId, dispatch.Number, dispatch.NomadJobID,
+	)
+	return
+}
+
+func (a runDispatchRepository) End(nomadJobId string, status domain.RunStatus, finishedAt time.Time) (bool, error) {
+	tag, err := a.DB.Exec(
+		context.Background(),
+		`UPDATE run_dispatch SET status = $2, finished_at = $3 WHERE nomad_job_id = $1 AND finished_at IS NULL`,
+		nomadJobId, status.String(), finishedAt,
+	)
+	return tag.RowsAffected() > 0, err
+}
+
+func (a runDispatchRepository) FailUndispatched(runId uuid.UUID, finishedAt time.Time) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run_dispatch SET status = 'failed', finished_at = $2 WHERE run_id = $1 AND nomad_job_id IS NULL`,
+		runId, finishedAt,
+	)
+	return
+}
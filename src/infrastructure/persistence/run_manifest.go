@@ -0,0 +1,47 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runManifestRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunManifestRepository(db config.PgxIface) repository.RunManifestRepository {
+	return runManifestRepository{mapErrors(db)}
+}
+
+func (a runManifestRepository) WithQuerier(querier config.PgxIface) repository.RunManifestRepository {
+	return runManifestRepository{mapErrors(querier)}
+}
+
+func (a runManifestRepository) GetByRunId(id uuid.UUID) (*domain.RunManifest, error) {
+	manifest, err := get(
+		a.DB, &domain.RunManifest{},
+		`SELECT * FROM run_manifest WHERE run_id = $1`,
+		id,
+	)
+	if manifest == nil {
+		return nil, err
+	}
+	return manifest.(*domain.RunManifest), err
+}
+
+func (a runManifestRepository) Save(manifest *domain.RunManifest) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_manifest (run_id, job, evaluators, cicero_version, cicero_commit, images)
+		VALUES ($1, $2::text::json, $3, $4, $5, $6)
+		RETURNING created_at`,
+		// As text so that the job is not reformatted on the way.
+		manifest.RunId, string(manifest.Job), manifest.Evaluators,
+		manifest.CiceroVersion, manifest.CiceroCommit, manifest.Images,
+	).Scan(&manifest.CreatedAt)
+}
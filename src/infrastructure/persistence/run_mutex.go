@@ -0,0 +1,134 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runMutexRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunMutexRepository(db config.PgxIface) repository.RunMutexRepository {
+	return runMutexRepository{mapErrors(db)}
+}
+
+func (a runMutexRepository) WithQuerier(querier config.PgxIface) repository.RunMutexRepository {
+	return runMutexRepository{mapErrors(querier)}
+}
+
+func (a runMutexRepository) Lock(name string) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`SELECT pg_advisory_xact_lock(hashtext('run_mutex:' || $1))`,
+		name,
+	)
+	return
+}
+
+// Mutexes of Runs that ended, were canceled, or denied are released.
+const runMutexRunningSelect = `
+	SELECT run_mutex.run_id, run_mutex.name, run_mutex.created_at, run_mutex.acquired_at
+	FROM run_mutex
+	JOIN run ON run.nomad_job_id = run_mutex.run_id
+	WHERE run.finished_at IS NULL AND run.status = 'running'`
+
+const runMutexRunningOrder = `ORDER BY run_mutex.name, run_mutex.acquired_at IS NULL, run_mutex.created_at`
+
+func (a runMutexRepository) GetRunning() (mutexes []domain.RunMutex, err error) {
+	mutexes = []domain.RunMutex{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &mutexes,
+		runMutexRunningSelect+` `+runMutexRunningOrder,
+	)
+	return
+}
+
+func (a runMutexRepository) GetRunningByName(name string) (mutexes []domain.RunMutex, err error) {
+	mutexes = []domain.RunMutex{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &mutexes,
+		runMutexRunningSelect+` AND run_mutex.name = $1 `+runMutexRunningOrder,
+		name,
+	)
+	return
+}
+
+func (a runMutexRepository) GetByRunId(runId uuid.UUID) (*domain.RunMutex, error) {
+	mutex, err := get(
+		a.DB, &domain.RunMutex{},
+		`SELECT run_id, name, created_at, acquired_at FROM run_mutex WHERE run_id = $1`,
+		runId,
+	)
+	if mutex == nil {
+		return nil, err
+	}
+	return mutex.(*domain.RunMutex), err
+}
+
+func (a runMutexRepository) GetJob(runId uuid.UUID) (*nomad.Job, error) {
+	var jobJson *string
+	if err := pgxscan.Get(
+		context.Background(), a.DB, &jobJson,
+		`SELECT job::text FROM run_mutex WHERE run_id = $1`,
+		runId,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if jobJson == nil {
+		return nil, nil
+	}
+
+	job := &nomad.Job{}
+	if err := json.Unmarshal([]byte(*jobJson), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (a runMutexRepository) Save(mutex *domain.RunMutex, job *nomad.Job) error {
+	var jobJson []byte
+	if job != nil {
+		var err error
+		if jobJson, err = json.Marshal(job); err != nil {
+			return err
+		}
+	}
+
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_mutex (run_id, name, job, acquired_at) VALUES ($1, $2, $3, $4) RETURNING created_at`,
+		mutex.RunId, mutex.Name, jobJson, mutex.AcquiredAt,
+	).Scan(&mutex.CreatedAt)
+}
+
+func (a runMutexRepository) Acquire(runId uuid.UUID, at time.Time) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run_mutex SET acquired_at = $2, job = NULL WHERE run_id = $1`,
+		runId, at,
+	)
+	return
+}
+
+func (a runMutexRepository) Delete(runId uuid.UUID) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM run_mutex WHERE run_id = $1`,
+		runId,
+	)
+	return
+}
@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runNomadTokenRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunNomadTokenRepository(db config.PgxIface) repository.RunNomadTokenRepository {
+	return runNomadTokenRepository{mapErrors(db)}
+}
+
+func (a runNomadTokenRepository) WithQuerier(querier config.PgxIface) repository.RunNomadTokenRepository {
+	return runNomadTokenRepository{mapErrors(querier)}
+}
+
+func (a runNomadTokenRepository) GetByRunId(runId uuid.UUID) (tokens []domain.RunNomadToken, err error) {
+	tokens = []domain.RunNomadToken{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &tokens,
+		`SELECT * FROM run_nomad_token WHERE run_id = $1 ORDER BY created_at`,
+		runId,
+	)
+	return
+}
+
+func (a runNomadTokenRepository) GetRevocable(at time.Time) (tokens []domain.RunNomadToken, err error) {
+	tokens = []domain.RunNomadToken{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &tokens,
+		`SELECT run_nomad_token.* FROM run_nomad_token
+		JOIN run ON run.nomad_job_id = run_nomad_token.run_id
+		WHERE run_nomad_token.revoked_at IS NULL AND (
+			run_nomad_token.expires_at < $1 OR
+			run.finished_at IS NOT NULL
+		)
+		ORDER BY run_nomad_token.expires_at`,
+		at,
+	)
+	return
+}
+
+func (a runNomadTokenRepository) Save(token *domain.RunNomadToken) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_nomad_token (accessor_id, run_id, nomad_cluster, policies, expires_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`,
+		token.AccessorId, token.RunId, token.NomadCluster, token.Policies, token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+}
+
+func (a runNomadTokenRepository) Revoke(accessorId string, at time.Time) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE run_nomad_token SET revoked_at = $2 WHERE accessor_id = $1`,
+		accessorId, at,
+	)
+	return
+}
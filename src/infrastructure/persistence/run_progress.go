@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runProgressRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunProgressRepository(db config.PgxIface) repository.RunProgressRepository {
+	return runProgressRepository{mapErrors(db)}
+}
+
+func (a runProgressRepository) WithQuerier(querier config.PgxIface) repository.RunProgressRepository {
+	return runProgressRepository{mapErrors(querier)}
+}
+
+func (a runProgressRepository) GetByRunId(id uuid.UUID) (progress []domain.RunProgress, err error) {
+	progress = []domain.RunProgress{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &progress,
+		`SELECT * FROM run_progress WHERE run_id = $1 ORDER BY created_at`,
+		id,
+	)
+	return
+}
+
+func (a runProgressRepository) GetLatestByRunId(id uuid.UUID) (*domain.RunProgress, error) {
+	progress, err := get(
+		a.DB, &domain.RunProgress{},
+		`SELECT * FROM run_progress WHERE run_id = $1 ORDER BY created_at DESC LIMIT 1`,
+		id,
+	)
+	if progress == nil {
+		return nil, err
+	}
+	return progress.(*domain.RunProgress), err
+}
+
+func (a runProgressRepository) Save(progress *domain.RunProgress) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_progress (run_id, done, total, message, value) VALUES ($1, $2, $3, $4, $5) RETURNING created_at`,
+		progress.RunId, progress.Done, progress.Total, progress.Message, progress.Value,
+	).Scan(&progress.CreatedAt)
+}
+
+func (a runProgressRepository) SaveToken(runId uuid.UUID, hash []byte) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`INSERT INTO run_token (run_id, hash) VALUES ($1, $2)
+		ON CONFLICT (run_id) DO UPDATE SET hash = EXCLUDED.hash`,
+		runId, hash,
+	)
+	return
+}
+
+func (a runProgressRepository) GetRunIdByTokenHash(hash []byte) (*uuid.UUID, error) {
+	var runId uuid.UUID
+	if err := pgxscan.Get(
+		context.Background(), a.DB, &runId,
+		`SELECT run_id FROM run_token WHERE hash = $1`,
+		hash,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &runId, nil
+}
@@ -0,0 +1,116 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	nomad "github.com/hashicorp/nomad/api"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runQueueRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunQueueRepository(db config.PgxIface) repository.RunQueueRepository {
+	return runQueueRepository{mapErrors(db)}
+}
+
+func (a runQueueRepository) WithQuerier(querier config.PgxIface) repository.RunQueueRepository {
+	return runQueueRepository{mapErrors(querier)}
+}
+
+func (a runQueueRepository) Lock() (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`SELECT pg_advisory_xact_lock(hashtext('run_queue'))`,
+	)
+	return
+}
+
+func (a runQueueRepository) GetAll() (entries []domain.RunQueueEntry, err error) {
+	entries = []domain.RunQueueEntry{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &entries,
+		`SELECT
+			run_queue.run_id,
+			COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source) AS project,
+			action.name AS action_name,
+			run_queue.created_at,
+			run_queue.overridden_by,
+			run_queue.override_reason
+		FROM run_queue
+		JOIN run ON run.nomad_job_id = run_queue.run_id
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE run.finished_at IS NULL AND run.status = 'running'
+		ORDER BY run_queue.created_at`,
+	)
+	return
+}
+
+func (a runQueueRepository) GetUsage() (usage []domain.RunQueueUsage, err error) {
+	usage = []domain.RunQueueUsage{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &usage,
+		`SELECT
+			COALESCE(action.meta->>'`+domain.ActionMetaProject+`', action.source) AS project,
+			action.name AS action_name,
+			count(*) AS running
+		FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE run.finished_at IS NULL
+			AND NOT EXISTS (SELECT FROM run_queue WHERE run_queue.run_id = run.nomad_job_id)
+			AND NOT EXISTS (SELECT FROM run_mutex WHERE run_mutex.run_id = run.nomad_job_id AND run_mutex.acquired_at IS NULL)
+		GROUP BY project, action.name`,
+	)
+	return
+}
+
+func (a runQueueRepository) Save(entry *domain.RunQueueEntry, job *nomad.Job) error {
+	jobJson, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_queue (run_id, job) VALUES ($1, $2) RETURNING created_at`,
+		entry.RunId, jobJson,
+	).Scan(&entry.CreatedAt)
+}
+
+func (a runQueueRepository) Take(runId uuid.UUID) (*nomad.Job, error) {
+	var jobJson string
+	if err := pgxscan.Get(
+		context.Background(), a.DB, &jobJson,
+		`DELETE FROM run_queue WHERE run_id = $1 RETURNING job::text`,
+		runId,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	job := &nomad.Job{}
+	if err := json.Unmarshal([]byte(jobJson), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (a runQueueRepository) Override(runId uuid.UUID, by, reason string) (bool, error) {
+	tag, err := a.DB.Exec(
+		context.Background(),
+		`UPDATE run_queue SET overridden_by = $2, override_reason = $3 WHERE run_id = $1`,
+		runId, by, reason,
+	)
+	return tag.RowsAffected() > 0, err
+}
@@ -77,3 +77,41 @@ func TestShouldUpdateRun(t *testing.T) {
 	// then
 	assert.Nil(t, err)
 }
+
+func TestShouldUpdateRunSuspect(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UTC()
+	run := domain.Run{
+		NomadJobID:   uuid.New(),
+		SuspectSince: &now,
+	}
+
+	// given
+	mock, _ := mocks.BuildTransaction(context.Background(), t)
+	mock.ExpectExec("UPDATE run SET suspect_since").WithArgs(run.NomadJobID, run.SuspectSince).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	repository := NewRunRepository(mock)
+
+	// when
+	err := repository.UpdateSuspect(&run)
+
+	// then
+	assert.Nil(t, err)
+}
+
+func TestShouldUpdateRunBlocked(t *testing.T) {
+	t.Parallel()
+	run := domain.Run{NomadJobID: uuid.New()}
+
+	// given
+	mock, _ := mocks.BuildTransaction(context.Background(), t)
+	mock.ExpectExec("UPDATE run SET blocked_since").WithArgs(run.NomadJobID, run.BlockedSince, []string{}).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	repository := NewRunRepository(mock)
+
+	// when
+	err := repository.UpdateBlocked(&run)
+
+	// then
+	assert.Nil(t, err)
+}
@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runTimingRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunTimingRepository(db config.PgxIface) repository.RunTimingRepository {
+	return runTimingRepository{mapErrors(db)}
+}
+
+func (a runTimingRepository) WithQuerier(querier config.PgxIface) repository.RunTimingRepository {
+	return runTimingRepository{mapErrors(querier)}
+}
+
+func (a runTimingRepository) GetByRunId(id uuid.UUID) (*domain.RunTiming, error) {
+	timing, err := get(
+		a.DB, &domain.RunTiming{},
+		`SELECT * FROM run_timing WHERE run_id = $1`,
+		id,
+	)
+	if timing == nil {
+		return nil, err
+	}
+	return timing.(*domain.RunTiming), err
+}
+
+func (a runTimingRepository) GetUnmeasured(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT run.* FROM run
+		WHERE finished_at < $1 AND NOT EXISTS (
+			SELECT FROM run_timing WHERE run_timing.run_id = run.nomad_job_id
+		)
+		ORDER BY finished_at ASC
+		LIMIT $2`,
+		finishedBefore, limit,
+	)
+	return
+}
+
+func (a runTimingRepository) Save(timing *domain.RunTiming) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_timing (run_id, queued_seconds, pending_seconds, executing_seconds, finalizing_seconds) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (run_id) DO UPDATE SET
+			queued_seconds = EXCLUDED.queued_seconds,
+			pending_seconds = EXCLUDED.pending_seconds,
+			executing_seconds = EXCLUDED.executing_seconds,
+			finalizing_seconds = EXCLUDED.finalizing_seconds,
+			measured_at = EXCLUDED.measured_at
+		RETURNING measured_at`,
+		timing.RunId, timing.QueuedSeconds, timing.PendingSeconds, timing.ExecutingSeconds, timing.FinalizingSeconds,
+	).Scan(&timing.MeasuredAt)
+}
+
+func (a runTimingRepository) GetAggregates(actionName string, since time.Time) (aggregates []domain.RunTimingAggregate, err error) {
+	aggregates = []domain.RunTimingAggregate{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &aggregates,
+		`SELECT
+			phases.phase,
+			count(*) AS runs,
+			avg(phases.seconds) AS avg_seconds,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY phases.seconds) AS p50_seconds,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY phases.seconds) AS p95_seconds,
+			max(phases.seconds) AS max_seconds
+		FROM run_timing
+		JOIN run ON run.nomad_job_id = run_timing.run_id
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		CROSS JOIN LATERAL (VALUES
+			(1, $3::text, run_timing.queued_seconds),
+			(2, $4::text, run_timing.pending_seconds),
+			(3, $5::text, run_timing.executing_seconds),
+			(4, $6::text, run_timing.finalizing_seconds)
+		) AS phases (ordinal, phase, seconds)
+		WHERE action.name = $1 AND run.finished_at >= $2 AND phases.seconds IS NOT NULL
+		GROUP BY phases.ordinal, phases.phase
+		ORDER BY phases.ordinal`,
+		actionName, since,
+		domain.RunTimingPhaseQueued, domain.RunTimingPhasePending, domain.RunTimingPhaseExecuting, domain.RunTimingPhaseFinalizing,
+	)
+	return
+}
@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type runUsageRepository struct {
+	DB config.PgxIface
+}
+
+func NewRunUsageRepository(db config.PgxIface) repository.RunUsageRepository {
+	return runUsageRepository{mapErrors(db)}
+}
+
+func (a runUsageRepository) WithQuerier(querier config.PgxIface) repository.RunUsageRepository {
+	return runUsageRepository{mapErrors(querier)}
+}
+
+func (a runUsageRepository) GetByRunId(id uuid.UUID) (*domain.RunUsage, error) {
+	usage, err := get(
+		a.DB, &domain.RunUsage{},
+		`SELECT * FROM run_usage WHERE run_id = $1`,
+		id,
+	)
+	if usage == nil {
+		return nil, err
+	}
+	return usage.(*domain.RunUsage), err
+}
+
+func (a runUsageRepository) GetUnmeasured(finishedBefore time.Time, limit int) (runs []domain.Run, err error) {
+	runs = []domain.Run{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT run.* FROM run
+		WHERE finished_at < $1 AND NOT EXISTS (
+			SELECT FROM run_usage WHERE run_usage.run_id = run.nomad_job_id
+		)
+		ORDER BY finished_at ASC
+		LIMIT $2`,
+		finishedBefore, limit,
+	)
+	return
+}
+
+func (a runUsageRepository) GetMonthlySums(by domain.RunUsageGroup, from, to time.Time) (sums []domain.RunUsageSum, err error) {
+	var group string
+	switch by {
+	case domain.RunUsageByAction:
+		group = `action.name`
+	case domain.RunUsageByProject:
+		group = `COALESCE(action.meta->>'` + domain.ActionMetaProject + `', action.source)`
+	default:
+		return nil, errors.Errorf("Cannot group usage by %q", by)
+	}
+
+	sums = []domain.RunUsageSum{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &sums,
+		`SELECT
+			date_trunc('month', run.finished_at) AS month,
+			`+group+` AS "group",
+			count(*) AS runs,
+			sum(run_usage.cpu_seconds) AS cpu_seconds,
+			sum(run_usage.memory_byte_seconds) AS memory_byte_seconds
+		FROM run_usage
+		JOIN run ON run.nomad_job_id = run_usage.run_id
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE run.finished_at >= $1 AND run.finished_at < $2
+		GROUP BY 1, 2
+		ORDER BY 1, 2`,
+		from, to,
+	)
+	return
+}
+
+func (a runUsageRepository) Save(usage *domain.RunUsage) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO run_usage (run_id, cpu_seconds, memory_byte_seconds) VALUES ($1, $2, $3)
+		ON CONFLICT (run_id) DO UPDATE SET
+			cpu_seconds = EXCLUDED.cpu_seconds,
+			memory_byte_seconds = EXCLUDED.memory_byte_seconds,
+			measured_at = EXCLUDED.measured_at
+		RETURNING measured_at`,
+		usage.RunId, usage.CPUSeconds, usage.MemoryByteSeconds,
+	).Scan(&usage.MeasuredAt)
+}
+
+func (a runUsageRepository) SavePeaks(peaks []domain.RunUsagePeak) error {
+	batch := &pgx.Batch{}
+	for _, peak := range peaks {
+		batch.Queue(
+			`INSERT INTO run_usage_peak (run_id, task_group, cpu_cores, memory_bytes) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (run_id, task_group) DO UPDATE SET
+				cpu_cores = EXCLUDED.cpu_cores,
+				memory_bytes = EXCLUDED.memory_bytes`,
+			peak.RunId, peak.TaskGroup, peak.CPUCores, peak.MemoryBytes,
+		)
+	}
+
+	br := a.DB.SendBatch(context.Background(), batch)
+	defer br.Close()
+
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a runUsageRepository) GetPeakPercentiles(actionName string, since time.Time, percentile float64) (percentiles []domain.RunUsagePercentile, err error) {
+	percentiles = []domain.RunUsagePercentile{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &percentiles,
+		`SELECT
+			run_usage_peak.task_group,
+			count(*) AS runs,
+			percentile_cont($3) WITHIN GROUP (ORDER BY run_usage_peak.cpu_cores) AS cpu_cores,
+			percentile_cont($3) WITHIN GROUP (ORDER BY run_usage_peak.memory_bytes) AS memory_bytes
+		FROM run_usage_peak
+		JOIN run ON run.nomad_job_id = run_usage_peak.run_id
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE action.name = $1 AND run.finished_at >= $2
+		GROUP BY 1
+		ORDER BY 1`,
+		actionName, since, percentile,
+	)
+	return
+}
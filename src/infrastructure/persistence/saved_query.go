@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/pgxscan"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type savedQueryRepository struct {
+	DB config.PgxIface
+}
+
+func NewSavedQueryRepository(db config.PgxIface) repository.SavedQueryRepository {
+	return savedQueryRepository{mapErrors(db)}
+}
+
+func (a savedQueryRepository) WithQuerier(querier config.PgxIface) repository.SavedQueryRepository {
+	return savedQueryRepository{mapErrors(querier)}
+}
+
+func (a savedQueryRepository) GetAll(kind domain.SavedQueryKind) (queries []domain.SavedQuery, err error) {
+	queries = []domain.SavedQuery{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &queries,
+		`SELECT * FROM saved_query WHERE $1 = '' OR kind = $1 ORDER BY name`,
+		string(kind),
+	)
+	return
+}
+
+func (a savedQueryRepository) Get(name string) (*domain.SavedQuery, error) {
+	query, err := get(
+		a.DB, &domain.SavedQuery{},
+		`SELECT * FROM saved_query WHERE name = $1`,
+		name,
+	)
+	if query == nil {
+		return nil, err
+	}
+	return query.(*domain.SavedQuery), err
+}
+
+func (a savedQueryRepository) Save(query *domain.SavedQuery) error {
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO saved_query (name, kind, query, description, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			query = EXCLUDED.query,
+			description = EXCLUDED.description,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		query.Name, string(query.Kind), query.Query, query.Description, query.UpdatedBy,
+	).Scan(&query.UpdatedAt)
+}
+
+func (a savedQueryRepository) Delete(name string) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM saved_query WHERE name = $1`,
+		name,
+	)
+	return
+}
@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+	"github.com/input-output-hk/cicero/src/domain/repository"
+)
+
+type watchRepository struct {
+	DB config.PgxIface
+}
+
+func NewWatchRepository(db config.PgxIface) repository.WatchRepository {
+	return watchRepository{mapErrors(db)}
+}
+
+func (a watchRepository) WithQuerier(querier config.PgxIface) repository.WatchRepository {
+	return watchRepository{mapErrors(querier)}
+}
+
+func (a watchRepository) GetById(id uuid.UUID) (*domain.Watch, error) {
+	watch, err := get(
+		a.DB, &domain.Watch{},
+		`SELECT * FROM watch WHERE id = $1`,
+		id,
+	)
+	if watch == nil {
+		return nil, err
+	}
+	return watch.(*domain.Watch), err
+}
+
+func (a watchRepository) GetByUser(user string) (watches []domain.Watch, err error) {
+	watches = []domain.Watch{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &watches,
+		`SELECT * FROM watch WHERE "user" = $1 ORDER BY created_at DESC`,
+		user,
+	)
+	return
+}
+
+func (a watchRepository) GetNotifiable() (watches []domain.Watch, err error) {
+	watches = []domain.Watch{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &watches,
+		`SELECT * FROM watch WHERE email IS NOT NULL ORDER BY created_at`,
+	)
+	return
+}
+
+func (a watchRepository) Save(watch *domain.Watch) error {
+	// Each kind of watch is unique by its own partial index.
+	conflict := `("user", run_id) WHERE run_id IS NOT NULL`
+	if watch.ActionName != nil {
+		conflict = `("user", action_name) WHERE action_name IS NOT NULL`
+	}
+
+	return a.DB.QueryRow(
+		context.Background(),
+		`INSERT INTO watch ("user", run_id, action_name, email) VALUES ($1, $2, $3, $4)
+		ON CONFLICT `+conflict+` DO UPDATE SET email = EXCLUDED.email
+		RETURNING id, created_at, last_notified_at`,
+		watch.User, watch.RunId, watch.ActionName, watch.Email,
+	).Scan(&watch.ID, &watch.CreatedAt, &watch.LastNotifiedAt)
+}
+
+func (a watchRepository) Delete(id uuid.UUID) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`DELETE FROM watch WHERE id = $1`,
+		id,
+	)
+	return
+}
+
+func (a watchRepository) UpdateLastNotified(id uuid.UUID, at time.Time) (err error) {
+	_, err = a.DB.Exec(
+		context.Background(),
+		`UPDATE watch SET last_notified_at = $2 WHERE id = $1`,
+		id, at,
+	)
+	return
+}
+
+func (a watchRepository) GetEndedRuns(watch domain.Watch, from, to time.Time) (runs []domain.WatchedRun, err error) {
+	runs = []domain.WatchedRun{}
+	err = pgxscan.Select(
+		context.Background(), a.DB, &runs,
+		`SELECT run.*, action.name AS action_name
+		FROM run
+		JOIN invocation ON invocation.id = run.invocation_id
+		JOIN action ON action.id = invocation.action_id
+		WHERE run.finished_at >= $1 AND run.finished_at < $2
+			AND (run.nomad_job_id = $3::uuid OR action.name = $4::text)
+		ORDER BY run.finished_at`,
+		from, to, watch.RunId, watch.ActionName,
+	)
+	return
+}
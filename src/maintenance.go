@@ -0,0 +1,62 @@
+package cicero
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type MaintenanceCmd struct {
+	ReconcileRuns *MaintenanceReconcileRunsCmd `arg:"subcommand:reconcile-runs" help:"end running Runs whose Nomad job vanished or died without Cicero noticing"`
+}
+
+func (cmd *MaintenanceCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.ReconcileRuns != nil:
+		return cmd.ReconcileRuns.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+type MaintenanceReconcileRunsCmd struct {
+	DryRun bool          `arg:"--dry-run" help:"only show which Runs would be ended"`
+	Grace  time.Duration `arg:"--grace" default:"10m" help:"how old Runs must be to be reconciled"`
+
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *MaintenanceReconcileRunsCmd) Run(logger *zerolog.Logger) error {
+	query := url.Values{}
+	query.Set("dry-run", strconv.FormatBool(cmd.DryRun))
+	query.Set("grace", cmd.Grace.String())
+
+	runs := []domain.Run{}
+	if err := cmd.request(http.MethodPost, "/api/v1/admin/reconcile-runs?"+query.Encode(), nil, &runs); err != nil {
+		return err
+	}
+
+	return cmd.print(runs, outputFormatTable, func() outputTable {
+		table := outputTable{Header: []string{"id", "status", "created at", "finished at", "note"}}
+		for _, run := range runs {
+			note := ""
+			if run.ReconcileNote != nil {
+				note = *run.ReconcileNote
+			}
+			table.add(
+				run.NomadJobID.String(),
+				run.Status.String(),
+				run.CreatedAt.Format(time.RFC3339),
+				outputTime(run.FinishedAt),
+				note,
+			)
+		}
+		return table
+	})
+}
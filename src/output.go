@@ -0,0 +1,125 @@
+package cicero
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/input-output-hk/cicero/src/config"
+)
+
+// Flags of subcommands that print what they got from the API.
+// All formats but table use the field names of the API's JSON
+// so that scripts do not break when the table changes.
+type OutputFlags struct {
+	Output string `arg:"--output,-o,env:CICERO_OUTPUT" help:"how to print the result: json, yaml, table, or go-template=TEMPLATE with the JSON field names, defaults to what fits the command"`
+}
+
+const (
+	outputFormatJson     = "json"
+	outputFormatYaml     = "yaml"
+	outputFormatTable    = "table"
+	outputFormatTemplate = "go-template="
+)
+
+func (self OutputFlags) Validate() error {
+	switch {
+	case self.Output == "", self.Output == outputFormatJson, self.Output == outputFormatYaml, self.Output == outputFormatTable:
+	case strings.HasPrefix(self.Output, outputFormatTemplate):
+		if _, err := template.New("output").Parse(strings.TrimPrefix(self.Output, outputFormatTemplate)); err != nil {
+			return config.KeyError{Key: "output", Err: err}
+		}
+	default:
+		return config.KeyError{Key: "output", Err: errors.Errorf("unknown output format %q", self.Output)}
+	}
+	return nil
+}
+
+// Rows to print if the output format is table.
+type outputTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+func (self *outputTable) add(row ...string) {
+	self.Rows = append(self.Rows, row)
+}
+
+// Formats an optional time for a table.
+func outputTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Prints the value in the chosen format or the given default.
+// The table is only built if needed.
+func (self OutputFlags) print(value interface{}, defaultFormat string, table func() outputTable) error {
+	format := self.Output
+	if format == "" {
+		format = defaultFormat
+	}
+	return printOutput(os.Stdout, format, value, table)
+}
+
+func printOutput(w io.Writer, format string, value interface{}, table func() outputTable) error {
+	switch {
+	case format == outputFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case format == outputFormatYaml:
+		generic, err := genericJson(value)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(generic); err != nil {
+			return errors.WithMessage(err, "Could not encode YAML")
+		}
+		return encoder.Close()
+	case format == outputFormatTable:
+		t := table()
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if len(t.Header) > 0 {
+			fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.Header, "\t")))
+		}
+		for _, row := range t.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case strings.HasPrefix(format, outputFormatTemplate):
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, outputFormatTemplate))
+		if err != nil {
+			return errors.WithMessage(err, "Invalid output template")
+		}
+		generic, err := genericJson(value)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, generic)
+	default:
+		return errors.Errorf("Unknown output format %q", format)
+	}
+}
+
+// Returns the value as decoded from its JSON
+// so that it has the same field names.
+func genericJson(value interface{}) (generic interface{}, err error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not encode JSON")
+	}
+	err = json.Unmarshal(encoded, &generic)
+	return
+}
@@ -0,0 +1,199 @@
+package cicero
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type QuotaCmd struct {
+	List     *QuotaListCmd     `arg:"subcommand:list" help:"list quotas and their usage"`
+	Show     *QuotaShowCmd     `arg:"subcommand:show" help:"show the quota and usage of a project or API token"`
+	Set      *QuotaSetCmd      `arg:"subcommand:set" help:"set the quota of a project or API token"`
+	Delete   *QuotaDeleteCmd   `arg:"subcommand:delete" help:"remove the quota of a project or API token"`
+	Override *QuotaOverrideCmd `arg:"subcommand:override" help:"lift the quota of a project or API token for a while"`
+}
+
+func (cmd *QuotaCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.List != nil:
+		return cmd.List.Run(logger)
+	case cmd.Show != nil:
+		return cmd.Show.Run(logger)
+	case cmd.Set != nil:
+		return cmd.Set.Run(logger)
+	case cmd.Delete != nil:
+		return cmd.Delete.Run(logger)
+	case cmd.Override != nil:
+		return cmd.Override.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+func quotaPath(subject, name string) (string, error) {
+	if _, err := domain.ParseQuotaSubject(subject); err != nil {
+		return "", err
+	}
+	return "/api/v1/quota/" + url.PathEscape(subject) + "/" + url.PathEscape(name), nil
+}
+
+type QuotaListCmd struct {
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *QuotaListCmd) Run(logger *zerolog.Logger) error {
+	reports := []domain.QuotaReport{}
+	if err := cmd.request(http.MethodGet, "/api/v1/quota", nil, &reports); err != nil {
+		return err
+	}
+
+	return cmd.print(reports, outputFormatJson, func() outputTable {
+		return quotaTable(reports...)
+	})
+}
+
+func quotaTable(reports ...domain.QuotaReport) outputTable {
+	table := outputTable{Header: []string{"subject", "name", "runs last hour", "concurrent runs", "fact bytes"}}
+	for _, report := range reports {
+		quota := domain.Quota{}
+		if report.Quota != nil {
+			quota = *report.Quota
+		}
+		table.add(
+			string(report.Subject),
+			report.Name,
+			quotaUsage(int64(report.Usage.RunsLastHour), intPtrToInt64(quota.MaxRunsPerHour)),
+			quotaUsage(int64(report.Usage.ConcurrentRuns), intPtrToInt64(quota.MaxConcurrentRuns)),
+			quotaUsage(report.Usage.FactBytes, quota.MaxFactBytes),
+		)
+	}
+	return table
+}
+
+// Formats usage like "3/10", or just "3" if unlimited.
+func quotaUsage(usage int64, max *int64) string {
+	if max == nil {
+		return strconv.FormatInt(usage, 10)
+	}
+	return strconv.FormatInt(usage, 10) + "/" + strconv.FormatInt(*max, 10)
+}
+
+func intPtrToInt64(i *int) *int64 {
+	if i == nil {
+		return nil
+	}
+	i64 := int64(*i)
+	return &i64
+}
+
+type QuotaShowCmd struct {
+	Subject string `arg:"positional,required" help:"project or token"`
+	Name    string `arg:"positional,required" help:"name of the project or API token"`
+
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *QuotaShowCmd) Run(logger *zerolog.Logger) error {
+	path, err := quotaPath(cmd.Subject, cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	report := domain.QuotaReport{}
+	if err := cmd.request(http.MethodGet, path, nil, &report); err != nil {
+		return err
+	}
+
+	return cmd.print(report, outputFormatJson, func() outputTable {
+		return quotaTable(report)
+	})
+}
+
+type QuotaSetCmd struct {
+	Subject           string `arg:"positional,required" help:"project or token"`
+	Name              string `arg:"positional,required" help:"name of the project or API token"`
+	MaxRunsPerHour    *int   `arg:"--max-runs-per-hour" help:"how many Runs may be started per hour, unlimited if not given"`
+	MaxConcurrentRuns *int   `arg:"--max-concurrent-runs" help:"how many Runs may run at once, unlimited if not given"`
+	MaxFactBytes      *int64 `arg:"--max-fact-bytes" help:"how many bytes of fact values and binaries may be stored, unlimited if not given"`
+
+	ApiFlags
+}
+
+func (cmd *QuotaSetCmd) Run(logger *zerolog.Logger) error {
+	path, err := quotaPath(cmd.Subject, cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	quota := domain.Quota{}
+	if err := cmd.request(http.MethodPut, path, map[string]interface{}{
+		"max_runs_per_hour":   cmd.MaxRunsPerHour,
+		"max_concurrent_runs": cmd.MaxConcurrentRuns,
+		"max_fact_bytes":      cmd.MaxFactBytes,
+	}, &quota); err != nil {
+		return err
+	}
+
+	logger.Info().Str("subject", string(quota.Subject)).Str("name", quota.Name).Msg("Set quota")
+	return nil
+}
+
+type QuotaDeleteCmd struct {
+	Subject string `arg:"positional,required" help:"project or token"`
+	Name    string `arg:"positional,required" help:"name of the project or API token"`
+
+	ApiFlags
+}
+
+func (cmd *QuotaDeleteCmd) Run(logger *zerolog.Logger) error {
+	path, err := quotaPath(cmd.Subject, cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.request(http.MethodDelete, path, nil, nil); err != nil {
+		return err
+	}
+
+	logger.Info().Str("subject", cmd.Subject).Str("name", cmd.Name).Msg("Removed quota")
+	return nil
+}
+
+type QuotaOverrideCmd struct {
+	Subject string        `arg:"positional,required" help:"project or token"`
+	Name    string        `arg:"positional,required" help:"name of the project or API token"`
+	For     time.Duration `arg:"--for,required" help:"how long to lift the quota, like 2h"`
+	Reason  string        `arg:"--reason,required" help:"why the quota is lifted"`
+
+	ApiFlags
+}
+
+func (cmd *QuotaOverrideCmd) Run(logger *zerolog.Logger) error {
+	path, err := quotaPath(cmd.Subject, cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	quota := domain.Quota{}
+	if err := cmd.request(http.MethodPost, path+"/override", map[string]interface{}{
+		"duration": cmd.For.String(),
+		"reason":   cmd.Reason,
+	}, &quota); err != nil {
+		return err
+	}
+
+	logger.Info().
+		Str("subject", string(quota.Subject)).
+		Str("name", quota.Name).
+		Time("until", *quota.OverrideUntil).
+		Msg("Overrode quota")
+	return nil
+}
@@ -0,0 +1,194 @@
+package cicero
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type RunsCmd struct {
+	List *RunsListCmd `arg:"subcommand:list" help:"list the latest Runs"`
+	Show *RunsShowCmd `arg:"subcommand:show" help:"show a Run, exits with 2 if it failed and 3 if it was canceled"`
+	Exec *RunsExecCmd `arg:"subcommand:exec" help:"execute a command in a running Run's task"`
+}
+
+func (cmd *RunsCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.List != nil:
+		return cmd.List.Run(logger)
+	case cmd.Show != nil:
+		return cmd.Show.Run(logger)
+	case cmd.Exec != nil:
+		return cmd.Exec.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+// Exit codes of `cicero runs show` by the Run's status
+// so that scripts can tell them apart from errors.
+var runStatusExitCodes = map[domain.RunStatus]int{
+	domain.RunStatusFailed:   2,
+	domain.RunStatusCanceled: 3,
+}
+
+func runsTable(runs ...domain.Run) outputTable {
+	table := outputTable{Header: []string{"id", "status", "created at", "finished at", "nomad cluster"}}
+	for _, run := range runs {
+		table.add(
+			run.NomadJobID.String(),
+			run.Status.String(),
+			run.CreatedAt.Format(time.RFC3339),
+			outputTime(run.FinishedAt),
+			run.NomadCluster,
+		)
+	}
+	return table
+}
+
+type RunsListCmd struct {
+	Limit  int `arg:"--limit" default:"10" help:"how many Runs to list"`
+	Offset int `arg:"--offset" help:"how many of the latest Runs to skip"`
+
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *RunsListCmd) Run(logger *zerolog.Logger) error {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(cmd.Limit))
+	query.Set("offset", strconv.Itoa(cmd.Offset))
+
+	runs := []domain.Run{}
+	if err := cmd.request(http.MethodGet, "/api/v1/run?"+query.Encode(), nil, &runs); err != nil {
+		return err
+	}
+
+	return cmd.print(runs, outputFormatTable, func() outputTable {
+		return runsTable(runs...)
+	})
+}
+
+type RunsShowCmd struct {
+	Id string `arg:"positional,required" help:"ID of the Run"`
+
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *RunsShowCmd) Run(logger *zerolog.Logger) error {
+	run := domain.Run{}
+	if err := cmd.request(http.MethodGet, "/api/v1/run/"+url.PathEscape(cmd.Id), nil, &run); err != nil {
+		return err
+	}
+
+	if err := cmd.print(run, outputFormatJson, func() outputTable {
+		return runsTable(run)
+	}); err != nil {
+		return err
+	}
+
+	if code, ok := runStatusExitCodes[run.Status]; ok {
+		os.Exit(code)
+	}
+	return nil
+}
+
+type RunsExecCmd struct {
+	Id      string   `arg:"positional,required" help:"ID of the Run"`
+	Command []string `arg:"positional" help:"command to execute, defaults to /bin/sh"`
+
+	Alloc string `arg:"--alloc" help:"ID of the allocation, needed if there is more than one running"`
+	Task  string `arg:"--task" help:"name of the task, needed if there is more than one running"`
+	Tty   bool   `arg:"--tty" help:"allocate a pseudo-terminal, put your own terminal in raw mode for this to work well"`
+
+	ApiFlags
+}
+
+func (cmd *RunsExecCmd) Run(logger *zerolog.Logger) error {
+	execUrl, err := cmd.url("/api/v1/run/" + url.PathEscape(cmd.Id) + "/exec")
+	if err != nil {
+		return err
+	}
+	switch execUrl.Scheme {
+	case "https":
+		execUrl.Scheme = "wss"
+	default:
+		execUrl.Scheme = "ws"
+	}
+
+	query := url.Values{}
+	query.Set("tty", strconv.FormatBool(cmd.Tty))
+	if cmd.Alloc != "" {
+		query.Set("alloc", cmd.Alloc)
+	}
+	if cmd.Task != "" {
+		query.Set("task", cmd.Task)
+	}
+	for _, arg := range cmd.Command {
+		query.Add("command", arg)
+	}
+	execUrl.RawQuery = query.Encode()
+
+	header := cmd.header()
+	logger.Debug().Stringer("url", execUrl).Msg("Connecting")
+
+	conn, res, err := websocket.DefaultDialer.Dial(execUrl.String(), header)
+	if err != nil {
+		if res != nil {
+			body, _ := io.ReadAll(res.Body)
+			return errors.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+		}
+		return errors.WithMessage(err, "Could not connect")
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				if err := conn.WriteJSON(nomad.ExecStreamingInput{Stdin: &nomad.ExecStreamingIOOperation{Data: buf[:n]}}); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				_ = conn.WriteJSON(nomad.ExecStreamingInput{Stdin: &nomad.ExecStreamingIOOperation{Close: true}})
+				return
+			}
+		}
+	}()
+
+	for {
+		var output nomad.ExecStreamingOutput
+		if err := conn.ReadJSON(&output); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return errors.New("Connection closed before the command exited")
+			}
+			return errors.WithMessage(err, "Connection lost")
+		}
+
+		switch {
+		case output.Stdout != nil:
+			_, _ = os.Stdout.Write(output.Stdout.Data)
+		case output.Stderr != nil:
+			_, _ = os.Stderr.Write(output.Stderr.Data)
+		case output.Exited && output.Result != nil:
+			if output.Result.ExitCode != 0 {
+				conn.Close()
+				os.Exit(output.Result.ExitCode)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cicero
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type SealCmd struct {
+	Action      string `arg:"--action" help:"name of the action that may use the value"`
+	File        string `arg:"positional" help:"file with the value to seal, - for stdin"`
+	Key         string `arg:"--key,env:CICERO_SEAL_PUBLIC_KEY" help:"public key to seal against instead of asking Cicero for it"`
+	GenerateKey bool   `arg:"--generate-key" help:"print a new private key for cicero start --seal-key-file instead of sealing"`
+
+	ApiFlags
+}
+
+func (cmd *SealCmd) Validate() error {
+	if cmd.GenerateKey {
+		return nil
+	}
+	if cmd.Action == "" {
+		return config.KeyError{Key: "seal.action", Err: errors.New("must be given")}
+	}
+	if cmd.File == "" {
+		return config.KeyError{Key: "seal.file", Err: errors.New("must be given")}
+	}
+	return nil
+}
+
+func (cmd *SealCmd) Run(logger *zerolog.Logger) error {
+	if cmd.GenerateKey {
+		private, public, err := domain.GenerateSealKey()
+		if err != nil {
+			return err
+		}
+		logger.Info().Stringer("public-key", public).Msg("Generated seal key")
+		fmt.Println(private)
+		return nil
+	}
+
+	publicKey, err := cmd.publicKey()
+	if err != nil {
+		return err
+	}
+
+	input := os.Stdin
+	if cmd.File != "-" {
+		if input, err = os.Open(cmd.File); err != nil {
+			return errors.WithMessagef(err, "Could not open %q", cmd.File)
+		}
+		defer input.Close()
+	}
+
+	value, err := io.ReadAll(input)
+	if err != nil {
+		return errors.WithMessagef(err, "Could not read %q", cmd.File)
+	}
+
+	sealed, err := domain.Seal(*publicKey, cmd.Action, value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(sealed)
+	return nil
+}
+
+func (cmd *SealCmd) publicKey() (*domain.SealKey, error) {
+	str := cmd.Key
+	if str == "" {
+		result := struct {
+			PublicKey string `json:"public_key"`
+		}{}
+		if err := cmd.request(http.MethodGet, "/api/v1/seal/key", nil, &result); err != nil {
+			return nil, errors.WithMessage(err, "Could not get public key")
+		}
+		str = result.PublicKey
+	}
+
+	key, err := domain.ParseSealKey(str)
+	return key, errors.WithMessage(err, "Invalid public key")
+}
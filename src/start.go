@@ -2,24 +2,41 @@ package cicero
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"log/syslog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"cirello.io/oversight"
+	promtail "github.com/grafana/loki/clients/pkg/promtail/api"
 	promtailClient "github.com/grafana/loki/clients/pkg/promtail/client"
-	nomad "github.com/hashicorp/nomad/api"
 	"github.com/pkg/errors"
 	prometheus "github.com/prometheus/client_golang/api"
 	"github.com/rs/zerolog"
 
+	"github.com/input-output-hk/cicero/actions"
+	migrations "github.com/input-output-hk/cicero/db"
 	"github.com/input-output-hk/cicero/src/application"
 	"github.com/input-output-hk/cicero/src/application/component"
 	"github.com/input-output-hk/cicero/src/application/component/web"
+	"github.com/input-output-hk/cicero/src/application/component/web/auth"
 	"github.com/input-output-hk/cicero/src/application/service"
 	"github.com/input-output-hk/cicero/src/config"
+	"github.com/input-output-hk/cicero/src/domain"
 )
 
 //go:generate mockery --all --keeptree
 
+// Where the web UI's templates and static files are
+// relative to the root of the repository.
+const webAssetsDir = "src/application/component/web"
+
 type StartCmd struct {
 	Components []string `arg:"positional,env:CICERO_COMPONENTS" help:"any of: nomad, web"`
 
@@ -27,10 +44,256 @@ type StartCmd struct {
 	VictoriaMetricsAddr string   `arg:"--victoriametrics-addr" default:"http://127.0.0.1:8428"`
 	Evaluators          []string `arg:"--evaluators"`
 	Transformers        []string `arg:"--transform"`
+	EvaluationCache     bool     `arg:"--evaluation-cache,env:CICERO_EVALUATION_CACHE" help:"reuse jobs rendered for the same action and inputs instead of evaluating again"`
+	ActionTemplateDirs  []string `arg:"--action-template-dir,env:CICERO_ACTION_TEMPLATE_DIRS" help:"directories with action templates in addition to the built-in ones, replacing those with the same name"`
+
+	EvaluatorExecs      []string `arg:"--evaluator-exec,env:CICERO_EVALUATOR_EXECS" help:"evaluators that are sent requests as JSON on stdin as name=path to their executable"`
+	EvaluatorExtensions []string `arg:"--evaluator-extension,env:CICERO_EVALUATOR_EXTENSIONS" help:"evaluators for sources that do not name one and contain a file with the extension as ext=name, like py=python or cue=cue for the built-in CUE evaluator"`
+
+	EvaluatorPoolSize         int `arg:"--evaluator-pool-size,env:CICERO_EVALUATOR_POOL_SIZE" help:"how many processes of each evaluator to keep running between evaluations, 0 starts one per evaluation"`
+	EvaluatorPoolRecycleAfter int `arg:"--evaluator-pool-recycle-after,env:CICERO_EVALUATOR_POOL_RECYCLE_AFTER" default:"100" help:"how many evaluations a warm evaluator process runs before it is replaced, 0 for unlimited"`
+
+	WebListen     string `arg:"--web-listen,env:CICERO_WEB_LISTEN" default:":8080"`
+	MetricsListen string `arg:"--metrics-listen,env:CICERO_METRICS_LISTEN" help:"address to serve Prometheus metrics on, disabled if empty"`
+
+	WebTLSCert     string `arg:"--web-tls-cert,env:CICERO_WEB_TLS_CERT" help:"serve HTTPS with this certificate"`
+	WebTLSKey      string `arg:"--web-tls-key,env:CICERO_WEB_TLS_KEY"`
+	WebTLSClientCA string `arg:"--web-tls-client-ca,env:CICERO_WEB_TLS_CLIENT_CA" help:"verify client certificates against this CA"`
+
+	WebAuth             []string `arg:"--web-auth,env:CICERO_WEB_AUTH" help:"authentication methods to try in order, any of: basic, bearer, cert, oidc, token; none means no authentication"`
+	WebAuthBasicFile    string   `arg:"--web-auth-basic-file,env:CICERO_WEB_AUTH_BASIC_FILE" help:"file with user:bcrypt-hash lines"`
+	WebAuthBearerFile   string   `arg:"--web-auth-bearer-file,env:CICERO_WEB_AUTH_BEARER_FILE" help:"file with name:sha256-hex-of-token lines"`
+	WebAuthOIDCIssuer   string   `arg:"--web-auth-oidc-issuer,env:CICERO_WEB_AUTH_OIDC_ISSUER"`
+	WebAuthOIDCClientID string   `arg:"--web-auth-oidc-client-id,env:CICERO_WEB_AUTH_OIDC_CLIENT_ID"`
+	WebExecAllow        []string `arg:"--web-exec-allow,env:CICERO_WEB_EXEC_ALLOW" help:"authenticated identities that may execute commands in running Runs, * for all"`
+	WebPublicBadges     bool     `arg:"--web-public-badges,env:CICERO_WEB_PUBLIC_BADGES" help:"serve status badges of actions without authentication"`
+	WebTrustedProxies   []string `arg:"--web-trusted-proxies,env:CICERO_WEB_TRUSTED_PROXIES" help:"IP addresses or CIDR ranges of reverse proxies whose X-Forwarded-For, -Proto, and -Host headers are believed; others sending them are rejected"`
+
+	Dev bool `arg:"--dev,env:CICERO_DEV" help:"serve the web UI's templates and static files from the source in the working directory and reload pages when they change"`
+
+	AuditLogLoki   bool   `arg:"--audit-log-loki,env:CICERO_AUDIT_LOG_LOKI" help:"also send the audit log of requests that may change something to Loki"`
+	AuditLogSyslog string `arg:"--audit-log-syslog,env:CICERO_AUDIT_LOG_SYSLOG" help:"also send the audit log to syslog at tcp://host:port, udp://host:port, or local; disabled if empty"`
+
+	FactValueLimit  int64         `arg:"--fact-value-limit,env:CICERO_FACT_VALUE_LIMIT" help:"maximum size of a fact's value in bytes, 0 for unlimited"`
+	FactBinaryLimit int64         `arg:"--fact-binary-limit,env:CICERO_FACT_BINARY_LIMIT" help:"maximum size of a fact's binary in bytes, 0 for unlimited"`
+	FactDedupWindow time.Duration `arg:"--fact-dedup-window,env:CICERO_FACT_DEDUP_WINDOW" help:"coalesce facts published to the API or received from fact sources with an identical one from the same source saved within this time, 0 disables it"`
+
+	NomadClusters []string `arg:"--nomad-cluster,env:CICERO_NOMAD_CLUSTERS" help:"Nomad clusters as name=address in order of preference, the first is the default; an empty address uses NOMAD_ADDR"`
+
+	NomadSchedulingFile string `arg:"--nomad-scheduling-file,env:CICERO_NOMAD_SCHEDULING_FILE" help:"JSON file with constraints, affinities, and spreads to add to all jobs, like the nomad_scheduling meta attribute of actions"`
+
+	FactSourceFile string `arg:"--fact-source-file,env:CICERO_FACT_SOURCE_FILE" help:"JSON file with a list of NATS subjects to ingest facts from and how to map their messages to facts"`
+
+	AdmissionPolicies []string `arg:"--admission-policy,env:CICERO_ADMISSION_POLICIES" help:"Rego policy files or directories that decide whether Runs' jobs are submitted to Nomad, evaluated with opa"`
+	AdmissionQuery    string   `arg:"--admission-query,env:CICERO_ADMISSION_QUERY" default:"data.cicero.admission.deny" help:"Rego query for the reasons to deny a job"`
+
+	SealKeyFile string `arg:"--seal-key-file,env:CICERO_SEAL_KEY_FILE" help:"file with the private key to unseal values sealed with cicero seal, see cicero seal --generate-key"`
+
+	BootstrapDir string `arg:"--bootstrap-dir,env:CICERO_BOOTSTRAP_DIR" help:"directory with YAML or JSON files declaring actions, maintenance windows, API tokens, and facts to apply on startup"`
+
+	NomadEventQueueSize int  `arg:"--nomad-event-queue-size,env:CICERO_NOMAD_EVENT_QUEUE_SIZE" default:"1000" help:"how many Nomad events may wait to be processed before those that do not affect Runs are dropped"`
+	NomadEventShards    bool `arg:"--nomad-event-shards,env:CICERO_NOMAD_EVENT_SHARDS" help:"consume each topic of Nomad events in its own stream in parallel"`
+
+	NomadEvents     []string `arg:"--nomad-events,env:CICERO_NOMAD_EVENTS" help:"Nomad events to save and handle as Topic or Topic:Type, * for all; defaults to Allocation Job Deployment Evaluation"`
+	NomadEventsSkip []string `arg:"--nomad-events-skip,env:CICERO_NOMAD_EVENTS_SKIP" help:"Nomad events to neither save nor handle as Topic or Topic:Type, like Evaluation; those needed to end Runs cannot be skipped"`
+
+	NomadEventHandlerInterval  time.Duration `arg:"--nomad-event-handler-interval,env:CICERO_NOMAD_EVENT_HANDLER_INTERVAL" default:"10s" help:"how often compiled-in Nomad event handlers look for stored events they did not handle yet in case a notification was missed"`
+	NomadEventHandlerBatchSize int           `arg:"--nomad-event-handler-batch-size,env:CICERO_NOMAD_EVENT_HANDLER_BATCH_SIZE" default:"100" help:"how many Nomad events to hand to such a handler at once"`
+
+	NomadGCInterval   time.Duration `arg:"--nomad-gc-interval,env:CICERO_NOMAD_GC_INTERVAL" default:"10m" help:"how often to check for garbage collected Nomad jobs, 0 disables it"`
+	NomadGCPurgeAfter time.Duration `arg:"--nomad-gc-purge-after,env:CICERO_NOMAD_GC_PURGE_AFTER" help:"purge Nomad jobs of Runs that finished this long ago, 0 leaves it to Nomad"`
+
+	RunWatchdogInterval   time.Duration `arg:"--run-watchdog-interval,env:CICERO_RUN_WATCHDOG_INTERVAL" default:"5m" help:"how often to look for Runs that seem stuck, 0 disables it"`
+	RunWatchdogStuckAfter time.Duration `arg:"--run-watchdog-stuck-after,env:CICERO_RUN_WATCHDOG_STUCK_AFTER" default:"1h" help:"how long a Run's allocations may have no new Nomad events and log lines before it is suspect"`
+	RunWatchdogAction     string        `arg:"--run-watchdog-action,env:CICERO_RUN_WATCHDOG_ACTION" help:"what to do with suspect Runs besides marking them, any of: restart, cancel; empty does nothing"`
+
+	RunHeartbeatInterval time.Duration `arg:"--run-heartbeat-interval,env:CICERO_RUN_HEARTBEAT_INTERVAL" help:"how often to publish a heartbeat fact for each Run that has been running this long, 0 disables it"`
+
+	RunReconcileInterval time.Duration `arg:"--run-reconcile-interval,env:CICERO_RUN_RECONCILE_INTERVAL" default:"1h" help:"how often to end Runs whose Nomad job vanished or died without Cicero noticing, 0 disables it"`
+	RunReconcileGrace    time.Duration `arg:"--run-reconcile-grace,env:CICERO_RUN_RECONCILE_GRACE" default:"10m" help:"how old Runs must be to be reconciled"`
+
+	RunMutexInterval time.Duration `arg:"--run-mutex-interval,env:CICERO_RUN_MUTEX_INTERVAL" default:"1m" help:"how often to pass mutexes of actions on to the next waiting Run in case a notification from the database was missed"`
+
+	RunApprovalInterval time.Duration `arg:"--run-approval-interval,env:CICERO_RUN_APPROVAL_INTERVAL" default:"1m" help:"how often to reject Runs that waited for approval longer than their action's timeout"`
+
+	NomadTokenTTL      time.Duration `arg:"--nomad-token-ttl,env:CICERO_NOMAD_TOKEN_TTL" default:"1h" help:"how long the Nomad tokens minted for Runs are valid unless their action says otherwise"`
+	NomadTokenInterval time.Duration `arg:"--nomad-token-interval,env:CICERO_NOMAD_TOKEN_INTERVAL" default:"1m" help:"how often to revoke Nomad tokens that expired or whose Run ended"`
+
+	RunQueueLimit    int           `arg:"--run-queue-limit,env:CICERO_RUN_QUEUE_LIMIT" help:"how many Runs may run at once, others wait in a queue that is shared fairly between projects and their actions; 0 does not limit them"`
+	RunQueueWeights  []string      `arg:"--run-queue-weight,env:CICERO_RUN_QUEUE_WEIGHTS" help:"shares of projects in the queue as project=weight, like infra=3; projects that are not listed have a weight of 1"`
+	RunQueueInterval time.Duration `arg:"--run-queue-interval,env:CICERO_RUN_QUEUE_INTERVAL" default:"1m" help:"how often to take Runs off the queue in case a notification from the database was missed, which is also how long Runs may wait after a maintenance window closed"`
+
+	MaintenanceICalInterval time.Duration `arg:"--maintenance-ical-interval,env:CICERO_MAINTENANCE_ICAL_INTERVAL" default:"15m" help:"how often to fetch the iCalendar feeds of maintenance windows"`
+
+	EnvironmentInterval time.Duration `arg:"--environment-interval,env:CICERO_ENVIRONMENT_INTERVAL" default:"1m" help:"how often to tear down preview environments that expired or were closed by a fact"`
+
+	RunLogArchiveInterval time.Duration `arg:"--run-log-archive-interval,env:CICERO_RUN_LOG_ARCHIVE_INTERVAL" help:"how often to copy the logs of finished Runs from Loki to the database so that they outlive Loki's retention, 0 disables it"`
+	RunAnnotateInterval   time.Duration `arg:"--run-annotate-interval,env:CICERO_RUN_ANNOTATE_INTERVAL" default:"1m" help:"how often to scan the logs of finished Runs with the log matchers of their action, 0 disables it"`
+
+	RunTimingInterval time.Duration `arg:"--run-timing-interval,env:CICERO_RUN_TIMING_INTERVAL" default:"1m" help:"how often to measure how long finished Runs were queued, pending, executing, and finalizing, 0 disables it"`
+
+	RunUsageInterval  time.Duration `arg:"--run-usage-interval,env:CICERO_RUN_USAGE_INTERVAL" default:"10m" help:"how often to measure the resources used by finished Runs, 0 disables it"`
+	CostCPUHour       float64       `arg:"--cost-cpu-hour,env:CICERO_COST_CPU_HOUR" help:"cost of one CPU core used for an hour"`
+	CostMemoryGiBHour float64       `arg:"--cost-memory-gib-hour,env:CICERO_COST_MEMORY_GIB_HOUR" help:"cost of one GiB of memory used for an hour"`
+
+	SizingHeadroom float64 `arg:"--sizing-headroom,env:CICERO_SIZING_HEADROOM" default:"0.2" help:"fraction added to the 95th percentile of the peak resources used by an action's Runs to recommend its resources"`
+	SizingCPUMHz   int     `arg:"--sizing-cpu-mhz,env:CICERO_SIZING_CPU_MHZ" default:"2000" help:"speed of a CPU core in MHz to convert the measured CPU usage to Nomad's resources"`
+
+	DigestInterval     time.Duration `arg:"--digest-interval,env:CICERO_DIGEST_INTERVAL" default:"10m" help:"how often to look for digests of failed Runs to email"`
+	DigestSMTPAddr     string        `arg:"--digest-smtp-addr,env:CICERO_DIGEST_SMTP_ADDR" help:"host:port of the SMTP server to send digests of failed Runs with, disabled if empty"`
+	DigestSMTPUser     string        `arg:"--digest-smtp-user,env:CICERO_DIGEST_SMTP_USER" help:"authenticate to the SMTP server as this user"`
+	DigestSMTPPassword string        `arg:"--digest-smtp-password,env:CICERO_DIGEST_SMTP_PASSWORD"`
+	DigestFrom         string        `arg:"--digest-from,env:CICERO_DIGEST_FROM" help:"sender address of digests"`
+	DigestBaseURL      string        `arg:"--digest-base-url,env:CICERO_DIGEST_BASE_URL" default:"http://localhost:8080" help:"URL of the web UI to link to in digests"`
+
+	WatchInterval time.Duration `arg:"--watch-interval,env:CICERO_WATCH_INTERVAL" default:"1m" help:"how often to look for ended Runs to email their watchers about, uses the digest SMTP server"`
+
+	AlertmanagerURL           string        `arg:"--alertmanager-url,env:CICERO_ALERTMANAGER_URL" help:"URL of an Alertmanager to send alerts about failing Runs and Nomad event lag to, disabled if empty"`
+	AlertmanagerInterval      time.Duration `arg:"--alertmanager-interval,env:CICERO_ALERTMANAGER_INTERVAL" default:"1m" help:"how often to check for alerts and repeat those still firing"`
+	AlertmanagerFailureStreak int           `arg:"--alertmanager-failure-streak,env:CICERO_ALERTMANAGER_FAILURE_STREAK" default:"3" help:"how many Runs of an action must fail in a row to alert, 0 disables it"`
+	AlertmanagerEventLag      time.Duration `arg:"--alertmanager-event-lag,env:CICERO_ALERTMANAGER_EVENT_LAG" default:"5m" help:"how long Nomad events may wait to be processed before alerting, 0 disables it"`
+	AlertmanagerLabels        []string      `arg:"--alertmanager-label,env:CICERO_ALERTMANAGER_LABELS" help:"labels to add to all alerts as name=value, like env=prod"`
+	AlertmanagerBaseURL       string        `arg:"--alertmanager-base-url,env:CICERO_ALERTMANAGER_BASE_URL" default:"http://localhost:8080" help:"URL of the web UI to link to in alerts"`
 
-	WebListen string `arg:"--web-listen,env:CICERO_WEB_LISTEN" default:":8080"`
+	ReadOnly bool `arg:"--read-only,env:CICERO_READ_ONLY" help:"only serve requests that read, for example from a standby replica of the database; writes are refused and Nomad events and fact sources are not processed"`
+
+	AllowNewerSchema bool `arg:"--allow-newer-schema,env:CICERO_ALLOW_NEWER_SCHEMA" help:"serve writes even if the database has migrations this version does not know, for example while rolling out a newer version"`
+
+	Migrate bool `arg:"--migrate,env:CICERO_MIGRATE" help:"apply the migrations built into this binary that were not applied yet before starting, so that dbmate is not needed"`
+
+	FactBinaryColdDir      string        `arg:"--fact-binary-cold-dir,env:CICERO_FACT_BINARY_COLD_DIR" help:"directory to store cold fact binaries in, compressed; without it binaries are at most warm"`
+	FactBinaryTierInterval time.Duration `arg:"--fact-binary-tier-interval,env:CICERO_FACT_BINARY_TIER_INTERVAL" default:"1h" help:"how often to move fact binaries between storage tiers according to the fact_binary_tiers runtime setting, 0 disables it"`
+
+	CircuitBreakerFailures  int           `arg:"--circuit-breaker-failures,env:CICERO_CIRCUIT_BREAKER_FAILURES" default:"5" help:"how many calls to Loki, VictoriaMetrics, or a Nomad cluster must fail in a row to stop calling it for a while, 0 disables it"`
+	CircuitBreakerCooldown  time.Duration `arg:"--circuit-breaker-cooldown,env:CICERO_CIRCUIT_BREAKER_COOLDOWN" default:"30s" help:"how long to stop calling a failing dependency before trying again"`
+	CircuitBreakerCacheSize int           `arg:"--circuit-breaker-cache-size,env:CICERO_CIRCUIT_BREAKER_CACHE_SIZE" default:"100" help:"how many responses of Loki and VictoriaMetrics to keep to answer from while they fail"`
+
+	RuntimeConfigFile string `arg:"--runtime-config-file,env:CICERO_RUNTIME_CONFIG_FILE" help:"JSON file with settings that override the above and are reloaded on SIGHUP: log_level, fact_value_limit, fact_binary_limit, fact_binary_tiers, fact_redactions, fact_ingest, nomad_gc_purge_after, cost_cpu_hour, cost_memory_gib_hour, log_levels"`
 
 	LogDb bool `arg:"--log-db"`
+
+	LogLevels *config.LogLevels `arg:"-"`
+}
+
+func (cmd *StartCmd) Validate() error {
+	for _, name := range cmd.Components {
+		switch name {
+		case "nomad", "web":
+		default:
+			return config.KeyError{Key: "start.components", Err: errors.Errorf("unknown component %q", name)}
+		}
+		if name == "nomad" && cmd.ReadOnly {
+			return config.KeyError{Key: "start.components", Err: errors.New("cannot process Nomad events in read-only mode")}
+		}
+	}
+	if cmd.Migrate && cmd.ReadOnly {
+		return config.KeyError{Key: "start.migrate", Err: errors.New("cannot apply migrations in read-only mode")}
+	}
+	if (cmd.WebTLSCert == "") != (cmd.WebTLSKey == "") {
+		return config.KeyError{Key: "start.web-tls-key", Err: errors.New("must be given together with the TLS certificate")}
+	}
+	if _, err := service.ParseStdioEvaluators(cmd.EvaluatorExecs, cmd.EvaluatorExtensions); err != nil {
+		return config.KeyError{Key: "start.evaluator-exec", Err: err}
+	}
+	if cmd.EvaluatorPoolSize < 0 {
+		return config.KeyError{Key: "start.evaluator-pool-size", Err: errors.New("must not be negative")}
+	}
+	if cmd.NomadEventQueueSize <= 0 {
+		return config.KeyError{Key: "start.nomad-event-queue-size", Err: errors.New("must be positive")}
+	}
+	if filter, err := domain.ParseNomadEventFilter(cmd.NomadEvents, cmd.NomadEventsSkip); err != nil {
+		return config.KeyError{Key: "start.nomad-events", Err: err}
+	} else if err := filter.Validate(); err != nil {
+		return config.KeyError{Key: "start.nomad-events-skip", Err: err}
+	}
+	if cmd.RunWatchdogInterval > 0 && cmd.RunWatchdogStuckAfter <= 0 {
+		return config.KeyError{Key: "start.run-watchdog-stuck-after", Err: errors.New("must be positive")}
+	}
+	if cmd.RunReconcileGrace < 0 {
+		return config.KeyError{Key: "start.run-reconcile-grace", Err: errors.New("must not be negative")}
+	}
+	if _, err := web.ParseTrustedProxies(cmd.WebTrustedProxies); err != nil {
+		return config.KeyError{Key: "start.web-trusted-proxies", Err: err}
+	}
+	if _, _, err := cmd.auditLogSyslogAddr(); cmd.AuditLogSyslog != "" && err != nil {
+		return config.KeyError{Key: "start.audit-log-syslog", Err: err}
+	}
+	if cmd.CircuitBreakerFailures < 0 {
+		return config.KeyError{Key: "start.circuit-breaker-failures", Err: errors.New("must not be negative")}
+	}
+	if cmd.CircuitBreakerCooldown <= 0 {
+		return config.KeyError{Key: "start.circuit-breaker-cooldown", Err: errors.New("must be positive")}
+	}
+	if cmd.CircuitBreakerCacheSize < 0 {
+		return config.KeyError{Key: "start.circuit-breaker-cache-size", Err: errors.New("must not be negative")}
+	}
+	if cmd.NomadEventHandlerInterval <= 0 {
+		return config.KeyError{Key: "start.nomad-event-handler-interval", Err: errors.New("must be positive")}
+	}
+	if cmd.NomadEventHandlerBatchSize <= 0 {
+		return config.KeyError{Key: "start.nomad-event-handler-batch-size", Err: errors.New("must be positive")}
+	}
+	if cmd.RunMutexInterval <= 0 {
+		return config.KeyError{Key: "start.run-mutex-interval", Err: errors.New("must be positive")}
+	}
+	if cmd.RunApprovalInterval <= 0 {
+		return config.KeyError{Key: "start.run-approval-interval", Err: errors.New("must be positive")}
+	}
+	if cmd.NomadTokenTTL <= 0 {
+		return config.KeyError{Key: "start.nomad-token-ttl", Err: errors.New("must be positive")}
+	}
+	if cmd.NomadTokenInterval <= 0 {
+		return config.KeyError{Key: "start.nomad-token-interval", Err: errors.New("must be positive")}
+	}
+	if cmd.RunQueueLimit < 0 {
+		return config.KeyError{Key: "start.run-queue-limit", Err: errors.New("must not be negative")}
+	}
+	if cmd.RunQueueInterval <= 0 {
+		return config.KeyError{Key: "start.run-queue-interval", Err: errors.New("must be positive")}
+	}
+	if cmd.MaintenanceICalInterval <= 0 {
+		return config.KeyError{Key: "start.maintenance-ical-interval", Err: errors.New("must be positive")}
+	}
+	if _, err := domain.ParseRunQueueWeights(cmd.RunQueueWeights); err != nil {
+		return config.KeyError{Key: "start.run-queue-weight", Err: err}
+	}
+	if cmd.SizingHeadroom < 0 {
+		return config.KeyError{Key: "start.sizing-headroom", Err: errors.New("must not be negative")}
+	}
+	if cmd.SizingCPUMHz <= 0 {
+		return config.KeyError{Key: "start.sizing-cpu-mhz", Err: errors.New("must be positive")}
+	}
+	if cmd.EnvironmentInterval <= 0 {
+		return config.KeyError{Key: "start.environment-interval", Err: errors.New("must be positive")}
+	}
+	if cmd.DigestSMTPAddr != "" {
+		if cmd.DigestFrom == "" {
+			return config.KeyError{Key: "start.digest-from", Err: errors.New("must be given together with the SMTP server")}
+		}
+		if cmd.DigestInterval <= 0 {
+			return config.KeyError{Key: "start.digest-interval", Err: errors.New("must be positive")}
+		}
+		if cmd.WatchInterval <= 0 {
+			return config.KeyError{Key: "start.watch-interval", Err: errors.New("must be positive")}
+		}
+	}
+	if cmd.AlertmanagerURL != "" {
+		if cmd.AlertmanagerInterval <= 0 {
+			return config.KeyError{Key: "start.alertmanager-interval", Err: errors.New("must be positive")}
+		}
+		if cmd.AlertmanagerFailureStreak < 0 {
+			return config.KeyError{Key: "start.alertmanager-failure-streak", Err: errors.New("must not be negative")}
+		}
+		if _, err := component.ParseAlertLabels(cmd.AlertmanagerLabels); err != nil {
+			return config.KeyError{Key: "start.alertmanager-label", Err: err}
+		}
+	}
+	switch component.RunWatchdogAction(cmd.RunWatchdogAction) {
+	case component.RunWatchdogNone, component.RunWatchdogRestart, component.RunWatchdogCancel:
+	default:
+		return config.KeyError{Key: "start.run-watchdog-action", Err: errors.Errorf("unknown action %q", cmd.RunWatchdogAction)}
+	}
+	return nil
 }
 
 func (cmd *StartCmd) Run(logger *zerolog.Logger) error {
@@ -63,6 +326,40 @@ func (cmd *StartCmd) Run(logger *zerolog.Logger) error {
 		cmd.Evaluators = []string{"nix"}
 	}
 
+	logLevel, componentLogLevels := cmd.LogLevels.Get()
+	logLevels := make(map[string]string, len(componentLogLevels))
+	for component, level := range componentLogLevels {
+		logLevels[component] = level.String()
+	}
+
+	bootstrap, err := cmd.bootstrap()
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+		return err
+	}
+
+	runtimeConfig, err := config.NewRuntimeConfig(cmd.RuntimeConfigFile, config.Runtime{
+		LogLevel:          logLevel.String(),
+		LogLevels:         logLevels,
+		FactValueLimit:    cmd.FactValueLimit,
+		FactBinaryLimit:   cmd.FactBinaryLimit,
+		FactDedupWindow:   config.Duration(cmd.FactDedupWindow),
+		NomadGCPurgeAfter: config.Duration(cmd.NomadGCPurgeAfter),
+		CostCPUHour:       cmd.CostCPUHour,
+		CostMemoryGiBHour: cmd.CostMemoryGiBHour,
+		// The runtime config file may override them.
+		MaintenanceWindows: bootstrap.MaintenanceWindows,
+	})
+	if err != nil {
+		return err
+	}
+	runtimeConfig.OnReload(func(runtime config.Runtime) {
+		// already validated
+		level, _ := zerolog.ParseLevel(runtime.LogLevel)
+		componentLevels, _ := runtime.ComponentLogLevels()
+		cmd.LogLevels.Set(level, componentLevels)
+	})
+
 	var db config.PgxIface
 	if db_, err := config.DBConnection(logger, cmd.LogDb); err != nil {
 		logger.Fatal().Err(err).Send()
@@ -71,14 +368,57 @@ func (cmd *StartCmd) Run(logger *zerolog.Logger) error {
 		db = db_
 	}
 
-	var nomadClient *nomad.Client
-	if client, err := config.NewNomadClient(); err != nil {
+	if cmd.Migrate {
+		if err := config.Migrate(context.Background(), db, migrations.Migrations, "migrations", logger); err != nil {
+			logger.Fatal().Err(err).Send()
+			return err
+		}
+	}
+
+	schema, err := config.GetSchemaStatus(context.Background(), db, migrations.Migrations, "migrations")
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+		return err
+	}
+	// Keep serving reads so that the mismatch can be seen on /readyz.
+	schemaErr := schema.Check(cmd.AllowNewerSchema)
+	if schemaErr != nil {
+		logger.Error().Err(schemaErr).Interface("schema", schema).Msg("Refusing writes and not processing Nomad events")
+		start.nomadEvent = false
+	} else if schema.Newer() {
+		logger.Warn().Interface("schema", schema).Msg("Database schema is newer than expected")
+	}
+	if cmd.ReadOnly {
+		logger.Info().Msg("Read-only mode, refusing writes and not processing Nomad events")
+		start.nomadEvent = false
+	}
+
+	nomadClusters, err := cmd.nomadClusters(logger)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+		return err
+	}
+
+	// already validated
+	nomadEventFilter, _ := domain.ParseNomadEventFilter(cmd.NomadEvents, cmd.NomadEventsSkip)
+
+	jobScheduling, err := cmd.jobScheduling()
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+		return err
+	}
+
+	factSources, err := cmd.factSources()
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+		return err
+	}
+
+	unsealer, err := cmd.unsealer()
+	if err != nil {
 		logger.Fatal().Err(err).Send()
 		return err
-	} else {
-		nomadClient = client
 	}
-	nomadClientWrapper := application.NewNomadClient(nomadClient)
 
 	var prometheusClient prometheus.Client
 	if client, err := prometheus.NewClient(prometheus.Config{
@@ -87,7 +427,19 @@ func (cmd *StartCmd) Run(logger *zerolog.Logger) error {
 		logger.Fatal().Err(err).Send()
 		return err
 	} else {
-		prometheusClient = client
+		breaker := application.NewCircuitBreaker("loki", cmd.CircuitBreakerFailures, cmd.CircuitBreakerCooldown, logger)
+		prometheusClient = application.NewCircuitBreakingApiClient(client, breaker, cmd.CircuitBreakerCacheSize)
+	}
+
+	var victoriaMetricsClient prometheus.Client
+	if client, err := prometheus.NewClient(prometheus.Config{
+		Address: cmd.VictoriaMetricsAddr,
+	}); err != nil {
+		logger.Fatal().Err(err).Send()
+		return err
+	} else {
+		breaker := application.NewCircuitBreaker("victoriametrics", cmd.CircuitBreakerFailures, cmd.CircuitBreakerCooldown, logger)
+		victoriaMetricsClient = application.NewCircuitBreakingApiClient(client, breaker, cmd.CircuitBreakerCacheSize)
 	}
 
 	var promtailClient promtailClient.Client
@@ -107,41 +459,484 @@ func (cmd *StartCmd) Run(logger *zerolog.Logger) error {
 	// These don't cyclically depend on other services so we don't need to put them behind a pointer.
 	lokiService := service.NewLokiService(prometheusClient, logger)
 	nomadEventService := service.NewNomadEventService(db, logger)
-	runService := service.NewRunService(db, lokiService, nomadEventService, cmd.VictoriaMetricsAddr, nomadClientWrapper, logger)
-	evaluationService := service.NewEvaluationService(cmd.Evaluators, cmd.Transformers, promtailClient.Chan(), logger)
+	runService := service.NewRunService(db, lokiService, nomadEventService, victoriaMetricsClient, nomadClusters, logger)
+	// already validated
+	stdioEvaluators, _ := service.ParseStdioEvaluators(cmd.EvaluatorExecs, cmd.EvaluatorExtensions)
+	evaluationService := service.NewEvaluationService(cmd.Evaluators, cmd.Transformers, stdioEvaluators, cmd.EvaluatorPoolSize, cmd.EvaluatorPoolRecycleAfter, runtimeConfig, promtailClient.Chan(), logger)
+	costService := service.NewCostService(db, runService, victoriaMetricsClient, runtimeConfig, logger)
+	runTimingService := service.NewRunTimingService(db, nomadEventService, logger)
+	if cmd.EvaluationCache {
+		evaluationService = service.NewCachingEvaluationService(evaluationService, db, logger)
+	}
+
+	quotaService := service.NewQuotaService(db, logger)
+	digestService := service.NewDigestService(db, runService, logger)
+	watchService := service.NewWatchService(db, runService, logger)
+	alertService := service.NewAlertService(db, logger)
+	runMutexService := service.NewRunMutexService(db, runService, logger)
+	runApprovalService := service.NewRunApprovalService(db, runService, logger)
+	runNomadTokenService := service.NewRunNomadTokenService(db, nomadClusters, cmd.NomadTokenTTL, logger)
+
+	maintenanceService := service.NewMaintenanceService(runtimeConfig, logger)
+
+	// The queue is also used to hold Runs during maintenance windows.
+	weights, err := domain.ParseRunQueueWeights(cmd.RunQueueWeights)
+	if err != nil {
+		return err
+	}
+	runQueueService := service.NewRunQueueService(db, runService, maintenanceService, cmd.RunQueueLimit, weights, logger)
+
+	sizingService := service.NewSizingService(db, cmd.SizingHeadroom, cmd.SizingCPUMHz, logger)
 
 	*invocationService = service.NewInvocationService(db, lokiService, actionService, factService, logger)
-	*actionService = service.NewActionService(db, nomadClientWrapper, invocationService, factService, runService, evaluationService, logger)
-	*factService = service.NewFactService(db, actionService, logger)
+	admissionHooks := service.AdmissionHooks{
+		service.SealAdmissionHook{Unsealer: unsealer},
+		service.QuotaAdmissionHook{QuotaService: quotaService},
+	}
+	if len(cmd.AdmissionPolicies) > 0 {
+		admissionHooks = append(admissionHooks, service.OPAAdmissionHook{
+			Policies: cmd.AdmissionPolicies,
+			Query:    cmd.AdmissionQuery,
+			Logger:   logger.With().Str("component", "OPAAdmissionHook").Logger(),
+		})
+	}
+
+	*actionService = service.NewActionService(db, nomadClusters, invocationService, factService, runService, runMutexService, runApprovalService, runQueueService, sizingService, runNomadTokenService, evaluationService, jobScheduling, admissionHooks, unsealer, logger)
+	factBinaryService := service.NewFactBinaryService(db, cmd.FactBinaryColdDir, logger)
+	*factService = service.NewFactService(db, actionService, factBinaryService, runtimeConfig, unsealer, logger)
+	environmentService := service.NewEnvironmentService(db, *factService, *invocationService, logger)
+	runAnnotationService := service.NewRunAnnotationService(db, runService, actionService, logger)
+	apiTokenService := service.NewApiTokenService(db, logger)
+
+	// The meta of actions can only be known by evaluating them
+	// so it is backfilled here instead of in a migration.
+	if schemaErr == nil && !cmd.ReadOnly {
+		go func() {
+			if err := (*actionService).BackfillMeta(); err != nil {
+				logger.Err(err).Msg("Could not backfill meta of actions")
+			}
+		}()
+	}
+
+	if cmd.BootstrapDir != "" {
+		if schemaErr != nil || cmd.ReadOnly {
+			logger.Warn().Str("dir", cmd.BootstrapDir).Msg("Not applying bootstrap directory because writes are refused")
+		} else if err := service.NewBootstrapService(db, *actionService, *factService, apiTokenService, logger).Apply(bootstrap); err != nil {
+			logger.Fatal().Err(err).Send()
+			return err
+		}
+	}
 
 	supervisor := cmd.newSupervisor(logger)
 
 	if start.nomadEvent {
-		child := component.NomadEventConsumer{
-			Logger:            logger.With().Str("component", "NomadEventConsumer").Logger(),
-			RunService:        runService,
-			NomadEventService: nomadEventService,
-			FactService:       *factService,
-			InvocationService: *invocationService,
-			NomadClient:       nomadClientWrapper,
-			Db:                db,
+		for _, cluster := range nomadClusters {
+			child := component.NomadEventConsumer{
+				Logger:             logger.With().Str("component", "NomadEventConsumer").Str("nomad-cluster", cluster.Name).Logger(),
+				RunService:         runService,
+				NomadEventService:  nomadEventService,
+				FactService:        *factService,
+				InvocationService:  *invocationService,
+				ActionService:      *actionService,
+				EnvironmentService: environmentService,
+				NomadCluster:       cluster,
+				NomadClusterNames:  nomadClusters.Aliases(cluster.Name),
+				Db:                 db,
+				QueueSize:          cmd.NomadEventQueueSize,
+				EventFilter:        nomadEventFilter,
+				ShardByTopic:       cmd.NomadEventShards,
+			}
+			if err := supervisor.Add(child.Start); err != nil {
+				return err
+			}
 		}
-		if err := supervisor.Add(child.Start); err != nil {
+
+		for _, handler := range component.NomadEventHandlers() {
+			runner := component.NomadEventHandlerRunner{
+				Logger:            logger.With().Str("component", "NomadEventHandlerRunner").Str("handler", handler.Name()).Logger(),
+				NomadEventService: nomadEventService,
+				Handler:           handler,
+				Db:                db,
+				Interval:          cmd.NomadEventHandlerInterval,
+				BatchSize:         cmd.NomadEventHandlerBatchSize,
+			}
+			if err := supervisor.Add(runner.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.NomadGCInterval > 0 {
+			gc := component.NomadGC{
+				Logger:        logger.With().Str("component", "NomadGC").Logger(),
+				RunService:    runService,
+				NomadClusters: nomadClusters,
+				Runtime:       runtimeConfig,
+				Interval:      cmd.NomadGCInterval,
+			}
+			if err := supervisor.Add(gc.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunWatchdogInterval > 0 {
+			watchdog := component.RunWatchdog{
+				Logger:            logger.With().Str("component", "RunWatchdog").Logger(),
+				RunService:        runService,
+				InvocationService: *invocationService,
+				Db:                db,
+				Interval:          cmd.RunWatchdogInterval,
+				StuckAfter:        cmd.RunWatchdogStuckAfter,
+				Action:            component.RunWatchdogAction(cmd.RunWatchdogAction),
+			}
+			if err := supervisor.Add(watchdog.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunHeartbeatInterval > 0 {
+			heartbeat := component.RunHeartbeat{
+				Logger:        logger.With().Str("component", "RunHeartbeat").Logger(),
+				RunService:    runService,
+				ActionService: *actionService,
+				FactService:   *factService,
+				Db:            db,
+				Interval:      cmd.RunHeartbeatInterval,
+			}
+			if err := supervisor.Add(heartbeat.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunReconcileInterval > 0 {
+			reconciler := component.RunReconciler{
+				Logger:     logger.With().Str("component", "RunReconciler").Logger(),
+				RunService: runService,
+				Interval:   cmd.RunReconcileInterval,
+				Grace:      cmd.RunReconcileGrace,
+			}
+			if err := supervisor.Add(reconciler.Start); err != nil {
+				return err
+			}
+		}
+
+		mutexScheduler := component.RunMutexScheduler{
+			Logger:          logger.With().Str("component", "RunMutexScheduler").Logger(),
+			RunMutexService: runMutexService,
+			RunService:      runService,
+			ActionService:   *actionService,
+			Db:              db,
+			Interval:        cmd.RunMutexInterval,
+		}
+		if err := supervisor.Add(mutexScheduler.Start); err != nil {
 			return err
 		}
+
+		approvalExpirer := component.RunApprovalExpirer{
+			Logger:             logger.With().Str("component", "RunApprovalExpirer").Logger(),
+			RunApprovalService: runApprovalService,
+			RunService:         runService,
+			ActionService:      *actionService,
+			Interval:           cmd.RunApprovalInterval,
+		}
+		if err := supervisor.Add(approvalExpirer.Start); err != nil {
+			return err
+		}
+
+		nomadTokenRevoker := component.RunNomadTokenRevoker{
+			Logger:               logger.With().Str("component", "RunNomadTokenRevoker").Logger(),
+			RunNomadTokenService: runNomadTokenService,
+			Interval:             cmd.NomadTokenInterval,
+		}
+		if err := supervisor.Add(nomadTokenRevoker.Start); err != nil {
+			return err
+		}
+
+		queueScheduler := component.RunQueueScheduler{
+			Logger:          logger.With().Str("component", "RunQueueScheduler").Logger(),
+			RunQueueService: runQueueService,
+			RunService:      runService,
+			ActionService:   *actionService,
+			Db:              db,
+			Interval:        cmd.RunQueueInterval,
+		}
+		if err := supervisor.Add(queueScheduler.Start); err != nil {
+			return err
+		}
+
+		calendar := component.MaintenanceCalendar{
+			Logger:             logger.With().Str("component", "MaintenanceCalendar").Logger(),
+			MaintenanceService: maintenanceService,
+			Runtime:            runtimeConfig,
+			Interval:           cmd.MaintenanceICalInterval,
+		}
+		if err := supervisor.Add(calendar.Start); err != nil {
+			return err
+		}
+
+		janitor := component.EnvironmentJanitor{
+			Logger:             logger.With().Str("component", "EnvironmentJanitor").Logger(),
+			EnvironmentService: environmentService,
+			Db:                 db,
+			Interval:           cmd.EnvironmentInterval,
+		}
+		if err := supervisor.Add(janitor.Start); err != nil {
+			return err
+		}
+
+		if cmd.DigestSMTPAddr != "" {
+			notifier := component.DigestNotifier{
+				Logger:        logger.With().Str("component", "DigestNotifier").Logger(),
+				DigestService: digestService,
+				Interval:      cmd.DigestInterval,
+				SMTPAddr:      cmd.DigestSMTPAddr,
+				SMTPUser:      cmd.DigestSMTPUser,
+				SMTPPassword:  cmd.DigestSMTPPassword,
+				From:          cmd.DigestFrom,
+				BaseURL:       cmd.DigestBaseURL,
+			}
+			if err := supervisor.Add(notifier.Start); err != nil {
+				return err
+			}
+
+			watchNotifier := component.WatchNotifier{
+				Logger:       logger.With().Str("component", "WatchNotifier").Logger(),
+				WatchService: watchService,
+				Interval:     cmd.WatchInterval,
+				SMTPAddr:     cmd.DigestSMTPAddr,
+				SMTPUser:     cmd.DigestSMTPUser,
+				SMTPPassword: cmd.DigestSMTPPassword,
+				From:         cmd.DigestFrom,
+				BaseURL:      cmd.DigestBaseURL,
+			}
+			if err := supervisor.Add(watchNotifier.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.AlertmanagerURL != "" {
+			labels, err := component.ParseAlertLabels(cmd.AlertmanagerLabels)
+			if err != nil {
+				return err
+			}
+
+			notifier := component.AlertNotifier{
+				Logger:        logger.With().Str("component", "AlertNotifier").Logger(),
+				AlertService:  alertService,
+				URL:           cmd.AlertmanagerURL,
+				Interval:      cmd.AlertmanagerInterval,
+				FailureStreak: cmd.AlertmanagerFailureStreak,
+				EventLag:      cmd.AlertmanagerEventLag,
+				Labels:        labels,
+				BaseURL:       cmd.AlertmanagerBaseURL,
+			}
+			if err := supervisor.Add(notifier.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunLogArchiveInterval > 0 {
+			archiver := component.RunLogArchiver{
+				Logger:     logger.With().Str("component", "RunLogArchiver").Logger(),
+				RunService: runService,
+				Interval:   cmd.RunLogArchiveInterval,
+			}
+			if err := supervisor.Add(archiver.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunAnnotateInterval > 0 {
+			annotator := component.RunLogAnnotator{
+				Logger:               logger.With().Str("component", "RunLogAnnotator").Logger(),
+				RunAnnotationService: runAnnotationService,
+				Interval:             cmd.RunAnnotateInterval,
+			}
+			if err := supervisor.Add(annotator.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunTimingInterval > 0 {
+			collector := component.RunTimingCollector{
+				Logger:           logger.With().Str("component", "RunTimingCollector").Logger(),
+				RunTimingService: runTimingService,
+				Interval:         cmd.RunTimingInterval,
+			}
+			if err := supervisor.Add(collector.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.RunUsageInterval > 0 {
+			collector := component.RunUsageCollector{
+				Logger:      logger.With().Str("component", "RunUsageCollector").Logger(),
+				CostService: costService,
+				Interval:    cmd.RunUsageInterval,
+			}
+			if err := supervisor.Add(collector.Start); err != nil {
+				return err
+			}
+		}
+
+		if cmd.FactBinaryTierInterval > 0 {
+			tierer := component.FactBinaryTierer{
+				Logger:            logger.With().Str("component", "FactBinaryTierer").Logger(),
+				FactBinaryService: factBinaryService,
+				Runtime:           runtimeConfig,
+				Cold:              cmd.FactBinaryColdDir != "",
+				Interval:          cmd.FactBinaryTierInterval,
+			}
+			if err := supervisor.Add(tierer.Start); err != nil {
+				return err
+			}
+		}
 	}
 
 	if start.web {
+
+		actionTemplateCatalogs := []fs.FS{}
+		if builtin, err := fs.Sub(actions.Templates, "templates"); err != nil {
+			return err
+		} else {
+			actionTemplateCatalogs = append(actionTemplateCatalogs, builtin)
+		}
+		for _, dir := range cmd.ActionTemplateDirs {
+			actionTemplateCatalogs = append(actionTemplateCatalogs, os.DirFS(dir))
+		}
+		actionTemplateService := service.NewActionTemplateService(actionTemplateCatalogs, logger)
+
+		authChain, err := auth.Config{
+			BasicFile:    cmd.WebAuthBasicFile,
+			BearerFile:   cmd.WebAuthBearerFile,
+			OIDCIssuer:   cmd.WebAuthOIDCIssuer,
+			OIDCClientID: cmd.WebAuthOIDCClientID,
+			Tokens: &auth.Token{
+				Prefix: domain.ApiTokenPrefix,
+				Verify: func(secret string) (*auth.Identity, error) {
+					if strings.HasPrefix(secret, domain.RunTokenPrefix) {
+						run, err := runService.AuthenticateToken(secret)
+						if run == nil || err != nil {
+							return nil, err
+						}
+						return &auth.Identity{Name: "run/" + run.NomadJobID.String(), Scopes: []string{domain.RunTokenScope(run.NomadJobID), domain.RunNomadTokenScope(run.NomadJobID)}}, nil
+					}
+
+					token, err := apiTokenService.Authenticate(secret)
+					if token == nil || err != nil {
+						return nil, err
+					}
+					return &auth.Identity{Name: token.Name, Scopes: token.Scopes, TokenId: &token.ID}, nil
+				},
+			},
+		}.Chain(cmd.WebAuth)
+		if err != nil {
+			return errors.WithMessage(err, "Invalid authentication configuration")
+		}
+
+		// The audit log cannot be written to while writes are refused.
+		var auditLogService service.AuditLogService
+		if schemaErr == nil && !cmd.ReadOnly {
+			var promtailChan chan<- promtail.Entry
+			if cmd.AuditLogLoki {
+				promtailChan = promtailClient.Chan()
+			}
+
+			var syslogWriter io.Writer
+			if cmd.AuditLogSyslog != "" {
+				// already validated
+				network, addr, _ := cmd.auditLogSyslogAddr()
+				if writer, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_AUTH, "cicero"); err != nil {
+					return errors.WithMessage(err, "Could not connect to syslog")
+				} else {
+					defer writer.Close()
+					syslogWriter = writer
+				}
+			}
+
+			auditLogService = service.NewAuditLogService(db, promtailChan, syslogWriter, logger)
+		}
+
+		assets := web.EmbeddedAssets()
+		if cmd.Dev {
+			if assets, err = web.DirAssets(webAssetsDir); err != nil {
+				return errors.WithMessage(err, "Cannot serve assets from source, run from the repository's root")
+			}
+		}
+
+		// already validated
+		trustedProxies, _ := web.ParseTrustedProxies(cmd.WebTrustedProxies)
+
 		child := web.Web{
-			Logger:            logger.With().Str("component", "Web").Logger(),
-			Listen:            cmd.WebListen,
-			InvocationService: *invocationService,
-			RunService:        runService,
-			ActionService:     *actionService,
-			FactService:       *factService,
-			NomadEventService: nomadEventService,
-			EvaluationService: evaluationService,
-			Db:                db,
+			Logger:                logger.With().Str("component", "Web").Logger(),
+			Listen:                cmd.WebListen,
+			InvocationService:     *invocationService,
+			RunService:            runService,
+			ActionService:         *actionService,
+			FactService:           *factService,
+			NomadEventService:     nomadEventService,
+			EvaluationService:     evaluationService,
+			ApiTokenService:       apiTokenService,
+			CostService:           costService,
+			RunTimingService:      runTimingService,
+			SizingService:         sizingService,
+			QuotaService:          quotaService,
+			DatabaseStatsService:  service.NewDatabaseStatsService(db, logger),
+			FactUsageService:      service.NewFactUsageService(db, logger),
+			SavedQueryService:     service.NewSavedQueryService(db, logger),
+			DigestService:         digestService,
+			WatchService:          watchService,
+			RunMutexService:       runMutexService,
+			RunApprovalService:    runApprovalService,
+			RunNomadTokenService:  runNomadTokenService,
+			RunQueueService:       runQueueService,
+			MaintenanceService:    maintenanceService,
+			RunAnnotationService:  runAnnotationService,
+			EnvironmentService:    environmentService,
+			ActionTemplateService: actionTemplateService,
+			FactPublisherService:  service.NewFactPublisherService(db, logger),
+			Db:                    db,
+			Schema:                schema,
+			SchemaError:           schemaErr,
+			ReadOnly:              cmd.ReadOnly,
+			Runtime:               runtimeConfig,
+			LogLevels:             cmd.LogLevels,
+			Auth:                  authChain,
+			ExecAllowed:           cmd.WebExecAllow,
+			PublicBadges:          cmd.WebPublicBadges,
+			TrustedProxies:        trustedProxies,
+			Unsealer:              unsealer,
+			AuditLogService:       auditLogService,
+			Assets:                assets,
+			TLS: web.TLS{
+				Cert:     cmd.WebTLSCert,
+				Key:      cmd.WebTLSKey,
+				ClientCA: cmd.WebTLSClientCA,
+			},
+		}
+		if err := supervisor.Add(child.Start); err != nil {
+			return err
+		}
+
+		// Facts cannot be saved while writes are refused.
+		if schemaErr == nil && !cmd.ReadOnly {
+			for i, source := range factSources {
+				consumer := component.FactSourceConsumer{
+					Logger:      logger.With().Str("component", "FactSourceConsumer").Int("fact-source", i).Logger(),
+					FactService: *factService,
+					Db:          db,
+					Source:      source,
+				}
+				if err := supervisor.Add(consumer.Start); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if cmd.MetricsListen != "" {
+		child := component.Metrics{
+			Logger: logger.With().Str("component", "Metrics").Logger(),
+			Listen: cmd.MetricsListen,
 		}
 		if err := supervisor.Add(child.Start); err != nil {
 			return err
@@ -155,10 +950,176 @@ func (cmd *StartCmd) Run(logger *zerolog.Logger) error {
 		return errors.WithMessage(err, "While starting supervisor")
 	}
 
+	go reloadOnSignal(ctx, logger, runtimeConfig)
+
 	<-ctx.Done()
 	return nil
 }
 
+// Returns empty strings for the local syslog daemon.
+func (cmd *StartCmd) auditLogSyslogAddr() (network, addr string, err error) {
+	if cmd.AuditLogSyslog == "local" {
+		return
+	}
+
+	network, addr, ok := strings.Cut(cmd.AuditLogSyslog, "://")
+	if !ok || addr == "" || (network != "tcp" && network != "udp") {
+		return "", "", errors.Errorf("invalid address %q, must be tcp://host:port, udp://host:port, or local", cmd.AuditLogSyslog)
+	}
+	return
+}
+
+func (cmd *StartCmd) nomadClusters(logger *zerolog.Logger) (application.NomadClusters, error) {
+	specs := cmd.NomadClusters
+	if len(specs) == 0 {
+		specs = []string{"default="}
+	}
+
+	clusters := make(application.NomadClusters, len(specs))
+	for i, spec := range specs {
+		name, address, ok := strings.Cut(spec, "=")
+		if !ok || name == "" {
+			return nil, errors.Errorf("Invalid Nomad cluster %q, expected name=address", spec)
+		}
+		if _, exists := clusters[:i].Get(name); exists {
+			return nil, errors.Errorf("Duplicate Nomad cluster %q", name)
+		}
+
+		client, err := config.NewNomadClient(address)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Could not create client for Nomad cluster %q", name)
+		}
+
+		breaker := application.NewCircuitBreaker("nomad/"+name, cmd.CircuitBreakerFailures, cmd.CircuitBreakerCooldown, logger)
+		clusters[i] = application.NomadCluster{
+			Name:        name,
+			NomadClient: application.NewCircuitBreakingNomadClient(application.NewNomadClient(client), breaker),
+		}
+	}
+
+	return clusters, nil
+}
+
+func (cmd *StartCmd) jobScheduling() (scheduling domain.JobScheduling, err error) {
+	if cmd.NomadSchedulingFile == "" {
+		return
+	}
+
+	content, err := os.ReadFile(cmd.NomadSchedulingFile)
+	if err != nil {
+		return scheduling, errors.WithMessagef(err, "Could not read Nomad scheduling file %q", cmd.NomadSchedulingFile)
+	}
+
+	if err := json.Unmarshal(content, &scheduling); err != nil {
+		return scheduling, errors.WithMessagef(err, "Could not parse Nomad scheduling file %q", cmd.NomadSchedulingFile)
+	}
+
+	err = errors.WithMessagef(scheduling.Validate(), "Invalid Nomad scheduling file %q", cmd.NomadSchedulingFile)
+	return
+}
+
+// Reads the files in the bootstrap directory in order of name
+// and resolves the secrets of tokens.
+func (cmd *StartCmd) bootstrap() (bootstrap domain.Bootstrap, err error) {
+	if cmd.BootstrapDir == "" {
+		return
+	}
+
+	entries, err := os.ReadDir(cmd.BootstrapDir)
+	if err != nil {
+		return bootstrap, errors.WithMessagef(err, "Could not read bootstrap directory %q", cmd.BootstrapDir)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+
+		path := filepath.Join(cmd.BootstrapDir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return bootstrap, errors.WithMessagef(err, "Could not read bootstrap file %q", path)
+		}
+
+		file, err := domain.ParseBootstrap(path, content)
+		if err != nil {
+			return bootstrap, errors.WithMessagef(err, "Could not parse bootstrap file %q", path)
+		}
+
+		for i, token := range file.Tokens {
+			switch {
+			case token.SecretEnv != "":
+				file.Tokens[i].Secret = os.Getenv(token.SecretEnv)
+				if file.Tokens[i].Secret == "" {
+					return bootstrap, errors.Errorf("Environment variable %q with the secret of token %q in %q is not set", token.SecretEnv, token.Name, path)
+				}
+			case token.SecretFile != "":
+				secretPath := token.SecretFile
+				if !filepath.IsAbs(secretPath) {
+					secretPath = filepath.Join(cmd.BootstrapDir, secretPath)
+				}
+				secret, err := os.ReadFile(secretPath)
+				if err != nil {
+					return bootstrap, errors.WithMessagef(err, "Could not read secret of token %q in %q", token.Name, path)
+				}
+				file.Tokens[i].Secret = strings.TrimSpace(string(secret))
+			}
+		}
+
+		bootstrap.Merge(file)
+	}
+
+	err = errors.WithMessagef(bootstrap.Validate(), "Invalid bootstrap directory %q", cmd.BootstrapDir)
+	return
+}
+
+func (cmd *StartCmd) factSources() (sources []component.FactSource, err error) {
+	if cmd.FactSourceFile == "" {
+		return
+	}
+
+	content, err := os.ReadFile(cmd.FactSourceFile)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not read fact source file %q", cmd.FactSourceFile)
+	}
+
+	if err := json.Unmarshal(content, &sources); err != nil {
+		return nil, errors.WithMessagef(err, "Could not parse fact source file %q", cmd.FactSourceFile)
+	}
+
+	for i, source := range sources {
+		if err := source.Validate(); err != nil {
+			return nil, errors.WithMessagef(err, "Invalid fact source %d in %q", i, cmd.FactSourceFile)
+		}
+	}
+
+	return
+}
+
+// Returns nil if no seal key file is given.
+func (cmd *StartCmd) unsealer() (*domain.Unsealer, error) {
+	if cmd.SealKeyFile == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(cmd.SealKeyFile)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Could not read seal key file %q", cmd.SealKeyFile)
+	}
+
+	key, err := domain.ParseSealKey(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Invalid seal key file %q", cmd.SealKeyFile)
+	}
+
+	return domain.NewUnsealer(*key)
+}
+
 func (cmd *StartCmd) newSupervisor(logger *zerolog.Logger) *oversight.Tree {
 	return oversight.New(
 		oversight.WithLogger(&config.SupervisorLogger{Logger: logger}),
@@ -169,3 +1130,22 @@ func (cmd *StartCmd) newSupervisor(logger *zerolog.Logger) *oversight.Tree {
 		),
 	)
 }
+
+func reloadOnSignal(ctx context.Context, logger *zerolog.Logger, runtimeConfig *config.RuntimeConfig) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := runtimeConfig.Reload(); err != nil {
+				logger.Err(err).Msg("Could not reload runtime configuration, keeping the current one")
+			} else {
+				logger.Info().Interface("runtime", runtimeConfig.Get()).Msg("Reloaded runtime configuration")
+			}
+		}
+	}
+}
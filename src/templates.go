@@ -0,0 +1,137 @@
+package cicero
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type TemplatesCmd struct {
+	List        *TemplatesListCmd        `arg:"subcommand:list" help:"list action templates"`
+	Show        *TemplatesShowCmd        `arg:"subcommand:show" help:"show an action template's parameters and source"`
+	Instantiate *TemplatesInstantiateCmd `arg:"subcommand:instantiate" help:"write the source of an action from a template"`
+}
+
+func (cmd *TemplatesCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.List != nil:
+		return cmd.List.Run(logger)
+	case cmd.Show != nil:
+		return cmd.Show.Run(logger)
+	case cmd.Instantiate != nil:
+		return cmd.Instantiate.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+type TemplatesListCmd struct {
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *TemplatesListCmd) Run(logger *zerolog.Logger) error {
+	templates := []domain.ActionTemplate{}
+	if err := cmd.request(http.MethodGet, "/api/v1/template", nil, &templates); err != nil {
+		return err
+	}
+
+	return cmd.print(templates, outputFormatTable, func() outputTable {
+		table := outputTable{Header: []string{"name", "description"}}
+		for _, template := range templates {
+			table.add(template.Name, template.Description)
+		}
+		return table
+	})
+}
+
+type TemplatesShowCmd struct {
+	Name string `arg:"positional,required" help:"name of the template"`
+
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *TemplatesShowCmd) Run(logger *zerolog.Logger) error {
+	template := domain.ActionTemplate{}
+	if err := cmd.request(http.MethodGet, "/api/v1/template/"+url.PathEscape(cmd.Name), nil, &template); err != nil {
+		return err
+	}
+
+	// By default the parameters are printed above the source.
+	if cmd.Output != "" {
+		return cmd.print(template, "", func() outputTable {
+			table := outputTable{Header: []string{"parameter", "description", "default"}}
+			for _, param := range template.Parameters {
+				def := "-"
+				if param.Default != nil {
+					def = *param.Default
+				}
+				table.add(param.Name, param.Description, def)
+			}
+			return table
+		})
+	}
+
+	fmt.Println(template.Description)
+	fmt.Println()
+	fmt.Println("Parameters:")
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, param := range template.Parameters {
+		def := "required"
+		if param.Default != nil {
+			def = "default: " + *param.Default
+		}
+		fmt.Fprintf(table, "  %s\t%s\t(%s)\n", param.Name, param.Description, def)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Print(template.Source)
+	return nil
+}
+
+type TemplatesInstantiateCmd struct {
+	Name   string   `arg:"positional,required" help:"name of the template"`
+	Params []string `arg:"--param,separate" help:"parameter as name=value, may be given multiple times"`
+	Output string   `arg:"--output,-o" help:"file to write the action to, stdout if not given"`
+
+	ApiFlags
+}
+
+func (cmd *TemplatesInstantiateCmd) Run(logger *zerolog.Logger) error {
+	params := map[string]string{}
+	for _, param := range cmd.Params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return errors.Errorf("Invalid parameter %q, must be name=value", param)
+		}
+		params[name] = value
+	}
+
+	result := struct {
+		Source string `json:"source"`
+	}{}
+	if err := cmd.request(http.MethodPost, "/api/v1/template/"+url.PathEscape(cmd.Name)+"/instantiate", map[string]interface{}{"params": params}, &result); err != nil {
+		return err
+	}
+
+	if cmd.Output == "" {
+		fmt.Print(result.Source)
+		return nil
+	}
+
+	if err := os.WriteFile(cmd.Output, []byte(result.Source), 0o644); err != nil {
+		return errors.WithMessagef(err, "Could not write %q", cmd.Output)
+	}
+	logger.Info().Str("template", cmd.Name).Str("file", cmd.Output).Msg("Instantiated action template")
+	return nil
+}
@@ -0,0 +1,131 @@
+package cicero
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/input-output-hk/cicero/src/domain"
+)
+
+type TokenCmd struct {
+	Create *TokenCreateCmd `arg:"subcommand:create" help:"create an API token and print its secret"`
+	List   *TokenListCmd   `arg:"subcommand:list" help:"list API tokens"`
+	Rotate *TokenRotateCmd `arg:"subcommand:rotate" help:"replace an API token with a new one and print its secret"`
+	Revoke *TokenRevokeCmd `arg:"subcommand:revoke" help:"revoke an API token"`
+}
+
+func (cmd *TokenCmd) Run(logger *zerolog.Logger) error {
+	switch {
+	case cmd.Create != nil:
+		return cmd.Create.Run(logger)
+	case cmd.List != nil:
+		return cmd.List.Run(logger)
+	case cmd.Rotate != nil:
+		return cmd.Rotate.Run(logger)
+	case cmd.Revoke != nil:
+		return cmd.Revoke.Run(logger)
+	}
+	return errors.New("No subcommand given")
+}
+
+// Response of creating or rotating an API token.
+type tokenSecret struct {
+	Secret string          `json:"secret"`
+	Token  domain.ApiToken `json:"token"`
+}
+
+type TokenCreateCmd struct {
+	Name      string        `arg:"positional,required" help:"what the token is used for"`
+	Scopes    []string      `arg:"--scope,separate" help:"what the token may do, like facts:write or runs:*, may be given multiple times"`
+	ExpiresIn time.Duration `arg:"--expires-in" help:"how long the token is valid, forever if not given"`
+
+	ApiFlags
+}
+
+func (cmd *TokenCreateCmd) Run(logger *zerolog.Logger) error {
+	body := map[string]interface{}{
+		"name":   cmd.Name,
+		"scopes": cmd.Scopes,
+	}
+	if cmd.Scopes == nil {
+		body["scopes"] = []string{}
+	}
+	if cmd.ExpiresIn > 0 {
+		body["expires_in"] = cmd.ExpiresIn.String()
+	}
+
+	result := tokenSecret{}
+	if err := cmd.request(http.MethodPost, "/api/v1/token", body, &result); err != nil {
+		return err
+	}
+
+	logger.Info().Stringer("id", result.Token.ID).Strs("scopes", result.Token.Scopes).Msg("Created API token")
+	fmt.Println(result.Secret)
+	return nil
+}
+
+type TokenListCmd struct {
+	ApiFlags
+	OutputFlags
+}
+
+func (cmd *TokenListCmd) Run(logger *zerolog.Logger) error {
+	tokens := []domain.ApiToken{}
+	if err := cmd.request(http.MethodGet, "/api/v1/token", nil, &tokens); err != nil {
+		return err
+	}
+
+	return cmd.print(tokens, outputFormatJson, func() outputTable {
+		table := outputTable{Header: []string{"id", "name", "scopes", "created by", "expires at", "last used at", "revoked at"}}
+		for _, token := range tokens {
+			table.add(
+				token.ID.String(),
+				token.Name,
+				strings.Join(token.Scopes, ","),
+				token.CreatedBy,
+				outputTime(token.ExpiresAt),
+				outputTime(token.LastUsedAt),
+				outputTime(token.RevokedAt),
+			)
+		}
+		return table
+	})
+}
+
+type TokenRotateCmd struct {
+	Id string `arg:"positional,required" help:"ID of the token"`
+
+	ApiFlags
+}
+
+func (cmd *TokenRotateCmd) Run(logger *zerolog.Logger) error {
+	result := tokenSecret{}
+	if err := cmd.request(http.MethodPost, "/api/v1/token/"+url.PathEscape(cmd.Id)+"/rotate", nil, &result); err != nil {
+		return err
+	}
+
+	logger.Info().Stringer("id", result.Token.ID).Msg("Rotated API token")
+	fmt.Println(result.Secret)
+	return nil
+}
+
+type TokenRevokeCmd struct {
+	Id string `arg:"positional,required" help:"ID of the token"`
+
+	ApiFlags
+}
+
+func (cmd *TokenRevokeCmd) Run(logger *zerolog.Logger) error {
+	if err := cmd.request(http.MethodDelete, "/api/v1/token/"+url.PathEscape(cmd.Id), nil, nil); err != nil {
+		return err
+	}
+
+	logger.Info().Str("id", cmd.Id).Msg("Revoked API token")
+	return nil
+}
@@ -12,6 +12,15 @@ import (
 // There is a race condition around global internal state of CUE.
 var cueMutex = &sync.Mutex{}
 
+// Calls the function with a new context
+// while no other goroutine is using CUE.
+func WithCUEContext(f func(*cue.Context)) {
+	cueMutex.Lock()
+	defer cueMutex.Unlock()
+
+	f(cuecontext.New())
+}
+
 type CUEString string
 
 func (self CUEString) Value(ctx *cue.Context, optionsFunc func(*cue.Context) []cue.BuildOption) cue.Value {
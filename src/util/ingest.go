@@ -0,0 +1,166 @@
+package util
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type IngestOp string
+
+const (
+	// Renames the object members at Path to To,
+	// or replaces matches of Pattern in their names with To.
+	IngestRename IngestOp = "rename"
+	// Makes the names of the object members at Path lower case.
+	IngestLowercase IngestOp = "lowercase"
+	// Removes the object members at Path.
+	IngestDrop IngestOp = "drop"
+	// Adds a member named To next to each string at Path
+	// with the value from Table of the longest key that the string starts with.
+	IngestLookup IngestOp = "lookup"
+)
+
+// Transforms JSON values, for example facts' before they are saved.
+// Path is a JSONPath with the syntax of a Redaction.
+type IngestStep struct {
+	Op      IngestOp               `json:"op"`
+	Path    string                 `json:"path"`
+	Pattern string                 `json:"pattern,omitempty"`
+	To      string                 `json:"to,omitempty"`
+	Table   map[string]interface{} `json:"table,omitempty"`
+
+	path    []pathSegment
+	pattern *regexp.Regexp
+	// Keys of Table, longest first.
+	prefixes []string
+}
+
+func NewIngestStep(op IngestOp, path, pattern, to string, table map[string]interface{}) (IngestStep, error) {
+	self := IngestStep{Op: op, Path: path, Pattern: pattern, To: to, Table: table}
+
+	if segments, err := parsePath(path); err != nil {
+		return self, errors.WithMessagef(err, "Invalid path %q", path)
+	} else if len(segments) == 0 {
+		return self, errors.New("Path must not be the root")
+	} else {
+		self.path = segments
+	}
+
+	switch op {
+	case IngestRename:
+		if pattern != "" {
+			if re, err := regexp.Compile(pattern); err != nil {
+				return self, errors.WithMessagef(err, "Invalid pattern %q", pattern)
+			} else {
+				self.pattern = re
+			}
+		} else if to == "" {
+			return self, errors.New(`Renaming needs "to" or "pattern"`)
+		}
+	case IngestLowercase, IngestDrop:
+	case IngestLookup:
+		if to == "" {
+			return self, errors.New(`Lookup needs "to"`)
+		}
+		self.prefixes = make([]string, 0, len(table))
+		for prefix := range table {
+			self.prefixes = append(self.prefixes, prefix)
+		}
+		sort.Slice(self.prefixes, func(i, j int) bool { return len(self.prefixes[i]) > len(self.prefixes[j]) })
+	default:
+		return self, errors.Errorf("Unknown op %q", op)
+	}
+
+	return self, nil
+}
+
+func (self *IngestStep) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Op      IngestOp               `json:"op"`
+		Path    string                 `json:"path"`
+		Pattern string                 `json:"pattern"`
+		To      string                 `json:"to"`
+		Table   map[string]interface{} `json:"table"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	step, err := NewIngestStep(raw.Op, raw.Path, raw.Pattern, raw.To, raw.Table)
+	*self = step
+	return err
+}
+
+// Steps that are applied in order.
+type IngestPipeline []IngestStep
+
+// Returns a transformed copy of the value.
+// The value must consist of the types that `json.Unmarshal`
+// produces for `interface{}`.
+func (self IngestPipeline) Apply(value interface{}) interface{} {
+	if len(self) == 0 {
+		return value
+	}
+
+	value = deepCopyJSON(value)
+	for _, step := range self {
+		value = step.apply(value)
+	}
+	return value
+}
+
+func (self IngestStep) apply(value interface{}) interface{} {
+	root := []interface{}{value}
+
+	// Changing objects while walking them could visit renamed members again.
+	type member struct {
+		object map[string]interface{}
+		name   string
+	}
+	members := []member{}
+	walkPath(self.path, root, 0, func(parent interface{}, key interface{}) {
+		if object, ok := parent.(map[string]interface{}); ok {
+			members = append(members, member{object, key.(string)})
+		}
+	})
+
+	for _, m := range members {
+		switch self.Op {
+		case IngestRename:
+			if self.pattern != nil {
+				rename(m.object, m.name, self.pattern.ReplaceAllString(m.name, self.To))
+			} else {
+				rename(m.object, m.name, self.To)
+			}
+		case IngestLowercase:
+			rename(m.object, m.name, strings.ToLower(m.name))
+		case IngestDrop:
+			delete(m.object, m.name)
+		case IngestLookup:
+			if str, ok := m.object[m.name].(string); ok {
+				for _, prefix := range self.prefixes {
+					if strings.HasPrefix(str, prefix) {
+						m.object[self.To] = deepCopyJSON(self.Table[prefix])
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return root[0]
+}
+
+func rename(object map[string]interface{}, from, to string) {
+	if from == to {
+		return
+	}
+	if value, ok := object[from]; ok {
+		delete(object, from)
+		object[to] = value
+	}
+}
@@ -0,0 +1,85 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIngestPipeline(t *testing.T) {
+	value := map[string]interface{}{
+		"Host-Name": "ci-1",
+		"ip":        "10.1.2.3",
+		"repo":      "infra/deploy",
+		"debug":     map[string]interface{}{"trace": "…"},
+		"nested": map[string]interface{}{
+			"User-Agent": "curl",
+			"debug":      true,
+		},
+	}
+
+	for _, c := range []struct {
+		pipeline string
+		expected interface{}
+	}{
+		{`[]`, value},
+		{`[{"op": "lowercase", "path": "$..*"}, {"op": "rename", "path": "$..*", "pattern": "-", "to": "_"}]`, map[string]interface{}{
+			"host_name": "ci-1",
+			"ip":        "10.1.2.3",
+			"repo":      "infra/deploy",
+			"debug":     map[string]interface{}{"trace": "…"},
+			"nested": map[string]interface{}{
+				"user_agent": "curl",
+				"debug":      true,
+			},
+		}},
+		{`[{"op": "drop", "path": "$..debug"}, {"op": "rename", "path": "$.Host-Name", "to": "host"}]`, map[string]interface{}{
+			"host": "ci-1",
+			"ip":   "10.1.2.3",
+			"repo": "infra/deploy",
+			"nested": map[string]interface{}{
+				"User-Agent": "curl",
+			},
+		}},
+		{`[
+			{"op": "lookup", "path": "$.ip", "to": "geo", "table": {"10.": "dc-1", "10.1.": "dc-2"}},
+			{"op": "lookup", "path": "$.repo", "to": "owner", "table": {"web/": "web-team"}}
+		]`, map[string]interface{}{
+			"Host-Name": "ci-1",
+			"ip":        "10.1.2.3",
+			"geo":       "dc-2",
+			"repo":      "infra/deploy",
+			"debug":     value["debug"],
+			"nested":    value["nested"],
+		}},
+	} {
+		var pipeline IngestPipeline
+		if err := json.Unmarshal([]byte(c.pipeline), &pipeline); err != nil {
+			t.Fatal(c.pipeline, err)
+		}
+
+		if actual := pipeline.Apply(value); !reflect.DeepEqual(actual, c.expected) {
+			t.Error(c.pipeline, c.expected, actual)
+		}
+	}
+
+	if _, ok := value["Host-Name"]; !ok {
+		t.Error("original value was modified")
+	}
+}
+
+func TestIngestStepInvalid(t *testing.T) {
+	for _, c := range []string{
+		`{"op": "drop", "path": "debug"}`,
+		`{"op": "drop", "path": "$"}`,
+		`{"op": "rename", "path": "$.a"}`,
+		`{"op": "rename", "path": "$.a", "pattern": "("}`,
+		`{"op": "lookup", "path": "$.a"}`,
+		`{"op": "upcase", "path": "$.a"}`,
+	} {
+		var step IngestStep
+		if err := json.Unmarshal([]byte(c), &step); err == nil {
+			t.Error("expected error for", c)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"io"
 	"unicode"
 	"unicode/utf8"
@@ -20,14 +21,10 @@ func (f *skipLeadingWhitespaceReader) Read(p []byte) (int, error) {
 		return f.Reader.Read(p)
 	}
 
-	var bufArr [utf8.UTFMax]byte
-	buf := bufArr[:]
-
-	n, err := f.Reader.Read(buf)
-	if err != nil {
-		return n, err
-	}
-	buf = buf[:n]
+	// Read into `p` directly so that nothing read
+	// is lost if it is shorter than what was read.
+	n, err := f.Reader.Read(p)
+	buf := p[:n]
 
 	for len(buf) > 0 {
 		r, rs := utf8.DecodeRune(buf)
@@ -40,8 +37,7 @@ func (f *skipLeadingWhitespaceReader) Read(p []byte) (int, error) {
 		buf = buf[rs:]
 	}
 
-	copy(p, buf)
-	return len(buf), nil
+	return copy(p, buf), err
 }
 
 type CompositeReadCloser struct {
@@ -49,6 +45,10 @@ type CompositeReadCloser struct {
 	c io.Closer
 }
 
+func NewCompositeReadCloser(r io.Reader, c io.Closer) CompositeReadCloser {
+	return CompositeReadCloser{r, c}
+}
+
 func (self CompositeReadCloser) Read(p []byte) (int, error) {
 	return self.r.Read(p)
 }
@@ -56,3 +56,53 @@ func (self CompositeReadCloser) Read(p []byte) (int, error) {
 func (self CompositeReadCloser) Close() error {
 	return self.c.Close()
 }
+
+// Like `io.LimitReader()` but returns a `*SizeLimitExceededError`
+// instead of `io.EOF` if more than `limit` bytes are available.
+// A limit less than or equal to zero disables the check.
+func SizeLimitReader(reader io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return reader
+	}
+	return &sizeLimitReader{Reader: reader, remaining: limit, limit: limit}
+}
+
+type sizeLimitReader struct {
+	io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (self *sizeLimitReader) Read(p []byte) (int, error) {
+	if self.remaining <= 0 {
+		// Read one more byte to find out whether
+		// the reader ends exactly at the limit.
+		var probe [1]byte
+		n, err := self.Reader.Read(probe[:])
+		switch {
+		case n > 0:
+			return 0, &SizeLimitExceededError{Limit: self.limit}
+		case err != nil:
+			// Also passes through `io.EOF`.
+			return 0, err
+		default:
+			// Nothing read yet, the caller may try again.
+			return 0, nil
+		}
+	}
+
+	if int64(len(p)) > self.remaining {
+		p = p[:self.remaining]
+	}
+	n, err := self.Reader.Read(p)
+	self.remaining -= int64(n)
+	return n, err
+}
+
+type SizeLimitExceededError struct {
+	Limit int64
+}
+
+func (self *SizeLimitExceededError) Error() string {
+	return fmt.Sprintf("size limit of %d bytes exceeded", self.Limit)
+}
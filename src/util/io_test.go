@@ -2,8 +2,10 @@ package util
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"testing"
+	"testing/iotest"
 )
 
 func TestSkipLeadingWhitespaceReader(t *testing.T) {
@@ -20,4 +22,65 @@ func TestSkipLeadingWhitespaceReader(t *testing.T) {
 	if !bytes.HasPrefix(actual, expected) {
 		t.Fatal(expected, actual)
 	}
+
+	// Reads into a buffer shorter than the input must not drop or miscount bytes.
+	actual, err = io.ReadAll(iotest.OneByteReader(&skipLeadingWhitespaceReader{Reader: bytes.NewReader(given)}))
+	if err != nil {
+		t.Fatal(actual, err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Fatal(expected, actual)
+	}
+
+	actual, err = io.ReadAll(SizeLimitReader(SkipLeadingWhitespaceReader(io.MultiReader(bytes.NewReader([]byte(" ")), bytes.NewReader([]byte("WXYZ")))), 3))
+	var sizeErr *SizeLimitExceededError
+	if !errors.As(err, &sizeErr) {
+		t.Fatal(actual, err)
+	}
+}
+
+func TestSizeLimitReader(t *testing.T) {
+	given := []byte("abcdef")
+
+	if actual, err := io.ReadAll(SizeLimitReader(bytes.NewReader(given), 6)); err != nil {
+		t.Fatal(actual, err)
+	}
+
+	actual, err := io.ReadAll(SizeLimitReader(bytes.NewReader(given), 5))
+	var sizeErr *SizeLimitExceededError
+	if !errors.As(err, &sizeErr) {
+		t.Fatal(actual, err)
+	}
+	if !bytes.Equal(actual, given[:5]) {
+		t.Fatal(given[:5], actual)
+	}
+}
+
+func TestSizeLimitReaderAtLimit(t *testing.T) {
+	given := []byte("ab")
+
+	errReset := errors.New("connection reset")
+	actual, err := io.ReadAll(SizeLimitReader(io.MultiReader(bytes.NewReader(given), iotest.ErrReader(errReset)), 2))
+	if err != errReset {
+		t.Fatal(actual, err)
+	}
+
+	actual, err = io.ReadAll(SizeLimitReader(io.MultiReader(bytes.NewReader(given), &emptyReadReader{}), 2))
+	if err != nil {
+		t.Fatal(actual, err)
+	}
+	if !bytes.Equal(actual, given) {
+		t.Fatal(given, actual)
+	}
+}
+
+// Returns `(0, nil)` once before `io.EOF`.
+type emptyReadReader struct{ done bool }
+
+func (self *emptyReadReader) Read([]byte) (int, error) {
+	if self.done {
+		return 0, io.EOF
+	}
+	self.done = true
+	return 0, nil
 }
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signatures in the format of https://jedisct1.github.io/minisign/
+
+var (
+	minisignAlgorithm          = []byte("Ed")
+	minisignAlgorithmPrehashed = []byte("ED")
+)
+
+const (
+	minisignKeyIdSize          = 8
+	minisignUntrustedComment   = "untrusted comment:"
+	minisignTrustedComment     = "trusted comment: "
+	minisignPublicKeySize      = len("Ed") + minisignKeyIdSize + ed25519.PublicKeySize
+	minisignSignatureBlockSize = len("Ed") + minisignKeyIdSize + ed25519.SignatureSize
+)
+
+type MinisignPublicKey struct {
+	KeyId [minisignKeyIdSize]byte
+	Key   ed25519.PublicKey
+}
+
+type MinisignSignature struct {
+	Prehashed       bool
+	KeyId           [minisignKeyIdSize]byte
+	Signature       []byte
+	TrustedComment  string
+	GlobalSignature []byte
+}
+
+// Formats a key ID like minisign does.
+func MinisignKeyIdString(keyId [minisignKeyIdSize]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyId[:]))
+}
+
+// Parses a public key file or just the line with the key.
+func ParseMinisignPublicKey(str string) (*MinisignPublicKey, error) {
+	lines := minisignLines(str)
+	if len(lines) != 1 {
+		return nil, errors.New("Minisign public key must have exactly one line besides the untrusted comment")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not decode minisign public key")
+	}
+	if len(decoded) != minisignPublicKeySize || !bytes.Equal(decoded[:2], minisignAlgorithm) {
+		return nil, errors.New("Not a minisign Ed25519 public key")
+	}
+
+	key := MinisignPublicKey{Key: ed25519.PublicKey(decoded[2+minisignKeyIdSize:])}
+	copy(key.KeyId[:], decoded[2:])
+	return &key, nil
+}
+
+// Parses a signature file.
+func ParseMinisignSignature(str string) (*MinisignSignature, error) {
+	lines := minisignLines(str)
+	if len(lines) != 3 {
+		return nil, errors.New("Minisign signature must have a signature, a trusted comment, and a global signature besides the untrusted comment")
+	}
+
+	sig := MinisignSignature{}
+
+	if decoded, err := base64.StdEncoding.DecodeString(lines[0]); err != nil {
+		return nil, errors.WithMessage(err, "Could not decode minisign signature")
+	} else if len(decoded) != minisignSignatureBlockSize {
+		return nil, errors.New("Minisign signature has the wrong size")
+	} else {
+		switch {
+		case bytes.Equal(decoded[:2], minisignAlgorithm):
+		case bytes.Equal(decoded[:2], minisignAlgorithmPrehashed):
+			sig.Prehashed = true
+		default:
+			return nil, errors.Errorf("Unknown minisign signature algorithm %q", decoded[:2])
+		}
+		copy(sig.KeyId[:], decoded[2:])
+		sig.Signature = decoded[2+minisignKeyIdSize:]
+	}
+
+	if comment := strings.TrimPrefix(lines[1], minisignTrustedComment); comment == lines[1] {
+		return nil, errors.New("Minisign signature has no trusted comment")
+	} else {
+		sig.TrustedComment = comment
+	}
+
+	if decoded, err := base64.StdEncoding.DecodeString(lines[2]); err != nil {
+		return nil, errors.WithMessage(err, "Could not decode minisign global signature")
+	} else if len(decoded) != ed25519.SignatureSize {
+		return nil, errors.New("Minisign global signature has the wrong size")
+	} else {
+		sig.GlobalSignature = decoded
+	}
+
+	return &sig, nil
+}
+
+// Returns the non-empty lines without the untrusted comment.
+func minisignLines(str string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, minisignUntrustedComment) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Verifies the signature of the message and its trusted comment.
+func (self MinisignPublicKey) Verify(message []byte, sig MinisignSignature) error {
+	if sig.KeyId != self.KeyId {
+		return errors.Errorf("Signature was made with key %s instead of %s", MinisignKeyIdString(sig.KeyId), MinisignKeyIdString(self.KeyId))
+	}
+
+	if sig.Prehashed {
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	}
+	if !ed25519.Verify(self.Key, message, sig.Signature) {
+		return errors.New("Invalid signature")
+	}
+
+	if !ed25519.Verify(self.Key, append(append([]byte{}, sig.Signature...), sig.TrustedComment...), sig.GlobalSignature) {
+		return errors.New("Invalid signature of the trusted comment")
+	}
+
+	return nil
+}
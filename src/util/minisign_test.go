@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signs like `minisign -S` does.
+func minisignSign(key ed25519.PrivateKey, keyId []byte, message []byte, prehashed bool, trustedComment string) string {
+	algorithm := "Ed"
+	if prehashed {
+		algorithm = "ED"
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	}
+	sig := ed25519.Sign(key, message)
+	globalSig := ed25519.Sign(key, append(append([]byte{}, sig...), trustedComment...))
+
+	return "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), keyId...), sig...)) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSig) + "\n"
+}
+
+func TestMinisign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyId := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	pubStr := "untrusted comment: minisign public key 0807060504030201\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyId...), pub...)) + "\n"
+
+	key, err := ParseMinisignPublicKey(pubStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := MinisignKeyIdString(key.KeyId); id != "0807060504030201" {
+		t.Errorf("expected key ID 0807060504030201 but got %s", id)
+	}
+
+	message := []byte(`{"ok":true}`)
+
+	for _, prehashed := range []bool{false, true} {
+		sigStr := minisignSign(priv, keyId, message, prehashed, "timestamp:1666000000")
+
+		sig, err := ParseMinisignSignature(sigStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := key.Verify(message, *sig); err != nil {
+			t.Errorf("prehashed=%t: expected valid signature but got: %s", prehashed, err)
+		}
+		if err := key.Verify([]byte(`{"ok":false}`), *sig); err == nil {
+			t.Errorf("prehashed=%t: expected signature of another message to be invalid", prehashed)
+		}
+
+		sig.TrustedComment = "forged"
+		if err := key.Verify(message, *sig); err == nil {
+			t.Errorf("prehashed=%t: expected forged trusted comment to be invalid", prehashed)
+		}
+	}
+
+	otherKey := *key
+	otherKey.KeyId[0] = 0
+	sig, _ := ParseMinisignSignature(minisignSign(priv, keyId, message, false, ""))
+	if err := otherKey.Verify(message, *sig); err == nil {
+		t.Error("expected signature with another key ID to be invalid")
+	}
+
+	if _, err := ParseMinisignSignature("untrusted comment: nothing\n"); err == nil {
+		t.Error("expected an incomplete signature to be invalid")
+	}
+}
@@ -0,0 +1,263 @@
+package util
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const Redacted = "[REDACTED]"
+
+// Hides parts of JSON values.
+// Path is a JSONPath of the values to redact and defaults to
+// all values. The supported syntax is `$`, `.name`, `['name']`,
+// `[0]`, `.*`, `[*]`, and `..` for recursive descent.
+// If Pattern is given only matches of this regular expression
+// in strings at Path are redacted, otherwise whole values are.
+type Redaction struct {
+	Path    string `json:"path,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+
+	path    []pathSegment
+	pattern *regexp.Regexp
+}
+
+func NewRedaction(path, pattern string) (Redaction, error) {
+	self := Redaction{Path: path, Pattern: pattern}
+
+	if path == "" {
+		path = "$..*"
+	}
+	if segments, err := parsePath(path); err != nil {
+		return self, errors.WithMessagef(err, "Invalid path %q", path)
+	} else {
+		self.path = segments
+	}
+
+	if pattern != "" {
+		if re, err := regexp.Compile(pattern); err != nil {
+			return self, errors.WithMessagef(err, "Invalid pattern %q", pattern)
+		} else {
+			self.pattern = re
+		}
+	}
+
+	return self, nil
+}
+
+func (self *Redaction) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Path    string `json:"path"`
+		Pattern string `json:"pattern"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	redaction, err := NewRedaction(raw.Path, raw.Pattern)
+	*self = redaction
+	return err
+}
+
+type Redactions []Redaction
+
+// Returns a copy of the value with all redactions applied.
+// The value must consist of the types that `json.Unmarshal`
+// produces for `interface{}`.
+func (self Redactions) Apply(value interface{}) interface{} {
+	if len(self) == 0 {
+		return value
+	}
+
+	value = deepCopyJSON(value)
+	for _, redaction := range self {
+		value = redaction.apply(value)
+	}
+	return value
+}
+
+func (self Redaction) apply(value interface{}) interface{} {
+	root := []interface{}{value}
+	walkPath(self.path, root, 0, func(parent interface{}, key interface{}) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key.(string)] = self.redact(p[key.(string)])
+		case []interface{}:
+			p[key.(int)] = self.redact(p[key.(int)])
+		}
+	})
+	return root[0]
+}
+
+func (self Redaction) redact(value interface{}) interface{} {
+	if self.pattern == nil {
+		return Redacted
+	}
+
+	switch v := value.(type) {
+	case string:
+		return self.pattern.ReplaceAllString(v, Redacted)
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = self.redact(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = self.redact(child)
+		}
+	}
+	return value
+}
+
+type pathSegmentKind int
+
+const (
+	pathChild pathSegmentKind = iota
+	pathIndex
+	pathWildcard
+	pathDescend
+)
+
+type pathSegment struct {
+	kind  pathSegmentKind
+	name  string
+	index int
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New("Must start with $")
+	}
+	rest := path[1:]
+
+	segments := []pathSegment{}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segments = append(segments, pathSegment{kind: pathDescend})
+			rest = rest[1:] // keep one dot for the following name
+			if strings.HasPrefix(rest, ".[") {
+				rest = rest[1:]
+			}
+		case strings.HasPrefix(rest, ".*"):
+			segments = append(segments, pathSegment{kind: pathWildcard})
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, errors.New("Empty name")
+			}
+			segments = append(segments, pathSegment{kind: pathChild, name: name})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, errors.New("Unclosed [")
+			}
+			inner := rest[1:end]
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{kind: pathWildcard})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, pathSegment{kind: pathChild, name: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, errors.Errorf("Invalid subscript %q", inner)
+				}
+				segments = append(segments, pathSegment{kind: pathIndex, index: index})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, errors.Errorf("Unexpected %q", rest)
+		}
+	}
+
+	return segments, nil
+}
+
+// Calls found with the parent and key of every value matching the path.
+// The root value is the only element of the slice given as parent.
+func walkPath(path []pathSegment, parent interface{}, key interface{}, found func(parent, key interface{})) {
+	if len(path) == 0 {
+		found(parent, key)
+		return
+	}
+
+	var value interface{}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		value = p[key.(string)]
+	case []interface{}:
+		value = p[key.(int)]
+	}
+
+	segment, rest := path[0], path[1:]
+	switch segment.kind {
+	case pathChild:
+		if v, ok := value.(map[string]interface{}); ok {
+			if _, exists := v[segment.name]; exists {
+				walkPath(rest, v, segment.name, found)
+			}
+		}
+	case pathIndex:
+		if v, ok := value.([]interface{}); ok {
+			index := segment.index
+			if index < 0 {
+				index += len(v)
+			}
+			if index >= 0 && index < len(v) {
+				walkPath(rest, v, index, found)
+			}
+		}
+	case pathWildcard:
+		eachChild(value, func(parent, key interface{}) {
+			walkPath(rest, parent, key, found)
+		})
+	case pathDescend:
+		// Match the rest here and at every level below.
+		walkPath(rest, parent, key, found)
+		eachChild(value, func(parent, key interface{}) {
+			walkPath(path, parent, key, found)
+		})
+	}
+}
+
+func eachChild(value interface{}, f func(parent, key interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			f(v, k)
+		}
+	case []interface{}:
+		for i := range v {
+			f(v, i)
+		}
+	}
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			c[k] = deepCopyJSON(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = deepCopyJSON(child)
+		}
+		return c
+	default:
+		return value
+	}
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRedactions(t *testing.T) {
+	value := map[string]interface{}{
+		"token": "secret",
+		"env": []interface{}{
+			"GITHUB_TOKEN=ghp_abc123",
+			"HOME=/root",
+		},
+		"nested": map[string]interface{}{
+			"token": "also secret",
+			"n":     float64(1),
+		},
+	}
+
+	for _, c := range []struct {
+		redactions string
+		expected   interface{}
+	}{
+		{`[]`, value},
+		{`[{"path": "$.token"}]`, map[string]interface{}{
+			"token":  Redacted,
+			"env":    value["env"],
+			"nested": value["nested"],
+		}},
+		{`[{"path": "$..token"}]`, map[string]interface{}{
+			"token": Redacted,
+			"env":   value["env"],
+			"nested": map[string]interface{}{
+				"token": Redacted,
+				"n":     float64(1),
+			},
+		}},
+		{`[{"path": "$.env[*]", "pattern": "ghp_\\w+"}]`, map[string]interface{}{
+			"token": "secret",
+			"env": []interface{}{
+				"GITHUB_TOKEN=" + Redacted,
+				"HOME=/root",
+			},
+			"nested": value["nested"],
+		}},
+		{`[{"pattern": "secret"}, {"path": "$['env'][-1]"}]`, map[string]interface{}{
+			"token": Redacted,
+			"env": []interface{}{
+				"GITHUB_TOKEN=ghp_abc123",
+				Redacted,
+			},
+			"nested": map[string]interface{}{
+				"token": "also " + Redacted,
+				"n":     float64(1),
+			},
+		}},
+	} {
+		var redactions Redactions
+		if err := json.Unmarshal([]byte(c.redactions), &redactions); err != nil {
+			t.Fatal(c.redactions, err)
+		}
+
+		if actual := redactions.Apply(value); !reflect.DeepEqual(actual, c.expected) {
+			t.Error(c.redactions, c.expected, actual)
+		}
+	}
+
+	if value["token"] != "secret" {
+		t.Error("original value was modified")
+	}
+}
+
+func TestRedactionInvalid(t *testing.T) {
+	for _, c := range []string{
+		`{"path": "token"}`,
+		`{"path": "$.a["}`,
+		`{"path": "$[x]"}`,
+		`{"pattern": "("}`,
+	} {
+		var redaction Redaction
+		if err := json.Unmarshal([]byte(c), &redaction); err == nil {
+			t.Error("expected error for", c)
+		}
+	}
+}